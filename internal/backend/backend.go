@@ -0,0 +1,37 @@
+// Package backend abstracts the media server a list is backed by, so lists
+// can browse and play from Jellyfin as well as Kodi. Results use the Kodi
+// client's types, which the rest of the app is built around.
+package backend
+
+import (
+	"io"
+
+	"whats-next/internal/kodi"
+)
+
+// MediaBackend is what lists need from a media server to be searched,
+// drilled into and played from. Library syncs, notifications and player
+// control remain Kodi-only.
+type MediaBackend interface {
+	// Search returns the movies ("movie") or TV shows ("tv") that may match
+	// query, for the caller to rank with kodi.FuzzySearch. Kodi also
+	// searches albums ("album") and artists ("artist") for music lists.
+	Search(mediaType, query string) ([]kodi.MediaItem, error)
+	GetSeasons(showID int) ([]kodi.MediaItem, error)
+	GetEpisodes(showID, season int) ([]kodi.MediaItem, error)
+	// GetArtwork downloads the artwork at an art URI from a MediaItem,
+	// returning kodi.ErrImageNotFound when there is none. The caller closes
+	// the body.
+	GetArtwork(imageURI string) (io.ReadCloser, error)
+	// Play starts playback of an item, replacing whatever was playing.
+	Play(item kodi.PlaylistItem) error
+}
+
+var _ MediaBackend = (*kodi.Client)(nil)
+
+// IDMap gives a media server's string item IDs the integer IDs lists and
+// caches key items by. The database implements it.
+type IDMap interface {
+	BackendLocalID(host, externalID string) (int, error)
+	BackendExternalID(host string, localID int) (string, error)
+}
@@ -0,0 +1,375 @@
+package backend
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"whats-next/internal/kodi"
+)
+
+// ticksPerSecond converts Jellyfin's 100ns runtime ticks.
+const ticksPerSecond = 10_000_000
+
+// searchLimit caps how many items a search asks Jellyfin for.
+const searchLimit = 100
+
+// jellyfinArt maps Jellyfin image types to the Kodi art types lists use.
+var jellyfinArt = map[string]string{
+	"Primary": "poster",
+	"Thumb":   "landscape",
+	"Banner":  "banner",
+	"Logo":    "clearlogo",
+	"Art":     "clearart",
+}
+
+// Jellyfin is a Jellyfin server reached with an API key. Its item IDs are
+// mapped to integers through IDs. When User is set, results carry that
+// user's watched state and playback goes to their sessions.
+type Jellyfin struct {
+	BaseURL    string
+	User       string
+	APIKey     string
+	HTTPClient *http.Client
+	IDs        IDMap
+
+	mu     sync.Mutex
+	userID string
+}
+
+// NewJellyfin returns a backend for the Jellyfin server at baseURL.
+func NewJellyfin(baseURL, user, apiKey string, hc *http.Client, ids IDMap) *Jellyfin {
+	baseURL = strings.TrimRight(baseURL, "/")
+	if !strings.HasPrefix(baseURL, "http") {
+		baseURL = "http://" + baseURL
+	}
+	return &Jellyfin{BaseURL: baseURL, User: user, APIKey: apiKey, HTTPClient: hc, IDs: ids}
+}
+
+// jellyfinItem is the subset of Jellyfin's BaseItemDto lists use.
+type jellyfinItem struct {
+	ID                 string            `json:"Id"`
+	Name               string            `json:"Name"`
+	Type               string            `json:"Type"`
+	ProductionYear     int               `json:"ProductionYear"`
+	CommunityRating    float64           `json:"CommunityRating"`
+	Overview           string            `json:"Overview"`
+	Genres             []string          `json:"Genres"`
+	RunTimeTicks       int64             `json:"RunTimeTicks"`
+	DateCreated        string            `json:"DateCreated"`
+	ProviderIDs        map[string]string `json:"ProviderIds"`
+	ImageTags          map[string]string `json:"ImageTags"`
+	BackdropImageTags  []string          `json:"BackdropImageTags"`
+	IndexNumber        int               `json:"IndexNumber"`
+	ParentIndexNumber  int               `json:"ParentIndexNumber"`
+	SeriesID           string            `json:"SeriesId"`
+	SeriesName         string            `json:"SeriesName"`
+	ChildCount         int               `json:"ChildCount"`
+	RecursiveItemCount int               `json:"RecursiveItemCount"`
+	UserData           *struct {
+		PlayCount             int    `json:"PlayCount"`
+		Played                bool   `json:"Played"`
+		PlaybackPositionTicks int64  `json:"PlaybackPositionTicks"`
+		LastPlayedDate        string `json:"LastPlayedDate"`
+		UnplayedItemCount     int    `json:"UnplayedItemCount"`
+	} `json:"UserData"`
+}
+
+// jellyfinSession is a client connected to Jellyfin.
+type jellyfinSession struct {
+	ID                    string `json:"Id"`
+	SupportsRemoteControl bool   `json:"SupportsRemoteControl"`
+	LastActivityDate      string `json:"LastActivityDate"`
+}
+
+// Search asks Jellyfin for the movies or series whose name matches query.
+func (j *Jellyfin) Search(mediaType, query string) ([]kodi.MediaItem, error) {
+	params := url.Values{
+		"IncludeItemTypes": {"Movie"},
+		"Recursive":        {"true"},
+		"SearchTerm":       {query},
+		"Fields":           {"Overview,Genres,ProviderIds,DateCreated,RecursiveItemCount"},
+		"Limit":            {fmt.Sprint(searchLimit)},
+	}
+	if mediaType == "tv" {
+		params.Set("IncludeItemTypes", "Series")
+	}
+	return j.items("/Items", params)
+}
+
+// GetSeasons returns the seasons of a series.
+func (j *Jellyfin) GetSeasons(showID int) ([]kodi.MediaItem, error) {
+	seriesID, err := j.IDs.BackendExternalID(j.BaseURL, showID)
+	if err != nil {
+		return nil, err
+	}
+	return j.items("/Shows/"+url.PathEscape(seriesID)+"/Seasons", url.Values{"Fields": {"ChildCount"}})
+}
+
+// GetEpisodes returns the episodes of one season of a series.
+func (j *Jellyfin) GetEpisodes(showID, season int) ([]kodi.MediaItem, error) {
+	seriesID, err := j.IDs.BackendExternalID(j.BaseURL, showID)
+	if err != nil {
+		return nil, err
+	}
+	params := url.Values{"Season": {fmt.Sprint(season)}, "Fields": {"Overview"}}
+	return j.items("/Shows/"+url.PathEscape(seriesID)+"/Episodes", params)
+}
+
+// GetArtwork downloads an image URL from a MediaItem Jellyfin returned.
+func (j *Jellyfin) GetArtwork(imageURI string) (io.ReadCloser, error) {
+	if !strings.HasPrefix(imageURI, j.BaseURL+"/Items/") {
+		return nil, fmt.Errorf("%s is not a Jellyfin image of %s", imageURI, j.BaseURL)
+	}
+	resp, err := j.do(http.MethodGet, strings.TrimPrefix(imageURI, j.BaseURL), nil)
+	if err != nil {
+		if errors.Is(err, errJellyfinNotFound) {
+			return nil, kodi.ErrImageNotFound
+		}
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Play starts a movie, episode, series or season on the user's most
+// recently active Jellyfin client that accepts remote control.
+func (j *Jellyfin) Play(item kodi.PlaylistItem) error {
+	itemID, err := j.playableID(item)
+	if err != nil {
+		return err
+	}
+	userID, err := j.resolveUser()
+	if err != nil {
+		return err
+	}
+	params := url.Values{}
+	if userID != "" {
+		params.Set("ControllableByUserId", userID)
+	}
+	var sessions []jellyfinSession
+	if err := j.get("/Sessions", params, &sessions); err != nil {
+		return err
+	}
+	sessions = slices.DeleteFunc(sessions, func(s jellyfinSession) bool { return !s.SupportsRemoteControl })
+	if len(sessions) == 0 {
+		return fmt.Errorf("no Jellyfin client of %s accepts remote control", j.BaseURL)
+	}
+	latest := sessions[0]
+	for _, s := range sessions[1:] {
+		if s.LastActivityDate > latest.LastActivityDate {
+			latest = s
+		}
+	}
+	path := "/Sessions/" + url.PathEscape(latest.ID) + "/Playing?" + url.Values{"playCommand": {"PlayNow"}, "itemIds": {itemID}}.Encode()
+	resp, err := j.do(http.MethodPost, path, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// playableID returns the Jellyfin ID of a playlist item. Shows and seasons
+// come as the videodb directories kodi.ShowPlaylistItem builds.
+func (j *Jellyfin) playableID(item kodi.PlaylistItem) (string, error) {
+	switch {
+	case item.MovieID != 0:
+		return j.IDs.BackendExternalID(j.BaseURL, item.MovieID)
+	case item.EpisodeID != 0:
+		return j.IDs.BackendExternalID(j.BaseURL, item.EpisodeID)
+	case strings.HasPrefix(item.Directory, "videodb://tvshows/titles/"):
+		var showID, season int
+		fmt.Sscanf(strings.TrimPrefix(item.Directory, "videodb://tvshows/titles/"), "%d/%d", &showID, &season)
+		if season == 0 {
+			return j.IDs.BackendExternalID(j.BaseURL, showID)
+		}
+		seasons, err := j.GetSeasons(showID)
+		if err != nil {
+			return "", err
+		}
+		for _, s := range seasons {
+			if s.Season == season {
+				return j.IDs.BackendExternalID(j.BaseURL, s.ID)
+			}
+		}
+		return "", fmt.Errorf("season %d not found on %s", season, j.BaseURL)
+	}
+	return "", errors.New("jellyfin can only play library movies, shows and episodes")
+}
+
+// items fetches a Jellyfin item query and converts its results.
+func (j *Jellyfin) items(path string, params url.Values) ([]kodi.MediaItem, error) {
+	userID, err := j.resolveUser()
+	if err != nil {
+		return nil, err
+	}
+	if userID != "" {
+		params.Set("UserId", userID)
+	}
+	var result struct {
+		Items []jellyfinItem `json:"Items"`
+	}
+	if err := j.get(path, params, &result); err != nil {
+		return nil, err
+	}
+	out := make([]kodi.MediaItem, 0, len(result.Items))
+	for _, it := range result.Items {
+		m, err := j.mediaItem(it)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+// mediaItem converts a Jellyfin item to the shape Kodi results have.
+func (j *Jellyfin) mediaItem(it jellyfinItem) (kodi.MediaItem, error) {
+	id, err := j.IDs.BackendLocalID(j.BaseURL, it.ID)
+	if err != nil {
+		return kodi.MediaItem{}, err
+	}
+	m := kodi.MediaItem{
+		ID:        id,
+		Label:     it.Name,
+		Title:     it.Name,
+		Year:      it.ProductionYear,
+		Rating:    it.CommunityRating,
+		Plot:      it.Overview,
+		Genres:    it.Genres,
+		Runtime:   int(it.RunTimeTicks / ticksPerSecond),
+		DateAdded: kodiTime(it.DateCreated),
+		Art:       map[string]string{},
+	}
+	for imageType, artType := range jellyfinArt {
+		if it.ImageTags[imageType] != "" {
+			m.Art[artType] = j.imageURL(it.ID, imageType)
+		}
+	}
+	if len(it.BackdropImageTags) > 0 {
+		m.Art["fanart"] = j.imageURL(it.ID, "Backdrop/0")
+	}
+	m.Thumbnail = m.Art["poster"]
+	for name, v := range it.ProviderIDs {
+		if m.UniqueID == nil {
+			m.UniqueID = map[string]string{}
+		}
+		m.UniqueID[strings.ToLower(name)] = v
+	}
+
+	switch it.Type {
+	case "Series":
+		m.EpisodeCount = it.RecursiveItemCount
+	case "Season":
+		m.Season = it.IndexNumber
+		m.EpisodeCount = it.ChildCount
+	case "Episode":
+		m.Season, m.Episode, m.ShowTitle = it.ParentIndexNumber, it.IndexNumber, it.SeriesName
+	}
+	if it.SeriesID != "" {
+		if m.TVShowID, err = j.IDs.BackendLocalID(j.BaseURL, it.SeriesID); err != nil {
+			return kodi.MediaItem{}, err
+		}
+	}
+	if ud := it.UserData; ud != nil {
+		m.Playcount = ud.PlayCount
+		if ud.Played && m.Playcount == 0 {
+			m.Playcount = 1
+		}
+		m.LastPlayed = kodiTime(ud.LastPlayedDate)
+		if ud.PlaybackPositionTicks > 0 {
+			m.Resume = &kodi.Resume{Position: float64(ud.PlaybackPositionTicks / ticksPerSecond), Total: float64(m.Runtime)}
+		}
+		if m.EpisodeCount > 0 {
+			m.WatchedEpisodes = max(m.EpisodeCount-ud.UnplayedItemCount, 0)
+		}
+	}
+	return m, nil
+}
+
+func (j *Jellyfin) imageURL(itemID, imageType string) string {
+	return j.BaseURL + "/Items/" + url.PathEscape(itemID) + "/Images/" + imageType
+}
+
+// kodiTime converts a Jellyfin timestamp to Kodi's "YYYY-MM-DD HH:MM:SS".
+func kodiTime(v string) string {
+	t, err := time.Parse(time.RFC3339Nano, v)
+	if err != nil {
+		return ""
+	}
+	return t.Local().Format(time.DateTime)
+}
+
+// resolveUser looks up the ID of User, once; it is "" when no user is set.
+func (j *Jellyfin) resolveUser() (string, error) {
+	if j.User == "" {
+		return "", nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.userID != "" {
+		return j.userID, nil
+	}
+	var users []struct {
+		ID   string `json:"Id"`
+		Name string `json:"Name"`
+	}
+	if err := j.get("/Users", nil, &users); err != nil {
+		return "", err
+	}
+	for _, u := range users {
+		if strings.EqualFold(u.Name, j.User) {
+			j.userID = u.ID
+			return u.ID, nil
+		}
+	}
+	return "", fmt.Errorf("jellyfin user %q not found on %s", j.User, j.BaseURL)
+}
+
+var errJellyfinNotFound = errors.New("not found")
+
+func (j *Jellyfin) get(path string, params url.Values, out interface{}) error {
+	if len(params) > 0 {
+		path += "?" + params.Encode()
+	}
+	resp, err := j.do(http.MethodGet, path, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode jellyfin %s: %w", path, err)
+	}
+	return nil
+}
+
+// do sends an authenticated request, turning error statuses into errors.
+func (j *Jellyfin) do(method, path string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, j.BaseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("MediaBrowser Token=%q", j.APIKey))
+	resp, err := j.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, fmt.Errorf("jellyfin %s rejected the API key", j.BaseURL)
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("jellyfin %s %s: %w", method, path, errJellyfinNotFound)
+	}
+	return nil, fmt.Errorf("jellyfin %s %s: status %d", method, path, resp.StatusCode)
+}
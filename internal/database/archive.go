@@ -0,0 +1,68 @@
+package database
+
+import "time"
+
+// ApplyWatchedPolicies removes or archives watched items on lists whose
+// on_watched setting asks for it. Items restored from the archive are left
+// alone until Kodi reports them unwatched and then watched again.
+func (db *DB) ApplyWatchedPolicies() (removed, archived int64, err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE items SET keep_watched = 0 WHERE keep_watched = 1 AND watched = 0"); err != nil {
+		return 0, 0, err
+	}
+	res, err := tx.Exec(`
+		DELETE FROM items
+		WHERE watched = 1 AND keep_watched = 0
+		AND list_id IN (SELECT id FROM lists WHERE on_watched = 'remove')`)
+	if err != nil {
+		return 0, 0, err
+	}
+	removed, _ = res.RowsAffected()
+
+	res, err = tx.Exec(`
+		UPDATE items SET archived_at = ?
+		WHERE watched = 1 AND keep_watched = 0 AND archived_at = ''
+		AND list_id IN (SELECT id FROM lists WHERE on_watched = 'archive')`,
+		time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return 0, 0, err
+	}
+	archived, _ = res.RowsAffected()
+	return removed, archived, tx.Commit()
+}
+
+// RestoreItem moves an archived item back onto its list, or returns
+// ErrItemNotFound if no archived item has that ID.
+func (db *DB) RestoreItem(id int64) error {
+	res, err := db.Exec("UPDATE items SET archived_at = '', keep_watched = 1 WHERE id = ? AND archived_at != ''", id)
+	if err != nil {
+		return err
+	}
+	return requireRow(res, ErrItemNotFound)
+}
+
+// ArchiveList hides a list from the list views, keeping its items, history
+// and settings. Archiving an archived list keeps its original date. It
+// returns ErrListNotFound for unknown lists.
+func (db *DB) ArchiveList(id int64) error {
+	res, err := db.Exec("UPDATE lists SET archived_at = CASE WHEN archived_at = '' THEN ? ELSE archived_at END WHERE id = ?",
+		time.Now().UTC().Format(time.RFC3339), id)
+	if err != nil {
+		return err
+	}
+	return requireRow(res, ErrListNotFound)
+}
+
+// RestoreList brings an archived list back into the list views.
+func (db *DB) RestoreList(id int64) error {
+	res, err := db.Exec("UPDATE lists SET archived_at = '' WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	return requireRow(res, ErrListNotFound)
+}
@@ -0,0 +1,241 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"slices"
+)
+
+// PosterFailure is library artwork that couldn't be downloaded during a
+// sync, kept per Kodi host so it can be retried without a full sync.
+type PosterFailure struct {
+	KodiHost  string `json:"kodi_host"`
+	MediaType string `json:"media_type"`
+	KodiID    int    `json:"kodi_id"`
+	Title     string `json:"title"`
+	Year      int    `json:"year"`
+	ImageURI  string `json:"image_uri"`
+	Error     string `json:"error"`
+	Attempts  int    `json:"attempts"`
+	FailedAt  string `json:"failed_at"`
+}
+
+// ReplacePosterFailures records the artwork a sync of mediaType on listID's
+// host failed to fetch, replacing the previous sync's failures. An
+// incremental sync passes the items it fetched as synced so only their
+// failures are replaced; nil replaces all.
+func (db *DB) ReplacePosterFailures(listID int64, mediaType string, synced []int, failures []PosterFailure) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var host string
+	if err := tx.QueryRow("SELECT effective_host FROM lists WHERE id = ?", listID).Scan(&host); err != nil {
+		return err
+	}
+	if err := clearSynced(tx, "poster_failures", host, mediaType, synced); err != nil {
+		return err
+	}
+	for _, f := range failures {
+		_, err := tx.Exec(`
+			INSERT OR REPLACE INTO poster_failures (kodi_host, media_type, kodi_id, title, year, image_uri, error)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`, host, mediaType, f.KodiID, f.Title, f.Year, f.ImageURI, f.Error)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetPosterFailures returns the outstanding artwork failures on listID's
+// host. An empty mediaType returns every type.
+func (db *DB) GetPosterFailures(listID int64, mediaType string) ([]PosterFailure, error) {
+	if err := db.listExists(listID); err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(`
+		SELECT pf.kodi_host, pf.media_type, pf.kodi_id, pf.title, pf.year, pf.image_uri, pf.error, pf.attempts, pf.failed_at
+		FROM poster_failures pf
+		JOIN lists l_current ON l_current.id = ?
+		WHERE pf.kodi_host = l_current.effective_host
+		AND (? = '' OR pf.media_type = ?)
+		ORDER BY pf.media_type, pf.title`, listID, mediaType, mediaType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	failures := []PosterFailure{}
+	for rows.Next() {
+		var f PosterFailure
+		if err := rows.Scan(&f.KodiHost, &f.MediaType, &f.KodiID, &f.Title, &f.Year, &f.ImageURI, &f.Error, &f.Attempts, &f.FailedAt); err != nil {
+			return nil, err
+		}
+		failures = append(failures, f)
+	}
+	return failures, rows.Err()
+}
+
+// ResolvePosterFailure stores a successfully retried poster on the cached
+// library item and on list items of the host that have no artwork yet, and
+// forgets the failure.
+func (db *DB) ResolvePosterFailure(f PosterFailure, poster string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	queries := []string{
+		`UPDATE library_cache SET poster_path = ?
+		WHERE kodi_id = ? AND media_type = ?
+		AND list_id IN (SELECT id FROM lists WHERE effective_host = ?)`,
+		`UPDATE items SET poster_path = ?
+		WHERE kodi_id = ? AND media_type = ? AND poster_path = ''
+		AND list_id IN (SELECT id FROM lists WHERE effective_host = ?)`,
+	}
+	for _, q := range queries {
+		if _, err := tx.Exec(q, poster, f.KodiID, f.MediaType, f.KodiHost); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec("DELETE FROM poster_failures WHERE kodi_host = ? AND media_type = ? AND kodi_id = ?", f.KodiHost, f.MediaType, f.KodiID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RecordPosterRetryFailure bumps the attempt count of a failure that failed
+// again.
+func (db *DB) RecordPosterRetryFailure(f PosterFailure, reason string) error {
+	_, err := db.Exec(`
+		UPDATE poster_failures SET attempts = attempts + 1, error = ?, failed_at = CURRENT_TIMESTAMP
+		WHERE kodi_host = ? AND media_type = ? AND kodi_id = ?`, reason, f.KodiHost, f.MediaType, f.KodiID)
+	return err
+}
+
+// ItemArt is a locally stored piece of library artwork other than the
+// poster, e.g. the fanart of a movie.
+type ItemArt struct {
+	KodiID  int
+	ArtType string // "fanart", "banner" or "clearlogo"
+	URL     string // /api/posters/... URL
+}
+
+// ReplaceItemArt records the artwork a sync of mediaType on listID's host
+// stored, replacing the previous sync's for the synced items (nil for all)
+// as ReplacePosterFailures does.
+func (db *DB) ReplaceItemArt(listID int64, mediaType string, synced []int, art []ItemArt) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var host string
+	if err := tx.QueryRow("SELECT effective_host FROM lists WHERE id = ?", listID).Scan(&host); err != nil {
+		return err
+	}
+	if err := clearSynced(tx, "item_art", host, mediaType, synced); err != nil {
+		return err
+	}
+	for _, a := range art {
+		_, err := tx.Exec(`
+			INSERT OR REPLACE INTO item_art (kodi_host, media_type, kodi_id, art_type, url)
+			VALUES (?, ?, ?, ?, ?)`, host, mediaType, a.KodiID, a.ArtType, a.URL)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// clearSynced deletes the rows of table recorded for the synced items of
+// mediaType on host, or every one of them when synced is nil.
+func clearSynced(tx *sql.Tx, table, host, mediaType string, synced []int) error {
+	if synced == nil {
+		_, err := tx.Exec("DELETE FROM "+table+" WHERE kodi_host = ? AND media_type = ?", host, mediaType)
+		return err
+	}
+	for ids := range slices.Chunk(synced, 500) {
+		args := []interface{}{host, mediaType}
+		for _, id := range ids {
+			args = append(args, id)
+		}
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE kodi_host = ? AND media_type = ? AND kodi_id IN ("+placeholders(len(ids))+")", args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attachArt fills in the stored artwork of the items on listID.
+func (db *DB) attachArt(listID int64, items []Item) error {
+	rows, err := db.Query(`
+		SELECT i.id, a.art_type, a.url
+		FROM items i
+		JOIN lists l ON l.id = i.list_id
+		JOIN item_art a ON a.kodi_host = l.effective_host AND a.media_type = i.media_type AND a.kodi_id = i.kodi_id
+		WHERE i.list_id = ?`, listID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	art := map[int64]map[string]string{}
+	for rows.Next() {
+		var id int64
+		var artType, url string
+		if err := rows.Scan(&id, &artType, &url); err != nil {
+			return err
+		}
+		if art[id] == nil {
+			art[id] = map[string]string{}
+		}
+		art[id][artType] = url
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for i := range items {
+		items[i].Art = art[items[i].ID]
+	}
+	return nil
+}
+
+// UpdateCachedArt records new artwork for one library item on listID's
+// host after Kodi changed it: the art URIs and poster of its cache row, and
+// the poster of list items that had none. Items keep their poster otherwise,
+// since the file it names was replaced in place.
+func (db *DB) UpdateCachedArt(listID int64, mediaType string, kodiID int, poster string, art map[string]string) error {
+	encoded := ""
+	if len(art) > 0 {
+		b, err := json.Marshal(art)
+		if err != nil {
+			return err
+		}
+		encoded = string(b)
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	hostLists := "SELECT l.id FROM lists l JOIN lists cur ON cur.id = ? WHERE l.effective_host = cur.effective_host"
+	if _, err := tx.Exec(`
+		UPDATE library_cache SET poster_path = ?, art = ?
+		WHERE kodi_id = ? AND media_type = ? AND list_id IN (`+hostLists+`)`,
+		poster, encoded, kodiID, mediaType, listID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		UPDATE items SET poster_path = ?
+		WHERE kodi_id = ? AND media_type = ? AND poster_path = '' AND list_id IN (`+hostLists+`)`,
+		poster, kodiID, mediaType, listID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
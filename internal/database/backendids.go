@@ -0,0 +1,30 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// BackendLocalID returns the integer ID standing in for a media server's
+// string item ID, such as a Jellyfin GUID, giving it one on first use. Lists,
+// caches and search results key items by these like they do Kodi IDs.
+func (db *DB) BackendLocalID(host, externalID string) (int, error) {
+	var id int
+	err := db.QueryRow(`
+		INSERT INTO backend_ids (host, external_id) VALUES (?, ?)
+		ON CONFLICT(host, external_id) DO UPDATE SET external_id = excluded.external_id
+		RETURNING id`, host, externalID).Scan(&id)
+	return id, err
+}
+
+// BackendExternalID returns the media server item ID that BackendLocalID
+// gave localID on host.
+func (db *DB) BackendExternalID(host string, localID int) (string, error) {
+	var externalID string
+	err := db.QueryRow("SELECT external_id FROM backend_ids WHERE host = ? AND id = ?", host, localID).Scan(&externalID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("unknown item %d on %s: %w", localID, host, ErrItemNotFound)
+	}
+	return externalID, err
+}
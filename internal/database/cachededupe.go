@@ -0,0 +1,110 @@
+package database
+
+import (
+	"fmt"
+)
+
+// mergedCacheColumns are the library_cache columns a merge fills in on the
+// surviving row when it is empty and an older duplicate has a value.
+var mergedCacheColumns = []string{
+	"poster_path", "plot", "art", "imdb_id", "tmdb_id", "audio_languages",
+	"subtitle_languages", "genres", "resolution", "hdr_type", "set_title", "cast_names", "artist",
+}
+
+// CacheDedupeReport summarizes a DedupeLibraryCache run.
+type CacheDedupeReport struct {
+	// Duplicates is how many host items had more than one cache row, and
+	// Removed how many rows were merged away.
+	Duplicates int   `json:"duplicates"`
+	Removed    int64 `json:"removed"`
+	// Orphans are rows of lists that no longer exist.
+	Orphans int64 `json:"orphans"`
+	DryRun  bool  `json:"dry_run"`
+}
+
+// cacheDuplicate is one host item cached under more than one list.
+type cacheDuplicate struct {
+	host, mediaType string
+	kodiID          int
+	keep            int64
+}
+
+// DedupeLibraryCache merges library cache rows left by versions that kept a
+// cache per list: the cache is shared by every list on a Kodi host, so each
+// host item should have one row. The most recently written row survives,
+// takes any metadata it lacks from the others and moves to the list that
+// last fully synced the host, whose prunes then cover it. Rows of deleted
+// lists are removed too.
+func (db *DB) DedupeLibraryCache(dryRun bool) (*CacheDedupeReport, error) {
+	report := &CacheDedupeReport{DryRun: dryRun}
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRow("SELECT COUNT(*) FROM library_cache WHERE list_id NOT IN (SELECT id FROM lists)").Scan(&report.Orphans); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(`
+		SELECT l.effective_host, lc.media_type, lc.kodi_id, MAX(lc.id), COUNT(*)
+		FROM library_cache lc
+		JOIN lists l ON l.id = lc.list_id
+		GROUP BY l.effective_host, lc.media_type, lc.kodi_id
+		HAVING COUNT(*) > 1`)
+	if err != nil {
+		return nil, err
+	}
+	var dups []cacheDuplicate
+	for rows.Next() {
+		var d cacheDuplicate
+		var n int64
+		if err := rows.Scan(&d.host, &d.mediaType, &d.kodiID, &d.keep, &n); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		dups = append(dups, d)
+		report.Removed += n - 1
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	report.Duplicates = len(dups)
+	if dryRun {
+		return report, nil
+	}
+
+	if _, err := tx.Exec("DELETE FROM library_cache WHERE list_id NOT IN (SELECT id FROM lists)"); err != nil {
+		return nil, err
+	}
+	// others selects the duplicates of a row, newest first
+	const others = `
+		FROM library_cache d JOIN lists dl ON dl.id = d.list_id
+		WHERE dl.effective_host = ? AND d.media_type = ? AND d.kodi_id = ? AND d.id != ?`
+	for _, d := range dups {
+		for _, col := range mergedCacheColumns {
+			_, err := tx.Exec(fmt.Sprintf(`
+				UPDATE library_cache SET %[1]s = (SELECT d.%[1]s `+others+` AND COALESCE(d.%[1]s, '') != '' ORDER BY d.id DESC LIMIT 1)
+				WHERE id = ? AND COALESCE(%[1]s, '') = ''
+				AND EXISTS (SELECT 1 `+others+` AND COALESCE(d.%[1]s, '') != '')`, col),
+				d.host, d.mediaType, d.kodiID, d.keep, d.keep, d.host, d.mediaType, d.kodiID, d.keep)
+			if err != nil {
+				return nil, fmt.Errorf("failed to merge %s of %s %d: %w", col, d.mediaType, d.kodiID, err)
+			}
+		}
+		if _, err := tx.Exec("DELETE FROM library_cache WHERE id IN (SELECT d.id "+others+")", d.host, d.mediaType, d.kodiID, d.keep); err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec(`
+			UPDATE library_cache SET list_id = COALESCE((
+				SELECT ss.list_id FROM sync_state ss JOIN lists l ON l.id = ss.list_id
+				WHERE l.effective_host = ? AND ss.media_type = ?
+				ORDER BY ss.full_sync_at DESC LIMIT 1), list_id)
+			WHERE id = ?`, d.host, d.mediaType, d.keep); err != nil {
+			return nil, err
+		}
+	}
+	return report, tx.Commit()
+}
@@ -0,0 +1,84 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// CopyItems adds items of list fromID to list toID after its own, in order,
+// in one transaction. It returns the ID each item got, zero for those toID
+// already held, and toID's new revision. A wanted item counts as held when
+// toID wants the same title. Items keep their section: one of the same name
+// on toID, created if it has none.
+func (db *DB) CopyItems(fromID, toID int64, items []Item) ([]int64, int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+	if err := checkRevision(tx, toID, nil); err != nil {
+		return nil, 0, err
+	}
+
+	var next int
+	if err := tx.QueryRow("SELECT COALESCE(MAX(sort_order), -1) + 1 FROM items WHERE list_id = ?", toID).Scan(&next); err != nil {
+		return nil, 0, err
+	}
+	sections := map[int64]int64{}
+	ids := make([]int64, len(items))
+	for n, i := range items {
+		if i.SectionID != 0 {
+			if _, ok := sections[i.SectionID]; !ok {
+				if sections[i.SectionID], err = copySection(tx, fromID, toID, i.SectionID); err != nil {
+					return nil, 0, err
+				}
+			}
+			i.SectionID = sections[i.SectionID]
+		}
+		if i.Wanted {
+			var held bool
+			err := tx.QueryRow(`
+				SELECT EXISTS(SELECT 1 FROM items WHERE list_id = ? AND wanted = 1
+					AND media_type = ? AND lower(title) = lower(?) AND year = ?)`,
+				toID, i.MediaType, i.Title, i.Year).Scan(&held)
+			if err != nil {
+				return nil, 0, err
+			} else if held {
+				continue
+			}
+		}
+		i.ListID, i.SortOrder = toID, next
+		id, err := insertItem(tx, i)
+		if errors.Is(err, ErrDuplicateItem) {
+			continue
+		} else if err != nil {
+			return nil, 0, err
+		}
+		ids[n] = id
+		next++
+	}
+	rev, err := commitRevision(tx, toID)
+	return ids, rev, err
+}
+
+// copySection returns the ID of the section on list toID named like section
+// id of list fromID, adding it after toID's sections if needed. It returns
+// zero if fromID has no such section.
+func copySection(tx *sql.Tx, fromID, toID, id int64) (int64, error) {
+	var name string
+	err := tx.QueryRow("SELECT name FROM sections WHERE id = ? AND list_id = ?", id, fromID).Scan(&name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(`
+		INSERT OR IGNORE INTO sections (list_id, name, sort_order)
+		SELECT ?, ?, COALESCE(MAX(sort_order), -1) + 1 FROM sections WHERE list_id = ?`,
+		toID, name, toID); err != nil {
+		return 0, err
+	}
+	var copied int64
+	err = tx.QueryRow("SELECT id FROM sections WHERE list_id = ? AND name = ?", toID, name).Scan(&copied)
+	return copied, err
+}
@@ -0,0 +1,108 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// DashboardList is a list as the dashboard shows it to one user: whether
+// they pinned it or marked it a favourite, and how many items it holds and
+// how many of those haven't been watched.
+type DashboardList struct {
+	ID          int64  `json:"id"`
+	GroupName   string `json:"group_name"`
+	Name        string `json:"list_name"`
+	ContentType string `json:"content_type"`
+	Pinned      bool   `json:"pinned"`
+	Favorite    bool   `json:"favorite"`
+	// PinnedAt is when the list was pinned; pins are shown oldest first.
+	PinnedAt  string `json:"pinned_at,omitempty"`
+	ItemCount int    `json:"item_count"`
+	Unwatched int    `json:"unwatched_count"`
+}
+
+// Dashboard returns every list but archived ones in user's order: pinned
+// lists first, in the order they were pinned, then favourites, then the rest
+// by group, in the groups' display order. An empty user is the shared
+// household view.
+func (db *DB) Dashboard(user string) ([]DashboardList, error) {
+	rows, err := db.Query(`
+		SELECT l.id, l.group_name, l.name, l.content_type,
+			COALESCE(p.pinned, 0), COALESCE(p.favorite, 0), COALESCE(p.pinned_at, ''),
+			COUNT(i.id), COUNT(CASE WHEN i.watched = 0 AND i.playcount = 0 AND i.wanted = 0
+				AND NOT (i.episode_count > 0 AND i.watched_episodes >= i.episode_count) THEN 1 END)
+		FROM lists l
+		LEFT JOIN list_pins p ON p.list_id = l.id AND p.user = ?
+		LEFT JOIN items i ON i.list_id = l.id AND i.archived_at = ''
+		LEFT JOIN groups g ON g.name = l.group_name
+		WHERE l.archived_at = ''
+		GROUP BY l.id
+		ORDER BY COALESCE(p.pinned, 0) DESC, CASE WHEN p.pinned THEN p.pinned_at END ASC,
+			COALESCE(p.favorite, 0) DESC, COALESCE(g.sort_order, 0), l.group_name COLLATE NOCASE, l.id`, user)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lists := []DashboardList{}
+	for rows.Next() {
+		var l DashboardList
+		if err := rows.Scan(&l.ID, &l.GroupName, &l.Name, &l.ContentType, &l.Pinned, &l.Favorite, &l.PinnedAt, &l.ItemCount, &l.Unwatched); err != nil {
+			return nil, err
+		}
+		lists = append(lists, l)
+	}
+	return lists, rows.Err()
+}
+
+// SetListPin updates user's pinned and favourite flags for a list; a nil
+// flag is left as it was. Pinning a list that was already pinned keeps its
+// place. It returns ErrListNotFound for unknown lists.
+func (db *DB) SetListPin(user string, listID int64, pinned, favorite *bool) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var one int
+	if err := tx.QueryRow("SELECT 1 FROM lists WHERE id = ?", listID).Scan(&one); errors.Is(err, sql.ErrNoRows) {
+		return ErrListNotFound
+	} else if err != nil {
+		return err
+	}
+	var wasPinned, wasFavorite bool
+	var pinnedAt string
+	err = tx.QueryRow("SELECT pinned, favorite, pinned_at FROM list_pins WHERE user = ? AND list_id = ?", user, listID).Scan(&wasPinned, &wasFavorite, &pinnedAt)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	isPinned, isFavorite := wasPinned, wasFavorite
+	if pinned != nil {
+		isPinned = *pinned
+	}
+	if favorite != nil {
+		isFavorite = *favorite
+	}
+	switch {
+	case !isPinned:
+		pinnedAt = ""
+	case !wasPinned:
+		pinnedAt = time.Now().UTC().Format(time.RFC3339Nano)
+	}
+
+	if !isPinned && !isFavorite {
+		_, err = tx.Exec("DELETE FROM list_pins WHERE user = ? AND list_id = ?", user, listID)
+	} else {
+		_, err = tx.Exec(`
+			INSERT INTO list_pins (user, list_id, pinned, favorite, pinned_at) VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT(user, list_id) DO UPDATE SET
+				pinned = excluded.pinned, favorite = excluded.favorite, pinned_at = excluded.pinned_at`,
+			user, listID, isPinned, isFavorite, pinnedAt)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"fmt"
 	"log/slog"
+	"strings"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -13,6 +14,12 @@ type DB struct {
 }
 
 func InitDB(dataSourceName string) (*DB, error) {
+	// Replicas may share the database file: wait for locks instead of
+	// failing, and take the write lock when a transaction begins so
+	// concurrent writers serialize cleanly.
+	if !strings.Contains(dataSourceName, "?") {
+		dataSourceName += "?_busy_timeout=5000&_txlock=immediate"
+	}
 	db, err := sql.Open("sqlite3", dataSourceName)
 	if err != nil {
 		return nil, err
@@ -158,6 +165,782 @@ func migrate(db *sql.DB) error {
 			}
 			return nil
 		},
+		// Migration 4: Audio/subtitle language availability
+		func(tx *sql.Tx) error {
+			queries := []string{
+				"ALTER TABLE items ADD COLUMN audio_languages TEXT DEFAULT ''",
+				"ALTER TABLE items ADD COLUMN subtitle_languages TEXT DEFAULT ''",
+				"ALTER TABLE library_cache ADD COLUMN audio_languages TEXT DEFAULT ''",
+				"ALTER TABLE library_cache ADD COLUMN subtitle_languages TEXT DEFAULT ''",
+			}
+			for _, q := range queries {
+				if _, err := tx.Exec(q); err != nil {
+					return fmt.Errorf("failed to add language column: %w", err)
+				}
+			}
+			return nil
+		},
+		// Migration 5: Pending import matches and notifications
+		func(tx *sql.Tx) error {
+			queries := []string{
+				`CREATE TABLE IF NOT EXISTS pending_matches (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					list_id INTEGER NOT NULL,
+					source TEXT NOT NULL, -- imdb, letterboxd, csv
+					title TEXT NOT NULL,
+					year INTEGER DEFAULT 0,
+					external_id TEXT DEFAULT '',
+					created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+					last_attempt_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+					FOREIGN KEY(list_id) REFERENCES lists(id),
+					UNIQUE(list_id, title, year)
+				);`,
+				`CREATE TABLE IF NOT EXISTS notifications (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					kind TEXT NOT NULL,
+					list_id INTEGER,
+					message TEXT NOT NULL,
+					created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+					read_at DATETIME
+				);`,
+			}
+			for _, q := range queries {
+				if _, err := tx.Exec(q); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		// Migration 6: Wanted (not yet in library) items
+		func(tx *sql.Tx) error {
+			if _, err := tx.Exec("ALTER TABLE items ADD COLUMN wanted INTEGER DEFAULT 0"); err != nil {
+				return fmt.Errorf("failed to add wanted column: %w", err)
+			}
+			return nil
+		},
+		// Migration 7: Per-group Kodi host definitions
+		func(tx *sql.Tx) error {
+			queries := []string{
+				`CREATE TABLE IF NOT EXISTS groups (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					name TEXT NOT NULL UNIQUE,
+					kodi_host TEXT DEFAULT '',
+					username TEXT DEFAULT '',
+					password TEXT DEFAULT ''
+				);`,
+				// Existing lists keep their own host as an explicit override
+				"ALTER TABLE lists ADD COLUMN inherits_host INTEGER DEFAULT 0",
+				// The host a list resolves to, its own or its group's, kept
+				// by every write to either so host lookups stay indexed
+				"ALTER TABLE lists ADD COLUMN effective_host TEXT DEFAULT ''",
+				"UPDATE lists SET effective_host = kodi_host",
+				"DROP INDEX IF EXISTS idx_lists_kodi_host",
+				"CREATE INDEX IF NOT EXISTS idx_lists_effective_host ON lists(effective_host)",
+				"INSERT OR IGNORE INTO groups (name) SELECT DISTINCT group_name FROM lists",
+				// Inheriting lists read their group's credentials; l.* picks
+				// up columns added to lists later
+				`CREATE VIEW IF NOT EXISTS resolved_lists AS SELECT l.*,
+					CASE WHEN l.inherits_host THEN COALESCE(g.username, '') ELSE l.username END AS resolved_username,
+					CASE WHEN l.inherits_host THEN COALESCE(g.password, '') ELSE l.password END AS resolved_password
+				FROM lists l LEFT JOIN groups g ON g.name = l.group_name`,
+			}
+			for _, q := range queries {
+				if _, err := tx.Exec(q); err != nil {
+					return fmt.Errorf("failed to create groups: %w", err)
+				}
+			}
+			return nil
+		},
+		// Migration 8: Track credentials changed through the API
+		func(tx *sql.Tx) error {
+			queries := []string{
+				"ALTER TABLE lists ADD COLUMN credentials_override INTEGER DEFAULT 0",
+				"ALTER TABLE lists ADD COLUMN config_credentials TEXT DEFAULT ''",
+			}
+			for _, q := range queries {
+				if _, err := tx.Exec(q); err != nil {
+					return fmt.Errorf("failed to add credentials columns: %w", err)
+				}
+			}
+			return nil
+		},
+		// Migration 9: Keep the Kodi artwork when a custom poster is uploaded
+		func(tx *sql.Tx) error {
+			if _, err := tx.Exec("ALTER TABLE items ADD COLUMN original_poster_path TEXT DEFAULT ''"); err != nil {
+				return fmt.Errorf("failed to add original_poster_path column: %w", err)
+			}
+			return nil
+		},
+		// Migration 10: Normalize runtimes stored in minutes to seconds
+		func(tx *sql.Tx) error {
+			// Mirrors kodi.normalizeRuntime: nothing under 300 can be a
+			// feature length in seconds. Only the cache is guessed at, since
+			// the next sync rewrites it from stream details; items take the
+			// synced runtime from there (SyncItemMetadata), so real shorts
+			// already in seconds are never scaled for good.
+			if _, err := tx.Exec("UPDATE library_cache SET runtime = runtime * 60 WHERE runtime > 0 AND runtime < 300"); err != nil {
+				return fmt.Errorf("failed to normalize runtimes: %w", err)
+			}
+			return nil
+		},
+		// Migration 11: Watched episode counters for shows
+		func(tx *sql.Tx) error {
+			queries := []string{
+				"ALTER TABLE items ADD COLUMN watched_episodes INTEGER DEFAULT 0",
+				"ALTER TABLE library_cache ADD COLUMN watched_episodes INTEGER DEFAULT 0",
+			}
+			for _, q := range queries {
+				if _, err := tx.Exec(q); err != nil {
+					return fmt.Errorf("failed to add watched_episodes column: %w", err)
+				}
+			}
+			return nil
+		},
+		// Migration 12: Leases so only one replica runs each background job
+		func(tx *sql.Tx) error {
+			_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS job_leases (
+				name TEXT PRIMARY KEY,
+				holder TEXT NOT NULL,
+				expires_at INTEGER NOT NULL
+			)`)
+			if err != nil {
+				return fmt.Errorf("failed to create job_leases table: %w", err)
+			}
+			return nil
+		},
+		// Migration 13: Key/value application state (e.g. one-off seeding)
+		func(tx *sql.Tx) error {
+			_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS app_state (
+				key TEXT PRIMARY KEY,
+				value TEXT NOT NULL
+			)`)
+			if err != nil {
+				return fmt.Errorf("failed to create app_state table: %w", err)
+			}
+			return nil
+		},
+		// Migration 14: Genres for browsing the library cache
+		func(tx *sql.Tx) error {
+			if _, err := tx.Exec("ALTER TABLE library_cache ADD COLUMN genres TEXT DEFAULT ''"); err != nil {
+				return fmt.Errorf("failed to add genres column: %w", err)
+			}
+			return nil
+		},
+		// Migration 15: Watched flag on list items
+		func(tx *sql.Tx) error {
+			if _, err := tx.Exec("ALTER TABLE items ADD COLUMN watched INTEGER DEFAULT 0"); err != nil {
+				return fmt.Errorf("failed to add watched column: %w", err)
+			}
+			return nil
+		},
+		// Migration 16: Playback state (playcount, last played, resume point)
+		func(tx *sql.Tx) error {
+			for _, table := range []string{"items", "library_cache"} {
+				queries := []string{
+					"ALTER TABLE " + table + " ADD COLUMN playcount INTEGER DEFAULT 0",
+					"ALTER TABLE " + table + " ADD COLUMN last_played TEXT DEFAULT ''",
+					"ALTER TABLE " + table + " ADD COLUMN resume_position INTEGER DEFAULT 0",
+				}
+				for _, q := range queries {
+					if _, err := tx.Exec(q); err != nil {
+						return fmt.Errorf("failed to add playback columns to %s: %w", table, err)
+					}
+				}
+			}
+			return nil
+		},
+		// Migration 17: Named search filter combinations per list
+		func(tx *sql.Tx) error {
+			_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS saved_searches (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				list_id INTEGER NOT NULL,
+				name TEXT NOT NULL,
+				params TEXT NOT NULL DEFAULT '{}', -- JSON object of search query parameters
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY(list_id) REFERENCES lists(id),
+				UNIQUE(list_id, name)
+			)`)
+			if err != nil {
+				return fmt.Errorf("failed to create saved_searches table: %w", err)
+			}
+			return nil
+		},
+		// Migration 18: Artwork that failed to download during a sync
+		func(tx *sql.Tx) error {
+			_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS poster_failures (
+				kodi_host TEXT NOT NULL,
+				media_type TEXT NOT NULL,
+				kodi_id INTEGER NOT NULL,
+				title TEXT NOT NULL,
+				year INTEGER DEFAULT 0,
+				image_uri TEXT NOT NULL,
+				error TEXT DEFAULT '',
+				attempts INTEGER DEFAULT 1,
+				failed_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (kodi_host, media_type, kodi_id)
+			)`)
+			if err != nil {
+				return fmt.Errorf("failed to create poster_failures table: %w", err)
+			}
+			return nil
+		},
+		// Migration 19: Per-list handling of watched items and the item archive
+		func(tx *sql.Tx) error {
+			queries := []string{
+				"ALTER TABLE lists ADD COLUMN on_watched TEXT DEFAULT ''",
+				"ALTER TABLE items ADD COLUMN archived_at TEXT DEFAULT ''",
+				// Restored items stay put until they are watched again
+				"ALTER TABLE items ADD COLUMN keep_watched INTEGER DEFAULT 0",
+			}
+			for _, q := range queries {
+				if _, err := tx.Exec(q); err != nil {
+					return fmt.Errorf("failed to add watched policy columns: %w", err)
+				}
+			}
+			return nil
+		},
+		// Migration 20: Per-list art preference and cached art URIs
+		func(tx *sql.Tx) error {
+			if _, err := tx.Exec("ALTER TABLE lists ADD COLUMN art_preference TEXT DEFAULT ''"); err != nil {
+				return fmt.Errorf("failed to add art_preference column: %w", err)
+			}
+			if _, err := tx.Exec("ALTER TABLE library_cache ADD COLUMN art TEXT DEFAULT ''"); err != nil {
+				return fmt.Errorf("failed to add art column: %w", err)
+			}
+			return nil
+		},
+		// Migration 21: Sections dividing a list
+		func(tx *sql.Tx) error {
+			_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS sections (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				list_id INTEGER NOT NULL,
+				name TEXT NOT NULL,
+				sort_order INTEGER DEFAULT 0,
+				UNIQUE(list_id, name)
+			)`)
+			if err != nil {
+				return fmt.Errorf("failed to create sections table: %w", err)
+			}
+			if _, err := tx.Exec("ALTER TABLE items ADD COLUMN section_id INTEGER"); err != nil {
+				return fmt.Errorf("failed to add section_id column: %w", err)
+			}
+			return nil
+		},
+		// Migration 22: Items whose media was removed from Kodi
+		func(tx *sql.Tx) error {
+			if _, err := tx.Exec("ALTER TABLE items ADD COLUMN missing_since TEXT DEFAULT ''"); err != nil {
+				return fmt.Errorf("failed to add missing_since column: %w", err)
+			}
+			return nil
+		},
+		// Migration 23: Feed subscriptions and the entries already seen
+		func(tx *sql.Tx) error {
+			stmts := []string{
+				`CREATE TABLE IF NOT EXISTS feeds (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					list_id INTEGER NOT NULL,
+					url TEXT NOT NULL,
+					title TEXT DEFAULT '',
+					last_checked_at TEXT DEFAULT '',
+					last_error TEXT DEFAULT '',
+					created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+					UNIQUE(list_id, url)
+				)`,
+				`CREATE TABLE IF NOT EXISTS feed_entries (
+					feed_id INTEGER NOT NULL,
+					guid TEXT NOT NULL,
+					seen_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+					PRIMARY KEY(feed_id, guid)
+				)`,
+			}
+			for _, stmt := range stmts {
+				if _, err := tx.Exec(stmt); err != nil {
+					return fmt.Errorf("failed to create feed tables: %w", err)
+				}
+			}
+			return nil
+		},
+		// Migration 24: List revisions, bumped by triggers whenever items are
+		// added, removed, reordered, moved between sections or archived
+		func(tx *sql.Tx) error {
+			stmts := []string{
+				"ALTER TABLE lists ADD COLUMN revision INTEGER DEFAULT 0",
+				`CREATE TRIGGER IF NOT EXISTS items_revision_insert AFTER INSERT ON items BEGIN
+					UPDATE lists SET revision = revision + 1 WHERE id = NEW.list_id;
+				END`,
+				`CREATE TRIGGER IF NOT EXISTS items_revision_delete AFTER DELETE ON items BEGIN
+					UPDATE lists SET revision = revision + 1 WHERE id = OLD.list_id;
+				END`,
+				`CREATE TRIGGER IF NOT EXISTS items_revision_update AFTER UPDATE OF sort_order, section_id, archived_at ON items
+				WHEN OLD.sort_order IS NOT NEW.sort_order OR OLD.section_id IS NOT NEW.section_id OR OLD.archived_at IS NOT NEW.archived_at
+				BEGIN
+					UPDATE lists SET revision = revision + 1 WHERE id = NEW.list_id;
+				END`,
+			}
+			for _, stmt := range stmts {
+				if _, err := tx.Exec(stmt); err != nil {
+					return fmt.Errorf("failed to add list revisions: %w", err)
+				}
+			}
+			return nil
+		},
+		// Migration 25: External IDs (IMDb, TMDB) reported by Kodi's scrapers
+		func(tx *sql.Tx) error {
+			for _, table := range []string{"items", "library_cache"} {
+				queries := []string{
+					"ALTER TABLE " + table + " ADD COLUMN imdb_id TEXT DEFAULT ''",
+					"ALTER TABLE " + table + " ADD COLUMN tmdb_id TEXT DEFAULT ''",
+				}
+				for _, q := range queries {
+					if _, err := tx.Exec(q); err != nil {
+						return fmt.Errorf("failed to add external ID columns to %s: %w", table, err)
+					}
+				}
+			}
+			return nil
+		},
+		// Migration 26: Viewing history pulled from Kodi, one row per play
+		func(tx *sql.Tx) error {
+			stmts := []string{
+				`CREATE TABLE IF NOT EXISTS viewing_history (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					kodi_host TEXT NOT NULL,
+					media_type TEXT NOT NULL,
+					kodi_id INTEGER NOT NULL,
+					tvshow_id INTEGER DEFAULT 0,
+					title TEXT NOT NULL,
+					show_title TEXT DEFAULT '',
+					season INTEGER DEFAULT 0,
+					episode INTEGER DEFAULT 0,
+					year INTEGER DEFAULT 0,
+					runtime INTEGER DEFAULT 0,
+					played_at TEXT NOT NULL,
+					playcount INTEGER DEFAULT 0,
+					resume_position INTEGER DEFAULT 0,
+					completed INTEGER DEFAULT 0,
+					UNIQUE(kodi_host, media_type, kodi_id, played_at)
+				)`,
+				"CREATE INDEX IF NOT EXISTS idx_viewing_history_played ON viewing_history(kodi_host, played_at)",
+			}
+			for _, stmt := range stmts {
+				if _, err := tx.Exec(stmt); err != nil {
+					return fmt.Errorf("failed to create viewing_history table: %w", err)
+				}
+			}
+			return nil
+		},
+		// Migration 27: Video resolution and HDR type from stream details
+		func(tx *sql.Tx) error {
+			for _, table := range []string{"items", "library_cache"} {
+				queries := []string{
+					"ALTER TABLE " + table + " ADD COLUMN resolution INTEGER DEFAULT 0",
+					"ALTER TABLE " + table + " ADD COLUMN hdr_type TEXT DEFAULT ''",
+				}
+				for _, q := range queries {
+					if _, err := tx.Exec(q); err != nil {
+						return fmt.Errorf("failed to add video quality columns to %s: %w", table, err)
+					}
+				}
+			}
+			return nil
+		},
+		// Migration 28: Locally stored artwork beyond the poster, per library item
+		func(tx *sql.Tx) error {
+			_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS item_art (
+				kodi_host TEXT NOT NULL,
+				media_type TEXT NOT NULL,
+				kodi_id INTEGER NOT NULL,
+				art_type TEXT NOT NULL,
+				url TEXT NOT NULL,
+				PRIMARY KEY(kodi_host, media_type, kodi_id, art_type)
+			)`)
+			if err != nil {
+				return fmt.Errorf("failed to create item_art table: %w", err)
+			}
+			return nil
+		},
+		// Migration 29: Season and episode listings kept per show for offline drill-down
+		func(tx *sql.Tx) error {
+			_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS show_cache (
+				kodi_host TEXT NOT NULL,
+				tvshowid INTEGER NOT NULL,
+				season INTEGER NOT NULL,
+				data TEXT NOT NULL,
+				fetched_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY(kodi_host, tvshowid, season)
+			)`)
+			if err != nil {
+				return fmt.Errorf("failed to create show_cache table: %w", err)
+			}
+			return nil
+		},
+		// Migration 30: Episode number and show of episode items
+		func(tx *sql.Tx) error {
+			queries := []string{
+				"ALTER TABLE items ADD COLUMN episode INTEGER DEFAULT 0",
+				"ALTER TABLE items ADD COLUMN tvshow_id INTEGER DEFAULT 0",
+				"ALTER TABLE items ADD COLUMN show_title TEXT DEFAULT ''",
+			}
+			for _, q := range queries {
+				if _, err := tx.Exec(q); err != nil {
+					return fmt.Errorf("failed to add episode columns: %w", err)
+				}
+			}
+			return nil
+		},
+		// Migration 31: Movie set (franchise) of cached movies
+		func(tx *sql.Tx) error {
+			queries := []string{
+				"ALTER TABLE library_cache ADD COLUMN set_id INTEGER DEFAULT 0",
+				"ALTER TABLE library_cache ADD COLUMN set_title TEXT DEFAULT ''",
+			}
+			for _, q := range queries {
+				if _, err := tx.Exec(q); err != nil {
+					return fmt.Errorf("failed to add movie set columns: %w", err)
+				}
+			}
+			return nil
+		},
+		// Migration 32: Hourly request and sync counters
+		func(tx *sql.Tx) error {
+			_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS metrics (
+				hour INTEGER NOT NULL,
+				name TEXT NOT NULL,
+				value INTEGER NOT NULL DEFAULT 0,
+				PRIMARY KEY(hour, name)
+			)`)
+			if err != nil {
+				return fmt.Errorf("failed to create metrics table: %w", err)
+			}
+			return nil
+		},
+		// Migration 33: Genre lists fetched alongside each library sync
+		func(tx *sql.Tx) error {
+			_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS genre_cache (
+				kodi_host TEXT NOT NULL,
+				media_type TEXT NOT NULL,
+				data TEXT NOT NULL,
+				fetched_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY(kodi_host, media_type)
+			)`)
+			if err != nil {
+				return fmt.Errorf("failed to create genre_cache table: %w", err)
+			}
+			return nil
+		},
+		// Migration 34: Top-billed cast of cached items, for suggestions
+		func(tx *sql.Tx) error {
+			if _, err := tx.Exec("ALTER TABLE library_cache ADD COLUMN cast_names TEXT DEFAULT ''"); err != nil {
+				return fmt.Errorf("failed to add cast column: %w", err)
+			}
+			return nil
+		},
+		// Migration 35: Progress of each list's library syncs, for
+		// incremental syncs
+		func(tx *sql.Tx) error {
+			_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS sync_state (
+				list_id INTEGER NOT NULL,
+				media_type TEXT NOT NULL,
+				kodi_host TEXT NOT NULL,
+				added_through TEXT NOT NULL DEFAULT '',
+				full_sync_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY(list_id, media_type),
+				FOREIGN KEY(list_id) REFERENCES lists(id)
+			)`)
+			if err != nil {
+				return fmt.Errorf("failed to create sync_state table: %w", err)
+			}
+			return nil
+		},
+		// Migration 36: Per-user notification preferences
+		func(tx *sql.Tx) error {
+			_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS notification_prefs (
+				user TEXT PRIMARY KEY COLLATE NOCASE,
+				muted_kinds TEXT DEFAULT '',
+				muted_channels TEXT DEFAULT '',
+				quiet_start TEXT DEFAULT '',
+				quiet_end TEXT DEFAULT '',
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			)`)
+			if err != nil {
+				return fmt.Errorf("failed to create notification_prefs table: %w", err)
+			}
+			return nil
+		},
+		// Migration 37: TLS options for Kodi hosts behind an HTTPS proxy
+		func(tx *sql.Tx) error {
+			for _, stmt := range []string{
+				"ALTER TABLE lists ADD COLUMN tls_skip_verify BOOLEAN DEFAULT 0",
+				"ALTER TABLE lists ADD COLUMN ca_cert TEXT DEFAULT ''",
+			} {
+				if _, err := tx.Exec(stmt); err != nil {
+					return fmt.Errorf("failed to add TLS columns: %w", err)
+				}
+			}
+			return nil
+		},
+		// Migration 38: Re-resolving list items after a list's Kodi host
+		// changes
+		func(tx *sql.Tx) error {
+			_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS host_migrations (
+				list_id INTEGER PRIMARY KEY,
+				from_host TEXT NOT NULL,
+				to_host TEXT NOT NULL,
+				status TEXT NOT NULL DEFAULT 'pending',
+				matched INTEGER DEFAULT 0,
+				unmatched TEXT DEFAULT '[]',
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				finished_at TEXT DEFAULT '',
+				FOREIGN KEY(list_id) REFERENCES lists(id)
+			)`)
+			if err != nil {
+				return fmt.Errorf("failed to create host_migrations table: %w", err)
+			}
+			return nil
+		},
+		// Migration 39: Per-list Kodi timeouts and sync concurrency
+		func(tx *sql.Tx) error {
+			for _, stmt := range []string{
+				"ALTER TABLE lists ADD COLUMN rpc_timeout TEXT DEFAULT ''",
+				"ALTER TABLE lists ADD COLUMN image_timeout TEXT DEFAULT ''",
+				"ALTER TABLE lists ADD COLUMN sync_concurrency INTEGER DEFAULT 0",
+			} {
+				if _, err := tx.Exec(stmt); err != nil {
+					return fmt.Errorf("failed to add connection tuning columns: %w", err)
+				}
+			}
+			return nil
+		},
+		// Migration 40: Files from Kodi sources that aren't in the library
+		func(tx *sql.Tx) error {
+			for _, stmt := range []string{
+				"ALTER TABLE items ADD COLUMN file_path TEXT DEFAULT ''",
+				"CREATE UNIQUE INDEX IF NOT EXISTS idx_items_file_path ON items(list_id, file_path) WHERE file_path != ''",
+			} {
+				if _, err := tx.Exec(stmt); err != nil {
+					return fmt.Errorf("failed to add file_path column: %w", err)
+				}
+			}
+			return nil
+		},
+		// Migration 41: Per-user pinned and favourite lists
+		func(tx *sql.Tx) error {
+			_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS list_pins (
+				user TEXT NOT NULL COLLATE NOCASE,
+				list_id INTEGER NOT NULL,
+				pinned BOOLEAN DEFAULT 0,
+				favorite BOOLEAN DEFAULT 0,
+				pinned_at TEXT DEFAULT '',
+				PRIMARY KEY(user, list_id),
+				FOREIGN KEY(list_id) REFERENCES lists(id)
+			)`)
+			if err != nil {
+				return fmt.Errorf("failed to create list_pins table: %w", err)
+			}
+			return nil
+		},
+		// Migration 42: Lists backed by media servers other than Kodi, and the
+		// local IDs given to those servers' string item IDs
+		func(tx *sql.Tx) error {
+			for _, stmt := range []string{
+				"ALTER TABLE lists ADD COLUMN backend TEXT DEFAULT 'kodi'",
+				`CREATE TABLE IF NOT EXISTS backend_ids (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					host TEXT NOT NULL,
+					external_id TEXT NOT NULL,
+					UNIQUE(host, external_id)
+				)`,
+			} {
+				if _, err := tx.Exec(stmt); err != nil {
+					return fmt.Errorf("failed to add list backends: %w", err)
+				}
+			}
+			return nil
+		},
+		// Migration 43: Replace the items UNIQUE(list_id, kodi_id, media_type,
+		// season) constraint with partial unique indexes. NULL seasons never
+		// matched it, so duplicates slipped in, while only seasons are told
+		// apart by season. SQLite can't drop a table constraint, so the table
+		// is rebuilt, then seasons are normalized and duplicates merged.
+		func(tx *sql.Tx) error {
+			const columns = `id, list_id, kodi_id, media_type, title, year, poster_path, runtime, episode_count, season, rating, sort_order, added_at,
+				audio_languages, subtitle_languages, wanted, original_poster_path, watched_episodes, watched, playcount, last_played, resume_position,
+				archived_at, keep_watched, section_id, missing_since, imdb_id, tmdb_id, resolution, hdr_type, episode, tvshow_id, show_title, file_path`
+			stmts := []string{
+				`CREATE TABLE items_new (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					list_id INTEGER NOT NULL,
+					kodi_id INTEGER,
+					media_type TEXT,
+					title TEXT,
+					year INTEGER,
+					poster_path TEXT,
+					runtime INTEGER,
+					episode_count INTEGER,
+					season INTEGER DEFAULT 0,
+					rating REAL,
+					sort_order INTEGER DEFAULT 0,
+					added_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+					audio_languages TEXT DEFAULT '',
+					subtitle_languages TEXT DEFAULT '',
+					wanted INTEGER DEFAULT 0,
+					original_poster_path TEXT DEFAULT '',
+					watched_episodes INTEGER DEFAULT 0,
+					watched INTEGER DEFAULT 0,
+					playcount INTEGER DEFAULT 0,
+					last_played TEXT DEFAULT '',
+					resume_position INTEGER DEFAULT 0,
+					archived_at TEXT DEFAULT '',
+					keep_watched INTEGER DEFAULT 0,
+					section_id INTEGER,
+					missing_since TEXT DEFAULT '',
+					imdb_id TEXT DEFAULT '',
+					tmdb_id TEXT DEFAULT '',
+					resolution INTEGER DEFAULT 0,
+					hdr_type TEXT DEFAULT '',
+					episode INTEGER DEFAULT 0,
+					tvshow_id INTEGER DEFAULT 0,
+					show_title TEXT DEFAULT '',
+					file_path TEXT DEFAULT '',
+					FOREIGN KEY(list_id) REFERENCES lists(id)
+				)`,
+				"INSERT INTO items_new (" + columns + ") SELECT " + columns + " FROM items",
+				"DROP TABLE items",
+				"ALTER TABLE items_new RENAME TO items",
+				"UPDATE items SET season = 0 WHERE season IS NULL OR media_type NOT IN ('season', 'episode')",
+				// Keep the oldest copy, preferring one still on the list
+				// over an archived one
+				`DELETE FROM items WHERE kodi_id IS NOT NULL AND id NOT IN (
+					SELECT (SELECT k.id FROM items k
+						WHERE k.list_id = d.list_id AND k.kodi_id = d.kodi_id AND k.media_type = d.media_type
+						AND (d.media_type != 'season' OR k.season = d.season)
+						ORDER BY k.archived_at != '', k.id LIMIT 1)
+					FROM items d WHERE d.kodi_id IS NOT NULL)`,
+				"CREATE UNIQUE INDEX IF NOT EXISTS idx_items_media ON items(list_id, media_type, kodi_id) WHERE kodi_id IS NOT NULL AND media_type != 'season'",
+				"CREATE UNIQUE INDEX IF NOT EXISTS idx_items_season ON items(list_id, kodi_id, season) WHERE kodi_id IS NOT NULL AND media_type = 'season'",
+				"CREATE UNIQUE INDEX IF NOT EXISTS idx_items_file_path ON items(list_id, file_path) WHERE file_path != ''",
+				`CREATE TRIGGER IF NOT EXISTS items_revision_insert AFTER INSERT ON items BEGIN
+					UPDATE lists SET revision = revision + 1 WHERE id = NEW.list_id;
+				END`,
+				`CREATE TRIGGER IF NOT EXISTS items_revision_delete AFTER DELETE ON items BEGIN
+					UPDATE lists SET revision = revision + 1 WHERE id = OLD.list_id;
+				END`,
+				`CREATE TRIGGER IF NOT EXISTS items_revision_update AFTER UPDATE OF sort_order, section_id, archived_at ON items
+				WHEN OLD.sort_order IS NOT NEW.sort_order OR OLD.section_id IS NOT NEW.section_id OR OLD.archived_at IS NOT NEW.archived_at
+				BEGIN
+					UPDATE lists SET revision = revision + 1 WHERE id = NEW.list_id;
+				END`,
+			}
+			for _, stmt := range stmts {
+				if _, err := tx.Exec(stmt); err != nil {
+					return fmt.Errorf("failed to rebuild items table: %w", err)
+				}
+			}
+			return nil
+		},
+		// Migration 44: When each list last synced, full or incremental
+		func(tx *sql.Tx) error {
+			for _, stmt := range []string{
+				"ALTER TABLE sync_state ADD COLUMN synced_at TEXT DEFAULT ''",
+				"UPDATE sync_state SET synced_at = strftime('%Y-%m-%dT%H:%M:%SZ', full_sync_at)",
+			} {
+				if _, err := tx.Exec(stmt); err != nil {
+					return fmt.Errorf("failed to add sync time: %w", err)
+				}
+			}
+			return nil
+		},
+		// Migration 45: Album artists, for music lists
+		func(tx *sql.Tx) error {
+			for _, table := range []string{"items", "library_cache"} {
+				if _, err := tx.Exec("ALTER TABLE " + table + " ADD COLUMN artist TEXT DEFAULT ''"); err != nil {
+					return fmt.Errorf("failed to add artist column to %s: %w", table, err)
+				}
+			}
+			return nil
+		},
+		// Migration 46: Daily counts of the endpoints and features used
+		func(tx *sql.Tx) error {
+			_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS usage_counts (
+				day INTEGER NOT NULL,
+				kind TEXT NOT NULL,
+				name TEXT NOT NULL,
+				count INTEGER NOT NULL DEFAULT 0,
+				PRIMARY KEY(day, kind, name)
+			)`)
+			if err != nil {
+				return fmt.Errorf("failed to create usage_counts table: %w", err)
+			}
+			return nil
+		},
+		// Migration 47: Per-list tuning of fuzzy search ranking
+		func(tx *sql.Tx) error {
+			if _, err := tx.Exec("ALTER TABLE lists ADD COLUMN fuzzy TEXT DEFAULT ''"); err != nil {
+				return fmt.Errorf("failed to add fuzzy column: %w", err)
+			}
+			return nil
+		},
+		// Migration 48: Track lists defined by config.json, so lists removed from it can be deleted
+		func(tx *sql.Tx) error {
+			if _, err := tx.Exec("ALTER TABLE lists ADD COLUMN from_config INTEGER DEFAULT 0"); err != nil {
+				return fmt.Errorf("failed to add from_config column: %w", err)
+			}
+			return nil
+		},
+		// Migration 49: Lists shared with and mirrored from other instances
+		func(tx *sql.Tx) error {
+			stmts := []string{
+				`CREATE TABLE IF NOT EXISTS list_shares (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					list_id INTEGER NOT NULL,
+					name TEXT NOT NULL DEFAULT '',
+					token_hash TEXT NOT NULL UNIQUE,
+					last_polled_at TEXT NOT NULL DEFAULT '',
+					created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+				)`,
+				`CREATE INDEX IF NOT EXISTS idx_list_shares_list ON list_shares(list_id)`,
+				`CREATE TABLE IF NOT EXISTS list_remotes (
+					list_id INTEGER PRIMARY KEY,
+					url TEXT NOT NULL,
+					token TEXT NOT NULL,
+					etag TEXT NOT NULL DEFAULT '',
+					last_polled_at TEXT NOT NULL DEFAULT '',
+					last_error TEXT NOT NULL DEFAULT '',
+					created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+				)`,
+			}
+			for _, stmt := range stmts {
+				if _, err := tx.Exec(stmt); err != nil {
+					return fmt.Errorf("failed to create federation tables: %w", err)
+				}
+			}
+			return nil
+		},
+		// Migration 50: Archived lists
+		func(tx *sql.Tx) error {
+			if _, err := tx.Exec("ALTER TABLE lists ADD COLUMN archived_at TEXT DEFAULT ''"); err != nil {
+				return fmt.Errorf("failed to add archived_at column: %w", err)
+			}
+			return nil
+		},
+		// Migration 51: Display order of groups, starting as the UI showed
+		// them: by their first list
+		func(tx *sql.Tx) error {
+			queries := []string{
+				"ALTER TABLE groups ADD COLUMN sort_order INTEGER DEFAULT 0",
+				"INSERT OR IGNORE INTO groups (name) SELECT DISTINCT group_name FROM lists",
+				`UPDATE groups SET sort_order = (
+					SELECT COUNT(*) FROM groups g
+					WHERE (COALESCE((SELECT MIN(id) FROM lists WHERE group_name = g.name), 9223372036854775807), g.id)
+						< (COALESCE((SELECT MIN(id) FROM lists WHERE group_name = groups.name), 9223372036854775807), groups.id))`,
+			}
+			for _, q := range queries {
+				if _, err := tx.Exec(q); err != nil {
+					return fmt.Errorf("failed to order groups: %w", err)
+				}
+			}
+			return nil
+		},
 	}
 
 	// 5. Apply migrations
@@ -168,6 +951,18 @@ func migrate(db *sql.DB) error {
 			return err
 		}
 
+		// Another replica may have applied this migration while we
+		// waited for the write lock.
+		var current int
+		if err := tx.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_version").Scan(&current); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if current > i {
+			tx.Rollback()
+			continue
+		}
+
 		if err := migrations[i](tx); err != nil {
 			tx.Rollback()
 			return fmt.Errorf("migration %d failed: %w", i+1, err)
@@ -0,0 +1,54 @@
+package database
+
+import "time"
+
+// ItemsAddedBetween returns the items added to any list in [from, to),
+// oldest first, including ones archived since.
+func (db *DB) ItemsAddedBetween(from, to time.Time) ([]Item, error) {
+	rows, err := db.Query(`
+		SELECT `+itemColumns+`
+		FROM items
+		WHERE added_at >= ? AND added_at < ?
+		ORDER BY added_at ASC, id ASC`,
+		from.UTC().Format(time.DateTime), to.UTC().Format(time.DateTime))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []Item{}
+	for rows.Next() {
+		i, err := scanItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	return items, rows.Err()
+}
+
+// PlaysBetween returns the completed plays recorded for host in [from, to),
+// oldest first. Kodi reports play times in the host's local time, which is
+// assumed to match the server's.
+func (db *DB) PlaysBetween(host string, from, to time.Time) ([]Viewing, error) {
+	rows, err := db.Query(`
+		SELECT `+viewingColumns+`
+		FROM viewing_history
+		WHERE kodi_host = ? AND completed AND played_at >= ? AND played_at < ?
+		ORDER BY played_at ASC, id ASC`,
+		host, from.Local().Format(time.DateTime), to.Local().Format(time.DateTime))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	plays := []Viewing{}
+	for rows.Next() {
+		v, err := scanViewing(rows)
+		if err != nil {
+			return nil, err
+		}
+		plays = append(plays, v)
+	}
+	return plays, rows.Err()
+}
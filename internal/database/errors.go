@@ -0,0 +1,41 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Sentinel errors returned by DB methods so callers can tell expected
+// failures apart from database faults.
+var (
+	ErrListNotFound     = errors.New("list not found")
+	ErrItemNotFound     = errors.New("item not found")
+	ErrDuplicateItem    = errors.New("item is already on the list")
+	ErrSearchNotFound   = errors.New("saved search not found")
+	ErrDuplicateSearch  = errors.New("a saved search with that name already exists")
+	ErrSectionNotFound  = errors.New("section not found")
+	ErrDuplicateSection = errors.New("a section with that name already exists")
+	ErrFeedNotFound     = errors.New("feed not found")
+	ErrDuplicateFeed    = errors.New("the list is already subscribed to that feed")
+	ErrRevisionMismatch = errors.New("the list was changed since it was read")
+	ErrInvalidSettings  = errors.New("invalid settings profile")
+	ErrPrefsNotFound    = errors.New("notification preferences not found")
+	ErrDuplicateList    = errors.New("a list with that name already exists in the group")
+	ErrInvalidList      = errors.New("invalid list")
+	ErrShareNotFound    = errors.New("list share not found")
+	ErrRemoteNotFound   = errors.New("the list doesn't mirror a remote list")
+	ErrListMirrored     = errors.New("the list mirrors a remote list")
+	ErrGroupNotFound    = errors.New("group not found")
+	ErrDuplicateGroup   = errors.New("a group with that name already exists")
+	ErrGroupNotEmpty    = errors.New("the group still has lists")
+	ErrGroupFromConfig  = errors.New("the group is defined in config.json")
+)
+
+// isUniqueViolation reports whether err is SQLite refusing a row that clashes
+// with a unique index. Unlike INSERT OR IGNORE, checking for it leaves other
+// constraint failures to surface as errors.
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && (sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique || sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey)
+}
@@ -0,0 +1,260 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ListShare lets another whats-next instance read a list. Only a hash of
+// its token is stored; the token itself is shown once, when the share is
+// created.
+type ListShare struct {
+	ID           int64  `json:"id"`
+	ListID       int64  `json:"list_id"`
+	Name         string `json:"name"`
+	LastPolledAt string `json:"last_polled_at"`
+	CreatedAt    string `json:"created_at"`
+}
+
+const shareColumns = "id, list_id, name, last_polled_at, created_at"
+
+func scanShare(row scanner) (ListShare, error) {
+	var sh ListShare
+	err := row.Scan(&sh.ID, &sh.ListID, &sh.Name, &sh.LastPolledAt, &sh.CreatedAt)
+	return sh, err
+}
+
+// AddListShare stores a share of listID under the hash of its token and
+// returns its ID.
+func (db *DB) AddListShare(listID int64, name, tokenHash string) (int64, error) {
+	if err := db.listExists(listID); err != nil {
+		return 0, err
+	}
+	res, err := db.Exec("INSERT INTO list_shares (list_id, name, token_hash) VALUES (?, ?, ?)", listID, name, tokenHash)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// GetListShare returns ErrShareNotFound unless the share belongs to listID.
+func (db *DB) GetListShare(listID, id int64) (*ListShare, error) {
+	sh, err := scanShare(db.QueryRow("SELECT "+shareColumns+" FROM list_shares WHERE id = ? AND list_id = ?", id, listID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrShareNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sh, nil
+}
+
+func (db *DB) GetListShares(listID int64) ([]ListShare, error) {
+	if err := db.listExists(listID); err != nil {
+		return nil, err
+	}
+	rows, err := db.Query("SELECT "+shareColumns+" FROM list_shares WHERE list_id = ? ORDER BY id ASC", listID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shares := []ListShare{}
+	for rows.Next() {
+		sh, err := scanShare(rows)
+		if err != nil {
+			return nil, err
+		}
+		shares = append(shares, sh)
+	}
+	return shares, rows.Err()
+}
+
+// FindListShare returns the share with the token hashing to tokenHash and
+// records that it was used, or ErrShareNotFound.
+func (db *DB) FindListShare(tokenHash string) (*ListShare, error) {
+	sh, err := scanShare(db.QueryRow("SELECT "+shareColumns+" FROM list_shares WHERE token_hash = ?", tokenHash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrShareNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	sh.LastPolledAt = time.Now().UTC().Format(time.RFC3339)
+	if _, err := db.Exec("UPDATE list_shares SET last_polled_at = ? WHERE id = ?", sh.LastPolledAt, sh.ID); err != nil {
+		return nil, err
+	}
+	return &sh, nil
+}
+
+// DeleteListShare revokes a share; the other instance's copy stops updating.
+func (db *DB) DeleteListShare(listID, id int64) error {
+	res, err := db.Exec("DELETE FROM list_shares WHERE id = ? AND list_id = ?", id, listID)
+	if err != nil {
+		return err
+	}
+	return requireRow(res, ErrShareNotFound)
+}
+
+// RemoteList is a list on another whats-next instance that a local list
+// mirrors. ETag is the version last applied, so unchanged lists aren't
+// re-applied.
+type RemoteList struct {
+	ListID       int64  `json:"list_id"`
+	URL          string `json:"url"`
+	Token        string `json:"-"`
+	ETag         string `json:"-"`
+	LastPolledAt string `json:"last_polled_at"`
+	LastError    string `json:"last_error,omitempty"`
+	CreatedAt    string `json:"created_at"`
+}
+
+const remoteColumns = "list_id, url, token, etag, last_polled_at, last_error, created_at"
+
+func scanRemote(row scanner) (RemoteList, error) {
+	var rl RemoteList
+	err := row.Scan(&rl.ListID, &rl.URL, &rl.Token, &rl.ETag, &rl.LastPolledAt, &rl.LastError, &rl.CreatedAt)
+	return rl, err
+}
+
+// SetRemoteList makes a list mirror a remote list, replacing the one it
+// mirrored before.
+func (db *DB) SetRemoteList(listID int64, url, token string) error {
+	if err := db.listExists(listID); err != nil {
+		return err
+	}
+	_, err := db.Exec(`
+		INSERT INTO list_remotes (list_id, url, token) VALUES (?, ?, ?)
+		ON CONFLICT(list_id) DO UPDATE SET url = excluded.url, token = excluded.token,
+			etag = '', last_polled_at = '', last_error = ''`, listID, url, token)
+	return err
+}
+
+func (db *DB) GetRemoteList(listID int64) (*RemoteList, error) {
+	rl, err := scanRemote(db.QueryRow("SELECT "+remoteColumns+" FROM list_remotes WHERE list_id = ?", listID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRemoteNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rl, nil
+}
+
+// GetAllRemoteLists returns every mirrored list but archived ones, for the
+// scheduled poll.
+func (db *DB) GetAllRemoteLists() ([]RemoteList, error) {
+	rows, err := db.Query("SELECT " + remoteColumns + " FROM list_remotes WHERE list_id NOT IN (SELECT id FROM lists WHERE archived_at != '') ORDER BY list_id ASC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	remotes := []RemoteList{}
+	for rows.Next() {
+		rl, err := scanRemote(rows)
+		if err != nil {
+			return nil, err
+		}
+		remotes = append(remotes, rl)
+	}
+	return remotes, rows.Err()
+}
+
+// DeleteRemoteList stops a list mirroring its remote list. Its items stay.
+func (db *DB) DeleteRemoteList(listID int64) error {
+	res, err := db.Exec("DELETE FROM list_remotes WHERE list_id = ?", listID)
+	if err != nil {
+		return err
+	}
+	return requireRow(res, ErrRemoteNotFound)
+}
+
+// UpdateRemoteStatus records the outcome of a poll: the version applied,
+// unchanged when etag is empty, and the error message, empty on success.
+func (db *DB) UpdateRemoteStatus(listID int64, etag, lastError string) error {
+	_, err := db.Exec(`
+		UPDATE list_remotes SET etag = CASE WHEN ? = '' THEN etag ELSE ? END,
+			last_error = ?, last_polled_at = ?
+		WHERE list_id = ?`, etag, etag, lastError, time.Now().UTC().Format(time.RFC3339), listID)
+	return err
+}
+
+// ReplaceItems swaps a list's items for items, in their order, in one
+// transaction, and returns the list's new revision. Items already on the
+// list keep their ID, position aside, so pins and links to them survive.
+func (db *DB) ReplaceItems(listID int64, items []Item) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	if err := checkRevision(tx, listID, nil); err != nil {
+		return 0, err
+	}
+
+	existing := map[string]int64{}
+	var all []int64
+	rows, err := tx.Query("SELECT id, media_type, COALESCE(kodi_id, 0), wanted, title, year, season, episode FROM items WHERE list_id = ?", listID)
+	if err != nil {
+		return 0, err
+	}
+	for rows.Next() {
+		var id int64
+		var i Item
+		if err := rows.Scan(&id, &i.MediaType, &i.KodiID, &i.Wanted, &i.Title, &i.Year, &i.Season, &i.Episode); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if _, ok := existing[replaceKey(i)]; !ok {
+			existing[replaceKey(i)] = id
+		}
+		all = append(all, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	// Drop what's no longer wanted first, so it can't clash with new rows
+	ids := make([]int64, len(items))
+	keep := map[int64]bool{}
+	for n, i := range items {
+		if id, ok := existing[replaceKey(i)]; ok && !keep[id] {
+			ids[n], keep[id] = id, true
+		}
+	}
+	for _, id := range all {
+		if keep[id] {
+			continue
+		}
+		if _, err := tx.Exec("DELETE FROM items WHERE id = ?", id); err != nil {
+			return 0, err
+		}
+	}
+	for n, i := range items {
+		if ids[n] != 0 {
+			if _, err := tx.Exec("UPDATE items SET sort_order = ?, archived_at = '' WHERE id = ?", n, ids[n]); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		i.ListID, i.SortOrder = listID, n
+		if _, err := insertItem(tx, i); err != nil && !errors.Is(err, ErrDuplicateItem) {
+			return 0, err
+		}
+	}
+	return commitRevision(tx, listID)
+}
+
+// replaceKey identifies an item across ReplaceItems calls: library items by
+// their Kodi ID, wanted items by title.
+func replaceKey(i Item) string {
+	if i.Wanted {
+		return fmt.Sprintf("wanted|%s|%s|%d|%d|%d", i.MediaType, strings.ToLower(i.Title), i.Year, i.Season, i.Episode)
+	}
+	return fmt.Sprintf("%s|%d|%d", i.MediaType, i.KodiID, i.Season)
+}
@@ -0,0 +1,131 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Feed is an RSS, Atom or JSON feed of titles a list is subscribed to, e.g.
+// a critic's monthly picks. New entries are matched against the library and
+// appended to the list.
+type Feed struct {
+	ID            int64  `json:"id"`
+	ListID        int64  `json:"list_id"`
+	URL           string `json:"url"`
+	Title         string `json:"title"`
+	LastCheckedAt string `json:"last_checked_at"`
+	LastError     string `json:"last_error,omitempty"`
+	CreatedAt     string `json:"created_at"`
+}
+
+const feedColumns = "id, list_id, url, title, last_checked_at, last_error, created_at"
+
+func scanFeed(row scanner) (Feed, error) {
+	var f Feed
+	err := row.Scan(&f.ID, &f.ListID, &f.URL, &f.Title, &f.LastCheckedAt, &f.LastError, &f.CreatedAt)
+	return f, err
+}
+
+// AddFeed subscribes a list to a feed and returns its ID, or
+// ErrDuplicateFeed if the list is already subscribed to that URL.
+func (db *DB) AddFeed(f Feed) (int64, error) {
+	if err := db.listExists(f.ListID); err != nil {
+		return 0, err
+	}
+	res, err := db.Exec("INSERT OR IGNORE INTO feeds (list_id, url) VALUES (?, ?)", f.ListID, f.URL)
+	if err != nil {
+		return 0, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return 0, err
+	} else if n == 0 {
+		return 0, ErrDuplicateFeed
+	}
+	return res.LastInsertId()
+}
+
+func (db *DB) GetFeeds(listID int64) ([]Feed, error) {
+	if err := db.listExists(listID); err != nil {
+		return nil, err
+	}
+	return db.queryFeeds("SELECT "+feedColumns+" FROM feeds WHERE list_id = ? ORDER BY id ASC", listID)
+}
+
+// GetAllFeeds returns the feeds of every list but archived ones, for the
+// scheduled refresh.
+func (db *DB) GetAllFeeds() ([]Feed, error) {
+	return db.queryFeeds("SELECT " + feedColumns + " FROM feeds WHERE list_id NOT IN (SELECT id FROM lists WHERE archived_at != '') ORDER BY id ASC")
+}
+
+func (db *DB) queryFeeds(query string, args ...interface{}) ([]Feed, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	feeds := []Feed{}
+	for rows.Next() {
+		f, err := scanFeed(rows)
+		if err != nil {
+			return nil, err
+		}
+		feeds = append(feeds, f)
+	}
+	return feeds, rows.Err()
+}
+
+// GetFeed returns ErrFeedNotFound unless the feed belongs to listID.
+func (db *DB) GetFeed(listID, id int64) (*Feed, error) {
+	f, err := scanFeed(db.QueryRow("SELECT "+feedColumns+" FROM feeds WHERE id = ? AND list_id = ?", id, listID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrFeedNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// DeleteFeed unsubscribes a list from a feed. Items it already added stay.
+func (db *DB) DeleteFeed(listID, id int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("DELETE FROM feeds WHERE id = ? AND list_id = ?", id, listID)
+	if err != nil {
+		return err
+	}
+	if err := requireRow(res, ErrFeedNotFound); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM feed_entries WHERE feed_id = ?", id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateFeedStatus records the outcome of a check: the feed's own title, if
+// it was fetched, and the error message, empty on success.
+func (db *DB) UpdateFeedStatus(id int64, title, lastError string) error {
+	_, err := db.Exec(`
+		UPDATE feeds SET title = CASE WHEN ? = '' THEN title ELSE ? END,
+			last_error = ?, last_checked_at = ?
+		WHERE id = ?`, title, title, lastError, time.Now().UTC().Format(time.RFC3339), id)
+	return err
+}
+
+// MarkFeedEntry records that a feed entry was processed and reports whether
+// it is new.
+func (db *DB) MarkFeedEntry(feedID int64, guid string) (bool, error) {
+	res, err := db.Exec("INSERT OR IGNORE INTO feed_entries (feed_id, guid) VALUES (?, ?)", feedID, guid)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
@@ -0,0 +1,35 @@
+package database
+
+// MovieSetRef names the Kodi movie set a list item belongs to.
+type MovieSetRef struct {
+	ID    int
+	Title string
+}
+
+// ItemSets returns the movie set of each movie on listID that belongs to
+// one, keyed by item ID, as recorded in the library cache of the list's host.
+func (db *DB) ItemSets(listID int64) (map[int64]MovieSetRef, error) {
+	rows, err := db.Query(`
+		SELECT i.id, MAX(lc.set_id), MAX(lc.set_title)
+		FROM items i
+		JOIN lists l_current ON l_current.id = i.list_id
+		JOIN library_cache lc ON lc.kodi_id = i.kodi_id AND lc.media_type = 'movie'
+		JOIN lists l_cache ON l_cache.id = lc.list_id AND l_cache.effective_host = l_current.effective_host
+		WHERE i.list_id = ? AND i.media_type = 'movie' AND lc.set_id != 0
+		GROUP BY i.id`, listID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sets := map[int64]MovieSetRef{}
+	for rows.Next() {
+		var id int64
+		var set MovieSetRef
+		if err := rows.Scan(&id, &set.ID, &set.Title); err != nil {
+			return nil, err
+		}
+		sets[id] = set
+	}
+	return sets, rows.Err()
+}
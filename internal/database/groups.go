@@ -0,0 +1,236 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// SyncGroups upserts group host definitions from config. Lists inheriting
+// a group's connection read its credentials from the group, and take its
+// host as their effective_host.
+func (db *DB) SyncGroups(groups []Group) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := syncGroups(tx, groups); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func syncGroups(tx *sql.Tx, groups []Group) error {
+	for _, g := range groups {
+		if _, err := tx.Exec(`
+			INSERT INTO groups (name, kodi_host, username, password, sort_order)
+			SELECT ?, ?, ?, ?, COALESCE(MAX(sort_order), -1) + 1 FROM groups WHERE true
+			ON CONFLICT(name) DO UPDATE SET kodi_host = excluded.kodi_host, username = excluded.username, password = excluded.password`,
+			g.Name, g.KodiHost, g.Username, g.Password); err != nil {
+			return err
+		}
+		if g.KodiHost == "" {
+			continue
+		}
+		if err := recordGroupHostChange(tx, g); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("UPDATE lists SET effective_host = ? WHERE group_name = ? AND inherits_host = 1", g.KodiHost, g.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordGroupHostChange queues host migrations for the lists inheriting g's
+// connection when its kodi_host changes.
+func recordGroupHostChange(tx *sql.Tx, g Group) error {
+	rows, err := tx.Query("SELECT id, effective_host FROM lists WHERE group_name = ? AND inherits_host = 1 AND effective_host != ?", g.Name, g.KodiHost)
+	if err != nil {
+		return err
+	}
+	hosts := map[int64]string{}
+	for rows.Next() {
+		var id int64
+		var host string
+		if err := rows.Scan(&id, &host); err != nil {
+			rows.Close()
+			return err
+		}
+		hosts[id] = host
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for id, host := range hosts {
+		if err := recordHostChange(tx, id, host, g.KodiHost); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const groupColumns = "id, name, kodi_host, username, password, sort_order"
+
+func scanGroup(row scanner) (Group, error) {
+	var g Group
+	err := row.Scan(&g.ID, &g.Name, &g.KodiHost, &g.Username, &g.Password, &g.SortOrder)
+	return g, err
+}
+
+// GetGroups returns every group in display order.
+func (db *DB) GetGroups() ([]Group, error) {
+	rows, err := db.Query("SELECT " + groupColumns + " FROM groups ORDER BY sort_order ASC, id ASC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := []Group{}
+	for rows.Next() {
+		g, err := scanGroup(rows)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+// GetGroup returns a group by ID, or ErrGroupNotFound.
+func (db *DB) GetGroup(id int64) (*Group, error) {
+	g, err := scanGroup(db.QueryRow("SELECT "+groupColumns+" FROM groups WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrGroupNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// AddGroup stores g after the other groups and returns its ID, or
+// ErrDuplicateGroup if a group already has its name.
+func (db *DB) AddGroup(g Group) (int64, error) {
+	res, err := db.Exec(`
+		INSERT INTO groups (name, kodi_host, username, password, sort_order)
+		SELECT ?, ?, ?, ?, COALESCE(MAX(sort_order), -1) + 1 FROM groups`,
+		g.Name, g.KodiHost, g.Username, g.Password)
+	if isUniqueViolation(err) {
+		return 0, ErrDuplicateGroup
+	} else if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// RenameGroup renames a group and moves its lists along. It returns
+// ErrGroupFromConfig if config.json defines any of its lists, since the next
+// start would bring them back under the old name.
+func (db *DB) RenameGroup(id int64, name string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var old string
+	if err := tx.QueryRow("SELECT name FROM groups WHERE id = ?", id).Scan(&old); errors.Is(err, sql.ErrNoRows) {
+		return ErrGroupNotFound
+	} else if err != nil {
+		return err
+	}
+	if old == name {
+		return nil
+	}
+	var fromConfig bool
+	if err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM lists WHERE group_name = ? AND from_config != 0)", old).Scan(&fromConfig); err != nil {
+		return err
+	} else if fromConfig {
+		return ErrGroupFromConfig
+	}
+	if _, err := tx.Exec("UPDATE groups SET name = ? WHERE id = ?", name, id); isUniqueViolation(err) {
+		return ErrDuplicateGroup
+	} else if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE lists SET group_name = ? WHERE group_name = ?", name, old); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteGroup removes a group without lists, or returns ErrGroupNotEmpty.
+func (db *DB) DeleteGroup(id int64) error {
+	res, err := db.Exec(`
+		DELETE FROM groups WHERE id = ?
+		AND NOT EXISTS (SELECT 1 FROM lists WHERE group_name = groups.name)`, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		if _, err := db.GetGroup(id); err != nil {
+			return err
+		}
+		return ErrGroupNotEmpty
+	}
+	return nil
+}
+
+// ReorderGroups sets the display order of groups to groupIDs. Groups left
+// out follow in their current order.
+func (db *DB) ReorderGroups(groupIDs []int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query("SELECT id FROM groups ORDER BY sort_order ASC, id ASC")
+	if err != nil {
+		return err
+	}
+	var current []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		current = append(current, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	exists := make(map[int64]bool, len(current))
+	for _, id := range current {
+		exists[id] = true
+	}
+	placed := make(map[int64]bool, len(groupIDs))
+	order := make([]int64, 0, len(current))
+	for _, id := range groupIDs {
+		if !exists[id] {
+			return ErrGroupNotFound
+		}
+		if !placed[id] {
+			placed[id] = true
+			order = append(order, id)
+		}
+	}
+	for _, id := range current {
+		if !placed[id] {
+			order = append(order, id)
+		}
+	}
+	for pos, id := range order {
+		if _, err := tx.Exec("UPDATE groups SET sort_order = ? WHERE id = ?", pos, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
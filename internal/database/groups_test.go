@@ -0,0 +1,52 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestInheritingListsReadGroupConnection(t *testing.T) {
+	db, err := InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	defer db.Close()
+
+	group := Group{Name: "Lounge", KodiHost: "http://lounge:8080", Username: "kodi", Password: "old"}
+	if err := db.SyncGroups([]Group{group}); err != nil {
+		t.Fatalf("SyncGroups: %v", err)
+	}
+	if err := db.SyncLists([]List{{GroupName: "Lounge", Name: "Movies", ContentType: "movie"}}); err != nil {
+		t.Fatalf("SyncLists: %v", err)
+	}
+	lists, err := db.GetAllLists()
+	if err != nil || len(lists) != 1 {
+		t.Fatalf("GetAllLists = %+v, %v", lists, err)
+	}
+	l := &lists[0]
+	if !l.InheritsHost || l.KodiHost != group.KodiHost || l.Password != "old" {
+		t.Fatalf("list = %+v, want the group's connection", l)
+	}
+
+	// The row keeps no copy of the group's connection
+	var host, user, pass string
+	if err := db.QueryRow("SELECT kodi_host, username, password FROM lists WHERE id = ?", l.ID).Scan(&host, &user, &pass); err != nil {
+		t.Fatalf("read list row: %v", err)
+	}
+	if host != "" || user != "" || pass != "" {
+		t.Fatalf("list row stores %q, %q, %q; want empty", host, user, pass)
+	}
+
+	// A new host and password on the group reach the list without copying
+	// the credentials onto it
+	group.KodiHost, group.Password = "http://lounge:9090", "new"
+	if err := db.SyncGroups([]Group{group}); err != nil {
+		t.Fatalf("SyncGroups: %v", err)
+	}
+	if l, err = db.GetList(l.ID); err != nil {
+		t.Fatalf("GetList: %v", err)
+	}
+	if l.KodiHost != group.KodiHost || l.Password != "new" {
+		t.Fatalf("list = %+v, want the group's new connection", l)
+	}
+}
@@ -0,0 +1,242 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"whats-next/internal/media"
+)
+
+// Viewing is one play of a movie or episode, pulled from the last played
+// time Kodi keeps for it. Items don't have to be on a list.
+type Viewing struct {
+	ID        int64  `json:"id"`
+	KodiHost  string `json:"-"`
+	MediaType string `json:"media_type"` // "movie" or "episode"
+	KodiID    int    `json:"kodi_id"`
+	TVShowID  int    `json:"tvshow_id,omitempty"`
+	Title     string `json:"title"`
+	ShowTitle string `json:"show_title,omitempty"`
+	Season    int    `json:"season,omitempty"`
+	Episode   int    `json:"episode,omitempty"`
+	Year      int    `json:"year,omitempty"`
+	Runtime   int    `json:"runtime"` // seconds
+
+	// PlayedAt is Kodi's last played time, "YYYY-MM-DD HH:MM:SS" in the
+	// Kodi host's local time.
+	PlayedAt  string `json:"played_at"`
+	Playcount int    `json:"playcount"`
+
+	// Completed is set when the play raised Kodi's playcount. Other plays
+	// were stopped at ResumePosition (seconds).
+	ResumePosition int  `json:"resume_position"`
+	Completed      bool `json:"completed"`
+}
+
+const viewingColumns = "id, kodi_host, media_type, kodi_id, tvshow_id, title, show_title, season, episode, year, runtime, played_at, playcount, resume_position, completed"
+
+func scanViewing(row scanner) (Viewing, error) {
+	var v Viewing
+	err := row.Scan(&v.ID, &v.KodiHost, &v.MediaType, &v.KodiID, &v.TVShowID, &v.Title, &v.ShowTitle, &v.Season, &v.Episode, &v.Year, &v.Runtime, &v.PlayedAt, &v.Playcount, &v.ResumePosition, &v.Completed)
+	return v, err
+}
+
+// HistoryWatermark returns the most recent play of mediaType recorded for
+// host, or "" if there is none.
+func (db *DB) HistoryWatermark(host, mediaType string) (string, error) {
+	var latest string
+	err := db.QueryRow("SELECT COALESCE(MAX(played_at), '') FROM viewing_history WHERE kodi_host = ? AND media_type = ?", host, mediaType).Scan(&latest)
+	return latest, err
+}
+
+// RecordViewings stores the plays Kodi reported for host, skipping ones
+// already recorded. A play counts as completed when the playcount rose
+// since the item's previous recorded play, or, for the first play seen of an
+// item, when it was played at all. It returns the number of new plays.
+func (db *DB) RecordViewings(host string, views []Viewing) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	prev, err := tx.Prepare("SELECT MAX(playcount) FROM viewing_history WHERE kodi_host = ? AND media_type = ? AND kodi_id = ? AND played_at < ?")
+	if err != nil {
+		return 0, err
+	}
+	defer prev.Close()
+
+	insert, err := tx.Prepare(`
+		INSERT OR IGNORE INTO viewing_history (kodi_host, media_type, kodi_id, tvshow_id, title, show_title, season, episode, year, runtime, played_at, playcount, resume_position, completed)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return 0, err
+	}
+	defer insert.Close()
+
+	added := 0
+	for _, v := range views {
+		var before sql.NullInt64
+		if err := prev.QueryRow(host, v.MediaType, v.KodiID, v.PlayedAt).Scan(&before); err != nil {
+			return 0, err
+		}
+		completed := v.Playcount > 0
+		if before.Valid {
+			completed = int64(v.Playcount) > before.Int64
+		}
+		res, err := insert.Exec(host, v.MediaType, v.KodiID, v.TVShowID, v.Title, v.ShowTitle, v.Season, v.Episode, v.Year, v.Runtime, v.PlayedAt, v.Playcount, v.ResumePosition, completed)
+		if err != nil {
+			return 0, fmt.Errorf("failed to record play of %s %d: %w", v.MediaType, v.KodiID, err)
+		}
+		n, _ := res.RowsAffected()
+		added += int(n)
+	}
+	return added, tx.Commit()
+}
+
+// GetHistory returns the plays recorded for listID's Kodi host, most recent
+// first. An empty mediaType returns both movies and episodes.
+func (db *DB) GetHistory(listID int64, mediaType string, limit, offset int) ([]Viewing, error) {
+	if err := db.listExists(listID); err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(`
+		SELECT `+prefixColumns("h", viewingColumns)+`
+		FROM viewing_history h JOIN lists l ON l.effective_host = h.kodi_host
+		WHERE l.id = ? AND (? = '' OR h.media_type = ?)
+		ORDER BY h.played_at DESC, h.id DESC
+		LIMIT ? OFFSET ?`, listID, mediaType, mediaType, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []Viewing{}
+	for rows.Next() {
+		v, err := scanViewing(rows)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, v)
+	}
+	return history, rows.Err()
+}
+
+// WatchDay is one day's viewing in HistoryStats.
+type WatchDay struct {
+	Date         string `json:"date"` // YYYY-MM-DD
+	Plays        int    `json:"plays"`
+	WatchSeconds int    `json:"watch_seconds"`
+}
+
+// HistoryStats summarises the viewing on a Kodi host. Watch time counts the
+// runtime of completed plays and the resume point of the others.
+type HistoryStats struct {
+	Plays            int        `json:"plays"`
+	MoviesWatched    int        `json:"movies_watched"`
+	EpisodesWatched  int        `json:"episodes_watched"`
+	WatchSeconds     int        `json:"watch_seconds"`
+	WatchFormatted   string     `json:"watch_formatted"`
+	Days             []WatchDay `json:"days"`
+	MostWatchedShows []string   `json:"most_watched_shows"`
+}
+
+// maxStatShows caps HistoryStats.MostWatchedShows.
+const maxStatShows = 5
+
+const watchSecondsExpr = "CASE WHEN h.completed THEN h.runtime ELSE h.resume_position END"
+
+// GetHistoryStats summarises the plays on listID's Kodi host since the given
+// "YYYY-MM-DD HH:MM:SS" time, or all of them when since is empty.
+func (db *DB) GetHistoryStats(listID int64, since string) (*HistoryStats, error) {
+	if err := db.listExists(listID); err != nil {
+		return nil, err
+	}
+	const from = `
+		FROM viewing_history h JOIN lists l ON l.effective_host = h.kodi_host
+		WHERE l.id = ? AND h.played_at >= ?`
+
+	stats := &HistoryStats{Days: []WatchDay{}, MostWatchedShows: []string{}}
+	err := db.QueryRow(`
+		SELECT COUNT(*),
+			COALESCE(SUM(h.completed AND h.media_type = 'movie'), 0),
+			COALESCE(SUM(h.completed AND h.media_type = 'episode'), 0),
+			COALESCE(SUM(`+watchSecondsExpr+`), 0)`+from, listID, since).
+		Scan(&stats.Plays, &stats.MoviesWatched, &stats.EpisodesWatched, &stats.WatchSeconds)
+	if err != nil {
+		return nil, err
+	}
+	stats.WatchFormatted = media.FormatRuntime(stats.WatchSeconds)
+
+	rows, err := db.Query(`
+		SELECT substr(h.played_at, 1, 10) AS day, COUNT(*), COALESCE(SUM(`+watchSecondsExpr+`), 0)`+from+`
+		GROUP BY day ORDER BY day ASC`, listID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var d WatchDay
+		if err := rows.Scan(&d.Date, &d.Plays, &d.WatchSeconds); err != nil {
+			return nil, err
+		}
+		stats.Days = append(stats.Days, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	shows, err := db.Query(`
+		SELECT h.show_title`+from+` AND h.media_type = 'episode' AND h.show_title != ''
+		GROUP BY h.show_title ORDER BY COUNT(*) DESC, MAX(h.played_at) DESC LIMIT ?`, listID, since, maxStatShows)
+	if err != nil {
+		return nil, err
+	}
+	defer shows.Close()
+	for shows.Next() {
+		var title string
+		if err := shows.Scan(&title); err != nil {
+			return nil, err
+		}
+		stats.MostWatchedShows = append(stats.MostWatchedShows, title)
+	}
+	return stats, shows.Err()
+}
+
+// LastWatched returns when each of the given movies, or shows when
+// mediaType is "show", was last watched to the end on listID's Kodi host.
+// Shows count as watched when any of their episodes was. Items never watched
+// are left out.
+func (db *DB) LastWatched(listID int64, mediaType string, kodiIDs []int) (map[int]string, error) {
+	watched := map[int]string{}
+	if len(kodiIDs) == 0 {
+		return watched, nil
+	}
+	idColumn, historyType := "h.kodi_id", "movie"
+	if mediaType == "show" {
+		idColumn, historyType = "h.tvshow_id", "episode"
+	}
+	args := []interface{}{listID, historyType}
+	for _, id := range kodiIDs {
+		args = append(args, id)
+	}
+	rows, err := db.Query(`
+		SELECT `+idColumn+`, MAX(h.played_at)
+		FROM viewing_history h JOIN lists l ON l.effective_host = h.kodi_host
+		WHERE l.id = ? AND h.media_type = ? AND h.completed
+		AND `+idColumn+` IN (`+placeholders(len(kodiIDs))+`)
+		GROUP BY `+idColumn, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		var at string
+		if err := rows.Scan(&id, &at); err != nil {
+			return nil, err
+		}
+		watched[id] = at
+	}
+	return watched, rows.Err()
+}
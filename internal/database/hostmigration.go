@@ -0,0 +1,178 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// HostMigration tracks re-resolving a list's items against its new Kodi host
+// after kodi_host changed, since Kodi IDs are only meaningful on the host
+// that assigned them.
+type HostMigration struct {
+	ListID     int64           `json:"list_id"`
+	FromHost   string          `json:"from_host"`
+	ToHost     string          `json:"to_host"`
+	Status     string          `json:"status"` // pending, done
+	Matched    int             `json:"matched"`
+	Unmatched  []UnmatchedItem `json:"unmatched"`
+	CreatedAt  string          `json:"created_at"`
+	FinishedAt string          `json:"finished_at,omitempty"`
+}
+
+// UnmatchedItem is a list item the new host's library had no match for.
+type UnmatchedItem struct {
+	ItemID    int64  `json:"item_id"`
+	MediaType string `json:"media_type"`
+	Title     string `json:"title"`
+	Year      int    `json:"year,omitempty"`
+	Reason    string `json:"reason"`
+}
+
+const hostMigrationColumns = "list_id, from_host, to_host, status, matched, unmatched, created_at, finished_at"
+
+func scanHostMigration(row scanner) (HostMigration, error) {
+	var m HostMigration
+	var unmatched string
+	if err := row.Scan(&m.ListID, &m.FromHost, &m.ToHost, &m.Status, &m.Matched, &unmatched, &m.CreatedAt, &m.FinishedAt); err != nil {
+		return m, err
+	}
+	m.Unmatched = []UnmatchedItem{}
+	if unmatched != "" {
+		if err := json.Unmarshal([]byte(unmatched), &m.Unmatched); err != nil {
+			return m, err
+		}
+	}
+	return m, nil
+}
+
+// recordHostChange queues a host migration for a list whose kodi_host is
+// changing from one host to another. A migration still pending keeps its
+// original host, and one that ends up back where it started is dropped. The
+// list's cache rows describe the old host's library, and would pass for the
+// new host's until the next sync, so they are dropped too.
+func recordHostChange(ex execer, listID int64, from, to string) error {
+	if from == "" || from == to {
+		return nil
+	}
+	if _, err := ex.Exec("DELETE FROM library_cache WHERE list_id = ?", listID); err != nil {
+		return err
+	}
+	if _, err := ex.Exec(`
+		INSERT INTO host_migrations (list_id, from_host, to_host) VALUES (?, ?, ?)
+		ON CONFLICT(list_id) DO UPDATE SET
+			from_host = CASE WHEN status = 'pending' THEN from_host ELSE excluded.from_host END,
+			to_host = excluded.to_host, status = 'pending', matched = 0, unmatched = '[]',
+			created_at = CURRENT_TIMESTAMP, finished_at = ''`,
+		listID, from, to); err != nil {
+		return err
+	}
+	_, err := ex.Exec("DELETE FROM host_migrations WHERE list_id = ? AND status = 'pending' AND from_host = to_host", listID)
+	return err
+}
+
+// GetHostMigration returns the latest host migration of a list, or nil if
+// its host never changed.
+func (db *DB) GetHostMigration(listID int64) (*HostMigration, error) {
+	m, err := scanHostMigration(db.QueryRow("SELECT "+hostMigrationColumns+" FROM host_migrations WHERE list_id = ?", listID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// PendingHostMigrations returns the pending host migrations of every list.
+func (db *DB) PendingHostMigrations() ([]HostMigration, error) {
+	return db.queryHostMigrations("SELECT " + hostMigrationColumns + " FROM host_migrations WHERE status = 'pending' ORDER BY list_id")
+}
+
+// PendingHostMigrationsForHost returns the pending host migrations of the
+// lists that moved to listID's Kodi host.
+func (db *DB) PendingHostMigrationsForHost(listID int64) ([]HostMigration, error) {
+	return db.queryHostMigrations(`
+		SELECT `+prefixColumns("m", hostMigrationColumns)+`
+		FROM host_migrations m
+		JOIN lists l ON l.id = m.list_id
+		JOIN lists cur ON cur.id = ?
+		WHERE m.status = 'pending' AND l.effective_host = cur.effective_host AND m.to_host = l.effective_host
+		ORDER BY m.list_id`, listID)
+}
+
+func (db *DB) queryHostMigrations(query string, args ...interface{}) ([]HostMigration, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	migrations := []HostMigration{}
+	for rows.Next() {
+		m, err := scanHostMigration(rows)
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, m)
+	}
+	return migrations, rows.Err()
+}
+
+// FinishHostMigration records the outcome of a pending migration. It does
+// nothing if the list moved host again in the meantime, so the newer
+// migration stays pending.
+func (db *DB) FinishHostMigration(m HostMigration) error {
+	unmatched, err := json.Marshal(m.Unmatched)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`
+		UPDATE host_migrations SET status = 'done', matched = ?, unmatched = ?, finished_at = ?
+		WHERE list_id = ? AND to_host = ? AND status = 'pending'`,
+		m.Matched, string(unmatched), time.Now().UTC().Format(time.RFC3339), m.ListID, m.ToHost)
+	return err
+}
+
+// RemapItem points an item at its media on a new Kodi host and clears any
+// missing flag. poster replaces the Kodi artwork, or only the retained
+// original while a custom poster is set; an empty poster keeps the current
+// one. It returns ErrDuplicateItem if the list already holds the new media.
+func (db *DB) RemapItem(id int64, kodiID, tvshowID int, poster string) error {
+	res, err := db.Exec(`
+		UPDATE OR IGNORE items SET kodi_id = ?, tvshow_id = ?, missing_since = '',
+			poster_path = CASE WHEN ? = '' OR original_poster_path != '' THEN poster_path ELSE ? END,
+			original_poster_path = CASE WHEN ? != '' AND original_poster_path != '' THEN ? ELSE original_poster_path END
+		WHERE id = ?`,
+		kodiID, tvshowID, poster, poster, poster, poster, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		if _, err := db.GetItem(id); err != nil {
+			return err
+		}
+		return ErrDuplicateItem
+	}
+	return nil
+}
+
+// UnlinkItem turns a library item into a wanted one, so a later sync links
+// it again once the title shows up in the library.
+func (db *DB) UnlinkItem(id int64) error {
+	res, err := db.Exec("UPDATE items SET kodi_id = NULL, tvshow_id = 0, wanted = 1, missing_since = '' WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	return requireRow(res, ErrItemNotFound)
+}
+
+// FlagItemMissing marks an item as missing from the Kodi library unless it
+// is flagged already.
+func (db *DB) FlagItemMissing(id int64) error {
+	_, err := db.Exec("UPDATE items SET missing_since = ? WHERE id = ? AND missing_since = ''", time.Now().UTC().Format(time.RFC3339), id)
+	return err
+}
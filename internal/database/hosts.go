@@ -0,0 +1,101 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// RehostReport counts the rows RehostKodi rewrote.
+type RehostReport struct {
+	Lists          int64 `json:"lists"`
+	Groups         int64 `json:"groups"`
+	Posters        int64 `json:"posters"`
+	History        int64 `json:"history"`
+	Art            int64 `json:"art"`
+	PosterFailures int64 `json:"poster_failures"`
+	ShowCache      int64 `json:"show_cache"`
+	GenreCache     int64 `json:"genre_cache"`
+	DryRun         bool  `json:"dry_run"`
+}
+
+// RehostKodi moves everything recorded for the Kodi host oldHost to newHost
+// in one transaction: the lists and groups pointing at it, the viewing
+// history, artwork, poster failures, show listings and genres kept per host, and
+// poster URLs that still reference the old address. Moved lists stop
+// inheriting their group's host and keep newHost across restarts until their
+// config entry changes, like credentials rotated with UpdateListCredentials.
+// It returns ErrListNotFound when no list uses oldHost. A dry run counts
+// the same rows and rolls back.
+func (db *DB) RehostKodi(oldHost, newHost string, dryRun bool) (*RehostReport, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	report := &RehostReport{DryRun: dryRun}
+	// Inheriting lists take their group's credentials along as their own
+	res, err := tx.Exec(`
+		UPDATE lists SET kodi_host = ?, effective_host = ?, username = r.resolved_username, password = r.resolved_password, inherits_host = 0, credentials_override = 1
+		FROM resolved_lists r WHERE r.id = lists.id AND lists.effective_host = ?`, newHost, newHost, oldHost)
+	if err != nil {
+		return nil, err
+	}
+	if report.Lists, _ = res.RowsAffected(); report.Lists == 0 {
+		return nil, ErrListNotFound
+	}
+
+	if report.Groups, err = rowsAffected(tx.Exec("UPDATE groups SET kodi_host = ? WHERE kodi_host = ?", newHost, oldHost)); err != nil {
+		return nil, err
+	}
+
+	// Posters stored by older versions as absolute Kodi URLs
+	prefix := oldHost + "/"
+	posterUpdates := []string{
+		"UPDATE items SET poster_path = ? || substr(poster_path, ?) WHERE substr(poster_path, 1, ?) = ?",
+		"UPDATE items SET original_poster_path = ? || substr(original_poster_path, ?) WHERE substr(original_poster_path, 1, ?) = ?",
+		"UPDATE library_cache SET poster_path = ? || substr(poster_path, ?) WHERE substr(poster_path, 1, ?) = ?",
+	}
+	for _, q := range posterUpdates {
+		n, err := rowsAffected(tx.Exec(q, newHost+"/", len(prefix)+1, len(prefix), prefix))
+		if err != nil {
+			return nil, fmt.Errorf("failed to rewrite poster URLs: %w", err)
+		}
+		report.Posters += n
+	}
+
+	// Tables keyed by host rather than list. Rows already recorded under the
+	// new host win over the old host's copies.
+	hostTables := []struct {
+		name  string
+		count *int64
+	}{
+		{"viewing_history", &report.History},
+		{"item_art", &report.Art},
+		{"poster_failures", &report.PosterFailures},
+		{"show_cache", &report.ShowCache},
+		{"genre_cache", &report.GenreCache},
+	}
+	for _, t := range hostTables {
+		n, err := rowsAffected(tx.Exec("UPDATE OR IGNORE "+t.name+" SET kodi_host = ? WHERE kodi_host = ?", newHost, oldHost))
+		if err != nil {
+			return nil, fmt.Errorf("failed to rehost %s: %w", t.name, err)
+		}
+		if _, err := tx.Exec("DELETE FROM "+t.name+" WHERE kodi_host = ?", oldHost); err != nil {
+			return nil, fmt.Errorf("failed to rehost %s: %w", t.name, err)
+		}
+		*t.count = n
+	}
+
+	if dryRun {
+		return report, nil
+	}
+	return report, tx.Commit()
+}
+
+func rowsAffected(res sql.Result, err error) (int64, error) {
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,123 @@
+package database
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// PendingMatch is an imported title (IMDb, Letterboxd or plain CSV) that
+// could not be found in the Kodi library at import time.
+type PendingMatch struct {
+	ID            int64  `json:"id"`
+	ListID        int64  `json:"list_id"`
+	Source        string `json:"source"`
+	Title         string `json:"title"`
+	Year          int    `json:"year"`
+	ExternalID    string `json:"external_id"`
+	CreatedAt     string `json:"created_at"`
+	LastAttemptAt string `json:"last_attempt_at"`
+}
+
+func (db *DB) AddPendingMatch(p PendingMatch) error {
+	_, err := db.Exec(`
+		INSERT OR IGNORE INTO pending_matches (list_id, source, title, year, external_id)
+		VALUES (?, ?, ?, ?, ?)`, p.ListID, p.Source, p.Title, p.Year, p.ExternalID)
+	return err
+}
+
+func (db *DB) GetPendingMatches(listID int64) ([]PendingMatch, error) {
+	return db.queryPendingMatches(`
+		SELECT id, list_id, source, title, year, external_id, created_at, last_attempt_at
+		FROM pending_matches
+		WHERE list_id = ?
+		ORDER BY id ASC`, listID)
+}
+
+// GetPendingMatchesForHost returns pending matches for every list that shares
+// the Kodi host of the given list, since a sync refreshes the shared cache.
+func (db *DB) GetPendingMatchesForHost(listID int64) ([]PendingMatch, error) {
+	return db.queryPendingMatches(`
+		SELECT pm.id, pm.list_id, pm.source, pm.title, pm.year, pm.external_id, pm.created_at, pm.last_attempt_at
+		FROM pending_matches pm
+		JOIN lists l_pending ON pm.list_id = l_pending.id
+		JOIN lists l_current ON l_current.id = ?
+		WHERE l_pending.effective_host = l_current.effective_host
+		ORDER BY pm.id ASC`, listID)
+}
+
+func (db *DB) queryPendingMatches(query string, args ...interface{}) ([]PendingMatch, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	matches := make([]PendingMatch, 0)
+	for rows.Next() {
+		var p PendingMatch
+		if err := rows.Scan(&p.ID, &p.ListID, &p.Source, &p.Title, &p.Year, &p.ExternalID, &p.CreatedAt, &p.LastAttemptAt); err != nil {
+			return nil, err
+		}
+		matches = append(matches, p)
+	}
+	return matches, rows.Err()
+}
+
+func (db *DB) TouchPendingMatch(id int64) error {
+	_, err := db.Exec("UPDATE pending_matches SET last_attempt_at = CURRENT_TIMESTAMP WHERE id = ?", id)
+	return err
+}
+
+func (db *DB) DeletePendingMatch(id int64) error {
+	_, err := db.Exec("DELETE FROM pending_matches WHERE id = ?", id)
+	return err
+}
+
+// FindCachedByIMDbID finds a library item by its IMDb ID on the list's Kodi
+// host, returning sql.ErrNoRows when nothing matches.
+func (db *DB) FindCachedByIMDbID(listID int64, mediaType, imdbID string) (*CachedItem, error) {
+	if imdbID == "" {
+		return nil, sql.ErrNoRows
+	}
+	var kodiID int
+	err := db.QueryRow(`
+		SELECT lc.kodi_id
+		FROM library_cache lc
+		JOIN lists l_cache ON lc.list_id = l_cache.id
+		JOIN lists l_current ON l_current.id = ?
+		WHERE l_cache.effective_host = l_current.effective_host
+		AND lc.media_type = ?
+		AND lc.imdb_id = ?
+		LIMIT 1`, listID, mediaType, imdbID).Scan(&kodiID)
+	if err != nil {
+		return nil, err
+	}
+	return db.GetCachedItem(listID, kodiID, mediaType)
+}
+
+// FindCachedMatch finds a library item by title (case-insensitive) for the
+// list's Kodi host. A non-zero year must match within one year either way,
+// since release years often differ between IMDb, Letterboxd and scrapers.
+// It returns sql.ErrNoRows when nothing matches.
+func (db *DB) FindCachedMatch(listID int64, mediaType, title string, year int) (*CachedItem, error) {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return nil, sql.ErrNoRows
+	}
+	var kodiID int
+	err := db.QueryRow(`
+		SELECT lc.kodi_id
+		FROM library_cache lc
+		JOIN lists l_cache ON lc.list_id = l_cache.id
+		JOIN lists l_current ON l_current.id = ?
+		WHERE l_cache.effective_host = l_current.effective_host
+		AND lc.media_type = ?
+		AND lower(lc.title) = lower(?)
+		AND (? = 0 OR lc.year BETWEEN ? - 1 AND ? + 1)
+		ORDER BY ABS(lc.year - ?) ASC
+		LIMIT 1`, listID, mediaType, title, year, year, year, year).Scan(&kodiID)
+	if err != nil {
+		return nil, err
+	}
+	return db.GetCachedItem(listID, kodiID, mediaType)
+}
@@ -0,0 +1,46 @@
+package database
+
+import "time"
+
+// AcquireLease claims the named job lease for holder until ttl from now. It
+// succeeds only when the lease is free or expired, so replicas sharing the
+// database elect a single job runner and a held lease is never granted
+// twice, even to the same holder. Holders renew with RenewLease.
+func (db *DB) AcquireLease(name, holder string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	res, err := db.Exec(`
+		INSERT INTO job_leases (name, holder, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET holder = excluded.holder, expires_at = excluded.expires_at
+		WHERE job_leases.expires_at < ?`,
+		name, holder, now.Add(ttl).Unix(), now.Unix())
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// RenewLease extends the named lease to ttl from now if holder still owns
+// it, reporting whether it did.
+func (db *DB) RenewLease(name, holder string, ttl time.Duration) (bool, error) {
+	res, err := db.Exec("UPDATE job_leases SET expires_at = ? WHERE name = ? AND holder = ?",
+		time.Now().Add(ttl).Unix(), name, holder)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ReleaseLease gives up the named lease if holder still owns it.
+func (db *DB) ReleaseLease(name, holder string) error {
+	_, err := db.Exec("DELETE FROM job_leases WHERE name = ? AND holder = ?", name, holder)
+	return err
+}
+
+// LeaseHeld reports whether anyone holds the named lease.
+func (db *DB) LeaseHeld(name string) (bool, error) {
+	var held bool
+	err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM job_leases WHERE name = ? AND expires_at >= ?)", name, time.Now().Unix()).Scan(&held)
+	return held, err
+}
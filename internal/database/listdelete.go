@@ -0,0 +1,184 @@
+package database
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// ListDeletion counts what DeleteList removed. OrphanedPosters are the
+// stored poster files nothing references any more, for the caller to delete
+// from the poster store.
+type ListDeletion struct {
+	ListID          int64    `json:"list_id"`
+	Items           int64    `json:"items"`
+	CacheRows       int64    `json:"cache_rows"`
+	HostRows        int64    `json:"host_rows"`
+	OrphanedPosters []string `json:"orphaned_posters"`
+	DryRun          bool     `json:"dry_run"`
+}
+
+// listTables hold rows that belong to a single list.
+var listTables = []string{"pending_matches", "notifications", "saved_searches", "sections", "sync_state", "host_migrations", "list_pins", "list_shares", "list_remotes"}
+
+// hostCacheTables hold caches kept per Kodi host, which go with the last
+// list on the host. Viewing history is kept.
+var hostCacheTables = []string{"item_art", "poster_failures", "show_cache", "genre_cache"}
+
+// DeleteList removes a list in one transaction: its items, sections, saved
+// searches, feeds and other rows of its own. The library cache rows it holds
+// move to another list on the same host, or are deleted with the host's
+// other caches if it was the host's last list. A dry run counts the same
+// rows and rolls back.
+func (db *DB) DeleteList(listID int64, dryRun bool) (*ListDeletion, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var host string
+	if err := tx.QueryRow("SELECT effective_host FROM lists WHERE id = ?", listID).Scan(&host); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrListNotFound
+		}
+		return nil, err
+	}
+	var sibling sql.NullInt64
+	if err := tx.QueryRow("SELECT MIN(id) FROM lists WHERE effective_host = ? AND id != ?", host, listID).Scan(&sibling); err != nil {
+		return nil, err
+	}
+
+	// Posters that may be left unreferenced, checked once everything is gone
+	posterQueries := []string{
+		"SELECT poster_path FROM items WHERE list_id = ?",
+		"SELECT original_poster_path FROM items WHERE list_id = ?",
+	}
+	if !sibling.Valid {
+		posterQueries = append(posterQueries,
+			"SELECT poster_path FROM library_cache WHERE list_id = ?",
+			"SELECT url FROM item_art WHERE kodi_host = (SELECT effective_host FROM lists WHERE id = ?)")
+	}
+	candidates := map[string]bool{}
+	for _, q := range posterQueries {
+		if err := collectPosters(tx, candidates, q, listID); err != nil {
+			return nil, err
+		}
+	}
+
+	report := &ListDeletion{ListID: listID, OrphanedPosters: []string{}, DryRun: dryRun}
+	if report.Items, err = rowsAffected(tx.Exec("DELETE FROM items WHERE list_id = ?", listID)); err != nil {
+		return nil, err
+	}
+	if sibling.Valid {
+		report.CacheRows, err = rowsAffected(tx.Exec("UPDATE library_cache SET list_id = ? WHERE list_id = ?", sibling.Int64, listID))
+	} else {
+		report.CacheRows, err = rowsAffected(tx.Exec("DELETE FROM library_cache WHERE list_id = ?", listID))
+		for _, table := range hostCacheTables {
+			if err != nil {
+				break
+			}
+			var n int64
+			n, err = rowsAffected(tx.Exec("DELETE FROM "+table+" WHERE kodi_host = ?", host))
+			report.HostRows += n
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec("DELETE FROM feed_entries WHERE feed_id IN (SELECT id FROM feeds WHERE list_id = ?)", listID); err != nil {
+		return nil, err
+	}
+	for _, table := range append([]string{"feeds"}, listTables...) {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE list_id = ?", listID); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := tx.Exec("DELETE FROM lists WHERE id = ?", listID); err != nil {
+		return nil, err
+	}
+
+	for url := range candidates {
+		var used bool
+		err := tx.QueryRow(`
+			SELECT EXISTS(SELECT 1 FROM items WHERE poster_path = ?1 OR original_poster_path = ?1)
+			OR EXISTS(SELECT 1 FROM library_cache WHERE poster_path = ?1)
+			OR EXISTS(SELECT 1 FROM item_art WHERE url = ?1)`, url).Scan(&used)
+		if err != nil {
+			return nil, err
+		}
+		if !used {
+			report.OrphanedPosters = append(report.OrphanedPosters, strings.TrimPrefix(url, posterURLPrefix))
+		}
+	}
+
+	if dryRun {
+		return report, nil
+	}
+	return report, tx.Commit()
+}
+
+// posterURLPrefix starts the URL of every poster in the poster store.
+const posterURLPrefix = "/api/posters/"
+
+// collectPosters adds the stored posters the query selects to posters.
+// Posters elsewhere, such as Kodi URLs kept by older versions, are skipped.
+func collectPosters(tx *sql.Tx, posters map[string]bool, query string, args ...interface{}) error {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var url string
+		if err := rows.Scan(&url); err != nil {
+			return err
+		}
+		if strings.HasPrefix(url, posterURLPrefix) {
+			posters[url] = true
+		}
+	}
+	return rows.Err()
+}
+
+// SetConfigLists records the lists config.json defines and returns those it
+// defined at the last start but no longer does, for the caller to delete.
+// Lists created any other way, e.g. by a settings import, are never
+// returned.
+func (db *DB) SetConfigLists(lists []List) ([]List, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE lists SET from_config = 2 WHERE from_config = 1"); err != nil {
+		return nil, err
+	}
+	for _, l := range lists {
+		if _, err := tx.Exec("UPDATE lists SET from_config = 1 WHERE group_name = ? AND lower(name) = lower(?)", l.GroupName, l.Name); err != nil {
+			return nil, err
+		}
+	}
+	rows, err := tx.Query("SELECT " + listColumns + " FROM resolved_lists WHERE from_config = 2 ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	var removed []List
+	for rows.Next() {
+		l, err := scanList(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		removed = append(removed, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// Until they are deleted they still count as defined by config
+	if _, err := tx.Exec("UPDATE lists SET from_config = 1 WHERE from_config = 2"); err != nil {
+		return nil, err
+	}
+	return removed, tx.Commit()
+}
@@ -0,0 +1,76 @@
+package database
+
+import "time"
+
+// MetricCount is the total of one counter during one hour.
+type MetricCount struct {
+	Hour  time.Time
+	Name  string
+	Value int64
+}
+
+// AddMetrics adds counts to the counters of the hour containing at.
+func (db *DB) AddMetrics(at time.Time, counts map[string]int64) error {
+	hour := at.Truncate(time.Hour).Unix()
+	return addCounts(db, `
+		INSERT INTO metrics (hour, name, value) VALUES (?, ?, ?)
+		ON CONFLICT(hour, name) DO UPDATE SET value = value + excluded.value`,
+		counts, func(name string, n int64) []interface{} { return []interface{}{hour, name, n} })
+}
+
+// GetMetrics returns the hourly counters from the hour containing since
+// onwards, oldest first.
+func (db *DB) GetMetrics(since time.Time) ([]MetricCount, error) {
+	rows, err := db.Query("SELECT hour, name, value FROM metrics WHERE hour >= ? ORDER BY hour, name", since.Truncate(time.Hour).Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []MetricCount{}
+	for rows.Next() {
+		var c MetricCount
+		var hour int64
+		if err := rows.Scan(&hour, &c.Name, &c.Value); err != nil {
+			return nil, err
+		}
+		c.Hour = time.Unix(hour, 0).UTC()
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+// PruneMetrics deletes counters of hours before the one containing before.
+func (db *DB) PruneMetrics(before time.Time) error {
+	return db.pruneCounts("metrics", "hour", before.Truncate(time.Hour).Unix())
+}
+
+// addCounts runs upsert with the arguments row gives for every counter, in
+// one transaction. Replicas sharing the database each add their own counts,
+// so upsert adds to a stored total rather than replacing it.
+func addCounts[K comparable](db *DB, upsert string, counts map[K]int64, row func(key K, n int64) []interface{}) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(upsert)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for key, n := range counts {
+		if _, err := stmt.Exec(row(key, n)...); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// pruneCounts deletes the rows of a counter table whose period, stored as a
+// Unix time in column, starts before before.
+func (db *DB) pruneCounts(table, column string, before int64) error {
+	_, err := db.Exec("DELETE FROM "+table+" WHERE "+column+" < ?", before)
+	return err
+}
@@ -0,0 +1,122 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// cachedTypesFor returns the item media types backed by cache rows of
+// mediaType. Season items carry their show's Kodi ID.
+func cachedTypesFor(mediaType string) []interface{} {
+	if mediaType == "show" {
+		return []interface{}{"show", "season"}
+	}
+	return []interface{}{"movie"}
+}
+
+// ReconcileMissingItems compares list items against the library cache just
+// synced for listID. Items whose media is gone are flagged missing and
+// returned if they weren't flagged already; flagged items that are back are
+// cleared. An empty cache is treated as a failed sync and changes nothing.
+func (db *DB) ReconcileMissingItems(listID int64, mediaType string) ([]Item, error) {
+	var cached int
+	if err := db.QueryRow("SELECT COUNT(*) FROM library_cache WHERE list_id = ? AND media_type = ?", listID, mediaType).Scan(&cached); err != nil {
+		return nil, err
+	}
+	if cached == 0 {
+		return []Item{}, nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Items of the host's lists backed by this cache, with whether the
+	// synced library still has them
+	types := cachedTypesFor(mediaType)
+	scope := `
+		i.wanted = 0 AND i.kodi_id IS NOT NULL AND i.media_type IN (` + placeholders(len(types)) + `)
+		AND i.list_id IN (
+			SELECT l.id FROM lists l JOIN lists cur ON cur.id = ? WHERE l.effective_host = cur.effective_host
+		)
+		AND %s EXISTS (
+			SELECT 1 FROM library_cache lc
+			WHERE lc.list_id = ? AND lc.media_type = ? AND lc.kodi_id = i.kodi_id
+		)`
+	args := append(types, listID, listID, mediaType)
+
+	_, err = tx.Exec(`
+		UPDATE items SET missing_since = ''
+		WHERE id IN (SELECT i.id FROM items i WHERE i.missing_since != '' AND `+fmt.Sprintf(scope, "")+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(`
+		SELECT `+prefixColumns("i", itemColumns)+`
+		FROM items i
+		WHERE i.missing_since = '' AND `+fmt.Sprintf(scope, "NOT")+`
+		ORDER BY i.list_id, i.sort_order`, args...)
+	if err != nil {
+		return nil, err
+	}
+	missing := []Item{}
+	for rows.Next() {
+		i, err := scanItem(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		missing = append(missing, i)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	for idx := range missing {
+		if _, err := tx.Exec("UPDATE items SET missing_since = ? WHERE id = ?", now, missing[idx].ID); err != nil {
+			return nil, err
+		}
+		missing[idx].MissingSince = now
+	}
+	return missing, tx.Commit()
+}
+
+// GetMissingItems returns the items of a list flagged as gone from Kodi.
+func (db *DB) GetMissingItems(listID int64) ([]Item, error) {
+	return db.getItems(listID, `
+		SELECT `+itemColumns+`
+		FROM items
+		WHERE list_id = ? AND missing_since != ''
+		ORDER BY sort_order ASC, id ASC`)
+}
+
+// DeleteMissingItems removes every item of a list flagged as gone from Kodi
+// and returns how many were removed and the list's new revision. It fails
+// with ErrRevisionMismatch unless the list is still at revision expected
+// (nil skips the check).
+func (db *DB) DeleteMissingItems(listID int64, expected *int64) (int64, int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	if err := checkRevision(tx, listID, expected); err != nil {
+		return 0, 0, err
+	}
+	res, err := tx.Exec("DELETE FROM items WHERE list_id = ? AND missing_since != ''", listID)
+	if err != nil {
+		return 0, 0, err
+	}
+	removed, err := res.RowsAffected()
+	if err != nil {
+		return 0, 0, err
+	}
+	rev, err := commitRevision(tx, listID)
+	return removed, rev, err
+}
@@ -0,0 +1,153 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+)
+
+type Notification struct {
+	ID        int64  `json:"id"`
+	Kind      string `json:"kind"`
+	ListID    int64  `json:"list_id,omitempty"`
+	Message   string `json:"message"`
+	CreatedAt string `json:"created_at"`
+	Read      bool   `json:"read"`
+}
+
+func (db *DB) AddNotification(kind string, listID int64, message string) error {
+	var list sql.NullInt64
+	if listID != 0 {
+		list = sql.NullInt64{Int64: listID, Valid: true}
+	}
+	_, err := db.Exec("INSERT INTO notifications (kind, list_id, message) VALUES (?, ?, ?)", kind, list, message)
+	return err
+}
+
+func (db *DB) GetNotifications(unreadOnly bool) ([]Notification, error) {
+	query := "SELECT id, kind, list_id, message, created_at, read_at IS NOT NULL FROM notifications"
+	if unreadOnly {
+		query += " WHERE read_at IS NULL"
+	}
+	query += " ORDER BY id DESC LIMIT 100"
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := make([]Notification, 0)
+	for rows.Next() {
+		var n Notification
+		var listID sql.NullInt64
+		if err := rows.Scan(&n.ID, &n.Kind, &listID, &n.Message, &n.CreatedAt, &n.Read); err != nil {
+			return nil, err
+		}
+		n.ListID = listID.Int64
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
+
+func (db *DB) MarkNotificationRead(id int64) error {
+	_, err := db.Exec("UPDATE notifications SET read_at = CURRENT_TIMESTAMP WHERE id = ? AND read_at IS NULL", id)
+	return err
+}
+
+// Notification kinds, as recorded by AddNotification.
+const (
+	NotificationPendingMatched  = "pending_matched"
+	NotificationWantedAvailable = "wanted_available"
+	NotificationFeedMatched     = "feed_matched"
+	NotificationMediaRemoved    = "media_removed"
+	NotificationDigest          = "digest"
+	NotificationHostMigration   = "host_migration"
+	NotificationChannelInApp    = "in_app"
+)
+
+// NotificationKinds and NotificationChannels list what preferences can mute.
+var (
+	NotificationKinds    = []string{NotificationPendingMatched, NotificationWantedAvailable, NotificationFeedMatched, NotificationMediaRemoved, NotificationDigest, NotificationHostMigration}
+	NotificationChannels = []string{NotificationChannelInApp}
+)
+
+// NotificationPrefs are one user's notification preferences. Users aren't
+// accounts, just names the clients of a household pick for themselves.
+type NotificationPrefs struct {
+	User          string   `json:"user"`
+	MutedKinds    []string `json:"muted_kinds"`
+	MutedChannels []string `json:"muted_channels"`
+
+	// QuietStart and QuietEnd ("HH:MM", server local time) bound the quiet
+	// hours, which may span midnight. Both are empty when there are none.
+	QuietStart string `json:"quiet_start,omitempty"`
+	QuietEnd   string `json:"quiet_end,omitempty"`
+
+	UpdatedAt string `json:"updated_at,omitempty"`
+}
+
+const notificationPrefsColumns = "user, muted_kinds, muted_channels, quiet_start, quiet_end, updated_at"
+
+func scanNotificationPrefs(row scanner) (NotificationPrefs, error) {
+	var p NotificationPrefs
+	var kinds, channels string
+	if err := row.Scan(&p.User, &kinds, &channels, &p.QuietStart, &p.QuietEnd, &p.UpdatedAt); err != nil {
+		return p, err
+	}
+	p.MutedKinds, p.MutedChannels = splitList(kinds), splitList(channels)
+	return p, nil
+}
+
+// GetNotificationPrefs returns user's preferences, or nil if they never set
+// any.
+func (db *DB) GetNotificationPrefs(user string) (*NotificationPrefs, error) {
+	p, err := scanNotificationPrefs(db.QueryRow("SELECT "+notificationPrefsColumns+" FROM notification_prefs WHERE user = ?", user))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// GetAllNotificationPrefs returns every user's preferences by name.
+func (db *DB) GetAllNotificationPrefs() ([]NotificationPrefs, error) {
+	rows, err := db.Query("SELECT " + notificationPrefsColumns + " FROM notification_prefs ORDER BY user COLLATE NOCASE")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prefs := []NotificationPrefs{}
+	for rows.Next() {
+		p, err := scanNotificationPrefs(rows)
+		if err != nil {
+			return nil, err
+		}
+		prefs = append(prefs, p)
+	}
+	return prefs, rows.Err()
+}
+
+// SetNotificationPrefs creates or replaces p.User's preferences.
+func (db *DB) SetNotificationPrefs(p NotificationPrefs) error {
+	_, err := db.Exec(`
+		INSERT INTO notification_prefs (user, muted_kinds, muted_channels, quiet_start, quiet_end, updated_at)
+		VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(user) DO UPDATE SET
+			muted_kinds = excluded.muted_kinds, muted_channels = excluded.muted_channels,
+			quiet_start = excluded.quiet_start, quiet_end = excluded.quiet_end, updated_at = excluded.updated_at`,
+		p.User, joinList(p.MutedKinds), joinList(p.MutedChannels), p.QuietStart, p.QuietEnd)
+	return err
+}
+
+// DeleteNotificationPrefs removes user's preferences, returning
+// ErrPrefsNotFound if they had none.
+func (db *DB) DeleteNotificationPrefs(user string) error {
+	res, err := db.Exec("DELETE FROM notification_prefs WHERE user = ?", user)
+	if err != nil {
+		return err
+	}
+	return requireRow(res, ErrPrefsNotFound)
+}
@@ -1,10 +1,21 @@
 package database
 
 import (
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
 	"strings"
+	"time"
+
+	"whats-next/internal/kodi"
+	"whats-next/internal/media"
+	"whats-next/internal/outbound"
+	"whats-next/internal/storage"
 )
 
 // Data structs related to DB
@@ -16,28 +27,271 @@ type List struct {
 	KodiHost    string `json:"kodi_host"`
 	Username    string `json:"username"`
 	Password    string `json:"password"`
+
+	// Backend is the media server kodi_host points at: "kodi" (the
+	// default) or "jellyfin". For Jellyfin, kodi_host is the server's URL,
+	// username the Jellyfin user and password an API key.
+	Backend string `json:"backend,omitempty"`
+
+	// InheritsHost is set when the list has no kodi_host of its own and uses
+	// its group's host and credentials instead.
+	InheritsHost bool `json:"inherits_host"`
+
+	// TLSSkipVerify and CACert apply to an https:// kodi_host: the first
+	// accepts any certificate, the second names a PEM bundle of extra CAs
+	// to trust, e.g. for a self-signed reverse proxy.
+	TLSSkipVerify bool   `json:"tls_skip_verify,omitempty"`
+	CACert        string `json:"ca_cert,omitempty"`
+
+	// RPCTimeout and ImageTimeout bound a JSON-RPC call and an image
+	// download from the list's Kodi host, e.g. "30s" for a slow Raspberry
+	// Pi, and SyncConcurrency is how many items a sync downloads artwork for
+	// at once. Unset, they default to 10s, 30s and 8.
+	RPCTimeout      string `json:"rpc_timeout,omitempty"`
+	ImageTimeout    string `json:"image_timeout,omitempty"`
+	SyncConcurrency int    `json:"sync_concurrency,omitempty"`
+
+	// OnWatched is what happens to items once Kodi reports them watched:
+	// empty keeps them, "remove" deletes them and "archive" moves them to the
+	// list's archive.
+	OnWatched string `json:"on_watched,omitempty"`
+
+	// ArtPreference lists the Kodi art types to show on the list's cards in
+	// order of preference, e.g. ["banner", "poster"]. The poster, then thumb,
+	// is used when none is available.
+	ArtPreference []string `json:"art_preference,omitempty"`
+
+	// Fuzzy tunes how live searches for the list rank titles, over the
+	// global fuzzy settings.
+	Fuzzy *kodi.FuzzyOptions `json:"fuzzy,omitempty"`
+
+	// Revision changes whenever the list's items are added, removed or
+	// reordered. Clients send it back as If-Match to detect concurrent edits.
+	Revision int64 `json:"revision"`
+
+	// ArchivedAt is when the list was archived, hiding it from the list
+	// views; empty for lists in use. Archiving keeps everything on it.
+	ArchivedAt string `json:"archived_at,omitempty"`
+}
+
+// MaxSyncConcurrency caps a list's sync_concurrency.
+const MaxSyncConcurrency = 64
+
+// Backends a list can be served by.
+const (
+	BackendKodi     = "kodi"
+	BackendJellyfin = "jellyfin"
+)
+
+// ArtTypes are the Kodi art types a list may prefer.
+var ArtTypes = []string{"poster", "thumb", "banner", "landscape", "fanart", "clearlogo", "clearart"}
+
+// Group holds the Kodi connection shared by every list in the group that
+// doesn't define its own kodi_host.
+type Group struct {
+	ID       int64  `json:"id,omitempty"`
+	Name     string `json:"group_name"`
+	KodiHost string `json:"kodi_host"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+
+	// SortOrder places the group among the others, e.g. as UI tabs. It is
+	// set through the API, never from config.json.
+	SortOrder int `json:"sort_order,omitempty"`
 }
 
 type Config struct {
-	Lists    []List `json:"lists"`
-	Subtitle string `json:"subtitle"`
-	Footer   string `json:"footer"`
+	Groups   []Group `json:"groups"`
+	Lists    []List  `json:"lists"`
+	Subtitle string  `json:"subtitle"`
+	Footer   string  `json:"footer"`
+
+	// FrameAncestors lists origins allowed to embed the UI in an iframe,
+	// e.g. "https://organizr.example.com".
+	FrameAncestors []string `json:"frame_ancestors"`
+
+	// ShowRefreshInterval controls how often show episode/watched counters
+	// are refreshed from Kodi, e.g. "15m". Defaults to 30m; "0" disables.
+	ShowRefreshInterval string `json:"show_refresh_interval,omitempty"`
+
+	// FeedRefreshInterval controls how often list feeds are checked for new
+	// titles, e.g. "6h". Defaults to 1h; "0" disables.
+	FeedRefreshInterval string `json:"feed_refresh_interval,omitempty"`
+
+	// HistoryRefreshInterval controls how often plays are pulled from Kodi
+	// into the viewing history, e.g. "1h". Defaults to 15m; "0" disables.
+	HistoryRefreshInterval string `json:"history_refresh_interval,omitempty"`
+
+	// RemoteListInterval controls how often lists mirrored from other
+	// instances are polled for changes, e.g. "5m". Defaults to 15m; "0"
+	// disables.
+	RemoteListInterval string `json:"remote_list_interval,omitempty"`
+
+	// FullSyncInterval controls how often a library sync rebuilds the whole
+	// cache; syncs in between only fetch what Kodi added since the last
+	// one. Defaults to 24h; "0" makes every sync a full one.
+	FullSyncInterval string `json:"full_sync_interval,omitempty"`
+
+	// KodiEventPort is the port of Kodi's TCP JSON-RPC interface, which
+	// pushes library and player notifications. Defaults to 9090; -1
+	// disables the subscription.
+	KodiEventPort int `json:"kodi_event_port,omitempty"`
+
+	// KodiWebhookSecret enables POST /api/kodi/events, through which a Kodi
+	// addon can forward notifications when the TCP interface is unreachable.
+	// Requests must carry the same secret.
+	KodiWebhookSecret string `json:"kodi_webhook_secret,omitempty"`
+
+	// KodiPageSize is how many movies or shows are fetched from Kodi per
+	// request during a sync. Defaults to 500.
+	KodiPageSize int `json:"kodi_page_size,omitempty"`
+
+	// KodiRetry tunes how failed Kodi calls are retried and when a host is
+	// treated as down.
+	KodiRetry KodiRetryConfig `json:"kodi_retry"`
+
+	// Digest schedules a weekly summary of list activity.
+	Digest DigestConfig `json:"digest"`
+
+	// PosterStorage selects where poster images are kept (local disk by
+	// default, or an S3-compatible bucket).
+	PosterStorage storage.Config `json:"poster_storage"`
+
+	// ExternalAPIs sets request budgets and response caching for
+	// third-party web APIs by host, adding to or replacing the built-in
+	// budgets for TMDB, OMDb and Trakt.
+	ExternalAPIs map[string]outbound.Budget `json:"external_apis,omitempty"`
+
+	// UsageStats counts which endpoints and features are used, kept in the
+	// local database for GET /api/admin/usage and never sent anywhere.
+	// Defaults to on; false disables counting.
+	UsageStats *bool `json:"usage_stats,omitempty"`
+
+	// TextureCache downloads artwork from Kodi's texture cache, the
+	// thumbnails it already decoded and sized down, where the host has them.
+	// Defaults to on; false always fetches the original artwork.
+	TextureCache *bool `json:"texture_cache,omitempty"`
+
+	// Fuzzy tunes how titles fetched live from a media server are ranked
+	// for a search. Lists can override it with their own fuzzy settings.
+	Fuzzy kodi.FuzzyOptions `json:"fuzzy"`
+}
+
+// DigestConfig schedules the weekly digest and says where it goes. It is
+// off unless Notify is set or an SMTP host is configured.
+type DigestConfig struct {
+	// Day and Hour (server local time) pick when the digest is sent;
+	// defaults to "monday" at 8.
+	Day  string `json:"day,omitempty"`
+	Hour *int   `json:"hour,omitempty"`
+
+	// Notify records the digest as an in-app notification.
+	Notify bool       `json:"notify,omitempty"`
+	SMTP   SMTPConfig `json:"smtp"`
+}
+
+// SMTPConfig is the mail server the digest is sent through.
+type SMTPConfig struct {
+	Host     string   `json:"host,omitempty"`
+	Port     int      `json:"port,omitempty"` // defaults to 587
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from,omitempty"`
+	To       []string `json:"to,omitempty"`
+}
+
+// KodiRetryConfig overrides the default retry policy for Kodi calls.
+// Durations are strings such as "500ms"; unset fields keep their defaults.
+type KodiRetryConfig struct {
+	// Attempts is the total number of tries for a read; 1 disables retries.
+	Attempts  int    `json:"attempts,omitempty"`
+	BaseDelay string `json:"base_delay,omitempty"`
+	MaxDelay  string `json:"max_delay,omitempty"`
+
+	// BreakerThreshold consecutive failures make calls to a host fail fast
+	// for BreakerCooldown; -1 disables the breaker.
+	BreakerThreshold int    `json:"breaker_threshold,omitempty"`
+	BreakerCooldown  string `json:"breaker_cooldown,omitempty"`
 }
 
 type Item struct {
 	ID           int64   `json:"id"`
 	ListID       int64   `json:"list_id"`
 	KodiID       int     `json:"kodi_id"`
-	MediaType    string  `json:"media_type"` // movie, episode, show, season
+	MediaType    string  `json:"media_type"` // movie, episode, show, season, set, file
 	Title        string  `json:"title"`
 	Year         int     `json:"year"`
 	Poster       string  `json:"poster_path"`
-	Runtime      int     `json:"runtime"`
+	Runtime      int     `json:"runtime"` // seconds
 	EpisodeCount int     `json:"episode_count"`
 	Season       int     `json:"season"`
 	Rating       float64 `json:"rating"`
 	SortOrder    int     `json:"sort_order"`
 	AddedAt      string  `json:"added_at"`
+
+	AudioLanguages    []string `json:"audio_languages"`
+	SubtitleLanguages []string `json:"subtitle_languages"`
+
+	// Wanted items are free-form titles that aren't in the Kodi library yet.
+	// They have no kodi_id until a later sync finds a match.
+	Wanted bool `json:"wanted"`
+
+	// OriginalPoster holds the Kodi artwork while a custom upload overrides
+	// poster_path. Empty when no custom poster is set.
+	OriginalPoster string `json:"original_poster_path,omitempty"`
+
+	RuntimeFormatted string `json:"runtime_formatted"`
+
+	WatchedEpisodes int `json:"watched_episodes"`
+
+	// Watched is set when the item was marked watched through the API or
+	// Kodi reported a playcount on the last sync.
+	Watched bool `json:"watched"`
+
+	Playcount      int    `json:"playcount"`
+	LastPlayed     string `json:"last_played"`
+	ResumePosition int    `json:"resume_position"` // seconds
+
+	// ArchivedAt is set once a watched item was moved to the list's archive.
+	ArchivedAt string `json:"archived_at,omitempty"`
+
+	// SectionID places the item under one of its list's sections; zero
+	// leaves it above the first section.
+	SectionID int64 `json:"section_id,omitempty"`
+
+	// MissingSince is set when a sync found the item gone from the Kodi
+	// library, and cleared if it comes back.
+	MissingSince string `json:"missing_since,omitempty"`
+
+	// IMDbID and TMDbID are the external IDs Kodi's scrapers recorded for
+	// the item's media, empty when unknown or for wanted items.
+	IMDbID string `json:"imdb_id,omitempty"`
+	TMDbID string `json:"tmdb_id,omitempty"`
+
+	// Resolution (e.g. 1080) and HDRType come from the video stream details
+	// Kodi reported; Quality renders them as a badge such as "2160p HDR".
+	Resolution int    `json:"resolution,omitempty"`
+	HDRType    string `json:"hdr_type,omitempty"`
+	Quality    string `json:"quality,omitempty"`
+
+	// Art maps art types such as "fanart" or "clearlogo" to locally stored
+	// copies, for the item's library movie or show.
+	Art map[string]string `json:"art,omitempty"`
+
+	// Episode items record their episode number (with Season) and the show
+	// they belong to; EpisodeCode renders them as "S03E05".
+	Episode     int    `json:"episode,omitempty"`
+	TVShowID    int    `json:"tvshow_id,omitempty"`
+	ShowTitle   string `json:"show_title,omitempty"`
+	EpisodeCode string `json:"episode_code,omitempty"`
+
+	// FilePath is the path of a file item, a video from one of Kodi's
+	// sources that isn't in the library. File items have no kodi_id and are
+	// left alone by syncs.
+	FilePath string `json:"file_path,omitempty"`
+
+	// Artist names the artists of an album item.
+	Artist string `json:"artist,omitempty"`
 }
 
 type CachedItem struct {
@@ -51,10 +305,103 @@ type CachedItem struct {
 	EpisodeCount int     `json:"episode_count"`
 	Rating       float64 `json:"rating"`
 	Plot         string  `json:"plot"`
+
+	AudioLanguages    []string `json:"audio_languages"`
+	SubtitleLanguages []string `json:"subtitle_languages"`
+
+	WatchedEpisodes int `json:"watched_episodes"`
+
+	Genres []string `json:"genres"`
+
+	// Playback state as last synced from Kodi. ResumePosition is in seconds
+	// and zero when there's nothing to resume.
+	Playcount      int    `json:"playcount"`
+	LastPlayed     string `json:"last_played"`
+	ResumePosition int    `json:"resume_position"`
+
+	// Art maps Kodi art types to their image:// URIs.
+	Art map[string]string `json:"art,omitempty"`
+
+	IMDbID string `json:"imdb_id,omitempty"`
+	TMDbID string `json:"tmdb_id,omitempty"`
+
+	Resolution int    `json:"resolution,omitempty"`
+	HDRType    string `json:"hdr_type,omitempty"`
+	Quality    string `json:"quality,omitempty"`
+
+	// SetID and SetTitle name the Kodi movie set a movie belongs to.
+	SetID    int    `json:"set_id,omitempty"`
+	SetTitle string `json:"set_title,omitempty"`
+
+	// Cast holds the top-billed actors' names.
+	Cast []string `json:"cast,omitempty"`
+
+	// Artist names an album's artists.
+	Artist string `json:"artist,omitempty"`
+}
+
+// SearchFilter narrows library cache searches beyond the title query.
+type SearchFilter struct {
+	AudioLanguage    string // ISO 639-2 code, e.g. "eng"
+	SubtitleLanguage string
+	// Plot also matches items whose plot contains every word of the query.
+	// Title matches still rank first.
+	Plot bool
+
+	// Genres requires every listed genre; MaxRuntime (seconds) keeps items
+	// with a known runtime no longer than it, and MinResolution (e.g. 1080)
+	// those with a known resolution of at least it.
+	Genres        []string
+	MaxRuntime    int
+	MinResolution int
+
+	// Exclusions rule items out entirely: any of the genres (case-insensitive),
+	// anything already on one of the lists, and with ExcludeWatched, fully
+	// watched shows and items marked watched. Only lists sharing the Kodi
+	// host count, since Kodi IDs differ between hosts.
+	ExcludeGenres  []string
+	ExcludeLists   []int64
+	ExcludeWatched bool
+}
+
+// Language and genre lists are stored comma-separated so they can be matched
+// with a simple LIKE against ",eng," style patterns.
+func joinList(langs []string) string {
+	return strings.Join(langs, ",")
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, ",")
+}
+
+// listColumns reads a list through resolved_lists, so KodiHost, Username and
+// Password are the connection the list uses, its own or its group's.
+const listColumns = "id, group_name, name, content_type, effective_host, resolved_username, resolved_password, inherits_host, on_watched, art_preference, revision, tls_skip_verify, ca_cert, rpc_timeout, image_timeout, sync_concurrency, backend, fuzzy, archived_at"
+
+func scanList(row scanner) (List, error) {
+	var l List
+	var contentType sql.NullString
+	var artPreference, fuzzy string
+	if err := row.Scan(&l.ID, &l.GroupName, &l.Name, &contentType, &l.KodiHost, &l.Username, &l.Password, &l.InheritsHost, &l.OnWatched, &artPreference, &l.Revision, &l.TLSSkipVerify, &l.CACert, &l.RPCTimeout, &l.ImageTimeout, &l.SyncConcurrency, &l.Backend, &fuzzy, &l.ArchivedAt); err != nil {
+		return l, err
+	}
+	l.ContentType = contentType.String
+	if artPreference != "" {
+		l.ArtPreference = splitList(artPreference)
+	}
+	if fuzzy != "" {
+		if err := json.Unmarshal([]byte(fuzzy), &l.Fuzzy); err != nil {
+			return l, err
+		}
+	}
+	return l, nil
 }
 
 func (db *DB) GetAllLists() ([]List, error) {
-	rows, err := db.Query("SELECT id, group_name, name, content_type, kodi_host, username, password FROM lists ORDER BY id ASC")
+	rows, err := db.Query("SELECT " + listColumns + " FROM resolved_lists ORDER BY id ASC")
 	if err != nil {
 		return nil, err
 	}
@@ -62,12 +409,10 @@ func (db *DB) GetAllLists() ([]List, error) {
 
 	var lists []List
 	for rows.Next() {
-		var l List
-		var contentType sql.NullString
-		if err := rows.Scan(&l.ID, &l.GroupName, &l.Name, &contentType, &l.KodiHost, &l.Username, &l.Password); err != nil {
+		l, err := scanList(rows)
+		if err != nil {
 			return nil, err
 		}
-		l.ContentType = contentType.String
 		lists = append(lists, l)
 	}
 	return lists, nil
@@ -79,27 +424,70 @@ func (db *DB) SyncLists(lists []List) error {
 		return err
 	}
 	defer tx.Rollback()
+	if err := syncLists(tx, lists); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
 
+func syncLists(tx *sql.Tx, lists []List) error {
 	// Match list names case-insensitively so config casing changes don't create duplicates.
 	// We still store the name exactly as provided in config (display should match config).
-	stmtFind, err := tx.Prepare("SELECT id FROM lists WHERE group_name = ? AND lower(name) = lower(?) ORDER BY id ASC LIMIT 1")
+	stmtFind, err := tx.Prepare("SELECT id, effective_host, credentials_override, config_credentials FROM lists WHERE group_name = ? AND lower(name) = lower(?) ORDER BY id ASC LIMIT 1")
 	if err != nil {
 		return err
 	}
 	defer stmtFind.Close()
 
-	stmtUpdate, err := tx.Prepare("UPDATE lists SET name=?, kodi_host=?, username=?, password=?, content_type=? WHERE id=?")
+	stmtUpdate, err := tx.Prepare("UPDATE lists SET name=?, kodi_host=?, effective_host=?, username=?, password=?, content_type=?, inherits_host=?, credentials_override=0, config_credentials=?, on_watched=?, art_preference=?, tls_skip_verify=?, ca_cert=?, rpc_timeout=?, image_timeout=?, sync_concurrency=?, backend=?, fuzzy=? WHERE id=?")
 	if err != nil {
 		return err
 	}
 	defer stmtUpdate.Close()
 
-	stmtInsert, err := tx.Prepare("INSERT INTO lists (group_name, name, content_type, kodi_host, username, password) VALUES (?, ?, ?, ?, ?, ?)")
+	stmtUpdateKeepCreds, err := tx.Prepare("UPDATE lists SET name=?, content_type=?, on_watched=?, art_preference=?, tls_skip_verify=?, ca_cert=?, rpc_timeout=?, image_timeout=?, sync_concurrency=?, backend=?, fuzzy=? WHERE id=?")
+	if err != nil {
+		return err
+	}
+	defer stmtUpdateKeepCreds.Close()
+
+	stmtInsert, err := tx.Prepare("INSERT INTO lists (group_name, name, content_type, kodi_host, effective_host, username, password, inherits_host, config_credentials, on_watched, art_preference, tls_skip_verify, ca_cert, rpc_timeout, image_timeout, sync_concurrency, backend, fuzzy) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
 	if err != nil {
 		return err
 	}
 	defer stmtInsert.Close()
+
+	stmtGroup, err := tx.Prepare("SELECT kodi_host, username, password FROM groups WHERE name = ?")
+	if err != nil {
+		return err
+	}
+	defer stmtGroup.Close()
+
+	stmtEnsureGroup, err := tx.Prepare("INSERT OR IGNORE INTO groups (name, sort_order) SELECT ?, COALESCE(MAX(sort_order), -1) + 1 FROM groups")
+	if err != nil {
+		return err
+	}
+	defer stmtEnsureGroup.Close()
 	for _, l := range lists {
+		if _, err := stmtEnsureGroup.Exec(l.GroupName); err != nil {
+			return err
+		}
+
+		// Lists without their own kodi_host inherit the group's connection.
+		// It stays on the group, and the list's own columns are left empty;
+		// only the host it resolves to is kept, as effective_host, so host
+		// lookups stay indexed.
+		l.InheritsHost = l.KodiHost == ""
+		stored := l
+		if l.InheritsHost {
+			if err := stmtGroup.QueryRow(l.GroupName).Scan(&l.KodiHost, &l.Username, &l.Password); err != nil && err != sql.ErrNoRows {
+				return err
+			}
+			if l.KodiHost == "" {
+				return fmt.Errorf("list %q (group %q) has no kodi_host and its group defines none", l.Name, l.GroupName)
+			}
+		}
+
 		// Default content_type if missing in config
 		if l.ContentType == "" {
 			if strings.EqualFold(l.Name, "tv") {
@@ -110,31 +498,201 @@ func (db *DB) SyncLists(lists []List) error {
 			slog.Warn("List missing content_type, defaulting", "list", l.Name, "group", l.GroupName, "defaulted_to", l.ContentType)
 		}
 
-		// Validate content_type: only "movie" or "tv" are allowed
-		if l.ContentType != "movie" && l.ContentType != "tv" {
-			return fmt.Errorf("invalid content_type %q for list %q (group %q): must be \"movie\" or \"tv\"", l.ContentType, l.Name, l.GroupName)
+		l.Backend = strings.ToLower(strings.TrimSpace(l.Backend))
+		if l.Backend == "" {
+			l.Backend = BackendKodi
+		}
+
+		if err := validateListSettings(l); err != nil {
+			return err
+		}
+		fuzzy := ""
+		if l.Fuzzy != nil {
+			b, err := json.Marshal(l.Fuzzy)
+			if err != nil {
+				return err
+			}
+			fuzzy = string(b)
 		}
 		var id int64
-		err := stmtFind.QueryRow(l.GroupName, l.Name).Scan(&id)
+		var storedHost string
+		var override bool
+		var storedCreds string
+		fingerprint := credentialsFingerprint(l.KodiHost, l.Username, l.Password)
+		err := stmtFind.QueryRow(l.GroupName, l.Name).Scan(&id, &storedHost, &override, &storedCreds)
 		if err == nil {
-			if _, err := stmtUpdate.Exec(l.Name, l.KodiHost, l.Username, l.Password, l.ContentType, id); err != nil {
+			// Credentials rotated through the API survive restarts until the
+			// config's own connection details change.
+			if override && storedCreds == fingerprint {
+				if _, err := stmtUpdateKeepCreds.Exec(l.Name, l.ContentType, l.OnWatched, joinList(l.ArtPreference), l.TLSSkipVerify, l.CACert, l.RPCTimeout, l.ImageTimeout, l.SyncConcurrency, l.Backend, fuzzy, id); err != nil {
+					return err
+				}
+				continue
+			}
+			if _, err := stmtUpdate.Exec(l.Name, stored.KodiHost, l.KodiHost, stored.Username, stored.Password, l.ContentType, l.InheritsHost, fingerprint, l.OnWatched, joinList(l.ArtPreference), l.TLSSkipVerify, l.CACert, l.RPCTimeout, l.ImageTimeout, l.SyncConcurrency, l.Backend, fuzzy, id); err != nil {
+				return err
+			}
+			if err := recordHostChange(tx, id, storedHost, l.KodiHost); err != nil {
 				return err
 			}
 		} else {
-			if _, err := stmtInsert.Exec(l.GroupName, l.Name, l.ContentType, l.KodiHost, l.Username, l.Password); err != nil {
+			if _, err := stmtInsert.Exec(l.GroupName, l.Name, l.ContentType, stored.KodiHost, l.KodiHost, stored.Username, stored.Password, l.InheritsHost, fingerprint, l.OnWatched, joinList(l.ArtPreference), l.TLSSkipVerify, l.CACert, l.RPCTimeout, l.ImageTimeout, l.SyncConcurrency, l.Backend, fuzzy); err != nil {
 				return err
 			}
 		}
 	}
-	return tx.Commit()
+	return nil
+}
+
+// CreateList adds a list as if config.json defined it and returns it. It
+// returns ErrDuplicateList if the group already has a list by that name,
+// and ErrInvalidList for settings SyncLists refuses.
+func (db *DB) CreateList(l List) (*List, error) {
+	find := "SELECT id FROM lists WHERE group_name = ? AND lower(name) = lower(?) ORDER BY id LIMIT 1"
+	var id int64
+	err := db.QueryRow(find, l.GroupName, l.Name).Scan(&id)
+	if err == nil {
+		return nil, ErrDuplicateList
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err := db.SyncLists([]List{l}); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidList, err)
+	}
+	if err := db.QueryRow(find, l.GroupName, l.Name).Scan(&id); err != nil {
+		return nil, err
+	}
+	return db.GetList(id)
+}
+
+// validateListSettings checks the settings a list may be configured with.
+func validateListSettings(l List) error {
+	// Validate content_type: only "movie", "tv" or "music" are allowed
+	if l.ContentType != "movie" && l.ContentType != "tv" && l.ContentType != "music" {
+		return fmt.Errorf("invalid content_type %q for list %q (group %q): must be \"movie\", \"tv\" or \"music\"", l.ContentType, l.Name, l.GroupName)
+	}
+	if l.Backend != "" && l.Backend != BackendKodi && l.Backend != BackendJellyfin {
+		return fmt.Errorf("invalid backend %q for list %q (group %q): must be %q or %q", l.Backend, l.Name, l.GroupName, BackendKodi, BackendJellyfin)
+	}
+	if l.Backend == BackendJellyfin && l.ContentType == "music" {
+		return fmt.Errorf("list %q (group %q): music lists need a Kodi host", l.Name, l.GroupName)
+	}
+	if l.OnWatched != "" && l.OnWatched != "remove" && l.OnWatched != "archive" {
+		return fmt.Errorf("invalid on_watched %q for list %q (group %q): must be \"remove\" or \"archive\"", l.OnWatched, l.Name, l.GroupName)
+	}
+	for _, art := range l.ArtPreference {
+		if !slices.Contains(ArtTypes, art) {
+			return fmt.Errorf("invalid art_preference %q for list %q (group %q): must be one of %s", art, l.Name, l.GroupName, strings.Join(ArtTypes, ", "))
+		}
+	}
+	for _, t := range [][2]string{{"rpc_timeout", l.RPCTimeout}, {"image_timeout", l.ImageTimeout}} {
+		if t[1] == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(t[1]); err != nil || d <= 0 {
+			return fmt.Errorf("invalid %s %q for list %q (group %q): must be a positive duration such as \"30s\"", t[0], t[1], l.Name, l.GroupName)
+		}
+	}
+	if l.SyncConcurrency < 0 || l.SyncConcurrency > MaxSyncConcurrency {
+		return fmt.Errorf("invalid sync_concurrency %d for list %q (group %q): must be between 1 and %d", l.SyncConcurrency, l.Name, l.GroupName, MaxSyncConcurrency)
+	}
+	if l.Fuzzy != nil {
+		if err := l.Fuzzy.Validate(); err != nil {
+			return fmt.Errorf("invalid fuzzy for list %q (group %q): %w", l.Name, l.GroupName, err)
+		}
+	}
+	return nil
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx.
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// scanner is satisfied by both *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+const itemColumns = "id, list_id, kodi_id, media_type, title, year, poster_path, runtime, episode_count, season, rating, sort_order, added_at, audio_languages, subtitle_languages, wanted, original_poster_path, watched_episodes, watched, playcount, last_played, resume_position, archived_at, section_id, missing_since, imdb_id, tmdb_id, resolution, hdr_type, episode, tvshow_id, show_title, file_path, artist"
+
+func scanItem(row scanner) (Item, error) {
+	var i Item
+	var kodiID, sectionID sql.NullInt64
+	var audio, subtitles string
+	if err := row.Scan(&i.ID, &i.ListID, &kodiID, &i.MediaType, &i.Title, &i.Year, &i.Poster, &i.Runtime, &i.EpisodeCount, &i.Season, &i.Rating, &i.SortOrder, &i.AddedAt, &audio, &subtitles, &i.Wanted, &i.OriginalPoster, &i.WatchedEpisodes, &i.Watched, &i.Playcount, &i.LastPlayed, &i.ResumePosition, &i.ArchivedAt, &sectionID, &i.MissingSince, &i.IMDbID, &i.TMDbID, &i.Resolution, &i.HDRType, &i.Episode, &i.TVShowID, &i.ShowTitle, &i.FilePath, &i.Artist); err != nil {
+		return i, err
+	}
+	i.KodiID = int(kodiID.Int64)
+	i.SectionID = sectionID.Int64
+	i.RuntimeFormatted = media.FormatRuntime(i.Runtime)
+	i.Quality = media.FormatQuality(i.Resolution, i.HDRType)
+	if i.MediaType == "episode" {
+		i.EpisodeCode = media.FormatEpisode(i.Season, i.Episode)
+	}
+	i.AudioLanguages = splitList(audio)
+	i.SubtitleLanguages = splitList(subtitles)
+	return i, nil
 }
 
+// insertItem adds i and returns its ID, or ErrDuplicateItem if the list
+// already holds the same library item.
+func insertItem(ex execer, i Item) (int64, error) {
+	// Wanted and file items are stored with a NULL kodi_id so they never
+	// collide on the library item unique indexes; file items are unique by
+	// path instead. Only seasons are told apart by season.
+	var kodiID sql.NullInt64
+	if !i.Wanted && i.MediaType != "file" {
+		kodiID = sql.NullInt64{Int64: int64(i.KodiID), Valid: true}
+	}
+	if i.MediaType != "season" && i.MediaType != "episode" {
+		i.Season = 0
+	}
+	res, err := ex.Exec(`
+		INSERT INTO items (list_id, kodi_id, media_type, title, year, poster_path, runtime, episode_count, season, rating, sort_order, audio_languages, subtitle_languages, wanted, watched_episodes, watched, playcount, last_played, resume_position, section_id, imdb_id, tmdb_id, resolution, hdr_type, episode, tvshow_id, show_title, file_path, artist)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		i.ListID, kodiID, i.MediaType, i.Title, i.Year, i.Poster, i.Runtime, i.EpisodeCount, i.Season, i.Rating, i.SortOrder, joinList(i.AudioLanguages), joinList(i.SubtitleLanguages), i.Wanted, i.WatchedEpisodes, i.Watched, i.Playcount, i.LastPlayed, i.ResumePosition, nullableID(i.SectionID), i.IMDbID, i.TMDbID, i.Resolution, i.HDRType, i.Episode, i.TVShowID, i.ShowTitle, i.FilePath, i.Artist)
+	if isUniqueViolation(err) {
+		return 0, ErrDuplicateItem
+	} else if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// listExists reports ErrListNotFound for unknown list IDs.
+func (db *DB) listExists(id int64) error {
+	var one int
+	err := db.QueryRow("SELECT 1 FROM lists WHERE id = ?", id).Scan(&one)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrListNotFound
+	}
+	return err
+}
+
+// GetItems returns the items on a list, leaving out archived ones. Items
+// without a section come first, then each section in its own order.
 func (db *DB) GetItems(listID int64) ([]Item, error) {
-	rows, err := db.Query(`
-		SELECT id, list_id, kodi_id, media_type, title, year, poster_path, runtime, episode_count, season, rating, sort_order, added_at 
-		FROM items 
-		WHERE list_id = ? 
-		ORDER BY sort_order ASC, added_at DESC`, listID)
+	return db.getItems(listID, `
+		SELECT `+prefixColumns("i", itemColumns)+`
+		FROM items i
+		LEFT JOIN sections s ON s.id = i.section_id
+		WHERE i.list_id = ? AND i.archived_at = ''
+		ORDER BY s.id IS NOT NULL, s.sort_order ASC, s.id ASC, i.sort_order ASC, i.added_at DESC`)
+}
+
+// GetArchivedItems returns the archived items of a list, most recently
+// archived first.
+func (db *DB) GetArchivedItems(listID int64) ([]Item, error) {
+	return db.getItems(listID, `
+		SELECT `+itemColumns+`
+		FROM items
+		WHERE list_id = ? AND archived_at != ''
+		ORDER BY archived_at DESC, id DESC`)
+}
+
+func (db *DB) getItems(listID int64, query string) ([]Item, error) {
+	rows, err := db.Query(query, listID)
 	if err != nil {
 		return nil, err
 	}
@@ -142,16 +700,47 @@ func (db *DB) GetItems(listID int64) ([]Item, error) {
 
 	items := make([]Item, 0)
 	for rows.Next() {
-		var i Item
-		if err := rows.Scan(&i.ID, &i.ListID, &i.KodiID, &i.MediaType, &i.Title, &i.Year, &i.Poster, &i.Runtime, &i.EpisodeCount, &i.Season, &i.Rating, &i.SortOrder, &i.AddedAt); err != nil {
+		i, err := scanItem(rows)
+		if err != nil {
 			return nil, err
 		}
 		items = append(items, i)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// Distinguish an empty list from one that doesn't exist
+	if len(items) == 0 {
+		if err := db.listExists(listID); err != nil {
+			return nil, err
+		}
+		return items, nil
+	}
+	if err := db.attachArt(listID, items); err != nil {
+		return nil, err
+	}
 	return items, nil
 }
 
+// GetItem returns a single item by ID, or ErrItemNotFound if it doesn't exist.
+func (db *DB) GetItem(id int64) (*Item, error) {
+	i, err := scanItem(db.QueryRow("SELECT "+itemColumns+" FROM items WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrItemNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+// AddItem inserts an item, returning ErrListNotFound for an unknown list and
+// ErrDuplicateItem if the library item is already on it.
 func (db *DB) AddItem(i Item) (int64, error) {
+	if err := db.listExists(i.ListID); err != nil {
+		return 0, err
+	}
+
 	// Handle automatic positioning:
 	// -1 = add to top (shift all items down)
 	// 0 = add to bottom (use max + 1)
@@ -171,21 +760,12 @@ func (db *DB) AddItem(i Item) (int64, error) {
 		i.SortOrder = 0
 
 		// Insert the new item at the top within the same transaction
-		res, err := tx.Exec(`
-		INSERT OR IGNORE INTO items (list_id, kodi_id, media_type, title, year, poster_path, runtime, episode_count, season, rating, sort_order)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-			i.ListID, i.KodiID, i.MediaType, i.Title, i.Year, i.Poster, i.Runtime, i.EpisodeCount, i.Season, i.Rating, i.SortOrder)
+		lastID, err := insertItem(tx, i)
 		if err != nil {
 			_ = tx.Rollback()
 			return 0, fmt.Errorf("failed to insert item: %w", err)
 		}
 
-		lastID, err := res.LastInsertId()
-		if err != nil {
-			_ = tx.Rollback()
-			return 0, fmt.Errorf("failed to get last insert id: %w", err)
-		}
-
 		if err := tx.Commit(); err != nil {
 			return 0, fmt.Errorf("failed to commit transaction: %w", err)
 		}
@@ -201,14 +781,7 @@ func (db *DB) AddItem(i Item) (int64, error) {
 	}
 	// else: explicit position, use as-is
 
-	res, err := db.Exec(`
-		INSERT OR IGNORE INTO items (list_id, kodi_id, media_type, title, year, poster_path, runtime, episode_count, season, rating, sort_order)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		i.ListID, i.KodiID, i.MediaType, i.Title, i.Year, i.Poster, i.Runtime, i.EpisodeCount, i.Season, i.Rating, i.SortOrder)
-	if err != nil {
-		return 0, err
-	}
-	return res.LastInsertId()
+	return insertItem(db, i)
 }
 
 func (db *DB) GetMaxSortOrder(listID int64) (int, error) {
@@ -218,27 +791,74 @@ func (db *DB) GetMaxSortOrder(listID int64) (int, error) {
 }
 
 func (db *DB) DeleteItem(id int64) error {
-	_, err := db.Exec("DELETE FROM items WHERE id = ?", id)
-	return err
+	res, err := db.Exec("DELETE FROM items WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	return requireRow(res, ErrItemNotFound)
 }
 
-func (db *DB) UpdateItemOrder(id int64, sortOrder int) error {
-	_, err := db.Exec("UPDATE items SET sort_order = ? WHERE id = ?", sortOrder, id)
-	return err
+// requireRow returns notFound when an UPDATE or DELETE matched no rows.
+func requireRow(res sql.Result, notFound error) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return notFound
+	}
+	return nil
 }
 
 // Library Cache Operations
 
-func (db *DB) ClearLibraryCache(listID int64, mediaType string) error {
-	// Clear cache only for this list and media type to avoid interfering with
-	// other lists that may be syncing concurrently, even if they share the same Kodi host.
-	_, err := db.Exec(`
-		DELETE FROM library_cache 
-		WHERE list_id = ? 
-		AND media_type = ?`, listID, mediaType)
-	return err
+// hostLists selects the IDs of the lists sharing the Kodi host of the list
+// given as its parameter.
+const hostLists = "SELECT id FROM lists WHERE effective_host = (SELECT effective_host FROM lists WHERE id = ?)"
+
+// PruneLibraryCache removes cached items of mediaType on listID's Kodi host
+// whose Kodi ID isn't in keep, i.e. those a sync no longer found in the
+// library. It returns the number removed.
+func (db *DB) PruneLibraryCache(listID int64, mediaType string, keep map[int]bool) (int64, error) {
+	rows, err := db.Query("SELECT kodi_id FROM library_cache WHERE list_id IN ("+hostLists+") AND media_type = ?", listID, mediaType)
+	if err != nil {
+		return 0, err
+	}
+	var stale []interface{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if !keep[id] {
+			stale = append(stale, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	var removed int64
+	for ids := range slices.Chunk(stale, 500) {
+		n, err := rowsAffected(tx.Exec("DELETE FROM library_cache WHERE list_id IN ("+hostLists+") AND media_type = ? AND kodi_id IN ("+placeholders(len(ids))+")",
+			append([]interface{}{listID, mediaType}, ids...)...))
+		if err != nil {
+			return 0, err
+		}
+		removed += n
+	}
+	return removed, tx.Commit()
 }
 
+// AddToLibraryCache stores items in the library cache, replacing any copy
+// cached under another list on the same Kodi host.
 func (db *DB) AddToLibraryCache(items []CachedItem) error {
 	tx, err := db.Begin()
 	if err != nil {
@@ -246,16 +866,33 @@ func (db *DB) AddToLibraryCache(items []CachedItem) error {
 	}
 	defer tx.Rollback()
 
+	dedupe, err := tx.Prepare("DELETE FROM library_cache WHERE media_type = ? AND kodi_id = ? AND list_id != ? AND list_id IN (" + hostLists + ")")
+	if err != nil {
+		return err
+	}
+	defer dedupe.Close()
+
 	stmt, err := tx.Prepare(`
-		INSERT OR REPLACE INTO library_cache (list_id, kodi_id, media_type, title, year, poster_path, runtime, episode_count, rating, plot)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+		INSERT OR REPLACE INTO library_cache (list_id, kodi_id, media_type, title, year, poster_path, runtime, episode_count, rating, plot, audio_languages, subtitle_languages, watched_episodes, genres, playcount, last_played, resume_position, art, imdb_id, tmdb_id, resolution, hdr_type, set_id, set_title, cast_names, artist)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 
 	for _, i := range items {
-		_, err := stmt.Exec(i.ListID, i.KodiID, i.MediaType, i.Title, i.Year, i.Poster, i.Runtime, i.EpisodeCount, i.Rating, i.Plot)
+		art := ""
+		if len(i.Art) > 0 {
+			b, err := json.Marshal(i.Art)
+			if err != nil {
+				return err
+			}
+			art = string(b)
+		}
+		if _, err := dedupe.Exec(i.MediaType, i.KodiID, i.ListID, i.ListID); err != nil {
+			return err
+		}
+		_, err := stmt.Exec(i.ListID, i.KodiID, i.MediaType, i.Title, i.Year, i.Poster, i.Runtime, i.EpisodeCount, i.Rating, i.Plot, joinList(i.AudioLanguages), joinList(i.SubtitleLanguages), i.WatchedEpisodes, joinList(i.Genres), i.Playcount, i.LastPlayed, i.ResumePosition, art, i.IMDbID, i.TMDbID, i.Resolution, i.HDRType, i.SetID, i.SetTitle, strings.Join(i.Cast, "\n"), i.Artist)
 		if err != nil {
 			return err
 		}
@@ -263,19 +900,105 @@ func (db *DB) AddToLibraryCache(items []CachedItem) error {
 	return tx.Commit()
 }
 
-func (db *DB) SearchLibraryCache(listID int64, mediaType string, query string) ([]CachedItem, error) {
+// cacheColumns are the library_cache columns scanCachedItem reads after the
+// list ID, which callers select themselves (often as MAX(lc.list_id)).
+const cacheColumns = "lc.kodi_id, lc.media_type, lc.title, lc.year, lc.poster_path, lc.runtime, lc.episode_count, lc.rating, lc.plot, lc.audio_languages, lc.subtitle_languages, lc.watched_episodes, lc.genres, lc.playcount, lc.last_played, lc.resume_position, lc.art, lc.imdb_id, lc.tmdb_id, lc.resolution, lc.hdr_type, lc.set_id, lc.set_title, lc.cast_names, lc.artist"
+
+func scanCachedItem(row scanner) (CachedItem, error) {
+	var i CachedItem
+	var audio, subtitles, genres, art, cast string
+	if err := row.Scan(&i.ListID, &i.KodiID, &i.MediaType, &i.Title, &i.Year, &i.Poster, &i.Runtime, &i.EpisodeCount, &i.Rating, &i.Plot, &audio, &subtitles, &i.WatchedEpisodes, &genres, &i.Playcount, &i.LastPlayed, &i.ResumePosition, &art, &i.IMDbID, &i.TMDbID, &i.Resolution, &i.HDRType, &i.SetID, &i.SetTitle, &cast, &i.Artist); err != nil {
+		return i, err
+	}
+	if cast != "" {
+		i.Cast = strings.Split(cast, "\n")
+	}
+	i.AudioLanguages = splitList(audio)
+	i.SubtitleLanguages = splitList(subtitles)
+	i.Genres = splitList(genres)
+	i.Quality = media.FormatQuality(i.Resolution, i.HDRType)
+	if art != "" {
+		if err := json.Unmarshal([]byte(art), &i.Art); err != nil {
+			return i, fmt.Errorf("invalid cached art: %w", err)
+		}
+	}
+	return i, nil
+}
+
+func (db *DB) SearchLibraryCache(listID int64, mediaType string, query string, filter SearchFilter) ([]CachedItem, error) {
 	searchQuery := fmt.Sprintf("%%%s%%", query)
+	args := []interface{}{listID, mediaType, searchQuery}
+	match := "lc.title LIKE ?"
+	order := ""
+	if words := strings.Fields(query); filter.Plot && len(words) > 0 {
+		plot := make([]string, len(words))
+		for i, word := range words {
+			plot[i] = "lc.plot LIKE ?"
+			args = append(args, "%"+word+"%")
+		}
+		match = "(lc.title LIKE ? OR (" + strings.Join(plot, " AND ") + "))"
+		order = "ORDER BY MAX(lc.title LIKE ?) DESC"
+	}
+	var conditions strings.Builder
+	if filter.AudioLanguage != "" {
+		conditions.WriteString(" AND (',' || lc.audio_languages || ',') LIKE ?")
+		args = append(args, "%,"+strings.ToLower(filter.AudioLanguage)+",%")
+	}
+	if filter.SubtitleLanguage != "" {
+		conditions.WriteString(" AND (',' || lc.subtitle_languages || ',') LIKE ?")
+		args = append(args, "%,"+strings.ToLower(filter.SubtitleLanguage)+",%")
+	}
+	for _, genre := range filter.Genres {
+		conditions.WriteString(" AND (',' || lower(lc.genres) || ',') LIKE ?")
+		args = append(args, "%,"+strings.ToLower(genre)+",%")
+	}
+	if filter.MaxRuntime > 0 {
+		conditions.WriteString(" AND lc.runtime > 0 AND lc.runtime <= ?")
+		args = append(args, filter.MaxRuntime)
+	}
+	if filter.MinResolution > 0 {
+		conditions.WriteString(" AND lc.resolution >= ?")
+		args = append(args, filter.MinResolution)
+	}
+	for _, genre := range filter.ExcludeGenres {
+		conditions.WriteString(" AND (',' || lower(lc.genres) || ',') NOT LIKE ?")
+		args = append(args, "%,"+strings.ToLower(genre)+",%")
+	}
+	if len(filter.ExcludeLists) > 0 {
+		conditions.WriteString(` AND NOT EXISTS (
+			SELECT 1 FROM items ex
+			JOIN lists ex_list ON ex.list_id = ex_list.id
+			WHERE ex.list_id IN (` + placeholders(len(filter.ExcludeLists)) + `)
+			AND ex_list.effective_host = l_current.effective_host
+			AND ex.kodi_id = lc.kodi_id AND ex.media_type = lc.media_type)`)
+		for _, id := range filter.ExcludeLists {
+			args = append(args, id)
+		}
+	}
+	if filter.ExcludeWatched {
+		conditions.WriteString(` AND NOT (lc.episode_count > 0 AND lc.watched_episodes >= lc.episode_count)
+			AND NOT EXISTS (
+				SELECT 1 FROM items ex
+				JOIN lists ex_list ON ex.list_id = ex_list.id
+				WHERE ex_list.effective_host = l_current.effective_host
+				AND ex.kodi_id = lc.kodi_id AND ex.media_type = lc.media_type AND ex.watched = 1)`)
+	}
+	if order != "" {
+		args = append(args, searchQuery)
+	}
+
 	// Search across all lists that share the same Kodi host to leverage shared cache
 	rows, err := db.Query(`
-		SELECT MAX(lc.list_id), lc.kodi_id, lc.media_type, lc.title, lc.year, lc.poster_path, lc.runtime, lc.episode_count, lc.rating, lc.plot
+		SELECT MAX(lc.list_id), `+cacheColumns+`
 		FROM library_cache lc
 		JOIN lists l_cache ON lc.list_id = l_cache.id
 		JOIN lists l_current ON l_current.id = ?
-		WHERE l_cache.kodi_host = l_current.kodi_host 
+		WHERE l_cache.effective_host = l_current.effective_host 
 		AND lc.media_type = ? 
-		AND lc.title LIKE ?
+		AND `+match+conditions.String()+`
 		GROUP BY lc.kodi_id
-		LIMIT 50`, listID, mediaType, searchQuery)
+		`+order+`
+		LIMIT 50`, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -283,8 +1006,8 @@ func (db *DB) SearchLibraryCache(listID int64, mediaType string, query string) (
 
 	var results []CachedItem
 	for rows.Next() {
-		var i CachedItem
-		if err := rows.Scan(&i.ListID, &i.KodiID, &i.MediaType, &i.Title, &i.Year, &i.Poster, &i.Runtime, &i.EpisodeCount, &i.Rating, &i.Plot); err != nil {
+		i, err := scanCachedItem(rows)
+		if err != nil {
 			return nil, err
 		}
 		results = append(results, i)
@@ -292,6 +1015,64 @@ func (db *DB) SearchLibraryCache(listID int64, mediaType string, query string) (
 	return results, nil
 }
 
+// ExcludedKodiIDs returns the Kodi IDs of mediaType items that filter's list
+// and watched exclusions rule out for listID, for filtering results fetched
+// live from Kodi the same way SearchLibraryCache does.
+func (db *DB) ExcludedKodiIDs(listID int64, mediaType string, filter SearchFilter) (map[int]bool, error) {
+	excluded := map[int]bool{}
+	if len(filter.ExcludeLists) == 0 && !filter.ExcludeWatched {
+		return excluded, nil
+	}
+	inLists := "0"
+	args := []interface{}{listID, mediaType}
+	if len(filter.ExcludeLists) > 0 {
+		inLists = "i.list_id IN (" + placeholders(len(filter.ExcludeLists)) + ")"
+		for _, id := range filter.ExcludeLists {
+			args = append(args, id)
+		}
+	}
+	args = append(args, filter.ExcludeWatched)
+	rows, err := db.Query(`
+		SELECT DISTINCT i.kodi_id
+		FROM items i
+		JOIN lists l_item ON i.list_id = l_item.id
+		JOIN lists l_current ON l_current.id = ?
+		WHERE i.kodi_id IS NOT NULL AND i.media_type = ?
+		AND l_item.effective_host = l_current.effective_host
+		AND (`+inLists+` OR (? AND i.watched = 1))`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		excluded[id] = true
+	}
+	return excluded, rows.Err()
+}
+
+// GetCachedItem looks up a single library item in the cache shared by all
+// lists on the same Kodi host. It returns sql.ErrNoRows when the item has not
+// been synced yet.
+func (db *DB) GetCachedItem(listID int64, kodiID int, mediaType string) (*CachedItem, error) {
+	i, err := scanCachedItem(db.QueryRow(`
+		SELECT lc.list_id, `+cacheColumns+`
+		FROM library_cache lc
+		JOIN lists l_cache ON lc.list_id = l_cache.id
+		JOIN lists l_current ON l_current.id = ?
+		WHERE l_cache.effective_host = l_current.effective_host
+		AND lc.kodi_id = ?
+		AND lc.media_type = ?
+		LIMIT 1`, listID, kodiID, mediaType))
+	if err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
 func (db *DB) GetLibraryCacheCount(listID int64, mediaType string) (int, error) {
 	var count int
 	// Count items across all lists that share the same Kodi host
@@ -300,7 +1081,377 @@ func (db *DB) GetLibraryCacheCount(listID int64, mediaType string) (int, error)
 		FROM library_cache lc
 		JOIN lists l_cache ON lc.list_id = l_cache.id
 		JOIN lists l_current ON l_current.id = ?
-		WHERE l_cache.kodi_host = l_current.kodi_host 
+		WHERE l_cache.effective_host = l_current.effective_host 
 		AND lc.media_type = ?`, listID, mediaType).Scan(&count)
 	return count, err
 }
+
+// GetLibraryCache returns every cached item of mediaType on listID's Kodi
+// host, ordered by title.
+func (db *DB) GetLibraryCache(listID int64, mediaType string) ([]CachedItem, error) {
+	rows, err := db.Query(`
+		SELECT MAX(lc.list_id), `+cacheColumns+`
+		FROM library_cache lc
+		JOIN lists l_cache ON lc.list_id = l_cache.id
+		JOIN lists l_current ON l_current.id = ?
+		WHERE l_cache.effective_host = l_current.effective_host
+		AND lc.media_type = ?
+		GROUP BY lc.kodi_id
+		ORDER BY lc.title COLLATE NOCASE`, listID, mediaType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []CachedItem{}
+	for rows.Next() {
+		i, err := scanCachedItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, i)
+	}
+	return results, rows.Err()
+}
+
+// SyncItemPlayback copies the playback state cached for listID's sync to the
+// matching items on every list sharing its Kodi host, marking them watched
+// when Kodi reports a playcount. It returns the number of items changed.
+func (db *DB) SyncItemPlayback(listID int64, mediaType string) (int64, error) {
+	res, err := db.Exec(`
+		UPDATE items SET
+			playcount = lc.playcount,
+			last_played = lc.last_played,
+			resume_position = lc.resume_position,
+			watched = lc.playcount > 0
+		FROM library_cache lc
+		WHERE lc.list_id = ? AND lc.media_type = ?
+		AND items.kodi_id = lc.kodi_id AND items.media_type = lc.media_type
+		AND items.list_id IN (
+			SELECT l.id FROM lists l JOIN lists cur ON cur.id = ? WHERE l.effective_host = cur.effective_host
+		)
+		AND (items.playcount <> lc.playcount OR items.last_played <> lc.last_played
+			OR items.resume_position <> lc.resume_position OR items.watched <> (lc.playcount > 0))`,
+		listID, mediaType, listID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// SyncItemMetadata copies the external IDs, video quality and runtime
+// cached for listID's sync to the matching items on every list sharing its
+// Kodi host, so items pick up IDs Kodi reported later, upgraded files and
+// runtimes stored in the wrong unit by older versions. It returns the number
+// of items changed.
+func (db *DB) SyncItemMetadata(listID int64, mediaType string) (int64, error) {
+	res, err := db.Exec(`
+		UPDATE items SET imdb_id = lc.imdb_id, tmdb_id = lc.tmdb_id, resolution = lc.resolution, hdr_type = lc.hdr_type,
+			runtime = CASE WHEN lc.runtime > 0 THEN lc.runtime ELSE items.runtime END
+		FROM library_cache lc
+		WHERE lc.list_id = ? AND lc.media_type = ?
+		AND items.kodi_id = lc.kodi_id AND items.media_type = lc.media_type
+		AND items.list_id IN (
+			SELECT l.id FROM lists l JOIN lists cur ON cur.id = ? WHERE l.effective_host = cur.effective_host
+		)
+		AND (items.imdb_id <> lc.imdb_id OR items.tmdb_id <> lc.tmdb_id
+			OR items.resolution <> lc.resolution OR items.hdr_type <> lc.hdr_type
+			OR (lc.runtime > 0 AND items.runtime <> lc.runtime))`,
+		listID, mediaType, listID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// PlaybackState is the playback state Kodi reports for a library item.
+type PlaybackState struct {
+	KodiID         int
+	Playcount      int
+	LastPlayed     string
+	ResumePosition int
+}
+
+// UpdatePlayback refreshes the playback state of cached items of mediaType
+// and of the matching items on every list sharing listID's Kodi host. It
+// returns the number of list items that changed.
+func (db *DB) UpdatePlayback(listID int64, mediaType string, states []PlaybackState) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmtCache, err := tx.Prepare(`
+		UPDATE library_cache SET playcount = ?, last_played = ?, resume_position = ?
+		WHERE kodi_id = ? AND media_type = ?
+		AND list_id IN (
+			SELECT l_cache.id FROM lists l_cache
+			JOIN lists l_current ON l_current.id = ?
+			WHERE l_cache.effective_host = l_current.effective_host
+		)`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmtCache.Close()
+
+	stmtItems, err := tx.Prepare(`
+		UPDATE items SET playcount = ?, last_played = ?, resume_position = ?, watched = ? > 0
+		WHERE kodi_id = ? AND media_type = ?
+		AND (playcount != ? OR last_played != ? OR resume_position != ? OR watched != (? > 0))
+		AND list_id IN (
+			SELECT l_items.id FROM lists l_items
+			JOIN lists l_current ON l_current.id = ?
+			WHERE l_items.effective_host = l_current.effective_host
+		)`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmtItems.Close()
+
+	var changed int64
+	for _, p := range states {
+		if _, err := stmtCache.Exec(p.Playcount, p.LastPlayed, p.ResumePosition, p.KodiID, mediaType, listID); err != nil {
+			return 0, fmt.Errorf("failed to update cached playback for %s %d: %w", mediaType, p.KodiID, err)
+		}
+		res, err := stmtItems.Exec(p.Playcount, p.LastPlayed, p.ResumePosition, p.Playcount, p.KodiID, mediaType,
+			p.Playcount, p.LastPlayed, p.ResumePosition, p.Playcount, listID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to update playback for %s %d: %w", mediaType, p.KodiID, err)
+		}
+		n, _ := res.RowsAffected()
+		changed += n
+	}
+	return changed, tx.Commit()
+}
+
+// SetItemPlaycount records a playcount reported by Kodi on the items with
+// the given Kodi ID and media type on every list sharing listID's Kodi host,
+// marking them watched when it is positive. It returns the number of items
+// changed.
+func (db *DB) SetItemPlaycount(listID int64, mediaType string, kodiID, playcount int) (int64, error) {
+	res, err := db.Exec(`
+		UPDATE items SET playcount = ?, watched = ? > 0
+		WHERE kodi_id = ? AND media_type = ?
+		AND (playcount != ? OR watched != (? > 0))
+		AND list_id IN (
+			SELECT l.id FROM lists l JOIN lists cur ON cur.id = ? WHERE l.effective_host = cur.effective_host
+		)`, playcount, playcount, kodiID, mediaType, playcount, playcount, listID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// ShowCounter carries the episode counters Kodi reports for a show.
+type ShowCounter struct {
+	KodiID          int
+	EpisodeCount    int
+	WatchedEpisodes int
+}
+
+// UpdateShowCounters refreshes episode_count and watched_episodes for shows in
+// the library cache and on every list sharing listID's Kodi host. It returns
+// the number of list items that changed.
+func (db *DB) UpdateShowCounters(listID int64, counters []ShowCounter) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmtCache, err := tx.Prepare(`
+		UPDATE library_cache SET episode_count = ?, watched_episodes = ?
+		WHERE kodi_id = ? AND media_type = 'show'
+		AND list_id IN (
+			SELECT l_cache.id FROM lists l_cache
+			JOIN lists l_current ON l_current.id = ?
+			WHERE l_cache.effective_host = l_current.effective_host
+		)`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmtCache.Close()
+
+	stmtItems, err := tx.Prepare(`
+		UPDATE items SET episode_count = ?, watched_episodes = ?
+		WHERE kodi_id = ? AND media_type = 'show'
+		AND (episode_count != ? OR watched_episodes != ?)
+		AND list_id IN (
+			SELECT l_items.id FROM lists l_items
+			JOIN lists l_current ON l_current.id = ?
+			WHERE l_items.effective_host = l_current.effective_host
+		)`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmtItems.Close()
+
+	var changed int64
+	for _, c := range counters {
+		if _, err := stmtCache.Exec(c.EpisodeCount, c.WatchedEpisodes, c.KodiID, listID); err != nil {
+			return 0, fmt.Errorf("failed to update cached show %d: %w", c.KodiID, err)
+		}
+		res, err := stmtItems.Exec(c.EpisodeCount, c.WatchedEpisodes, c.KodiID, c.EpisodeCount, c.WatchedEpisodes, listID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to update show %d: %w", c.KodiID, err)
+		}
+		n, _ := res.RowsAffected()
+		changed += n
+	}
+	return changed, tx.Commit()
+}
+
+// GetList returns a single list by ID, or ErrListNotFound if it doesn't exist.
+func (db *DB) GetList(id int64) (*List, error) {
+	l, err := scanList(db.QueryRow("SELECT "+listColumns+" FROM resolved_lists WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrListNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// prefixColumns qualifies a comma-separated column list with a table alias.
+func prefixColumns(alias, columns string) string {
+	cols := strings.Split(columns, ", ")
+	for i, c := range cols {
+		cols[i] = alias + "." + c
+	}
+	return strings.Join(cols, ", ")
+}
+
+func credentialsFingerprint(host, username, password string) string {
+	sum := sha256.Sum256([]byte(host + "\x00" + username + "\x00" + password))
+	return hex.EncodeToString(sum[:])
+}
+
+// UpdateListCredentials replaces a list's Kodi connection. The list stops
+// inheriting its group's host, and the new values take precedence over
+// config.json until the config's values for the list change.
+func (db *DB) UpdateListCredentials(id int64, host, username, password string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var storedHost string
+	if err := tx.QueryRow("SELECT effective_host FROM lists WHERE id = ?", id).Scan(&storedHost); errors.Is(err, sql.ErrNoRows) {
+		return ErrListNotFound
+	} else if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		UPDATE lists SET kodi_host = ?, effective_host = ?, username = ?, password = ?, inherits_host = 0, credentials_override = 1
+		WHERE id = ?`, host, host, username, password, id); err != nil {
+		return err
+	}
+	if err := recordHostChange(tx, id, storedHost, host); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetItemPoster updates an item's poster and the retained original artwork.
+func (db *DB) SetItemPoster(id int64, poster, original string) error {
+	res, err := db.Exec("UPDATE items SET poster_path = ?, original_poster_path = ? WHERE id = ?", poster, original, id)
+	if err != nil {
+		return err
+	}
+	return requireRow(res, ErrItemNotFound)
+}
+
+// SetItemWatched records whether the item has been watched on Kodi.
+func (db *DB) SetItemWatched(id int64, watched bool) error {
+	res, err := db.Exec("UPDATE items SET watched = ? WHERE id = ?", watched, id)
+	if err != nil {
+		return err
+	}
+	return requireRow(res, ErrItemNotFound)
+}
+
+// SetItemsWatched sets the watched flag of several items on listID in one
+// transaction. If any of them isn't on the list, nothing is changed and
+// ErrItemNotFound is returned.
+func (db *DB) SetItemsWatched(listID int64, itemIDs []int64, watched bool) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare("UPDATE items SET watched = ? WHERE id = ? AND list_id = ?")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, id := range itemIDs {
+		res, err := stmt.Exec(watched, id, listID)
+		if err != nil {
+			return err
+		}
+		if err := requireRow(res, ErrItemNotFound); err != nil {
+			return fmt.Errorf("item %d: %w", id, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// ListRef identifies a list in API annotations.
+type ListRef struct {
+	ID   int64  `json:"id"`
+	Name string `json:"list_name"`
+}
+
+// GetGroupMembership reports, for each of the given Kodi IDs, which lists in
+// the same group (and on the same Kodi host) as listID already contain it.
+// For shows, a list holding any of the show's seasons counts as containing it.
+func (db *DB) GetGroupMembership(listID int64, mediaType string, kodiIDs []int) (map[int][]ListRef, error) {
+	membership := map[int][]ListRef{}
+	if len(kodiIDs) == 0 {
+		return membership, nil
+	}
+
+	mediaTypes := []interface{}{mediaType}
+	if mediaType == "show" {
+		mediaTypes = append(mediaTypes, "season")
+	}
+	args := []interface{}{listID}
+	args = append(args, mediaTypes...)
+	for _, id := range kodiIDs {
+		args = append(args, id)
+	}
+
+	rows, err := db.Query(`
+		SELECT DISTINCT i.kodi_id, l.id, l.name
+		FROM items i
+		JOIN lists l ON i.list_id = l.id
+		JOIN lists l_current ON l_current.id = ?
+		WHERE l.group_name = l_current.group_name
+		AND l.effective_host = l_current.effective_host
+		AND i.media_type IN (`+placeholders(len(mediaTypes))+`)
+		AND i.kodi_id IN (`+placeholders(len(kodiIDs))+`)
+		ORDER BY l.id ASC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var kodiID int
+		var ref ListRef
+		if err := rows.Scan(&kodiID, &ref.ID, &ref.Name); err != nil {
+			return nil, err
+		}
+		membership[kodiID] = append(membership[kodiID], ref)
+	}
+	return membership, rows.Err()
+}
+
+// placeholders returns "?, ?, ..." for n query parameters.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
@@ -0,0 +1,131 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// ListRevision returns the list's current revision.
+func (db *DB) ListRevision(listID int64) (int64, error) {
+	var rev int64
+	err := db.QueryRow("SELECT revision FROM lists WHERE id = ?", listID).Scan(&rev)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrListNotFound
+	}
+	return rev, err
+}
+
+// checkRevision returns ErrRevisionMismatch unless the list is still at
+// revision expected. A nil expected skips the check.
+func checkRevision(tx *sql.Tx, listID int64, expected *int64) error {
+	var rev int64
+	err := tx.QueryRow("SELECT revision FROM lists WHERE id = ?", listID).Scan(&rev)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrListNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if expected != nil && *expected != rev {
+		return ErrRevisionMismatch
+	}
+	return nil
+}
+
+// MoveItem sets an item's sort order if its list is still at revision
+// expected (nil skips the check), and returns the list's new revision.
+func (db *DB) MoveItem(id int64, sortOrder int, expected *int64) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var listID int64
+	err = tx.QueryRow("SELECT list_id FROM items WHERE id = ?", id).Scan(&listID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrItemNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	if err := checkRevision(tx, listID, expected); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec("UPDATE items SET sort_order = ? WHERE id = ?", sortOrder, id); err != nil {
+		return 0, err
+	}
+	return commitRevision(tx, listID)
+}
+
+// ReorderItems puts the given items first, in order, followed by the list's
+// other items in their current order. It fails with ErrRevisionMismatch
+// unless the list is still at revision expected (nil skips the check), and
+// with ErrItemNotFound if an ID isn't on the list. It returns the new
+// revision.
+func (db *DB) ReorderItems(listID int64, itemIDs []int64, expected *int64) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if err := checkRevision(tx, listID, expected); err != nil {
+		return 0, err
+	}
+
+	rows, err := tx.Query("SELECT id FROM items WHERE list_id = ? ORDER BY sort_order ASC, added_at DESC", listID)
+	if err != nil {
+		return 0, err
+	}
+	var current []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		current = append(current, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	onList := make(map[int64]bool, len(current))
+	for _, id := range current {
+		onList[id] = true
+	}
+	placed := make(map[int64]bool, len(itemIDs))
+	order := make([]int64, 0, len(current))
+	for _, id := range itemIDs {
+		if !onList[id] {
+			return 0, ErrItemNotFound
+		}
+		if !placed[id] {
+			placed[id] = true
+			order = append(order, id)
+		}
+	}
+	for _, id := range current {
+		if !placed[id] {
+			order = append(order, id)
+		}
+	}
+
+	for pos, id := range order {
+		if _, err := tx.Exec("UPDATE items SET sort_order = ? WHERE id = ?", pos, id); err != nil {
+			return 0, err
+		}
+	}
+	return commitRevision(tx, listID)
+}
+
+// commitRevision commits tx and returns the revision it left the list at.
+func commitRevision(tx *sql.Tx, listID int64) (int64, error) {
+	var rev int64
+	if err := tx.QueryRow("SELECT revision FROM lists WHERE id = ?", listID).Scan(&rev); err != nil {
+		return 0, err
+	}
+	return rev, tx.Commit()
+}
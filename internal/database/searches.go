@@ -0,0 +1,94 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// SavedSearch is a named combination of /search query parameters kept on a
+// list, e.g. "short comedies, unwatched" for genre=comedy&max_runtime=100
+// &exclude_watched=true.
+type SavedSearch struct {
+	ID        int64               `json:"id"`
+	ListID    int64               `json:"list_id"`
+	Name      string              `json:"name"`
+	Params    map[string][]string `json:"params"`
+	CreatedAt string              `json:"created_at"`
+}
+
+// AddSavedSearch stores s and returns its ID, or ErrDuplicateSearch if the
+// list already has a search with that name.
+func (db *DB) AddSavedSearch(s SavedSearch) (int64, error) {
+	if err := db.listExists(s.ListID); err != nil {
+		return 0, err
+	}
+	params, err := json.Marshal(s.Params)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode search params: %w", err)
+	}
+	res, err := db.Exec("INSERT OR IGNORE INTO saved_searches (list_id, name, params) VALUES (?, ?, ?)", s.ListID, s.Name, string(params))
+	if err != nil {
+		return 0, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return 0, err
+	} else if n == 0 {
+		return 0, ErrDuplicateSearch
+	}
+	return res.LastInsertId()
+}
+
+func (db *DB) GetSavedSearches(listID int64) ([]SavedSearch, error) {
+	if err := db.listExists(listID); err != nil {
+		return nil, err
+	}
+	rows, err := db.Query("SELECT id, list_id, name, params, created_at FROM saved_searches WHERE list_id = ? ORDER BY name COLLATE NOCASE", listID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	searches := []SavedSearch{}
+	for rows.Next() {
+		s, err := scanSavedSearch(rows)
+		if err != nil {
+			return nil, err
+		}
+		searches = append(searches, s)
+	}
+	return searches, rows.Err()
+}
+
+// GetSavedSearch returns ErrSearchNotFound unless the search belongs to listID.
+func (db *DB) GetSavedSearch(listID, id int64) (*SavedSearch, error) {
+	s, err := scanSavedSearch(db.QueryRow("SELECT id, list_id, name, params, created_at FROM saved_searches WHERE id = ? AND list_id = ?", id, listID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSearchNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (db *DB) DeleteSavedSearch(listID, id int64) error {
+	res, err := db.Exec("DELETE FROM saved_searches WHERE id = ? AND list_id = ?", id, listID)
+	if err != nil {
+		return err
+	}
+	return requireRow(res, ErrSearchNotFound)
+}
+
+func scanSavedSearch(row scanner) (SavedSearch, error) {
+	var s SavedSearch
+	var params string
+	if err := row.Scan(&s.ID, &s.ListID, &s.Name, &params, &s.CreatedAt); err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal([]byte(params), &s.Params); err != nil {
+		return s, fmt.Errorf("failed to decode saved search %d: %w", s.ID, err)
+	}
+	return s, nil
+}
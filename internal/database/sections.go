@@ -0,0 +1,141 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// Section is a named divider within a list, e.g. "This weekend" or "Later".
+// Sections are shown in sort_order, each followed by its items.
+type Section struct {
+	ID        int64  `json:"id"`
+	ListID    int64  `json:"list_id"`
+	Name      string `json:"name"`
+	SortOrder int    `json:"sort_order"`
+}
+
+// nullableID stores a zero ID as NULL.
+func nullableID(id int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: id, Valid: id != 0}
+}
+
+// AddSection stores s after the list's other sections and returns its ID,
+// or ErrDuplicateSection if the list already has a section with that name.
+func (db *DB) AddSection(s Section) (int64, error) {
+	if err := db.listExists(s.ListID); err != nil {
+		return 0, err
+	}
+	res, err := db.Exec(`
+		INSERT OR IGNORE INTO sections (list_id, name, sort_order)
+		SELECT ?, ?, COALESCE(MAX(sort_order), -1) + 1 FROM sections WHERE list_id = ?`,
+		s.ListID, s.Name, s.ListID)
+	if err != nil {
+		return 0, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return 0, err
+	} else if n == 0 {
+		return 0, ErrDuplicateSection
+	}
+	return res.LastInsertId()
+}
+
+func (db *DB) GetSections(listID int64) ([]Section, error) {
+	if err := db.listExists(listID); err != nil {
+		return nil, err
+	}
+	rows, err := db.Query("SELECT id, list_id, name, sort_order FROM sections WHERE list_id = ? ORDER BY sort_order ASC, id ASC", listID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sections := []Section{}
+	for rows.Next() {
+		var s Section
+		if err := rows.Scan(&s.ID, &s.ListID, &s.Name, &s.SortOrder); err != nil {
+			return nil, err
+		}
+		sections = append(sections, s)
+	}
+	return sections, rows.Err()
+}
+
+// GetSection returns ErrSectionNotFound unless the section belongs to listID.
+func (db *DB) GetSection(listID, id int64) (*Section, error) {
+	var s Section
+	err := db.QueryRow("SELECT id, list_id, name, sort_order FROM sections WHERE id = ? AND list_id = ?", id, listID).
+		Scan(&s.ID, &s.ListID, &s.Name, &s.SortOrder)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSectionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// UpdateSection renames and reorders a section, returning
+// ErrDuplicateSection if another section of the list has the new name.
+func (db *DB) UpdateSection(s Section) error {
+	var clash int
+	err := db.QueryRow("SELECT COUNT(*) FROM sections WHERE list_id = ? AND name = ? AND id != ?", s.ListID, s.Name, s.ID).Scan(&clash)
+	if err != nil {
+		return err
+	}
+	if clash > 0 {
+		return ErrDuplicateSection
+	}
+	res, err := db.Exec("UPDATE sections SET name = ?, sort_order = ? WHERE id = ? AND list_id = ?", s.Name, s.SortOrder, s.ID, s.ListID)
+	if err != nil {
+		return err
+	}
+	return requireRow(res, ErrSectionNotFound)
+}
+
+// DeleteSection removes a section. Its items stay on the list, above the
+// remaining sections.
+func (db *DB) DeleteSection(listID, id int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("DELETE FROM sections WHERE id = ? AND list_id = ?", id, listID)
+	if err != nil {
+		return err
+	}
+	if err := requireRow(res, ErrSectionNotFound); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE items SET section_id = NULL WHERE section_id = ?", id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetItemSection moves an item into one of its list's sections, or out of
+// any section when sectionID is zero.
+func (db *DB) SetItemSection(itemID, sectionID int64) error {
+	if sectionID != 0 {
+		var one int
+		err := db.QueryRow(`
+			SELECT 1 FROM sections s JOIN items i ON i.list_id = s.list_id
+			WHERE s.id = ? AND i.id = ?`, sectionID, itemID).Scan(&one)
+		if errors.Is(err, sql.ErrNoRows) {
+			if _, err := db.GetItem(itemID); err != nil {
+				return err
+			}
+			return ErrSectionNotFound
+		}
+		if err != nil {
+			return err
+		}
+	}
+	res, err := db.Exec("UPDATE items SET section_id = ? WHERE id = ?", nullableID(sectionID), itemID)
+	if err != nil {
+		return err
+	}
+	return requireRow(res, ErrItemNotFound)
+}
@@ -0,0 +1,54 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// ExpandItem replaces the item id, typically a movie set, with the given
+// items, inserted in order where it stood and in the same section. Items
+// already on the list are skipped. It returns the items that were added.
+func (db *DB) ExpandItem(id int64, items []Item) ([]Item, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var listID int64
+	var sortOrder int
+	var sectionID sql.NullInt64
+	err = tx.QueryRow("SELECT list_id, sort_order, section_id FROM items WHERE id = ?", id).Scan(&listID, &sortOrder, &sectionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrItemNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec("DELETE FROM items WHERE id = ?", id); err != nil {
+		return nil, err
+	}
+	if len(items) > 1 {
+		if _, err := tx.Exec("UPDATE items SET sort_order = sort_order + ? WHERE list_id = ? AND sort_order > ?", len(items)-1, listID, sortOrder); err != nil {
+			return nil, err
+		}
+	}
+
+	added := []Item{}
+	for _, i := range items {
+		i.ListID = listID
+		i.SortOrder = sortOrder + len(added)
+		i.SectionID = sectionID.Int64
+		newID, err := insertItem(tx, i)
+		if errors.Is(err, ErrDuplicateItem) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		i.ID = newID
+		added = append(added, i)
+	}
+	return added, tx.Commit()
+}
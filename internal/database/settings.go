@@ -0,0 +1,262 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// importedSettingsKey is the app_state key holding the branding and job
+// schedules of the last imported settings profile.
+const importedSettingsKey = "imported_settings"
+
+// SettingsProfile is the shareable part of an instance's setup: its lists
+// and their rules, branding and job schedules, without Kodi hosts,
+// credentials or any list items.
+type SettingsProfile struct {
+	Version int `json:"version"`
+
+	ProfileSettings
+
+	// Groups carry no connection details on export; whoever imports the
+	// profile fills in kodi_host and credentials for their own boxes.
+	Groups []Group        `json:"groups"`
+	Lists  []ListSettings `json:"lists"`
+}
+
+// ProfileSettings are the config.json settings a profile carries.
+type ProfileSettings struct {
+	Subtitle               string `json:"subtitle,omitempty"`
+	Footer                 string `json:"footer,omitempty"`
+	ShowRefreshInterval    string `json:"show_refresh_interval,omitempty"`
+	FeedRefreshInterval    string `json:"feed_refresh_interval,omitempty"`
+	HistoryRefreshInterval string `json:"history_refresh_interval,omitempty"`
+}
+
+// ListSettings is a list's configuration and rules as kept in a profile.
+type ListSettings struct {
+	GroupName     string   `json:"group_name"`
+	Name          string   `json:"list_name"`
+	ContentType   string   `json:"content_type"`
+	OnWatched     string   `json:"on_watched,omitempty"`
+	ArtPreference []string `json:"art_preference,omitempty"`
+
+	Sections      []string             `json:"sections,omitempty"`
+	SavedSearches []SavedSearchProfile `json:"saved_searches,omitempty"`
+	Feeds         []string             `json:"feeds,omitempty"`
+}
+
+type SavedSearchProfile struct {
+	Name   string              `json:"name"`
+	Params map[string][]string `json:"params"`
+}
+
+// SettingsImport reports what importing a profile changed.
+type SettingsImport struct {
+	ListsCreated  int `json:"lists_created"`
+	ListsUpdated  int `json:"lists_updated"`
+	Sections      int `json:"sections_added"`
+	SavedSearches int `json:"saved_searches_added"`
+	Feeds         int `json:"feeds_added"`
+}
+
+// ExportListSettings returns every list's settings with its sections, saved
+// searches and feed subscriptions. Saved searches drop exclude_list, whose
+// list IDs mean nothing on another instance.
+func (db *DB) ExportListSettings() ([]ListSettings, error) {
+	lists, err := db.GetAllLists()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]ListSettings, 0, len(lists))
+	for _, l := range lists {
+		ls := ListSettings{
+			GroupName:     l.GroupName,
+			Name:          l.Name,
+			ContentType:   l.ContentType,
+			OnWatched:     l.OnWatched,
+			ArtPreference: l.ArtPreference,
+		}
+		sections, err := db.GetSections(l.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, sec := range sections {
+			ls.Sections = append(ls.Sections, sec.Name)
+		}
+		searches, err := db.GetSavedSearches(l.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range searches {
+			delete(s.Params, "exclude_list")
+			ls.SavedSearches = append(ls.SavedSearches, SavedSearchProfile{Name: s.Name, Params: s.Params})
+		}
+		feeds, err := db.GetFeeds(l.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range feeds {
+			ls.Feeds = append(ls.Feeds, f.URL)
+		}
+		out = append(out, ls)
+	}
+	return out, nil
+}
+
+// ImportListSettings applies a profile's groups and lists. Groups that come
+// with a kodi_host are upserted; lists that don't exist yet are created
+// inheriting their group's connection, while existing lists keep theirs and
+// only take the profile's settings. Sections, saved searches and feeds are
+// added where the list doesn't already have one of the same name or URL.
+// The import is applied in one transaction; dryRun reports what it would
+// change and rolls it back.
+func (db *DB) ImportListSettings(groups []Group, lists []ListSettings, dryRun bool) (*SettingsImport, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var withHosts []Group
+	for _, g := range groups {
+		if g.KodiHost != "" {
+			withHosts = append(withHosts, g)
+		}
+	}
+	if err := syncGroups(tx, withHosts); err != nil {
+		return nil, err
+	}
+
+	// Create missing lists first; one whose group has no host fails the
+	// whole import
+	report := &SettingsImport{}
+	var created, existing []List
+	for _, ls := range lists {
+		l := List{GroupName: ls.GroupName, Name: ls.Name, ContentType: ls.ContentType, OnWatched: ls.OnWatched, ArtPreference: ls.ArtPreference}
+		if err := validateListSettings(l); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidSettings, err)
+		}
+		var id int64
+		err := tx.QueryRow("SELECT id FROM lists WHERE group_name = ? AND lower(name) = lower(?) LIMIT 1", l.GroupName, l.Name).Scan(&id)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			created = append(created, l)
+		case err != nil:
+			return nil, err
+		default:
+			existing = append(existing, l)
+		}
+	}
+	if err := syncLists(tx, created); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSettings, err)
+	}
+	report.ListsCreated = len(created)
+	for _, l := range existing {
+		if _, err := tx.Exec("UPDATE lists SET on_watched = ?, art_preference = ? WHERE group_name = ? AND lower(name) = lower(?)",
+			l.OnWatched, joinList(l.ArtPreference), l.GroupName, l.Name); err != nil {
+			return nil, err
+		}
+	}
+	report.ListsUpdated = len(existing)
+
+	for _, ls := range lists {
+		var listID int64
+		if err := tx.QueryRow("SELECT id FROM lists WHERE group_name = ? AND lower(name) = lower(?) ORDER BY id LIMIT 1", ls.GroupName, ls.Name).Scan(&listID); err != nil {
+			return nil, err
+		}
+		for _, name := range ls.Sections {
+			if name = strings.TrimSpace(name); name == "" {
+				continue
+			}
+			n, err := rowsAffected(tx.Exec(`
+				INSERT OR IGNORE INTO sections (list_id, name, sort_order)
+				SELECT ?, ?, COALESCE(MAX(sort_order), -1) + 1 FROM sections WHERE list_id = ?`,
+				listID, name, listID))
+			if err != nil {
+				return nil, err
+			}
+			report.Sections += int(n)
+		}
+		for _, s := range ls.SavedSearches {
+			if strings.TrimSpace(s.Name) == "" {
+				continue
+			}
+			params, err := json.Marshal(s.Params)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode search params: %w", err)
+			}
+			n, err := rowsAffected(tx.Exec("INSERT OR IGNORE INTO saved_searches (list_id, name, params) VALUES (?, ?, ?)", listID, s.Name, string(params)))
+			if err != nil {
+				return nil, err
+			}
+			report.SavedSearches += int(n)
+		}
+		for _, url := range ls.Feeds {
+			if url == "" {
+				continue
+			}
+			n, err := rowsAffected(tx.Exec("INSERT OR IGNORE INTO feeds (list_id, url) VALUES (?, ?)", listID, url))
+			if err != nil {
+				return nil, err
+			}
+			report.Feeds += int(n)
+		}
+	}
+	if dryRun {
+		return report, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// StoreImportedSettings keeps a profile's branding and schedules for the
+// next start, see ApplyImportedSettings.
+func (db *DB) StoreImportedSettings(s ProfileSettings) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return db.SetState(importedSettingsKey, string(data))
+}
+
+// ImportedSettings returns the settings StoreImportedSettings kept, empty
+// if none were.
+func (db *DB) ImportedSettings() (ProfileSettings, error) {
+	var s ProfileSettings
+	data, err := db.GetState(importedSettingsKey)
+	if err != nil || data == "" {
+		return s, err
+	}
+	return s, json.Unmarshal([]byte(data), &s)
+}
+
+// ApplyImportedSettings overlays the last imported branding and schedules
+// onto cfg. It runs before config.json is decoded, so the file still wins
+// for any setting it defines.
+func (db *DB) ApplyImportedSettings(cfg *Config) error {
+	s, err := db.ImportedSettings()
+	if err != nil {
+		return err
+	}
+	if s.Subtitle != "" {
+		cfg.Subtitle = s.Subtitle
+	}
+	if s.Footer != "" {
+		cfg.Footer = s.Footer
+	}
+	if s.ShowRefreshInterval != "" {
+		cfg.ShowRefreshInterval = s.ShowRefreshInterval
+	}
+	if s.FeedRefreshInterval != "" {
+		cfg.FeedRefreshInterval = s.FeedRefreshInterval
+	}
+	if s.HistoryRefreshInterval != "" {
+		cfg.HistoryRefreshInterval = s.HistoryRefreshInterval
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SeasonList is the season number under which a show's list of seasons is
+// cached; episode listings are cached under their own season number.
+const SeasonList = -1
+
+// GetShowCache returns the cached listing for season of showID on listID's
+// Kodi host (SeasonList for the show's seasons) and whether it was fetched
+// within maxAge. data is nil when nothing is cached.
+func (db *DB) GetShowCache(listID int64, showID, season int, maxAge time.Duration) (data []byte, fresh bool, err error) {
+	if err := db.listExists(listID); err != nil {
+		return nil, false, err
+	}
+	err = db.QueryRow(`
+		SELECT sc.data, sc.fetched_at > datetime('now', ?)
+		FROM show_cache sc
+		JOIN lists l ON l.id = ?
+		WHERE sc.kodi_host = l.effective_host AND sc.tvshowid = ? AND sc.season = ?`,
+		fmt.Sprintf("-%d seconds", int(maxAge.Seconds())), listID, showID, season).Scan(&data, &fresh)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	return data, fresh, err
+}
+
+// StoreShowCache replaces the cached listing for season of showID on
+// listID's Kodi host.
+func (db *DB) StoreShowCache(listID int64, showID, season int, data []byte) error {
+	res, err := db.Exec(`
+		INSERT OR REPLACE INTO show_cache (kodi_host, tvshowid, season, data, fetched_at)
+		SELECT effective_host, ?, ?, ?, CURRENT_TIMESTAMP FROM lists WHERE id = ?`,
+		showID, season, string(data), listID)
+	if err != nil {
+		return err
+	}
+	return requireRow(res, ErrListNotFound)
+}
+
+// GetGenreCache returns the genre list stored for mediaType ("movie" or
+// "tvshow") by the last sync of listID's Kodi host, or nil if none was.
+func (db *DB) GetGenreCache(listID int64, mediaType string) ([]byte, error) {
+	var data []byte
+	err := db.QueryRow(`
+		SELECT gc.data
+		FROM genre_cache gc
+		JOIN lists l ON l.id = ?
+		WHERE gc.kodi_host = l.effective_host AND gc.media_type = ?`,
+		listID, mediaType).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// StoreGenreCache replaces the genre list for mediaType on listID's Kodi
+// host.
+func (db *DB) StoreGenreCache(listID int64, mediaType string, data []byte) error {
+	res, err := db.Exec(`
+		INSERT OR REPLACE INTO genre_cache (kodi_host, media_type, data, fetched_at)
+		SELECT effective_host, ?, ?, CURRENT_TIMESTAMP FROM lists WHERE id = ?`,
+		mediaType, string(data), listID)
+	if err != nil {
+		return err
+	}
+	return requireRow(res, ErrListNotFound)
+}
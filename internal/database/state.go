@@ -0,0 +1,78 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// GetState returns the value stored under key, or "" if it was never set.
+func (db *DB) GetState(key string) (string, error) {
+	var value string
+	err := db.QueryRow("SELECT value FROM app_state WHERE key = ?", key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return value, err
+}
+
+func (db *DB) SetState(key, value string) error {
+	_, err := db.Exec("INSERT INTO app_state (key, value) VALUES (?, ?) ON CONFLICT(key) DO UPDATE SET value = excluded.value", key, value)
+	return err
+}
+
+// SyncWatermark returns the Kodi dateadded an incremental sync of mediaType
+// on listID can fetch from: the newest one seen by earlier syncs. It returns
+// "" when a full sync is due instead, because the list was never synced, its
+// Kodi host changed since, or its last full sync is older than fullEvery.
+func (db *DB) SyncWatermark(listID int64, mediaType string, fullEvery time.Duration) (string, error) {
+	var addedThrough string
+	err := db.QueryRow(`
+		SELECT ss.added_through
+		FROM sync_state ss
+		JOIN lists l ON l.id = ss.list_id
+		WHERE ss.list_id = ? AND ss.media_type = ? AND ss.kodi_host = l.effective_host
+		AND ss.full_sync_at > datetime('now', ?)`,
+		listID, mediaType, fmt.Sprintf("-%d seconds", int(fullEvery.Seconds()))).Scan(&addedThrough)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return addedThrough, err
+}
+
+// RecordSync stores the newest dateadded a sync of mediaType on listID saw.
+// A full sync replaces the watermark and restarts the full sync interval; an
+// incremental one only moves the watermark forward.
+func (db *DB) RecordSync(listID int64, mediaType, addedThrough string, full bool) error {
+	now := time.Now().UTC().Format(time.RFC3339)
+	if !full {
+		_, err := db.Exec("UPDATE sync_state SET added_through = MAX(added_through, ?), synced_at = ? WHERE list_id = ? AND media_type = ?", addedThrough, now, listID, mediaType)
+		return err
+	}
+	res, err := db.Exec(`
+		INSERT INTO sync_state (list_id, media_type, kodi_host, added_through, full_sync_at, synced_at)
+		SELECT id, ?, effective_host, ?, CURRENT_TIMESTAMP, ? FROM lists WHERE id = ?
+		ON CONFLICT(list_id, media_type) DO UPDATE SET
+			kodi_host = excluded.kodi_host, added_through = excluded.added_through, full_sync_at = excluded.full_sync_at,
+			synced_at = excluded.synced_at`,
+		mediaType, addedThrough, now, listID)
+	if err != nil {
+		return err
+	}
+	return requireRow(res, ErrListNotFound)
+}
+
+// LastSynced returns when the library cache of mediaType that listID reads
+// was last synced, by any list on its Kodi host, or "" if it never was.
+func (db *DB) LastSynced(listID int64, mediaType string) (string, error) {
+	var syncedAt string
+	err := db.QueryRow(`
+		SELECT COALESCE(MAX(ss.synced_at), '')
+		FROM sync_state ss
+		JOIN lists l ON l.id = ss.list_id
+		JOIN lists l_current ON l_current.id = ?
+		WHERE l.effective_host = l_current.effective_host AND ss.kodi_host = l_current.effective_host
+		AND ss.media_type = ?`, listID, mediaType).Scan(&syncedAt)
+	return syncedAt, err
+}
@@ -0,0 +1,54 @@
+package database
+
+import "time"
+
+// UsageKey names a usage counter: Kind is "endpoint" (Name is e.g.
+// "GET /lists/{id}/items") or "feature" (Name is e.g. "queue").
+type UsageKey struct {
+	Kind string
+	Name string
+}
+
+// UsageCount is the total of one usage counter during one day.
+type UsageCount struct {
+	Day time.Time
+	UsageKey
+	Count int64
+}
+
+// AddUsage adds counts to the usage counters of the day (UTC) containing at.
+func (db *DB) AddUsage(at time.Time, counts map[UsageKey]int64) error {
+	day := at.UTC().Truncate(24 * time.Hour).Unix()
+	return addCounts(db, `
+		INSERT INTO usage_counts (day, kind, name, count) VALUES (?, ?, ?, ?)
+		ON CONFLICT(day, kind, name) DO UPDATE SET count = count + excluded.count`,
+		counts, func(key UsageKey, n int64) []interface{} { return []interface{}{day, key.Kind, key.Name, n} })
+}
+
+// GetUsage returns the daily usage counters from the day containing since
+// onwards, oldest first.
+func (db *DB) GetUsage(since time.Time) ([]UsageCount, error) {
+	rows, err := db.Query("SELECT day, kind, name, count FROM usage_counts WHERE day >= ? ORDER BY day, kind, name", since.UTC().Truncate(24*time.Hour).Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []UsageCount{}
+	for rows.Next() {
+		var c UsageCount
+		var day int64
+		if err := rows.Scan(&day, &c.Kind, &c.Name, &c.Count); err != nil {
+			return nil, err
+		}
+		c.Day = time.Unix(day, 0).UTC()
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+// PruneUsage deletes usage counters of days before the one containing
+// before.
+func (db *DB) PruneUsage(before time.Time) error {
+	return db.pruneCounts("usage_counts", "day", before.UTC().Truncate(24*time.Hour).Unix())
+}
@@ -0,0 +1,69 @@
+package database
+
+import "fmt"
+
+// GetWantedItemsForHost returns the wanted items of every list that shares the
+// Kodi host of the given list.
+func (db *DB) GetWantedItemsForHost(listID int64) ([]Item, error) {
+	rows, err := db.Query(`
+		SELECT `+prefixColumns("i", itemColumns)+`
+		FROM items i
+		JOIN lists l_item ON i.list_id = l_item.id
+		JOIN lists l_current ON l_current.id = ?
+		WHERE l_item.effective_host = l_current.effective_host
+		AND i.wanted = 1
+		ORDER BY i.id ASC`, listID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]Item, 0)
+	for rows.Next() {
+		i, err := scanItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	return items, rows.Err()
+}
+
+// LinkWantedItem turns a wanted item into a regular library item using the
+// matched cache row. If the list already holds that library item the wanted
+// placeholder is removed instead. It reports whether the item was linked.
+func (db *DB) LinkWantedItem(itemID int64, c *CachedItem) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var exists int
+	err = tx.QueryRow(`
+		SELECT COUNT(*) FROM items
+		WHERE list_id = (SELECT list_id FROM items WHERE id = ?)
+		AND kodi_id = ? AND media_type = ?`, itemID, c.KodiID, c.MediaType).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check for existing item: %w", err)
+	}
+
+	if exists > 0 {
+		if _, err := tx.Exec("DELETE FROM items WHERE id = ?", itemID); err != nil {
+			return false, fmt.Errorf("failed to remove wanted item: %w", err)
+		}
+		return false, tx.Commit()
+	}
+
+	_, err = tx.Exec(`
+		UPDATE items
+		SET kodi_id = ?, media_type = ?, title = ?, year = ?, poster_path = ?, runtime = ?, episode_count = ?, rating = ?,
+			audio_languages = ?, subtitle_languages = ?, imdb_id = ?, tmdb_id = ?, resolution = ?, hdr_type = ?, wanted = 0
+		WHERE id = ?`,
+		c.KodiID, c.MediaType, c.Title, c.Year, c.Poster, c.Runtime, c.EpisodeCount, c.Rating,
+		joinList(c.AudioLanguages), joinList(c.SubtitleLanguages), c.IMDbID, c.TMDbID, c.Resolution, c.HDRType, itemID)
+	if err != nil {
+		return false, fmt.Errorf("failed to link wanted item: %w", err)
+	}
+	return true, tx.Commit()
+}
@@ -0,0 +1,168 @@
+package kodi
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// BatchCall is one call sent as part of a Batch. Result and Err are filled
+// in once the batch returns.
+type BatchCall struct {
+	Method string
+	Params interface{}
+
+	Result json.RawMessage
+	Err    error
+}
+
+// Batch sends calls to Kodi as a single JSON-RPC batch, saving a round trip
+// per call over slow links. The returned error covers the batch as a whole
+// (unreachable host, rejected credentials); failures of individual calls
+// are reported in their Err.
+func (c *Client) Batch(calls ...*BatchCall) error {
+	if len(calls) == 0 {
+		return nil
+	}
+	reqs := make([]JsonRPCRequest, len(calls))
+	retry := true
+	for i, call := range calls {
+		// IDs only need to be unique within the batch
+		reqs[i] = JsonRPCRequest{JSONRPC: "2.0", Method: call.Method, Params: call.Params, ID: i + 1}
+		retry = retry && retryable(call.Method)
+	}
+	body, _ := json.Marshal(reqs)
+
+	raw := c.withRetries(retry, func() rawResponse { return c.post(body) })
+	if err := c.checkResponse(raw); err != nil {
+		return err
+	}
+
+	var resps []JsonRPCResponse
+	if err := json.Unmarshal(raw.body, &resps); err != nil {
+		return fmt.Errorf("failed to decode batch response: %w", err)
+	}
+	answered := make([]bool, len(calls))
+	for _, resp := range resps {
+		i := resp.ID - 1
+		if i < 0 || i >= len(calls) {
+			continue
+		}
+		answered[i] = true
+		if resp.Error != nil {
+			calls[i].Err = resp.Error
+			continue
+		}
+		calls[i].Result = resp.Result
+	}
+	for i, ok := range answered {
+		if !ok {
+			calls[i].Err = fmt.Errorf("kodi sent no response to %s", calls[i].Method)
+		}
+	}
+	return nil
+}
+
+// Library is what GetLibrary fetched. Movies and TVShows stay empty when
+// pages were handed to a callback instead.
+type Library struct {
+	Movies      []MediaItem
+	TVShows     []MediaItem
+	MovieGenres []Genre
+	ShowGenres  []Genre
+}
+
+// GetLibrary fetches the movies and/or TV shows of the library with their
+// genres. The genres and the first page of each item type come back in one
+// batch; any further pages follow one request at a time. Each page is
+// passed to onPage with its type ("movie" or "tvshow") as it arrives, or
+// collected into the Library when onPage is nil. A failure of any part
+// fails the whole.
+func (c *Client) GetLibrary(movies, shows bool, onPage func(mediaType string, items []MediaItem) error) (*Library, error) {
+	return c.GetLibraryAddedSince(movies, shows, "", onPage)
+}
+
+// GetLibraryAddedSince is GetLibrary limited to the movies and shows Kodi
+// added after since ("YYYY-MM-DD HH:MM:SS" in the host's local time, as
+// Kodi reports dateadded); an empty since fetches everything. A show's
+// dateadded is that of its newest episode, so shows with new episodes are
+// included. Genres are always listed in full.
+func (c *Client) GetLibraryAddedSince(movies, shows bool, since string, onPage func(mediaType string, items []MediaItem) error) (*Library, error) {
+	lib := &Library{}
+	if onPage == nil {
+		onPage = func(mediaType string, items []MediaItem) error {
+			if mediaType == "movie" {
+				lib.Movies = append(lib.Movies, items...)
+			} else {
+				lib.TVShows = append(lib.TVShows, items...)
+			}
+			return nil
+		}
+	}
+
+	type itemQuery struct {
+		mediaType, method, key string
+		id                     int
+		params                 map[string]interface{}
+		genres                 *[]Genre
+	}
+	var queries []itemQuery
+	if movies {
+		queries = append(queries, itemQuery{"movie", "VideoLibrary.GetMovies", "movies", 1, moviesParams(), &lib.MovieGenres})
+	}
+	if shows {
+		queries = append(queries, itemQuery{"tvshow", "VideoLibrary.GetTVShows", "tvshows", 3, tvShowsParams(), &lib.ShowGenres})
+	}
+
+	if since != "" {
+		for i := range queries {
+			queries[i].params["filter"] = map[string]string{"field": "dateadded", "operator": "after", "value": since}
+		}
+	}
+
+	size := c.pageSize()
+	var calls []*BatchCall
+	for _, q := range queries {
+		calls = append(calls,
+			&BatchCall{Method: q.method, Params: withLimits(q.params, 0, size)},
+			&BatchCall{Method: "VideoLibrary.GetGenres", Params: genresParams(q.mediaType)})
+	}
+	if err := c.Batch(calls...); err != nil {
+		return nil, err
+	}
+	for _, call := range calls {
+		if call.Err != nil {
+			return nil, call.Err
+		}
+	}
+
+	for i, q := range queries {
+		first, genres := calls[2*i], calls[2*i+1]
+		var err error
+		if *q.genres, err = decodeGenres(genres.Result); err != nil {
+			return nil, err
+		}
+		items, total, err := decodePage(first.Result, q.key)
+		if err != nil {
+			return nil, err
+		}
+		if err := onPage(q.mediaType, items); err != nil {
+			return nil, err
+		}
+		if !c.morePages(len(items), len(items), total) {
+			continue
+		}
+		err = c.pageItems(q.method, q.id, q.params, q.key, len(items), func(items []MediaItem) error {
+			return onPage(q.mediaType, items)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if lib.Movies == nil {
+		lib.Movies = []MediaItem{}
+	}
+	if lib.TVShows == nil {
+		lib.TVShows = []MediaItem{}
+	}
+	return lib, nil
+}
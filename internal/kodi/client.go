@@ -4,17 +4,51 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strings"
+	"sync/atomic"
 	"time"
+
+	"whats-next/internal/media"
 )
 
+// Kodi reports runtimes in seconds on current API versions, but some
+// endpoints and older versions return minutes. Values below this threshold
+// can't be a feature length in seconds and are treated as minutes.
+const minutesRuntimeThreshold = 300
+
 type Client struct {
 	HostURL    string
 	Username   string
 	Password   string
 	HTTPClient *http.Client
+
+	// OnAuth, when set, is told after every response whether Kodi accepted
+	// the client's credentials.
+	OnAuth func(ok bool)
+
+	// OnCall, when set, is told after every call whether the host answered:
+	// nil, or why the last attempt failed.
+	OnCall func(err error)
+
+	// Retry controls retries of failed calls and the host's circuit breaker.
+	Retry RetryPolicy
+
+	// PageSize is how many movies or shows a library query fetches per
+	// request; zero uses DefaultPageSize.
+	PageSize int
+
+	// ImageTimeout bounds GetArtwork downloads; zero uses 30s.
+	ImageTimeout time.Duration
+
+	// UseTextures makes GetArtwork prefer Kodi's cached thumbnails, found
+	// through Textures.GetTextures, over the original artwork.
+	UseTextures bool
+	// noTextures is set once the host turns out not to offer them.
+	noTextures atomic.Bool
 }
 
 func NewClient(hostURL, username, password string) *Client {
@@ -25,6 +59,7 @@ func NewClient(hostURL, username, password string) *Client {
 		HTTPClient: &http.Client{
 			Timeout: 10 * time.Second,
 		},
+		Retry: DefaultRetryPolicy,
 	}
 }
 
@@ -47,6 +82,21 @@ type JsonRPCError struct {
 	Message string `json:"message"`
 }
 
+// AuthError is returned when Kodi rejects the client's credentials, so bad
+// credentials can be told apart from an unreachable host.
+type AuthError struct {
+	Host       string
+	StatusCode int
+}
+
+func (e *AuthError) Error() string {
+	return fmt.Sprintf("Kodi authentication failed for host %s (HTTP %d)", e.Host, e.StatusCode)
+}
+
+func (e *JsonRPCError) Error() string {
+	return fmt.Sprintf("kodi rpc error: %s (code: %d)", e.Message, e.Code)
+}
+
 type MediaItem struct {
 	ID        int               `json:"id"`
 	Label     string            `json:"label"`
@@ -54,33 +104,121 @@ type MediaItem struct {
 	Rating    float64           `json:"rating,omitempty"`
 	Year      int               `json:"year,omitempty"`
 	Plot      string            `json:"plot,omitempty"`
-	Runtime   int               `json:"runtime,omitempty"`
+	Genres    []string          `json:"genre,omitempty"`
+	Runtime   int               `json:"runtime,omitempty"`  // Always seconds after decoding
 	Duration  int               `json:"duration,omitempty"` // Fallback for some Kodi versions
 	Thumbnail string            `json:"thumbnail,omitempty"`
 	Art       map[string]string `json:"art,omitempty"` // Added Art map
 
+	RuntimeFormatted string `json:"runtime_formatted,omitempty"`
+
+	// Quality is a badge such as "2160p HDR" or "1080p" from the stream
+	// details, empty when they are unknown.
+	Quality string `json:"quality,omitempty"`
+
 	StreamDetails *StreamDetails `json:"streamdetails,omitempty"` // Deeply nested duration
 
-	ShowTitle    string `json:"showtitle,omitempty"`
-	Season       int    `json:"season,omitempty"`
-	Episode      int    `json:"episode,omitempty"`
-	EpisodeCount int    `json:"episode_count,omitempty"`
+	AudioLanguages    []string `json:"audio_languages,omitempty"`
+	SubtitleLanguages []string `json:"subtitle_languages,omitempty"`
+
+	ShowTitle       string `json:"showtitle,omitempty"`
+	TVShowID        int    `json:"tvshowid,omitempty"` // Episodes only
+	Season          int    `json:"season,omitempty"`
+	Episode         int    `json:"episode,omitempty"`
+	EpisodeCount    int    `json:"episode_count,omitempty"`
+	WatchedEpisodes int    `json:"watchedepisodes,omitempty"`
+
+	// The movie set (collection) a movie belongs to, if any
+	SetID int    `json:"setid,omitempty"`
+	Set   string `json:"set,omitempty"`
+
+	// Artist names an album's artists, e.g. "Simon & Garfunkel".
+	Artist string `json:"artist,omitempty"`
+
+	Playcount  int     `json:"playcount"`
+	LastPlayed string  `json:"lastplayed,omitempty"` // "YYYY-MM-DD HH:MM:SS", empty if never played
+	DateAdded  string  `json:"dateadded,omitempty"`  // "YYYY-MM-DD HH:MM:SS"
+	Resume     *Resume `json:"resume,omitempty"`
+
+	// UniqueID maps scraper names ("imdb", "tmdb", "tvdb") to the item's ID
+	// on that site.
+	UniqueID map[string]string `json:"uniqueid,omitempty"`
+
+	// Actors are the top-billed cast names from a library listing, kept
+	// for suggestions rather than sent to clients.
+	Actors []string `json:"-"`
+}
+
+// maxActors is how many top-billed cast members a library listing keeps.
+const maxActors = 5
+
+// Resume is Kodi's saved resume point, in seconds. Position is zero when
+// there is nothing to resume.
+type Resume struct {
+	Position float64 `json:"position"`
+	Total    float64 `json:"total"`
 }
 
 type StreamDetails struct {
 	Video []struct {
-		Duration int `json:"duration"`
+		Duration int    `json:"duration"`
+		Width    int    `json:"width"`
+		Height   int    `json:"height"`
+		HDRType  string `json:"hdrtype"`
 	} `json:"video"`
+	Audio []struct {
+		Language string `json:"language"`
+		Codec    string `json:"codec"`
+		Channels int    `json:"channels"`
+	} `json:"audio"`
+	Subtitle []struct {
+		Language string `json:"language"`
+	} `json:"subtitle"`
+}
+
+// languages returns the distinct, lower-cased language codes of the audio and
+// subtitle streams. Kodi reports ISO 639-2 codes (e.g. "eng") and leaves the
+// language empty for untagged streams, which are skipped.
+func (sd *StreamDetails) languages() (audio, subtitles []string) {
+	for _, a := range sd.Audio {
+		audio = appendLanguage(audio, a.Language)
+	}
+	for _, s := range sd.Subtitle {
+		subtitles = appendLanguage(subtitles, s.Language)
+	}
+	return audio, subtitles
+}
+
+func appendLanguage(langs []string, lang string) []string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if lang == "" {
+		return langs
+	}
+	for _, l := range langs {
+		if l == lang {
+			return langs
+		}
+	}
+	return append(langs, lang)
 }
 
 func (m *MediaItem) UnmarshalJSON(data []byte) error {
 	type Alias MediaItem
 	aux := &struct {
-		MovieID   int `json:"movieid"`
-		EpisodeID int `json:"episodeid"`
-		TVShowID  int `json:"tvshowid"`
-		SeasonID  int `json:"seasonid"`
-		Episodes  int `json:"episode"`
+		MovieID   int          `json:"movieid"`
+		EpisodeID int          `json:"episodeid"`
+		TVShowID  int          `json:"tvshowid"`
+		SeasonID  int          `json:"seasonid"`
+		SetID     int          `json:"setid"`
+		AlbumID   int          `json:"albumid"`
+		ArtistID  int          `json:"artistid"`
+		Episodes  int          `json:"episode"`
+		Cast      []CastMember `json:"cast"`
+		// Albums list their artists as an array and artists their name,
+		// so both are read here rather than into Artist.
+		Artists       json.RawMessage `json:"artist"`
+		DisplayArtist string          `json:"displayartist"`
+		Description   string          `json:"description"`
 		*Alias
 	}{
 		Alias: (*Alias)(m),
@@ -91,25 +229,58 @@ func (m *MediaItem) UnmarshalJSON(data []byte) error {
 
 	if aux.MovieID != 0 {
 		m.ID = aux.MovieID
+		m.SetID = aux.SetID
 	} else if aux.EpisodeID != 0 {
 		m.ID = aux.EpisodeID
 		m.Episode = aux.Episodes
+		m.TVShowID = aux.TVShowID
 	} else if aux.TVShowID != 0 {
 		m.ID = aux.TVShowID
 		m.EpisodeCount = aux.Episodes
 	} else if aux.SeasonID != 0 {
 		m.ID = aux.SeasonID
 		m.EpisodeCount = aux.Episodes
+	} else if aux.SetID != 0 {
+		m.ID = aux.SetID
+	} else if aux.AlbumID != 0 {
+		m.ID = aux.AlbumID
+		m.Artist = aux.DisplayArtist
+		var artists []string
+		if m.Artist == "" && json.Unmarshal(aux.Artists, &artists) == nil {
+			m.Artist = strings.Join(artists, " / ")
+		}
+	} else if aux.ArtistID != 0 {
+		m.ID = aux.ArtistID
+	}
+	if m.Plot == "" {
+		m.Plot = aux.Description
+	}
+
+	slices.SortStableFunc(aux.Cast, func(a, b CastMember) int { return a.Order - b.Order })
+	for _, c := range aux.Cast[:min(len(aux.Cast), maxActors)] {
+		m.Actors = append(m.Actors, c.Name)
 	}
 
 	// Logic to pick the best runtime info
+	streamDuration := 0
+	if m.StreamDetails != nil && len(m.StreamDetails.Video) > 0 {
+		streamDuration = m.StreamDetails.Video[0].Duration
+	}
 	if m.Runtime == 0 {
 		if m.Duration != 0 {
 			m.Runtime = m.Duration
-		} else if m.StreamDetails != nil && len(m.StreamDetails.Video) > 0 {
-			m.Runtime = m.StreamDetails.Video[0].Duration
+		} else {
+			m.Runtime = streamDuration
 		}
 	}
+	m.Runtime = normalizeRuntime(m.Runtime, streamDuration)
+	m.RuntimeFormatted = media.FormatRuntime(m.Runtime)
+
+	m.Quality = media.FormatQuality(m.VideoResolution(), m.HDRType())
+
+	if m.StreamDetails != nil && len(m.AudioLanguages) == 0 && len(m.SubtitleLanguages) == 0 {
+		m.AudioLanguages, m.SubtitleLanguages = m.StreamDetails.languages()
+	}
 
 	if m.Title == "" && m.Label != "" {
 		m.Title = m.Label
@@ -117,39 +288,108 @@ func (m *MediaItem) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
-func (c *Client) GetMovies() ([]MediaItem, error) {
-	if c.HostURL == "mock" {
-		return []MediaItem{
-			{ID: 1, Title: "The Matrix", Year: 1999, Rating: 8.7, Runtime: 8160, Thumbnail: "https://www.themoviedb.org/t/p/w600_and_h900_bestv2/f89U3Y9YvYvwsf9qTMRS9XBt7qy.jpg"},
-			{ID: 2, Title: "Inception", Year: 2010, Rating: 8.8, Runtime: 8880, Thumbnail: "https://www.themoviedb.org/t/p/w600_and_h900_bestv2/edv5CZv0jH9upBPaY6PeBjj9d7A.jpg"},
-		}, nil
-	}
-	params := map[string]interface{}{"properties": []string{"title", "year", "rating", "plot", "runtime", "thumbnail", "art"}}
-	req := JsonRPCRequest{JSONRPC: "2.0", Method: "VideoLibrary.GetMovies", Params: params, ID: 1}
-	var resp JsonRPCResponse
-	if err := c.sendRequest(req, &resp); err != nil {
-		return nil, err
+// VideoResolution returns the resolution class of the first video stream
+// (see media.Resolution), or 0 when unknown.
+func (m *MediaItem) VideoResolution() int {
+	if m.StreamDetails == nil || len(m.StreamDetails.Video) == 0 {
+		return 0
 	}
-	var result struct {
-		Movies []MediaItem `json:"movies"`
+	v := m.StreamDetails.Video[0]
+	return media.Resolution(v.Width, v.Height)
+}
+
+// HDRType returns the HDR format of the first video stream, e.g. "hdr10"
+// or "dolbyvision", or "" for SDR.
+func (m *MediaItem) HDRType() string {
+	if m.StreamDetails == nil || len(m.StreamDetails.Video) == 0 {
+		return ""
 	}
-	result.Movies = []MediaItem{} // Initialize to avoid null
-	if err := json.Unmarshal(resp.Result, &result); err != nil {
-		slog.Error("Error unmarshaling movies", "error", err)
-		return result.Movies, nil
+	return m.StreamDetails.Video[0].HDRType
+}
+
+// IMDbID returns the item's IMDb ID (e.g. "tt0133093"), or "".
+func (m *MediaItem) IMDbID() string {
+	return m.UniqueID["imdb"]
+}
+
+// TMDbID returns the item's TMDB ID, or "".
+func (m *MediaItem) TMDbID() string {
+	return m.UniqueID["tmdb"]
+}
+
+// ResumePoint returns the resume position in whole seconds, or 0.
+func (m *MediaItem) ResumePoint() int {
+	if m.Resume == nil {
+		return 0
 	}
-	return result.Movies, nil
+	return int(m.Resume.Position)
 }
 
+// normalizeRuntime converts a runtime that may be in minutes to seconds.
+// Stream details always report seconds, so when available they decide which
+// unit the scraped runtime is in.
+func normalizeRuntime(runtime, streamDuration int) int {
+	if runtime <= 0 {
+		return 0
+	}
+	if streamDuration > 0 {
+		if abs(runtime*60-streamDuration) < abs(runtime-streamDuration) {
+			return runtime * 60
+		}
+		return runtime
+	}
+	if runtime < minutesRuntimeThreshold {
+		return runtime * 60
+	}
+	return runtime
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func moviesParams() map[string]interface{} {
+	return map[string]interface{}{"properties": []string{"title", "year", "rating", "plot", "genre", "runtime", "thumbnail", "art", "streamdetails", "playcount", "lastplayed", "resume", "uniqueid", "set", "setid", "cast", "dateadded"}}
+}
+
+func tvShowsParams() map[string]interface{} {
+	return map[string]interface{}{"properties": []string{"title", "year", "rating", "plot", "genre", "thumbnail", "episode", "watchedepisodes", "art", "playcount", "lastplayed", "uniqueid", "cast", "dateadded"}}
+}
+
+// GetMovies returns every movie in the library, fetched a page at a time.
+func (c *Client) GetMovies() ([]MediaItem, error) {
+	return c.allItems("VideoLibrary.GetMovies", 1, moviesParams(), "movies")
+}
+
+// GetTVShows returns every TV show in the library, fetched a page at a time.
 func (c *Client) GetTVShows() ([]MediaItem, error) {
-	if c.HostURL == "mock" {
-		return []MediaItem{
-			{ID: 201, Title: "Breaking Bad", Year: 2008, Rating: 9.5, EpisodeCount: 62, Thumbnail: "https://www.themoviedb.org/t/p/w600_and_h900_bestv2/ggws000vxiO0Hcm37m0B3m6idXN.jpg"},
-			{ID: 202, Title: "The Office", Year: 2005, Rating: 8.9, EpisodeCount: 201, Thumbnail: "https://www.themoviedb.org/t/p/w600_and_h900_bestv2/7D980V87m274Y6968mY96Jvwpis.jpg"},
-		}, nil
-	}
-	params := map[string]interface{}{"properties": []string{"title", "year", "rating", "plot", "thumbnail", "episode", "art"}}
-	req := JsonRPCRequest{JSONRPC: "2.0", Method: "VideoLibrary.GetTVShows", Params: params, ID: 3}
+	return c.allItems("VideoLibrary.GetTVShows", 3, tvShowsParams(), "tvshows")
+}
+
+// Search returns the movies ("movie"), TV shows ("tv"), albums ("album" or
+// "music") or artists ("artist") that may match query. Kodi can't rank
+// titles itself, so this is the whole library of that type for the caller to
+// rank with FuzzySearch and PlotSearch.
+func (c *Client) Search(mediaType, query string) ([]MediaItem, error) {
+	switch mediaType {
+	case "tv":
+		return c.GetTVShows()
+	case "album", "music":
+		return c.GetAlbums()
+	case "artist":
+		return c.GetArtists()
+	}
+	return c.GetMovies()
+}
+
+// GetTVShowCounters fetches only the episode and watched-episode counters of
+// every show, which is far cheaper than a full GetTVShows.
+func (c *Client) GetTVShowCounters() ([]MediaItem, error) {
+	params := map[string]interface{}{"properties": []string{"episode", "watchedepisodes"}}
+	req := JsonRPCRequest{JSONRPC: "2.0", Method: "VideoLibrary.GetTVShows", Params: params, ID: 7}
 	var resp JsonRPCResponse
 	if err := c.sendRequest(req, &resp); err != nil {
 		return nil, err
@@ -159,16 +399,12 @@ func (c *Client) GetTVShows() ([]MediaItem, error) {
 	}
 	result.TVShows = []MediaItem{}
 	if err := json.Unmarshal(resp.Result, &result); err != nil {
-		slog.Error("Error unmarshaling tvshows", "error", err)
-		return result.TVShows, nil
+		return nil, fmt.Errorf("failed to decode show counters: %w", err)
 	}
 	return result.TVShows, nil
 }
 
 func (c *Client) GetSeasons(tvshowid int) ([]MediaItem, error) {
-	if c.HostURL == "mock" {
-		return []MediaItem{{ID: 20101, Title: "Season 1", Season: 1, EpisodeCount: 7, ShowTitle: "Breaking Bad"}}, nil
-	}
 	params := map[string]interface{}{"tvshowid": tvshowid, "properties": []string{"season", "episode", "thumbnail", "showtitle"}}
 	req := JsonRPCRequest{JSONRPC: "2.0", Method: "VideoLibrary.GetSeasons", Params: params, ID: 4}
 	var resp JsonRPCResponse
@@ -187,9 +423,6 @@ func (c *Client) GetSeasons(tvshowid int) ([]MediaItem, error) {
 }
 
 func (c *Client) GetEpisodes(tvshowid int, season int) ([]MediaItem, error) {
-	if c.HostURL == "mock" {
-		return []MediaItem{{ID: 1001, Title: "Pilot", Season: 1, Episode: 1, Runtime: 3480, Rating: 9.2}}, nil
-	}
 	params := map[string]interface{}{"tvshowid": tvshowid, "season": season, "properties": []string{"title", "season", "episode", "runtime", "rating", "streamdetails"}}
 	req := JsonRPCRequest{JSONRPC: "2.0", Method: "VideoLibrary.GetEpisodes", Params: params, ID: 5}
 	var resp JsonRPCResponse
@@ -207,34 +440,165 @@ func (c *Client) GetEpisodes(tvshowid int, season int) ([]MediaItem, error) {
 	return result.Episodes, nil
 }
 
-func (c *Client) sendRequest(req JsonRPCRequest, resp interface{}) error {
-	body, _ := json.Marshal(req)
-	target := c.HostURL + "/jsonrpc"
-	if !strings.HasPrefix(target, "http") {
-		target = "http://" + target
+// GetShowEpisodes returns every episode of a show with its watched state, in
+// season and episode order.
+func (c *Client) GetShowEpisodes(tvshowid int) ([]MediaItem, error) {
+	params := map[string]interface{}{
+		"tvshowid":   tvshowid,
+		"properties": []string{"title", "season", "episode", "playcount", "lastplayed", "resume"},
+		"sort":       map[string]string{"method": "episode", "order": "ascending"},
+	}
+	req := JsonRPCRequest{JSONRPC: "2.0", Method: "VideoLibrary.GetEpisodes", Params: params, ID: 36}
+	var resp JsonRPCResponse
+	if err := c.sendRequest(req, &resp); err != nil {
+		return nil, err
 	}
+	var result struct {
+		Episodes []MediaItem `json:"episodes"`
+	}
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode episodes of show %d: %w", tvshowid, err)
+	}
+	if result.Episodes == nil {
+		result.Episodes = []MediaItem{}
+	}
+	return result.Episodes, nil
+}
 
-	httpReq, _ := http.NewRequest("POST", target, bytes.NewBuffer(body))
-	httpReq.Header.Set("Content-Type", "application/json")
-	if c.Username != "" {
-		httpReq.SetBasicAuth(c.Username, c.Password)
+// SetMoviePlaycount marks a movie watched (playcount > 0) or unwatched.
+func (c *Client) SetMoviePlaycount(movieID, playcount int) error {
+	params := map[string]interface{}{"movieid": movieID, "playcount": playcount}
+	req := JsonRPCRequest{JSONRPC: "2.0", Method: "VideoLibrary.SetMovieDetails", Params: params, ID: 20}
+	var resp JsonRPCResponse
+	return c.sendRequest(req, &resp)
+}
+
+// SetEpisodePlaycount marks an episode watched (playcount > 0) or unwatched.
+func (c *Client) SetEpisodePlaycount(episodeID, playcount int) error {
+	params := map[string]interface{}{"episodeid": episodeID, "playcount": playcount}
+	req := JsonRPCRequest{JSONRPC: "2.0", Method: "VideoLibrary.SetEpisodeDetails", Params: params, ID: 21}
+	var resp JsonRPCResponse
+	return c.sendRequest(req, &resp)
+}
+
+// ScanLibrary starts a video library scan for new content, of every source
+// or only of directory when it is set. Kodi scans in the background and
+// announces VideoLibrary.OnScanFinished when done.
+func (c *Client) ScanLibrary(directory string) error {
+	params := map[string]interface{}{"showdialogs": false}
+	if directory != "" {
+		params["directory"] = directory
 	}
+	req := JsonRPCRequest{JSONRPC: "2.0", Method: "VideoLibrary.Scan", Params: params, ID: 22}
+	var resp JsonRPCResponse
+	return c.sendRequest(req, &resp)
+}
 
-	httpResp, err := c.HTTPClient.Do(httpReq)
-	if err != nil {
+// CleanLibrary starts removing library entries whose files no longer exist,
+// limited to content ("movies" or "tvshows") when it is set. Kodi announces
+// VideoLibrary.OnCleanFinished when done.
+func (c *Client) CleanLibrary(content string) error {
+	params := map[string]interface{}{"showdialogs": false}
+	if content != "" {
+		params["content"] = content
+	}
+	req := JsonRPCRequest{JSONRPC: "2.0", Method: "VideoLibrary.Clean", Params: params, ID: 23}
+	var resp JsonRPCResponse
+	return c.sendRequest(req, &resp)
+}
+
+// Ping checks that the host is reachable and accepts the client's credentials.
+func (c *Client) Ping() error {
+	req := JsonRPCRequest{JSONRPC: "2.0", Method: "JSONRPC.Ping", ID: 6}
+	var resp JsonRPCResponse
+	if err := c.sendRequest(req, &resp); err != nil {
 		return err
 	}
-	defer httpResp.Body.Close()
+	var pong string
+	if err := json.Unmarshal(resp.Result, &pong); err != nil || pong != "pong" {
+		return fmt.Errorf("unexpected ping response: %s", string(resp.Result))
+	}
+	return nil
+}
 
-	if err := json.NewDecoder(httpResp.Body).Decode(resp); err != nil {
+func (c *Client) sendRequest(req JsonRPCRequest, resp interface{}) error {
+	body, _ := json.Marshal(req)
+	post := func() rawResponse { return c.post(body) }
+
+	var raw rawResponse
+	if coalesced(req.Method) {
+		// The request ID is fixed per call site, so the key leaves it out.
+		params, _ := json.Marshal(req.Params)
+		key := strings.Join([]string{c.HostURL, c.Username, c.Password, req.Method, string(params)}, "\x00")
+		var shared bool
+		raw, shared = coalesce(key, func() rawResponse { return c.withRetries(retryable(req.Method), post) })
+		if shared {
+			slog.Debug("Shared in-flight Kodi query", "host", c.HostURL, "method", req.Method)
+		}
+	} else {
+		raw = c.withRetries(retryable(req.Method), post)
+	}
+	if err := c.checkResponse(raw); err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(raw.body, resp); err != nil {
 		return err
 	}
 
 	// Check for RPC error
 	r, ok := resp.(*JsonRPCResponse)
 	if ok && r.Error != nil {
-		return fmt.Errorf("kodi rpc error: %s (code: %d)", r.Error.Message, r.Error.Code)
+		return r.Error
 	}
 
 	return nil
 }
+
+// checkResponse turns a failed exchange into an error, telling OnAuth
+// whether Kodi accepted the credentials.
+func (c *Client) checkResponse(raw rawResponse) error {
+	if raw.err != nil {
+		return raw.err
+	}
+	authFailed := raw.status == http.StatusUnauthorized || raw.status == http.StatusForbidden
+	if c.OnAuth != nil {
+		c.OnAuth(!authFailed)
+	}
+	if authFailed {
+		return &AuthError{Host: c.HostURL, StatusCode: raw.status}
+	}
+	if raw.status != http.StatusOK {
+		return fmt.Errorf("kodi returned HTTP %d", raw.status)
+	}
+	return nil
+}
+
+// baseURL returns HostURL with http:// added when it has no scheme, as a
+// bare host name means.
+func (c *Client) baseURL() string {
+	if strings.HasPrefix(c.HostURL, "http://") || strings.HasPrefix(c.HostURL, "https://") {
+		return c.HostURL
+	}
+	return "http://" + c.HostURL
+}
+
+// post sends a JSON-RPC request body to Kodi and reads the whole response.
+func (c *Client) post(body []byte) rawResponse {
+	target := c.baseURL() + "/jsonrpc"
+
+	httpReq, _ := http.NewRequest("POST", target, bytes.NewBuffer(body))
+	httpReq.Header.Set("Content-Type", "application/json")
+	if c.Username != "" {
+		httpReq.SetBasicAuth(c.Username, c.Password)
+	}
+
+	httpResp, err := c.HTTPClient.Do(httpReq)
+	if err != nil {
+		return rawResponse{err: err}
+	}
+	defer httpResp.Body.Close()
+
+	data, err := io.ReadAll(httpResp.Body)
+	return rawResponse{status: httpResp.StatusCode, body: data, err: err}
+}
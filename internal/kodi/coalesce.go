@@ -0,0 +1,59 @@
+package kodi
+
+import (
+	"strings"
+	"sync"
+)
+
+// Identical library queries that arrive while one is already in flight to
+// the same host share its response instead of sending their own, so a
+// handful of people opening the add dialog at once costs an underpowered
+// Kodi box one query rather than one each.
+
+// rawResponse is what a coalesced query hands to every caller waiting on it.
+type rawResponse struct {
+	status int
+	body   []byte
+	err    error
+}
+
+type flight struct {
+	done chan struct{}
+	resp rawResponse
+}
+
+var (
+	flightsMu sync.Mutex
+	flights   = map[string]*flight{}
+)
+
+// coalesced reports whether method may share a response with identical
+// concurrent calls. Only library reads qualify; writes and player commands
+// always go out individually.
+func coalesced(method string) bool {
+	return strings.HasPrefix(method, "VideoLibrary.Get")
+}
+
+// coalesce runs fetch for key unless a call with the same key is already in
+// flight, in which case it waits for that call and returns its response.
+// shared reports whether the response came from another caller's fetch.
+func coalesce(key string, fetch func() rawResponse) (resp rawResponse, shared bool) {
+	flightsMu.Lock()
+	if f, ok := flights[key]; ok {
+		flightsMu.Unlock()
+		<-f.done
+		return f.resp, true
+	}
+	f := &flight{done: make(chan struct{})}
+	flights[key] = f
+	flightsMu.Unlock()
+
+	defer func() {
+		flightsMu.Lock()
+		delete(flights, key)
+		flightsMu.Unlock()
+		close(f.done)
+	}()
+	f.resp = fetch()
+	return f.resp, false
+}
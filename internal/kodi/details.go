@@ -0,0 +1,244 @@
+package kodi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"whats-next/internal/media"
+)
+
+// ErrNotFound is returned when Kodi has no library item with the requested ID.
+var ErrNotFound = errors.New("not found in the Kodi library")
+
+// rpcInvalidParams is the JSON-RPC code Kodi answers unknown library IDs with.
+const rpcInvalidParams = -32602
+
+// CastMember is one actor of a title. Thumbnail is a Kodi image:// URI.
+type CastMember struct {
+	Name      string `json:"name"`
+	Role      string `json:"role,omitempty"`
+	Order     int    `json:"order"`
+	Thumbnail string `json:"thumbnail,omitempty"`
+}
+
+type VideoStreamDetail struct {
+	Codec    string  `json:"codec,omitempty"`
+	Width    int     `json:"width,omitempty"`
+	Height   int     `json:"height,omitempty"`
+	Aspect   float64 `json:"aspect,omitempty"`
+	Duration int     `json:"duration,omitempty"`
+	HDRType  string  `json:"hdrtype,omitempty"`
+}
+
+type AudioStreamDetail struct {
+	Codec    string `json:"codec,omitempty"`
+	Language string `json:"language,omitempty"`
+	Channels int    `json:"channels,omitempty"`
+}
+
+type SubtitleStreamDetail struct {
+	Language string `json:"language,omitempty"`
+}
+
+// MovieDetails is everything a detail view shows about one movie.
+type MovieDetails struct {
+	ID               int               `json:"id"`
+	Title            string            `json:"title"`
+	OriginalTitle    string            `json:"originaltitle,omitempty"`
+	Tagline          string            `json:"tagline,omitempty"`
+	Plot             string            `json:"plot,omitempty"`
+	Year             int               `json:"year,omitempty"`
+	Rating           float64           `json:"rating,omitempty"`
+	MPAA             string            `json:"mpaa,omitempty"`
+	Runtime          int               `json:"runtime,omitempty"` // seconds
+	RuntimeFormatted string            `json:"runtime_formatted,omitempty"`
+	Genres           []string          `json:"genre,omitempty"`
+	Director         []string          `json:"director,omitempty"`
+	Writer           []string          `json:"writer,omitempty"`
+	Studio           []string          `json:"studio,omitempty"`
+	Country          []string          `json:"country,omitempty"`
+	Cast             []CastMember      `json:"cast"`
+	Thumbnail        string            `json:"thumbnail,omitempty"`
+	Art              map[string]string `json:"art,omitempty"`
+	Playcount        int               `json:"playcount"`
+	StreamDetails    DetailStreams     `json:"streamdetails"`
+}
+
+// DetailStreams are the video, audio and subtitle streams of a file.
+type DetailStreams struct {
+	Video    []VideoStreamDetail    `json:"video"`
+	Audio    []AudioStreamDetail    `json:"audio"`
+	Subtitle []SubtitleStreamDetail `json:"subtitle"`
+}
+
+// runtime normalizes a scraped runtime against the stream duration.
+func (d DetailStreams) runtime(runtime int) int {
+	streamDuration := 0
+	if len(d.Video) > 0 {
+		streamDuration = d.Video[0].Duration
+	}
+	return normalizeRuntime(runtime, streamDuration)
+}
+
+// TVShowDetails is everything a detail view shows about one show.
+type TVShowDetails struct {
+	ID              int               `json:"id"`
+	Title           string            `json:"title"`
+	OriginalTitle   string            `json:"originaltitle,omitempty"`
+	Plot            string            `json:"plot,omitempty"`
+	Year            int               `json:"year,omitempty"`
+	Premiered       string            `json:"premiered,omitempty"` // first aired, "YYYY-MM-DD"
+	Rating          float64           `json:"rating,omitempty"`
+	MPAA            string            `json:"mpaa,omitempty"`
+	Status          string            `json:"status,omitempty"`
+	Genres          []string          `json:"genre,omitempty"`
+	Studio          []string          `json:"studio,omitempty"`
+	Cast            []CastMember      `json:"cast"`
+	Seasons         int               `json:"season,omitempty"`
+	Episodes        int               `json:"episode,omitempty"`
+	WatchedEpisodes int               `json:"watchedepisodes"`
+	Thumbnail       string            `json:"thumbnail,omitempty"`
+	Art             map[string]string `json:"art,omitempty"`
+}
+
+// EpisodeDetails is everything a detail view shows about one episode.
+type EpisodeDetails struct {
+	ID               int               `json:"id"`
+	Title            string            `json:"title"`
+	ShowTitle        string            `json:"showtitle,omitempty"`
+	TVShowID         int               `json:"tvshowid"`
+	Season           int               `json:"season"`
+	Episode          int               `json:"episode"`
+	FirstAired       string            `json:"firstaired,omitempty"` // "YYYY-MM-DD"
+	Plot             string            `json:"plot,omitempty"`
+	Rating           float64           `json:"rating,omitempty"`
+	Runtime          int               `json:"runtime,omitempty"` // seconds
+	RuntimeFormatted string            `json:"runtime_formatted,omitempty"`
+	Director         []string          `json:"director,omitempty"`
+	Writer           []string          `json:"writer,omitempty"`
+	Cast             []CastMember      `json:"cast"`
+	Thumbnail        string            `json:"thumbnail,omitempty"`
+	Art              map[string]string `json:"art,omitempty"`
+	Playcount        int               `json:"playcount"`
+	StreamDetails    DetailStreams     `json:"streamdetails"`
+}
+
+// GetMovieDetails fetches the full details of one movie, returning
+// ErrNotFound if the library has no movie with that ID.
+func (c *Client) GetMovieDetails(movieID int) (*MovieDetails, error) {
+	params := map[string]interface{}{
+		"movieid": movieID,
+		"properties": []string{"title", "originaltitle", "tagline", "plot", "year", "rating", "mpaa", "runtime",
+			"genre", "director", "writer", "studio", "country", "cast", "thumbnail", "art", "playcount", "streamdetails"},
+	}
+	var result struct {
+		MovieDetails *struct {
+			MovieID int    `json:"movieid"`
+			Label   string `json:"label"`
+			MovieDetails
+		} `json:"moviedetails"`
+	}
+	if err := c.getDetails("VideoLibrary.GetMovieDetails", params, 24, &result); err != nil {
+		return nil, err
+	}
+	if result.MovieDetails == nil {
+		return nil, ErrNotFound
+	}
+	d := result.MovieDetails.MovieDetails
+	d.ID = result.MovieDetails.MovieID
+	if d.Title == "" {
+		d.Title = result.MovieDetails.Label
+	}
+	if d.Cast == nil {
+		d.Cast = []CastMember{}
+	}
+	d.Runtime = d.StreamDetails.runtime(d.Runtime)
+	d.RuntimeFormatted = media.FormatRuntime(d.Runtime)
+	return &d, nil
+}
+
+// GetTVShowDetails fetches the full details of one show, returning
+// ErrNotFound if the library has no show with that ID.
+func (c *Client) GetTVShowDetails(tvshowID int) (*TVShowDetails, error) {
+	params := map[string]interface{}{
+		"tvshowid": tvshowID,
+		"properties": []string{"title", "originaltitle", "plot", "year", "premiered", "rating", "mpaa", "status",
+			"genre", "studio", "cast", "season", "episode", "watchedepisodes", "thumbnail", "art"},
+	}
+	var result struct {
+		TVShowDetails *struct {
+			TVShowID int    `json:"tvshowid"`
+			Label    string `json:"label"`
+			TVShowDetails
+		} `json:"tvshowdetails"`
+	}
+	if err := c.getDetails("VideoLibrary.GetTVShowDetails", params, 25, &result); err != nil {
+		return nil, err
+	}
+	if result.TVShowDetails == nil {
+		return nil, ErrNotFound
+	}
+	d := result.TVShowDetails.TVShowDetails
+	d.ID = result.TVShowDetails.TVShowID
+	if d.Title == "" {
+		d.Title = result.TVShowDetails.Label
+	}
+	if d.Cast == nil {
+		d.Cast = []CastMember{}
+	}
+	return &d, nil
+}
+
+// GetEpisodeDetails fetches the full details of one episode, returning
+// ErrNotFound if the library has no episode with that ID.
+func (c *Client) GetEpisodeDetails(episodeID int) (*EpisodeDetails, error) {
+	params := map[string]interface{}{
+		"episodeid": episodeID,
+		"properties": []string{"title", "showtitle", "tvshowid", "season", "episode", "firstaired", "plot", "rating",
+			"runtime", "director", "writer", "cast", "thumbnail", "art", "playcount", "streamdetails"},
+	}
+	var result struct {
+		EpisodeDetails *struct {
+			EpisodeID int    `json:"episodeid"`
+			Label     string `json:"label"`
+			EpisodeDetails
+		} `json:"episodedetails"`
+	}
+	if err := c.getDetails("VideoLibrary.GetEpisodeDetails", params, 26, &result); err != nil {
+		return nil, err
+	}
+	if result.EpisodeDetails == nil {
+		return nil, ErrNotFound
+	}
+	d := result.EpisodeDetails.EpisodeDetails
+	d.ID = result.EpisodeDetails.EpisodeID
+	if d.Title == "" {
+		d.Title = result.EpisodeDetails.Label
+	}
+	if d.Cast == nil {
+		d.Cast = []CastMember{}
+	}
+	d.Runtime = d.StreamDetails.runtime(d.Runtime)
+	d.RuntimeFormatted = media.FormatRuntime(d.Runtime)
+	return &d, nil
+}
+
+// getDetails sends a Get*Details request, of the video library or the PVR,
+// and decodes its result into out, mapping Kodi's answer for unknown IDs to
+// ErrNotFound.
+func (c *Client) getDetails(method string, params map[string]interface{}, id int, out interface{}) error {
+	req := JsonRPCRequest{JSONRPC: "2.0", Method: method, Params: params, ID: id}
+	var resp JsonRPCResponse
+	if err := c.sendRequest(req, &resp); err != nil {
+		var rpcErr *JsonRPCError
+		if errors.As(err, &rpcErr) && rpcErr.Code == rpcInvalidParams {
+			return ErrNotFound
+		}
+		return err
+	}
+	if err := json.Unmarshal(resp.Result, out); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", method, err)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package kodi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// DefaultEventPort is Kodi's raw TCP JSON-RPC port, where it pushes
+// notifications to every connected client.
+const DefaultEventPort = 9090
+
+// Notification is a JSON-RPC notification pushed by Kodi, e.g.
+// "VideoLibrary.OnUpdate". Data holds params.data, whose shape depends on
+// the method.
+type Notification struct {
+	Method string
+	Data   json.RawMessage
+}
+
+// NotificationItem identifies the library item a notification refers to.
+// Type is "movie", "episode", "tvshow", etc.
+type NotificationItem struct {
+	ID   int    `json:"id"`
+	Type string `json:"type"`
+}
+
+// LibraryUpdate is the data of VideoLibrary.OnUpdate. Playcount is only set
+// when the watched state changed; Added marks newly scanned items.
+type LibraryUpdate struct {
+	Item      NotificationItem `json:"item"`
+	Playcount *int             `json:"playcount,omitempty"`
+	Added     bool             `json:"added,omitempty"`
+}
+
+// LibraryRemove is the data of VideoLibrary.OnRemove.
+type LibraryRemove struct {
+	ID   int    `json:"id"`
+	Type string `json:"type"`
+}
+
+// PlayerStop is the data of Player.OnStop. End is true when playback reached
+// the end of the item rather than being stopped early.
+type PlayerStop struct {
+	Item NotificationItem `json:"item"`
+	End  bool             `json:"end"`
+}
+
+// EventAddress returns the host:port of the client's TCP notification
+// interface on the given port.
+func (c *Client) EventAddress(port int) (string, error) {
+	u, err := url.Parse(c.baseURL())
+	if err != nil {
+		return "", fmt.Errorf("invalid Kodi host %q: %w", c.HostURL, err)
+	}
+	if u.Hostname() == "" {
+		return "", fmt.Errorf("invalid Kodi host %q", c.HostURL)
+	}
+	return net.JoinHostPort(u.Hostname(), strconv.Itoa(port)), nil
+}
+
+// Listen connects to Kodi's TCP notification interface at addr and calls
+// handle for each notification until ctx is cancelled or the connection
+// drops. connected is called once the connection is up. Listen always
+// returns a non-nil error; it is ctx.Err() after cancellation.
+func Listen(ctx context.Context, addr string, connected func(), handle func(Notification)) error {
+	dialer := net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if connected != nil {
+		connected()
+	}
+
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	// The stream is a sequence of JSON objects with no framing
+	dec := json.NewDecoder(conn)
+	for {
+		var msg struct {
+			Method string `json:"method"`
+			Params struct {
+				Data json.RawMessage `json:"data"`
+			} `json:"params"`
+		}
+		if err := dec.Decode(&msg); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("notification stream closed: %w", err)
+		}
+		if msg.Method == "" {
+			continue // a response to a request, not a notification
+		}
+		handle(Notification{Method: msg.Method, Data: msg.Params.Data})
+	}
+}
@@ -0,0 +1,19 @@
+package kodi
+
+import "testing"
+
+func TestEventAddress(t *testing.T) {
+	for host, want := range map[string]string{
+		"192.168.1.10:8080":        "192.168.1.10:9090",
+		"kodi1":                    "kodi1:9090",
+		"kodi.local:8080":          "kodi.local:9090",
+		"http://kodi.local:8080":   "kodi.local:9090",
+		"https://kodi.example.com": "kodi.example.com:9090",
+		"http://[::1]:8080":        "[::1]:9090",
+	} {
+		got, err := NewClient(host, "", "").EventAddress(9090)
+		if err != nil || got != want {
+			t.Errorf("EventAddress(%q) = %q, %v; want %q", host, got, err, want)
+		}
+	}
+}
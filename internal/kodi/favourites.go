@@ -0,0 +1,68 @@
+package kodi
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Favourite is an entry in Kodi's favourites menu. Type is media, window,
+// script, androidapp or unknown; media favourites play Path, and window
+// favourites open Window at WindowParameter.
+type Favourite struct {
+	Title           string `json:"title"`
+	Type            string `json:"type"`
+	Path            string `json:"path,omitempty"`
+	Window          string `json:"window,omitempty"`
+	WindowParameter string `json:"windowparameter,omitempty"`
+	Thumbnail       string `json:"thumbnail,omitempty"`
+}
+
+// GetFavourites returns the favourites pinned in Kodi, in menu order.
+func (c *Client) GetFavourites() ([]Favourite, error) {
+	params := map[string]interface{}{
+		"properties": []string{"path", "window", "windowparameter", "thumbnail"},
+	}
+	var result struct {
+		Favourites []Favourite `json:"favourites"`
+	}
+	if err := c.call("Favourites.GetFavourites", 37, params, &result); err != nil {
+		return nil, err
+	}
+	if result.Favourites == nil {
+		return []Favourite{}, nil
+	}
+	return result.Favourites, nil
+}
+
+// LibraryItem returns the library movie or TV show a favourite points at
+// through a videodb:// URL, such as videodb://movies/titles/12 or, for a
+// show's seasons or episodes, videodb://tvshows/titles/3/1/. ok is false for
+// favourites that aren't library items or only name a file.
+func (f Favourite) LibraryItem() (mediaType string, id int, ok bool) {
+	target := f.Path
+	if f.Type == "window" {
+		target = f.WindowParameter
+	}
+	path, found := strings.CutPrefix(target, "videodb://")
+	if !found {
+		return "", 0, false
+	}
+	path, _, _ = strings.Cut(path, "?")
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) < 3 || parts[1] != "titles" {
+		return "", 0, false
+	}
+	switch parts[0] {
+	case "movies":
+		mediaType = "movie"
+	case "tvshows":
+		mediaType = "show"
+	default:
+		return "", 0, false
+	}
+	id, err := strconv.Atoi(parts[2])
+	if err != nil || id <= 0 {
+		return "", 0, false
+	}
+	return mediaType, id, true
+}
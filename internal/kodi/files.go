@@ -0,0 +1,66 @@
+package kodi
+
+// Source is a media source configured in Kodi, such as a network share of
+// home videos. File is its root path.
+type Source struct {
+	File  string `json:"file"`
+	Label string `json:"label"`
+}
+
+// FileEntry is an entry of a directory listing. FileType is "file" or
+// "directory"; Type is the library type Kodi knows the file as, "unknown"
+// for files that aren't in the library.
+type FileEntry struct {
+	File     string `json:"file"`
+	Label    string `json:"label"`
+	FileType string `json:"filetype"`
+	Type     string `json:"type,omitempty"`
+	Title    string `json:"title,omitempty"`
+	// Runtime is in seconds, when Kodi knows it.
+	Runtime      int    `json:"runtime,omitempty"`
+	Size         int64  `json:"size,omitempty"`
+	LastModified string `json:"lastmodified,omitempty"`
+	Thumbnail    string `json:"thumbnail,omitempty"`
+}
+
+// GetSources returns Kodi's video sources.
+func (c *Client) GetSources() ([]Source, error) {
+	params := map[string]interface{}{"media": "video"}
+	var result struct {
+		Sources []Source `json:"sources"`
+	}
+	if err := c.call("Files.GetSources", 38, params, &result); err != nil {
+		return nil, err
+	}
+	if result.Sources == nil {
+		return []Source{}, nil
+	}
+	return result.Sources, nil
+}
+
+// GetDirectory lists the video files and subdirectories of dir, which must
+// be within one of the sources, directories first.
+func (c *Client) GetDirectory(dir string) ([]FileEntry, error) {
+	params := map[string]interface{}{
+		"directory":  dir,
+		"media":      "video",
+		"properties": []string{"title", "runtime", "size", "lastmodified", "thumbnail"},
+		"sort":       map[string]interface{}{"method": "label", "order": "ascending", "ignorearticle": true},
+	}
+	var result struct {
+		Files []FileEntry `json:"files"`
+	}
+	if err := c.call("Files.GetDirectory", 39, params, &result); err != nil {
+		return nil, err
+	}
+	dirs := []FileEntry{}
+	var files []FileEntry
+	for _, f := range result.Files {
+		if f.FileType == "directory" {
+			dirs = append(dirs, f)
+		} else {
+			files = append(files, f)
+		}
+	}
+	return append(dirs, files...), nil
+}
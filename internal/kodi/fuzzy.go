@@ -1,14 +1,106 @@
 package kodi
 
 import (
+	"fmt"
+	"slices"
 	"sort"
 	"strings"
+	"unicode"
 )
 
+// Fuzzy search strategies.
+const (
+	FuzzyLevenshtein = "levenshtein"
+	FuzzyTokenSet    = "token_set"
+)
+
+// FuzzyOptions tunes how FuzzySearch ranks titles. A title starting with the
+// query scores -PrefixBonus, one containing it ContainsScore, and any other
+// its edit distance from the query if that is at most MaxDistance; lower
+// scores rank first. Unset fields take the defaults.
+type FuzzyOptions struct {
+	// Strategy is "levenshtein" (the default), which compares the query
+	// with the whole title, or "token_set", which compares their words in
+	// any order and ignores articles, so "matrix the" finds "The Matrix".
+	Strategy string `json:"strategy,omitempty"`
+
+	PrefixBonus   *int `json:"prefix_bonus,omitempty"`   // defaults to 50
+	ContainsScore *int `json:"contains_score,omitempty"` // defaults to 0
+
+	// MaxDistance defaults to half the query's length, and at least 3.
+	MaxDistance int `json:"max_distance,omitempty"`
+	MaxResults  int `json:"max_results,omitempty"` // defaults to 20
+}
+
+// With returns o with the fields set in other replacing its own, e.g. a
+// list's tuning over the global one.
+func (o FuzzyOptions) With(other FuzzyOptions) FuzzyOptions {
+	if other.Strategy != "" {
+		o.Strategy = other.Strategy
+	}
+	if other.PrefixBonus != nil {
+		o.PrefixBonus = other.PrefixBonus
+	}
+	if other.ContainsScore != nil {
+		o.ContainsScore = other.ContainsScore
+	}
+	if other.MaxDistance != 0 {
+		o.MaxDistance = other.MaxDistance
+	}
+	if other.MaxResults != 0 {
+		o.MaxResults = other.MaxResults
+	}
+	return o
+}
+
+// Validate reports options FuzzySearch can't apply.
+func (o FuzzyOptions) Validate() error {
+	if o.Strategy != "" && o.Strategy != FuzzyLevenshtein && o.Strategy != FuzzyTokenSet {
+		return fmt.Errorf("invalid strategy %q: must be %q or %q", o.Strategy, FuzzyLevenshtein, FuzzyTokenSet)
+	}
+	if o.MaxDistance < 0 {
+		return fmt.Errorf("invalid max_distance %d: must not be negative", o.MaxDistance)
+	}
+	if o.MaxResults < 0 {
+		return fmt.Errorf("invalid max_results %d: must not be negative", o.MaxResults)
+	}
+	return nil
+}
+
+func (o FuzzyOptions) maxDistance(query string) int {
+	if o.MaxDistance > 0 {
+		return o.MaxDistance
+	}
+	return max(len(query)/2, 3)
+}
+
+func (o FuzzyOptions) prefixScore() int {
+	if o.PrefixBonus != nil {
+		return -*o.PrefixBonus
+	}
+	return -50
+}
+
+func (o FuzzyOptions) containsScore() int {
+	if o.ContainsScore != nil {
+		return *o.ContainsScore
+	}
+	return 0
+}
+
 // FuzzySearch filters and ranks items based on the query.
 // It returns a subset of items that match the query, sorted by relevance.
 func FuzzySearch(items []MediaItem, query string) []MediaItem {
+	return FuzzySearchWith(items, query, FuzzyOptions{})
+}
+
+// FuzzySearchWith is FuzzySearch tuned by opts.
+func FuzzySearchWith(items []MediaItem, query string, opts FuzzyOptions) []MediaItem {
 	query = strings.ToLower(query)
+	score := opts.scoreTitle
+	if opts.Strategy == FuzzyTokenSet {
+		score = opts.scoreWords
+	}
 	var results []struct {
 		item  MediaItem
 		score int
@@ -20,43 +112,16 @@ func FuzzySearch(items []MediaItem, query string) []MediaItem {
 		if item.ShowTitle != "" {
 			target += " " + strings.ToLower(item.ShowTitle)
 		}
-
-		// 0. Exact Prefix (Highest Priority) - "bre" -> "breaking bad"
-		if strings.HasPrefix(target, query) {
-			results = append(results, struct {
-				item  MediaItem
-				score int
-			}{item, -50}) // Extremely low score = best rank
-			continue
-		}
-
-		// 1. Exact match or Contains (Medium Priority)
-		if strings.Contains(target, query) {
+		if s, ok := score(query, target); ok {
 			results = append(results, struct {
 				item  MediaItem
 				score int
-			}{item, 0}) // 0 score for "good" match
-			continue
-		}
-
-		// 2. Levenshtein Distance (Fuzzy)
-		// Only consider if the distance is within a reasonable threshold (e.g., 30% of length or absolute value)
-		dist := levenshtein(query, target)
-		threshold := len(query) / 2
-		if threshold < 3 {
-			threshold = 3
-		}
-
-		if dist <= threshold {
-			results = append(results, struct {
-				item  MediaItem
-				score int
-			}{item, dist})
+			}{item, s})
 		}
 	}
 
 	// Sort results: Lower score is better
-	sort.Slice(results, func(i, j int) bool {
+	sort.SliceStable(results, func(i, j int) bool {
 		return results[i].score < results[j].score
 	})
 
@@ -64,13 +129,78 @@ func FuzzySearch(items []MediaItem, query string) []MediaItem {
 	for i, r := range results {
 		out[i] = r.item
 	}
-	// Limit to top 20 to keep UI snappy
-	if len(out) > 20 {
-		return out[:20]
+	// Limit to top 20 by default to keep UI snappy
+	limit := opts.MaxResults
+	if limit == 0 {
+		limit = 20
+	}
+	if len(out) > limit {
+		return out[:limit]
 	}
 	return out
 }
 
+// scoreTitle scores target against the whole query string.
+func (o FuzzyOptions) scoreTitle(query, target string) (int, bool) {
+	// Exact prefix ranks highest - "bre" -> "breaking bad"
+	if strings.HasPrefix(target, query) {
+		return o.prefixScore(), true
+	}
+	if strings.Contains(target, query) {
+		return o.containsScore(), true
+	}
+	// Levenshtein distance, only within a reasonable threshold
+	dist := levenshtein(query, target)
+	return dist, dist <= o.maxDistance(query)
+}
+
+// scoreWords scores target by the words it shares with the query. The last
+// query word may be unfinished, so it also matches words it starts.
+func (o FuzzyOptions) scoreWords(query, target string) (int, bool) {
+	q, t := titleWords(query), titleWords(target)
+	if len(q) == 0 || len(t) == 0 {
+		return o.scoreTitle(query, target)
+	}
+	last := len(q) - 1
+	matches := func(word, qWord string, i int) bool {
+		return word == qWord || (i == last && strings.HasPrefix(word, qWord))
+	}
+
+	prefix := len(t) >= len(q)
+	for i := range q {
+		prefix = prefix && matches(t[i], q[i], i)
+	}
+	if prefix {
+		return o.prefixScore(), true
+	}
+	contains := true
+	for i, qWord := range q {
+		if !slices.ContainsFunc(t, func(word string) bool { return matches(word, qWord, i) }) {
+			contains = false
+			break
+		}
+	}
+	if contains {
+		return o.containsScore(), true
+	}
+
+	slices.Sort(q)
+	slices.Sort(t)
+	sortedQuery := strings.Join(q, " ")
+	dist := levenshtein(sortedQuery, strings.Join(t, " "))
+	return dist, dist <= o.maxDistance(sortedQuery)
+}
+
+// titleArticles are left out when titles are compared word by word.
+var titleArticles = []string{"the", "a", "an"}
+
+// titleWords splits s into words, dropping articles. It is empty when s is
+// nothing but articles, which are then compared whole.
+func titleWords(s string) []string {
+	words := strings.FieldsFunc(s, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+	return slices.DeleteFunc(words, func(w string) bool { return slices.Contains(titleArticles, w) })
+}
+
 func levenshtein(s1, s2 string) int {
 	r1, r2 := []rune(s1), []rune(s2)
 	n, m := len(r1), len(r2)
@@ -105,3 +235,27 @@ func min(a, b int) int {
 	}
 	return b
 }
+
+// PlotSearch returns the items whose plot contains every word of the query,
+// for when the title can't be remembered. Items are returned in library order.
+func PlotSearch(items []MediaItem, query string) []MediaItem {
+	words := strings.Fields(strings.ToLower(query))
+	if len(words) == 0 {
+		return nil
+	}
+	var out []MediaItem
+	for _, item := range items {
+		plot := strings.ToLower(item.Plot)
+		matched := plot != ""
+		for _, word := range words {
+			if !strings.Contains(plot, word) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			out = append(out, item)
+		}
+	}
+	return out
+}
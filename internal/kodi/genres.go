@@ -0,0 +1,50 @@
+package kodi
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Genre is a genre known to the Kodi library.
+type Genre struct {
+	ID    int    `json:"genreid"`
+	Title string `json:"title"`
+}
+
+func genresParams(mediaType string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":       mediaType,
+		"properties": []string{"title"},
+		"sort":       map[string]string{"method": "label", "order": "ascending"},
+	}
+}
+
+// GetGenres returns the genres Kodi has for mediaType, "movie" or "tvshow".
+func (c *Client) GetGenres(mediaType string) ([]Genre, error) {
+	req := JsonRPCRequest{JSONRPC: "2.0", Method: "VideoLibrary.GetGenres", Params: genresParams(mediaType), ID: 31}
+	var resp JsonRPCResponse
+	if err := c.sendRequest(req, &resp); err != nil {
+		return nil, err
+	}
+	return decodeGenres(resp.Result)
+}
+
+func decodeGenres(result json.RawMessage) ([]Genre, error) {
+	var decoded struct {
+		Genres []struct {
+			Genre
+			Label string `json:"label"`
+		} `json:"genres"`
+	}
+	if err := json.Unmarshal(result, &decoded); err != nil {
+		return nil, fmt.Errorf("failed to decode genres: %w", err)
+	}
+	genres := make([]Genre, 0, len(decoded.Genres))
+	for _, g := range decoded.Genres {
+		if g.Title == "" {
+			g.Title = g.Label
+		}
+		genres = append(genres, g.Genre)
+	}
+	return genres, nil
+}
@@ -0,0 +1,61 @@
+package kodi
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// earliestPlayed is the lastplayed filter value that matches any item Kodi
+// has a last played time for.
+const earliestPlayed = "1900-01-01 00:00:00"
+
+// GetPlayedMovies returns the movies last played after since, a Kodi
+// "YYYY-MM-DD HH:MM:SS" time, most recent first. An empty since returns
+// every movie that was ever played.
+func (c *Client) GetPlayedMovies(since string) ([]MediaItem, error) {
+	var result struct {
+		Movies []MediaItem `json:"movies"`
+	}
+	if err := c.getPlayed("VideoLibrary.GetMovies", 27, since, []string{"title", "year", "runtime", "streamdetails", "playcount", "lastplayed", "resume"}, &result); err != nil {
+		return nil, err
+	}
+	if result.Movies == nil {
+		result.Movies = []MediaItem{}
+	}
+	return result.Movies, nil
+}
+
+// GetPlayedEpisodes returns the episodes of every show last played after
+// since, most recent first, like GetPlayedMovies.
+func (c *Client) GetPlayedEpisodes(since string) ([]MediaItem, error) {
+	var result struct {
+		Episodes []MediaItem `json:"episodes"`
+	}
+	if err := c.getPlayed("VideoLibrary.GetEpisodes", 28, since, []string{"title", "showtitle", "tvshowid", "season", "episode", "runtime", "streamdetails", "playcount", "lastplayed", "resume"}, &result); err != nil {
+		return nil, err
+	}
+	if result.Episodes == nil {
+		result.Episodes = []MediaItem{}
+	}
+	return result.Episodes, nil
+}
+
+func (c *Client) getPlayed(method string, id int, since string, properties []string, out interface{}) error {
+	if since == "" {
+		since = earliestPlayed
+	}
+	params := map[string]interface{}{
+		"properties": properties,
+		"filter":     map[string]string{"field": "lastplayed", "operator": "greaterthan", "value": since},
+		"sort":       map[string]string{"method": "lastplayed", "order": "descending"},
+	}
+	req := JsonRPCRequest{JSONRPC: "2.0", Method: method, Params: params, ID: id}
+	var resp JsonRPCResponse
+	if err := c.sendRequest(req, &resp); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(resp.Result, out); err != nil {
+		return fmt.Errorf("failed to decode played items: %w", err)
+	}
+	return nil
+}
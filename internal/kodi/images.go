@@ -0,0 +1,73 @@
+package kodi
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ErrImageNotFound is returned for artwork the host doesn't have.
+var ErrImageNotFound = errors.New("image not found")
+
+// defaultImageTimeout bounds artwork downloads when ImageTimeout is unset.
+const defaultImageTimeout = 30 * time.Second
+
+// errImageForbidden is returned when Kodi's web server refuses a path, as
+// recent versions do for /vfs/ paths outside the media sources.
+var errImageForbidden = errors.New("image path not allowed by Kodi")
+
+// GetArtwork downloads the artwork at an art URI such as
+// image://video@host/poster.jpg/. With UseTextures, Kodi's cached thumbnail
+// is preferred when it has one; otherwise the original is fetched through
+// /image/. The caller closes the body.
+func (c *Client) GetArtwork(imageURI string) (io.ReadCloser, error) {
+	if c.UseTextures && !c.noTextures.Load() {
+		body, err := c.getCachedArtwork(imageURI)
+		if err == nil {
+			return body, nil
+		}
+		if !errors.Is(err, errNoTexture) {
+			slog.Warn("Failed to read Kodi's texture cache, downloading original", "uri", imageURI, "error", err)
+		}
+	}
+	return c.fetchImage("/image/" + url.QueryEscape(imageURI))
+}
+
+// fetchImage downloads a file Kodi's web server serves at path.
+func (c *Client) fetchImage(path string) (io.ReadCloser, error) {
+	targetURL := c.baseURL() + path
+	req, err := http.NewRequest(http.MethodGet, targetURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.Username != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+
+	// Images come through the same TLS setup as the API calls
+	hc := http.Client{Transport: c.HTTPClient.Transport, Timeout: defaultImageTimeout}
+	if c.ImageTimeout > 0 {
+		hc.Timeout = c.ImageTimeout
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, ErrImageNotFound
+	case http.StatusForbidden:
+		resp.Body.Close()
+		return nil, errImageForbidden
+	default:
+		resp.Body.Close()
+		return nil, fmt.Errorf("kodi image error: %d", resp.StatusCode)
+	}
+}
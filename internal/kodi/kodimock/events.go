@@ -0,0 +1,80 @@
+package kodimock
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// notifier pushes JSON-RPC notifications to every client connected to the
+// mock's TCP interface, like Kodi does on port 9090.
+type notifier struct {
+	mu    sync.Mutex
+	conns map[net.Conn]bool
+}
+
+func (n *notifier) serve(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		n.mu.Lock()
+		n.conns[conn] = true
+		n.mu.Unlock()
+		go func() {
+			// Requests over TCP aren't supported; drain until the client leaves
+			io.Copy(io.Discard, conn)
+			n.drop(conn)
+		}()
+	}
+}
+
+func (n *notifier) drop(conn net.Conn) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.conns, conn)
+	conn.Close()
+}
+
+func (n *notifier) send(method string, data interface{}) {
+	msg, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  method,
+		"params":  map[string]interface{}{"sender": "xbmc", "data": data},
+	})
+	if err != nil {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for conn := range n.conns {
+		conn.SetWriteDeadline(time.Now().Add(time.Second))
+		if _, err := conn.Write(msg); err != nil {
+			delete(n.conns, conn)
+			conn.Close()
+		}
+	}
+}
+
+func (n *notifier) close() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for conn := range n.conns {
+		conn.Close()
+	}
+	n.conns = map[net.Conn]bool{}
+}
+
+// notify announces a library or player change to connected clients.
+func (m *mock) notify(method string, data interface{}) {
+	if m.notifier != nil {
+		m.notifier.send(method, data)
+	}
+}
+
+func itemRef(mediaType string, id int) map[string]interface{} {
+	return map[string]interface{}{"id": id, "type": mediaType}
+}
@@ -0,0 +1,460 @@
+package kodimock
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+//go:embed fixtures/default.json
+var fixtures embed.FS
+
+// Library is the fixture data a mock Kodi serves, in Kodi's own field names.
+// Show and season episode counts are derived from the listed episodes.
+type Library struct {
+	// Username and Password, when set, are required as HTTP basic auth.
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+
+	Movies    []Movie    `json:"movies"`
+	MovieSets []MovieSet `json:"moviesets,omitempty"`
+	TVShows   []TVShow   `json:"tvshows"`
+
+	// Albums and Artists are the music library.
+	Albums  []Album  `json:"albums,omitempty"`
+	Artists []Artist `json:"artists,omitempty"`
+
+	// Recordings and Channels are the PVR's recorded programmes and live TV
+	// channels.
+	Recordings []Recording `json:"recordings,omitempty"`
+	Channels   []Channel   `json:"channels,omitempty"`
+
+	// Favourites are served as Favourites.GetFavourites returns them.
+	Favourites []Favourite `json:"favourites,omitempty"`
+
+	// Sources are the video sources, and Files the paths of the files
+	// within them; directories are derived from the paths.
+	Sources []Source `json:"sources,omitempty"`
+	Files   []string `json:"files,omitempty"`
+
+	// Playlists are the playlists saved in Kodi's userdata.
+	Playlists []SavedPlaylist `json:"playlists,omitempty"`
+
+	// Player is what's currently playing; nil means nothing is.
+	Player *Player `json:"player,omitempty"`
+
+	// FlakyArt lists art URIs whose first download fails with 503, to
+	// exercise poster retries.
+	FlakyArt []string `json:"flaky_art,omitempty"`
+
+	// Simulate slows down or fails requests like a real box on a busy
+	// network.
+	Simulate Simulation `json:"simulate"`
+
+	// ArtDir holds image files served for art URIs in place of the
+	// placeholders: image://video@mock/movies/up/poster.jpg/ is served from
+	// ArtDir/movies/up/poster.jpg. Set to the fixture directory's art
+	// folder when loading one.
+	ArtDir string `json:"-"`
+}
+
+// Simulation describes how a mock misbehaves. Durations are strings such as
+// "150ms".
+type Simulation struct {
+	// Latency delays every request, plus a random extra of up to Jitter.
+	Latency string `json:"latency,omitempty"`
+	Jitter  string `json:"jitter,omitempty"`
+	// ErrorRate is the fraction of JSON-RPC requests answered with a 503,
+	// limited to ErrorMethods when any are listed.
+	ErrorRate    float64  `json:"error_rate,omitempty"`
+	ErrorMethods []string `json:"error_methods,omitempty"`
+
+	latency, jitter time.Duration
+}
+
+// parse checks the simulation settings.
+func (s *Simulation) parse() error {
+	var err error
+	if s.latency, err = parseDuration("latency", s.Latency); err != nil {
+		return err
+	}
+	if s.jitter, err = parseDuration("jitter", s.Jitter); err != nil {
+		return err
+	}
+	if s.ErrorRate < 0 || s.ErrorRate > 1 {
+		return fmt.Errorf("simulate error_rate must be between 0 and 1")
+	}
+	return nil
+}
+
+func parseDuration(name, v string) (time.Duration, error) {
+	if v == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid simulate %s %q", name, v)
+	}
+	return d, nil
+}
+
+// Override replaces the simulated latency and error rate with any non-empty
+// values given, e.g. from environment variables.
+func (s *Simulation) Override(latency, errorRate string) error {
+	if latency != "" {
+		s.Latency = latency
+	}
+	if errorRate != "" {
+		rate, err := strconv.ParseFloat(errorRate, 64)
+		if err != nil {
+			return fmt.Errorf("invalid simulate error_rate %q", errorRate)
+		}
+		s.ErrorRate = rate
+	}
+	return s.parse()
+}
+
+type Movie struct {
+	MovieID       int               `json:"movieid"`
+	Title         string            `json:"title"`
+	Year          int               `json:"year,omitempty"`
+	Rating        float64           `json:"rating,omitempty"`
+	Plot          string            `json:"plot,omitempty"`
+	Genre         []string          `json:"genre,omitempty"`
+	Playcount     int               `json:"playcount"`
+	LastPlayed    string            `json:"lastplayed,omitempty"`
+	DateAdded     string            `json:"dateadded,omitempty"`
+	Resume        *Resume           `json:"resume,omitempty"`
+	Runtime       int               `json:"runtime,omitempty"` // seconds
+	Thumbnail     string            `json:"thumbnail,omitempty"`
+	Art           map[string]string `json:"art,omitempty"`
+	StreamDetails *StreamDetails    `json:"streamdetails,omitempty"`
+	UniqueID      map[string]string `json:"uniqueid,omitempty"`
+	SetID         int               `json:"setid,omitempty"` // MovieSet the movie belongs to
+
+	// Detail fields, returned by VideoLibrary.GetMovieDetails
+	Tagline  string       `json:"tagline,omitempty"`
+	MPAA     string       `json:"mpaa,omitempty"`
+	Director []string     `json:"director,omitempty"`
+	Writer   []string     `json:"writer,omitempty"`
+	Studio   []string     `json:"studio,omitempty"`
+	Cast     []CastMember `json:"cast,omitempty"`
+}
+
+// MovieSet is a movie collection such as "The Matrix Collection". Its movies
+// are the ones whose SetID matches.
+type MovieSet struct {
+	SetID     int               `json:"setid"`
+	Title     string            `json:"title"`
+	Plot      string            `json:"plot,omitempty"`
+	Thumbnail string            `json:"thumbnail,omitempty"`
+	Art       map[string]string `json:"art,omitempty"`
+}
+
+type CastMember struct {
+	Name      string `json:"name"`
+	Role      string `json:"role,omitempty"`
+	Order     int    `json:"order"`
+	Thumbnail string `json:"thumbnail,omitempty"`
+}
+
+type TVShow struct {
+	TVShowID        int               `json:"tvshowid"`
+	Title           string            `json:"title"`
+	Year            int               `json:"year,omitempty"`
+	Rating          float64           `json:"rating,omitempty"`
+	Plot            string            `json:"plot,omitempty"`
+	Genre           []string          `json:"genre,omitempty"`
+	Thumbnail       string            `json:"thumbnail,omitempty"`
+	Art             map[string]string `json:"art,omitempty"`
+	WatchedEpisodes int               `json:"watchedepisodes"`
+	UniqueID        map[string]string `json:"uniqueid,omitempty"`
+	Seasons         []Season          `json:"seasons"`
+
+	// Detail fields, returned by VideoLibrary.GetTVShowDetails
+	Premiered string       `json:"premiered,omitempty"`
+	MPAA      string       `json:"mpaa,omitempty"`
+	Status    string       `json:"status,omitempty"`
+	Studio    []string     `json:"studio,omitempty"`
+	Cast      []CastMember `json:"cast,omitempty"`
+}
+
+// Album is an album of the music library. Artist lists its album artists,
+// which Kodi also joins into displayartist.
+type Album struct {
+	AlbumID     int               `json:"albumid"`
+	Title       string            `json:"title"`
+	Artist      []string          `json:"artist"`
+	Year        int               `json:"year,omitempty"`
+	Rating      float64           `json:"rating,omitempty"`
+	Genre       []string          `json:"genre,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Playcount   int               `json:"playcount"`
+	LastPlayed  string            `json:"lastplayed,omitempty"`
+	DateAdded   string            `json:"dateadded,omitempty"`
+	Thumbnail   string            `json:"thumbnail,omitempty"`
+	Art         map[string]string `json:"art,omitempty"`
+}
+
+// Artist is an album artist of the music library; Kodi names artists in
+// their artist field.
+type Artist struct {
+	ArtistID    int               `json:"artistid"`
+	Artist      string            `json:"artist"`
+	Genre       []string          `json:"genre,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Thumbnail   string            `json:"thumbnail,omitempty"`
+	Art         map[string]string `json:"art,omitempty"`
+}
+
+// Recording is a PVR recording; Radio recordings are left out of TV
+// listings by the client.
+type Recording struct {
+	RecordingID int               `json:"recordingid"`
+	Title       string            `json:"title"`
+	Plot        string            `json:"plot,omitempty"`
+	Channel     string            `json:"channel,omitempty"`
+	StartTime   string            `json:"starttime,omitempty"`
+	Runtime     int               `json:"runtime,omitempty"`
+	Genre       []string          `json:"genre,omitempty"`
+	Playcount   int               `json:"playcount"`
+	Icon        string            `json:"icon,omitempty"`
+	Art         map[string]string `json:"art,omitempty"`
+	File        string            `json:"file,omitempty"`
+	Radio       bool              `json:"radio,omitempty"`
+}
+
+// Channel is a live TV channel with, optionally, what's on air.
+type Channel struct {
+	ChannelID     int             `json:"channelid"`
+	Channel       string          `json:"channel"`
+	ChannelNumber int             `json:"channelnumber,omitempty"`
+	Thumbnail     string          `json:"thumbnail,omitempty"`
+	BroadcastNow  json.RawMessage `json:"broadcastnow,omitempty"`
+}
+
+type Season struct {
+	SeasonID  int       `json:"seasonid"`
+	Season    int       `json:"season"`
+	Thumbnail string    `json:"thumbnail,omitempty"`
+	Episodes  []Episode `json:"episodes"`
+}
+
+type Episode struct {
+	EpisodeID     int            `json:"episodeid"`
+	Title         string         `json:"title"`
+	Episode       int            `json:"episode"`
+	Playcount     int            `json:"playcount"`
+	LastPlayed    string         `json:"lastplayed,omitempty"`
+	DateAdded     string         `json:"dateadded,omitempty"`
+	Resume        *Resume        `json:"resume,omitempty"`
+	Runtime       int            `json:"runtime,omitempty"` // seconds
+	Rating        float64        `json:"rating,omitempty"`
+	StreamDetails *StreamDetails `json:"streamdetails,omitempty"`
+
+	// Detail fields, returned by VideoLibrary.GetEpisodeDetails
+	FirstAired string       `json:"firstaired,omitempty"`
+	Plot       string       `json:"plot,omitempty"`
+	Director   []string     `json:"director,omitempty"`
+	Writer     []string     `json:"writer,omitempty"`
+	Cast       []CastMember `json:"cast,omitempty"`
+	Thumbnail  string       `json:"thumbnail,omitempty"`
+}
+
+// Resume is a saved resume point in seconds.
+type Resume struct {
+	Position float64 `json:"position"`
+	Total    float64 `json:"total"`
+}
+
+type StreamDetails struct {
+	Video    []VideoStream    `json:"video"`
+	Audio    []AudioStream    `json:"audio"`
+	Subtitle []SubtitleStream `json:"subtitle"`
+}
+
+type VideoStream struct {
+	Duration int    `json:"duration"`
+	Codec    string `json:"codec,omitempty"`
+	Width    int    `json:"width,omitempty"`
+	Height   int    `json:"height,omitempty"`
+	HDRType  string `json:"hdrtype,omitempty"`
+}
+
+type AudioStream struct {
+	Language string `json:"language"`
+	Codec    string `json:"codec,omitempty"`
+	Channels int    `json:"channels,omitempty"`
+}
+
+type SubtitleStream struct {
+	Language string `json:"language"`
+}
+
+// Favourite is an entry in Kodi's favourites menu.
+type Favourite struct {
+	Title           string `json:"title"`
+	Type            string `json:"type"` // media, window, script, androidapp
+	Path            string `json:"path,omitempty"`
+	Window          string `json:"window,omitempty"`
+	WindowParameter string `json:"windowparameter,omitempty"`
+	Thumbnail       string `json:"thumbnail,omitempty"`
+}
+
+// Source is a video source as Files.GetSources returns it.
+type Source struct {
+	File  string `json:"file"`
+	Label string `json:"label"`
+}
+
+// SavedPlaylist is a smart (.xsp) or plain (.m3u) playlist saved under
+// special://profile/playlists/, with the items it resolves to.
+type SavedPlaylist struct {
+	File  string       `json:"file"`
+	Label string       `json:"label"`
+	Items []PlayingRef `json:"items"`
+}
+
+// Player mirrors the Player.GetProperties stream properties.
+type Player struct {
+	Type               string         `json:"type"` // video, audio, picture
+	AudioStreams       []PlayerStream `json:"audiostreams"`
+	Subtitles          []PlayerStream `json:"subtitles"`
+	CurrentAudioStream *PlayerStream  `json:"currentaudiostream,omitempty"`
+	CurrentSubtitle    *PlayerStream  `json:"currentsubtitle,omitempty"`
+	SubtitleEnabled    bool           `json:"subtitleenabled"`
+
+	// Item is the library item being played, as reported by Player.GetItem.
+	Item *PlayingRef `json:"item,omitempty"`
+}
+
+// PlayingRef points at a movie, show, episode, album, artist, recording or
+// channel by its Kodi ID.
+type PlayingRef struct {
+	Type string `json:"type"` // movie, tvshow, episode, album, artist, recording, channel, file
+	ID   int    `json:"id,omitempty"`
+	File string `json:"file,omitempty"`
+}
+
+type PlayerStream struct {
+	Index    int    `json:"index"`
+	Language string `json:"language"`
+	Name     string `json:"name"`
+	Codec    string `json:"codec,omitempty"`
+	Channels int    `json:"channels,omitempty"`
+}
+
+// LoadLibrary reads a fixture library from path, or the built-in demo
+// library when path is empty. path may be a single JSON file or a directory
+// of them, read in name order and merged, so movies, shows and the rest can
+// be kept in separate files; a directory's art folder supplies the images.
+func LoadLibrary(path string) (*Library, error) {
+	var lib Library
+	switch info, err := os.Stat(path); {
+	case path == "":
+		data, err := fixtures.ReadFile("fixtures/default.json")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read mock library: %w", err)
+		}
+		if err := json.Unmarshal(data, &lib); err != nil {
+			return nil, fmt.Errorf("failed to parse mock library: %w", err)
+		}
+	case err != nil:
+		return nil, fmt.Errorf("failed to read mock library: %w", err)
+	case info.IsDir():
+		files, err := filepath.Glob(filepath.Join(path, "*.json"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read mock library: %w", err)
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no JSON fixtures in %s", path)
+		}
+		for _, file := range files {
+			part, err := readLibraryFile(file)
+			if err != nil {
+				return nil, err
+			}
+			lib.merge(part)
+		}
+		if art := filepath.Join(path, "art"); dirExists(art) {
+			lib.ArtDir = art
+		}
+	default:
+		part, err := readLibraryFile(path)
+		if err != nil {
+			return nil, err
+		}
+		lib = *part
+	}
+	if err := lib.Simulate.parse(); err != nil {
+		return nil, fmt.Errorf("invalid mock library: %w", err)
+	}
+	return &lib, nil
+}
+
+func readLibraryFile(path string) (*Library, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mock library: %w", err)
+	}
+	var lib Library
+	if err := json.Unmarshal(data, &lib); err != nil {
+		return nil, fmt.Errorf("failed to parse mock library %s: %w", filepath.Base(path), err)
+	}
+	return &lib, nil
+}
+
+func dirExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// merge adds the fixtures of another file: lists are appended, and settings
+// it sets replace earlier ones.
+func (l *Library) merge(o *Library) {
+	if o.Username != "" {
+		l.Username, l.Password = o.Username, o.Password
+	}
+	l.Movies = append(l.Movies, o.Movies...)
+	l.MovieSets = append(l.MovieSets, o.MovieSets...)
+	l.TVShows = append(l.TVShows, o.TVShows...)
+	l.Albums = append(l.Albums, o.Albums...)
+	l.Artists = append(l.Artists, o.Artists...)
+	l.Recordings = append(l.Recordings, o.Recordings...)
+	l.Channels = append(l.Channels, o.Channels...)
+	l.Favourites = append(l.Favourites, o.Favourites...)
+	l.Sources = append(l.Sources, o.Sources...)
+	l.Files = append(l.Files, o.Files...)
+	l.Playlists = append(l.Playlists, o.Playlists...)
+	l.FlakyArt = append(l.FlakyArt, o.FlakyArt...)
+	if o.Player != nil {
+		l.Player = o.Player
+	}
+	if o.Simulate.Latency != "" || o.Simulate.Jitter != "" || o.Simulate.ErrorRate != 0 {
+		l.Simulate = o.Simulate
+	}
+}
+
+// dateAdded is when the show's newest episode was added, which is what
+// Kodi reports as a show's dateadded.
+func (s TVShow) dateAdded() string {
+	latest := ""
+	for _, season := range s.Seasons {
+		for _, ep := range season.Episodes {
+			latest = max(latest, ep.DateAdded)
+		}
+	}
+	return latest
+}
+
+func (s TVShow) episodeCount() int {
+	n := 0
+	for _, season := range s.Seasons {
+		n += len(season.Episodes)
+	}
+	return n
+}
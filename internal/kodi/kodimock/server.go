@@ -0,0 +1,1219 @@
+// Package kodimock is an in-process Kodi JSON-RPC server backed by a fixture
+// library. It implements the subset of methods the app uses, for demo mode
+// and end-to-end testing without a real Kodi box.
+package kodimock
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"io"
+	"maps"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server is a running mock Kodi. URL is its base address and EventAddr the
+// host:port of its TCP notification interface.
+type Server struct {
+	*httptest.Server
+	EventAddr string
+
+	events   net.Listener
+	notifier *notifier
+}
+
+// Start serves lib on a loopback port until Close is called.
+func Start(lib *Library) *Server {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("kodimock: failed to listen for events: %v", err))
+	}
+	n := &notifier{conns: map[net.Conn]bool{}}
+	go n.serve(ln)
+	m := &mock{lib: lib, notifier: n}
+	return &Server{Server: httptest.NewServer(m.handler()), EventAddr: ln.Addr().String(), events: ln, notifier: n}
+}
+
+// Close stops the notification interface and the HTTP server.
+func (s *Server) Close() {
+	s.events.Close()
+	s.notifier.close()
+	s.Server.Close()
+}
+
+// Handler returns an http.Handler serving lib, for callers that manage their
+// own listener.
+func Handler(lib *Library) http.Handler {
+	return (&mock{lib: lib}).handler()
+}
+
+type mock struct {
+	mu        sync.Mutex
+	lib       *Library
+	playlists map[int][]json.RawMessage // playlist entries as sent by the client, by playlist ID
+
+	paused     bool
+	percentage float64
+
+	flakyServed map[string]bool // FlakyArt URIs that already failed once
+	textures    []string        // art URIs looked up in the texture cache, by texture ID - 1
+
+	notifier *notifier // nil when served through Handler
+}
+
+// mockRuntime is the length, in seconds, the mock pretends every item has.
+const mockRuntime = 7200
+
+type rpcRequest struct {
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	ID     int             `json:"id"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+var (
+	errMethodNotFound = &rpcError{Code: -32601, Message: "Method not found."}
+	errInvalidParams  = &rpcError{Code: -32602, Message: "Invalid params."}
+	errNoPlayer       = &rpcError{Code: -32100, Message: "Failed to execute method."}
+)
+
+func (m *mock) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/jsonrpc", m.handleRPC)
+	mux.HandleFunc("/image/", m.handleImage)
+	mux.HandleFunc("/vfs/", m.handleThumbnail)
+	return m.delay(m.requireAuth(mux))
+}
+
+// delay holds every request for the simulated latency.
+func (m *mock) delay(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sim := m.lib.Simulate
+		if d := sim.latency + randDuration(sim.jitter); d > 0 {
+			select {
+			case <-time.After(d):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func randDuration(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return rand.N(max)
+}
+
+// simulatedFailure reports whether a request calling methods should be
+// answered with a simulated error.
+func (m *mock) simulatedFailure(methods []string) bool {
+	sim := m.lib.Simulate
+	if sim.ErrorRate <= 0 {
+		return false
+	}
+	if len(sim.ErrorMethods) > 0 && !slices.ContainsFunc(methods, func(method string) bool { return slices.Contains(sim.ErrorMethods, method) }) {
+		return false
+	}
+	return rand.Float64() < sim.ErrorRate
+}
+
+func (m *mock) requireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.lib.Username != "" {
+			user, pass, ok := r.BasicAuth()
+			if !ok || user != m.lib.Username || pass != m.lib.Password {
+				w.Header().Set("WWW-Authenticate", `Basic realm="Kodi"`)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (m *mock) handleRPC(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	// Like Kodi, answer a batch array with an array of responses
+	w.Header().Set("Content-Type", "application/json")
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		var reqs []rpcRequest
+		if err := json.Unmarshal(trimmed, &reqs); err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+		methods := make([]string, len(reqs))
+		for i, req := range reqs {
+			methods[i] = req.Method
+		}
+		if m.simulatedFailure(methods) {
+			http.Error(w, "Service unavailable (simulated)", http.StatusServiceUnavailable)
+			return
+		}
+		resps := make([]map[string]interface{}, 0, len(reqs))
+		for _, req := range reqs {
+			resps = append(resps, m.respond(req))
+		}
+		json.NewEncoder(w).Encode(resps)
+		return
+	}
+	var req rpcRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if m.simulatedFailure([]string{req.Method}) {
+		http.Error(w, "Service unavailable (simulated)", http.StatusServiceUnavailable)
+		return
+	}
+	json.NewEncoder(w).Encode(m.respond(req))
+}
+
+func (m *mock) respond(req rpcRequest) map[string]interface{} {
+	m.mu.Lock()
+	result, rpcErr := m.call(req.Method, req.Params)
+	m.mu.Unlock()
+
+	resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+	if rpcErr != nil {
+		resp["error"] = rpcErr
+	} else {
+		resp["result"] = result
+	}
+	return resp
+}
+
+// call dispatches one JSON-RPC method. Requested "properties" are ignored;
+// every known field is returned.
+func (m *mock) call(method string, raw json.RawMessage) (interface{}, *rpcError) {
+	var params struct {
+		TVShowID    *int               `json:"tvshowid"`
+		Season      *int               `json:"season"`
+		PlayerID    *int               `json:"playerid"`
+		PlaylistID  *int               `json:"playlistid"`
+		MovieID     *int               `json:"movieid"`
+		SetID       *int               `json:"setid"`
+		Type        string             `json:"type"`
+		EpisodeID   *int               `json:"episodeid"`
+		RecordingID *int               `json:"recordingid"`
+		ChannelID   *int               `json:"channelid"`
+		Playcount   *int               `json:"playcount"`
+		Art         map[string]*string `json:"art"`
+		Item        json.RawMessage    `json:"item"`
+		Directory   string             `json:"directory"`
+		Value       struct {
+			Percentage *float64 `json:"percentage"`
+			Seconds    *int     `json:"seconds"`
+			Step       string   `json:"step"`
+		} `json:"value"`
+		Filter struct {
+			Field    string `json:"field"`
+			Operator string `json:"operator"`
+			Value    string `json:"value"`
+		} `json:"filter"`
+		Limits struct {
+			Start int `json:"start"`
+			End   int `json:"end"`
+		} `json:"limits"`
+	}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &params); err != nil {
+			return nil, errInvalidParams
+		}
+	}
+
+	switch method {
+	case "JSONRPC.Ping":
+		return "pong", nil
+
+	case "JSONRPC.Version":
+		return map[string]interface{}{"version": map[string]int{"major": 13, "minor": 5, "patch": 0}}, nil
+
+	case "Application.GetProperties":
+		return map[string]interface{}{
+			"name":    "Kodi",
+			"version": map[string]interface{}{"major": 21, "minor": 1, "revision": "mock", "tag": "stable"},
+		}, nil
+
+	case "Favourites.GetFavourites":
+		favourites := m.lib.Favourites
+		if favourites == nil {
+			favourites = []Favourite{}
+		}
+		return map[string]interface{}{"favourites": favourites, "limits": limits(len(favourites))}, nil
+
+	case "PVR.GetRecordings":
+		recordings := make([]map[string]interface{}, 0, len(m.lib.Recordings))
+		for _, rec := range m.lib.Recordings {
+			recordings = append(recordings, withLabel(rec, rec.Title))
+		}
+		return map[string]interface{}{"recordings": recordings, "limits": limits(len(recordings))}, nil
+
+	case "PVR.GetRecordingDetails":
+		for _, rec := range m.lib.Recordings {
+			if params.RecordingID != nil && rec.RecordingID == *params.RecordingID {
+				return map[string]interface{}{"recordingdetails": withLabel(rec, rec.Title)}, nil
+			}
+		}
+		return nil, errInvalidParams
+
+	case "PVR.GetChannels":
+		channels := make([]map[string]interface{}, 0, len(m.lib.Channels))
+		for _, ch := range m.lib.Channels {
+			channels = append(channels, withLabel(ch, ch.Channel))
+		}
+		return map[string]interface{}{"channels": channels, "limits": limits(len(channels))}, nil
+
+	case "PVR.GetChannelDetails":
+		for _, ch := range m.lib.Channels {
+			if params.ChannelID != nil && ch.ChannelID == *params.ChannelID {
+				return map[string]interface{}{"channeldetails": withLabel(ch, ch.Channel)}, nil
+			}
+		}
+		return nil, errInvalidParams
+
+	case "Files.GetSources":
+		sources := m.lib.Sources
+		if sources == nil {
+			sources = []Source{}
+		}
+		return map[string]interface{}{"sources": sources, "limits": limits(len(sources))}, nil
+
+	case "Files.GetDirectory":
+		if strings.HasPrefix(params.Directory, "special://profile/playlists/") {
+			files, ok := m.savedPlaylist(params.Directory)
+			if !ok {
+				return nil, errInvalidParams
+			}
+			return map[string]interface{}{"files": files, "limits": limits(len(files))}, nil
+		}
+		files, ok := m.directory(params.Directory)
+		if !ok {
+			return nil, errInvalidParams
+		}
+		return map[string]interface{}{"files": files, "limits": limits(len(files))}, nil
+
+	case "VideoLibrary.GetMovies":
+		movies := make([]map[string]interface{}, 0, len(m.lib.Movies))
+		for _, mv := range m.lib.Movies {
+			if params.Filter.Field == "inprogress" && (mv.Resume == nil || mv.Resume.Position <= 0) {
+				continue
+			}
+			if params.Filter.Field == "dateadded" && !addedAfter(mv.DateAdded, params.Filter.Operator, params.Filter.Value) {
+				continue
+			}
+			movie := withLabel(mv, mv.Title)
+			if set := m.movieSet(mv.SetID); set != nil {
+				movie["set"] = set.Title
+			}
+			movies = append(movies, movie)
+		}
+		movies, pageLimits := page(movies, params.Limits.Start, params.Limits.End)
+		return map[string]interface{}{"movies": movies, "limits": pageLimits}, nil
+
+	case "VideoLibrary.GetRecentlyAddedMovies":
+		movies := make([]map[string]interface{}, 0, len(m.lib.Movies))
+		for _, mv := range m.lib.Movies {
+			movies = append(movies, withLabel(mv, mv.Title))
+		}
+		movies = recentlyAdded(movies, params.Limits.End)
+		return map[string]interface{}{"movies": movies, "limits": limits(len(movies))}, nil
+
+	case "VideoLibrary.GetMovieDetails":
+		for _, mv := range m.lib.Movies {
+			if params.MovieID != nil && mv.MovieID == *params.MovieID {
+				return map[string]interface{}{"moviedetails": withLabel(mv, mv.Title)}, nil
+			}
+		}
+		return nil, errInvalidParams
+
+	case "VideoLibrary.GetMovieSets":
+		sets := make([]map[string]interface{}, 0, len(m.lib.MovieSets))
+		for _, set := range m.lib.MovieSets {
+			sets = append(sets, withLabel(set, set.Title))
+		}
+		return map[string]interface{}{"sets": sets, "limits": limits(len(sets))}, nil
+
+	case "VideoLibrary.GetMovieSetDetails":
+		for _, set := range m.lib.MovieSets {
+			if params.SetID == nil || set.SetID != *params.SetID {
+				continue
+			}
+			movies := []map[string]interface{}{}
+			for _, mv := range m.lib.Movies {
+				if mv.SetID == set.SetID {
+					movies = append(movies, withLabel(mv, mv.Title))
+				}
+			}
+			details := withLabel(set, set.Title)
+			details["movies"] = movies
+			return map[string]interface{}{"setdetails": details}, nil
+		}
+		return nil, errInvalidParams
+
+	case "VideoLibrary.GetGenres":
+		if params.Type != "movie" && params.Type != "tvshow" {
+			return nil, errInvalidParams
+		}
+		genres := []map[string]interface{}{}
+		for i, g := range m.genres() {
+			if g.types[params.Type] {
+				genres = append(genres, map[string]interface{}{"genreid": i + 1, "label": g.title, "title": g.title})
+			}
+		}
+		return map[string]interface{}{"genres": genres, "limits": limits(len(genres))}, nil
+
+	case "VideoLibrary.GetTVShows":
+		shows := make([]map[string]interface{}, 0, len(m.lib.TVShows))
+		for _, s := range m.lib.TVShows {
+			if params.Filter.Field == "dateadded" && !addedAfter(s.dateAdded(), params.Filter.Operator, params.Filter.Value) {
+				continue
+			}
+			show := withLabel(s, s.Title)
+			delete(show, "seasons")
+			show["dateadded"] = s.dateAdded()
+			show["episode"] = s.episodeCount()
+			show["playcount"] = 0
+			if n := s.episodeCount(); n > 0 && s.WatchedEpisodes >= n {
+				show["playcount"] = 1
+			}
+			shows = append(shows, show)
+		}
+		shows, pageLimits := page(shows, params.Limits.Start, params.Limits.End)
+		return map[string]interface{}{"tvshows": shows, "limits": pageLimits}, nil
+
+	case "VideoLibrary.GetSeasons":
+		show := m.show(params.TVShowID)
+		if show == nil {
+			return nil, errInvalidParams
+		}
+		seasons := make([]map[string]interface{}, 0, len(show.Seasons))
+		for _, se := range show.Seasons {
+			season := withLabel(se, fmt.Sprintf("Season %d", se.Season))
+			delete(season, "episodes")
+			season["episode"] = len(se.Episodes)
+			season["showtitle"] = show.Title
+			seasons = append(seasons, season)
+		}
+		return map[string]interface{}{"seasons": seasons, "limits": limits(len(seasons))}, nil
+
+	case "VideoLibrary.GetEpisodes":
+		// Without a tvshowid Kodi returns the episodes of every show
+		shows := m.lib.TVShows
+		if params.TVShowID != nil {
+			show := m.show(params.TVShowID)
+			if show == nil {
+				return nil, errInvalidParams
+			}
+			shows = []TVShow{*show}
+		}
+		episodes := []map[string]interface{}{}
+		for _, show := range shows {
+			for _, se := range show.Seasons {
+				if params.Season != nil && *params.Season != se.Season {
+					continue
+				}
+				for _, ep := range se.Episodes {
+					if params.Filter.Field == "inprogress" && (ep.Resume == nil || ep.Resume.Position <= 0) {
+						continue
+					}
+					episode := withLabel(ep, ep.Title)
+					episode["season"] = se.Season
+					episode["showtitle"] = show.Title
+					episode["tvshowid"] = show.TVShowID
+					episodes = append(episodes, episode)
+				}
+			}
+		}
+		return map[string]interface{}{"episodes": episodes, "limits": limits(len(episodes))}, nil
+
+	case "VideoLibrary.GetRecentlyAddedEpisodes":
+		episodes := []map[string]interface{}{}
+		for _, show := range m.lib.TVShows {
+			for _, se := range show.Seasons {
+				for _, ep := range se.Episodes {
+					episode := withLabel(ep, ep.Title)
+					episode["season"] = se.Season
+					episode["showtitle"] = show.Title
+					episode["tvshowid"] = show.TVShowID
+					episodes = append(episodes, episode)
+				}
+			}
+		}
+		episodes = recentlyAdded(episodes, params.Limits.End)
+		return map[string]interface{}{"episodes": episodes, "limits": limits(len(episodes))}, nil
+
+	case "VideoLibrary.GetTVShowDetails":
+		show := m.show(params.TVShowID)
+		if show == nil {
+			return nil, errInvalidParams
+		}
+		details := withLabel(show, show.Title)
+		delete(details, "seasons")
+		details["season"] = len(show.Seasons)
+		details["episode"] = show.episodeCount()
+		return map[string]interface{}{"tvshowdetails": details}, nil
+
+	case "VideoLibrary.GetEpisodeDetails":
+		for _, show := range m.lib.TVShows {
+			for _, se := range show.Seasons {
+				for _, ep := range se.Episodes {
+					if params.EpisodeID == nil || ep.EpisodeID != *params.EpisodeID {
+						continue
+					}
+					details := withLabel(ep, ep.Title)
+					details["season"] = se.Season
+					details["showtitle"] = show.Title
+					details["tvshowid"] = show.TVShowID
+					return map[string]interface{}{"episodedetails": details}, nil
+				}
+			}
+		}
+		return nil, errInvalidParams
+
+	case "VideoLibrary.SetMovieDetails":
+		for i := range m.lib.Movies {
+			mv := &m.lib.Movies[i]
+			if params.MovieID != nil && mv.MovieID == *params.MovieID {
+				if params.Playcount != nil {
+					mv.Playcount = *params.Playcount
+					mv.LastPlayed = lastPlayed(mv.Playcount)
+					m.notify("VideoLibrary.OnUpdate", map[string]interface{}{"item": itemRef("movie", mv.MovieID), "playcount": mv.Playcount})
+				}
+				if params.Art != nil {
+					mv.Art = setArt(mv.Art, params.Art)
+					m.notify("VideoLibrary.OnUpdate", map[string]interface{}{"item": itemRef("movie", mv.MovieID)})
+				}
+				return "OK", nil
+			}
+		}
+		return nil, errInvalidParams
+
+	case "VideoLibrary.SetTVShowDetails":
+		for i := range m.lib.TVShows {
+			show := &m.lib.TVShows[i]
+			if params.TVShowID != nil && show.TVShowID == *params.TVShowID {
+				if params.Art != nil {
+					show.Art = setArt(show.Art, params.Art)
+					m.notify("VideoLibrary.OnUpdate", map[string]interface{}{"item": itemRef("tvshow", show.TVShowID)})
+				}
+				return "OK", nil
+			}
+		}
+		return nil, errInvalidParams
+
+	case "VideoLibrary.SetEpisodeDetails":
+		show, ep := m.episode(params.EpisodeID)
+		if ep == nil {
+			return nil, errInvalidParams
+		}
+		if params.Playcount != nil {
+			// Keep the show's watched counter in step with its episodes
+			if ep.Playcount == 0 && *params.Playcount > 0 {
+				show.WatchedEpisodes = min(show.WatchedEpisodes+1, show.episodeCount())
+			} else if ep.Playcount > 0 && *params.Playcount == 0 && show.WatchedEpisodes > 0 {
+				show.WatchedEpisodes--
+			}
+			ep.Playcount = *params.Playcount
+			ep.LastPlayed = lastPlayed(ep.Playcount)
+			m.notify("VideoLibrary.OnUpdate", map[string]interface{}{"item": itemRef("episode", ep.EpisodeID), "playcount": ep.Playcount})
+		}
+		return "OK", nil
+
+	case "VideoLibrary.Scan", "VideoLibrary.Clean", "AudioLibrary.Scan", "AudioLibrary.Clean":
+		// The fixture library never changes on disk, so the job finishes at once
+		library, kind, _ := strings.Cut(method, ".")
+		m.notify(library+".On"+kind+"Started", nil)
+		m.notify(library+".On"+kind+"Finished", nil)
+		return "OK", nil
+
+	case "AudioLibrary.GetAlbums":
+		albums := make([]map[string]interface{}, 0, len(m.lib.Albums))
+		for _, al := range m.lib.Albums {
+			album := withLabel(al, al.Title)
+			album["displayartist"] = strings.Join(al.Artist, " / ")
+			albums = append(albums, album)
+		}
+		albums, pageLimits := page(albums, params.Limits.Start, params.Limits.End)
+		return map[string]interface{}{"albums": albums, "limits": pageLimits}, nil
+
+	case "AudioLibrary.GetArtists":
+		artists := make([]map[string]interface{}, 0, len(m.lib.Artists))
+		for _, ar := range m.lib.Artists {
+			artists = append(artists, withLabel(ar, ar.Artist))
+		}
+		artists, pageLimits := page(artists, params.Limits.Start, params.Limits.End)
+		return map[string]interface{}{"artists": artists, "limits": pageLimits}, nil
+
+	case "Playlist.Clear":
+		if params.PlaylistID == nil || (*params.PlaylistID != 0 && *params.PlaylistID != 1) {
+			return nil, errInvalidParams
+		}
+		delete(m.playlists, *params.PlaylistID)
+		return "OK", nil
+
+	case "Textures.GetTextures":
+		// Every artwork is treated as cached, as on a box that has shown
+		// the whole library
+		if params.Filter.Field != "url" || params.Filter.Value == "" {
+			return map[string]interface{}{"textures": []interface{}{}}, nil
+		}
+		id := slices.Index(m.textures, params.Filter.Value) + 1
+		if id == 0 {
+			m.textures = append(m.textures, params.Filter.Value)
+			id = len(m.textures)
+		}
+		texture := map[string]interface{}{
+			"textureid": id,
+			"url":       params.Filter.Value,
+			"cachedurl": thumbnailPath(id),
+			"sizes":     []map[string]int{{"width": thumbWidth, "height": thumbHeight, "size": 0}},
+		}
+		return map[string]interface{}{"textures": []interface{}{texture}}, nil
+
+	case "Playlist.GetItems":
+		if params.PlaylistID == nil || (*params.PlaylistID != 0 && *params.PlaylistID != 1) {
+			return nil, errInvalidParams
+		}
+		items := []map[string]interface{}{}
+		for _, raw := range m.playlists[*params.PlaylistID] {
+			if ref := playingRef(raw); ref != nil {
+				items = append(items, m.libraryEntry(*ref))
+			}
+		}
+		return map[string]interface{}{"items": items, "limits": limits(len(items))}, nil
+
+	case "Playlist.Add":
+		if params.PlaylistID == nil || (*params.PlaylistID != 0 && *params.PlaylistID != 1) || len(params.Item) == 0 {
+			return nil, errInvalidParams
+		}
+		var items []json.RawMessage
+		if params.Item[0] == '[' {
+			if err := json.Unmarshal(params.Item, &items); err != nil {
+				return nil, errInvalidParams
+			}
+		} else {
+			items = []json.RawMessage{params.Item}
+		}
+		if m.playlists == nil {
+			m.playlists = map[int][]json.RawMessage{}
+		}
+		m.playlists[*params.PlaylistID] = append(m.playlists[*params.PlaylistID], items...)
+		return "OK", nil
+
+	case "Player.Open":
+		target := params.Item
+		var open struct {
+			PlaylistID *int `json:"playlistid"`
+		}
+		if json.Unmarshal(target, &open) == nil && open.PlaylistID != nil && len(m.playlists[*open.PlaylistID]) > 0 {
+			target = m.playlists[*open.PlaylistID][0]
+		}
+		ref := playingRef(target)
+		kind := "video"
+		if ref != nil && (ref.Type == "album" || ref.Type == "artist") {
+			kind = "audio"
+		}
+		if m.lib.Player == nil || m.lib.Player.Type != kind {
+			// Opening video replaces music or a slideshow and music replaces
+			// video, as in Kodi
+			m.lib.Player = &Player{Type: kind, AudioStreams: []PlayerStream{}, Subtitles: []PlayerStream{}}
+		}
+		m.lib.Player.Item = ref
+		m.paused, m.percentage = false, 0
+		return "OK", nil
+
+	case "Player.PlayPause":
+		if !m.playing(params.PlayerID) {
+			return nil, errNoPlayer
+		}
+		m.paused = !m.paused
+		speed := 1
+		if m.paused {
+			speed = 0
+		}
+		return map[string]int{"speed": speed}, nil
+
+	case "Player.Stop":
+		if !m.playing(params.PlayerID) {
+			return nil, errNoPlayer
+		}
+		m.stopped()
+		if ref := m.lib.Player.Item; ref != nil {
+			m.notify("Player.OnStop", map[string]interface{}{"item": itemRef(ref.Type, ref.ID), "end": m.percentage >= 90})
+		}
+		m.lib.Player = nil
+		m.paused, m.percentage = false, 0
+		return "OK", nil
+
+	case "Player.Seek":
+		if !m.playing(params.PlayerID) {
+			return nil, errNoPlayer
+		}
+		steps := map[string]float64{"smallforward": 30, "smallbackward": -30, "bigforward": 600, "bigbackward": -600}
+		v := params.Value
+		switch {
+		case v.Percentage != nil:
+			m.percentage = *v.Percentage
+		case v.Seconds != nil:
+			m.percentage += float64(*v.Seconds) * 100 / mockRuntime
+		case steps[v.Step] != 0:
+			m.percentage += steps[v.Step] * 100 / mockRuntime
+		default:
+			return nil, errInvalidParams
+		}
+		m.percentage = min(max(m.percentage, 0), 100)
+		return m.progress(), nil
+
+	case "Player.GetActivePlayers":
+		if m.lib.Player == nil {
+			return []interface{}{}, nil
+		}
+		return []map[string]interface{}{{"playerid": playerIDs[m.lib.Player.Type], "type": m.lib.Player.Type}}, nil
+
+	case "Player.GetProperties":
+		if !m.playing(params.PlayerID) {
+			return nil, errNoPlayer
+		}
+		props := withLabel(m.lib.Player, "")
+		delete(props, "label")
+		delete(props, "item")
+		for k, v := range m.progress() {
+			props[k] = v
+		}
+		props["speed"] = 1
+		if m.paused {
+			props["speed"] = 0
+		}
+		return props, nil
+
+	case "Player.GetItem":
+		if !m.playing(params.PlayerID) {
+			return nil, errNoPlayer
+		}
+		return map[string]interface{}{"item": m.playingItem()}, nil
+	}
+	return nil, errMethodNotFound
+}
+
+// playerIDs are the fixed IDs Kodi gives its players.
+var playerIDs = map[string]int{"audio": 0, "video": 1, "picture": 2}
+
+// playing reports whether id names the mock's single active player.
+func (m *mock) playing(id *int) bool {
+	return m.lib.Player != nil && id != nil && *id == playerIDs[m.lib.Player.Type]
+}
+
+// stopped records what Kodi would when playback of a movie stops: past 90%
+// it counts as watched, otherwise the position is saved for resuming.
+func (m *mock) stopped() {
+	ref := m.lib.Player.Item
+	if ref == nil || ref.Type != "movie" {
+		return
+	}
+	for i := range m.lib.Movies {
+		mv := &m.lib.Movies[i]
+		if mv.MovieID != ref.ID {
+			continue
+		}
+		mv.LastPlayed = time.Now().Format(time.DateTime)
+		if m.percentage >= 90 {
+			mv.Playcount++
+			mv.Resume = nil
+			m.notify("VideoLibrary.OnUpdate", map[string]interface{}{"item": itemRef("movie", mv.MovieID), "playcount": mv.Playcount})
+		} else if m.percentage > 0 {
+			mv.Resume = &Resume{Position: float64(int(m.percentage * mockRuntime / 100)), Total: mockRuntime}
+		}
+		return
+	}
+}
+
+func (m *mock) progress() map[string]interface{} {
+	return map[string]interface{}{
+		"percentage": m.percentage,
+		"time":       playerTime(int(m.percentage * mockRuntime / 100)),
+		"totaltime":  playerTime(mockRuntime),
+	}
+}
+
+// playingRef extracts the movie, episode, album, artist, recording or channel
+// a Player.Open or playlist item refers to; anything else plays as an
+// unknown file.
+func playingRef(raw json.RawMessage) *PlayingRef {
+	var item struct {
+		MovieID     int    `json:"movieid"`
+		EpisodeID   int    `json:"episodeid"`
+		AlbumID     int    `json:"albumid"`
+		ArtistID    int    `json:"artistid"`
+		RecordingID int    `json:"recordingid"`
+		ChannelID   int    `json:"channelid"`
+		File        string `json:"file"`
+	}
+	json.Unmarshal(raw, &item)
+	switch {
+	case item.File != "":
+		return &PlayingRef{Type: "file", File: item.File}
+	case item.MovieID != 0:
+		return &PlayingRef{Type: "movie", ID: item.MovieID}
+	case item.EpisodeID != 0:
+		return &PlayingRef{Type: "episode", ID: item.EpisodeID}
+	case item.AlbumID != 0:
+		return &PlayingRef{Type: "album", ID: item.AlbumID}
+	case item.ArtistID != 0:
+		return &PlayingRef{Type: "artist", ID: item.ArtistID}
+	case item.RecordingID != 0:
+		return &PlayingRef{Type: "recording", ID: item.RecordingID}
+	case item.ChannelID != 0:
+		return &PlayingRef{Type: "channel", ID: item.ChannelID}
+	}
+	return nil
+}
+
+// playingItem renders the current item the way Player.GetItem does.
+func (m *mock) playingItem() map[string]interface{} {
+	ref := m.lib.Player.Item
+	if ref != nil && ref.Type == "movie" {
+		for _, mv := range m.lib.Movies {
+			if mv.MovieID == ref.ID {
+				item := withLabel(mv, mv.Title)
+				delete(item, "movieid")
+				item["id"], item["type"] = mv.MovieID, "movie"
+				return item
+			}
+		}
+	}
+	if ref != nil && ref.Type == "episode" {
+		for _, show := range m.lib.TVShows {
+			for _, se := range show.Seasons {
+				for _, ep := range se.Episodes {
+					if ep.EpisodeID != ref.ID {
+						continue
+					}
+					item := withLabel(ep, ep.Title)
+					delete(item, "episodeid")
+					item["id"], item["type"] = ep.EpisodeID, "episode"
+					item["season"], item["showtitle"], item["tvshowid"] = se.Season, show.Title, show.TVShowID
+					item["art"] = map[string]string{"tvshow.poster": show.Thumbnail}
+					return item
+				}
+			}
+		}
+	}
+	if ref != nil && ref.Type == "album" {
+		// Kodi reports the album's first song; the album stands in for it
+		for _, al := range m.lib.Albums {
+			if al.AlbumID == ref.ID {
+				return map[string]interface{}{"type": "song", "id": al.AlbumID*100 + 1, "label": al.Title, "title": al.Title, "album": al.Title, "albumid": al.AlbumID, "artist": al.Artist}
+			}
+		}
+	}
+	if ref != nil && ref.Type == "file" {
+		// Recordings queued by their pvr:// path play as themselves
+		for _, rec := range m.lib.Recordings {
+			if rec.File == ref.File {
+				ref = &PlayingRef{Type: "recording", ID: rec.RecordingID}
+			}
+		}
+	}
+	if ref != nil && ref.Type == "recording" {
+		for _, rec := range m.lib.Recordings {
+			if rec.RecordingID == ref.ID {
+				return map[string]interface{}{"type": "recording", "id": rec.RecordingID, "label": rec.Title, "title": rec.Title, "art": rec.Art, "thumbnail": rec.Icon}
+			}
+		}
+	}
+	if ref != nil && ref.Type == "channel" {
+		for _, ch := range m.lib.Channels {
+			if ch.ChannelID == ref.ID {
+				return map[string]interface{}{"type": "channel", "id": ch.ChannelID, "label": ch.Channel, "title": ch.Channel, "thumbnail": ch.Thumbnail}
+			}
+		}
+	}
+	if ref != nil && ref.Type == "file" {
+		// Files outside the library play as unknown items named after the file
+		name := ref.File[strings.LastIndex(ref.File, "/")+1:]
+		return map[string]interface{}{"type": "unknown", "label": name, "title": "", "file": ref.File}
+	}
+	return map[string]interface{}{"type": "unknown", "label": "", "title": ""}
+}
+
+// savedPlaylist lists the saved playlists of a playlists folder, or the
+// items of one playlist.
+func (m *mock) savedPlaylist(dir string) ([]map[string]interface{}, bool) {
+	entries := []map[string]interface{}{}
+	for _, pl := range m.lib.Playlists {
+		if pl.File == dir {
+			for _, ref := range pl.Items {
+				entries = append(entries, m.libraryEntry(ref))
+			}
+			return entries, true
+		}
+		if rest, ok := strings.CutPrefix(pl.File, dir); ok && !strings.Contains(rest, "/") {
+			filetype := "file"
+			if strings.HasSuffix(pl.File, ".xsp") {
+				filetype = "directory"
+			}
+			entries = append(entries, map[string]interface{}{"file": pl.File, "label": pl.Label, "filetype": filetype, "type": "unknown"})
+		}
+	}
+	return entries, len(entries) > 0
+}
+
+// libraryEntry renders a library item the way directory listings and
+// Playlist.GetItems do.
+func (m *mock) libraryEntry(ref PlayingRef) map[string]interface{} {
+	switch ref.Type {
+	case "movie":
+		for _, mv := range m.lib.Movies {
+			if mv.MovieID == ref.ID {
+				return map[string]interface{}{"type": "movie", "id": mv.MovieID, "label": mv.Title, "title": mv.Title, "year": mv.Year, "imdbnumber": mv.UniqueID["imdb"]}
+			}
+		}
+	case "tvshow":
+		for _, show := range m.lib.TVShows {
+			if show.TVShowID == ref.ID {
+				return map[string]interface{}{"type": "tvshow", "id": show.TVShowID, "label": show.Title, "title": show.Title, "year": show.Year}
+			}
+		}
+	case "episode":
+		if _, ep := m.episode(&ref.ID); ep != nil {
+			return map[string]interface{}{"type": "episode", "id": ep.EpisodeID, "label": ep.Title, "title": ep.Title}
+		}
+	case "album":
+		for _, al := range m.lib.Albums {
+			if al.AlbumID == ref.ID {
+				return map[string]interface{}{"type": "album", "id": al.AlbumID, "label": al.Title, "title": al.Title, "year": al.Year}
+			}
+		}
+	case "file":
+		name := ref.File[strings.LastIndex(ref.File, "/")+1:]
+		return map[string]interface{}{"type": "unknown", "label": name, "title": "", "file": ref.File}
+	}
+	return map[string]interface{}{"type": "unknown", "label": "", "title": ""}
+}
+
+// directory lists the files and subdirectories directly within dir, which
+// must be a source or one of its directories.
+func (m *mock) directory(dir string) ([]map[string]interface{}, bool) {
+	if !strings.HasSuffix(dir, "/") {
+		dir += "/"
+	}
+	found := false
+	for _, src := range m.lib.Sources {
+		found = found || strings.HasPrefix(dir, src.File)
+	}
+	if !found {
+		return nil, false
+	}
+	entries := []map[string]interface{}{}
+	seen := map[string]bool{}
+	for _, file := range m.lib.Files {
+		rest, ok := strings.CutPrefix(file, dir)
+		if !ok {
+			continue
+		}
+		if sub, _, isDir := strings.Cut(rest, "/"); isDir {
+			if !seen[sub] {
+				seen[sub] = true
+				entries = append(entries, map[string]interface{}{"file": dir + sub + "/", "label": sub, "filetype": "directory", "type": "unknown"})
+			}
+			continue
+		}
+		entries = append(entries, map[string]interface{}{"file": file, "label": rest, "filetype": "file", "type": "unknown", "size": 1 << 30})
+	}
+	return entries, true
+}
+
+func playerTime(seconds int) map[string]int {
+	return map[string]int{"hours": seconds / 3600, "minutes": seconds / 60 % 60, "seconds": seconds % 60, "milliseconds": 0}
+}
+
+// episode finds an episode by ID along with the show it belongs to.
+func (m *mock) episode(id *int) (*TVShow, *Episode) {
+	if id == nil {
+		return nil, nil
+	}
+	for i := range m.lib.TVShows {
+		show := &m.lib.TVShows[i]
+		for j := range show.Seasons {
+			for k := range show.Seasons[j].Episodes {
+				if ep := &show.Seasons[j].Episodes[k]; ep.EpisodeID == *id {
+					return show, ep
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (m *mock) show(id *int) *TVShow {
+	if id == nil {
+		return nil
+	}
+	for i := range m.lib.TVShows {
+		if m.lib.TVShows[i].TVShowID == *id {
+			return &m.lib.TVShows[i]
+		}
+	}
+	return nil
+}
+
+func (m *mock) movieSet(id int) *MovieSet {
+	for i := range m.lib.MovieSets {
+		if m.lib.MovieSets[i].SetID == id {
+			return &m.lib.MovieSets[i]
+		}
+	}
+	return nil
+}
+
+type mockGenre struct {
+	title string
+	types map[string]bool // "movie", "tvshow"
+}
+
+// genres returns every genre in the library in alphabetical order. Kodi
+// shares genre IDs between movies and shows, so IDs are positions in the
+// combined list.
+func (m *mock) genres() []mockGenre {
+	byTitle := map[string]*mockGenre{}
+	add := func(title, mediaType string) {
+		g, ok := byTitle[title]
+		if !ok {
+			g = &mockGenre{title: title, types: map[string]bool{}}
+			byTitle[title] = g
+		}
+		g.types[mediaType] = true
+	}
+	for _, mv := range m.lib.Movies {
+		for _, g := range mv.Genre {
+			add(g, "movie")
+		}
+	}
+	for _, s := range m.lib.TVShows {
+		for _, g := range s.Genre {
+			add(g, "tvshow")
+		}
+	}
+	genres := make([]mockGenre, 0, len(byTitle))
+	for _, g := range byTitle {
+		genres = append(genres, *g)
+	}
+	sort.Slice(genres, func(i, j int) bool { return genres[i].title < genres[j].title })
+	return genres
+}
+
+// lastPlayed is the last played time Kodi records when a playcount is set:
+// now for a watched item, none for an unwatched one.
+func lastPlayed(playcount int) string {
+	if playcount > 0 {
+		return time.Now().Format(time.DateTime)
+	}
+	return ""
+}
+
+// withLabel converts a fixture to a JSON object and adds Kodi's "label".
+func withLabel(v interface{}, label string) map[string]interface{} {
+	data, _ := json.Marshal(v)
+	obj := map[string]interface{}{}
+	json.Unmarshal(data, &obj)
+	obj["label"] = label
+	return obj
+}
+
+// recentlyAddedLimit is how many items Kodi's GetRecentlyAdded* methods
+// return when no limit is given.
+const recentlyAddedLimit = 25
+
+// recentlyAdded orders items newest first by date added and keeps the first
+// end of them.
+func recentlyAdded(items []map[string]interface{}, end int) []map[string]interface{} {
+	if end <= 0 {
+		end = recentlyAddedLimit
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		a, _ := items[i]["dateadded"].(string)
+		b, _ := items[j]["dateadded"].(string)
+		return a > b
+	})
+	return items[:min(end, len(items))]
+}
+
+func limits(total int) map[string]int {
+	return map[string]int{"start": 0, "end": total, "total": total}
+}
+
+// addedAfter applies a dateadded filter. Only "after" is supported; Kodi
+// compares the "YYYY-MM-DD HH:MM:SS" strings, so this does too.
+func addedAfter(dateAdded, operator, value string) bool {
+	return operator != "after" || dateAdded > value
+}
+
+// page applies a request's limits to items, returning the slice [start, end)
+// and the limits to report. An end of zero means no limit.
+func page(items []map[string]interface{}, start, end int) ([]map[string]interface{}, map[string]int) {
+	total := len(items)
+	start = min(max(start, 0), total)
+	if end <= 0 || end > total {
+		end = total
+	}
+	end = max(end, start)
+	return items[start:end], map[string]int{"start": start, "end": end, "total": total}
+}
+
+// failOnce reports whether uri is flaky art being requested for the first time.
+func (m *mock) failOnce(uri string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.flakyServed[uri] || !slices.Contains(m.lib.FlakyArt, uri) {
+		return false
+	}
+	if m.flakyServed == nil {
+		m.flakyServed = map[string]bool{}
+	}
+	m.flakyServed[uri] = true
+	return true
+}
+
+// Cached thumbnails are served at half the placeholders' size.
+const (
+	thumbWidth  = 100
+	thumbHeight = 150
+)
+
+// thumbnailPath is where a texture's thumbnail is cached under
+// special://thumbnails/, named like Kodi's by a hash.
+func thumbnailPath(id int) string {
+	name := fmt.Sprintf("%08x", id*2654435761%(1<<32))
+	return name[:1] + "/" + name + ".jpg"
+}
+
+// handleImage serves the library's art file for an art URI, or else a
+// placeholder poster coloured by the URI so different items are
+// distinguishable.
+func (m *mock) handleImage(w http.ResponseWriter, r *http.Request) {
+	uri := strings.TrimPrefix(r.URL.Path, "/image/")
+	if uri == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if m.failOnce(uri) {
+		http.Error(w, "Service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	if file := m.artFile(uri); file != "" {
+		http.ServeFile(w, r, file)
+		return
+	}
+	servePlaceholder(w, uri, 2*thumbWidth, 2*thumbHeight)
+}
+
+// handleThumbnail serves the cached thumbnail of a texture looked up through
+// Textures.GetTextures, at /vfs/special://thumbnails/{cachedurl}.
+func (m *mock) handleThumbnail(w http.ResponseWriter, r *http.Request) {
+	path, ok := strings.CutPrefix(strings.TrimPrefix(r.URL.Path, "/vfs/"), "special://thumbnails/")
+	if !ok {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	m.mu.Lock()
+	uri := ""
+	for i, u := range m.textures {
+		if thumbnailPath(i+1) == path {
+			uri = u
+		}
+	}
+	m.mu.Unlock()
+	if uri == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if m.failOnce(uri) {
+		http.Error(w, "Service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	if file := m.artFile(uri); file != "" {
+		http.ServeFile(w, r, file)
+		return
+	}
+	servePlaceholder(w, uri, thumbWidth, thumbHeight)
+}
+
+// servePlaceholder writes a poster-shaped JPEG coloured by uri.
+func servePlaceholder(w http.ResponseWriter, uri string, width, height int) {
+	h := fnv.New32a()
+	h.Write([]byte(uri))
+	sum := h.Sum32()
+	c := color.RGBA{R: uint8(sum >> 16), G: uint8(sum >> 8), B: uint8(sum), A: 255}
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: c}, image.Point{}, draw.Src)
+	w.Header().Set("Content-Type", "image/jpeg")
+	jpeg.Encode(w, img, &jpeg.Options{Quality: 80})
+}
+
+// artFile returns the file in the library's art directory for an art URI
+// such as image://video@mock/movies/up/poster.jpg/, or "" when there is
+// none.
+func (m *mock) artFile(uri string) string {
+	if m.lib.ArtDir == "" {
+		return ""
+	}
+	rel := strings.TrimSuffix(strings.TrimPrefix(uri, "image://"), "/")
+	_, rel, ok := strings.Cut(rel, "/") // drop the image's source, e.g. video@mock
+	if !ok || rel == "" || slices.Contains(strings.Split(rel, "/"), "..") {
+		return ""
+	}
+	file := filepath.Join(m.lib.ArtDir, filepath.FromSlash(rel))
+	if info, err := os.Stat(file); err != nil || info.IsDir() {
+		return ""
+	}
+	return file
+}
+
+// setArt applies a Set*Details art change to art: a null value removes that
+// art type, as in Kodi.
+func setArt(art map[string]string, changes map[string]*string) map[string]string {
+	art = maps.Clone(art)
+	if art == nil {
+		art = map[string]string{}
+	}
+	for t, uri := range changes {
+		if uri == nil {
+			delete(art, t)
+		} else {
+			art[t] = *uri
+		}
+	}
+	return art
+}
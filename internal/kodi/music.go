@@ -0,0 +1,43 @@
+package kodi
+
+func albumsParams() map[string]interface{} {
+	return map[string]interface{}{"properties": []string{"title", "displayartist", "year", "rating", "description", "genre", "thumbnail", "art", "playcount", "lastplayed", "dateadded"}}
+}
+
+func artistsParams() map[string]interface{} {
+	// Only album artists, so the list isn't padded with every guest
+	// performer of every track
+	return map[string]interface{}{"albumartistsonly": true, "properties": []string{"description", "genre", "thumbnail", "art"}}
+}
+
+// GetAlbums returns every album in the music library, fetched a page at a
+// time.
+func (c *Client) GetAlbums() ([]MediaItem, error) {
+	return c.allItems("AudioLibrary.GetAlbums", 41, albumsParams(), "albums")
+}
+
+// GetArtists returns every album artist in the music library, fetched a
+// page at a time.
+func (c *Client) GetArtists() ([]MediaItem, error) {
+	return c.allItems("AudioLibrary.GetArtists", 42, artistsParams(), "artists")
+}
+
+// ScanMusicLibrary starts a music library scan, of every source or only of
+// directory when it is set.
+func (c *Client) ScanMusicLibrary(directory string) error {
+	params := map[string]interface{}{"showdialogs": false}
+	if directory != "" {
+		params["directory"] = directory
+	}
+	req := JsonRPCRequest{JSONRPC: "2.0", Method: "AudioLibrary.Scan", Params: params, ID: 43}
+	var resp JsonRPCResponse
+	return c.sendRequest(req, &resp)
+}
+
+// CleanMusicLibrary starts removing music library entries whose files no
+// longer exist.
+func (c *Client) CleanMusicLibrary() error {
+	req := JsonRPCRequest{JSONRPC: "2.0", Method: "AudioLibrary.Clean", Params: map[string]interface{}{"showdialogs": false}, ID: 44}
+	var resp JsonRPCResponse
+	return c.sendRequest(req, &resp)
+}
@@ -0,0 +1,94 @@
+package kodi
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+)
+
+// DefaultPageSize is how many movies or shows a library query asks Kodi for
+// at once. Large libraries are fetched page by page so no single response
+// is big enough to time out.
+const DefaultPageSize = 500
+
+func (c *Client) pageSize() int {
+	if c.PageSize > 0 {
+		return c.PageSize
+	}
+	return DefaultPageSize
+}
+
+// withLimits returns a copy of params asking for items [start, end).
+func withLimits(params map[string]interface{}, start, end int) map[string]interface{} {
+	p := maps.Clone(params)
+	p["limits"] = map[string]int{"start": start, "end": end}
+	return p
+}
+
+// decodePage reads the item array stored under key in a library result and
+// the total Kodi reported, never returning nil items.
+func decodePage(result json.RawMessage, key string) (items []MediaItem, total int, err error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(result, &fields); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode %s: %w", key, err)
+	}
+	items = []MediaItem{}
+	if raw, ok := fields[key]; ok {
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return nil, 0, fmt.Errorf("failed to decode %s: %w", key, err)
+		}
+	}
+	var limits struct {
+		Total int `json:"total"`
+	}
+	if raw, ok := fields["limits"]; ok {
+		json.Unmarshal(raw, &limits)
+	}
+	return items, limits.Total, nil
+}
+
+// morePages reports whether items past next remain after a page of n items.
+// Without a total from Kodi, a full page means there may be more.
+func (c *Client) morePages(next, n, total int) bool {
+	if total > 0 {
+		return n > 0 && next < total
+	}
+	return n == c.pageSize()
+}
+
+// pageItems fetches method's items, listed under key in the result, a page
+// at a time from start onwards, handing each page to fn.
+func (c *Client) pageItems(method string, id int, params map[string]interface{}, key string, start int, fn func([]MediaItem) error) error {
+	size := c.pageSize()
+	for {
+		req := JsonRPCRequest{JSONRPC: "2.0", Method: method, Params: withLimits(params, start, start+size), ID: id}
+		var resp JsonRPCResponse
+		if err := c.sendRequest(req, &resp); err != nil {
+			return err
+		}
+		items, total, err := decodePage(resp.Result, key)
+		if err != nil {
+			return err
+		}
+		if err := fn(items); err != nil {
+			return err
+		}
+		start += len(items)
+		if !c.morePages(start, len(items), total) {
+			return nil
+		}
+	}
+}
+
+// allItems collects every page of method's items.
+func (c *Client) allItems(method string, id int, params map[string]interface{}, key string) ([]MediaItem, error) {
+	all := []MediaItem{}
+	err := c.pageItems(method, id, params, key, 0, func(items []MediaItem) error {
+		all = append(all, items...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return all, nil
+}
@@ -0,0 +1,186 @@
+package kodi
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type ActivePlayer struct {
+	PlayerID int    `json:"playerid"`
+	Type     string `json:"type"` // video, audio, picture
+}
+
+type PlayerStream struct {
+	Index    int    `json:"index"`
+	Language string `json:"language"`
+	Name     string `json:"name"`
+	Codec    string `json:"codec,omitempty"`
+	Channels int    `json:"channels,omitempty"`
+}
+
+// PlayerStreams describes the audio and subtitle tracks of the item that is
+// currently loaded in a Kodi player.
+type PlayerStreams struct {
+	AudioStreams       []PlayerStream `json:"audiostreams"`
+	Subtitles          []PlayerStream `json:"subtitles"`
+	CurrentAudioStream *PlayerStream  `json:"currentaudiostream,omitempty"`
+	CurrentSubtitle    *PlayerStream  `json:"currentsubtitle,omitempty"`
+	SubtitleEnabled    bool           `json:"subtitleenabled"`
+}
+
+func (c *Client) GetActivePlayers() ([]ActivePlayer, error) {
+	req := JsonRPCRequest{JSONRPC: "2.0", Method: "Player.GetActivePlayers", ID: 10}
+	var resp JsonRPCResponse
+	if err := c.sendRequest(req, &resp); err != nil {
+		return nil, err
+	}
+	players := []ActivePlayer{}
+	if err := json.Unmarshal(resp.Result, &players); err != nil {
+		return nil, fmt.Errorf("failed to decode active players: %w", err)
+	}
+	return players, nil
+}
+
+func (c *Client) GetPlayerStreams(playerID int) (*PlayerStreams, error) {
+	params := map[string]interface{}{
+		"playerid":   playerID,
+		"properties": []string{"audiostreams", "subtitles", "currentaudiostream", "currentsubtitle", "subtitleenabled"},
+	}
+	req := JsonRPCRequest{JSONRPC: "2.0", Method: "Player.GetProperties", Params: params, ID: 11}
+	var resp JsonRPCResponse
+	if err := c.sendRequest(req, &resp); err != nil {
+		return nil, err
+	}
+	var streams PlayerStreams
+	if err := json.Unmarshal(resp.Result, &streams); err != nil {
+		return nil, fmt.Errorf("failed to decode player properties: %w", err)
+	}
+	return &streams, nil
+}
+
+// PlayerState is the playback position reported by Player.Seek.
+type PlayerState struct {
+	Percentage float64    `json:"percentage"`
+	Time       PlayerTime `json:"time"`
+	TotalTime  PlayerTime `json:"totaltime"`
+}
+
+type PlayerTime struct {
+	Hours        int `json:"hours"`
+	Minutes      int `json:"minutes"`
+	Seconds      int `json:"seconds"`
+	Milliseconds int `json:"milliseconds"`
+}
+
+// SeekTarget selects how Player.Seek moves. Set exactly one field:
+// Percentage seeks to an absolute position, Seconds jumps relative to the
+// current one, and Step is one of "smallforward", "smallbackward",
+// "bigforward" or "bigbackward".
+type SeekTarget struct {
+	Percentage *float64 `json:"percentage,omitempty"`
+	Seconds    *int     `json:"seconds,omitempty"`
+	Step       string   `json:"step,omitempty"`
+}
+
+// PlayPause toggles playback and returns the new speed (0 when paused).
+func (c *Client) PlayPause(playerID int) (int, error) {
+	req := JsonRPCRequest{JSONRPC: "2.0", Method: "Player.PlayPause", Params: map[string]interface{}{"playerid": playerID, "play": "toggle"}, ID: 15}
+	var resp JsonRPCResponse
+	if err := c.sendRequest(req, &resp); err != nil {
+		return 0, err
+	}
+	var result struct {
+		Speed int `json:"speed"`
+	}
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		return 0, fmt.Errorf("failed to decode play/pause result: %w", err)
+	}
+	return result.Speed, nil
+}
+
+// Stop ends playback in the player.
+func (c *Client) Stop(playerID int) error {
+	req := JsonRPCRequest{JSONRPC: "2.0", Method: "Player.Stop", Params: map[string]interface{}{"playerid": playerID}, ID: 16}
+	var resp JsonRPCResponse
+	return c.sendRequest(req, &resp)
+}
+
+// Seek moves playback in the player to target and returns the position
+// it landed on.
+func (c *Client) Seek(playerID int, target SeekTarget) (*PlayerState, error) {
+	req := JsonRPCRequest{JSONRPC: "2.0", Method: "Player.Seek", Params: map[string]interface{}{"playerid": playerID, "value": target}, ID: 17}
+	var resp JsonRPCResponse
+	if err := c.sendRequest(req, &resp); err != nil {
+		return nil, err
+	}
+	var state PlayerState
+	if err := json.Unmarshal(resp.Result, &state); err != nil {
+		return nil, fmt.Errorf("failed to decode seek result: %w", err)
+	}
+	return &state, nil
+}
+
+// PlayingItem is what Player.GetItem reports for the item loaded in a player.
+// Type is "movie", "episode", "song" (with its album), or "unknown" for files
+// outside the library, in which case ID is zero.
+type PlayingItem struct {
+	ID        int               `json:"id"`
+	Type      string            `json:"type"`
+	Label     string            `json:"label"`
+	Title     string            `json:"title"`
+	Year      int               `json:"year,omitempty"`
+	ShowTitle string            `json:"showtitle,omitempty"`
+	TVShowID  int               `json:"tvshowid,omitempty"`
+	Season    int               `json:"season,omitempty"`
+	Episode   int               `json:"episode,omitempty"`
+	Album     string            `json:"album,omitempty"`
+	AlbumID   int               `json:"albumid,omitempty"`
+	Thumbnail string            `json:"thumbnail,omitempty"`
+	Art       map[string]string `json:"art,omitempty"`
+}
+
+// PlayerProgress is the playback position of a player plus its speed, which
+// is 0 while paused.
+type PlayerProgress struct {
+	PlayerState
+	Speed int `json:"speed"`
+}
+
+func (c *Client) GetPlayingItem(playerID int) (*PlayingItem, error) {
+	params := map[string]interface{}{
+		"playerid":   playerID,
+		"properties": []string{"title", "year", "showtitle", "tvshowid", "season", "episode", "album", "albumid", "thumbnail", "art"},
+	}
+	req := JsonRPCRequest{JSONRPC: "2.0", Method: "Player.GetItem", Params: params, ID: 18}
+	var resp JsonRPCResponse
+	if err := c.sendRequest(req, &resp); err != nil {
+		return nil, err
+	}
+	var result struct {
+		Item PlayingItem `json:"item"`
+	}
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode playing item: %w", err)
+	}
+	if result.Item.Title == "" {
+		result.Item.Title = result.Item.Label
+	}
+	return &result.Item, nil
+}
+
+func (c *Client) GetPlayerProgress(playerID int) (*PlayerProgress, error) {
+	params := map[string]interface{}{
+		"playerid":   playerID,
+		"properties": []string{"percentage", "time", "totaltime", "speed"},
+	}
+	req := JsonRPCRequest{JSONRPC: "2.0", Method: "Player.GetProperties", Params: params, ID: 19}
+	var resp JsonRPCResponse
+	if err := c.sendRequest(req, &resp); err != nil {
+		return nil, err
+	}
+	var progress PlayerProgress
+	if err := json.Unmarshal(resp.Result, &progress); err != nil {
+		return nil, fmt.Errorf("failed to decode player progress: %w", err)
+	}
+	return &progress, nil
+}
@@ -0,0 +1,69 @@
+package kodi
+
+import "fmt"
+
+// AudioPlaylistID and VideoPlaylistID are Kodi's fixed IDs for its music
+// and video playlists.
+const (
+	AudioPlaylistID = 0
+	VideoPlaylistID = 1
+)
+
+// PlaylistItem is one entry for Playlist.Add or Player.Open. Exactly one of
+// MovieID, EpisodeID, AlbumID, ArtistID, RecordingID, ChannelID, Directory or
+// File should be set; Directory accepts videodb:// paths such as a whole show
+// or season, and File plays a file by path whether or not it is in the
+// library. Albums and artists go on the audio playlist. RecordingID and
+// ChannelID are only accepted by Player.Open; playlists take recordings by
+// their pvr:// path.
+type PlaylistItem struct {
+	MovieID     int    `json:"movieid,omitempty"`
+	EpisodeID   int    `json:"episodeid,omitempty"`
+	AlbumID     int    `json:"albumid,omitempty"`
+	ArtistID    int    `json:"artistid,omitempty"`
+	RecordingID int    `json:"recordingid,omitempty"`
+	ChannelID   int    `json:"channelid,omitempty"`
+	Directory   string `json:"directory,omitempty"`
+	Recursive   bool   `json:"recursive,omitempty"`
+	File        string `json:"file,omitempty"`
+}
+
+// ShowPlaylistItem queues every episode of a show, or of one season when
+// season is non-zero.
+func ShowPlaylistItem(tvshowID, season int) PlaylistItem {
+	dir := fmt.Sprintf("videodb://tvshows/titles/%d/", tvshowID)
+	if season > 0 {
+		dir += fmt.Sprintf("%d/", season)
+	}
+	return PlaylistItem{Directory: dir, Recursive: true}
+}
+
+// ClearPlaylist removes every item from the playlist.
+func (c *Client) ClearPlaylist(playlistID int) error {
+	req := JsonRPCRequest{JSONRPC: "2.0", Method: "Playlist.Clear", Params: map[string]interface{}{"playlistid": playlistID}, ID: 12}
+	var resp JsonRPCResponse
+	return c.sendRequest(req, &resp)
+}
+
+// AddToPlaylist appends items, in order, to the end of the playlist.
+func (c *Client) AddToPlaylist(playlistID int, items []PlaylistItem) error {
+	req := JsonRPCRequest{JSONRPC: "2.0", Method: "Playlist.Add", Params: map[string]interface{}{"playlistid": playlistID, "item": items}, ID: 13}
+	var resp JsonRPCResponse
+	return c.sendRequest(req, &resp)
+}
+
+// PlayPlaylist starts playback of the playlist from position.
+func (c *Client) PlayPlaylist(playlistID, position int) error {
+	params := map[string]interface{}{"item": map[string]int{"playlistid": playlistID, "position": position}}
+	req := JsonRPCRequest{JSONRPC: "2.0", Method: "Player.Open", Params: params, ID: 14}
+	var resp JsonRPCResponse
+	return c.sendRequest(req, &resp)
+}
+
+// Play starts playback of a single item, replacing whatever the player of
+// its kind was playing.
+func (c *Client) Play(item PlaylistItem) error {
+	req := JsonRPCRequest{JSONRPC: "2.0", Method: "Player.Open", Params: map[string]interface{}{"item": item}, ID: 40}
+	var resp JsonRPCResponse
+	return c.sendRequest(req, &resp)
+}
@@ -0,0 +1,131 @@
+package kodi
+
+// Recording is a PVR recording. Runtime is in seconds and StartTime is local
+// time, "2006-01-02 15:04:05". File is a pvr://recordings/ path, which
+// playlists accept.
+type Recording struct {
+	ID        int               `json:"recordingid"`
+	Label     string            `json:"label"`
+	Title     string            `json:"title"`
+	Plot      string            `json:"plot,omitempty"`
+	Channel   string            `json:"channel,omitempty"`
+	StartTime string            `json:"starttime,omitempty"`
+	Runtime   int               `json:"runtime,omitempty"`
+	Genre     []string          `json:"genre,omitempty"`
+	Playcount int               `json:"playcount"`
+	Resume    *Resume           `json:"resume,omitempty"`
+	Icon      string            `json:"icon,omitempty"`
+	Art       map[string]string `json:"art,omitempty"`
+	File      string            `json:"file,omitempty"`
+	Radio     bool              `json:"radio,omitempty"`
+}
+
+// Channel is a live TV channel. BroadcastNow is the programme on air, when
+// the guide has one.
+type Channel struct {
+	ID            int        `json:"channelid"`
+	Label         string     `json:"label"`
+	Channel       string     `json:"channel"`
+	ChannelNumber int        `json:"channelnumber,omitempty"`
+	Thumbnail     string     `json:"thumbnail,omitempty"`
+	BroadcastNow  *Broadcast `json:"broadcastnow,omitempty"`
+}
+
+// Broadcast is a programme of the TV guide.
+type Broadcast struct {
+	Title     string `json:"title"`
+	StartTime string `json:"starttime,omitempty"`
+	EndTime   string `json:"endtime,omitempty"`
+}
+
+var (
+	recordingProperties = []string{"title", "plot", "channel", "starttime", "runtime", "genre", "playcount", "resume", "icon", "art", "file", "radio"}
+	channelProperties   = []string{"channel", "channelnumber", "thumbnail", "broadcastnow"}
+)
+
+// Poster returns the recording's best artwork: its poster or thumb, else the
+// channel icon.
+func (r Recording) Poster() string {
+	for _, key := range []string{"poster", "thumb"} {
+		if art := r.Art[key]; art != "" {
+			return art
+		}
+	}
+	return r.Icon
+}
+
+// GetRecordings returns the PVR's TV recordings, leaving out radio.
+func (c *Client) GetRecordings() ([]Recording, error) {
+	var result struct {
+		Recordings []Recording `json:"recordings"`
+	}
+	if err := c.call("PVR.GetRecordings", 45, map[string]interface{}{"properties": recordingProperties}, &result); err != nil {
+		return nil, err
+	}
+	recordings := []Recording{}
+	for _, r := range result.Recordings {
+		if r.Radio {
+			continue
+		}
+		if r.Title == "" {
+			r.Title = r.Label
+		}
+		recordings = append(recordings, r)
+	}
+	return recordings, nil
+}
+
+// GetRecordingDetails fetches one recording, returning ErrNotFound if the
+// PVR has no recording with that ID.
+func (c *Client) GetRecordingDetails(recordingID int) (*Recording, error) {
+	params := map[string]interface{}{"recordingid": recordingID, "properties": recordingProperties}
+	var result struct {
+		RecordingDetails *Recording `json:"recordingdetails"`
+	}
+	if err := c.getDetails("PVR.GetRecordingDetails", params, 46, &result); err != nil {
+		return nil, err
+	}
+	if result.RecordingDetails == nil {
+		return nil, ErrNotFound
+	}
+	r := result.RecordingDetails
+	if r.Title == "" {
+		r.Title = r.Label
+	}
+	return r, nil
+}
+
+// GetChannels returns the live TV channels, in channel number order.
+func (c *Client) GetChannels() ([]Channel, error) {
+	params := map[string]interface{}{"channelgroupid": "alltv", "properties": channelProperties}
+	var result struct {
+		Channels []Channel `json:"channels"`
+	}
+	if err := c.call("PVR.GetChannels", 47, params, &result); err != nil {
+		return nil, err
+	}
+	if result.Channels == nil {
+		return []Channel{}, nil
+	}
+	return result.Channels, nil
+}
+
+// GetChannelDetails fetches one channel, returning ErrNotFound if the PVR
+// has no channel with that ID.
+func (c *Client) GetChannelDetails(channelID int) (*Channel, error) {
+	params := map[string]interface{}{"channelid": channelID, "properties": channelProperties}
+	var result struct {
+		ChannelDetails *Channel `json:"channeldetails"`
+	}
+	if err := c.getDetails("PVR.GetChannelDetails", params, 48, &result); err != nil {
+		return nil, err
+	}
+	if result.ChannelDetails == nil {
+		return nil, ErrNotFound
+	}
+	ch := result.ChannelDetails
+	if ch.Channel == "" {
+		ch.Channel = ch.Label
+	}
+	return ch, nil
+}
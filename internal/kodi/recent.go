@@ -0,0 +1,100 @@
+package kodi
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+var (
+	shelfMovieProperties   = []string{"title", "year", "rating", "runtime", "thumbnail", "art", "streamdetails", "playcount", "lastplayed", "dateadded", "resume", "uniqueid"}
+	shelfEpisodeProperties = []string{"title", "showtitle", "tvshowid", "season", "episode", "rating", "runtime", "thumbnail", "art", "streamdetails", "playcount", "lastplayed", "dateadded", "resume"}
+)
+
+// inProgressFilter matches items with a saved resume point.
+var inProgressFilter = map[string]string{"field": "inprogress", "operator": "true", "value": ""}
+
+// GetRecentlyAddedMovies returns up to limit movies, newest additions to the
+// library first.
+func (c *Client) GetRecentlyAddedMovies(limit int) ([]MediaItem, error) {
+	params := map[string]interface{}{
+		"properties": shelfMovieProperties,
+		"limits":     map[string]int{"start": 0, "end": limit},
+	}
+	var result struct {
+		Movies []MediaItem `json:"movies"`
+	}
+	if err := c.call("VideoLibrary.GetRecentlyAddedMovies", 32, params, &result); err != nil {
+		return nil, err
+	}
+	return nonNil(result.Movies), nil
+}
+
+// GetRecentlyAddedEpisodes returns up to limit episodes of any show, newest
+// additions first.
+func (c *Client) GetRecentlyAddedEpisodes(limit int) ([]MediaItem, error) {
+	params := map[string]interface{}{
+		"properties": shelfEpisodeProperties,
+		"limits":     map[string]int{"start": 0, "end": limit},
+	}
+	var result struct {
+		Episodes []MediaItem `json:"episodes"`
+	}
+	if err := c.call("VideoLibrary.GetRecentlyAddedEpisodes", 33, params, &result); err != nil {
+		return nil, err
+	}
+	return nonNil(result.Episodes), nil
+}
+
+// GetInProgressMovies returns the movies that were stopped part way through,
+// most recently played first.
+func (c *Client) GetInProgressMovies() ([]MediaItem, error) {
+	params := map[string]interface{}{
+		"properties": shelfMovieProperties,
+		"filter":     inProgressFilter,
+		"sort":       map[string]string{"method": "lastplayed", "order": "descending"},
+	}
+	var result struct {
+		Movies []MediaItem `json:"movies"`
+	}
+	if err := c.call("VideoLibrary.GetMovies", 34, params, &result); err != nil {
+		return nil, err
+	}
+	return nonNil(result.Movies), nil
+}
+
+// GetInProgressEpisodes returns the episodes of every show that were stopped
+// part way through, most recently played first.
+func (c *Client) GetInProgressEpisodes() ([]MediaItem, error) {
+	params := map[string]interface{}{
+		"properties": shelfEpisodeProperties,
+		"filter":     inProgressFilter,
+		"sort":       map[string]string{"method": "lastplayed", "order": "descending"},
+	}
+	var result struct {
+		Episodes []MediaItem `json:"episodes"`
+	}
+	if err := c.call("VideoLibrary.GetEpisodes", 35, params, &result); err != nil {
+		return nil, err
+	}
+	return nonNil(result.Episodes), nil
+}
+
+// call sends method and decodes its result into out.
+func (c *Client) call(method string, id int, params interface{}, out interface{}) error {
+	req := JsonRPCRequest{JSONRPC: "2.0", Method: method, Params: params, ID: id}
+	var resp JsonRPCResponse
+	if err := c.sendRequest(req, &resp); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(resp.Result, out); err != nil {
+		return fmt.Errorf("failed to decode %s result: %w", method, err)
+	}
+	return nil
+}
+
+func nonNil(items []MediaItem) []MediaItem {
+	if items == nil {
+		return []MediaItem{}
+	}
+	return items
+}
@@ -0,0 +1,188 @@
+package kodi
+
+import (
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kodi boxes that sleep take a moment to answer again after waking, so
+// reads are retried with backoff. A host that keeps failing trips a circuit
+// breaker shared by every client for that host, and further calls fail fast
+// with an UnavailableError until the cooldown has passed and a trial call
+// gets through.
+
+// RetryPolicy controls how failed calls are retried and when a host is
+// considered down.
+type RetryPolicy struct {
+	// Attempts is the total number of tries for a retryable call; 1
+	// disables retries.
+	Attempts int
+	// BaseDelay is the wait before the first retry, doubling for each
+	// further one up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	// BreakerThreshold consecutive failed calls open the host's breaker for
+	// BreakerCooldown; zero disables the breaker.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+// DefaultRetryPolicy is used by NewClient.
+var DefaultRetryPolicy = RetryPolicy{
+	Attempts:         3,
+	BaseDelay:        250 * time.Millisecond,
+	MaxDelay:         2 * time.Second,
+	BreakerThreshold: 5,
+	BreakerCooldown:  30 * time.Second,
+}
+
+// UnavailableError is returned without contacting Kodi while the host's
+// circuit breaker is open.
+type UnavailableError struct {
+	Host    string
+	RetryAt time.Time
+}
+
+func (e *UnavailableError) Error() string {
+	return fmt.Sprintf("Kodi host %s is unavailable, retrying after %s", e.Host, e.RetryAt.Format(time.RFC3339))
+}
+
+// retryable reports whether method is safe to send again when Kodi may
+// already have acted on it. Reads and setters are; commands such as
+// Player.PlayPause or Playlist.Add are not.
+func retryable(method string) bool {
+	return strings.Contains(method, ".Get") || strings.Contains(method, ".Set") || method == "JSONRPC.Ping"
+}
+
+// transient reports whether a response is worth retrying: the host couldn't
+// be reached or a proxy in front of it answered for it.
+func transient(resp rawResponse) bool {
+	if resp.err != nil {
+		return true
+	}
+	switch resp.status {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// delay returns the wait before retry n (from 0), with jitter so clients
+// woken by the same outage don't retry in lockstep.
+func (p RetryPolicy) delay(n int) time.Duration {
+	d := p.BaseDelay << n
+	if d > p.MaxDelay || d <= 0 {
+		d = p.MaxDelay
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// withRetries calls fetch until it succeeds, fails for good or, if retry is
+// set, runs out of attempts, recording the outcome on the host's breaker.
+func (c *Client) withRetries(retry bool, fetch func() rawResponse) rawResponse {
+	b := hostBreaker(c.HostURL)
+	if retryAt, open := b.check(c.Retry); open {
+		return rawResponse{err: &UnavailableError{Host: c.HostURL, RetryAt: retryAt}}
+	}
+
+	attempts := 1
+	if retry && c.Retry.Attempts > 1 {
+		attempts = c.Retry.Attempts
+	}
+	var resp rawResponse
+	for n := 0; n < attempts; n++ {
+		if n > 0 {
+			time.Sleep(c.Retry.delay(n - 1))
+		}
+		resp = fetch()
+		if !transient(resp) {
+			b.succeeded(c.HostURL)
+			c.reportCall(nil)
+			return resp
+		}
+	}
+	b.failed(c.Retry, c.HostURL)
+	if resp.err != nil {
+		c.reportCall(resp.err)
+	} else {
+		c.reportCall(fmt.Errorf("kodi returned HTTP %d", resp.status))
+	}
+	return resp
+}
+
+func (c *Client) reportCall(err error) {
+	if c.OnCall != nil {
+		c.OnCall(err)
+	}
+}
+
+type breaker struct {
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	trial     bool // a call is testing whether an open host is back
+}
+
+var (
+	breakersMu sync.Mutex
+	breakers   = map[string]*breaker{}
+)
+
+func hostBreaker(host string) *breaker {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	b, ok := breakers[host]
+	if !ok {
+		b = &breaker{}
+		breakers[host] = b
+	}
+	return b
+}
+
+// check reports whether calls to the host should fail fast. Once the
+// cooldown has passed a single call is let through as a trial; the rest keep
+// failing fast until it reports back.
+func (b *breaker) check(p RetryPolicy) (retryAt time.Time, open bool) {
+	if p.BreakerThreshold <= 0 {
+		return time.Time{}, false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < p.BreakerThreshold {
+		return time.Time{}, false
+	}
+	if time.Now().Before(b.openUntil) || b.trial {
+		return b.openUntil, true
+	}
+	b.trial = true
+	return time.Time{}, false
+}
+
+func (b *breaker) succeeded(host string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.trial {
+		slog.Info("Kodi host reachable again", "host", host)
+	}
+	b.failures = 0
+	b.trial = false
+}
+
+func (b *breaker) failed(p RetryPolicy, host string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.trial = false
+	if p.BreakerThreshold > 0 && b.failures >= p.BreakerThreshold {
+		b.openUntil = time.Now().Add(p.BreakerCooldown)
+		if b.failures == p.BreakerThreshold {
+			slog.Warn("Kodi host unreachable, failing fast", "host", host, "cooldown", p.BreakerCooldown.String())
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package kodi
+
+import (
+	"errors"
+	"strings"
+)
+
+// PlaylistsDir is where Kodi keeps saved playlists, with a video and a
+// music folder of smart (.xsp) and plain (.m3u) playlists.
+const PlaylistsDir = "special://profile/playlists/"
+
+// PlaylistEntry is an item of a saved playlist or of the play queue. ID and
+// Type identify library items (movie, tvshow, episode, album, song, ...);
+// Type is "unknown" for files outside the library.
+type PlaylistEntry struct {
+	ID         int    `json:"id,omitempty"`
+	Type       string `json:"type"`
+	Label      string `json:"label"`
+	Title      string `json:"title,omitempty"`
+	Year       int    `json:"year,omitempty"`
+	IMDbNumber string `json:"imdbnumber,omitempty"`
+	File       string `json:"file,omitempty"`
+}
+
+var playlistEntryProperties = []string{"title", "year", "imdbnumber", "file"}
+
+// GetSavedPlaylists returns the saved playlists of media, "video" or
+// "music". Kodi lists smart playlists as directories.
+func (c *Client) GetSavedPlaylists(media string) ([]FileEntry, error) {
+	params := map[string]interface{}{
+		"directory": PlaylistsDir + media + "/",
+		"media":     media,
+		"sort":      map[string]interface{}{"method": "label", "order": "ascending", "ignorearticle": true},
+	}
+	var result struct {
+		Files []FileEntry `json:"files"`
+	}
+	// Kodi rejects the folder until a playlist has been saved in it
+	if err := c.getDetails("Files.GetDirectory", params, 49, &result); err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	playlists := []FileEntry{}
+	for _, f := range result.Files {
+		if strings.HasSuffix(f.File, ".xsp") || strings.HasSuffix(f.File, ".m3u") || f.FileType == "directory" {
+			playlists = append(playlists, f)
+		}
+	}
+	return playlists, nil
+}
+
+// GetSavedPlaylistItems returns what the saved playlist at path holds; a
+// smart playlist is resolved against the library. It returns ErrNotFound if
+// Kodi can't open the playlist.
+func (c *Client) GetSavedPlaylistItems(path, media string) ([]PlaylistEntry, error) {
+	params := map[string]interface{}{"directory": path, "media": media, "properties": playlistEntryProperties}
+	var result struct {
+		Files []PlaylistEntry `json:"files"`
+	}
+	if err := c.getDetails("Files.GetDirectory", params, 50, &result); err != nil {
+		return nil, err
+	}
+	if result.Files == nil {
+		return []PlaylistEntry{}, nil
+	}
+	return result.Files, nil
+}
+
+// GetPlaylistItems returns the items queued on a play queue,
+// VideoPlaylistID or AudioPlaylistID.
+func (c *Client) GetPlaylistItems(playlistID int) ([]PlaylistEntry, error) {
+	params := map[string]interface{}{"playlistid": playlistID, "properties": playlistEntryProperties}
+	var result struct {
+		Items []PlaylistEntry `json:"items"`
+	}
+	if err := c.call("Playlist.GetItems", 51, params, &result); err != nil {
+		return nil, err
+	}
+	if result.Items == nil {
+		return []PlaylistEntry{}, nil
+	}
+	return result.Items, nil
+}
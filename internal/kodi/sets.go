@@ -0,0 +1,59 @@
+package kodi
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// GetMovieSets returns the movie sets (collections such as "The Lord of the
+// Rings Collection") in the library.
+func (c *Client) GetMovieSets() ([]MediaItem, error) {
+	params := map[string]interface{}{
+		"properties": []string{"title", "plot", "thumbnail", "art", "playcount"},
+		"sort":       map[string]string{"method": "sorttitle", "order": "ascending"},
+	}
+	req := JsonRPCRequest{JSONRPC: "2.0", Method: "VideoLibrary.GetMovieSets", Params: params, ID: 29}
+	var resp JsonRPCResponse
+	if err := c.sendRequest(req, &resp); err != nil {
+		return nil, err
+	}
+	var result struct {
+		Sets []MediaItem `json:"sets"`
+	}
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode movie sets: %w", err)
+	}
+	if result.Sets == nil {
+		result.Sets = []MediaItem{}
+	}
+	return result.Sets, nil
+}
+
+// GetMovieSetMovies returns the movies in a movie set in release order.
+func (c *Client) GetMovieSetMovies(setID int) ([]MediaItem, error) {
+	params := map[string]interface{}{
+		"setid":      setID,
+		"properties": []string{"title"},
+		"movies": map[string]interface{}{
+			"properties": []string{"title", "year", "rating", "runtime", "thumbnail", "art", "streamdetails", "playcount", "lastplayed", "resume", "uniqueid"},
+			"sort":       map[string]string{"method": "year", "order": "ascending"},
+		},
+	}
+	req := JsonRPCRequest{JSONRPC: "2.0", Method: "VideoLibrary.GetMovieSetDetails", Params: params, ID: 30}
+	var resp JsonRPCResponse
+	if err := c.sendRequest(req, &resp); err != nil {
+		return nil, err
+	}
+	var result struct {
+		SetDetails struct {
+			Movies []MediaItem `json:"movies"`
+		} `json:"setdetails"`
+	}
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode movie set %d: %w", setID, err)
+	}
+	if result.SetDetails.Movies == nil {
+		result.SetDetails.Movies = []MediaItem{}
+	}
+	return result.SetDetails.Movies, nil
+}
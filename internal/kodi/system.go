@@ -0,0 +1,86 @@
+package kodi
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// MinAPIMajor is the oldest JSON-RPC API major version (Kodi 17 Krypton)
+// the app's library queries work against; older hosts lack uniqueid.
+const MinAPIMajor = 8
+
+// Version is a Kodi release, e.g. 21.1 "stable".
+type Version struct {
+	Major    int    `json:"major"`
+	Minor    int    `json:"minor"`
+	Revision string `json:"revision,omitempty"`
+	Tag      string `json:"tag,omitempty"`
+}
+
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d", v.Major, v.Minor)
+	if v.Tag != "" && v.Tag != "stable" {
+		s += " " + v.Tag
+	}
+	return s
+}
+
+// APIVersion is the version of Kodi's JSON-RPC API.
+type APIVersion struct {
+	Major int `json:"major"`
+	Minor int `json:"minor"`
+	Patch int `json:"patch"`
+}
+
+func (v APIVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// SystemInfo identifies a Kodi installation.
+type SystemInfo struct {
+	Name    string
+	Version Version
+	API     APIVersion
+}
+
+// Capabilities reports which optional features the app relies on the host
+// supports, from its API version.
+func (i SystemInfo) Capabilities() map[string]bool {
+	return map[string]bool{
+		// uniqueid (IMDb/TMDb IDs) arrived in Kodi 17
+		"unique_ids": i.API.Major >= 8,
+		// Stream details report hdrtype from Kodi 19
+		"hdr_type": i.API.Major >= 12,
+	}
+}
+
+// GetSystemInfo fetches the Kodi version and JSON-RPC API version in one
+// batch.
+func (c *Client) GetSystemInfo() (*SystemInfo, error) {
+	app := &BatchCall{Method: "Application.GetProperties", Params: map[string]interface{}{"properties": []string{"name", "version"}}}
+	api := &BatchCall{Method: "JSONRPC.Version"}
+	if err := c.Batch(app, api); err != nil {
+		return nil, err
+	}
+	if app.Err != nil {
+		return nil, app.Err
+	}
+	if api.Err != nil {
+		return nil, api.Err
+	}
+
+	var props struct {
+		Name    string  `json:"name"`
+		Version Version `json:"version"`
+	}
+	if err := json.Unmarshal(app.Result, &props); err != nil {
+		return nil, fmt.Errorf("failed to decode application properties: %w", err)
+	}
+	var version struct {
+		Version APIVersion `json:"version"`
+	}
+	if err := json.Unmarshal(api.Result, &version); err != nil {
+		return nil, fmt.Errorf("failed to decode API version: %w", err)
+	}
+	return &SystemInfo{Name: props.Name, Version: props.Version, API: version.Version}, nil
+}
@@ -0,0 +1,92 @@
+package kodi
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/url"
+	"strings"
+)
+
+// errNoTexture means Kodi hasn't cached an image, or can't say whether it
+// has; GetArtwork then downloads it through /image/.
+var errNoTexture = errors.New("image not in Kodi's texture cache")
+
+// rpcMethodNotFound is the JSON-RPC code for methods a Kodi version lacks.
+const rpcMethodNotFound = -32601
+
+// Texture is an image in Kodi's texture cache. CachedURL is the cached
+// thumbnail's path under special://thumbnails/, which Kodi decoded and sized
+// down from the original URL.
+type Texture struct {
+	ID        int           `json:"textureid"`
+	URL       string        `json:"url"`
+	CachedURL string        `json:"cachedurl"`
+	Sizes     []TextureSize `json:"sizes,omitempty"`
+}
+
+// TextureSize is one cached rendition of a texture.
+type TextureSize struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+	Size   int `json:"size"`
+}
+
+// textureURL unwraps an art URI such as image://http%3a%2f%2fhost%2fa.jpg/
+// into the URL the texture cache knows it by. URIs of embedded art
+// (image://video@...) are cached under the full URI.
+func textureURL(imageURI string) string {
+	inner, ok := strings.CutPrefix(imageURI, "image://")
+	if !ok {
+		return imageURI
+	}
+	inner = strings.TrimSuffix(inner, "/")
+	unescaped, err := url.PathUnescape(inner)
+	if err != nil || strings.HasPrefix(unescaped, "video@") || strings.HasPrefix(unescaped, "music@") {
+		return imageURI
+	}
+	return unescaped
+}
+
+// GetTexture looks up an art URI in Kodi's texture cache, returning
+// errNoTexture if it isn't cached.
+func (c *Client) GetTexture(imageURI string) (*Texture, error) {
+	params := map[string]interface{}{
+		"properties": []string{"url", "cachedurl", "sizes"},
+		"filter":     map[string]interface{}{"field": "url", "operator": "is", "value": textureURL(imageURI)},
+	}
+	var result struct {
+		Textures []Texture `json:"textures"`
+	}
+	if err := c.call("Textures.GetTextures", 52, params, &result); err != nil {
+		var rpcErr *JsonRPCError
+		if errors.As(err, &rpcErr) && rpcErr.Code == rpcMethodNotFound {
+			c.noTextures.Store(true)
+			return nil, errNoTexture
+		}
+		return nil, err
+	}
+	for _, t := range result.Textures {
+		if t.CachedURL != "" {
+			return &t, nil
+		}
+	}
+	return nil, errNoTexture
+}
+
+// getCachedArtwork downloads Kodi's cached thumbnail of an art URI, which is
+// usually far smaller than the original. Hosts whose web server won't serve
+// the thumbnails folder are remembered and skipped from then on.
+func (c *Client) getCachedArtwork(imageURI string) (io.ReadCloser, error) {
+	t, err := c.GetTexture(imageURI)
+	if err != nil {
+		return nil, err
+	}
+	body, err := c.fetchImage("/vfs/" + url.QueryEscape("special://thumbnails/"+t.CachedURL))
+	if errors.Is(err, ErrImageNotFound) || errors.Is(err, errImageForbidden) {
+		slog.Info("Kodi doesn't serve cached thumbnails, downloading originals", "host", c.HostURL, "error", err)
+		c.noTextures.Store(true)
+		return nil, errNoTexture
+	}
+	return body, err
+}
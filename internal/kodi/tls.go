@@ -0,0 +1,69 @@
+package kodi
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// TLSOptions configure HTTPS connections to a Kodi host behind a TLS
+// reverse proxy, e.g. one with a self-signed certificate.
+type TLSOptions struct {
+	// SkipVerify accepts any certificate the host presents.
+	SkipVerify bool
+	// CACert is the path of a PEM bundle of CAs to trust in addition to the
+	// system roots.
+	CACert string
+}
+
+// Transports are shared by every client with the same options, so
+// connections to a host are reused across requests.
+var (
+	transportsMu sync.Mutex
+	transports   = map[TLSOptions]*http.Transport{}
+)
+
+// Transport returns the HTTP transport for o, or nil for the default one.
+func (o TLSOptions) Transport() (http.RoundTripper, error) {
+	if o == (TLSOptions{}) {
+		return nil, nil
+	}
+	transportsMu.Lock()
+	defer transportsMu.Unlock()
+	if t, ok := transports[o]; ok {
+		return t, nil
+	}
+
+	cfg := &tls.Config{InsecureSkipVerify: o.SkipVerify}
+	if o.CACert != "" {
+		pem, err := os.ReadFile(o.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", o.CACert)
+		}
+		cfg.RootCAs = pool
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.TLSClientConfig = cfg
+	transports[o] = t
+	return t, nil
+}
+
+// SetTLS makes the client connect with o.
+func (c *Client) SetTLS(o TLSOptions) error {
+	t, err := o.Transport()
+	if err != nil {
+		return err
+	}
+	c.HTTPClient.Transport = t
+	return nil
+}
@@ -0,0 +1,8 @@
+package media
+
+import "fmt"
+
+// FormatEpisode renders a season and episode number as "S03E05".
+func FormatEpisode(season, episode int) string {
+	return fmt.Sprintf("S%02dE%02d", season, episode)
+}
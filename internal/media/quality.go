@@ -0,0 +1,68 @@
+package media
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Resolution classifies a video stream's frame size as 2160, 1080, 720, 576
+// or 480 lines, the way Kodi's own resolution flags do: a frame counts as
+// the smallest class it fits in, so letterboxed encodes like 1920x800 are
+// still 1080. It returns 0 when the size is unknown.
+func Resolution(width, height int) int {
+	switch {
+	case width <= 0 && height <= 0:
+		return 0
+	case width <= 720 && height <= 480:
+		return 480
+	case width <= 768 && height <= 576:
+		return 576
+	case width <= 1280 && height <= 720:
+		return 720
+	case width <= 1920 && height <= 1080:
+		return 1080
+	default:
+		return 2160
+	}
+}
+
+// FormatQuality renders a resolution and Kodi HDR type as a compact badge,
+// e.g. "2160p HDR", "1080p" or "DVD" for standard definition. It returns an
+// empty string for unknown resolutions.
+func FormatQuality(resolution int, hdrType string) string {
+	var q string
+	switch {
+	case resolution <= 0:
+		return ""
+	case resolution <= 576:
+		q = "DVD"
+	default:
+		q = fmt.Sprintf("%dp", resolution)
+	}
+	if hdrType != "" {
+		q += " HDR"
+	}
+	return q
+}
+
+// ParseResolution reads a minimum resolution such as "1080", "1080p" or
+// "4k" and returns its line count.
+func ParseResolution(s string) (int, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "4k", "uhd":
+		return 2160, nil
+	case "hd":
+		return 720, nil
+	case "fhd":
+		return 1080, nil
+	case "sd", "dvd":
+		return 480, nil
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(s, "p"))
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid resolution %q", s)
+	}
+	return n, nil
+}
@@ -0,0 +1,22 @@
+// Package media holds small helpers shared by the Kodi client and the
+// database layer.
+package media
+
+import "fmt"
+
+// FormatRuntime renders a runtime in seconds as "2h 16m" or "45m". It returns
+// an empty string for unknown (zero) runtimes.
+func FormatRuntime(seconds int) string {
+	if seconds <= 0 {
+		return ""
+	}
+	h := seconds / 3600
+	m := (seconds % 3600) / 60
+	if h > 0 {
+		return fmt.Sprintf("%dh %dm", h, m)
+	}
+	if m == 0 {
+		return fmt.Sprintf("%ds", seconds)
+	}
+	return fmt.Sprintf("%dm", m)
+}
@@ -0,0 +1,340 @@
+// Package outbound paces requests to third-party web APIs such as TMDB, OMDb
+// and Trakt. Each provider gets a request budget, backs off when it answers
+// 429 or 503, and has successful GET responses cached, so a large sync can't
+// burn through a household's API quota or get its IP throttled.
+package outbound
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxCachedBody is the largest response body kept in the cache.
+	maxCachedBody = 1 << 20
+	// maxCacheEntries bounds the number of cached responses.
+	maxCacheEntries = 512
+
+	minBackoff = time.Second
+	maxBackoff = 5 * time.Minute
+)
+
+// Budget limits one provider to Requests per Per, e.g. 40 per "10s", and
+// caches its successful GET responses for CacheTTL. Durations are strings
+// such as "24h"; a zero Requests leaves the provider unthrottled and an
+// empty CacheTTL disables caching.
+type Budget struct {
+	Requests int    `json:"requests,omitempty"`
+	Per      string `json:"per,omitempty"`
+	CacheTTL string `json:"cache_ttl,omitempty"`
+}
+
+// DefaultBudgets are the budgets of the APIs the app knows about, keyed by
+// host, kept within each provider's published limits. Configured budgets
+// replace them.
+var DefaultBudgets = map[string]Budget{
+	"api.themoviedb.org": {Requests: 40, Per: "10s", CacheTTL: "24h"},
+	"www.omdbapi.com":    {Requests: 1000, Per: "24h", CacheTTL: "24h"},
+	"api.trakt.tv":       {Requests: 1000, Per: "5m", CacheTTL: "1h"},
+}
+
+// ThrottledError is returned instead of waiting when a provider's budget or
+// backoff wouldn't let a request through before its deadline.
+type ThrottledError struct {
+	Host  string
+	Until time.Time
+}
+
+func (e *ThrottledError) Error() string {
+	return fmt.Sprintf("requests to %s are throttled until %s", e.Host, e.Until.Format(time.RFC3339))
+}
+
+// Stats describe one provider's traffic since startup.
+type Stats struct {
+	Host         string     `json:"host"`
+	Requests     int        `json:"requests"`
+	CacheHits    int        `json:"cache_hits"`
+	Throttled    int        `json:"throttled"`
+	BackoffUntil *time.Time `json:"backoff_until,omitempty"`
+}
+
+// provider is the budget and state of one API host. Hosts without a budget
+// still get a provider, so their 429s are backed off from.
+type provider struct {
+	host     string
+	requests int
+	per      time.Duration
+	cacheTTL time.Duration
+
+	// tokens refill at requests per per, up to requests
+	tokens float64
+	refill time.Time
+
+	backoff      time.Duration
+	backoffUntil time.Time
+
+	stats Stats
+}
+
+type cacheEntry struct {
+	status  int
+	header  http.Header
+	body    []byte
+	expires time.Time
+}
+
+// Transport is an http.RoundTripper applying provider budgets, backoff and
+// caching to requests made through Base.
+type Transport struct {
+	Base http.RoundTripper
+
+	mu        sync.Mutex
+	budgets   map[string]Budget
+	providers map[string]*provider
+	cache     map[string]cacheEntry
+}
+
+// New returns a Transport sending requests through base, with budgets added
+// to or replacing DefaultBudgets. Budgets match their host and its
+// subdomains.
+func New(base http.RoundTripper, budgets map[string]Budget) (*Transport, error) {
+	all := map[string]Budget{}
+	for host, b := range DefaultBudgets {
+		all[host] = b
+	}
+	for host, b := range budgets {
+		host = strings.ToLower(strings.TrimSpace(host))
+		if b.Requests < 0 {
+			return nil, fmt.Errorf("external API %s: requests must not be negative", host)
+		}
+		for _, f := range [][2]string{{"per", b.Per}, {"cache_ttl", b.CacheTTL}} {
+			if f[1] == "" {
+				continue
+			}
+			if d, err := time.ParseDuration(f[1]); err != nil || d < 0 {
+				return nil, fmt.Errorf("external API %s: invalid %s %q", host, f[0], f[1])
+			}
+		}
+		if b.Requests > 0 && b.Per == "" {
+			return nil, fmt.Errorf("external API %s: requests needs a per duration", host)
+		}
+		all[host] = b
+	}
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{
+		Base:      base,
+		budgets:   all,
+		providers: map[string]*provider{},
+		cache:     map[string]cacheEntry{},
+	}, nil
+}
+
+// provider returns the state of the provider serving host: the most
+// specific budget matching it, shared by its subdomains, or host itself when
+// no budget does.
+func (t *Transport) provider(host string) *provider {
+	host = strings.ToLower(host)
+	best := ""
+	for n := range t.budgets {
+		if (host == n || strings.HasSuffix(host, "."+n)) && len(n) > len(best) {
+			best = n
+		}
+	}
+	name, budget := host, Budget{}
+	if best != "" {
+		name, budget = best, t.budgets[best]
+	}
+	if p, ok := t.providers[name]; ok {
+		return p
+	}
+	p := &provider{host: name, requests: budget.Requests, stats: Stats{Host: name}}
+	p.per, _ = time.ParseDuration(budget.Per)
+	p.cacheTTL, _ = time.ParseDuration(budget.CacheTTL)
+	p.tokens, p.refill = float64(p.requests), time.Now()
+	t.providers[name] = p
+	return p
+}
+
+// reserve takes a request from p's budget, returning how long to wait
+// before sending it; zero means send now.
+func (t *Transport) reserve(p *provider) time.Duration {
+	now := time.Now()
+	if now.Before(p.backoffUntil) {
+		return p.backoffUntil.Sub(now)
+	}
+	if p.requests == 0 || p.per <= 0 {
+		return 0
+	}
+	rate := float64(p.requests) / p.per.Seconds()
+	p.tokens = min(float64(p.requests), p.tokens+now.Sub(p.refill).Seconds()*rate)
+	p.refill = now
+	if p.tokens >= 1 {
+		p.tokens--
+		return 0
+	}
+	return time.Duration((1 - p.tokens) / rate * float64(time.Second))
+}
+
+// RoundTrip sends req once the provider's budget and backoff allow it,
+// answering repeated GETs from the cache while they are fresh.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := ""
+	if req.Method == http.MethodGet && req.Header.Get("Authorization") == "" {
+		key = req.URL.String()
+	}
+
+	for {
+		t.mu.Lock()
+		p := t.provider(req.URL.Hostname())
+		if key != "" && p.cacheTTL > 0 {
+			if e, ok := t.cache[key]; ok && time.Now().Before(e.expires) {
+				p.stats.CacheHits++
+				t.mu.Unlock()
+				return cachedResponse(req, e), nil
+			}
+		}
+		wait := t.reserve(p)
+		t.mu.Unlock()
+		if wait == 0 {
+			break
+		}
+		if deadline, ok := req.Context().Deadline(); ok && time.Now().Add(wait).After(deadline) {
+			return nil, &ThrottledError{Host: req.URL.Hostname(), Until: time.Now().Add(wait)}
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+
+	resp, err := t.Base.RoundTrip(req)
+	t.mu.Lock()
+	p := t.provider(req.URL.Hostname())
+	p.stats.Requests++
+	cacheTTL := p.cacheTTL
+	if err == nil {
+		t.recordStatus(p, resp)
+	}
+	t.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if key == "" || cacheTTL <= 0 || resp.StatusCode != http.StatusOK || strings.Contains(resp.Header.Get("Cache-Control"), "no-store") {
+		return resp, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCachedBody+1))
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if len(body) > maxCachedBody {
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return resp, nil
+	}
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	t.mu.Lock()
+	t.store(key, cacheEntry{status: resp.StatusCode, header: resp.Header.Clone(), body: body, expires: time.Now().Add(cacheTTL)})
+	t.mu.Unlock()
+	return resp, nil
+}
+
+// recordStatus backs p off after a 429 or 503, for as long as Retry-After
+// asks or else exponentially, and resets the backoff after any other
+// response. The caller holds t.mu.
+func (t *Transport) recordStatus(p *provider, resp *http.Response) {
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		p.stats.Throttled++
+		p.backoff = min(max(2*p.backoff, minBackoff), maxBackoff)
+		wait := p.backoff
+		if after, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			wait = min(after, maxBackoff)
+		}
+		p.backoffUntil = time.Now().Add(wait)
+	default:
+		p.backoff = 0
+	}
+}
+
+// store caches e, making room by dropping expired entries and then
+// arbitrary ones. The caller holds t.mu.
+func (t *Transport) store(key string, e cacheEntry) {
+	if len(t.cache) >= maxCacheEntries {
+		now := time.Now()
+		for k, old := range t.cache {
+			if !now.Before(old.expires) {
+				delete(t.cache, k)
+			}
+		}
+		for k := range t.cache {
+			if len(t.cache) < maxCacheEntries {
+				break
+			}
+			delete(t.cache, k)
+		}
+	}
+	t.cache[key] = e
+}
+
+func cachedResponse(req *http.Request, e cacheEntry) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.status, http.StatusText(e.status)),
+		StatusCode:    e.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+		Request:       req,
+	}
+}
+
+// retryAfter parses a Retry-After header given in seconds or as a date.
+func retryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if at, err := http.ParseTime(v); err == nil {
+		return max(at.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// Stats returns the traffic of every host called so far, by host.
+func (t *Transport) Stats() []Stats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stats := make([]Stats, 0, len(t.providers))
+	now := time.Now()
+	for _, p := range t.providers {
+		s := p.stats
+		if now.Before(p.backoffUntil) {
+			until := p.backoffUntil
+			s.BackoffUntil = &until
+		}
+		stats = append(stats, s)
+	}
+	slices.SortFunc(stats, func(a, b Stats) int { return strings.Compare(a.Host, b.Host) })
+	return stats
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+type rehostRequest struct {
+	OldHost string `json:"old_host"`
+	NewHost string `json:"new_host"`
+}
+
+// handleRehost moves every list, cached record and poster URL from one Kodi
+// host to another, e.g. after the Kodi box got a new IP address. Unlike
+// PATCH /lists/{id}/credentials the new host isn't contacted first, since
+// the box may be asleep while it's being renumbered. ?dry_run=true reports
+// what would move without moving it.
+func (s *Server) handleRehost(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req rehostRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Warn("Invalid request body for rehost", "error", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	oldHost := strings.TrimSuffix(strings.TrimSpace(req.OldHost), "/")
+	newHost := strings.TrimSuffix(strings.TrimSpace(req.NewHost), "/")
+	if oldHost == "" || newHost == "" {
+		http.Error(w, "old_host and new_host are required", http.StatusBadRequest)
+		return
+	}
+	if oldHost == newHost {
+		http.Error(w, "old_host and new_host are the same", http.StatusBadRequest)
+		return
+	}
+
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+	report, err := s.db.RehostKodi(oldHost, newHost, dryRun)
+	if err != nil {
+		writeDBError(w, err, "Failed to rehost Kodi", "old_host", oldHost, "new_host", newHost)
+		return
+	}
+	if dryRun {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(report)
+		return
+	}
+
+	s.kodiClients.invalidate(oldHost)
+	s.refreshKodiEvents()
+	slog.Info("Moved Kodi host", "old_host", oldHost, "new_host", newHost, "lists", report.Lists, "posters", report.Posters, "history", report.History)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// handleDedupeCache merges duplicate library cache rows, as the dedupe-cache
+// command does: POST /admin/dedupe-cache, with ?dry_run=true to only count
+// them.
+func (s *Server) handleDedupeCache(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	report, err := s.db.DedupeLibraryCache(r.URL.Query().Get("dry_run") == "true")
+	if err != nil {
+		slog.Error("Failed to dedupe library cache", "error", err)
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	slog.Info("Deduplicated library cache", "duplicates", report.Duplicates, "removed", report.Removed, "orphans", report.Orphans, "dry_run", report.DryRun)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// handleArchiveList archives or restores a list: POST /lists/{id}/archive
+// hides it from GET /lists and the dashboard, and POST /lists/{id}/restore
+// brings it back. Its items, history and settings are kept, and its feeds
+// and remote list aren't polled while it is archived.
+func (s *Server) handleArchiveList(w http.ResponseWriter, r *http.Request, listID int64, action string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	setArchived := s.db.ArchiveList
+	if action == "restore" {
+		setArchived = s.db.RestoreList
+	}
+	if err := setArchived(listID); err != nil {
+		writeDBError(w, err, "Failed to "+action+" list", "list_id", listID)
+		return
+	}
+	list, err := s.db.GetList(listID)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve list", "list_id", listID)
+		return
+	}
+	slog.Info("Changed list archive state", "list_id", listID, "action", action)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(listView{List: *list, HasCredentials: list.Username != "" || list.Password != ""})
+}
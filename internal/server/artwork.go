@@ -0,0 +1,283 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"maps"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"whats-next/internal/database"
+	"whats-next/internal/kodi"
+)
+
+// posterFailureCount reports how many posters the last sync of syncType on
+// listID's host couldn't fetch.
+func (s *Server) posterFailureCount(listID int64, syncType string) int {
+	failures, err := s.db.GetPosterFailures(listID, cacheTypeFor(syncType))
+	if err != nil {
+		slog.Error("Failed to count poster failures", "list_id", listID, "error", err)
+		return 0
+	}
+	return len(failures)
+}
+
+// handlePosterFailures reports artwork that failed to download on the list's
+// host: GET /sync/failures?list_id=N[&content_type=movie|tv].
+func (s *Server) handlePosterFailures(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	listID, mediaType, ok := posterFailureScope(w, r)
+	if !ok {
+		return
+	}
+	failures, err := s.db.GetPosterFailures(listID, mediaType)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve poster failures", "list_id", listID)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(failures)
+}
+
+// handleRetryFailedPosters downloads only the artwork the last sync failed
+// to fetch: POST /sync/retry-failed?list_id=N[&content_type=movie|tv].
+func (s *Server) handleRetryFailedPosters(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	listID, mediaType, ok := posterFailureScope(w, r)
+	if !ok {
+		return
+	}
+	failures, err := s.db.GetPosterFailures(listID, mediaType)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve poster failures", "list_id", listID)
+		return
+	}
+	client, err := s.getKodiClient(listID)
+	if err != nil {
+		writeDBError(w, err, "Kodi connection failed", "list_id", listID)
+		return
+	}
+
+	// Don't race a full sync rewriting the same cache rows
+	holder := s.leaseHolder()
+	for _, syncType := range []string{"movie", "tv", "album", "artist"} {
+		if mediaType != "" && cacheTypeFor(syncType) != mediaType {
+			continue
+		}
+		lease := "sync:" + client.HostURL + ":" + syncType
+		acquired, err := s.db.AcquireLease(lease, holder, syncLeaseTTL)
+		if err != nil {
+			slog.Error("Failed to acquire sync lease", "lease", lease, "error", err)
+			http.Error(w, "Failed to acquire sync lease", http.StatusInternalServerError)
+			return
+		}
+		if !acquired {
+			http.Error(w, errSyncInProgress.Error(), http.StatusConflict)
+			return
+		}
+		defer s.db.ReleaseLease(lease, holder)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, s.syncConcurrency(listID))
+	remaining := []database.PosterFailure{}
+	for _, f := range failures {
+		wg.Go(func() {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			saveType := "movie"
+			if f.MediaType == "show" || f.MediaType == "album" || f.MediaType == "artist" {
+				saveType = f.MediaType
+			}
+			item := kodi.MediaItem{ID: f.KodiID, Title: f.Title, Year: f.Year, Thumbnail: f.ImageURI}
+			poster, err := s.downloadBestImage(client, item, saveType)
+			if err == nil && poster == "" {
+				err = errors.New("artwork no longer exists on Kodi")
+			}
+			if err == nil {
+				err = s.db.ResolvePosterFailure(f, poster)
+			}
+			if err != nil {
+				if dbErr := s.db.RecordPosterRetryFailure(f, err.Error()); dbErr != nil {
+					slog.Error("Failed to record poster retry failure", "kodi_id", f.KodiID, "error", dbErr)
+				}
+				f.Attempts++
+				f.Error = err.Error()
+				mu.Lock()
+				remaining = append(remaining, f)
+				mu.Unlock()
+			}
+		})
+	}
+	wg.Wait()
+
+	slog.Info("Retried failed posters", "list_id", listID, "retried", len(failures), "still_failing", len(remaining))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"retried":   len(failures),
+		"succeeded": len(failures) - len(remaining),
+		"failed":    remaining,
+	})
+}
+
+// posterFailureScope parses list_id and the optional content_type; an empty
+// media type means both movies and shows.
+func posterFailureScope(w http.ResponseWriter, r *http.Request) (int64, string, bool) {
+	listID, err := strconv.ParseInt(r.URL.Query().Get("list_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid list_id", http.StatusBadRequest)
+		return 0, "", false
+	}
+	mediaType := ""
+	if ct := r.URL.Query().Get("content_type"); ct != "" {
+		mediaType = cacheTypeFor(ct)
+	}
+	return listID, mediaType, true
+}
+
+// preferredPoster downloads the cached art the item's list prefers for a
+// movie or show, or returns "" when the list has no preference or the item
+// isn't in the library cache. Seasons and episodes keep their own artwork.
+func (s *Server) preferredPoster(item database.Item) string {
+	if item.Wanted || (item.MediaType != "movie" && item.MediaType != "show") {
+		return ""
+	}
+	list, err := s.db.GetList(item.ListID)
+	if err != nil || len(list.ArtPreference) == 0 {
+		return ""
+	}
+	cached, err := s.db.GetCachedItem(item.ListID, item.KodiID, item.MediaType)
+	if err != nil {
+		return ""
+	}
+	client, err := s.getKodiClient(item.ListID)
+	if err != nil {
+		slog.Warn("Failed to get Kodi client for preferred art", "list_id", item.ListID, "error", err)
+		return ""
+	}
+	media := kodi.MediaItem{ID: cached.KodiID, Title: cached.Title, Year: cached.Year, Art: cached.Art}
+	poster, err := s.downloadArt(client, media, item.MediaType, list.ArtPreference)
+	if err != nil {
+		slog.Warn("Failed to download preferred art", "list_id", item.ListID, "kodi_id", item.KodiID, "error", err)
+		return ""
+	}
+	return poster
+}
+
+// applyArtPreferences switches the items of mediaType on lists sharing
+// listID's Kodi host to their list's preferred art after a sync refreshed
+// the cached art. Custom uploads are left alone.
+func (s *Server) applyArtPreferences(listID int64, mediaType string) {
+	current, err := s.db.GetList(listID)
+	if err != nil {
+		return
+	}
+	lists, err := s.db.GetAllLists()
+	if err != nil {
+		slog.Error("Failed to get lists for art preferences", "error", err)
+		return
+	}
+	for _, l := range lists {
+		if l.KodiHost != current.KodiHost || len(l.ArtPreference) == 0 {
+			continue
+		}
+		items, err := s.db.GetItems(l.ID)
+		if err != nil {
+			slog.Error("Failed to get items for art preferences", "list_id", l.ID, "error", err)
+			continue
+		}
+		updated := 0
+		for _, item := range items {
+			if item.MediaType != mediaType || item.OriginalPoster != "" {
+				continue
+			}
+			poster := s.preferredPoster(item)
+			if poster == "" || poster == item.Poster {
+				continue
+			}
+			if err := s.db.SetItemPoster(item.ID, poster, ""); err != nil {
+				slog.Error("Failed to update item artwork", "item_id", item.ID, "error", err)
+				continue
+			}
+			updated++
+		}
+		if updated > 0 {
+			slog.Info("Applied list art preference", "list_id", l.ID, "art", l.ArtPreference, "items", updated)
+		}
+	}
+}
+
+// refreshItemArt re-downloads the artwork of a cached movie or show when
+// Kodi's art for it no longer matches the cache, e.g. after a new poster
+// was chosen in Kodi. Stored files are replaced in place, so items already
+// showing them pick up the new artwork without a resync.
+func (s *Server) refreshItemArt(listID int64, mediaType string, kodiID int) {
+	cached, err := s.db.GetCachedItem(listID, kodiID, mediaType)
+	if err != nil {
+		// Not synced yet; the next sync downloads its artwork
+		return
+	}
+	client, err := s.getKodiClient(listID)
+	if err != nil {
+		slog.Error("Failed to get Kodi client for artwork refresh", "list_id", listID, "error", err)
+		return
+	}
+	var item kodi.MediaItem
+	switch mediaType {
+	case "movie":
+		d, err := client.GetMovieDetails(kodiID)
+		if err != nil {
+			slog.Warn("Failed to fetch movie artwork", "kodi_id", kodiID, "error", err)
+			return
+		}
+		item = kodi.MediaItem{ID: d.ID, Title: d.Title, Year: d.Year, Thumbnail: d.Thumbnail, Art: d.Art}
+	case "show":
+		d, err := client.GetTVShowDetails(kodiID)
+		if err != nil {
+			slog.Warn("Failed to fetch show artwork", "kodi_id", kodiID, "error", err)
+			return
+		}
+		item = kodi.MediaItem{ID: d.ID, Title: d.Title, Year: d.Year, Thumbnail: d.Thumbnail, Art: d.Art}
+	default:
+		return
+	}
+	if maps.Equal(item.Art, cached.Art) {
+		return
+	}
+
+	// Only files whose Kodi art changed are downloaded again
+	old := kodi.MediaItem{Art: cached.Art}
+	newPoster, _ := bestImageURI(item, nil)
+	oldPoster, _ := bestImageURI(old, nil)
+	poster, err := s.storeArt(client, item, mediaType, nil, newPoster != oldPoster)
+	if err != nil {
+		slog.Warn("Failed to refresh artwork", "media_type", mediaType, "kodi_id", kodiID, "error", err)
+		return
+	}
+	var changed []string
+	for _, t := range extraArtTypes {
+		if item.Art[t] != cached.Art[t] {
+			changed = append(changed, t)
+		}
+	}
+	stored := s.storeExtraArt(client, item, mediaType, changed)
+	if err := s.db.UpdateCachedArt(listID, mediaType, kodiID, poster, item.Art); err != nil {
+		slog.Error("Failed to store refreshed artwork", "media_type", mediaType, "kodi_id", kodiID, "error", err)
+		return
+	}
+	if err := s.db.ReplaceItemArt(listID, mediaType, []int{kodiID}, stored); err != nil {
+		slog.Error("Failed to store refreshed artwork", "media_type", mediaType, "kodi_id", kodiID, "error", err)
+		return
+	}
+	slog.Info("Refreshed artwork after Kodi update", "media_type", mediaType, "kodi_id", kodiID, "title", item.Title)
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"whats-next/internal/backend"
+	"whats-next/internal/database"
+)
+
+// errKodiOnly is returned for features only Kodi hosts support, such as
+// library syncs and player control, when a list is on another backend.
+var errKodiOnly = errors.New("only supported for lists on a Kodi backend")
+
+// jellyfinTimeout bounds Jellyfin requests when the list sets no
+// rpc_timeout, like the Kodi client's default.
+const jellyfinTimeout = 10 * time.Second
+
+// jellyfinBackends caches one backend per Jellyfin connection, so the user
+// ID each looks up is kept between requests.
+type jellyfinBackends struct {
+	mu       sync.Mutex
+	backends map[kodiConn]*backend.Jellyfin
+}
+
+// getBackend returns the media backend of a list: its shared Kodi client,
+// or its Jellyfin server.
+func (s *Server) getBackend(listID int64) (backend.MediaBackend, error) {
+	list, err := s.db.GetList(listID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get list %d: %w", listID, err)
+	}
+	if list.Backend != database.BackendJellyfin {
+		return s.kodiClient(list.KodiHost, list.Username, list.Password, listKodiOptions(list))
+	}
+
+	opts := listKodiOptions(list)
+	key := kodiConn{host: list.KodiHost, user: list.Username, pass: list.Password, opts: opts}
+	m := &s.jellyfin
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if b, ok := m.backends[key]; ok {
+		return b, nil
+	}
+	transport, err := opts.TLS.Transport()
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS options for %s: %w", list.KodiHost, err)
+	}
+	hc := &http.Client{Transport: transport, Timeout: jellyfinTimeout}
+	if opts.RPCTimeout > 0 {
+		hc.Timeout = opts.RPCTimeout
+	}
+	b := backend.NewJellyfin(list.KodiHost, list.Username, list.Password, hc, s.db)
+	if m.backends == nil {
+		m.backends = map[kodiConn]*backend.Jellyfin{}
+	}
+	m.backends[key] = b
+	return b, nil
+}
@@ -0,0 +1,254 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"whats-next/internal/database"
+	"whats-next/internal/kodi"
+)
+
+// unknownBrowseKey groups items missing the browsed field (no year, rating
+// or genre).
+const unknownBrowseKey = "unknown"
+
+type browseGroup struct {
+	Key   string `json:"key"`
+	Label string `json:"label"`
+	Count int    `json:"count"`
+}
+
+// browseKeys returns the groups an item belongs to when browsing by field.
+// Only genre can place an item in more than one group.
+func browseKeys(item database.CachedItem, by string) []string {
+	switch by {
+	case "decade":
+		if item.Year > 0 {
+			return []string{strconv.Itoa(item.Year / 10 * 10)}
+		}
+	case "year":
+		if item.Year > 0 {
+			return []string{strconv.Itoa(item.Year)}
+		}
+	case "rating":
+		if item.Rating > 0 {
+			return []string{strconv.Itoa(int(math.Floor(item.Rating)))}
+		}
+	case "genre":
+		if len(item.Genres) > 0 {
+			return item.Genres
+		}
+	}
+	return []string{unknownBrowseKey}
+}
+
+func browseLabel(by, key string) string {
+	if key == unknownBrowseKey {
+		return "Unknown"
+	}
+	switch by {
+	case "decade":
+		return key + "s"
+	case "rating":
+		if key == "10" {
+			return "10"
+		}
+		n, _ := strconv.Atoi(key)
+		return key + "–" + strconv.Itoa(n+1)
+	}
+	return key
+}
+
+// handleBrowse groups the cached library for discovery without a search
+// query: GET /library/browse?list_id=N&by=decade|year|genre|rating returns
+// group counts, and adding &key=K lists the items in one group. by=title
+// pages through the whole library in title order with &offset= and &limit=,
+// the order GET /library/index counts its offsets in.
+func (s *Server) handleBrowse(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	listID, err := strconv.ParseInt(q.Get("list_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid list_id", http.StatusBadRequest)
+		return
+	}
+	by := q.Get("by")
+	switch by {
+	case "decade", "year", "genre", "rating", "title":
+	default:
+		http.Error(w, "by must be decade, year, genre, rating or title", http.StatusBadRequest)
+		return
+	}
+	offset, limit, err := pageParams(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	cacheType := "movie"
+	if q.Get("content_type") == "tv" {
+		cacheType = "show"
+	}
+
+	if _, err := s.db.GetList(listID); err != nil {
+		writeDBError(w, err, "Failed to retrieve list", "list_id", listID)
+		return
+	}
+	cached, err := s.db.GetLibraryCache(listID, cacheType)
+	if err != nil {
+		slog.Error("Failed to read library cache", "list_id", listID, "error", err)
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if by == "title" {
+		sortByTitle(cached, func(c database.CachedItem) string { return c.Title })
+		page := pageOf(cached, offset, limit)
+		items := make([]kodi.MediaItem, len(page))
+		for i, c := range page {
+			items[i] = cachedMediaItem(c)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"by": by, "total": len(cached), "offset": offset,
+			"items": s.annotateMembership(listID, cacheType, items),
+		})
+		return
+	}
+	if q.Has("key") {
+		key := q.Get("key")
+		items := []kodi.MediaItem{}
+		for _, c := range cached {
+			for _, k := range browseKeys(c, by) {
+				if strings.EqualFold(k, key) {
+					items = append(items, cachedMediaItem(c))
+					break
+				}
+			}
+		}
+		if by == "rating" {
+			sort.SliceStable(items, func(i, j int) bool { return items[i].Rating > items[j].Rating })
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"by": by, "key": key, "label": browseLabel(by, key),
+			"items": s.annotateMembership(listID, cacheType, items),
+		})
+		return
+	}
+
+	counts := map[string]int{}
+	for _, c := range cached {
+		for _, k := range browseKeys(c, by) {
+			counts[k]++
+		}
+	}
+	groups := make([]browseGroup, 0, len(counts))
+	for k, n := range counts {
+		groups = append(groups, browseGroup{Key: k, Label: browseLabel(by, k), Count: n})
+	}
+	// Newest and best-rated first, genres by size; unknown always last
+	sort.Slice(groups, func(i, j int) bool {
+		a, b := groups[i], groups[j]
+		if (a.Key == unknownBrowseKey) != (b.Key == unknownBrowseKey) {
+			return b.Key == unknownBrowseKey
+		}
+		if by == "genre" {
+			if a.Count != b.Count {
+				return a.Count > b.Count
+			}
+			return a.Key < b.Key
+		}
+		an, _ := strconv.Atoi(a.Key)
+		bn, _ := strconv.Atoi(b.Key)
+		return an > bn
+	})
+	json.NewEncoder(w).Encode(map[string]interface{}{"by": by, "groups": groups})
+}
+
+type genreCount struct {
+	ID    int    `json:"genre_id,omitempty"`
+	Title string `json:"title"`
+	Count int    `json:"count"`
+}
+
+// handleGenres lists the genres of a list's library:
+// GET /library/genres?list_id=N&content_type=movie|tv. Genres come from Kodi,
+// with the number of cached titles in each; when Kodi can't be reached the
+// genres seen in the library cache are listed instead, without IDs. Use a
+// title as the genre filter on /search.
+func (s *Server) handleGenres(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	listID, err := strconv.ParseInt(q.Get("list_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid list_id", http.StatusBadRequest)
+		return
+	}
+	cacheType, kodiType := "movie", "movie"
+	if q.Get("content_type") == "tv" {
+		cacheType, kodiType = "show", "tvshow"
+	}
+
+	client, err := s.getKodiClient(listID)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve list", "list_id", listID)
+		return
+	}
+	cached, err := s.db.GetLibraryCache(listID, cacheType)
+	if err != nil {
+		slog.Error("Failed to read library cache", "list_id", listID, "error", err)
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	counts := map[string]int{}
+	for _, c := range cached {
+		for _, g := range c.Genres {
+			counts[strings.ToLower(g)]++
+		}
+	}
+
+	genres := []genreCount{}
+	if fromKodi, err := s.libraryGenres(client, listID, kodiType); err == nil {
+		for _, g := range fromKodi {
+			genres = append(genres, genreCount{ID: g.ID, Title: g.Title, Count: counts[strings.ToLower(g.Title)]})
+		}
+	} else {
+		slog.Warn("Failed to get genres from Kodi, using library cache", "list_id", listID, "error", err)
+		seen := map[string]bool{}
+		for _, c := range cached {
+			for _, g := range c.Genres {
+				if key := strings.ToLower(g); !seen[key] {
+					seen[key] = true
+					genres = append(genres, genreCount{Title: g, Count: counts[key]})
+				}
+			}
+		}
+		sort.Slice(genres, func(i, j int) bool { return genres[i].Title < genres[j].Title })
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(genres)
+}
+
+// libraryGenres returns the genres Kodi has for kodiType as stored by the
+// last sync of the list's host, asking Kodi only when none are stored.
+func (s *Server) libraryGenres(client *kodi.Client, listID int64, kodiType string) ([]kodi.Genre, error) {
+	if data, err := s.db.GetGenreCache(listID, kodiType); err != nil {
+		slog.Warn("Failed to read cached genres", "list_id", listID, "error", err)
+	} else if data != nil {
+		var genres []kodi.Genre
+		if err := json.Unmarshal(data, &genres); err == nil {
+			return genres, nil
+		}
+	}
+	return client.GetGenres(kodiType)
+}
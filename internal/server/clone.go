@@ -0,0 +1,208 @@
+package server
+
+import (
+	"cmp"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"whats-next/internal/database"
+)
+
+// listClone reports what cloning a list copied: Added items, of which Wanted
+// became wanted items for lack of a match on the target's host, and the
+// items Skipped with the reason why.
+type listClone struct {
+	List    listView                 `json:"list"`
+	Created bool                     `json:"created"`
+	Added   int                      `json:"added"`
+	Wanted  int                      `json:"wanted"`
+	Skipped []database.UnmatchedItem `json:"skipped"`
+}
+
+// handleCloneList copies a list's items to another list:
+// POST /lists/{id}/clone. The body names an existing list of the same
+// content type, {"list_id": 7}, or a new one, {"list_name": "Halloween
+// 2025"} with an optional group_name (the list's own by default) and
+// kodi_host, username, password and backend. A new list in the same group
+// shares the list's connection unless it sets its own; in another group it
+// inherits the group's. Copies go after the target's items, in list order
+// and sections; archived items stay behind. On another host, items are
+// matched against the target's library cache like after a host change:
+// movies and shows with no match are copied as wanted items, and other
+// unmatched items, files among them, are skipped.
+func (s *Server) handleCloneList(w http.ResponseWriter, r *http.Request, listID int64) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		ListID    int64  `json:"list_id"`
+		GroupName string `json:"group_name"`
+		Name      string `json:"list_name"`
+		KodiHost  string `json:"kodi_host"`
+		Username  string `json:"username"`
+		Password  string `json:"password"`
+		Backend   string `json:"backend"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if (req.ListID == 0) == (req.Name == "") {
+		http.Error(w, "Set either list_id or list_name", http.StatusBadRequest)
+		return
+	}
+	source, err := s.db.GetList(listID)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve list", "list_id", listID)
+		return
+	}
+	items, err := s.db.GetItems(listID)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve items", "list_id", listID)
+		return
+	}
+
+	var target *database.List
+	if req.ListID != 0 {
+		if req.ListID == listID {
+			http.Error(w, "A list can't be cloned into itself", http.StatusBadRequest)
+			return
+		}
+		if target, err = s.db.GetList(req.ListID); err != nil {
+			writeDBError(w, err, "Failed to retrieve target list", "list_id", req.ListID)
+			return
+		}
+		if target.ContentType != source.ContentType {
+			http.Error(w, "The target list holds another content type", http.StatusBadRequest)
+			return
+		}
+		if s.refuseMirrored(w, target.ID) {
+			return
+		}
+	} else {
+		l := database.List{
+			GroupName:     cmp.Or(req.GroupName, source.GroupName),
+			Name:          req.Name,
+			ContentType:   source.ContentType,
+			OnWatched:     source.OnWatched,
+			ArtPreference: source.ArtPreference,
+			Fuzzy:         source.Fuzzy,
+			KodiHost:      req.KodiHost,
+			Username:      req.Username,
+			Password:      req.Password,
+			Backend:       req.Backend,
+		}
+		if req.KodiHost == "" && l.GroupName == source.GroupName && !source.InheritsHost {
+			l.KodiHost, l.Username, l.Password, l.Backend = source.KodiHost, source.Username, source.Password, source.Backend
+			l.TLSSkipVerify, l.CACert = source.TLSSkipVerify, source.CACert
+			l.RPCTimeout, l.ImageTimeout, l.SyncConcurrency = source.RPCTimeout, source.ImageTimeout, source.SyncConcurrency
+		}
+		if target, err = s.db.CreateList(l); err != nil {
+			writeDBError(w, err, "Failed to create list")
+			return
+		}
+	}
+
+	result, err := s.cloneItems(source, target, items)
+	if err != nil {
+		if req.ListID == 0 {
+			// Don't leave a half-made list behind
+			if _, err := s.db.DeleteList(target.ID, false); err != nil {
+				slog.Warn("Failed to remove list after failed clone", "list_id", target.ID, "error", err)
+			}
+		}
+		writeDBError(w, err, "Failed to clone list", "list_id", listID)
+		return
+	}
+	// Re-read for the revision the copies gave it
+	cloned, err := s.db.GetList(target.ID)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve list", "list_id", target.ID)
+		return
+	}
+	result.List = listView{List: *cloned, HasCredentials: cloned.Username != "" || cloned.Password != ""}
+	result.Created = req.ListID == 0
+	if result.Created {
+		s.refreshKodiEvents()
+	}
+	slog.Info("Cloned list", "list_id", listID, "target_id", target.ID, "added", result.Added, "wanted", result.Wanted, "skipped", len(result.Skipped))
+
+	w.Header().Set("Content-Type", "application/json")
+	if result.Created {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+// cloneItems copies items of source onto target, matching them against
+// target's library when it is on another host.
+func (s *Server) cloneItems(source, target *database.List, items []database.Item) (*listClone, error) {
+	result := &listClone{Skipped: []database.UnmatchedItem{}}
+	sameHost := source.KodiHost == target.KodiHost && source.Backend == target.Backend
+	var matcher *hostMatcher
+	if !sameHost {
+		matcher = s.newHostMatcher(target)
+	}
+
+	copies := make([]database.Item, 0, len(items))
+	unlinked := map[int]bool{} // copies that became wanted items
+	for _, item := range items {
+		if sameHost || item.Wanted {
+			copies = append(copies, item)
+			continue
+		}
+		skip := func(reason string) {
+			result.Skipped = append(result.Skipped, database.UnmatchedItem{
+				ItemID: item.ID, MediaType: item.MediaType, Title: itemTitle(item), Year: item.Year, Reason: reason,
+			})
+		}
+		match, ok, err := matcher.match(item)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			skip("Only library titles carry over to another host")
+			continue
+		}
+		// Playback state belongs to the old host; the next sync fills it in
+		item.Watched, item.Playcount, item.LastPlayed, item.ResumePosition, item.WatchedEpisodes = false, 0, "", 0, 0
+		switch {
+		case match.kodiID != 0:
+			item.KodiID, item.TVShowID = match.kodiID, match.tvshowID
+			if match.poster != "" && item.OriginalPoster != "" {
+				item.OriginalPoster = match.poster
+			} else if match.poster != "" {
+				item.Poster = match.poster
+			}
+		case item.MediaType == "movie" || item.MediaType == "show":
+			item.KodiID, item.TVShowID, item.Wanted = 0, 0, true
+			unlinked[len(copies)] = true
+		default:
+			skip(match.reason)
+			continue
+		}
+		copies = append(copies, item)
+	}
+
+	ids, _, err := s.db.CopyItems(source.ID, target.ID, copies)
+	if err != nil {
+		return nil, err
+	}
+	for n, id := range ids {
+		if id == 0 {
+			result.Skipped = append(result.Skipped, database.UnmatchedItem{
+				ItemID: copies[n].ID, MediaType: copies[n].MediaType, Title: itemTitle(copies[n]), Year: copies[n].Year, Reason: "Already on the list",
+			})
+			continue
+		}
+		result.Added++
+		if unlinked[n] {
+			result.Wanted++
+		}
+		copies[n].ListID = target.ID
+		s.prewarmShow(copies[n])
+	}
+	return result, nil
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+
+	"whats-next/internal/database"
+	"whats-next/internal/kodi"
+)
+
+// connectionTest is the outcome of testing a list's Kodi connection.
+type connectionTest struct {
+	Reachable bool   `json:"reachable"`
+	AuthOK    bool   `json:"auth_ok"`
+	LatencyMS int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+
+	Name         string          `json:"name,omitempty"`
+	Version      string          `json:"version,omitempty"`
+	APIVersion   string          `json:"api_version,omitempty"`
+	Capabilities map[string]bool `json:"capabilities,omitempty"`
+	// Supported is false for hosts older than the app's queries need
+	Supported bool     `json:"supported"`
+	Warnings  []string `json:"warnings"`
+}
+
+// handleTestConnection checks a list's Kodi host: POST /lists/{id}/test.
+// It pings the host, then reads its Kodi and JSON-RPC API versions. Failures
+// are reported in the result rather than as an error status, so the UI can
+// warn about an unreachable or outdated host before a sync fails.
+func (s *Server) handleTestConnection(w http.ResponseWriter, r *http.Request, listID int64) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	list, err := s.db.GetList(listID)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve list", "list_id", listID)
+		return
+	}
+
+	result := s.testConnection(list)
+	slog.Info("Tested Kodi connection", "list_id", listID, "host", list.KodiHost, "reachable", result.Reachable, "auth_ok", result.AuthOK, "version", result.Version)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// testConnection pings list's Kodi host and describes it. The list need not
+// be stored yet, so the setup wizard can check a host before creating one.
+func (s *Server) testConnection(list *database.List) connectionTest {
+	result := connectionTest{Warnings: []string{}}
+	client, err := s.newKodiClient(list.KodiHost, list.Username, list.Password, listKodiOptions(list))
+	if err == nil {
+		// A test should answer promptly, not wait out retries
+		client.Retry.Attempts = 1
+		start := time.Now()
+		err = client.Ping()
+		result.LatencyMS = time.Since(start).Milliseconds()
+	}
+	var authErr *kodi.AuthError
+	switch {
+	case errors.As(err, &authErr):
+		result.Reachable = true
+		result.Error = err.Error()
+		result.Warnings = append(result.Warnings, "Kodi rejected the list's username or password")
+	case err != nil:
+		result.Error = err.Error()
+		result.Warnings = append(result.Warnings, "Kodi is unreachable; check kodi_host and that Kodi allows remote control via HTTP")
+	default:
+		result.Reachable, result.AuthOK = true, true
+		describeHost(client, &result)
+	}
+	return result
+}
+
+// describeHost fills in the host's versions and capabilities.
+func describeHost(client *kodi.Client, result *connectionTest) {
+	info, err := client.GetSystemInfo()
+	if err != nil {
+		result.Error = err.Error()
+		result.Warnings = append(result.Warnings, "Couldn't determine the Kodi version")
+		return
+	}
+	result.Name = info.Name
+	result.Version = info.Version.String()
+	result.APIVersion = info.API.String()
+	result.Capabilities = info.Capabilities()
+	result.Supported = info.API.Major >= kodi.MinAPIMajor
+	if !result.Supported {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("Kodi %s is too old; Kodi 17 or later is required", result.Version))
+	}
+	for _, capability := range slices.Sorted(maps.Keys(result.Capabilities)) {
+		if !result.Capabilities[capability] && result.Supported {
+			result.Warnings = append(result.Warnings, "Kodi "+result.Version+" doesn't support "+capability)
+		}
+	}
+}
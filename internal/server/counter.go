@@ -0,0 +1,50 @@
+package server
+
+import "sync"
+
+// counter accumulates counts in memory between flushes, so counting a
+// request never waits on SQLite.
+type counter[K comparable] struct {
+	mu     sync.Mutex
+	counts map[K]int64
+}
+
+func (c *counter[K]) add(key K, n int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.counts == nil {
+		c.counts = map[K]int64{}
+	}
+	c.counts[key] += n
+}
+
+// take returns the counts gathered since the last call and resets them.
+func (c *counter[K]) take() map[K]int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	counts := c.counts
+	c.counts = nil
+	return counts
+}
+
+// putBack returns counts taken for a flush that failed, so the next flush
+// retries them.
+func (c *counter[K]) putBack(counts map[K]int64) {
+	for key, n := range counts {
+		c.add(key, n)
+	}
+}
+
+// flush hands the counts gathered since the last flush to store, putting
+// them back if it fails.
+func (c *counter[K]) flush(store func(counts map[K]int64) error) error {
+	counts := c.take()
+	if len(counts) == 0 {
+		return nil
+	}
+	if err := store(counts); err != nil {
+		c.putBack(counts)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"whats-next/internal/kodi"
+)
+
+type credentialsRequest struct {
+	KodiHost *string `json:"kodi_host"`
+	Username *string `json:"username"`
+	Password *string `json:"password"`
+}
+
+// handleUpdateCredentials rotates a list's Kodi host and credentials. The new
+// connection is verified with JSONRPC.Ping before it is saved, unless
+// ?force=true is given (e.g. while the Kodi box is asleep).
+func (s *Server) handleUpdateCredentials(w http.ResponseWriter, r *http.Request, listID int64) {
+	if r.Method != http.MethodPatch {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req credentialsRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		slog.Warn("Invalid request body for credentials", "error", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	list, err := s.db.GetList(listID)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve list", "list_id", listID)
+		return
+	}
+
+	// Omitted fields keep their current values
+	host, user, pass := list.KodiHost, list.Username, list.Password
+	if req.KodiHost != nil {
+		host = strings.TrimSpace(*req.KodiHost)
+	}
+	if req.Username != nil {
+		user = *req.Username
+	}
+	if req.Password != nil {
+		pass = *req.Password
+	}
+	if host == "" {
+		http.Error(w, "kodi_host must not be empty", http.StatusBadRequest)
+		return
+	}
+
+	verified := false
+	if r.URL.Query().Get("force") != "true" {
+		client, err := s.newKodiClient(host, user, pass, listKodiOptions(list))
+		if err == nil {
+			err = client.Ping()
+		}
+		if err != nil {
+			slog.Warn("Kodi did not respond with new credentials", "list_id", listID, "host", host, "error", err)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadGateway)
+			var authErr *kodi.AuthError
+			json.NewEncoder(w).Encode(map[string]interface{}{"verified": false, "auth_failed": errors.As(err, &authErr), "error": err.Error()})
+			return
+		}
+		verified = true
+	}
+
+	if err := s.db.UpdateListCredentials(listID, host, user, pass); err != nil {
+		writeDBError(w, err, "Failed to update credentials", "list_id", listID)
+		return
+	}
+
+	// Cached clients are keyed by host and credentials, so the new ones get
+	// fresh clients; dropping the old host's clients and health, and any
+	// the new host had, keeps neither describing the old settings.
+	s.kodiClients.invalidate(list.KodiHost)
+	s.kodiClients.invalidate(host)
+	s.refreshKodiEvents()
+	slog.Info("Updated Kodi credentials", "list_id", listID, "host", host, "verified", verified)
+	resp := map[string]interface{}{"verified": verified, "kodi_host": host, "username": user}
+	if host != list.KodiHost {
+		// The list's items still carry the old host's Kodi IDs; re-resolve
+		// them now rather than at the next scheduled run
+		resp["migration_task"] = s.startHostMigration(list).ID
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
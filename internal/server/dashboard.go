@@ -0,0 +1,56 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// handleDashboard returns every list in the order the dashboard shows them
+// to a user: GET /dashboard?user=NAME puts their pinned lists first, then
+// their favourites. Without a user the shared household pins apply.
+func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user := strings.TrimSpace(r.URL.Query().Get("user"))
+	lists, err := s.db.Dashboard(user)
+	if err != nil {
+		writeDBError(w, err, "Failed to build dashboard", "user", user)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(lists)
+}
+
+// handleListPin pins a list or marks it a favourite for a user:
+// PUT /lists/{id}/pin?user=NAME with {"pinned": true, "favorite": false},
+// where either field may be left out to keep it. DELETE clears both.
+func (s *Server) handleListPin(w http.ResponseWriter, r *http.Request, listID int64) {
+	user := strings.TrimSpace(r.URL.Query().Get("user"))
+	var req struct {
+		Pinned   *bool `json:"pinned"`
+		Favorite *bool `json:"favorite"`
+	}
+	switch r.Method {
+	case http.MethodPut:
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			slog.Warn("Invalid request body for list pin", "error", err)
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	case http.MethodDelete:
+		off := false
+		req.Pinned, req.Favorite = &off, &off
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := s.db.SetListPin(user, listID, req.Pinned, req.Favorite); err != nil {
+		writeDBError(w, err, "Failed to pin list", "list_id", listID, "user", user)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"whats-next/internal/database"
+)
+
+const demoSeededKey = "demo_seeded"
+
+// demoHost is a placeholder; in demo mode every list talks to the mock Kodi.
+const demoHost = "http://demo-kodi:8080"
+
+// demoSeed lists the titles each demo list starts with, in order.
+var demoSeed = []struct {
+	group, list, contentType string
+	titles                   []string
+}{
+	{"Lounge", "Movie Night", "movie", []string{"Interstellar", "Parasite", "Amélie", "The Matrix"}},
+	{"Lounge", "Shows", "tv", []string{"Breaking Bad", "Planet Earth", "The Office"}},
+	{"Kids Room", "Movies", "movie", []string{"Paddington 2", "Toy Story", "Up", "Spirited Away"}},
+	{"Kids Room", "Cartoons", "tv", []string{"Bluey"}},
+}
+
+// DemoConfig returns the groups and lists used when DEMO_MODE runs without
+// a config of its own.
+func DemoConfig() ([]database.Group, []database.List) {
+	var groups []database.Group
+	var lists []database.List
+	seen := map[string]bool{}
+	for _, d := range demoSeed {
+		if !seen[d.group] {
+			seen[d.group] = true
+			groups = append(groups, database.Group{Name: d.group, KodiHost: demoHost})
+		}
+		lists = append(lists, database.List{GroupName: d.group, Name: d.list, ContentType: d.contentType})
+	}
+	return groups, lists
+}
+
+// SeedDemo syncs the mock library and fills the demo lists with sample
+// items. It runs once per database; later boots leave the user's edits alone.
+func (s *Server) SeedDemo() error {
+	seeded, err := s.db.GetState(demoSeededKey)
+	if err != nil {
+		return err
+	}
+	if seeded != "" {
+		return nil
+	}
+
+	lists, err := s.db.GetAllLists()
+	if err != nil {
+		return err
+	}
+	synced := map[string]bool{}
+	added := 0
+	for _, d := range demoSeed {
+		var list *database.List
+		for i := range lists {
+			if lists[i].GroupName == d.group && lists[i].Name == d.list {
+				list = &lists[i]
+				break
+			}
+		}
+		if list == nil {
+			continue // config replaced the demo lists
+		}
+
+		key := list.KodiHost + "|" + d.contentType
+		if !synced[key] {
+			if _, err := s.syncLibrary(list.ID, d.contentType, true); err != nil {
+				return fmt.Errorf("failed to sync demo library: %w", err)
+			}
+			synced[key] = true
+		}
+
+		for _, title := range d.titles {
+			cached, err := s.db.FindCachedMatch(list.ID, cacheTypeFor(d.contentType), title, 0)
+			if err != nil {
+				slog.Warn("Demo title missing from mock library", "title", title, "error", err)
+				continue
+			}
+			if _, err := s.db.AddItem(itemFromCache(list.ID, cached)); err != nil && !errors.Is(err, database.ErrDuplicateItem) {
+				return fmt.Errorf("failed to add demo item %q: %w", title, err)
+			}
+			added++
+		}
+	}
+
+	slog.Info("Seeded demo data", "items", added)
+	return s.db.SetState(demoSeededKey, "1")
+}
@@ -0,0 +1,173 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"whats-next/internal/kodi"
+)
+
+// maxDetailCast caps how many cast photos a details request stores.
+const maxDetailCast = 15
+
+// movieDetails is kodi.MovieDetails with artwork the browser can load: the
+// poster and cast thumbnails point at stored copies rather than Kodi URIs.
+type movieDetails struct {
+	*kodi.MovieDetails
+	Poster string `json:"poster_path,omitempty"`
+}
+
+// handleMovieDetails returns the cast, crew, tagline, rating and stream
+// details of one movie: GET /details?list_id=&movie_id=.
+func (s *Server) handleMovieDetails(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	listID, err := strconv.ParseInt(r.URL.Query().Get("list_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid list_id parameter", http.StatusBadRequest)
+		return
+	}
+	movieID, err := strconv.Atoi(r.URL.Query().Get("movie_id"))
+	if err != nil {
+		http.Error(w, "Invalid movie_id parameter", http.StatusBadRequest)
+		return
+	}
+	client, err := s.getKodiClient(listID)
+	if err != nil {
+		writeDBError(w, err, "Failed to connect to Kodi", "list_id", listID)
+		return
+	}
+
+	details, err := client.GetMovieDetails(movieID)
+	if errors.Is(err, kodi.ErrNotFound) {
+		http.Error(w, "Movie not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to get movie details from Kodi", "list_id", listID, "movie_id", movieID, "error", err)
+		writeKodiError(w, err, "Failed to fetch movie details", http.StatusBadGateway)
+		return
+	}
+
+	resp := movieDetails{MovieDetails: details}
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		resp.Poster = s.detailImage(client, kodi.MediaItem{ID: details.ID, Title: details.Title, Year: details.Year, Thumbnail: details.Thumbnail, Art: details.Art}, "movie")
+	})
+	s.storeCastPhotos(&wg, client, details.Cast)
+	wg.Wait()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+type tvShowDetails struct {
+	*kodi.TVShowDetails
+	Poster string `json:"poster_path,omitempty"`
+}
+
+type episodeDetails struct {
+	*kodi.EpisodeDetails
+	Thumbnail string `json:"thumbnail_path,omitempty"`
+}
+
+// handleTVDetails returns the plot, premiere or air date, cast, studio and
+// artwork of a show (GET /tv/details?list_id=&tvshowid=) or of one episode
+// (GET /tv/details?list_id=&episodeid=).
+func (s *Server) handleTVDetails(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	listID, err := strconv.ParseInt(q.Get("list_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid list_id parameter", http.StatusBadRequest)
+		return
+	}
+	param := "tvshowid"
+	if q.Has("episodeid") {
+		param = "episodeid"
+	}
+	id, err := strconv.Atoi(q.Get(param))
+	if err != nil || q.Has("tvshowid") && q.Has("episodeid") {
+		http.Error(w, "Provide exactly one of tvshowid or episodeid", http.StatusBadRequest)
+		return
+	}
+	client, err := s.getKodiClient(listID)
+	if err != nil {
+		writeDBError(w, err, "Failed to connect to Kodi", "list_id", listID)
+		return
+	}
+
+	var resp interface{}
+	var wg sync.WaitGroup
+	if param == "tvshowid" {
+		var show *kodi.TVShowDetails
+		if show, err = client.GetTVShowDetails(id); err == nil {
+			d := &tvShowDetails{TVShowDetails: show}
+			wg.Go(func() {
+				d.Poster = s.detailImage(client, kodi.MediaItem{ID: show.ID, Title: show.Title, Year: show.Year, Thumbnail: show.Thumbnail, Art: show.Art}, "show")
+			})
+			s.storeCastPhotos(&wg, client, show.Cast)
+			resp = d
+		}
+	} else {
+		var ep *kodi.EpisodeDetails
+		if ep, err = client.GetEpisodeDetails(id); err == nil {
+			d := &episodeDetails{EpisodeDetails: ep}
+			wg.Go(func() {
+				title := fmt.Sprintf("%s S%02dE%02d", ep.ShowTitle, ep.Season, ep.Episode)
+				d.Thumbnail = s.detailImage(client, kodi.MediaItem{ID: ep.ID, Title: title, Thumbnail: ep.Thumbnail, Art: ep.Art}, "episode")
+			})
+			s.storeCastPhotos(&wg, client, ep.Cast)
+			resp = d
+		}
+	}
+	if errors.Is(err, kodi.ErrNotFound) {
+		http.Error(w, "Not found in the Kodi library", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to get TV details from Kodi", "list_id", listID, param, id, "error", err)
+		writeKodiError(w, err, "Failed to fetch TV details", http.StatusBadGateway)
+		return
+	}
+	wg.Wait()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// detailImage stores the artwork of a detail view, returning its public URL
+// or "" if it couldn't be fetched.
+func (s *Server) detailImage(client *kodi.Client, item kodi.MediaItem, mediaType string) string {
+	path, err := s.downloadBestImage(client, item, mediaType)
+	if err != nil {
+		slog.Warn("Failed to fetch artwork", "media_type", mediaType, "kodi_id", item.ID, "error", err)
+	}
+	return path
+}
+
+// storeCastPhotos replaces the Kodi thumbnails of the first maxDetailCast
+// cast members with stored copies, in goroutines tracked by wg. The rest are
+// cleared, since browsers can't load image:// URIs.
+func (s *Server) storeCastPhotos(wg *sync.WaitGroup, client *kodi.Client, cast []kodi.CastMember) {
+	for i := range cast {
+		c := &cast[i]
+		if i >= maxDetailCast || c.Thumbnail == "" {
+			c.Thumbnail = ""
+			continue
+		}
+		wg.Go(func() {
+			c.Thumbnail = s.detailImage(client, kodi.MediaItem{Title: c.Name, Thumbnail: c.Thumbnail}, "cast")
+		})
+	}
+}
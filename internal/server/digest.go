@@ -0,0 +1,344 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"whats-next/internal/database"
+	"whats-next/internal/media"
+)
+
+const (
+	// digestSentKey is the app_state key holding when the last scheduled
+	// digest went out.
+	digestSentKey = "digest_sent_at"
+	// digestUpNext is how many upcoming items the digest lists per list
+	digestUpNext = 5
+)
+
+// digest summarizes a period of activity per list group: what was added to
+// each list, what was watched on the group's Kodi hosts and what is next up
+// on each list.
+type digest struct {
+	From   time.Time     `json:"from"`
+	To     time.Time     `json:"to"`
+	Groups []digestGroup `json:"groups"`
+}
+
+type digestGroup struct {
+	Name    string        `json:"group_name"`
+	Watched []digestEntry `json:"watched"`
+	Lists   []digestList  `json:"lists"`
+}
+
+type digestList struct {
+	ID     int64         `json:"list_id"`
+	Name   string        `json:"list_name"`
+	Added  []digestEntry `json:"added"`
+	UpNext []digestEntry `json:"up_next"`
+}
+
+type digestEntry struct {
+	Title     string `json:"title"`
+	MediaType string `json:"media_type"`
+	Year      int    `json:"year,omitempty"`
+	When      string `json:"when,omitempty"`
+}
+
+// buildDigest gathers the activity of [from, to).
+func (s *Server) buildDigest(from, to time.Time) (*digest, error) {
+	lists, err := s.db.GetAllLists()
+	if err != nil {
+		return nil, err
+	}
+	added, err := s.db.ItemsAddedBetween(from, to)
+	if err != nil {
+		return nil, err
+	}
+	addedByList := map[int64][]digestEntry{}
+	for _, item := range added {
+		addedByList[item.ListID] = append(addedByList[item.ListID], digestEntry{
+			Title: itemTitle(item), MediaType: item.MediaType, Year: item.Year, When: item.AddedAt,
+		})
+	}
+
+	d := &digest{From: from, To: to, Groups: []digestGroup{}}
+	groupIndex := map[string]int{}
+	groupHosts := map[string]map[string]bool{}
+	for _, l := range lists {
+		i, ok := groupIndex[l.GroupName]
+		if !ok {
+			i = len(d.Groups)
+			groupIndex[l.GroupName] = i
+			groupHosts[l.GroupName] = map[string]bool{}
+			d.Groups = append(d.Groups, digestGroup{Name: l.GroupName, Watched: []digestEntry{}})
+		}
+		items, err := s.db.GetItems(l.ID)
+		if err != nil {
+			return nil, err
+		}
+		dl := digestList{ID: l.ID, Name: l.Name, Added: addedByList[l.ID], UpNext: []digestEntry{}}
+		if dl.Added == nil {
+			dl.Added = []digestEntry{}
+		}
+		for _, item := range items {
+			if len(dl.UpNext) == digestUpNext {
+				break
+			}
+			if !itemWatched(item) && !item.Wanted {
+				dl.UpNext = append(dl.UpNext, digestEntry{Title: itemTitle(item), MediaType: item.MediaType, Year: item.Year})
+			}
+		}
+		d.Groups[i].Lists = append(d.Groups[i].Lists, dl)
+
+		// Lists sharing a host share its plays; count them once per group
+		if groupHosts[l.GroupName][l.KodiHost] {
+			continue
+		}
+		groupHosts[l.GroupName][l.KodiHost] = true
+		plays, err := s.db.PlaysBetween(l.KodiHost, from, to)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range plays {
+			title := v.Title
+			if v.MediaType == "episode" && v.ShowTitle != "" {
+				title = v.ShowTitle + " " + media.FormatEpisode(v.Season, v.Episode) + " " + v.Title
+			}
+			d.Groups[i].Watched = append(d.Groups[i].Watched, digestEntry{Title: title, MediaType: v.MediaType, Year: v.Year, When: v.PlayedAt})
+		}
+	}
+	return d, nil
+}
+
+// itemTitle names an item the way a list card does.
+func itemTitle(item database.Item) string {
+	switch {
+	case item.MediaType == "episode" && item.ShowTitle != "":
+		return item.ShowTitle + " " + media.FormatEpisode(item.Season, item.Episode) + " " + item.Title
+	case item.MediaType == "season":
+		return fmt.Sprintf("%s season %d", item.Title, item.Season)
+	}
+	return item.Title
+}
+
+// itemWatched reports whether an item has been seen: marked watched, played
+// or, for shows and seasons, with every episode watched.
+func itemWatched(item database.Item) bool {
+	if item.Watched || item.Playcount > 0 {
+		return true
+	}
+	return (item.MediaType == "show" || item.MediaType == "season") && item.EpisodeCount > 0 && item.WatchedEpisodes >= item.EpisodeCount
+}
+
+// subject is the digest's one-line title.
+func (d *digest) subject() string {
+	return fmt.Sprintf("What's next: %s to %s", d.From.Format("2 Jan"), d.To.AddDate(0, 0, -1).Format("2 Jan 2006"))
+}
+
+// text renders the digest as plain text for email and notifications.
+func (d *digest) text() string {
+	var b strings.Builder
+	b.WriteString(d.subject() + "\n")
+	entries := func(heading string, list []digestEntry) {
+		if len(list) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "  %s:\n", heading)
+		for _, e := range list {
+			b.WriteString("    - " + e.Title)
+			if e.Year > 0 && e.MediaType == "movie" {
+				fmt.Fprintf(&b, " (%d)", e.Year)
+			}
+			b.WriteString("\n")
+		}
+	}
+	for _, g := range d.Groups {
+		fmt.Fprintf(&b, "\n%s\n", g.Name)
+		entries("Watched", g.Watched)
+		for _, l := range g.Lists {
+			if len(l.Added) == 0 && len(l.UpNext) == 0 {
+				continue
+			}
+			fmt.Fprintf(&b, " %s\n", l.Name)
+			entries("Added", l.Added)
+			entries("Up next", l.UpNext)
+		}
+	}
+	return b.String()
+}
+
+// handleDigest previews or sends the digest of the last week:
+// GET /digest?days=7 returns it (as text with &format=text) and POST /digest
+// sends it now through the configured channels.
+func (s *Server) handleDigest(w http.ResponseWriter, r *http.Request) {
+	days := 7
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 366 {
+			http.Error(w, "days must be between 1 and 366", http.StatusBadRequest)
+			return
+		}
+		days = n
+	}
+	to := time.Now()
+	d, err := s.buildDigest(to.AddDate(0, 0, -days), to)
+	if err != nil {
+		writeDBError(w, err, "Failed to build digest")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if r.URL.Query().Get("format") == "text" {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.Write([]byte(d.text()))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(d)
+	case http.MethodPost:
+		if !digestEnabled(s.config.Digest) {
+			http.Error(w, "No digest channel is configured", http.StatusConflict)
+			return
+		}
+		if err := s.sendDigest(d); err != nil {
+			slog.Error("Failed to send digest", "error", err)
+			http.Error(w, "Failed to send digest: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func digestEnabled(cfg database.DigestConfig) bool {
+	return cfg.Notify || cfg.SMTP.Host != ""
+}
+
+// sendDigest delivers d through every configured channel.
+func (s *Server) sendDigest(d *digest) error {
+	cfg := s.config.Digest
+	if cfg.Notify {
+		if err := s.db.AddNotification(database.NotificationDigest, 0, d.text()); err != nil {
+			return fmt.Errorf("failed to record digest notification: %w", err)
+		}
+	}
+	if cfg.SMTP.Host != "" {
+		if err := sendMail(cfg.SMTP, d.subject(), d.text()); err != nil {
+			return err
+		}
+	}
+	slog.Info("Sent digest", "from", d.From.Format(time.DateOnly), "to", d.To.Format(time.DateOnly), "notify", cfg.Notify, "email", cfg.SMTP.Host != "")
+	return nil
+}
+
+// sendMail sends a plain text message. net/smtp upgrades to TLS when the
+// server offers STARTTLS.
+func sendMail(cfg database.SMTPConfig, subject, body string) error {
+	if cfg.From == "" || len(cfg.To) == 0 {
+		return fmt.Errorf("smtp from and to must be set")
+	}
+	port := cfg.Port
+	if port == 0 {
+		port = 587
+	}
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(port))
+	if err := smtp.SendMail(addr, auth, cfg.From, cfg.To, []byte(msg.String())); err != nil {
+		return fmt.Errorf("failed to send digest email: %w", err)
+	}
+	return nil
+}
+
+// digestSlot returns the most recent scheduled digest time at or before
+// now.
+func digestSlot(now time.Time, day time.Weekday, hour int) time.Time {
+	slot := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, now.Location())
+	slot = slot.AddDate(0, 0, -int((now.Weekday()-day+7)%7))
+	if slot.After(now) {
+		slot = slot.AddDate(0, 0, -7)
+	}
+	return slot
+}
+
+// digestSchedule parses the configured day and hour, falling back to Monday
+// at 8.
+func digestSchedule(cfg database.DigestConfig) (time.Weekday, int) {
+	day, hour := time.Monday, 8
+	if cfg.Day != "" {
+		found := false
+		for d := time.Sunday; d <= time.Saturday; d++ {
+			if strings.EqualFold(cfg.Day, d.String()) {
+				day, found = d, true
+			}
+		}
+		if !found {
+			slog.Error("Invalid digest day, using Monday", "day", cfg.Day)
+		}
+	}
+	if cfg.Hour != nil {
+		if *cfg.Hour >= 0 && *cfg.Hour < 24 {
+			hour = *cfg.Hour
+		} else {
+			slog.Error("Invalid digest hour, using 8", "hour", *cfg.Hour)
+		}
+	}
+	return day, hour
+}
+
+// sendDueDigest sends the digest of the week before the latest scheduled
+// slot if it hasn't gone out yet. Runs hourly on the leader replica. The
+// first run only records the time, so enabling the digest doesn't send one
+// straight away.
+func (s *Server) sendDueDigest() {
+	day, hour := digestSchedule(s.config.Digest)
+	now := time.Now()
+	slot := digestSlot(now, day, hour)
+
+	last, err := s.db.GetState(digestSentKey)
+	if err != nil {
+		slog.Error("Failed to read digest state", "error", err)
+		return
+	}
+	if last == "" {
+		if err := s.db.SetState(digestSentKey, now.Format(time.RFC3339)); err != nil {
+			slog.Error("Failed to record digest state", "error", err)
+		}
+		return
+	}
+	if sent, err := time.Parse(time.RFC3339, last); err == nil && !sent.Before(slot) {
+		return
+	}
+
+	d, err := s.buildDigest(slot.AddDate(0, 0, -7), slot)
+	if err != nil {
+		slog.Error("Failed to build digest", "error", err)
+		return
+	}
+	if err := s.sendDigest(d); err != nil {
+		slog.Error("Failed to send digest", "error", err)
+		return
+	}
+	if err := s.db.SetState(digestSentKey, slot.Format(time.RFC3339)); err != nil {
+		slog.Error("Failed to record digest state", "error", err)
+	}
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"errors"
+	"log/slog"
+
+	"whats-next/internal/database"
+	"whats-next/internal/kodi"
+	"whats-next/internal/media"
+)
+
+// enrichEpisode fills in an episode item being added from Kodi: its show,
+// season and episode numbers, runtime and watched state. Episodes use their
+// show's poster, taken from the library cache when it has been synced.
+// Kodi being unreachable isn't fatal; the item keeps what the client sent.
+func (s *Server) enrichEpisode(item *database.Item) error {
+	client, err := s.getKodiClient(item.ListID)
+	if errors.Is(err, errKodiOnly) {
+		// Other backends' episodes keep what the client sent from their
+		// listing
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	ep, err := client.GetEpisodeDetails(item.KodiID)
+	if errors.Is(err, kodi.ErrNotFound) {
+		return err
+	}
+	if err != nil {
+		slog.Warn("Failed to get episode details from Kodi", "episode_id", item.KodiID, "error", err)
+	} else {
+		item.Title = ep.Title
+		item.ShowTitle = ep.ShowTitle
+		item.TVShowID = ep.TVShowID
+		item.Season = ep.Season
+		item.Episode = ep.Episode
+		item.Runtime = ep.Runtime
+		item.Rating = ep.Rating
+		item.Playcount = ep.Playcount
+		item.Watched = ep.Playcount > 0
+		if poster := ep.Art["tvshow.poster"]; poster != "" {
+			item.Poster = poster
+		} else if item.Poster == "" {
+			item.Poster = ep.Thumbnail
+		}
+	}
+
+	if item.TVShowID != 0 {
+		if show, err := s.db.GetCachedItem(item.ListID, item.TVShowID, "show"); err == nil {
+			if item.ShowTitle == "" {
+				item.ShowTitle = show.Title
+			}
+			item.Year = show.Year
+			if show.Poster != "" {
+				item.Poster = show.Poster
+			}
+		}
+	}
+	item.EpisodeCode = media.FormatEpisode(item.Season, item.Episode)
+	return nil
+}
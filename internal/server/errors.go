@@ -0,0 +1,99 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"whats-next/internal/database"
+	"whats-next/internal/kodi"
+)
+
+// writeDBError maps database sentinel errors to their HTTP status. Anything
+// else is logged with logArgs and reported as a 500 with msg.
+func writeDBError(w http.ResponseWriter, err error, msg string, logArgs ...any) {
+	switch {
+	case errors.Is(err, database.ErrListNotFound):
+		http.Error(w, "List not found", http.StatusNotFound)
+	case errors.Is(err, database.ErrItemNotFound):
+		http.Error(w, "Item not found", http.StatusNotFound)
+	case errors.Is(err, database.ErrDuplicateItem):
+		http.Error(w, "Item is already on this list", http.StatusConflict)
+	case errors.Is(err, database.ErrSearchNotFound):
+		http.Error(w, "Saved search not found", http.StatusNotFound)
+	case errors.Is(err, database.ErrDuplicateSearch):
+		http.Error(w, "A saved search with that name already exists", http.StatusConflict)
+	case errors.Is(err, database.ErrSectionNotFound):
+		http.Error(w, "Section not found", http.StatusNotFound)
+	case errors.Is(err, database.ErrDuplicateSection):
+		http.Error(w, "A section with that name already exists", http.StatusConflict)
+	case errors.Is(err, database.ErrFeedNotFound):
+		http.Error(w, "Feed not found", http.StatusNotFound)
+	case errors.Is(err, database.ErrDuplicateFeed):
+		http.Error(w, "The list is already subscribed to that feed", http.StatusConflict)
+	case errors.Is(err, database.ErrRevisionMismatch):
+		http.Error(w, "The list was changed by someone else; refresh and try again", http.StatusConflict)
+	case errors.Is(err, database.ErrPrefsNotFound):
+		http.Error(w, "Notification preferences not found", http.StatusNotFound)
+	case errors.Is(err, database.ErrDuplicateList):
+		http.Error(w, "A list with that name already exists in the group", http.StatusConflict)
+	case errors.Is(err, database.ErrInvalidList):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, database.ErrGroupNotFound):
+		http.Error(w, "Group not found", http.StatusNotFound)
+	case errors.Is(err, database.ErrDuplicateGroup):
+		http.Error(w, "A group with that name already exists", http.StatusConflict)
+	case errors.Is(err, database.ErrGroupNotEmpty):
+		http.Error(w, "The group still has lists; move or delete them first", http.StatusConflict)
+	case errors.Is(err, database.ErrGroupFromConfig):
+		http.Error(w, "The group is defined in config.json; rename it there", http.StatusConflict)
+	case errors.Is(err, database.ErrShareNotFound):
+		http.Error(w, "Share not found", http.StatusNotFound)
+	case errors.Is(err, database.ErrRemoteNotFound):
+		http.Error(w, "The list doesn't mirror a remote list", http.StatusNotFound)
+	case errors.Is(err, database.ErrListMirrored):
+		http.Error(w, "The list mirrors a remote list and is read-only; stop mirroring to edit it", http.StatusConflict)
+	case errors.Is(err, database.ErrInvalidSettings):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, errKodiOnly):
+		http.Error(w, "This list's media backend doesn't support that; it needs a Kodi host", http.StatusNotImplemented)
+	default:
+		slog.Error(msg, append(logArgs, "error", err)...)
+		http.Error(w, msg, http.StatusInternalServerError)
+	}
+}
+
+// writeKodiError reports a failed Kodi call. Rejected credentials get a 502
+// with a JSON payload naming the host, so clients can tell them apart from
+// an outage, and a host that is failing fast after repeated errors gets a
+// 503 with Retry-After; anything else is reported with msg and status.
+func writeKodiError(w http.ResponseWriter, err error, msg string, status int) {
+	var authErr *kodi.AuthError
+	if errors.As(err, &authErr) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadGateway)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error":   "kodi_auth_failed",
+			"message": "Kodi authentication failed for host " + authErr.Host,
+			"host":    authErr.Host,
+		})
+		return
+	}
+	var downErr *kodi.UnavailableError
+	if errors.As(err, &downErr) {
+		retryAfter := int(time.Until(downErr.RetryAt).Seconds()) + 1
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error":   "kodi_unavailable",
+			"message": "Kodi host " + downErr.Host + " is not responding",
+			"host":    downErr.Host,
+		})
+		return
+	}
+	http.Error(w, msg, status)
+}
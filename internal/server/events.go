@@ -0,0 +1,414 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"whats-next/internal/database"
+	"whats-next/internal/kodi"
+)
+
+const (
+	// Kodi announces every item of a scan or a run of playcount changes
+	// separately, so work is deferred and done once per burst.
+	playbackEventDelay = 5 * time.Second
+	syncEventDelay     = 30 * time.Second
+
+	minEventBackoff = 5 * time.Second
+	maxEventBackoff = 5 * time.Minute
+)
+
+// eventHost is a Kodi host with one representative list per content type.
+// The lists are re-read whenever the listener reconnects and whenever lists
+// change, so a deleted or moved list is never used for long.
+type eventHost struct {
+	host   string
+	cancel context.CancelFunc // stops the host's listener
+
+	mu    sync.Mutex
+	lists map[string]int64 // "movie" or "tv" -> list ID
+}
+
+// list returns the host's representative list of contentType.
+func (h *eventHost) list(contentType string) (int64, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	id, ok := h.lists[contentType]
+	return id, ok
+}
+
+// anyList returns a list on the host, for host-wide updates.
+func (h *eventHost) anyList() int64 {
+	if id, ok := h.list("movie"); ok {
+		return id
+	}
+	id, _ := h.list("tv")
+	return id
+}
+
+func (h *eventHost) setLists(lists map[string]int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lists = lists
+}
+
+// startKodiEvents subscribes to the notification interface of every Kodi
+// host, so watched state and library changes show up without a manual sync.
+// Every replica listens; the work triggered is idempotent or leased.
+func (s *Server) startKodiEvents(ctx context.Context) {
+	port := kodi.DefaultEventPort
+	if s.config.KodiEventPort != 0 {
+		port = s.config.KodiEventPort
+	}
+	if port < 0 {
+		slog.Info("Kodi notifications disabled")
+		return
+	}
+
+	s.listenerMu.Lock()
+	s.eventCtx, s.eventPort = ctx, port
+	s.eventListeners = map[string]*eventHost{}
+	s.listenerMu.Unlock()
+	s.refreshKodiEvents()
+	s.jobs.Go(func() {
+		<-ctx.Done()
+		s.eventMu.Lock()
+		defer s.eventMu.Unlock()
+		for key, t := range s.eventTimers {
+			t.Stop()
+			delete(s.eventTimers, key)
+		}
+	})
+}
+
+// refreshKodiEvents brings the listeners in line with the lists: hosts new
+// to the lists get a listener, hosts no list uses any more lose theirs, and
+// the rest pick up their current lists. Handlers call it after any change
+// to a list's host; before startKodiEvents, or with notifications
+// disabled, it does nothing.
+func (s *Server) refreshKodiEvents() {
+	s.listenerMu.Lock()
+	defer s.listenerMu.Unlock()
+	if s.eventCtx == nil || s.eventCtx.Err() != nil {
+		return
+	}
+	hosts, err := s.eventHosts()
+	if err != nil {
+		slog.Error("Failed to get lists for Kodi notifications", "error", err)
+		return
+	}
+	current := map[string]bool{}
+	for _, h := range hosts {
+		current[h.host] = true
+		if running, ok := s.eventListeners[h.host]; ok {
+			running.setLists(h.lists)
+			continue
+		}
+		ctx, cancel := context.WithCancel(s.eventCtx)
+		h.cancel = cancel
+		s.eventListeners[h.host] = h
+		port := s.eventPort
+		s.jobs.Go(func() { s.listenKodiEvents(ctx, h, port) })
+	}
+	for host, h := range s.eventListeners {
+		if !current[host] {
+			h.cancel()
+			delete(s.eventListeners, host)
+			slog.Info("Stopped Kodi notifications, no lists use the host", "host", host)
+		}
+	}
+}
+
+// eventHosts groups the Kodi video lists by host. Notifications are only
+// acted on for the video library.
+func (s *Server) eventHosts() ([]*eventHost, error) {
+	lists, err := s.db.GetAllLists()
+	if err != nil {
+		return nil, err
+	}
+	var hosts []*eventHost
+	byHost := map[string]*eventHost{}
+	for _, l := range lists {
+		if l.Backend == database.BackendJellyfin || l.ContentType == "music" {
+			continue
+		}
+		contentType := l.ContentType
+		if contentType != "tv" {
+			contentType = "movie"
+		}
+		h, ok := byHost[l.KodiHost]
+		if !ok {
+			h = &eventHost{host: l.KodiHost, lists: map[string]int64{}}
+			byHost[l.KodiHost] = h
+			hosts = append(hosts, h)
+		}
+		if _, ok := h.lists[contentType]; !ok {
+			h.lists[contentType] = l.ID
+		}
+	}
+	return hosts, nil
+}
+
+// listenKodiEvents keeps a subscription to one host open until ctx is
+// cancelled, reconnecting with exponential backoff.
+func (s *Server) listenKodiEvents(ctx context.Context, h *eventHost, port int) {
+	backoff := minEventBackoff
+	for {
+		var connectedAt time.Time
+		var addr string
+		err := s.reloadEventHost(h)
+		if err == nil {
+			addr, err = s.kodiEventAddress(h.anyList(), port)
+		}
+		if err == nil {
+			err = kodi.Listen(ctx, addr,
+				func() {
+					connectedAt = time.Now()
+					slog.Info("Subscribed to Kodi notifications", "host", h.host, "addr", addr)
+				},
+				func(n kodi.Notification) { s.handleKodiEvent(ctx, h, n) })
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		// A connection that stayed up was healthy; retry promptly
+		if !connectedAt.IsZero() && time.Since(connectedAt) > maxEventBackoff {
+			backoff = minEventBackoff
+		}
+		slog.Warn("Kodi notifications unavailable, retrying", "host", h.host, "retry_in", backoff.String(), "error", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxEventBackoff)
+	}
+}
+
+// reloadEventHost re-reads h's lists, so a reconnect never uses a list
+// that was deleted or moved to another host since the last one.
+func (s *Server) reloadEventHost(h *eventHost) error {
+	hosts, err := s.eventHosts()
+	if err != nil {
+		return err
+	}
+	for _, current := range hosts {
+		if current.host == h.host {
+			h.setLists(current.lists)
+			return nil
+		}
+	}
+	return errors.New("no lists use the host")
+}
+
+func (s *Server) kodiEventAddress(listID int64, port int) (string, error) {
+	if s.kodiEventsOverride != "" {
+		return s.kodiEventsOverride, nil
+	}
+	client, err := s.getKodiClient(listID)
+	if err != nil {
+		return "", err
+	}
+	return client.EventAddress(port)
+}
+
+// handleKodiEvent reacts to one notification from h. Playcount and resume
+// changes refresh playback state; added or removed items resync the library;
+// other edits of a movie or show re-download its artwork if it changed.
+func (s *Server) handleKodiEvent(ctx context.Context, h *eventHost, n kodi.Notification) {
+	slog.Debug("Kodi notification", "host", h.host, "method", n.Method)
+	switch n.Method {
+	case "VideoLibrary.OnUpdate":
+		var u kodi.LibraryUpdate
+		if err := json.Unmarshal(n.Data, &u); err != nil {
+			slog.Warn("Invalid Kodi notification", "host", h.host, "method", n.Method, "error", err)
+			return
+		}
+		if u.Added {
+			s.scheduleEventSync(ctx, h, u.Item.Type, false)
+			return
+		}
+		switch u.Item.Type {
+		case "movie":
+			s.schedulePlaybackRefresh(ctx, h)
+			if u.Playcount == nil {
+				s.scheduleArtRefresh(ctx, h, u.Item.Type, u.Item.ID)
+			}
+		case "episode":
+			if u.Playcount != nil {
+				if _, err := s.db.SetItemPlaycount(h.anyList(), "episode", u.Item.ID, *u.Playcount); err != nil {
+					slog.Error("Failed to store episode playcount", "host", h.host, "kodi_id", u.Item.ID, "error", err)
+				} else {
+					s.applyWatchedPolicies()
+				}
+			}
+			s.scheduleCounterRefresh(ctx, h)
+		case "tvshow":
+			s.scheduleCounterRefresh(ctx, h)
+			s.scheduleArtRefresh(ctx, h, u.Item.Type, u.Item.ID)
+		}
+
+	case "VideoLibrary.OnRemove":
+		var r kodi.LibraryRemove
+		if err := json.Unmarshal(n.Data, &r); err != nil {
+			slog.Warn("Invalid Kodi notification", "host", h.host, "method", n.Method, "error", err)
+			return
+		}
+		s.scheduleEventSync(ctx, h, r.Type, true)
+
+	case "VideoLibrary.OnScanFinished":
+		s.scheduleEventSync(ctx, h, "movie", false)
+		s.scheduleEventSync(ctx, h, "tvshow", false)
+
+	case "VideoLibrary.OnCleanFinished":
+		s.scheduleEventSync(ctx, h, "movie", true)
+		s.scheduleEventSync(ctx, h, "tvshow", true)
+
+	case "Player.OnStop":
+		var stop kodi.PlayerStop
+		if err := json.Unmarshal(n.Data, &stop); err != nil {
+			slog.Warn("Invalid Kodi notification", "host", h.host, "method", n.Method, "error", err)
+			return
+		}
+		// Stopping saves a resume point or bumps the playcount
+		switch stop.Item.Type {
+		case "movie":
+			s.schedulePlaybackRefresh(ctx, h)
+		case "episode":
+			s.scheduleCounterRefresh(ctx, h)
+		default:
+			return
+		}
+		s.scheduleHistoryPull(ctx, h)
+	}
+}
+
+// scheduleEventSync resyncs the content type itemType belongs to, if any
+// list on the host holds that content type. Removals need a full sync to be
+// noticed; additions are picked up by an incremental one.
+func (s *Server) scheduleEventSync(ctx context.Context, h *eventHost, itemType string, full bool) {
+	contentType := "movie"
+	switch itemType {
+	case "movie":
+	case "tvshow", "season", "episode":
+		contentType = "tv"
+	default:
+		return
+	}
+	listID, ok := h.list(contentType)
+	if !ok {
+		return
+	}
+	key := "sync|" + h.host + "|" + contentType
+	if full {
+		key += "|full"
+	}
+	s.debounce(ctx, key, syncEventDelay, func() {
+		count, err := s.syncLibrary(listID, contentType, full)
+		if errors.Is(err, errSyncInProgress) {
+			slog.Debug("Skipping notification sync, already in progress", "host", h.host, "content_type", contentType)
+			return
+		}
+		if err != nil {
+			slog.Error("Notification-triggered sync failed", "host", h.host, "content_type", contentType, "error", err)
+			return
+		}
+		slog.Info("Synced library after Kodi notification", "host", h.host, "content_type", contentType, "count", count)
+	})
+}
+
+func (s *Server) scheduleCounterRefresh(ctx context.Context, h *eventHost) {
+	listID, ok := h.list("tv")
+	if !ok {
+		return
+	}
+	s.debounce(ctx, "counters|"+h.host, playbackEventDelay, func() {
+		s.refreshHostShowCounters(listID, h.host)
+	})
+}
+
+func (s *Server) schedulePlaybackRefresh(ctx context.Context, h *eventHost) {
+	listID, ok := h.list("movie")
+	if !ok {
+		return
+	}
+	s.debounce(ctx, "playback|"+h.host, playbackEventDelay, func() {
+		s.refreshMoviePlayback(listID, h.host)
+	})
+}
+
+// scheduleArtRefresh checks a movie or show Kodi updated for new artwork.
+// Edits that only touch watched state are filtered out by the caller.
+func (s *Server) scheduleArtRefresh(ctx context.Context, h *eventHost, itemType string, kodiID int) {
+	contentType := "movie"
+	if itemType == "tvshow" {
+		contentType = "tv"
+	}
+	listID, ok := h.list(contentType)
+	if !ok {
+		return
+	}
+	key := fmt.Sprintf("art|%s|%s|%d", h.host, contentType, kodiID)
+	s.debounce(ctx, key, playbackEventDelay, func() {
+		s.refreshItemArt(listID, cacheTypeFor(contentType), kodiID)
+	})
+}
+
+func (s *Server) scheduleHistoryPull(ctx context.Context, h *eventHost) {
+	s.debounce(ctx, "history|"+h.host, playbackEventDelay, func() {
+		if _, err := s.pullHistory(h.anyList()); err != nil {
+			slog.Warn("Failed to pull viewing history", "host", h.host, "error", err)
+		}
+	})
+}
+
+// refreshMoviePlayback pulls the playcount, last played time and resume
+// point of every movie on the host of listID into the cache and list items.
+func (s *Server) refreshMoviePlayback(listID int64, host string) {
+	client, err := s.getKodiClient(listID)
+	if err != nil {
+		slog.Error("Failed to get Kodi client for playback refresh", "list_id", listID, "error", err)
+		return
+	}
+	movies, err := client.GetMovies()
+	if err != nil {
+		slog.Warn("Failed to fetch movie playback state", "host", host, "error", err)
+		return
+	}
+	states := make([]database.PlaybackState, 0, len(movies))
+	for _, m := range movies {
+		states = append(states, database.PlaybackState{KodiID: m.ID, Playcount: m.Playcount, LastPlayed: m.LastPlayed, ResumePosition: m.ResumePoint()})
+	}
+	changed, err := s.db.UpdatePlayback(listID, "movie", states)
+	if err != nil {
+		slog.Error("Failed to store movie playback state", "host", host, "error", err)
+		return
+	}
+	slog.Info("Refreshed movie playback state", "host", host, "items_changed", changed)
+	if changed > 0 {
+		s.applyWatchedPolicies()
+	}
+}
+
+// debounce runs fn once, delay after the first call for key since the last
+// run. Pending work is dropped when ctx is cancelled.
+func (s *Server) debounce(ctx context.Context, key string, delay time.Duration, fn func()) {
+	s.eventMu.Lock()
+	defer s.eventMu.Unlock()
+	if _, pending := s.eventTimers[key]; pending || ctx.Err() != nil {
+		return
+	}
+	s.eventTimers[key] = time.AfterFunc(delay, func() {
+		s.eventMu.Lock()
+		delete(s.eventTimers, key)
+		s.eventMu.Unlock()
+		if ctx.Err() == nil {
+			fn()
+		}
+	})
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"whats-next/internal/database"
+	"whats-next/internal/kodi"
+)
+
+// favouriteResult is a Kodi favourite with the library item it resolved to,
+// if any. Item is annotated like a search result so it can be added to a
+// list directly.
+type favouriteResult struct {
+	kodi.Favourite
+	MediaType string        `json:"media_type,omitempty"`
+	Item      *searchResult `json:"item,omitempty"`
+}
+
+// resolveFavourite finds the cached library item a favourite stands for:
+// by its videodb:// URL when it has one, otherwise, for media favourites
+// pointing at a file, by title. It returns nil for favourites that aren't
+// library titles, such as add-ons or windows, or haven't been synced yet.
+func (s *Server) resolveFavourite(listID int64, f kodi.Favourite) *database.CachedItem {
+	if mediaType, id, ok := f.LibraryItem(); ok {
+		cached, err := s.db.GetCachedItem(listID, id, mediaType)
+		if err != nil {
+			if !errors.Is(err, sql.ErrNoRows) {
+				slog.Error("Failed to look up favourite", "list_id", listID, "title", f.Title, "error", err)
+			}
+			return nil
+		}
+		return cached
+	}
+	if f.Type != "media" {
+		return nil
+	}
+	for _, mediaType := range []string{"movie", "show"} {
+		cached, err := s.db.FindCachedMatch(listID, mediaType, f.Title, 0)
+		if err == nil {
+			return cached
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			slog.Error("Failed to match favourite", "list_id", listID, "title", f.Title, "error", err)
+			return nil
+		}
+	}
+	return nil
+}
+
+// handleFavourites lists the favourites pinned on a list's Kodi host, with
+// the library items they resolve to: GET /library/favourites?list_id=.
+// POST adds every resolved favourite of the list's content type to the list,
+// skipping those already on it.
+func (s *Server) handleFavourites(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	listID, err := strconv.ParseInt(r.URL.Query().Get("list_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid list_id", http.StatusBadRequest)
+		return
+	}
+	list, err := s.db.GetList(listID)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve list", "list_id", listID)
+		return
+	}
+	client, err := s.getKodiClient(listID)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve list", "list_id", listID)
+		return
+	}
+	favourites, err := client.GetFavourites()
+	if err != nil {
+		slog.Error("Failed to get favourites from Kodi", "list_id", listID, "error", err)
+		writeKodiError(w, err, "Failed to fetch favourites", http.StatusBadGateway)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		var result struct {
+			Added   []string `json:"added"`
+			Skipped int      `json:"skipped"`
+		}
+		result.Added = []string{}
+		cacheType := cacheTypeFor(list.ContentType)
+		for _, f := range favourites {
+			cached := s.resolveFavourite(listID, f)
+			if cached == nil || cached.MediaType != cacheType {
+				result.Skipped++
+				continue
+			}
+			item := itemFromCache(listID, cached)
+			if _, err := s.db.AddItem(item); errors.Is(err, database.ErrDuplicateItem) {
+				result.Skipped++
+				continue
+			} else if err != nil {
+				writeDBError(w, err, "Failed to add favourite", "list_id", listID, "title", f.Title)
+				return
+			}
+			s.prewarmShow(item)
+			result.Added = append(result.Added, cached.Title)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+		return
+	}
+
+	results := make([]favouriteResult, len(favourites))
+	for i, f := range favourites {
+		results[i] = favouriteResult{Favourite: f}
+		cached := s.resolveFavourite(listID, f)
+		if cached == nil {
+			continue
+		}
+		annotated := s.annotateMembership(listID, cached.MediaType, []kodi.MediaItem{cachedMediaItem(*cached)})
+		results[i].MediaType = cached.MediaType
+		results[i].Item = &annotated[0]
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
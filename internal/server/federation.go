@@ -0,0 +1,430 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"whats-next/internal/database"
+)
+
+// maxFederatedListSize caps how much of another instance's list is read.
+const maxFederatedListSize = 5 << 20
+
+// federatedList is a shared list as another instance reads it: titles and
+// IDs only, since library IDs, posters and files mean nothing elsewhere.
+type federatedList struct {
+	Name        string          `json:"list_name"`
+	ContentType string          `json:"content_type"`
+	Items       []federatedItem `json:"items"`
+}
+
+type federatedItem struct {
+	MediaType string `json:"media_type"`
+	Title     string `json:"title"`
+	Year      int    `json:"year,omitempty"`
+	IMDbID    string `json:"imdb_id,omitempty"`
+	TMDbID    string `json:"tmdb_id,omitempty"`
+	ShowTitle string `json:"show_title,omitempty"`
+	Season    int    `json:"season,omitempty"`
+	Episode   int    `json:"episode,omitempty"`
+}
+
+// remoteSync reports what one poll of a mirrored list did. Skipped are
+// titles of another kind than the list holds, such as episodes.
+type remoteSync struct {
+	database.RemoteList
+	Changed bool     `json:"changed"`
+	Matched int      `json:"matched"`
+	Wanted  int      `json:"wanted"`
+	Skipped []string `json:"skipped,omitempty"`
+}
+
+// hashShareToken is how share tokens are stored and looked up.
+func hashShareToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// handleListShares manages who may read a list from another instance:
+//
+//	GET    /lists/{id}/shares            list them
+//	POST   /lists/{id}/shares            share {"name"}; the token is returned once
+//	DELETE /lists/{id}/shares/{shareID}  revoke
+func (s *Server) handleListShares(w http.ResponseWriter, r *http.Request, listID int64, rest []string) {
+	if len(rest) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			shares, err := s.db.GetListShares(listID)
+			if err != nil {
+				writeDBError(w, err, "Failed to retrieve shares", "list_id", listID)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(shares)
+		case http.MethodPost:
+			s.createListShare(w, r, listID)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	shareID, err := strconv.ParseInt(rest[0], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid share ID", http.StatusBadRequest)
+		return
+	}
+	switch {
+	case len(rest) == 1 && r.Method == http.MethodDelete:
+		if err := s.db.DeleteListShare(listID, shareID); err != nil {
+			writeDBError(w, err, "Failed to delete share", "list_id", listID, "share_id", shareID)
+			return
+		}
+		slog.Info("Revoked list share", "list_id", listID, "share_id", shareID)
+		w.WriteHeader(http.StatusNoContent)
+	case len(rest) == 1:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) createListShare(w http.ResponseWriter, r *http.Request, listID int64) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	b := make([]byte, 32)
+	rand.Read(b)
+	token := hex.EncodeToString(b)
+	id, err := s.db.AddListShare(listID, strings.TrimSpace(req.Name), hashShareToken(token))
+	if err != nil {
+		writeDBError(w, err, "Failed to share list", "list_id", listID)
+		return
+	}
+	share, err := s.db.GetListShare(listID, id)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve share", "list_id", listID, "share_id", id)
+		return
+	}
+	slog.Info("Shared list", "list_id", listID, "share_id", id, "name", share.Name)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(struct {
+		database.ListShare
+		Token string `json:"token"`
+		Path  string `json:"path"`
+	}{*share, token, "/api/federation/list"})
+}
+
+// handleFederatedList serves a shared list to another instance:
+// GET /federation/list with the share's token as a bearer token. The list
+// revision is the ETag, so polls of an unchanged list get a 304.
+func (s *Server) handleFederatedList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "A share token is required", http.StatusUnauthorized)
+		return
+	}
+	share, err := s.db.FindListShare(hashShareToken(token))
+	if errors.Is(err, database.ErrShareNotFound) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "Invalid share token", http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		writeDBError(w, err, "Failed to check share token")
+		return
+	}
+
+	rev, err := s.db.ListRevision(share.ListID)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve list", "list_id", share.ListID)
+		return
+	}
+	setRevision(w, rev)
+	if r.Header.Get("If-None-Match") == w.Header().Get("ETag") {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	list, err := s.db.GetList(share.ListID)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve list", "list_id", share.ListID)
+		return
+	}
+	items, err := s.db.GetItems(share.ListID)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve items", "list_id", share.ListID)
+		return
+	}
+	shared := federatedList{Name: list.Name, ContentType: list.ContentType, Items: []federatedItem{}}
+	for _, item := range items {
+		shared.Items = append(shared.Items, federatedItem{
+			MediaType: item.MediaType, Title: item.Title, Year: item.Year, IMDbID: item.IMDbID, TMDbID: item.TMDbID,
+			ShowTitle: item.ShowTitle, Season: item.Season, Episode: item.Episode,
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(shared)
+}
+
+// mirroredListRoutes are the /lists/{id} routes that edit a list's items or
+// sections, and mirroredItemRoutes the /items/{id} ones ("" being the item
+// itself). A mirrored list refuses them: the next poll would undo the edit.
+var (
+	mirroredListRoutes = map[string]bool{"items": true, "order": true, "import": true, "pending": true, "sections": true, "missing": true}
+	mirroredItemRoutes = map[string]bool{"": true, "reorder": true, "section": true, "restore": true, "expand": true, "poster": true}
+)
+
+// refuseMirrored answers 409 and returns true when list listID mirrors a
+// remote list.
+func (s *Server) refuseMirrored(w http.ResponseWriter, listID int64) bool {
+	_, err := s.db.GetRemoteList(listID)
+	if errors.Is(err, database.ErrRemoteNotFound) {
+		return false
+	}
+	if err == nil {
+		err = database.ErrListMirrored
+	}
+	writeDBError(w, err, "Failed to retrieve remote list", "list_id", listID)
+	return true
+}
+
+// handleRemoteList makes a list a read-only copy of a list shared by
+// another instance:
+//
+//	GET    /lists/{id}/remote       the list it mirrors and the last poll
+//	PUT    /lists/{id}/remote       mirror {"url", "token"}, checked by a first poll
+//	POST   /lists/{id}/remote/sync  poll now
+//	DELETE /lists/{id}/remote       stop mirroring; the items stay
+//
+// While mirroring, edits to its items and sections are refused with 409.
+func (s *Server) handleRemoteList(w http.ResponseWriter, r *http.Request, listID int64, rest []string) {
+	switch {
+	case len(rest) == 0 && r.Method == http.MethodGet:
+		remote, err := s.db.GetRemoteList(listID)
+		if err != nil {
+			writeDBError(w, err, "Failed to retrieve remote list", "list_id", listID)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(remote)
+	case len(rest) == 0 && r.Method == http.MethodPut:
+		s.setRemoteList(w, r, listID)
+	case len(rest) == 0 && r.Method == http.MethodDelete:
+		if err := s.db.DeleteRemoteList(listID); err != nil {
+			writeDBError(w, err, "Failed to stop mirroring", "list_id", listID)
+			return
+		}
+		slog.Info("Stopped mirroring remote list", "list_id", listID)
+		w.WriteHeader(http.StatusNoContent)
+	case len(rest) == 1 && rest[0] == "sync" && r.Method == http.MethodPost:
+		remote, err := s.db.GetRemoteList(listID)
+		if err != nil {
+			writeDBError(w, err, "Failed to retrieve remote list", "list_id", listID)
+			return
+		}
+		s.writeRemoteSync(w, *remote, http.StatusOK)
+	case len(rest) <= 1:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) setRemoteList(w http.ResponseWriter, r *http.Request, listID int64) {
+	var req struct {
+		URL   string `json:"url"`
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	u, err := url.Parse(strings.TrimSpace(req.URL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		http.Error(w, "url must be an http or https URL", http.StatusBadRequest)
+		return
+	}
+	token := strings.TrimSpace(req.Token)
+	if token == "" {
+		http.Error(w, "token is required", http.StatusBadRequest)
+		return
+	}
+	list, err := s.db.GetList(listID)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve list", "list_id", listID)
+		return
+	}
+
+	// Check the share before replacing anything on the list
+	shared, _, err := s.fetchRemoteList(u.String(), token, "")
+	if err == nil && shared.ContentType != list.ContentType {
+		err = fmt.Errorf("the shared list holds %s, this list holds %s", shared.ContentType, list.ContentType)
+	}
+	if err != nil {
+		slog.Warn("Failed to read remote list", "list_id", listID, "url", u.String(), "error", err)
+		http.Error(w, "Failed to read the shared list: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	if err := s.db.SetRemoteList(listID, u.String(), token); err != nil {
+		writeDBError(w, err, "Failed to mirror remote list", "list_id", listID)
+		return
+	}
+	remote, err := s.db.GetRemoteList(listID)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve remote list", "list_id", listID)
+		return
+	}
+	slog.Info("Mirroring remote list", "list_id", listID, "url", remote.URL, "remote_list", shared.Name)
+	s.writeRemoteSync(w, *remote, http.StatusCreated)
+}
+
+// writeRemoteSync polls a mirrored list and writes the outcome. A failed
+// poll is kept on the remote list and reported with a 502.
+func (s *Server) writeRemoteSync(w http.ResponseWriter, remote database.RemoteList, status int) {
+	result, err := s.syncRemoteList(remote)
+	if err != nil && result.RemoteList.LastError == "" {
+		writeDBError(w, err, "Failed to sync remote list", "list_id", remote.ListID)
+		return
+	}
+	if err != nil {
+		status = http.StatusBadGateway
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result)
+}
+
+// fetchRemoteList reads a list shared by another instance. It returns a nil
+// list when the list is unchanged since etag.
+func (s *Server) fetchRemoteList(remoteURL, token, etag string) (*federatedList, string, error) {
+	req, err := http.NewRequest(http.MethodGet, remoteURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return nil, etag, nil
+	case http.StatusUnauthorized:
+		return nil, "", errors.New("the share token was rejected; it may have been revoked")
+	default:
+		return nil, "", fmt.Errorf("remote instance returned status %d", resp.StatusCode)
+	}
+	var shared federatedList
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxFederatedListSize)).Decode(&shared); err != nil {
+		return nil, "", fmt.Errorf("invalid shared list: %w", err)
+	}
+	return &shared, resp.Header.Get("ETag"), nil
+}
+
+// syncRemoteList polls a mirrored list and, when it changed, replaces the
+// local list's items with its titles in its order. Titles in the library
+// are matched by IMDb ID or title and year; the rest become wanted items,
+// linked by a later sync once they turn up.
+func (s *Server) syncRemoteList(remote database.RemoteList) (remoteSync, error) {
+	result := remoteSync{RemoteList: remote}
+	list, err := s.db.GetList(remote.ListID)
+	if err != nil {
+		return result, err
+	}
+
+	shared, etag, err := s.fetchRemoteList(remote.URL, remote.Token, remote.ETag)
+	if err == nil && shared != nil && shared.ContentType != list.ContentType {
+		err = fmt.Errorf("the shared list holds %s, this list holds %s", shared.ContentType, list.ContentType)
+	}
+	if err == nil && shared != nil {
+		result.Changed = true
+		err = s.applyRemoteList(list, shared, &result)
+	}
+	status := ""
+	if err != nil {
+		slog.Warn("Failed to sync remote list", "list_id", list.ID, "url", remote.URL, "error", err)
+		status, etag = err.Error(), ""
+	}
+	if err := s.db.UpdateRemoteStatus(list.ID, etag, status); err != nil {
+		slog.Error("Failed to record remote list status", "list_id", list.ID, "error", err)
+	}
+	if rl, err := s.db.GetRemoteList(list.ID); err == nil {
+		result.RemoteList = *rl
+	}
+	if result.Changed && err == nil {
+		slog.Info("Synced remote list", "list_id", list.ID, "url", remote.URL, "matched", result.Matched, "wanted", result.Wanted, "skipped", len(result.Skipped))
+	}
+	return result, err
+}
+
+func (s *Server) applyRemoteList(list *database.List, shared *federatedList, result *remoteSync) error {
+	cacheType := cacheTypeFor(list.ContentType)
+	items := []database.Item{}
+	for _, it := range shared.Items {
+		if it.MediaType != cacheType {
+			result.Skipped = append(result.Skipped, it.Title)
+			continue
+		}
+		cached, err := s.matchImport(list.ID, cacheType, it.Title, it.Year, it.IMDbID)
+		if err == nil {
+			items = append(items, itemFromCache(list.ID, cached))
+			result.Matched++
+			continue
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		items = append(items, database.Item{
+			ListID: list.ID, MediaType: cacheType, Title: it.Title, Year: it.Year,
+			IMDbID: it.IMDbID, TMDbID: it.TMDbID, Wanted: true,
+		})
+		result.Wanted++
+	}
+	if _, err := s.db.ReplaceItems(list.ID, items); err != nil {
+		return err
+	}
+	for _, item := range items {
+		s.prewarmShow(item)
+	}
+	return nil
+}
+
+// refreshRemoteLists polls every mirrored list, for the scheduled job.
+func (s *Server) refreshRemoteLists() {
+	remotes, err := s.db.GetAllRemoteLists()
+	if err != nil {
+		slog.Error("Failed to get remote lists", "error", err)
+		return
+	}
+	for _, rl := range remotes {
+		s.syncRemoteList(rl) // failures are logged and kept on the remote list
+	}
+}
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"whats-next/internal/database"
+)
+
+func TestMirroredListIsReadOnly(t *testing.T) {
+	e := newTestEnv(t)
+	list := e.addList("Mirror", "movie")
+	itemID, err := e.db.AddItem(database.Item{ListID: list.ID, MediaType: "movie", Title: "Inception", Year: 2010, Wanted: true})
+	if err != nil {
+		t.Fatalf("AddItem: %v", err)
+	}
+	if err := e.db.SetRemoteList(list.ID, "https://other.example/api/federation/list", "token"); err != nil {
+		t.Fatalf("SetRemoteList: %v", err)
+	}
+
+	lists, items := fmt.Sprintf("/lists/%d", list.ID), fmt.Sprintf("/items/%d", itemID)
+	for _, tc := range []struct {
+		method, path string
+		body         interface{}
+	}{
+		{http.MethodPost, lists + "/items", map[string]interface{}{"title": "Heat", "wanted": true}},
+		{http.MethodPut, lists + "/order", map[string]interface{}{"item_ids": []int64{itemID}}},
+		{http.MethodPost, lists + "/sections", map[string]string{"name": "Later"}},
+		{http.MethodPost, items + "/reorder", map[string]int{"sort_order": 3}},
+		{http.MethodPatch, items + "/section", map[string]interface{}{"section_id": nil}},
+		{http.MethodDelete, items, nil},
+	} {
+		t.Run(tc.method+" "+tc.path, func(t *testing.T) {
+			e.expect(http.StatusConflict, tc.method, tc.path, tc.body, nil)
+		})
+	}
+	if _, err := e.db.GetItem(itemID); err != nil {
+		t.Fatalf("item gone after refused delete: %v", err)
+	}
+
+	// Reading still works, and so does editing once mirroring stops
+	e.expect(http.StatusOK, http.MethodGet, lists+"/items", nil, nil)
+	e.expect(http.StatusNoContent, http.MethodDelete, lists+"/remote", nil, nil)
+	e.expect(http.StatusNoContent, http.MethodDelete, items, nil, nil)
+}
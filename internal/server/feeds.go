@@ -0,0 +1,354 @@
+package server
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"whats-next/internal/database"
+)
+
+// maxFeedSize caps how much of a feed response is read.
+const maxFeedSize = 5 << 20
+
+// feedEntry is one title announced by a feed. GUID identifies it across
+// fetches so each entry is only processed once.
+type feedEntry struct {
+	GUID  string
+	Title string
+	Year  int
+}
+
+// feedResult reports what one check of a feed did.
+type feedResult struct {
+	database.Feed
+	New     int `json:"new"`
+	Matched int `json:"matched"`
+	Pending int `json:"pending"`
+}
+
+// titleYearPattern matches entry titles such as "The Matrix (1999)".
+var titleYearPattern = regexp.MustCompile(`^(.+?)\s*\((\d{4})\)\s*$`)
+
+// splitTitleYear separates a trailing "(year)" from a feed entry title.
+func splitTitleYear(title string) (string, int) {
+	title = strings.TrimSpace(title)
+	if m := titleYearPattern.FindStringSubmatch(title); m != nil {
+		year, _ := strconv.Atoi(m[2])
+		return m[1], year
+	}
+	return title, 0
+}
+
+// parseFeed reads RSS 2.0, RSS 1.0, Atom, JSON Feed, or a plain JSON array
+// of {"title", "year"} objects. It returns the feed's title and its entries.
+func parseFeed(data []byte) (string, []feedEntry, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return "", nil, errors.New("feed is empty")
+	}
+	switch data[0] {
+	case '{', '[':
+		return parseJSONFeed(data)
+	case '<':
+		return parseXMLFeed(data)
+	}
+	return "", nil, errors.New("feed is neither XML nor JSON")
+}
+
+func parseXMLFeed(data []byte) (string, []feedEntry, error) {
+	type rssItem struct {
+		Title string `xml:"title"`
+		GUID  string `xml:"guid"`
+		Link  string `xml:"link"`
+	}
+	var doc struct {
+		Title   string `xml:"title"` // Atom
+		Channel struct {
+			Title string    `xml:"title"`
+			Items []rssItem `xml:"item"`
+		} `xml:"channel"`
+		Items   []rssItem `xml:"item"` // RSS 1.0 items sit beside the channel
+		Entries []struct {
+			Title string `xml:"title"`
+			ID    string `xml:"id"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return "", nil, fmt.Errorf("invalid XML feed: %w", err)
+	}
+
+	title := doc.Channel.Title
+	if title == "" {
+		title = doc.Title
+	}
+	var entries []feedEntry
+	for _, it := range append(doc.Channel.Items, doc.Items...) {
+		entries = appendFeedEntry(entries, it.Title, 0, it.GUID, it.Link)
+	}
+	for _, e := range doc.Entries {
+		entries = appendFeedEntry(entries, e.Title, 0, e.ID)
+	}
+	return strings.TrimSpace(title), entries, nil
+}
+
+func parseJSONFeed(data []byte) (string, []feedEntry, error) {
+	type jsonItem struct {
+		ID    json.RawMessage `json:"id"`
+		URL   string          `json:"url"`
+		Title string          `json:"title"`
+		Year  int             `json:"year"`
+	}
+	var doc struct {
+		Title string     `json:"title"`
+		Items []jsonItem `json:"items"`
+	}
+	if data[0] == '[' {
+		if err := json.Unmarshal(data, &doc.Items); err != nil {
+			return "", nil, fmt.Errorf("invalid JSON feed: %w", err)
+		}
+	} else if err := json.Unmarshal(data, &doc); err != nil {
+		return "", nil, fmt.Errorf("invalid JSON feed: %w", err)
+	}
+
+	var entries []feedEntry
+	for _, it := range doc.Items {
+		// JSON Feed IDs are strings, but numbers turn up in the wild
+		id := strings.Trim(string(it.ID), `"`)
+		entries = appendFeedEntry(entries, it.Title, it.Year, id, it.URL)
+	}
+	return strings.TrimSpace(doc.Title), entries, nil
+}
+
+// appendFeedEntry adds an entry identified by the first non-empty id, or by
+// its title when it has none. Entries without a title are skipped.
+func appendFeedEntry(entries []feedEntry, title string, year int, ids ...string) []feedEntry {
+	title, titleYear := splitTitleYear(title)
+	if title == "" {
+		return entries
+	}
+	if year == 0 {
+		year = titleYear
+	}
+	guid := title + "|" + strconv.Itoa(year)
+	for _, id := range ids {
+		if id = strings.TrimSpace(id); id != "" {
+			guid = id
+			break
+		}
+	}
+	return append(entries, feedEntry{GUID: guid, Title: title, Year: year})
+}
+
+// fetchFeed downloads and parses a feed.
+func (s *Server) fetchFeed(feedURL string) (string, []feedEntry, error) {
+	resp, err := s.httpClient.Get(feedURL)
+	if err != nil {
+		return "", nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", nil, fmt.Errorf("feed returned status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFeedSize))
+	if err != nil {
+		return "", nil, err
+	}
+	return parseFeed(data)
+}
+
+// checkFeed processes the entries of a feed it hasn't seen before: titles in
+// the library are appended to the list, the rest are kept as pending matches
+// and picked up by a later sync. Matches are announced in one notification.
+func (s *Server) checkFeed(feed database.Feed) (feedResult, error) {
+	result := feedResult{Feed: feed}
+	list, err := s.db.GetList(feed.ListID)
+	if err != nil {
+		return result, err
+	}
+
+	title, entries, err := s.fetchFeed(feed.URL)
+	if err != nil {
+		slog.Warn("Failed to fetch feed", "list_id", feed.ListID, "url", feed.URL, "error", err)
+		if err := s.db.UpdateFeedStatus(feed.ID, "", err.Error()); err != nil {
+			slog.Error("Failed to record feed status", "feed_id", feed.ID, "error", err)
+		}
+		if f, err := s.db.GetFeed(feed.ListID, feed.ID); err == nil {
+			result.Feed = *f
+		}
+		return result, err
+	}
+
+	cacheType := cacheTypeFor(list.ContentType)
+	var added []string
+	for _, e := range entries {
+		isNew, err := s.db.MarkFeedEntry(feed.ID, e.GUID)
+		if err != nil {
+			slog.Error("Failed to record feed entry", "feed_id", feed.ID, "guid", e.GUID, "error", err)
+			return result, err
+		}
+		if !isNew {
+			continue
+		}
+		result.New++
+
+		cached, err := s.db.FindCachedMatch(list.ID, cacheType, e.Title, e.Year)
+		if err == nil {
+			item := itemFromCache(list.ID, cached)
+			if _, err := s.db.AddItem(item); errors.Is(err, database.ErrDuplicateItem) {
+				continue
+			} else if err != nil {
+				slog.Error("Failed to add feed title", "list_id", list.ID, "title", e.Title, "error", err)
+				continue
+			}
+			s.prewarmShow(item)
+			result.Matched++
+			added = append(added, cached.Title)
+			continue
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			slog.Error("Failed to match feed title", "list_id", list.ID, "title", e.Title, "error", err)
+		}
+		pending := database.PendingMatch{ListID: list.ID, Source: "feed", Title: e.Title, Year: e.Year}
+		if err := s.db.AddPendingMatch(pending); err != nil {
+			slog.Error("Failed to store pending match", "list_id", list.ID, "title", e.Title, "error", err)
+			continue
+		}
+		result.Pending++
+	}
+
+	if err := s.db.UpdateFeedStatus(feed.ID, title, ""); err != nil {
+		slog.Error("Failed to record feed status", "feed_id", feed.ID, "error", err)
+	}
+	if f, err := s.db.GetFeed(feed.ListID, feed.ID); err == nil {
+		result.Feed = *f
+	}
+
+	if len(added) > 0 {
+		name := result.Title
+		if name == "" {
+			name = feed.URL
+		}
+		msg := fmt.Sprintf("%d title(s) from %s were added to %s: %s", len(added), name, list.Name, strings.Join(added, ", "))
+		if err := s.db.AddNotification(database.NotificationFeedMatched, list.ID, msg); err != nil {
+			slog.Error("Failed to add notification", "error", err)
+		}
+	}
+	slog.Info("Checked feed", "list_id", list.ID, "url", feed.URL, "new", result.New, "matched", result.Matched, "pending", result.Pending)
+	return result, nil
+}
+
+// refreshFeeds checks every list's feeds, for the scheduled job.
+func (s *Server) refreshFeeds() {
+	feeds, err := s.db.GetAllFeeds()
+	if err != nil {
+		slog.Error("Failed to get feeds", "error", err)
+		return
+	}
+	for _, f := range feeds {
+		s.checkFeed(f) // failures are logged and kept on the feed
+	}
+}
+
+// handleFeeds manages a list's feed subscriptions:
+//
+//	GET    /lists/{id}/feeds                 list them
+//	POST   /lists/{id}/feeds                 subscribe {"url"} and check it
+//	POST   /lists/{id}/feeds/{feedID}/check  check for new entries now
+//	DELETE /lists/{id}/feeds/{feedID}        unsubscribe
+func (s *Server) handleFeeds(w http.ResponseWriter, r *http.Request, listID int64, rest []string) {
+	if len(rest) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			feeds, err := s.db.GetFeeds(listID)
+			if err != nil {
+				writeDBError(w, err, "Failed to retrieve feeds", "list_id", listID)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(feeds)
+		case http.MethodPost:
+			s.createFeed(w, r, listID)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	feedID, err := strconv.ParseInt(rest[0], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid feed ID", http.StatusBadRequest)
+		return
+	}
+	switch {
+	case len(rest) == 1 && r.Method == http.MethodDelete:
+		if err := s.db.DeleteFeed(listID, feedID); err != nil {
+			writeDBError(w, err, "Failed to delete feed", "list_id", listID, "feed_id", feedID)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case len(rest) == 2 && rest[1] == "check" && r.Method == http.MethodPost:
+		feed, err := s.db.GetFeed(listID, feedID)
+		if err != nil {
+			writeDBError(w, err, "Failed to retrieve feed", "list_id", listID, "feed_id", feedID)
+			return
+		}
+		s.writeFeedCheck(w, *feed, http.StatusOK)
+	case len(rest) <= 2:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) createFeed(w http.ResponseWriter, r *http.Request, listID int64) {
+	var req struct {
+		URL string `json:"url"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	u, err := url.Parse(strings.TrimSpace(req.URL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		http.Error(w, "url must be an http or https URL", http.StatusBadRequest)
+		return
+	}
+
+	id, err := s.db.AddFeed(database.Feed{ListID: listID, URL: u.String()})
+	if err != nil {
+		writeDBError(w, err, "Failed to add feed", "list_id", listID)
+		return
+	}
+	feed, err := s.db.GetFeed(listID, id)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve feed", "list_id", listID, "feed_id", id)
+		return
+	}
+	slog.Info("Subscribed list to feed", "list_id", listID, "url", feed.URL)
+	s.writeFeedCheck(w, *feed, http.StatusCreated)
+}
+
+// writeFeedCheck checks a feed and reports the result. A feed that can't be
+// fetched is still reported, with last_error set, since the subscription
+// stays and is retried on schedule.
+func (s *Server) writeFeedCheck(w http.ResponseWriter, feed database.Feed, status int) {
+	result, err := s.checkFeed(feed)
+	if errors.Is(err, database.ErrListNotFound) {
+		writeDBError(w, err, "Failed to check feed")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result)
+}
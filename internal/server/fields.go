@@ -0,0 +1,77 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// requestedFields returns the keys asked for with ?fields=id,title,..., or
+// nil when every field is wanted.
+func requestedFields(r *http.Request) map[string]bool {
+	names := queryValues(r.URL.Query(), "fields")
+	if len(names) == 0 {
+		return nil
+	}
+	fields := make(map[string]bool, len(names))
+	for _, name := range names {
+		fields[name] = true
+	}
+	return fields
+}
+
+// encodeFields writes v, which must encode as an array of objects, keeping
+// only the given fields of each object; with no fields v is written whole.
+// When nested is set, the outer objects are kept whole and the fields are
+// picked from the objects in their nested array instead, e.g. the items of
+// each franchise group. Fields an object doesn't have are left out.
+func encodeFields(w http.ResponseWriter, v interface{}, fields map[string]bool, nested string) {
+	selected, err := selectFields(v, fields, nested)
+	if err != nil {
+		slog.Error("Failed to select response fields", "error", err)
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(selected)
+}
+
+// selectFields is encodeFields without the writing, for responses that wrap
+// the array in an envelope.
+func selectFields(v interface{}, fields map[string]bool, nested string) (interface{}, error) {
+	if fields == nil {
+		return v, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var objects []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &objects); err != nil {
+		return nil, err
+	}
+	for i, obj := range objects {
+		if nested == "" {
+			objects[i] = pickFields(obj, fields)
+			continue
+		}
+		var inner []map[string]json.RawMessage
+		if err := json.Unmarshal(obj[nested], &inner); err != nil {
+			continue
+		}
+		for j := range inner {
+			inner[j] = pickFields(inner[j], fields)
+		}
+		obj[nested], _ = json.Marshal(inner)
+	}
+	return objects, nil
+}
+
+func pickFields(obj map[string]json.RawMessage, fields map[string]bool) map[string]json.RawMessage {
+	picked := make(map[string]json.RawMessage, len(fields))
+	for name := range fields {
+		if v, ok := obj[name]; ok {
+			picked[name] = v
+		}
+	}
+	return picked
+}
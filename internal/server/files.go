@@ -0,0 +1,150 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"whats-next/internal/database"
+	"whats-next/internal/kodi"
+)
+
+// errOutsideSources rejects file paths that aren't within any of Kodi's
+// video sources.
+var errOutsideSources = errors.New("path is not within a Kodi video source")
+
+// sourceFor returns the video source containing path.
+func sourceFor(client *kodi.Client, path string) (*kodi.Source, error) {
+	sources, err := client.GetSources()
+	if err != nil {
+		return nil, err
+	}
+	for _, src := range sources {
+		if src.File != "" && strings.HasPrefix(path, src.File) && !strings.Contains(path, "..") {
+			return &src, nil
+		}
+	}
+	return nil, errOutsideSources
+}
+
+// fileLabel names a file item after its file, without the extension.
+func fileLabel(path string) string {
+	name := strings.TrimRight(path, `/\`)
+	name = name[strings.LastIndexAny(name, `/\`)+1:]
+	if dot := strings.LastIndex(name, "."); dot > 0 {
+		name = name[:dot]
+	}
+	return name
+}
+
+// handleFiles browses the video sources of a list's Kodi host, for adding
+// files that were never scanned into the library, such as home videos:
+// GET /library/files?list_id= returns the sources, and &path= the files and
+// subdirectories of a directory within one.
+func (s *Server) handleFiles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	listID, err := strconv.ParseInt(r.URL.Query().Get("list_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid list_id", http.StatusBadRequest)
+		return
+	}
+	client, err := s.getKodiClient(listID)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve list", "list_id", listID)
+		return
+	}
+
+	path := r.URL.Query().Get("path")
+	var result interface{}
+	if path == "" {
+		result, err = client.GetSources()
+	} else if _, err = sourceFor(client, path); err == nil {
+		result, err = client.GetDirectory(path)
+	}
+	if errors.Is(err, errOutsideSources) {
+		http.Error(w, "path must be within one of Kodi's video sources", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to browse Kodi files", "list_id", listID, "path", path, "error", err)
+		writeKodiError(w, err, "Failed to browse files", http.StatusBadGateway)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// prepareFileItem checks that a file item's path, which must be set, is
+// within one of the list's Kodi sources and fills in its title from the file
+// name if unset.
+func (s *Server) prepareFileItem(item *database.Item) error {
+	item.FilePath = strings.TrimSpace(item.FilePath)
+	client, err := s.getKodiClient(item.ListID)
+	if err != nil {
+		return err
+	}
+	if _, err := sourceFor(client, item.FilePath); err != nil {
+		return err
+	}
+	item.Title = strings.TrimSpace(item.Title)
+	if item.Title == "" {
+		item.Title = fileLabel(item.FilePath)
+	}
+	item.KodiID, item.Wanted, item.Season, item.Episode, item.TVShowID = 0, false, 0, 0, 0
+	return nil
+}
+
+// handlePlayItem starts playback of a single list item on its list's
+// backend: POST /items/{id}/play. File items are opened by path. Like
+// queueing, it won't interrupt another kind of player on Kodi, such as
+// music during a movie, unless ?player_id names it.
+func (s *Server) handlePlayItem(w http.ResponseWriter, r *http.Request, itemID int64) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	want, err := playerParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	item, err := s.db.GetItem(itemID)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve item", "item_id", itemID)
+		return
+	}
+	pi, ok := playlistItemFor(*item)
+	if !ok {
+		http.Error(w, "Item is not playable", http.StatusUnprocessableEntity)
+		return
+	}
+	b, err := s.getBackend(item.ListID)
+	if err != nil {
+		writeDBError(w, err, "Failed to connect to Kodi", "list_id", item.ListID)
+		return
+	}
+	if client, ok := b.(*kodi.Client); ok {
+		if busy, err := otherPlayer(client, want, playerKindFor(item.MediaType)); err != nil {
+			slog.Error("Failed to get active players from Kodi", "list_id", item.ListID, "error", err)
+			writeKodiError(w, err, "Failed to fetch active players", http.StatusBadGateway)
+			return
+		} else if busy != nil {
+			http.Error(w, fmt.Sprintf("Kodi is playing %s (player %d); pass player_id=%d to replace it", busy.Type, busy.PlayerID, busy.PlayerID), http.StatusConflict)
+			return
+		}
+	}
+	if err := b.Play(pi); err != nil {
+		slog.Error("Failed to start playback in Kodi", "item_id", itemID, "error", err)
+		writeKodiError(w, err, "Failed to start playback", http.StatusBadGateway)
+		return
+	}
+	slog.Info("Started playback of list item", "item_id", itemID, "list_id", item.ListID)
+	w.WriteHeader(http.StatusNoContent)
+}
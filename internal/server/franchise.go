@@ -0,0 +1,103 @@
+package server
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"whats-next/internal/database"
+)
+
+// franchiseGroup is a run of list items from the same franchise, or a
+// single item that isn't part of one (Franchise empty).
+type franchiseGroup struct {
+	Franchise string          `json:"franchise,omitempty"`
+	SetID     int             `json:"set_id,omitempty"`
+	SectionID int64           `json:"section_id,omitempty"`
+	Items     []database.Item `json:"items"`
+}
+
+var (
+	// Sequel markers trailing a title: "2", "III", "Part 2", "Chapter 3"
+	sequelSuffix = regexp.MustCompile(`(?i)\s+(?:(?:part|chapter|vol\.?|volume)\s+)?(?:\d+|[ivx]+)$`)
+	// Words Kodi scrapers append to set names
+	setSuffix = regexp.MustCompile(`(?i)\s+(?:collection|saga|trilogy|series|anthology)$`)
+)
+
+// franchiseName guesses the franchise of a title without a movie set:
+// the part before a colon ("Star Trek: Picard") or the title without a
+// trailing sequel number ("Toy Story 2").
+func franchiseName(title string) string {
+	if before, _, ok := strings.Cut(title, ":"); ok && strings.TrimSpace(before) != "" {
+		return strings.TrimSpace(before)
+	}
+	return strings.TrimSpace(sequelSuffix.ReplaceAllString(title, ""))
+}
+
+// franchiseKey normalizes a franchise or set name for comparison, so "The
+// Matrix Collection" and "The Matrix" match.
+func franchiseKey(name string) string {
+	key := strings.ToLower(strings.TrimSpace(setSuffix.ReplaceAllString(name, "")))
+	return strings.TrimPrefix(key, "the ")
+}
+
+// groupByFranchise clusters items of the same franchise, taken from their
+// Kodi movie set or guessed from their titles, within each section. Groups
+// appear where their first item stands and keep the manual order inside;
+// franchises with a single item on the list aren't grouped.
+func groupByFranchise(items []database.Item, sets map[int64]database.MovieSetRef) []franchiseGroup {
+	type member struct {
+		key  string
+		name string
+		set  int
+	}
+	members := make([]member, len(items))
+	counts := map[string]int{}
+	for i, item := range items {
+		name, setID := franchiseName(item.Title), 0
+		switch {
+		case item.MediaType == "set":
+			name, setID = item.Title, item.KodiID
+		case item.MediaType == "episode" && item.ShowTitle != "":
+			name = franchiseName(item.ShowTitle)
+		default:
+			if set, ok := sets[item.ID]; ok {
+				name, setID = set.Title, set.ID
+			}
+		}
+		key := franchiseKey(name)
+		members[i] = member{key: key, name: name, set: setID}
+		if key != "" {
+			counts[franchiseSectionKey(item.SectionID, key)]++
+		}
+	}
+
+	groups := []franchiseGroup{}
+	index := map[string]int{}
+	for i, item := range items {
+		m := members[i]
+		sectionKey := franchiseSectionKey(item.SectionID, m.key)
+		if m.key == "" || counts[sectionKey] < 2 {
+			groups = append(groups, franchiseGroup{SectionID: item.SectionID, Items: []database.Item{item}})
+			continue
+		}
+		g, ok := index[sectionKey]
+		if !ok {
+			g = len(groups)
+			index[sectionKey] = g
+			groups = append(groups, franchiseGroup{Franchise: m.name, SectionID: item.SectionID})
+		}
+		// A movie set's name beats one guessed from a title
+		if m.set != 0 && groups[g].SetID == 0 {
+			groups[g].Franchise, groups[g].SetID = m.name, m.set
+		}
+		groups[g].Items = append(groups[g].Items, item)
+	}
+	return groups
+}
+
+// franchiseSectionKey scopes a franchise key to a section, since groups never
+// span sections.
+func franchiseSectionKey(sectionID int64, key string) string {
+	return fmt.Sprintf("%d/%s", sectionID, key)
+}
@@ -0,0 +1,179 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"whats-next/internal/database"
+)
+
+// groupView is a group as the API returns it: credentials hidden as for
+// lists, its position always shown, and the number of lists in it.
+type groupView struct {
+	database.Group
+	Username       string `json:"username,omitempty"`
+	Password       string `json:"password,omitempty"`
+	SortOrder      int    `json:"sort_order"`
+	HasCredentials bool   `json:"has_credentials"`
+	Lists          int    `json:"lists"`
+}
+
+// handleGroups manages list groups, shown in their order as the UI's tabs:
+//
+//	GET    /groups         list them in display order
+//	POST   /groups         add one {"group_name", "kodi_host", "username", "password"}
+//	PUT    /groups/order   set the order {"group_ids": [...]}; others follow
+//	PATCH  /groups/{id}    rename it {"group_name"}, moving its lists along
+//	DELETE /groups/{id}    remove a group without lists
+//
+// Groups defined in config.json can't be renamed or removed here, since the
+// next start would bring them back.
+func (s *Server) handleGroups(w http.ResponseWriter, r *http.Request) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/groups"), "/")
+	switch {
+	case rest == "" && r.Method == http.MethodGet:
+		s.writeGroups(w)
+	case rest == "" && r.Method == http.MethodPost:
+		var g database.Group
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&g); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if g.Name = strings.TrimSpace(g.Name); g.Name == "" {
+			http.Error(w, "group_name is required", http.StatusBadRequest)
+			return
+		}
+		id, err := s.db.AddGroup(g)
+		if err != nil {
+			writeDBError(w, err, "Failed to add group")
+			return
+		}
+		slog.Info("Added group", "group_id", id, "group", g.Name)
+		s.writeGroup(w, id, http.StatusCreated)
+	case rest == "order":
+		if r.Method != http.MethodPut {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			GroupIDs []int64 `json:"group_ids"`
+		}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := s.db.ReorderGroups(req.GroupIDs); err != nil {
+			writeDBError(w, err, "Failed to reorder groups")
+			return
+		}
+		s.writeGroups(w)
+	case rest != "" && !strings.Contains(rest, "/"):
+		id, err := strconv.ParseInt(rest, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid group ID", http.StatusBadRequest)
+			return
+		}
+		s.handleGroup(w, r, id)
+	case rest == "":
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// handleGroup renames or removes one group.
+func (s *Server) handleGroup(w http.ResponseWriter, r *http.Request, id int64) {
+	if r.Method != http.MethodPatch && r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	g, err := s.db.GetGroup(id)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve group", "group_id", id)
+		return
+	}
+	if slices.ContainsFunc(s.config.Groups, func(c database.Group) bool { return c.Name == g.Name }) {
+		writeDBError(w, database.ErrGroupFromConfig, "")
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		if err := s.db.DeleteGroup(id); err != nil {
+			writeDBError(w, err, "Failed to delete group", "group_id", id)
+			return
+		}
+		slog.Info("Deleted group", "group_id", id, "group", g.Name)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var req struct {
+		Name string `json:"group_name"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if req.Name = strings.TrimSpace(req.Name); req.Name == "" {
+		http.Error(w, "group_name is required", http.StatusBadRequest)
+		return
+	}
+	if err := s.db.RenameGroup(id, req.Name); err != nil {
+		writeDBError(w, err, "Failed to rename group", "group_id", id)
+		return
+	}
+	slog.Info("Renamed group", "group_id", id, "from", g.Name, "to", req.Name)
+	s.writeGroup(w, id, http.StatusOK)
+}
+
+// groupViews returns every group in display order with its list count.
+func (s *Server) groupViews() ([]groupView, error) {
+	groups, err := s.db.GetGroups()
+	if err != nil {
+		return nil, err
+	}
+	lists, err := s.db.GetAllLists()
+	if err != nil {
+		return nil, err
+	}
+	counts := map[string]int{}
+	for _, l := range lists {
+		counts[l.GroupName]++
+	}
+	views := make([]groupView, 0, len(groups))
+	for _, g := range groups {
+		views = append(views, groupView{Group: g, SortOrder: g.SortOrder, HasCredentials: g.Username != "" || g.Password != "", Lists: counts[g.Name]})
+	}
+	return views, nil
+}
+
+func (s *Server) writeGroups(w http.ResponseWriter) {
+	views, err := s.groupViews()
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve groups")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(views)
+}
+
+func (s *Server) writeGroup(w http.ResponseWriter, id int64, status int) {
+	views, err := s.groupViews()
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve groups")
+		return
+	}
+	for _, v := range views {
+		if v.ID == id {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			json.NewEncoder(w).Encode(v)
+			return
+		}
+	}
+	writeDBError(w, database.ErrGroupNotFound, "")
+}
@@ -0,0 +1,176 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"whats-next/internal/database"
+	"whats-next/internal/kodi"
+)
+
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 500
+)
+
+// refreshHistory records new plays from every Kodi host into the viewing
+// history.
+func (s *Server) refreshHistory() {
+	lists, err := s.db.GetAllLists()
+	if err != nil {
+		slog.Error("Failed to get lists for history refresh", "error", err)
+		return
+	}
+	seen := map[string]bool{}
+	for _, l := range lists {
+		if seen[l.KodiHost] || l.Backend == database.BackendJellyfin {
+			continue
+		}
+		seen[l.KodiHost] = true
+		if _, err := s.pullHistory(l.ID); err != nil {
+			slog.Warn("Failed to pull viewing history", "host", l.KodiHost, "error", err)
+		}
+	}
+}
+
+// pullHistory records the movies and episodes played on the Kodi host of
+// listID since the last recorded play of each, and returns how many plays
+// were new. The host is read from the list each time so plays land on the
+// current host after a rehost.
+func (s *Server) pullHistory(listID int64) (int, error) {
+	list, err := s.db.GetList(listID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get list %d: %w", listID, err)
+	}
+	host := list.KodiHost
+	client, err := s.kodiClient(host, list.Username, list.Password, listKodiOptions(list))
+	if err != nil {
+		return 0, err
+	}
+
+	added := 0
+	for _, mediaType := range []string{"movie", "episode"} {
+		since, err := s.db.HistoryWatermark(host, mediaType)
+		if err != nil {
+			return added, err
+		}
+		var played []kodi.MediaItem
+		if mediaType == "movie" {
+			played, err = client.GetPlayedMovies(since)
+		} else {
+			played, err = client.GetPlayedEpisodes(since)
+		}
+		if err != nil {
+			return added, err
+		}
+
+		views := make([]database.Viewing, 0, len(played))
+		for _, p := range played {
+			if p.LastPlayed == "" {
+				continue
+			}
+			views = append(views, database.Viewing{
+				MediaType: mediaType, KodiID: p.ID, TVShowID: p.TVShowID, Title: p.Title, ShowTitle: p.ShowTitle,
+				Season: p.Season, Episode: p.Episode, Year: p.Year, Runtime: p.Runtime,
+				PlayedAt: p.LastPlayed, Playcount: p.Playcount, ResumePosition: p.ResumePoint(),
+			})
+		}
+		n, err := s.db.RecordViewings(host, views)
+		if err != nil {
+			return added, err
+		}
+		added += n
+	}
+	if added > 0 {
+		slog.Info("Recorded viewing history", "host", host, "plays", added)
+	}
+	return added, nil
+}
+
+// handleHistory serves the viewing history of a list's Kodi host:
+// GET /lists/{id}/history (?media_type=movie|episode, limit, offset),
+// GET /lists/{id}/history/stats (?days=30) and POST /lists/{id}/history/refresh
+// to pull new plays right away.
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request, listID int64, rest []string) {
+	switch {
+	case len(rest) == 0 && r.Method == http.MethodGet:
+		s.writeHistory(w, r, listID)
+	case len(rest) == 1 && rest[0] == "stats" && r.Method == http.MethodGet:
+		s.writeHistoryStats(w, r, listID)
+	case len(rest) == 1 && rest[0] == "refresh" && r.Method == http.MethodPost:
+		if _, err := s.db.GetList(listID); err != nil {
+			writeDBError(w, err, "Failed to retrieve list", "list_id", listID)
+			return
+		}
+		added, err := s.pullHistory(listID)
+		if err != nil {
+			slog.Error("Failed to pull viewing history", "list_id", listID, "error", err)
+			writeKodiError(w, err, "Failed to pull history from Kodi", http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]int{"added": added})
+	case len(rest) <= 1:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) writeHistory(w http.ResponseWriter, r *http.Request, listID int64) {
+	q := r.URL.Query()
+	mediaType := q.Get("media_type")
+	if mediaType != "" && mediaType != "movie" && mediaType != "episode" {
+		http.Error(w, "media_type must be movie or episode", http.StatusBadRequest)
+		return
+	}
+	limit, offset := defaultHistoryLimit, 0
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "limit must be a positive number", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, maxHistoryLimit)
+	}
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "Invalid offset", http.StatusBadRequest)
+			return
+		}
+		offset = n
+	}
+
+	history, err := s.db.GetHistory(listID, mediaType, limit, offset)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve history", "list_id", listID)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
+
+func (s *Server) writeHistoryStats(w http.ResponseWriter, r *http.Request, listID int64) {
+	since := ""
+	if v := r.URL.Query().Get("days"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil || days <= 0 {
+			http.Error(w, "days must be a positive number", http.StatusBadRequest)
+			return
+		}
+		// Kodi records local times; the server's clock is close enough
+		since = time.Now().AddDate(0, 0, -days).Format(time.DateTime)
+	}
+	stats, err := s.db.GetHistoryStats(listID, since)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve history stats", "list_id", listID)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
@@ -0,0 +1,274 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"whats-next/internal/database"
+	"whats-next/internal/kodi"
+)
+
+// hostMigrationInterval is how often pending host migrations are retried,
+// e.g. while the new Kodi box is still switched off.
+const hostMigrationInterval = 5 * time.Minute
+
+// runHostMigrations runs a full sync for every list whose host changed and
+// hasn't been migrated yet; the sync re-resolves its items.
+func (s *Server) runHostMigrations() {
+	migrations, err := s.db.PendingHostMigrations()
+	if err != nil {
+		slog.Error("Failed to load pending host migrations", "error", err)
+		return
+	}
+	for _, m := range migrations {
+		list, err := s.db.GetList(m.ListID)
+		if err != nil {
+			slog.Error("Failed to load list for host migration", "list_id", m.ListID, "error", err)
+			continue
+		}
+		if _, err := s.syncLibrary(list.ID, list.ContentType, true); err != nil && !errors.Is(err, errSyncInProgress) {
+			slog.Warn("Host migration sync failed, will retry", "list_id", list.ID, "host", m.ToHost, "error", err)
+		}
+	}
+}
+
+// startHostMigration starts a background task running a full sync of list,
+// which re-resolves its items on its new host. The task's result is the
+// migration's outcome.
+func (s *Server) startHostMigration(list *database.List) task {
+	return s.startTask("host_migration", func() (interface{}, error) {
+		if _, err := s.syncLibrary(list.ID, list.ContentType, true); err != nil {
+			return nil, err
+		}
+		return s.db.GetHostMigration(list.ID)
+	})
+}
+
+// migrateHostItems re-resolves the items of lists that moved to listID's
+// Kodi host, once its library cache of mediaType has been synced. It runs
+// before the sync's item updates so they never apply another title's state
+// through a stale Kodi ID.
+func (s *Server) migrateHostItems(listID int64, mediaType string) {
+	migrations, err := s.db.PendingHostMigrationsForHost(listID)
+	if err != nil {
+		slog.Error("Failed to load pending host migrations", "list_id", listID, "error", err)
+		return
+	}
+	for _, m := range migrations {
+		list, err := s.db.GetList(m.ListID)
+		if err != nil {
+			slog.Error("Failed to load list for host migration", "list_id", m.ListID, "error", err)
+			continue
+		}
+		if cacheTypeFor(list.ContentType) != mediaType {
+			continue
+		}
+		if err := s.remapListItems(list, &m); err != nil {
+			slog.Error("Host migration failed, will retry", "list_id", list.ID, "host", m.ToHost, "error", err)
+			continue
+		}
+		if err := s.db.FinishHostMigration(m); err != nil {
+			slog.Error("Failed to record host migration", "list_id", list.ID, "error", err)
+			continue
+		}
+		slog.Info("Migrated list to new Kodi host", "list_id", list.ID, "from", m.FromHost, "to", m.ToHost, "matched", m.Matched, "unmatched", len(m.Unmatched))
+		if len(m.Unmatched) > 0 {
+			msg := fmt.Sprintf("%d items on %s weren't found on %s", len(m.Unmatched), list.Name, m.ToHost)
+			if err := s.db.AddNotification(database.NotificationHostMigration, list.ID, msg); err != nil {
+				slog.Error("Failed to add notification", "error", err)
+			}
+		}
+	}
+}
+
+// remapListItems matches each item of list, archived ones included, against
+// the new host's library by IMDb ID or title and year, and points it at the
+// matched media and its artwork. Episodes are matched by show, season and
+// episode number. Movies and shows with no match become wanted items, so a
+// later sync links them when they turn up; anything else is flagged missing.
+// The outcome is recorded on m.
+func (s *Server) remapListItems(list *database.List, m *database.HostMigration) error {
+	items, err := s.db.GetItems(list.ID)
+	if err != nil {
+		return err
+	}
+	archived, err := s.db.GetArchivedItems(list.ID)
+	if err != nil {
+		return err
+	}
+	items = append(items, archived...)
+
+	matcher := s.newHostMatcher(list)
+	m.Matched, m.Unmatched = 0, []database.UnmatchedItem{}
+	for _, item := range items {
+		if item.Wanted {
+			continue
+		}
+		match, ok, err := matcher.match(item)
+		if err != nil {
+			return err
+		} else if !ok {
+			continue
+		}
+
+		reason := match.reason
+		if match.kodiID != 0 {
+			err := s.db.RemapItem(item.ID, match.kodiID, match.tvshowID, match.poster)
+			if err == nil {
+				m.Matched++
+				continue
+			}
+			if !errors.Is(err, database.ErrDuplicateItem) {
+				return err
+			}
+			reason = "Matched media is already on the list"
+		}
+		m.Unmatched = append(m.Unmatched, database.UnmatchedItem{
+			ItemID: item.ID, MediaType: item.MediaType, Title: itemTitle(item), Year: item.Year, Reason: reason,
+		})
+		if item.MediaType == "movie" || item.MediaType == "show" {
+			err = s.db.UnlinkItem(item.ID)
+		} else {
+			err = s.db.FlagItemMissing(item.ID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hostMatch is the media an item was matched to on a host, or the reason
+// none was.
+type hostMatch struct {
+	kodiID, tvshowID int
+	poster           string
+	reason           string
+}
+
+// hostMatcher matches items against the library of a list's host, as its
+// library cache holds it. Episodes are listed from Kodi, once per show and
+// season.
+type hostMatcher struct {
+	s        *Server
+	list     *database.List
+	client   *kodi.Client
+	episodes map[[2]int][]kodi.MediaItem
+}
+
+func (s *Server) newHostMatcher(list *database.List) *hostMatcher {
+	return &hostMatcher{s: s, list: list, episodes: map[[2]int][]kodi.MediaItem{}}
+}
+
+// match finds item on the host: movies, shows and seasons by IMDb ID or
+// title and year, sets by name, and episodes by show, season and episode
+// number. It returns false for kinds of item it doesn't match, such as
+// files.
+func (h *hostMatcher) match(item database.Item) (hostMatch, bool, error) {
+	var m hostMatch
+	switch item.MediaType {
+	case "movie", "show", "season":
+		cacheType := item.MediaType
+		if cacheType == "season" {
+			cacheType = "show"
+		}
+		cached, err := h.s.matchImport(h.list.ID, cacheType, item.Title, item.Year, item.IMDbID)
+		if errors.Is(err, sql.ErrNoRows) {
+			m.reason = "Not in the new host's library"
+			break
+		} else if err != nil {
+			return m, true, err
+		}
+		m.kodiID, m.poster = cached.KodiID, cached.Poster
+	case "set":
+		m.kodiID = h.s.matchMovieSet(h.list.ID, item.Title)
+		if m.kodiID == 0 {
+			m.reason = "No movie set with this name on the new host"
+		}
+	case "episode":
+		show, err := h.s.db.FindCachedMatch(h.list.ID, "show", item.ShowTitle, 0)
+		if errors.Is(err, sql.ErrNoRows) {
+			m.reason = "Show not in the new host's library"
+			break
+		} else if err != nil {
+			return m, true, err
+		}
+		key := [2]int{show.KodiID, item.Season}
+		if _, ok := h.episodes[key]; !ok {
+			if h.client == nil {
+				if h.client, err = h.s.getKodiClient(h.list.ID); err != nil {
+					return m, true, err
+				}
+			}
+			if h.episodes[key], err = h.client.GetEpisodes(show.KodiID, item.Season); err != nil {
+				return m, true, fmt.Errorf("failed to list episodes of %s: %w", show.Title, err)
+			}
+		}
+		for _, ep := range h.episodes[key] {
+			if ep.Episode == item.Episode {
+				m.kodiID, m.tvshowID, m.poster = ep.ID, show.KodiID, show.Poster
+			}
+		}
+		if m.kodiID == 0 {
+			m.reason = "Episode not in the new host's library"
+		}
+	default:
+		return m, false, nil
+	}
+	return m, true, nil
+}
+
+// matchMovieSet returns the ID of the movie set named title in the list's
+// cached library, or zero.
+func (s *Server) matchMovieSet(listID int64, title string) int {
+	cached, err := s.db.GetLibraryCache(listID, "movie")
+	if err != nil {
+		slog.Warn("Failed to read library cache", "list_id", listID, "error", err)
+		return 0
+	}
+	for _, c := range cached {
+		if c.SetID != 0 && strings.EqualFold(c.SetTitle, title) {
+			return c.SetID
+		}
+	}
+	return 0
+}
+
+// handleHostMigration reports the latest host migration of a list:
+// GET /lists/{id}/migration. POST re-runs a pending one now.
+func (s *Server) handleHostMigration(w http.ResponseWriter, r *http.Request, listID int64) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	list, err := s.db.GetList(listID)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve list", "list_id", listID)
+		return
+	}
+	m, err := s.db.GetHostMigration(listID)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve host migration", "list_id", listID)
+		return
+	}
+	if m == nil {
+		http.Error(w, "The list's Kodi host has not changed", http.StatusNotFound)
+		return
+	}
+	if r.Method == http.MethodPost {
+		if m.Status != "pending" {
+			http.Error(w, "The host migration has already finished", http.StatusConflict)
+			return
+		}
+		writeTaskAccepted(w, s.startHostMigration(list))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(m)
+}
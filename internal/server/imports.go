@@ -0,0 +1,351 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"whats-next/internal/database"
+)
+
+type importRow struct {
+	Title      string
+	Year       int
+	ExternalID string
+	// KodiID is set for rows read from Kodi itself, such as its playlists.
+	KodiID int
+}
+
+type importResult struct {
+	Source  string                  `json:"source"`
+	Matched []database.Item         `json:"matched"`
+	Pending []database.PendingMatch `json:"pending"`
+	// Skipped are titles of another kind than the list holds, such as
+	// episodes in a playlist imported into a movie list.
+	Skipped []string `json:"skipped,omitempty"`
+	DryRun  bool     `json:"dry_run,omitempty"`
+}
+
+// cacheTypeFor maps a list content_type to the media_type used in
+// library_cache. Music lists hold albums by default; "album" and "artist"
+// name their cache types directly.
+func cacheTypeFor(contentType string) string {
+	switch contentType {
+	case "tv":
+		return "show"
+	case "music":
+		return "album"
+	case "album", "artist":
+		return contentType
+	}
+	return "movie"
+}
+
+func itemFromCache(listID int64, c *database.CachedItem) database.Item {
+	return database.Item{
+		ListID:            listID,
+		KodiID:            c.KodiID,
+		MediaType:         c.MediaType,
+		Title:             c.Title,
+		Year:              c.Year,
+		Poster:            c.Poster,
+		Runtime:           c.Runtime,
+		EpisodeCount:      c.EpisodeCount,
+		Rating:            c.Rating,
+		AudioLanguages:    c.AudioLanguages,
+		SubtitleLanguages: c.SubtitleLanguages,
+		WatchedEpisodes:   c.WatchedEpisodes,
+		Watched:           c.Playcount > 0,
+		Playcount:         c.Playcount,
+		LastPlayed:        c.LastPlayed,
+		ResumePosition:    c.ResumePosition,
+		IMDbID:            c.IMDbID,
+		TMDbID:            c.TMDbID,
+		Resolution:        c.Resolution,
+		HDRType:           c.HDRType,
+		Quality:           c.Quality,
+		Artist:            c.Artist,
+	}
+}
+
+// matchImport finds the library item for an imported title, preferring its
+// IMDb ID when the export carried one since titles are often localised.
+func (s *Server) matchImport(listID int64, cacheType, title string, year int, imdbID string) (*database.CachedItem, error) {
+	if strings.HasPrefix(imdbID, "tt") {
+		cached, err := s.db.FindCachedByIMDbID(listID, cacheType, imdbID)
+		if !errors.Is(err, sql.ErrNoRows) {
+			return cached, err
+		}
+	}
+	return s.db.FindCachedMatch(listID, cacheType, title, year)
+}
+
+// parseImportCSV reads IMDb ("Const","Title","Year"), Letterboxd
+// ("Name","Year","Letterboxd URI") or plain title,year CSV exports.
+func parseImportCSV(r io.Reader) ([]importRow, string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	titleCol, yearCol, idCol := -1, -1, -1
+	source := "csv"
+	for i, h := range header {
+		switch strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff"))) {
+		case "title", "name":
+			if titleCol == -1 {
+				titleCol = i
+			}
+		case "year":
+			yearCol = i
+		case "const":
+			idCol = i
+			source = "imdb"
+		case "letterboxd uri":
+			source = "letterboxd"
+		}
+	}
+	if titleCol == -1 {
+		return nil, "", errors.New("the CSV has no title or name column")
+	}
+
+	var rows []importRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read CSV row: %w", err)
+		}
+		if titleCol >= len(record) || strings.TrimSpace(record[titleCol]) == "" {
+			continue
+		}
+		row := importRow{Title: strings.TrimSpace(record[titleCol])}
+		if yearCol >= 0 && yearCol < len(record) {
+			row.Year, _ = strconv.Atoi(strings.TrimSpace(record[yearCol]))
+		}
+		if idCol >= 0 && idCol < len(record) {
+			row.ExternalID = strings.TrimSpace(record[idCol])
+		}
+		rows = append(rows, row)
+	}
+	return rows, source, nil
+}
+
+// listedMedia returns the library items a list already holds, archived ones
+// included, keyed by media type and Kodi ID: what AddItem would reject as
+// duplicates.
+func (s *Server) listedMedia(listID int64) (map[string]bool, error) {
+	items, err := s.db.GetItems(listID)
+	if err != nil {
+		return nil, err
+	}
+	archived, err := s.db.GetArchivedItems(listID)
+	if err != nil {
+		return nil, err
+	}
+	listed := map[string]bool{}
+	for _, item := range append(items, archived...) {
+		if !item.Wanted {
+			listed[fmt.Sprintf("%s:%d", item.MediaType, item.KodiID)] = true
+		}
+	}
+	return listed, nil
+}
+
+// handleImport adds the titles of a CSV export to a list:
+// POST /lists/{id}/import. Titles not in the library are kept as pending
+// matches. ?dry_run=true reports the matches without storing anything.
+func (s *Server) handleImport(w http.ResponseWriter, r *http.Request, listID int64) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	list, err := s.db.GetList(listID)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve list", "list_id", listID)
+		return
+	}
+
+	// Accept either a multipart upload ("file" field) or a raw CSV body
+	var body io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "Missing file upload", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	rows, source, err := parseImportCSV(io.LimitReader(body, 10<<20))
+	if err != nil {
+		slog.Warn("Invalid import file", "list_id", listID, "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if override := r.URL.Query().Get("source"); override != "" {
+		source = override
+	}
+
+	result, err := s.importRows(list, source, rows, r.URL.Query().Get("dry_run") == "true")
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve items", "list_id", listID)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// importRows adds the library items rows match to list and keeps the rest
+// as pending matches from source. Rows carrying a Kodi ID are looked up by
+// it first. A dry run stores nothing and leaves out titles the list
+// already has.
+func (s *Server) importRows(list *database.List, source string, rows []importRow, dryRun bool) (*importResult, error) {
+	listID := list.ID
+	var listed map[string]bool
+	if dryRun {
+		var err error
+		if listed, err = s.listedMedia(listID); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &importResult{Source: source, Matched: []database.Item{}, Pending: []database.PendingMatch{}, DryRun: dryRun}
+	cacheType := cacheTypeFor(list.ContentType)
+	for _, row := range rows {
+		var cached *database.CachedItem
+		err := sql.ErrNoRows
+		if row.KodiID != 0 {
+			cached, err = s.db.GetCachedItem(listID, row.KodiID, cacheType)
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			cached, err = s.matchImport(listID, cacheType, row.Title, row.Year, row.ExternalID)
+		}
+		if err == nil {
+			item := itemFromCache(listID, cached)
+			if dryRun {
+				key := fmt.Sprintf("%s:%d", item.MediaType, item.KodiID)
+				if !listed[key] {
+					listed[key] = true
+					result.Matched = append(result.Matched, item)
+				}
+				continue
+			}
+			id, err := s.db.AddItem(item)
+			if errors.Is(err, database.ErrDuplicateItem) {
+				slog.Info("Imported title is already on the list", "list_id", listID, "title", row.Title)
+				continue
+			}
+			if err != nil {
+				slog.Error("Failed to add imported item", "list_id", listID, "title", row.Title, "error", err)
+				continue
+			}
+			item.ID = id
+			result.Matched = append(result.Matched, item)
+			s.prewarmShow(item)
+			continue
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			slog.Error("Failed to match imported title", "list_id", listID, "title", row.Title, "error", err)
+		}
+
+		pending := database.PendingMatch{ListID: listID, Source: source, Title: row.Title, Year: row.Year, ExternalID: row.ExternalID}
+		if dryRun {
+			result.Pending = append(result.Pending, pending)
+			continue
+		}
+		if err := s.db.AddPendingMatch(pending); err != nil {
+			slog.Error("Failed to store pending match", "list_id", listID, "title", row.Title, "error", err)
+			continue
+		}
+		result.Pending = append(result.Pending, pending)
+	}
+
+	slog.Info("Imported titles", "list_id", listID, "source", source, "matched", len(result.Matched), "pending", len(result.Pending), "dry_run", dryRun)
+	return result, nil
+}
+
+func (s *Server) handlePendingMatches(w http.ResponseWriter, r *http.Request, listID int64) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	pending, err := s.db.GetPendingMatches(listID)
+	if err != nil {
+		slog.Error("Failed to get pending matches", "list_id", listID, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pending)
+}
+
+// retryPendingMatches re-attempts matching of previously unmatched imports for
+// every list on the synced list's host, adding any titles that have since
+// arrived in the library and leaving a notification for each.
+func (s *Server) retryPendingMatches(listID int64) {
+	pending, err := s.db.GetPendingMatchesForHost(listID)
+	if err != nil {
+		slog.Error("Failed to load pending matches", "list_id", listID, "error", err)
+		return
+	}
+
+	lists := map[int64]*database.List{}
+	for _, p := range pending {
+		list, ok := lists[p.ListID]
+		if !ok {
+			list, err = s.db.GetList(p.ListID)
+			if err != nil {
+				slog.Error("Failed to load list for pending match", "list_id", p.ListID, "error", err)
+				continue
+			}
+			lists[p.ListID] = list
+		}
+
+		cached, err := s.matchImport(p.ListID, cacheTypeFor(list.ContentType), p.Title, p.Year, p.ExternalID)
+		if err != nil {
+			if !errors.Is(err, sql.ErrNoRows) {
+				slog.Error("Failed to match pending title", "list_id", p.ListID, "title", p.Title, "error", err)
+			}
+			_ = s.db.TouchPendingMatch(p.ID)
+			continue
+		}
+
+		item := itemFromCache(p.ListID, cached)
+		if _, err := s.db.AddItem(item); errors.Is(err, database.ErrDuplicateItem) {
+			// Added by hand in the meantime; the pending entry is done.
+			if err := s.db.DeletePendingMatch(p.ID); err != nil {
+				slog.Error("Failed to remove pending match", "id", p.ID, "error", err)
+			}
+			continue
+		} else if err != nil {
+			slog.Error("Failed to add matched pending title", "list_id", p.ListID, "title", p.Title, "error", err)
+			continue
+		}
+		s.prewarmShow(item)
+		if err := s.db.DeletePendingMatch(p.ID); err != nil {
+			slog.Error("Failed to remove pending match", "id", p.ID, "error", err)
+		}
+
+		slog.Info("Pending import matched after sync", "list_id", p.ListID, "title", cached.Title, "kodi_id", cached.KodiID)
+		msg := fmt.Sprintf("%q (%d) is now in the library and was added to %s", cached.Title, cached.Year, list.Name)
+		if err := s.db.AddNotification(database.NotificationPendingMatched, p.ListID, msg); err != nil {
+			slog.Error("Failed to add notification", "error", err)
+		}
+	}
+}
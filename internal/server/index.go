@@ -0,0 +1,199 @@
+package server
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"whats-next/internal/database"
+)
+
+// otherLetter is the index bucket of titles that don't start with a letter.
+const otherLetter = "#"
+
+// titleArticles are leading words ignored when sorting titles, as Kodi does.
+var titleArticles = []string{"the ", "a ", "an "}
+
+// accentFolds maps accented Latin letters to the letter they're filed under,
+// so "Émilie" sits with the E's.
+var accentFolds = map[rune]rune{}
+
+func init() {
+	for base, accented := range map[rune]string{
+		'a': "àáâãäåā", 'c': "çćč", 'e': "èéêëēė", 'i': "ìíîïī", 'n': "ñń",
+		'o': "òóôõöøō", 's': "śš", 'u': "ùúûüū", 'y': "ýÿ", 'z': "źżž",
+	} {
+		for _, r := range accented {
+			accentFolds[r] = base
+		}
+	}
+}
+
+type indexLetter struct {
+	Letter string `json:"letter"`
+	Count  int    `json:"count"`
+	Offset int    `json:"offset"` // of the letter's first title in title order
+}
+
+type titleIndex struct {
+	Total   int           `json:"total"`
+	Letters []indexLetter `json:"letters"`
+}
+
+// sortTitle is the lower-cased title without a leading article.
+func sortTitle(title string) string {
+	t := strings.ToLower(strings.TrimSpace(title))
+	for _, article := range titleArticles {
+		if rest, ok := strings.CutPrefix(t, article); ok && rest != "" {
+			return rest
+		}
+	}
+	return t
+}
+
+// titleLetter returns the index letter a title is filed under: its first
+// letter, ignoring articles and accents, or otherLetter.
+func titleLetter(title string) string {
+	for _, r := range sortTitle(title) {
+		if !unicode.IsLetter(r) {
+			return otherLetter
+		}
+		if base, ok := accentFolds[r]; ok {
+			r = base
+		}
+		return string(unicode.ToUpper(r))
+	}
+	return otherLetter
+}
+
+// letterRank orders index letters: otherLetter first, then A to Z, then
+// letters of other alphabets.
+func letterRank(letter string) int {
+	switch {
+	case letter == otherLetter:
+		return 0
+	case len(letter) == 1 && letter[0] >= 'A' && letter[0] <= 'Z':
+		return 1
+	}
+	return 2
+}
+
+// compareTitles orders titles by index letter, then by sort title.
+func compareTitles(a, b string) int {
+	la, lb := titleLetter(a), titleLetter(b)
+	return cmp.Or(
+		cmp.Compare(letterRank(la), letterRank(lb)),
+		strings.Compare(la, lb),
+		strings.Compare(sortTitle(a), sortTitle(b)),
+		strings.Compare(a, b),
+	)
+}
+
+// sortByTitle sorts items into title order, the order index offsets count in.
+func sortByTitle[T any](items []T, title func(T) string) {
+	slices.SortStableFunc(items, func(a, b T) int { return compareTitles(title(a), title(b)) })
+}
+
+// buildTitleIndex buckets titles, already in title order, by letter.
+func buildTitleIndex(titles []string) titleIndex {
+	index := titleIndex{Total: len(titles), Letters: []indexLetter{}}
+	for i, title := range titles {
+		letter := titleLetter(title)
+		if n := len(index.Letters); n == 0 || index.Letters[n-1].Letter != letter {
+			index.Letters = append(index.Letters, indexLetter{Letter: letter, Offset: i})
+		}
+		index.Letters[len(index.Letters)-1].Count++
+	}
+	return index
+}
+
+// pageParams reads ?offset= and ?limit=; a zero limit means no limit.
+func pageParams(q url.Values) (offset, limit int, err error) {
+	if v := q.Get("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+			return 0, 0, errors.New("offset must be a non-negative number")
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 {
+			return 0, 0, errors.New("limit must be a positive number")
+		}
+	}
+	return offset, limit, nil
+}
+
+// pageOf returns the items from offset, at most limit of them unless limit
+// is zero.
+func pageOf[T any](items []T, offset, limit int) []T {
+	if offset >= len(items) {
+		return items[:0]
+	}
+	items = items[offset:]
+	if limit > 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
+
+// handleListIndex returns the A–Z jump index of a list's items:
+// GET /lists/{id}/index. Each letter's offset points into
+// GET /lists/{id}/items?sort=title, so a client can jump to a letter with
+// &offset= and &limit= instead of fetching the whole list.
+func (s *Server) handleListIndex(w http.ResponseWriter, r *http.Request, listID int64) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	items, err := s.db.GetItems(listID)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve items", "list_id", listID)
+		return
+	}
+	sortByTitle(items, func(i database.Item) string { return i.Title })
+	titles := make([]string, len(items))
+	for i, item := range items {
+		titles[i] = item.Title
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(buildTitleIndex(titles))
+}
+
+// handleLibraryIndex returns the A–Z jump index of a list's cached library:
+// GET /library/index?list_id=N&content_type=movie|tv|music. Offsets point
+// into GET /library/browse?by=title.
+func (s *Server) handleLibraryIndex(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	listID, err := strconv.ParseInt(q.Get("list_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid list_id", http.StatusBadRequest)
+		return
+	}
+	if _, err := s.db.GetList(listID); err != nil {
+		writeDBError(w, err, "Failed to retrieve list", "list_id", listID)
+		return
+	}
+	cached, err := s.db.GetLibraryCache(listID, cacheTypeFor(q.Get("content_type")))
+	if err != nil {
+		slog.Error("Failed to read library cache", "list_id", listID, "error", err)
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	sortByTitle(cached, func(c database.CachedItem) string { return c.Title })
+	titles := make([]string, len(cached))
+	for i, c := range cached {
+		titles[i] = c.Title
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(buildTitleIndex(titles))
+}
@@ -0,0 +1,160 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"whats-next/internal/database"
+)
+
+const (
+	defaultShowRefreshInterval    = 30 * time.Minute
+	defaultFeedRefreshInterval    = time.Hour
+	defaultHistoryRefreshInterval = 15 * time.Minute
+	defaultFullSyncInterval       = 24 * time.Hour
+	defaultRemoteListInterval     = 15 * time.Minute
+)
+
+// StartBackgroundJobs launches periodic maintenance work. Jobs stop when ctx
+// is cancelled.
+func (s *Server) StartBackgroundJobs(ctx context.Context) {
+	s.jobsCtx = ctx
+	s.startKodiEvents(ctx)
+	s.jobs.Go(func() { s.runMetricsFlush(ctx) })
+	s.jobs.Go(func() { s.runSelfCheck() })
+
+	if interval := jobInterval("show_refresh_interval", s.config.ShowRefreshInterval, defaultShowRefreshInterval); interval > 0 {
+		s.jobs.Go(func() { s.runLeaderJob(ctx, "show_refresh", interval, s.refreshShowCounters) })
+		slog.Info("Show counter refresh scheduled", "interval", interval.String())
+	} else {
+		slog.Info("Show counter refresh disabled")
+	}
+
+	if interval := jobInterval("feed_refresh_interval", s.config.FeedRefreshInterval, defaultFeedRefreshInterval); interval > 0 {
+		s.jobs.Go(func() { s.runLeaderJob(ctx, "feed_refresh", interval, s.refreshFeeds) })
+		slog.Info("Feed refresh scheduled", "interval", interval.String())
+	} else {
+		slog.Info("Feed refresh disabled")
+	}
+
+	if interval := jobInterval("history_refresh_interval", s.config.HistoryRefreshInterval, defaultHistoryRefreshInterval); interval > 0 {
+		s.jobs.Go(func() { s.runLeaderJob(ctx, "history_refresh", interval, s.refreshHistory) })
+		slog.Info("Viewing history refresh scheduled", "interval", interval.String())
+	} else {
+		slog.Info("Viewing history refresh disabled")
+	}
+
+	if interval := jobInterval("remote_list_interval", s.config.RemoteListInterval, defaultRemoteListInterval); interval > 0 {
+		s.jobs.Go(func() { s.runLeaderJob(ctx, "remote_lists", interval, s.refreshRemoteLists) })
+		slog.Info("Remote list polling scheduled", "interval", interval.String())
+	} else {
+		slog.Info("Remote list polling disabled")
+	}
+
+	s.jobs.Go(func() { s.runLeaderJob(ctx, "host_migration", hostMigrationInterval, s.runHostMigrations) })
+
+	if digestEnabled(s.config.Digest) {
+		s.jobs.Go(func() { s.runLeaderJob(ctx, "digest", time.Hour, s.sendDueDigest) })
+		day, hour := digestSchedule(s.config.Digest)
+		slog.Info("Weekly digest scheduled", "day", day.String(), "hour", hour)
+	}
+}
+
+// jobInterval parses a configured job interval such as "15m", falling back
+// to def when it is unset or invalid. Zero or less disables the job.
+func jobInterval(name, value string, def time.Duration) time.Duration {
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		slog.Error("Invalid "+name+", using default", "value", value, "error", err)
+		return def
+	}
+	return d
+}
+
+// WaitForJobs blocks until background jobs have stopped and released their
+// leases after their context was cancelled.
+func (s *Server) WaitForJobs() {
+	s.jobs.Wait()
+}
+
+// runLeaderJob calls fn every interval, but only while this replica holds the
+// named lease. The holder renews the lease each tick, and it lapses after two
+// missed ticks so another replica can take over.
+func (s *Server) runLeaderJob(ctx context.Context, name string, interval time.Duration, fn func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if err := s.db.ReleaseLease(name, s.instanceID); err != nil {
+				slog.Warn("Failed to release job lease", "job", name, "error", err)
+			}
+			return
+		case <-ticker.C:
+			ttl := 2*interval + time.Minute
+			acquired, err := s.db.RenewLease(name, s.instanceID, ttl)
+			if err == nil && !acquired {
+				acquired, err = s.db.AcquireLease(name, s.instanceID, ttl)
+			}
+			if err != nil {
+				slog.Error("Failed to acquire job lease", "job", name, "error", err)
+				continue
+			}
+			if !acquired {
+				slog.Debug("Job is running on another instance", "job", name)
+				continue
+			}
+			fn()
+		}
+	}
+}
+
+// refreshShowCounters pulls only the episode and watched-episode counters for
+// every TV host and writes them to the cache and list items, so progress stays
+// current between full library syncs.
+func (s *Server) refreshShowCounters() {
+	lists, err := s.db.GetAllLists()
+	if err != nil {
+		slog.Error("Failed to get lists for show refresh", "error", err)
+		return
+	}
+
+	seen := map[string]bool{}
+	for _, l := range lists {
+		if l.ContentType != "tv" || seen[l.KodiHost] || l.Backend == database.BackendJellyfin {
+			continue
+		}
+		seen[l.KodiHost] = true
+		s.refreshHostShowCounters(l.ID, l.KodiHost)
+	}
+}
+
+// refreshHostShowCounters refreshes the show counters of the Kodi host of
+// listID.
+func (s *Server) refreshHostShowCounters(listID int64, host string) {
+	client, err := s.getKodiClient(listID)
+	if err != nil {
+		slog.Error("Failed to get Kodi client for show refresh", "list_id", listID, "error", err)
+		return
+	}
+	shows, err := client.GetTVShowCounters()
+	if err != nil {
+		slog.Warn("Failed to fetch show counters", "host", host, "error", err)
+		return
+	}
+
+	counters := make([]database.ShowCounter, 0, len(shows))
+	for _, show := range shows {
+		counters = append(counters, database.ShowCounter{KodiID: show.ID, EpisodeCount: show.EpisodeCount, WatchedEpisodes: show.WatchedEpisodes})
+	}
+	changed, err := s.db.UpdateShowCounters(listID, counters)
+	if err != nil {
+		slog.Error("Failed to store show counters", "host", host, "error", err)
+		return
+	}
+	slog.Info("Refreshed show counters", "host", host, "shows", len(counters), "items_changed", changed)
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"whats-next/internal/database"
+	"whats-next/internal/kodi"
+)
+
+// kodiOptions are a list's settings for connections to its Kodi host.
+type kodiOptions struct {
+	TLS kodi.TLSOptions
+	// RPCTimeout and ImageTimeout override the client's and image
+	// downloads' default timeouts when set.
+	RPCTimeout   time.Duration
+	ImageTimeout time.Duration
+}
+
+// listKodiOptions returns the connection settings of a list's Kodi host.
+// Durations were validated when the list was loaded from config.
+func listKodiOptions(l *database.List) kodiOptions {
+	opts := kodiOptions{TLS: kodi.TLSOptions{SkipVerify: l.TLSSkipVerify, CACert: l.CACert}}
+	opts.RPCTimeout, _ = time.ParseDuration(l.RPCTimeout)
+	opts.ImageTimeout, _ = time.ParseDuration(l.ImageTimeout)
+	return opts
+}
+
+// kodiConn identifies a Kodi connection: a host with the credentials and
+// options it is reached with.
+type kodiConn struct {
+	host, user, pass string
+	opts             kodiOptions
+}
+
+// hostHealth is what was last seen of a Kodi host.
+type hostHealth struct {
+	Host      string `json:"host"`
+	Reachable bool   `json:"reachable"`
+	// LastSeen is when the host last answered a call
+	LastSeen    *time.Time `json:"last_seen,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+}
+
+// kodiClientManager caches one client per Kodi connection, so requests reuse
+// clients (and through them, their host's transport and connections) rather
+// than building one each time, and tracks each host's health from the calls
+// made through any client.
+type kodiClientManager struct {
+	mu      sync.Mutex
+	clients map[kodiConn]*kodi.Client
+	health  map[string]*hostHealth
+}
+
+// kodiClient returns the shared client for a connection, building it on
+// first use. Clients are safe for concurrent use and must not be modified;
+// copy one to change a setting for a single call.
+func (s *Server) kodiClient(host, user, pass string, opts kodiOptions) (*kodi.Client, error) {
+	key := kodiConn{host: host, user: user, pass: pass, opts: opts}
+	m := &s.kodiClients
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if client, ok := m.clients[key]; ok {
+		return client, nil
+	}
+	client, err := s.newKodiClient(host, user, pass, opts)
+	if err != nil {
+		return nil, err
+	}
+	if m.clients == nil {
+		m.clients = map[kodiConn]*kodi.Client{}
+	}
+	m.clients[key] = client
+	return client, nil
+}
+
+// invalidate drops the cached clients of host after its connection settings
+// changed, along with its health, which may describe the old settings.
+func (m *kodiClientManager) invalidate(host string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key := range m.clients {
+		if key.host == host {
+			delete(m.clients, key)
+		}
+	}
+	delete(m.health, host)
+}
+
+// record notes the outcome of a call to host.
+func (m *kodiClientManager) record(host string, err error) {
+	now := time.Now().UTC()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.health == nil {
+		m.health = map[string]*hostHealth{}
+	}
+	h, ok := m.health[host]
+	if !ok {
+		h = &hostHealth{Host: host}
+		m.health[host] = h
+	}
+	if err == nil {
+		h.Reachable, h.LastSeen = true, &now
+		return
+	}
+	h.Reachable, h.LastError, h.LastErrorAt = false, err.Error(), &now
+}
+
+// snapshot returns the health of every host called so far, by host.
+func (m *kodiClientManager) snapshot() []hostHealth {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	hosts := make([]hostHealth, 0, len(m.health))
+	for _, h := range m.health {
+		hosts = append(hosts, *h)
+	}
+	slices.SortFunc(hosts, func(a, b hostHealth) int { return strings.Compare(a.Host, b.Host) })
+	return hosts
+}
@@ -0,0 +1,264 @@
+package server
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"whats-next/internal/database"
+	"whats-next/internal/kodi"
+)
+
+// smartPlaylistKinds maps a list's cache type to the Kodi library type of
+// its items and the smart playlist type and field that match them by name.
+var smartPlaylistKinds = map[string]struct{ kodiType, xspType, field string }{
+	"movie":  {"movie", "movies", "title"},
+	"show":   {"tvshow", "tvshows", "title"},
+	"album":  {"album", "albums", "album"},
+	"artist": {"artist", "artists", "artist"},
+}
+
+// smartPlaylist is a Kodi .xsp file. Values of one rule are alternatives.
+type smartPlaylist struct {
+	XMLName xml.Name  `xml:"smartplaylist"`
+	Type    string    `xml:"type,attr"`
+	Name    string    `xml:"name"`
+	Match   string    `xml:"match"`
+	Rules   []xspRule `xml:"rule"`
+	Order   xspOrder  `xml:"order"`
+}
+
+type xspRule struct {
+	Field    string   `xml:"field,attr"`
+	Operator string   `xml:"operator,attr"`
+	Values   []string `xml:"value"`
+}
+
+type xspOrder struct {
+	Direction string `xml:"direction,attr"`
+	Field     string `xml:",chardata"`
+}
+
+type playlistImportRequest struct {
+	// Path is a saved playlist under kodi.PlaylistsDir; Queue imports the
+	// play queue instead.
+	Path  string `json:"path"`
+	Queue bool   `json:"queue"`
+}
+
+// playlistMedia is the Kodi media a list's playlists are kept under.
+func playlistMedia(list *database.List) string {
+	if list.ContentType == "music" {
+		return "music"
+	}
+	return "video"
+}
+
+// handleKodiPlaylists lists the playlists saved on a list's Kodi host, its
+// music playlists for a music list: GET /library/playlists?list_id=.
+func (s *Server) handleKodiPlaylists(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	listID, err := strconv.ParseInt(r.URL.Query().Get("list_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid list_id", http.StatusBadRequest)
+		return
+	}
+	list, err := s.db.GetList(listID)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve list", "list_id", listID)
+		return
+	}
+	client, err := s.getKodiClient(listID)
+	if err != nil {
+		writeDBError(w, err, "Failed to connect to Kodi", "list_id", listID)
+		return
+	}
+	playlists, err := client.GetSavedPlaylists(playlistMedia(list))
+	if err != nil {
+		slog.Error("Failed to list Kodi playlists", "list_id", listID, "error", err)
+		writeKodiError(w, err, "Failed to list playlists", http.StatusBadGateway)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(playlists)
+}
+
+// handleKodiPlaylist moves a list to and from Kodi playlists:
+// GET /lists/{id}/kodi-playlist downloads the list as a smart playlist to
+// drop into Kodi's userdata playlists folder, and POST imports a saved
+// playlist ({"path": ...}) or the play queue ({"queue": true}) into the
+// list, honouring ?dry_run=true.
+func (s *Server) handleKodiPlaylist(w http.ResponseWriter, r *http.Request, listID int64) {
+	switch r.Method {
+	case http.MethodGet:
+		s.exportSmartPlaylist(w, listID)
+	case http.MethodPost:
+		s.importKodiPlaylist(w, r, listID)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// exportSmartPlaylist writes a list as a smart playlist matching its items
+// by name. Smart playlists hold one kind of item and can't keep the list's
+// order, so other kinds are left out and items sort by title; seasons are
+// matched through their show.
+func (s *Server) exportSmartPlaylist(w http.ResponseWriter, listID int64) {
+	list, err := s.db.GetList(listID)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve list", "list_id", listID)
+		return
+	}
+	items, err := s.db.GetItems(listID)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve items", "list_id", listID)
+		return
+	}
+	cacheType := cacheTypeFor(list.ContentType)
+	kind := smartPlaylistKinds[cacheType]
+
+	rule := xspRule{Field: kind.field, Operator: "is"}
+	seen := map[string]bool{}
+	for _, item := range items {
+		name := item.Title
+		switch {
+		case item.MediaType == "season" && cacheType == "show":
+			name = item.ShowTitle
+		case item.MediaType != cacheType:
+			continue
+		}
+		if name != "" && !seen[name] {
+			seen[name] = true
+			rule.Values = append(rule.Values, name)
+		}
+	}
+	if len(rule.Values) == 0 {
+		// A rule without values would match the whole library
+		http.Error(w, "List has nothing a smart playlist can hold", http.StatusUnprocessableEntity)
+		return
+	}
+
+	xsp := smartPlaylist{
+		Type:  kind.xspType,
+		Name:  list.Name,
+		Match: "one",
+		Rules: []xspRule{rule},
+		Order: xspOrder{Direction: "ascending", Field: "sorttitle"},
+	}
+	out, err := xml.MarshalIndent(xsp, "", "    ")
+	if err != nil {
+		slog.Error("Failed to encode smart playlist", "list_id", listID, "error", err)
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", playlistFileName(list.Name)+".xsp"))
+	w.Write([]byte(xml.Header))
+	w.Write(out)
+}
+
+// playlistFileName makes a list name safe to use as a file name.
+func playlistFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) || r < ' ' {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	if name == "" {
+		return "playlist"
+	}
+	return name
+}
+
+// importKodiPlaylist adds what a Kodi playlist holds to a list, like a CSV
+// import: library items of the list's kind are added by their Kodi ID, other
+// entries are matched by title and kept pending if that fails, and items of
+// another kind are skipped.
+func (s *Server) importKodiPlaylist(w http.ResponseWriter, r *http.Request, listID int64) {
+	var req playlistImportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Queue == (req.Path != "") {
+		http.Error(w, "Give either path or queue", http.StatusBadRequest)
+		return
+	}
+	if req.Path != "" && (!strings.HasPrefix(req.Path, kodi.PlaylistsDir) || strings.Contains(req.Path, "..")) {
+		http.Error(w, "path must be a playlist under "+kodi.PlaylistsDir, http.StatusBadRequest)
+		return
+	}
+
+	list, err := s.db.GetList(listID)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve list", "list_id", listID)
+		return
+	}
+	client, err := s.getKodiClient(listID)
+	if err != nil {
+		writeDBError(w, err, "Failed to connect to Kodi", "list_id", listID)
+		return
+	}
+	var entries []kodi.PlaylistEntry
+	source := "kodi_playlist"
+	if req.Queue {
+		playlistID := kodi.VideoPlaylistID
+		if list.ContentType == "music" {
+			playlistID = kodi.AudioPlaylistID
+		}
+		entries, err = client.GetPlaylistItems(playlistID)
+		source = "kodi_queue"
+	} else {
+		entries, err = client.GetSavedPlaylistItems(req.Path, playlistMedia(list))
+	}
+	if errors.Is(err, kodi.ErrNotFound) {
+		http.Error(w, "Playlist not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to read Kodi playlist", "list_id", listID, "path", req.Path, "error", err)
+		writeKodiError(w, err, "Failed to read the playlist", http.StatusBadGateway)
+		return
+	}
+
+	kodiType := smartPlaylistKinds[cacheTypeFor(list.ContentType)].kodiType
+	var rows []importRow
+	var skipped []string
+	for _, e := range entries {
+		title := e.Title
+		if title == "" && e.File != "" {
+			title = fileLabel(e.File)
+		}
+		if title == "" {
+			title = e.Label
+		}
+		switch e.Type {
+		case kodiType:
+			rows = append(rows, importRow{Title: title, Year: e.Year, ExternalID: e.IMDbNumber, KodiID: e.ID})
+		case "", "unknown":
+			if title != "" {
+				rows = append(rows, importRow{Title: title, Year: e.Year})
+			}
+		default:
+			skipped = append(skipped, title)
+		}
+	}
+
+	result, err := s.importRows(list, source, rows, r.URL.Query().Get("dry_run") == "true")
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve items", "list_id", listID)
+		return
+	}
+	result.Skipped = skipped
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
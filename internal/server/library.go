@@ -0,0 +1,65 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+// handleLibraryJob starts a Kodi library scan or clean on the list's host:
+// POST /lists/{id}/scan with an optional {"directory": "..."} to scan one
+// source, or POST /lists/{id}/clean to drop missing files of the list's
+// content type. Music lists scan and clean Kodi's music library. Kodi runs
+// the job in the background; with notifications enabled the library cache
+// of a video list is resynced when it finishes.
+func (s *Server) handleLibraryJob(w http.ResponseWriter, r *http.Request, listID int64, job string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Directory string `json:"directory"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	list, err := s.db.GetList(listID)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve list", "list_id", listID)
+		return
+	}
+	client, err := s.getKodiClient(listID)
+	if err != nil {
+		writeDBError(w, err, "Failed to connect to Kodi", "list_id", listID)
+		return
+	}
+
+	switch {
+	case job == "scan" && list.ContentType == "music":
+		err = client.ScanMusicLibrary(req.Directory)
+	case job == "scan":
+		err = client.ScanLibrary(req.Directory)
+	case list.ContentType == "music":
+		err = client.CleanMusicLibrary()
+	default:
+		content := "movies"
+		if list.ContentType == "tv" {
+			content = "tvshows"
+		}
+		err = client.CleanLibrary(content)
+	}
+	if err != nil {
+		slog.Error("Failed to start Kodi library job", "job", job, "list_id", listID, "error", err)
+		writeKodiError(w, err, "Failed to start library "+job+" on Kodi", http.StatusBadGateway)
+		return
+	}
+
+	slog.Info("Started Kodi library job", "job", job, "list_id", listID, "host", list.KodiHost, "directory", req.Directory)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"status": "started", "job": job})
+}
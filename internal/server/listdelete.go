@@ -0,0 +1,48 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"whats-next/internal/database"
+)
+
+// DeleteList removes a list with its items and caches, then deletes the
+// poster files nothing references any more. A dry run reports the same
+// without removing anything.
+func (s *Server) DeleteList(listID int64, dryRun bool) (*database.ListDeletion, error) {
+	report, err := s.db.DeleteList(listID, dryRun)
+	if err != nil || dryRun {
+		return report, err
+	}
+	for _, fileName := range report.OrphanedPosters {
+		if strings.ContainsAny(fileName, `/\`) {
+			continue
+		}
+		if err := s.posters.Delete(fileName); err != nil {
+			slog.Warn("Failed to remove orphaned poster", "file", fileName, "error", err)
+		}
+	}
+	slog.Info("Deleted list", "list_id", listID, "items", report.Items, "cache_rows", report.CacheRows, "host_rows", report.HostRows, "posters", len(report.OrphanedPosters))
+	s.refreshKodiEvents()
+	return report, nil
+}
+
+// handleDeleteList deletes a list: DELETE /lists/{id}, with ?dry_run=true to
+// only report what would go. A list still in config.json comes back, empty,
+// at the next start.
+func (s *Server) handleDeleteList(w http.ResponseWriter, r *http.Request, listID int64) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	report, err := s.DeleteList(listID, r.URL.Query().Get("dry_run") == "true")
+	if err != nil {
+		writeDBError(w, err, "Failed to delete list", "list_id", listID)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"whats-next/internal/database"
+)
+
+func TestListCRUD(t *testing.T) {
+	e := newTestEnv(t)
+	newList := map[string]string{"group_name": "Family", "list_name": "Movie night", "content_type": "movie", "kodi_host": "kodi.test:8080"}
+	var created struct {
+		List listView `json:"list"`
+	}
+	e.expect(http.StatusCreated, http.MethodPost, "/setup/lists", newList, &created)
+	e.expect(http.StatusConflict, http.MethodPost, "/setup/lists", newList, nil)
+
+	var lists []listView
+	e.expect(http.StatusOK, http.MethodGet, "/lists", nil, &lists)
+	if len(lists) != 1 || lists[0].ID != created.List.ID || lists[0].Name != "Movie night" {
+		t.Fatalf("GET /lists returned %+v", lists)
+	}
+
+	items := fmt.Sprintf("/lists/%d/items", created.List.ID)
+	var first, second database.Item
+	e.expect(http.StatusOK, http.MethodPost, items, map[string]interface{}{"title": "Heat", "year": 1995, "wanted": true}, &first)
+	e.expect(http.StatusOK, http.MethodPost, items, map[string]interface{}{"title": "Ronin", "wanted": true}, &second)
+	e.expect(http.StatusBadRequest, http.MethodPost, items, map[string]interface{}{"title": " ", "wanted": true}, nil)
+
+	var got itemsResponse
+	e.expect(http.StatusOK, http.MethodGet, items, nil, &got)
+	if n := len(got.Items.([]interface{})); n != 2 {
+		t.Fatalf("list holds %d items, want 2", n)
+	}
+
+	e.expect(http.StatusNoContent, http.MethodDelete, fmt.Sprintf("/items/%d", first.ID), nil, nil)
+	e.expect(http.StatusNotFound, http.MethodDelete, fmt.Sprintf("/items/%d", first.ID), nil, nil)
+
+	var report database.ListDeletion
+	e.expect(http.StatusOK, http.MethodDelete, fmt.Sprintf("/lists/%d", created.List.ID), nil, &report)
+	if report.Items != 1 {
+		t.Fatalf("deleting the list removed %d items, want 1", report.Items)
+	}
+	e.expect(http.StatusNotFound, http.MethodGet, items, nil, nil)
+	e.expect(http.StatusOK, http.MethodGet, "/lists", nil, &lists)
+	if len(lists) != 0 {
+		t.Fatalf("GET /lists after delete returned %+v", lists)
+	}
+}
+
+func TestReorderWithStaleRevision(t *testing.T) {
+	e := newTestEnv(t)
+	list := e.addList("Movies", "movie")
+	items := fmt.Sprintf("/lists/%d/items", list.ID)
+	var heat, ronin database.Item
+	e.expect(http.StatusOK, http.MethodPost, items, map[string]interface{}{"title": "Heat", "wanted": true}, &heat)
+
+	resp, _ := e.do(http.MethodGet, items, nil)
+	seen := resp.Header.Get("ETag")
+	if seen == "" {
+		t.Fatal("GET items sent no ETag")
+	}
+
+	// Someone else adds an item after the list was read
+	e.expect(http.StatusOK, http.MethodPost, items, map[string]interface{}{"title": "Ronin", "wanted": true}, &ronin)
+
+	order := map[string]interface{}{"item_ids": []int64{ronin.ID, heat.ID}}
+	resp, body := e.doWith(http.MethodPut, fmt.Sprintf("/lists/%d/order", list.ID), order, map[string]string{"If-Match": seen})
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("reorder with stale If-Match: got %d, want 409: %s", resp.StatusCode, body)
+	}
+
+	resp, _ = e.do(http.MethodGet, items, nil)
+	resp, body = e.doWith(http.MethodPut, fmt.Sprintf("/lists/%d/order", list.ID), order, map[string]string{"If-Match": resp.Header.Get("ETag")})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("reorder with current If-Match: got %d, want 200: %s", resp.StatusCode, body)
+	}
+}
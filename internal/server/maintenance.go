@@ -0,0 +1,148 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"whats-next/internal/kodi"
+)
+
+// PosterMigrationReport summarizes a MigratePosters run.
+type PosterMigrationReport struct {
+	Scanned    int     `json:"scanned"`
+	Rewritten  int     `json:"rewritten"`
+	Downloaded int     `json:"downloaded"`
+	Missing    []int64 `json:"missing"` // item IDs whose artwork couldn't be recovered
+	Failed     []int64 `json:"failed"`
+	DryRun     bool    `json:"dry_run"`
+}
+
+// MigratePosters rewrites poster paths stored by older versions to the
+// current /api/posters/<file> scheme:
+//   - absolute or relative filesystem paths (e.g. /app/data/posters/x.jpg)
+//     and the old /posters/x.jpg route are rewritten when the file exists
+//   - image:// URIs and remote URLs are downloaded from the list's Kodi host
+//   - Kodi /image/ proxy URLs are unwrapped and downloaded the same way
+func (s *Server) MigratePosters(dryRun bool) (*PosterMigrationReport, error) {
+	report := &PosterMigrationReport{Missing: []int64{}, Failed: []int64{}, DryRun: dryRun}
+	if !dryRun {
+		holder := s.leaseHolder()
+		acquired, err := s.db.AcquireLease("migrate_posters", holder, time.Hour)
+		if err != nil {
+			return nil, err
+		}
+		if !acquired {
+			return nil, fmt.Errorf("poster migration is already running on another instance")
+		}
+		defer s.db.ReleaseLease("migrate_posters", holder)
+	}
+	lists, err := s.db.GetAllLists()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, list := range lists {
+		items, err := s.db.GetItems(list.ID)
+		if err != nil {
+			return nil, err
+		}
+		var client *kodi.Client
+		for _, item := range items {
+			if item.Poster == "" {
+				continue
+			}
+			report.Scanned++
+
+			if fileName, ok := legacyPosterFile(item.Poster); ok {
+				publicURL := "/api/posters/" + fileName
+				if publicURL == item.Poster {
+					if ok, _ := s.posters.Exists(fileName); !ok {
+						report.Missing = append(report.Missing, item.ID)
+					}
+					continue
+				}
+				if ok, _ := s.posters.Exists(fileName); !ok {
+					slog.Warn("Legacy poster file is missing", "item_id", item.ID, "poster", item.Poster)
+					report.Missing = append(report.Missing, item.ID)
+					continue
+				}
+				if !dryRun {
+					if err := s.db.SetItemPoster(item.ID, publicURL, item.OriginalPoster); err != nil {
+						slog.Error("Failed to rewrite poster path", "item_id", item.ID, "error", err)
+						report.Failed = append(report.Failed, item.ID)
+						continue
+					}
+				}
+				slog.Info("Rewrote legacy poster path", "item_id", item.ID, "from", item.Poster, "to", publicURL)
+				report.Rewritten++
+				continue
+			}
+
+			imageURI := remoteImageURI(item.Poster)
+			if imageURI == "" {
+				continue
+			}
+			report.Downloaded++
+			if dryRun {
+				continue
+			}
+			if client == nil {
+				if client, err = s.getKodiClient(list.ID); err != nil {
+					return nil, err
+				}
+			}
+			localURL, err := s.downloadItemPoster(client, item, imageURI)
+			if err != nil || localURL == "" || !strings.HasPrefix(localURL, "/api/posters/") {
+				slog.Warn("Failed to re-download poster", "item_id", item.ID, "poster", item.Poster, "error", err)
+				report.Downloaded--
+				report.Failed = append(report.Failed, item.ID)
+				continue
+			}
+			if err := s.db.SetItemPoster(item.ID, localURL, item.OriginalPoster); err != nil {
+				slog.Error("Failed to save re-downloaded poster", "item_id", item.ID, "error", err)
+				report.Downloaded--
+				report.Failed = append(report.Failed, item.ID)
+			}
+		}
+	}
+
+	slog.Info("Poster migration finished", "scanned", report.Scanned, "rewritten", report.Rewritten, "downloaded", report.Downloaded, "missing", len(report.Missing), "failed", len(report.Failed), "dry_run", dryRun)
+	return report, nil
+}
+
+// legacyPosterFile extracts the file name from poster paths that point into
+// the local poster directory, in any of the forms older versions stored.
+func legacyPosterFile(poster string) (string, bool) {
+	switch {
+	case strings.HasPrefix(poster, "/api/posters/"):
+		return strings.TrimPrefix(poster, "/api/posters/"), true
+	case strings.HasPrefix(poster, "/posters/"):
+		return strings.TrimPrefix(poster, "/posters/"), true
+	case strings.Contains(poster, "data/posters/") && !strings.Contains(poster, "://"):
+		return path.Base(filepath.ToSlash(poster)), true
+	}
+	return "", false
+}
+
+// remoteImageURI returns the Kodi art URI to download for a poster that was
+// stored as a remote reference, or "" if it isn't one.
+func remoteImageURI(poster string) string {
+	if strings.HasPrefix(poster, "image://") {
+		return poster
+	}
+	if !strings.HasPrefix(poster, "http://") && !strings.HasPrefix(poster, "https://") {
+		return ""
+	}
+	// Kodi proxy URLs wrap the original art URI: http://host/image/<escaped>
+	if u, err := url.Parse(poster); err == nil && strings.HasPrefix(u.Path, "/image/") {
+		if inner, err := url.QueryUnescape(strings.TrimPrefix(u.EscapedPath(), "/image/")); err == nil {
+			return inner
+		}
+	}
+	return poster
+}
@@ -0,0 +1,210 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// metricsFlushInterval is how often counters are written to the database.
+	metricsFlushInterval = time.Minute
+	// metricsRetention bounds both the summary window and stored counters.
+	metricsRetention = 7 * 24 * time.Hour
+)
+
+// Counter names kept in the metrics table.
+const (
+	metricRequests     = "requests"
+	metricErrors       = "errors"        // 5xx responses
+	metricClientErrors = "client_errors" // 4xx responses
+	metricSyncRuns     = "sync_runs"
+	metricSyncFailures = "sync_failures"
+	metricSyncItems    = "sync_items"
+	metricSyncMillis   = "sync_ms"
+)
+
+// metricsRecorder counts requests and syncs under the names above.
+type metricsRecorder struct {
+	counter[string]
+}
+
+// recordSync counts one library sync.
+func (m *metricsRecorder) recordSync(items int, err error, took time.Duration) {
+	m.add(metricSyncRuns, 1)
+	if err != nil {
+		m.add(metricSyncFailures, 1)
+	}
+	m.add(metricSyncItems, int64(items))
+	m.add(metricSyncMillis, took.Milliseconds())
+}
+
+// flushMetrics writes the gathered counters to the database.
+func (s *Server) flushMetrics() {
+	err := s.metrics.flush(func(counts map[string]int64) error {
+		return s.db.AddMetrics(time.Now(), counts)
+	})
+	if err != nil {
+		slog.Warn("Failed to store metrics", "error", err)
+	}
+}
+
+// runMetricsFlush flushes counters and usage counts every
+// metricsFlushInterval until ctx is cancelled, then once more. Every replica
+// flushes its own counters.
+func (s *Server) runMetricsFlush(ctx context.Context) {
+	ticker := time.NewTicker(metricsFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			s.flushMetrics()
+			s.flushUsage()
+			return
+		case <-ticker.C:
+			s.flushMetrics()
+			s.flushUsage()
+			if err := s.db.PruneMetrics(time.Now().Add(-metricsRetention)); err != nil {
+				slog.Warn("Failed to prune metrics", "error", err)
+			}
+			if err := s.db.PruneUsage(time.Now().Add(-usageRetention)); err != nil {
+				slog.Warn("Failed to prune usage counts", "error", err)
+			}
+		}
+	}
+}
+
+// statusRecorder captures the status code a handler wrote.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// countRequests counts API requests and their errors, and what they were
+// used for. Health checks are left out so probes don't drown real traffic.
+func (s *Server) countRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		s.metrics.add(metricRequests, 1)
+		s.recordUsage(r, rec.status)
+		switch {
+		case rec.status >= 500:
+			s.metrics.add(metricErrors, 1)
+		case rec.status >= 400:
+			s.metrics.add(metricClientErrors, 1)
+		}
+	})
+}
+
+type metricsHour struct {
+	Hour         time.Time `json:"hour"`
+	Requests     int64     `json:"requests"`
+	Errors       int64     `json:"errors"`
+	ClientErrors int64     `json:"client_errors"`
+	Syncs        int64     `json:"syncs"`
+}
+
+type syncMetrics struct {
+	Runs          int64 `json:"runs"`
+	Failures      int64 `json:"failures"`
+	Items         int64 `json:"items"`
+	AvgDurationMs int64 `json:"avg_duration_ms"`
+}
+
+type metricsSummary struct {
+	WindowHours  int           `json:"window_hours"`
+	Requests     int64         `json:"requests"`
+	Errors       int64         `json:"errors"`
+	ClientErrors int64         `json:"client_errors"`
+	Syncs        syncMetrics   `json:"syncs"`
+	Hourly       []metricsHour `json:"hourly"`
+}
+
+// handleMetricsSummary totals the request and sync counters of the last
+// ?hours= hours (default 24, at most a week): GET /metrics/summary.
+func (s *Server) handleMetricsSummary(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	hours := 24
+	if v := r.URL.Query().Get("hours"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > int(metricsRetention/time.Hour) {
+			http.Error(w, "Invalid hours parameter", http.StatusBadRequest)
+			return
+		}
+		hours = n
+	}
+
+	// Include this replica's counters that haven't been flushed yet
+	s.flushMetrics()
+	counts, err := s.db.GetMetrics(time.Now().Add(-time.Duration(hours-1) * time.Hour))
+	if err != nil {
+		slog.Error("Failed to read metrics", "error", err)
+		http.Error(w, "Failed to read metrics", http.StatusInternalServerError)
+		return
+	}
+
+	summary := metricsSummary{WindowHours: hours, Hourly: []metricsHour{}}
+	var syncMillis int64
+	for _, c := range counts {
+		if n := len(summary.Hourly); n == 0 || !summary.Hourly[n-1].Hour.Equal(c.Hour) {
+			summary.Hourly = append(summary.Hourly, metricsHour{Hour: c.Hour})
+		}
+		h := &summary.Hourly[len(summary.Hourly)-1]
+		switch c.Name {
+		case metricRequests:
+			summary.Requests += c.Value
+			h.Requests = c.Value
+		case metricErrors:
+			summary.Errors += c.Value
+			h.Errors = c.Value
+		case metricClientErrors:
+			summary.ClientErrors += c.Value
+			h.ClientErrors = c.Value
+		case metricSyncRuns:
+			summary.Syncs.Runs += c.Value
+			h.Syncs = c.Value
+		case metricSyncFailures:
+			summary.Syncs.Failures += c.Value
+		case metricSyncItems:
+			summary.Syncs.Items += c.Value
+		case metricSyncMillis:
+			syncMillis += c.Value
+		}
+	}
+	if summary.Syncs.Runs > 0 {
+		summary.Syncs.AvgDurationMs = syncMillis / summary.Syncs.Runs
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Per-route budgets. Reads answer from the database; writes may talk to Kodi
+// or accept uploads; a foreground library sync downloads every poster.
+const (
+	readTimeout  = 15 * time.Second
+	writeTimeout = 2 * time.Minute
+	syncTimeout  = 15 * time.Minute
+)
+
+// withTimeout gives a route its own read/write deadlines, replacing the
+// server-wide ones, and bounds the request context to the same budget.
+func withTimeout(d time.Duration, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		deadline := time.Now().Add(d)
+		rc := http.NewResponseController(w)
+		if err := rc.SetWriteDeadline(deadline); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			slog.Warn("Failed to set write deadline", "path", r.URL.Path, "error", err)
+		}
+		if err := rc.SetReadDeadline(deadline); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			slog.Warn("Failed to set read deadline", "path", r.URL.Path, "error", err)
+		}
+		ctx, cancel := context.WithDeadline(r.Context(), deadline)
+		defer cancel()
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// SecurityHeaders sets a Content Security Policy and related headers on every
+// response. frameAncestors lists extra origins (e.g. an Organizr dashboard)
+// allowed to embed the UI in an iframe; by default only same-origin framing is
+// permitted.
+func SecurityHeaders(next http.Handler, frameAncestors []string) http.Handler {
+	ancestors := append([]string{"'self'"}, frameAncestors...)
+	csp := strings.Join([]string{
+		"default-src 'self'",
+		"script-src 'self'",
+		// Tailwind/React set inline style attributes
+		"style-src 'self' 'unsafe-inline'",
+		// Posters may be served directly from Kodi or remote scrapers
+		"img-src 'self' data: http: https:",
+		"connect-src 'self'",
+		"object-src 'none'",
+		"base-uri 'self'",
+		"frame-ancestors " + strings.Join(ancestors, " "),
+	}, "; ")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		h.Set("Content-Security-Policy", csp)
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Set("Referrer-Policy", "strict-origin-when-cross-origin")
+		if len(frameAncestors) == 0 {
+			// Legacy equivalent for browsers that ignore frame-ancestors
+			h.Set("X-Frame-Options", "SAMEORIGIN")
+		}
+		next.ServeHTTP(w, r)
+	})
+}
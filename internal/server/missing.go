@@ -0,0 +1,105 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"whats-next/internal/database"
+)
+
+// maxMissingTitles caps how many titles a removal notification spells out.
+const maxMissingTitles = 5
+
+// reportMissingItems flags list items whose media a sync found gone from the
+// Kodi library and records one notification per affected list, pointing at
+// /lists/{id}/missing for cleanup.
+func (s *Server) reportMissingItems(listID int64, mediaType string) {
+	missing, err := s.db.ReconcileMissingItems(listID, mediaType)
+	if err != nil {
+		slog.Error("Failed to reconcile list items with the library", "list_id", listID, "error", err)
+		return
+	}
+	if len(missing) == 0 {
+		return
+	}
+
+	byList := map[int64][]string{}
+	var order []int64
+	for _, item := range missing {
+		if _, ok := byList[item.ListID]; !ok {
+			order = append(order, item.ListID)
+		}
+		byList[item.ListID] = append(byList[item.ListID], item.Title)
+	}
+	for _, id := range order {
+		titles := byList[id]
+		listName := fmt.Sprint(id)
+		if l, err := s.db.GetList(id); err == nil {
+			listName = l.GroupName + "/" + l.Name
+		}
+		slog.Warn("List items were removed from Kodi", "list_id", id, "count", len(titles))
+
+		shown := titles
+		if len(shown) > maxMissingTitles {
+			shown = shown[:maxMissingTitles]
+		}
+		msg := fmt.Sprintf("%d item(s) on %s are no longer in the Kodi library: %s", len(titles), listName, strings.Join(shown, ", "))
+		if len(titles) > len(shown) {
+			msg += fmt.Sprintf(" and %d more", len(titles)-len(shown))
+		}
+		if err := s.db.AddNotification(database.NotificationMediaRemoved, id, msg); err != nil {
+			slog.Error("Failed to add notification", "error", err)
+		}
+	}
+}
+
+// handleMissingItems lists the items of a list whose media was removed from
+// Kodi (GET /lists/{id}/missing) or removes them all in one go (DELETE,
+// honouring If-Match). DELETE ?dry_run=true returns the items it would
+// remove instead.
+func (s *Server) handleMissingItems(w http.ResponseWriter, r *http.Request, listID int64) {
+	switch r.Method {
+	case http.MethodGet:
+		items, err := s.db.GetMissingItems(listID)
+		if err != nil {
+			writeDBError(w, err, "Failed to retrieve missing items", "list_id", listID)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(items)
+	case http.MethodDelete:
+		expected, err := ifMatchRevision(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if r.URL.Query().Get("dry_run") == "true" {
+			if _, err := s.db.GetList(listID); err != nil {
+				writeDBError(w, err, "Failed to retrieve list", "list_id", listID)
+				return
+			}
+			items, err := s.db.GetMissingItems(listID)
+			if err != nil {
+				writeDBError(w, err, "Failed to retrieve missing items", "list_id", listID)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{"removed": len(items), "items": items, "dry_run": true})
+			return
+		}
+		removed, rev, err := s.db.DeleteMissingItems(listID, expected)
+		if err != nil {
+			writeDBError(w, err, "Failed to remove missing items", "list_id", listID)
+			return
+		}
+		slog.Info("Removed items missing from Kodi", "list_id", listID, "removed", removed)
+		setRevision(w, rev)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]int64{"removed": removed})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"cmp"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"whats-next/internal/kodi"
+	"whats-next/internal/media"
+)
+
+// nextEpisode is the episode to watch next for a show or season on a list.
+type nextEpisode struct {
+	ItemID         int64  `json:"item_id"`
+	EpisodeID      int    `json:"episode_id"`
+	Season         int    `json:"season"`
+	Episode        int    `json:"episode"`
+	Code           string `json:"code"` // e.g. "S03E05"
+	Title          string `json:"title"`
+	ResumePosition int    `json:"resume_position,omitempty"`
+}
+
+// handleNextEpisodes works out the next unwatched episode of every show and
+// season on a list from Kodi's playcounts: GET /lists/{id}/next-episodes.
+// Items with nothing left to watch are left out.
+func (s *Server) handleNextEpisodes(w http.ResponseWriter, r *http.Request, listID int64) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	items, err := s.db.GetItems(listID)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve items", "list_id", listID)
+		return
+	}
+	client, err := s.getKodiClient(listID)
+	if err != nil {
+		writeDBError(w, err, "Failed to connect to Kodi", "list_id", listID)
+		return
+	}
+
+	// A show and its seasons on the same list share one query
+	episodesByShow := map[int][]kodi.MediaItem{}
+	results := []nextEpisode{}
+	var lastErr error
+	queried := 0
+	for _, item := range items {
+		if item.Wanted || (item.MediaType != "show" && item.MediaType != "season") {
+			continue
+		}
+		episodes, ok := episodesByShow[item.KodiID]
+		if !ok {
+			queried++
+			episodes, err = client.GetShowEpisodes(item.KodiID)
+			if err != nil {
+				slog.Warn("Failed to get show episodes from Kodi", "show_id", item.KodiID, "error", err)
+				lastErr = err
+				continue
+			}
+			episodesByShow[item.KodiID] = episodes
+		}
+		if item.MediaType == "season" {
+			episodes = slices.DeleteFunc(slices.Clone(episodes), func(ep kodi.MediaItem) bool { return ep.Season != item.Season })
+		}
+		if ep := nextUnwatched(episodes); ep != nil {
+			results = append(results, nextEpisode{
+				ItemID: item.ID, EpisodeID: ep.ID, Season: ep.Season, Episode: ep.Episode,
+				Code: media.FormatEpisode(ep.Season, ep.Episode), Title: ep.Title, ResumePosition: ep.ResumePoint(),
+			})
+		}
+	}
+	if lastErr != nil && len(episodesByShow) == 0 {
+		slog.Error("Failed to get next episodes from Kodi", "list_id", listID, "shows", queried, "error", lastErr)
+		writeKodiError(w, lastErr, "Failed to fetch episodes", http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
+
+// nextUnwatched picks the episode to watch next: the first unwatched one
+// after the last watched episode, or the earliest unwatched one when the
+// last watched episode is also the last aired. Specials (season 0) are only
+// considered when that's all there is.
+func nextUnwatched(episodes []kodi.MediaItem) *kodi.MediaItem {
+	if regular := slices.DeleteFunc(slices.Clone(episodes), func(ep kodi.MediaItem) bool { return ep.Season == 0 }); len(regular) > 0 {
+		episodes = regular
+	} else {
+		episodes = slices.Clone(episodes)
+	}
+	slices.SortFunc(episodes, func(a, b kodi.MediaItem) int {
+		return cmp.Or(cmp.Compare(a.Season, b.Season), cmp.Compare(a.Episode, b.Episode))
+	})
+
+	lastWatched := -1
+	for i, ep := range episodes {
+		if ep.Playcount > 0 {
+			lastWatched = i
+		}
+	}
+	for _, start := range []int{lastWatched + 1, 0} {
+		for i := start; i < len(episodes); i++ {
+			if episodes[i].Playcount == 0 {
+				return &episodes[i]
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"whats-next/internal/database"
+)
+
+func (s *Server) handleNotifications(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	notifications, err := s.db.GetNotifications(r.URL.Query().Get("unread") == "true")
+	if err != nil {
+		slog.Error("Failed to get notifications", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	// ?user=NAME applies that user's preferences to the in-app feed
+	if user := r.URL.Query().Get("user"); user != "" {
+		prefs, err := s.db.GetNotificationPrefs(user)
+		if err != nil {
+			slog.Error("Failed to get notification preferences", "user", user, "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if prefs != nil {
+			notifications = filterNotifications(notifications, *prefs, database.NotificationChannelInApp, time.Now())
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(notifications)
+}
+
+func (s *Server) handleNotificationRoutes(w http.ResponseWriter, r *http.Request) {
+	pathParts := strings.Split(strings.TrimPrefix(r.URL.Path, "/notifications/"), "/")
+	if pathParts[0] == "preferences" {
+		s.handleNotificationPrefs(w, r, pathParts[1:])
+		return
+	}
+	if len(pathParts) != 2 || pathParts[1] != "read" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.ParseInt(pathParts[0], 10, 64)
+	if err != nil {
+		slog.Warn("Invalid notification ID in request", "path", pathParts[0], "error", err)
+		http.Error(w, "Invalid notification ID", http.StatusBadRequest)
+		return
+	}
+	if err := s.db.MarkNotificationRead(id); err != nil {
+		slog.Error("Failed to mark notification read", "id", id, "error", err)
+		http.Error(w, "Failed to update notification", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleNotificationPrefs manages per-user notification preferences:
+// GET /notifications/preferences lists every user's, and GET, PUT or DELETE
+// /notifications/preferences/{user} reads, replaces or removes one user's.
+// A user without preferences gets everything.
+func (s *Server) handleNotificationPrefs(w http.ResponseWriter, r *http.Request, rest []string) {
+	if len(rest) == 0 || (len(rest) == 1 && rest[0] == "") {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		prefs, err := s.db.GetAllNotificationPrefs()
+		if err != nil {
+			writeDBError(w, err, "Failed to get notification preferences")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(prefs)
+		return
+	}
+	if len(rest) != 1 || strings.TrimSpace(rest[0]) == "" {
+		http.NotFound(w, r)
+		return
+	}
+	user := strings.TrimSpace(rest[0])
+
+	switch r.Method {
+	case http.MethodGet:
+		prefs, err := s.db.GetNotificationPrefs(user)
+		if err != nil {
+			writeDBError(w, err, "Failed to get notification preferences", "user", user)
+			return
+		}
+		if prefs == nil {
+			prefs = &database.NotificationPrefs{User: user, MutedKinds: []string{}, MutedChannels: []string{}}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(prefs)
+
+	case http.MethodPut:
+		var prefs database.NotificationPrefs
+		if err := json.NewDecoder(r.Body).Decode(&prefs); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		prefs.User = user
+		if err := validateNotificationPrefs(&prefs); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := s.db.SetNotificationPrefs(prefs); err != nil {
+			writeDBError(w, err, "Failed to save notification preferences", "user", user)
+			return
+		}
+		saved, err := s.db.GetNotificationPrefs(user)
+		if err != nil {
+			writeDBError(w, err, "Failed to get notification preferences", "user", user)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(saved)
+
+	case http.MethodDelete:
+		if err := s.db.DeleteNotificationPrefs(user); err != nil {
+			writeDBError(w, err, "Failed to delete notification preferences", "user", user)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// validateNotificationPrefs checks muted kinds and channels are known and
+// quiet hours are a pair of "HH:MM" times, normalizing them.
+func validateNotificationPrefs(p *database.NotificationPrefs) error {
+	for _, k := range p.MutedKinds {
+		if !slices.Contains(database.NotificationKinds, k) {
+			return fmt.Errorf("unknown notification kind %q; expected one of %s", k, strings.Join(database.NotificationKinds, ", "))
+		}
+	}
+	for _, c := range p.MutedChannels {
+		if !slices.Contains(database.NotificationChannels, c) {
+			return fmt.Errorf("unknown notification channel %q; expected one of %s", c, strings.Join(database.NotificationChannels, ", "))
+		}
+	}
+	if (p.QuietStart == "") != (p.QuietEnd == "") {
+		return fmt.Errorf("quiet_start and quiet_end must be set together")
+	}
+	if p.QuietStart == "" {
+		return nil
+	}
+	start, err := time.Parse("15:04", p.QuietStart)
+	if err != nil {
+		return fmt.Errorf("quiet_start must be HH:MM")
+	}
+	end, err := time.Parse("15:04", p.QuietEnd)
+	if err != nil {
+		return fmt.Errorf("quiet_end must be HH:MM")
+	}
+	p.QuietStart, p.QuietEnd = start.Format("15:04"), end.Format("15:04")
+	return nil
+}
+
+// filterNotifications drops the notifications prefs don't want delivered on
+// channel at now: muted kinds, everything on a muted channel, and during
+// quiet hours anything raised since they began, which is held back until
+// they end.
+func filterNotifications(notifications []database.Notification, prefs database.NotificationPrefs, channel string, now time.Time) []database.Notification {
+	if slices.Contains(prefs.MutedChannels, channel) {
+		return []database.Notification{}
+	}
+	quietSince, quiet := quietHoursStart(prefs, now)
+	out := make([]database.Notification, 0, len(notifications))
+	for _, n := range notifications {
+		if slices.Contains(prefs.MutedKinds, n.Kind) {
+			continue
+		}
+		if quiet {
+			created, err := time.Parse(time.RFC3339, n.CreatedAt)
+			if err == nil && !created.Before(quietSince) {
+				continue
+			}
+		}
+		out = append(out, n)
+	}
+	return out
+}
+
+// quietHoursStart reports whether now falls within prefs' quiet hours, in
+// now's location, and when the current quiet period began.
+func quietHoursStart(prefs database.NotificationPrefs, now time.Time) (time.Time, bool) {
+	start, err1 := time.Parse("15:04", prefs.QuietStart)
+	end, err2 := time.Parse("15:04", prefs.QuietEnd)
+	if err1 != nil || err2 != nil {
+		return time.Time{}, false
+	}
+	at := func(day time.Time, t time.Time) time.Time {
+		return time.Date(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), 0, 0, now.Location())
+	}
+	todayStart, todayEnd := at(now, start), at(now, end)
+	switch {
+	case !todayStart.After(todayEnd):
+		// Same-day window, e.g. 13:00-15:00
+		return todayStart, !now.Before(todayStart) && now.Before(todayEnd)
+	case !now.Before(todayStart):
+		// Overnight window that began this evening
+		return todayStart, true
+	case now.Before(todayEnd):
+		// Overnight window that began yesterday evening
+		return at(now.AddDate(0, 0, -1), start), true
+	}
+	return time.Time{}, false
+}
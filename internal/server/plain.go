@@ -0,0 +1,82 @@
+package server
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"whats-next/internal/database"
+)
+
+// plainLine is how an item reads on a plain rendering: its title, year and
+// runtime, with the year left out where it says nothing (episodes, seasons).
+func plainLine(item database.Item) string {
+	line := itemTitle(item)
+	if item.Year > 0 && (item.MediaType == "movie" || item.MediaType == "show") {
+		line += " (" + strconv.Itoa(item.Year) + ")"
+	}
+	if item.RuntimeFormatted != "" {
+		line += " - " + item.RuntimeFormatted
+	}
+	return line
+}
+
+// handlePlainList renders a list for displays that can't run the web app,
+// such as e-ink panels and terminal widgets: GET /lists/{id}/plain returns
+// one line per item as text/plain, or a bare HTML page with ?format=html or
+// when the client prefers HTML.
+func (s *Server) handlePlainList(w http.ResponseWriter, r *http.Request, listID int64) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "":
+		format = "text"
+		if strings.Contains(r.Header.Get("Accept"), "text/html") {
+			format = "html"
+		}
+	case "text", "html":
+	default:
+		http.Error(w, "format must be text or html", http.StatusBadRequest)
+		return
+	}
+
+	list, err := s.db.GetList(listID)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve list", "list_id", listID)
+		return
+	}
+	rev, err := s.db.ListRevision(listID)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve items", "list_id", listID)
+		return
+	}
+	items, err := s.db.GetItems(listID)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve items", "list_id", listID)
+		return
+	}
+	setRevision(w, rev)
+
+	var b strings.Builder
+	if format == "html" {
+		name := html.EscapeString(list.Name)
+		fmt.Fprintf(&b, "<!DOCTYPE html>\n<html><head><meta charset=\"utf-8\"><title>%s</title></head>\n<body>\n<h1>%s</h1>\n<ol>\n", name, name)
+		for _, item := range items {
+			fmt.Fprintf(&b, "<li>%s</li>\n", html.EscapeString(plainLine(item)))
+		}
+		b.WriteString("</ol>\n</body></html>\n")
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	} else {
+		b.WriteString(list.Name + "\n\n")
+		for i, item := range items {
+			fmt.Fprintf(&b, "%d. %s\n", i+1, plainLine(item))
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	w.Write([]byte(b.String()))
+}
@@ -0,0 +1,309 @@
+package server
+
+import (
+	"cmp"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"whats-next/internal/database"
+	"whats-next/internal/kodi"
+	"whats-next/internal/media"
+)
+
+const (
+	// maxPlanMinutes bounds the viewing window a plan is made for
+	maxPlanMinutes = 12 * 60
+	// maxPlanGroups is how many list items, in list order, plans are made from
+	maxPlanGroups = 40
+	// maxPlanEpisodes is how many upcoming episodes of a show one plan takes
+	maxPlanEpisodes = 4
+	// shortFilmRuntime is the longest a film runs and still counts as a short
+	shortFilmRuntime = 40 * 60
+	defaultPlans     = 5
+	maxPlans         = 20
+)
+
+// planEntry is something to watch in a viewing plan: a film, a short or an
+// episode, with the time left to watch it.
+type planEntry struct {
+	ItemID    int64  `json:"item_id"`
+	Kind      string `json:"kind"` // film, short or episode
+	Title     string `json:"title"`
+	ShowTitle string `json:"show_title,omitempty"`
+	EpisodeID int    `json:"episode_id,omitempty"`
+	Code      string `json:"code,omitempty"` // e.g. "S03E05"
+	Runtime   int    `json:"runtime"`        // seconds
+}
+
+// viewingPlan is a combination of list entries that fits the window, with
+// the time it leaves spare.
+type viewingPlan struct {
+	Runtime          int         `json:"runtime"` // seconds
+	RuntimeFormatted string      `json:"runtime_formatted"`
+	Spare            int         `json:"spare"` // seconds
+	Entries          []planEntry `json:"entries"`
+}
+
+// planGroup is one list item as plan choices: a film or episode on its own,
+// or the next one, two, ... episodes of a show, of which a plan takes at
+// most one.
+type planGroup struct {
+	choices [][]planEntry
+	minutes []int
+}
+
+// handlePlan proposes what to watch in a time window from the list's stored
+// runtimes: GET /lists/{id}/plan?minutes=120&limit=5. Each plan starts from
+// one of the list's unwatched items, in list order, and fills the rest of
+// the window as closely as it can, e.g. one film, or two episodes and a
+// short. Plans leaving the least time spare come first. Shows contribute
+// their next episodes from the show cache, so a show whose episodes were
+// never listed is left out. Films and episodes already started count only
+// the time left.
+func (s *Server) handlePlan(w http.ResponseWriter, r *http.Request, listID int64) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	window, err := strconv.Atoi(q.Get("minutes"))
+	if err != nil || window < 1 || window > maxPlanMinutes {
+		http.Error(w, "minutes must be between 1 and "+strconv.Itoa(maxPlanMinutes), http.StatusBadRequest)
+		return
+	}
+	limit := defaultPlans
+	if v := q.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(limit, maxPlans)
+	}
+
+	items, err := s.db.GetItems(listID)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve items", "list_id", listID)
+		return
+	}
+	var groups []planGroup
+	for _, item := range items {
+		if len(groups) == maxPlanGroups {
+			break
+		}
+		entries, err := s.planEntries(item)
+		if err != nil {
+			writeDBError(w, err, "Failed to read show cache", "list_id", listID)
+			return
+		}
+		if len(entries) == 0 {
+			continue
+		}
+		var g planGroup
+		if item.MediaType == "show" || item.MediaType == "season" {
+			for n := 1; n <= len(entries); n++ {
+				g.choices = append(g.choices, entries[:n])
+			}
+		} else {
+			g.choices = [][]planEntry{entries}
+		}
+		for _, c := range g.choices {
+			g.minutes = append(g.minutes, planMinutes(c))
+		}
+		groups = append(groups, g)
+	}
+
+	plans := []viewingPlan{}
+	seen := map[string]bool{}
+	for anchor := range groups {
+		picks := packPlan(groups, window, anchor)
+		if picks == nil {
+			continue
+		}
+		var key strings.Builder
+		plan := viewingPlan{Entries: []planEntry{}}
+		for g, pick := range picks {
+			if pick < 0 {
+				continue
+			}
+			key.WriteString(strconv.Itoa(g) + ":" + strconv.Itoa(pick) + ",")
+			for _, e := range groups[g].choices[pick] {
+				plan.Entries = append(plan.Entries, e)
+				plan.Runtime += e.Runtime
+			}
+		}
+		if seen[key.String()] {
+			continue
+		}
+		seen[key.String()] = true
+		plan.RuntimeFormatted = media.FormatRuntime(plan.Runtime)
+		plan.Spare = window*60 - plan.Runtime
+		plans = append(plans, plan)
+	}
+	slices.SortStableFunc(plans, func(a, b viewingPlan) int { return cmp.Compare(a.Spare, b.Spare) })
+	if len(plans) > limit {
+		plans = plans[:limit]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(plans)
+}
+
+// planEntries returns what item contributes to a plan: a film or episode
+// with a known runtime, or the next few episodes of a show or season. It
+// returns nothing for watched, wanted and missing items.
+func (s *Server) planEntries(item database.Item) ([]planEntry, error) {
+	if item.Wanted || item.Watched || item.MissingSince != "" {
+		return nil, nil
+	}
+	left := item.Runtime - item.ResumePosition
+	switch item.MediaType {
+	case "movie":
+		if item.Runtime == 0 || left <= 0 {
+			return nil, nil
+		}
+		kind := "film"
+		if item.Runtime <= shortFilmRuntime {
+			kind = "short"
+		}
+		return []planEntry{{ItemID: item.ID, Kind: kind, Title: item.Title, Runtime: left}}, nil
+	case "episode":
+		if item.Runtime == 0 || left <= 0 {
+			return nil, nil
+		}
+		return []planEntry{{
+			ItemID: item.ID, Kind: "episode", Title: item.Title, ShowTitle: item.ShowTitle,
+			EpisodeID: item.KodiID, Code: media.FormatEpisode(item.Season, item.Episode), Runtime: left,
+		}}, nil
+	case "show", "season":
+		episodes, err := s.upcomingEpisodes(item)
+		if err != nil {
+			return nil, err
+		}
+		var entries []planEntry
+		for _, ep := range episodes {
+			if ep.Runtime == 0 {
+				break
+			}
+			entries = append(entries, planEntry{
+				ItemID: item.ID, Kind: "episode", Title: ep.Title, ShowTitle: cmp.Or(item.ShowTitle, item.Title),
+				EpisodeID: ep.ID, Code: media.FormatEpisode(ep.Season, ep.Episode), Runtime: ep.Runtime,
+			})
+		}
+		return entries, nil
+	}
+	return nil, nil
+}
+
+// cachedEpisode is an episode as the show cache stores it. MediaItem can't
+// decode it back, since it reads episode numbers as Kodi sends them.
+type cachedEpisode struct {
+	ID      int    `json:"id"`
+	Title   string `json:"title"`
+	Season  int    `json:"season"`
+	Episode int    `json:"episode"`
+	Runtime int    `json:"runtime"`
+}
+
+// upcomingEpisodes returns up to maxPlanEpisodes episodes of a show or
+// season item from the show cache, following the episodes it has watched.
+// Episodes are assumed to be watched in order, and specials are skipped
+// unless a show has nothing else.
+func (s *Server) upcomingEpisodes(item database.Item) ([]cachedEpisode, error) {
+	seasons := []int{item.Season}
+	if item.MediaType == "show" {
+		data, _, err := s.db.GetShowCache(item.ListID, item.KodiID, database.SeasonList, showCacheTTL)
+		if err != nil || data == nil {
+			return nil, err
+		}
+		var listed []kodi.MediaItem
+		if err := json.Unmarshal(data, &listed); err != nil {
+			return nil, nil
+		}
+		seasons = seasons[:0]
+		for _, season := range listed {
+			if season.Season > 0 {
+				seasons = append(seasons, season.Season)
+			}
+		}
+		if len(seasons) == 0 && len(listed) > 0 {
+			seasons = append(seasons, 0)
+		}
+		slices.Sort(seasons)
+	}
+
+	var episodes []cachedEpisode
+	want := item.WatchedEpisodes + maxPlanEpisodes
+	for _, season := range seasons {
+		if len(episodes) >= want {
+			break
+		}
+		data, _, err := s.db.GetShowCache(item.ListID, item.KodiID, season, showCacheTTL)
+		if err != nil {
+			return nil, err
+		}
+		var listed []cachedEpisode
+		if data == nil || json.Unmarshal(data, &listed) != nil {
+			// Later seasons can't be placed without this one
+			break
+		}
+		slices.SortFunc(listed, func(a, b cachedEpisode) int { return cmp.Compare(a.Episode, b.Episode) })
+		episodes = append(episodes, listed...)
+	}
+	if len(episodes) <= item.WatchedEpisodes {
+		return nil, nil
+	}
+	return episodes[item.WatchedEpisodes:min(len(episodes), want)], nil
+}
+
+// planMinutes is the whole minutes entries take, rounded up so a plan never
+// runs over its window.
+func planMinutes(entries []planEntry) int {
+	seconds := 0
+	for _, e := range entries {
+		seconds += e.Runtime
+	}
+	return (seconds + 59) / 60
+}
+
+// packPlan picks at most one choice from each group so their minutes fill
+// window as closely as possible, always including a choice of group
+// required. It returns the choice picked from each group (-1 for none), or
+// nil when nothing of required fits.
+func packPlan(groups []planGroup, window, required int) []int {
+	// best[g][c] is the most minutes groups[:g] fill within c minutes, or -1
+	// when they can't, since required is among them and doesn't fit
+	best := make([][]int, len(groups)+1)
+	picked := make([][]int, len(groups))
+	best[0] = make([]int, window+1)
+	for g, group := range groups {
+		best[g+1] = make([]int, window+1)
+		picked[g] = make([]int, window+1)
+		for c := 0; c <= window; c++ {
+			best[g+1][c], picked[g][c] = -1, -1
+			if g != required {
+				best[g+1][c] = best[g][c]
+			}
+			for i, m := range group.minutes {
+				if m <= c && best[g][c-m] >= 0 && best[g][c-m]+m > best[g+1][c] {
+					best[g+1][c], picked[g][c] = best[g][c-m]+m, i
+				}
+			}
+		}
+	}
+	if best[len(groups)][window] < 0 {
+		return nil
+	}
+
+	picks := make([]int, len(groups))
+	c := window
+	for g := len(groups) - 1; g >= 0; g-- {
+		picks[g] = picked[g][c]
+		if picks[g] >= 0 {
+			c -= groups[g].minutes[picks[g]]
+		}
+	}
+	return picks
+}
@@ -0,0 +1,293 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"whats-next/internal/database"
+	"whats-next/internal/kodi"
+)
+
+var seekSteps = map[string]bool{"smallforward": true, "smallbackward": true, "bigforward": true, "bigbackward": true}
+
+// handlePlayerControl acts as a remote for the list's Kodi host:
+// POST /lists/{id}/player/{playpause|stop|seek}. It drives the video player,
+// or the audio player for a music list, unless ?player_id names another.
+func (s *Server) handlePlayerControl(w http.ResponseWriter, r *http.Request, listID int64, action string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	want, err := playerParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var target kodi.SeekTarget
+	switch action {
+	case "playpause", "stop":
+	case "seek":
+		if err := json.NewDecoder(r.Body).Decode(&target); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		set := 0
+		if target.Percentage != nil {
+			if *target.Percentage < 0 || *target.Percentage > 100 {
+				http.Error(w, "percentage must be between 0 and 100", http.StatusBadRequest)
+				return
+			}
+			set++
+		}
+		if target.Seconds != nil {
+			set++
+		}
+		if target.Step != "" {
+			if !seekSteps[target.Step] {
+				http.Error(w, "step must be smallforward, smallbackward, bigforward or bigbackward", http.StatusBadRequest)
+				return
+			}
+			set++
+		}
+		if set != 1 {
+			http.Error(w, "Provide exactly one of percentage, seconds or step", http.StatusBadRequest)
+			return
+		}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	client, err := s.getKodiClient(listID)
+	if err != nil {
+		writeDBError(w, err, "Failed to connect to Kodi", "list_id", listID)
+		return
+	}
+	playerID, ok, err := selectPlayer(client, want, s.listPlayerKind(listID))
+	if err != nil {
+		slog.Error("Failed to get active players from Kodi", "list_id", listID, "error", err)
+		writeKodiError(w, err, "Failed to fetch active players", http.StatusBadGateway)
+		return
+	}
+	if !ok {
+		http.Error(w, "Nothing is playing", http.StatusConflict)
+		return
+	}
+
+	var result interface{}
+	switch action {
+	case "playpause":
+		var speed int
+		if speed, err = client.PlayPause(playerID); err == nil {
+			result = map[string]interface{}{"speed": speed, "paused": speed == 0}
+		}
+	case "stop":
+		err = client.Stop(playerID)
+	case "seek":
+		result, err = client.Seek(playerID, target)
+	}
+	if err != nil {
+		slog.Error("Kodi player command failed", "action", action, "list_id", listID, "error", err)
+		writeKodiError(w, err, "Kodi rejected the command", http.StatusBadGateway)
+		return
+	}
+	if result == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// playerParam reads the optional ?player_id naming the Kodi player a
+// request targets.
+func playerParam(r *http.Request) (*int, error) {
+	v := r.URL.Query().Get("player_id")
+	if v == "" {
+		return nil, nil
+	}
+	id, err := strconv.Atoi(v)
+	if err != nil || id < 0 {
+		return nil, errors.New("player_id must be a non-negative integer")
+	}
+	return &id, nil
+}
+
+// selectPlayer returns the active player with ID want or, when want is nil,
+// the one of kind ("video", or "audio" for a music list), so commands never
+// land on another player or a slideshow unless asked to. ok is false when
+// that player isn't active.
+func selectPlayer(client *kodi.Client, want *int, kind string) (int, bool, error) {
+	players, err := client.GetActivePlayers()
+	if err != nil {
+		return 0, false, err
+	}
+	for _, p := range players {
+		if want != nil && p.PlayerID == *want || want == nil && p.Type == kind {
+			return p.PlayerID, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// handleListPlayers lists the players active on the list's Kodi host, e.g.
+// music alongside video: GET /lists/{id}/players. Their IDs can be passed as
+// ?player_id to the player, nowplaying and streams endpoints.
+func (s *Server) handleListPlayers(w http.ResponseWriter, r *http.Request, listID int64) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	client, err := s.getKodiClient(listID)
+	if err != nil {
+		writeDBError(w, err, "Failed to connect to Kodi", "list_id", listID)
+		return
+	}
+	players, err := client.GetActivePlayers()
+	if err != nil {
+		slog.Error("Failed to get active players from Kodi", "list_id", listID, "error", err)
+		writeKodiError(w, err, "Failed to fetch active players", http.StatusBadGateway)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(players)
+}
+
+// nowPlaying describes what the list's Kodi host is playing. ItemID is set
+// when the playing title is on the list, so the UI can highlight it.
+type nowPlaying struct {
+	Type       string          `json:"type"` // movie, episode, unknown
+	KodiID     int             `json:"kodi_id,omitempty"`
+	Title      string          `json:"title"`
+	ShowTitle  string          `json:"show_title,omitempty"`
+	Season     int             `json:"season,omitempty"`
+	Episode    int             `json:"episode,omitempty"`
+	Poster     string          `json:"poster_path,omitempty"`
+	Percentage float64         `json:"percentage"`
+	Time       kodi.PlayerTime `json:"time"`
+	TotalTime  kodi.PlayerTime `json:"total_time"`
+	Paused     bool            `json:"paused"`
+	ItemID     *int64          `json:"item_id"`
+}
+
+// handleNowPlaying reports the title, artwork and progress of whatever is
+// playing on the list's Kodi host: GET /lists/{id}/nowplaying. It reports the
+// video player (the audio player for a music list) unless ?player_id names
+// another, and answers 204 when that player isn't active.
+func (s *Server) handleNowPlaying(w http.ResponseWriter, r *http.Request, listID int64) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	want, err := playerParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	client, err := s.getKodiClient(listID)
+	if err != nil {
+		writeDBError(w, err, "Failed to connect to Kodi", "list_id", listID)
+		return
+	}
+	playerID, ok, err := selectPlayer(client, want, s.listPlayerKind(listID))
+	if err != nil {
+		slog.Error("Failed to get active players from Kodi", "list_id", listID, "error", err)
+		writeKodiError(w, err, "Failed to fetch active players", http.StatusBadGateway)
+		return
+	}
+	if !ok {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	playing, err := client.GetPlayingItem(playerID)
+	if err != nil {
+		slog.Error("Failed to get playing item from Kodi", "list_id", listID, "player_id", playerID, "error", err)
+		writeKodiError(w, err, "Failed to fetch playing item", http.StatusBadGateway)
+		return
+	}
+	progress, err := client.GetPlayerProgress(playerID)
+	if err != nil {
+		slog.Error("Failed to get player progress from Kodi", "list_id", listID, "player_id", playerID, "error", err)
+		writeKodiError(w, err, "Failed to fetch player progress", http.StatusBadGateway)
+		return
+	}
+
+	resp := nowPlaying{
+		Type: playing.Type, KodiID: playing.ID, Title: playing.Title, ShowTitle: playing.ShowTitle,
+		Season: playing.Season, Episode: playing.Episode,
+		Percentage: progress.Percentage, Time: progress.Time, TotalTime: progress.TotalTime, Paused: progress.Speed == 0,
+	}
+
+	items, err := s.db.GetItems(listID)
+	if err != nil {
+		// Highlighting is a convenience; still report what's playing
+		slog.Error("Failed to get list items", "list_id", listID, "error", err)
+	}
+	if item, ok := matchPlayingItem(items, playing); ok {
+		resp.ItemID = &item.ID
+		resp.Poster = item.Poster
+	}
+	if resp.Poster == "" && playing.ID != 0 {
+		resp.Poster = s.playingPoster(client, playing)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// matchPlayingItem finds the list item for the playing title: the movie or
+// episode itself, or the show or season an episode belongs to.
+func matchPlayingItem(items []database.Item, playing *kodi.PlayingItem) (database.Item, bool) {
+	var best database.Item
+	found := false
+	if playing.ID == 0 {
+		return best, false
+	}
+	for _, item := range items {
+		if item.Wanted {
+			continue
+		}
+		if item.MediaType == playing.Type && item.KodiID == playing.ID {
+			return item, true
+		}
+		if playing.Type == "song" && item.MediaType == "album" && item.KodiID == playing.AlbumID {
+			return item, true
+		}
+		if playing.Type != "episode" || item.KodiID != playing.TVShowID {
+			continue
+		}
+		// Prefer the episode's season over the whole show
+		if item.MediaType == "season" && item.Season == playing.Season {
+			best, found = item, true
+		} else if item.MediaType == "show" && !found {
+			best, found = item, true
+		}
+	}
+	return best, found
+}
+
+// playingPoster stores the artwork of a playing title that isn't on the list.
+// Episodes use their show's poster.
+func (s *Server) playingPoster(client *kodi.Client, playing *kodi.PlayingItem) string {
+	media := kodi.MediaItem{ID: playing.ID, Title: playing.Title, Year: playing.Year, Thumbnail: playing.Thumbnail, Art: playing.Art}
+	mediaType := "movie"
+	if playing.Type == "episode" {
+		media = kodi.MediaItem{ID: playing.TVShowID, Title: playing.ShowTitle, Thumbnail: playing.Art["tvshow.poster"]}
+		mediaType = "show"
+	} else if playing.Type == "song" && playing.AlbumID != 0 {
+		media = kodi.MediaItem{ID: playing.AlbumID, Title: playing.Album, Thumbnail: playing.Thumbnail, Art: playing.Art}
+		mediaType = "album"
+	}
+	poster, err := s.downloadBestImage(client, media, mediaType)
+	if err != nil {
+		slog.Warn("Failed to fetch now playing artwork", "title", playing.Title, "error", err)
+	}
+	return poster
+}
@@ -0,0 +1,279 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"whats-next/internal/storage"
+
+	// Register decoders for accepted upload formats
+	_ "image/gif"
+	_ "image/png"
+)
+
+const (
+	maxPosterUpload = 10 << 20 // 10 MiB
+	// maxPosterPixels caps the decoded size of an upload, whose compressed
+	// size says little about how much memory decoding it takes.
+	maxPosterPixels = 24 << 20
+	posterMaxWidth  = 600
+	posterMaxHeight = 900
+
+	// Poster files keep their name when artwork changes, so browsers cache
+	// them briefly and then revalidate against ETag/Last-Modified.
+	posterCacheControl = "public, max-age=3600"
+)
+
+// handleItemPoster uploads (POST) or resets (DELETE) a custom poster that
+// overrides the scraper artwork of a list item.
+func (s *Server) handleItemPoster(w http.ResponseWriter, r *http.Request, itemID int64) {
+	item, err := s.db.GetItem(itemID)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve item", "item_id", itemID)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		s.uploadItemPoster(w, r, item.ID, item.Poster, item.OriginalPoster)
+	case http.MethodDelete:
+		if item.OriginalPoster == "" {
+			http.Error(w, "Item has no custom poster", http.StatusConflict)
+			return
+		}
+		if err := s.db.SetItemPoster(itemID, item.OriginalPoster, ""); err != nil {
+			writeDBError(w, err, "Failed to reset poster", "item_id", itemID)
+			return
+		}
+		s.removeCustomPoster(item.Poster)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"poster_path": item.OriginalPoster})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) uploadItemPoster(w http.ResponseWriter, r *http.Request, itemID int64, current, original string) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxPosterUpload)
+
+	// Accept either a multipart upload ("file" field) or a raw image body
+	var body io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "Missing file upload", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		http.Error(w, "Upload too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		slog.Warn("Rejected poster upload", "item_id", itemID, "error", err)
+		http.Error(w, "Upload must be a JPEG, PNG or GIF image", http.StatusBadRequest)
+		return
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPosterPixels {
+		slog.Warn("Rejected poster upload", "item_id", itemID, "width", cfg.Width, "height", cfg.Height)
+		http.Error(w, "Upload dimensions are too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		slog.Warn("Rejected poster upload", "item_id", itemID, "error", err)
+		http.Error(w, "Upload must be a JPEG, PNG or GIF image", http.StatusBadRequest)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, resizeToFit(img, posterMaxWidth, posterMaxHeight), &jpeg.Options{Quality: 85}); err != nil {
+		slog.Error("Failed to encode poster", "item_id", itemID, "error", err)
+		http.Error(w, "Failed to store poster", http.StatusInternalServerError)
+		return
+	}
+	fileName := fmt.Sprintf("custom_item_%d_%d.jpg", itemID, time.Now().Unix())
+	if err := s.posters.Put(fileName, &buf); err != nil {
+		slog.Error("Failed to store poster", "file", fileName, "error", err)
+		http.Error(w, "Failed to store poster", http.StatusInternalServerError)
+		return
+	}
+
+	// Keep the first (Kodi) artwork around for reset; replacing an existing
+	// custom poster just discards the previous upload.
+	if original == "" {
+		original = current
+	} else {
+		s.removeCustomPoster(current)
+	}
+	publicURL := "/api/posters/" + fileName
+	if err := s.db.SetItemPoster(itemID, publicURL, original); err != nil {
+		s.posters.Delete(fileName)
+		writeDBError(w, err, "Failed to save poster", "item_id", itemID)
+		return
+	}
+
+	slog.Info("Stored custom poster", "item_id", itemID, "format", format, "file", fileName)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"poster_path": publicURL, "original_poster_path": original})
+}
+
+// removeCustomPoster deletes a previously uploaded poster file. Synced Kodi
+// artwork is shared between items and is never removed here.
+func (s *Server) removeCustomPoster(publicURL string) {
+	fileName := strings.TrimPrefix(publicURL, "/api/posters/")
+	if !strings.HasPrefix(fileName, "custom_item_") || strings.ContainsAny(fileName, `/\`) {
+		return
+	}
+	if err := s.posters.Delete(fileName); err != nil {
+		slog.Warn("Failed to remove custom poster", "file", fileName, "error", err)
+	}
+}
+
+// handlePosterFile serves a stored poster by file name.
+func (s *Server) handlePosterFile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	fileName := strings.TrimPrefix(r.URL.Path, "/posters/")
+	if fileName == "" || strings.ContainsAny(fileName, `/\`) {
+		http.NotFound(w, r)
+		return
+	}
+
+	if local, ok := s.posters.(*storage.Local); ok && s.config.PosterStorage.Sendfile != "" {
+		s.sendPosterFile(w, r, local, fileName)
+		return
+	}
+
+	rc, err := s.posters.Get(fileName)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotExist) {
+			http.NotFound(w, r)
+			return
+		}
+		slog.Error("Failed to read poster", "file", fileName, "error", err)
+		http.Error(w, "Failed to read poster", http.StatusBadGateway)
+		return
+	}
+	defer rc.Close()
+
+	// Posters are small, so remote objects are read whole; either way
+	// ServeContent answers range and conditional requests.
+	var content io.ReadSeeker
+	var modTime time.Time
+	if rs, ok := rc.(io.ReadSeeker); ok {
+		content = rs
+		if f, ok := rc.(interface{ Stat() (os.FileInfo, error) }); ok {
+			if fi, err := f.Stat(); err == nil {
+				modTime = fi.ModTime()
+				w.Header().Set("ETag", fmt.Sprintf(`"%x-%x"`, fi.Size(), fi.ModTime().UnixNano()))
+			}
+		}
+	} else {
+		data, err := io.ReadAll(io.LimitReader(rc, maxPosterUpload))
+		if err != nil {
+			slog.Error("Failed to read poster", "file", fileName, "error", err)
+			http.Error(w, "Failed to read poster", http.StatusBadGateway)
+			return
+		}
+		content = bytes.NewReader(data)
+		w.Header().Set("ETag", fmt.Sprintf(`"%x"`, crc32.ChecksumIEEE(data)))
+	}
+	w.Header().Set("Cache-Control", posterCacheControl)
+	http.ServeContent(w, r, fileName, modTime, content)
+}
+
+// sendPosterFile hands a locally stored poster to the fronting web server
+// with X-Accel-Redirect or X-Sendfile, leaving the app out of the transfer.
+func (s *Server) sendPosterFile(w http.ResponseWriter, r *http.Request, local *storage.Local, fileName string) {
+	if ok, err := local.Exists(fileName); err != nil {
+		slog.Error("Failed to read poster", "file", fileName, "error", err)
+		http.Error(w, "Failed to read poster", http.StatusBadGateway)
+		return
+	} else if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	cfg := s.config.PosterStorage
+	w.Header().Set("Cache-Control", posterCacheControl)
+	if ct := mime.TypeByExtension(path.Ext(fileName)); ct != "" {
+		w.Header().Set("Content-Type", ct)
+	}
+	switch cfg.Sendfile {
+	case storage.SendfileAccel:
+		w.Header().Set("X-Accel-Redirect", strings.TrimSuffix(cfg.SendfilePrefix, "/")+"/"+url.PathEscape(fileName))
+	case storage.SendfileXSend:
+		p, err := local.Path(fileName)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("X-Sendfile", p)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// resizeToFit scales an image down (never up) to fit within maxW x maxH,
+// averaging the source pixels covered by each destination pixel.
+func resizeToFit(src image.Image, maxW, maxH int) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= maxW && h <= maxH {
+		return src
+	}
+	scale := min(float64(maxW)/float64(w), float64(maxH)/float64(h))
+	dw, dh := max(1, int(float64(w)*scale)), max(1, int(float64(h)*scale))
+
+	// Work on RGBA pixels directly rather than through At(); draw converts
+	// the decoders' common types with its fast paths.
+	rgba, ok := src.(*image.RGBA)
+	if !ok {
+		rgba = image.NewRGBA(image.Rect(0, 0, w, h))
+		draw.Draw(rgba, rgba.Bounds(), src, b.Min, draw.Src)
+	}
+	sb := rgba.Bounds()
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		sy0, sy1 := y*h/dh, max((y+1)*h/dh, y*h/dh+1)
+		for x := 0; x < dw; x++ {
+			sx0, sx1 := x*w/dw, max((x+1)*w/dw, x*w/dw+1)
+			var sum [4]uint64
+			for sy := sy0; sy < sy1; sy++ {
+				row := rgba.Pix[rgba.PixOffset(sb.Min.X+sx0, sb.Min.Y+sy):rgba.PixOffset(sb.Min.X+sx1, sb.Min.Y+sy)]
+				for i := 0; i < len(row); i += 4 {
+					sum[0], sum[1], sum[2], sum[3] = sum[0]+uint64(row[i]), sum[1]+uint64(row[i+1]), sum[2]+uint64(row[i+2]), sum[3]+uint64(row[i+3])
+				}
+			}
+			n := uint64((sy1 - sy0) * (sx1 - sx0))
+			o := dst.PixOffset(x, y)
+			for c := range sum {
+				dst.Pix[o+c] = uint8(sum[c] / n)
+			}
+		}
+	}
+	return dst
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"whats-next/internal/database"
+	"whats-next/internal/kodi"
+)
+
+// errPVRMusicList rejects recordings and channels on music lists, which
+// queue on the audio playlist.
+var errPVRMusicList = errors.New("recordings and channels can't go on a music list")
+
+// isPVRType reports whether mediaType is a PVR recording or live channel.
+func isPVRType(mediaType string) bool {
+	return mediaType == "recording" || mediaType == "channel"
+}
+
+// handlePVR lists what a list's Kodi host has recorded or can tune to, for
+// adding to the list: GET /pvr/recordings?list_id= and
+// GET /pvr/channels?list_id=. Both are read live from Kodi's PVR.
+func (s *Server) handlePVR(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	listID, err := strconv.ParseInt(r.URL.Query().Get("list_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid list_id", http.StatusBadRequest)
+		return
+	}
+	client, err := s.getKodiClient(listID)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve list", "list_id", listID)
+		return
+	}
+
+	var result interface{}
+	switch r.URL.Path {
+	case "/pvr/recordings":
+		result, err = client.GetRecordings()
+	case "/pvr/channels":
+		result, err = client.GetChannels()
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to read Kodi PVR", "list_id", listID, "path", r.URL.Path, "error", err)
+		writeKodiError(w, err, "Failed to read the PVR", http.StatusBadGateway)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// preparePVRItem fills in a recording or channel item from Kodi's PVR by
+// its kodi_id. Recordings keep their pvr:// path, since recording IDs may
+// change when Kodi restarts and playlists only take recordings by path.
+func (s *Server) preparePVRItem(item *database.Item) error {
+	list, err := s.db.GetList(item.ListID)
+	if err != nil {
+		return err
+	}
+	if list.ContentType == "music" {
+		return errPVRMusicList
+	}
+	client, err := s.getKodiClient(item.ListID)
+	if err != nil {
+		return err
+	}
+	item.Wanted, item.Season, item.Episode, item.TVShowID = false, 0, 0, 0
+	if item.MediaType == "channel" {
+		ch, err := client.GetChannelDetails(item.KodiID)
+		if err != nil {
+			return err
+		}
+		item.Title, item.Poster, item.FilePath = ch.Channel, ch.Thumbnail, ""
+		return nil
+	}
+	rec, err := client.GetRecordingDetails(item.KodiID)
+	if err != nil {
+		return err
+	}
+	item.Title, item.ShowTitle, item.Poster = rec.Title, rec.Channel, rec.Poster()
+	item.Runtime, item.Playcount, item.Watched = rec.Runtime, rec.Playcount, rec.Playcount > 0
+	item.FilePath = rec.File
+	if rec.Resume != nil {
+		item.ResumePosition = int(rec.Resume.Position)
+	}
+	return nil
+}
+
+// pvrPlaylistItem maps a recording or channel item to what Kodi plays.
+// Live channels can only be opened, not queued.
+func pvrPlaylistItem(item database.Item) kodi.PlaylistItem {
+	if item.MediaType == "channel" {
+		return kodi.PlaylistItem{ChannelID: item.KodiID}
+	}
+	if item.FilePath != "" {
+		return kodi.PlaylistItem{File: item.FilePath}
+	}
+	return kodi.PlaylistItem{RecordingID: item.KodiID}
+}
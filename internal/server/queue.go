@@ -0,0 +1,192 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"whats-next/internal/database"
+	"whats-next/internal/kodi"
+)
+
+// playlistItemFor maps a list item to what Kodi's playlist accepts. Wanted
+// items aren't in the library yet and can't be queued; file items are queued
+// by path.
+func playlistItemFor(item database.Item) (kodi.PlaylistItem, bool) {
+	if item.MediaType == "file" && item.FilePath != "" {
+		return kodi.PlaylistItem{File: item.FilePath}, true
+	}
+	if item.Wanted || item.KodiID == 0 {
+		return kodi.PlaylistItem{}, false
+	}
+	if isPVRType(item.MediaType) {
+		return pvrPlaylistItem(item), true
+	}
+	switch item.MediaType {
+	case "movie":
+		return kodi.PlaylistItem{MovieID: item.KodiID}, true
+	case "episode":
+		return kodi.PlaylistItem{EpisodeID: item.KodiID}, true
+	case "show":
+		return kodi.ShowPlaylistItem(item.KodiID, 0), true
+	case "season":
+		return kodi.ShowPlaylistItem(item.KodiID, item.Season), true
+	case "album":
+		return kodi.PlaylistItem{AlbumID: item.KodiID}, true
+	case "artist":
+		return kodi.PlaylistItem{ArtistID: item.KodiID}, true
+	}
+	return kodi.PlaylistItem{}, false
+}
+
+// playerKindFor returns the Kodi player ("audio" or "video") that plays
+// items of mediaType.
+func playerKindFor(mediaType string) string {
+	if mediaType == "album" || mediaType == "artist" {
+		return "audio"
+	}
+	return "video"
+}
+
+// listPlayerKind returns the Kodi player a list's commands default to:
+// "audio" for a music list, otherwise "video".
+func (s *Server) listPlayerKind(listID int64) string {
+	if list, err := s.db.GetList(listID); err == nil && list.ContentType == "music" {
+		return "audio"
+	}
+	return "video"
+}
+
+// handleQueueList replaces Kodi's video playlist, or its music playlist for
+// a music list, with the list's items in sort order: POST /lists/{id}/queue.
+// ?play=true also starts playback, unless another kind of player is busy;
+// ?player_id naming that player replaces it.
+func (s *Server) handleQueueList(w http.ResponseWriter, r *http.Request, listID int64) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.queueList(w, r, listID, r.URL.Query().Get("play") == "true", false)
+}
+
+// handleQueueAll is party mode: it replaces the playlist with every
+// unwatched item of the list in sort order and starts playing it, POST
+// /lists/{id}/queue-all. Like the queue, ?player_id lets it replace another
+// kind of player.
+func (s *Server) handleQueueAll(w http.ResponseWriter, r *http.Request, listID int64) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.queueList(w, r, listID, true, true)
+}
+
+// queueList queues the list's playable items, leaving out watched ones when
+// unwatched is set, and starts playback when play is.
+func (s *Server) queueList(w http.ResponseWriter, r *http.Request, listID int64, play, unwatched bool) {
+	want, err := playerParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	items, err := s.db.GetItems(listID)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve items", "list_id", listID)
+		return
+	}
+
+	var queue []kodi.PlaylistItem
+	skipped := []int64{}
+	watched := []int64{}
+	for _, item := range items {
+		if unwatched && itemWatched(item) {
+			watched = append(watched, item.ID)
+			continue
+		}
+		pi, ok := playlistItemFor(item)
+		if !ok || pi.ChannelID != 0 {
+			// Live channels can be played but not queued
+			skipped = append(skipped, item.ID)
+			continue
+		}
+		queue = append(queue, pi)
+	}
+	if len(queue) == 0 {
+		msg := "List has no playable items"
+		if unwatched {
+			msg = "List has no unwatched playable items"
+		}
+		http.Error(w, msg, http.StatusUnprocessableEntity)
+		return
+	}
+
+	list, err := s.db.GetList(listID)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve list", "list_id", listID)
+		return
+	}
+	kind, playlistID := "video", kodi.VideoPlaylistID
+	if list.ContentType == "music" {
+		kind, playlistID = "audio", kodi.AudioPlaylistID
+	}
+	client, err := s.getKodiClient(listID)
+	if err != nil {
+		writeDBError(w, err, "Failed to connect to Kodi", "list_id", listID)
+		return
+	}
+	if play {
+		if busy, err := otherPlayer(client, want, kind); err != nil {
+			slog.Error("Failed to get active players from Kodi", "list_id", listID, "error", err)
+			writeKodiError(w, err, "Failed to fetch active players", http.StatusBadGateway)
+			return
+		} else if busy != nil {
+			http.Error(w, fmt.Sprintf("Kodi is playing %s (player %d); pass player_id=%d to replace it", busy.Type, busy.PlayerID, busy.PlayerID), http.StatusConflict)
+			return
+		}
+	}
+	if err := client.ClearPlaylist(playlistID); err != nil {
+		slog.Error("Failed to clear Kodi playlist", "list_id", listID, "error", err)
+		writeKodiError(w, err, "Failed to clear Kodi playlist", http.StatusBadGateway)
+		return
+	}
+	if err := client.AddToPlaylist(playlistID, queue); err != nil {
+		slog.Error("Failed to queue items in Kodi", "list_id", listID, "error", err)
+		writeKodiError(w, err, "Failed to queue items in Kodi", http.StatusBadGateway)
+		return
+	}
+
+	playing := false
+	if play {
+		if err := client.PlayPlaylist(playlistID, 0); err != nil {
+			slog.Error("Failed to start Kodi playlist", "list_id", listID, "error", err)
+			writeKodiError(w, err, "Queued, but failed to start playback", http.StatusBadGateway)
+			return
+		}
+		playing = true
+	}
+
+	slog.Info("Queued list in Kodi playlist", "list_id", listID, "queued", len(queue), "skipped", len(skipped), "watched", len(watched))
+	resp := map[string]interface{}{"queued": len(queue), "skipped": skipped, "playing": playing}
+	if unwatched {
+		resp["watched"] = watched
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// otherPlayer returns an active player of another kind than kind ("video"
+// or "audio") that starting playback would interrupt, unless want names it.
+func otherPlayer(client *kodi.Client, want *int, kind string) (*kodi.ActivePlayer, error) {
+	players, err := client.GetActivePlayers()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range players {
+		if p.Type != kind && (want == nil || *want != p.PlayerID) {
+			return &p, nil
+		}
+	}
+	return nil, nil
+}
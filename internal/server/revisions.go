@@ -0,0 +1,62 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ifMatchRevision reads the list revision a client last saw from If-Match,
+// e.g. `"12"`. It returns nil when the header is absent or "*", which skips
+// the check.
+func ifMatchRevision(r *http.Request) (*int64, error) {
+	v := strings.TrimSpace(r.Header.Get("If-Match"))
+	if v == "" || v == "*" {
+		return nil, nil
+	}
+	rev, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(v, "W/"), `"`), 10, 64)
+	if err != nil {
+		return nil, errors.New("If-Match must be a list revision, e.g. \"12\"")
+	}
+	return &rev, nil
+}
+
+// setRevision reports a list revision as the response's ETag.
+func setRevision(w http.ResponseWriter, rev int64) {
+	w.Header().Set("ETag", fmt.Sprintf("%q", strconv.FormatInt(rev, 10)))
+}
+
+// handleListOrder rewrites a list's order in one step: PUT /lists/{id}/order
+// with {"item_ids": [...]}. Items left out follow in their current order.
+// With If-Match, a list changed in the meantime is answered with 409, so two
+// people dragging at once can't interleave their orderings.
+func (s *Server) handleListOrder(w http.ResponseWriter, r *http.Request, listID int64) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	expected, err := ifMatchRevision(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var req struct {
+		ItemIDs []int64 `json:"item_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	rev, err := s.db.ReorderItems(listID, req.ItemIDs, expected)
+	if err != nil {
+		writeDBError(w, err, "Failed to reorder list", "list_id", listID)
+		return
+	}
+	setRevision(w, rev)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int64{"revision": rev})
+}
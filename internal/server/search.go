@@ -0,0 +1,292 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"whats-next/internal/database"
+	"whats-next/internal/kodi"
+	"whats-next/internal/media"
+)
+
+// searchResult is a library item annotated with the lists in the same group
+// that already contain it and when it was last watched, so the add dialog
+// can flag duplicates and titles already seen.
+type searchResult struct {
+	kodi.MediaItem
+	OnLists     []database.ListRef `json:"on_lists"`
+	LastWatched string             `json:"last_watched,omitempty"`
+}
+
+func (s *Server) annotateMembership(listID int64, cacheType string, items []kodi.MediaItem) []searchResult {
+	ids := make([]int, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+	membership, err := s.db.GetGroupMembership(listID, cacheType, ids)
+	if err != nil {
+		// Annotations are a convenience; still return the results
+		slog.Error("Failed to get list membership for search results", "list_id", listID, "error", err)
+	}
+	watched, err := s.db.LastWatched(listID, cacheType, ids)
+	if err != nil {
+		slog.Error("Failed to get viewing history for search results", "list_id", listID, "error", err)
+	}
+
+	results := make([]searchResult, len(items))
+	for i, item := range items {
+		onLists := membership[item.ID]
+		if onLists == nil {
+			onLists = []database.ListRef{}
+		}
+		results[i] = searchResult{MediaItem: item, OnLists: onLists, LastWatched: watched[item.ID]}
+	}
+	return results
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	listIDStr := r.URL.Query().Get("list_id")
+	searchType := r.URL.Query().Get("content_type")
+	if searchType == "" {
+		searchType = r.URL.Query().Get("type")
+	}
+	if searchType == "" {
+		searchType = "movie"
+	}
+
+	lID, err := strconv.ParseInt(listIDStr, 10, 64)
+	if err != nil {
+		slog.Warn("Invalid list_id", "id", listIDStr, "error", err)
+		http.Error(w, "Invalid list_id", http.StatusBadRequest)
+		return
+	}
+
+	cacheType := cacheTypeFor(searchType)
+
+	filter := database.SearchFilter{
+		AudioLanguage:    r.URL.Query().Get("audio_language"),
+		SubtitleLanguage: r.URL.Query().Get("subtitle_language"),
+	}
+	// plot=true always matches plot text too, plot=false never does; by
+	// default plots are only searched when no title matches.
+	plotMode := r.URL.Query().Get("plot")
+	filter.Plot = plotMode == "true"
+	filter.Genres = queryValues(r.URL.Query(), "genre")
+	if v := r.URL.Query().Get("max_runtime"); v != "" {
+		minutes, err := strconv.Atoi(v)
+		if err != nil || minutes <= 0 {
+			http.Error(w, "max_runtime must be a positive number of minutes", http.StatusBadRequest)
+			return
+		}
+		filter.MaxRuntime = minutes * 60
+	}
+	if v := r.URL.Query().Get("min_resolution"); v != "" {
+		res, err := media.ParseResolution(v)
+		if err != nil {
+			http.Error(w, "min_resolution must be a resolution such as 1080p or 4k", http.StatusBadRequest)
+			return
+		}
+		filter.MinResolution = res
+	}
+	filter.ExcludeGenres = queryValues(r.URL.Query(), "exclude_genre")
+	filter.ExcludeWatched = r.URL.Query().Get("exclude_watched") == "true"
+	for _, v := range queryValues(r.URL.Query(), "exclude_list") {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid exclude_list", http.StatusBadRequest)
+			return
+		}
+		filter.ExcludeLists = append(filter.ExcludeLists, id)
+	}
+
+	count, err := s.db.GetLibraryCacheCount(lID, cacheType)
+	if err != nil {
+		slog.Error("Failed to get cache count", "error", err)
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+
+	if count > 0 && !s.cacheBuilding(lID, cacheType) {
+		cached, err := s.db.SearchLibraryCache(lID, cacheType, query, filter)
+		if err == nil && len(cached) == 0 && plotMode == "" {
+			filter.Plot = true
+			cached, err = s.db.SearchLibraryCache(lID, cacheType, query, filter)
+		}
+		if err != nil {
+			slog.Error("Failed to search cache", "error", err)
+			http.Error(w, "Internal error", http.StatusInternalServerError)
+			return
+		}
+		var results []kodi.MediaItem
+		for _, c := range cached {
+			results = append(results, cachedMediaItem(c))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		encodeFields(w, s.annotateMembership(lID, cacheType, results), requestedFields(r), "")
+		return
+	}
+
+	b, err := s.getBackend(lID)
+	if err != nil {
+		writeDBError(w, err, "Kodi connection failed", "list_id", lID)
+		return
+	}
+	allItems, err := b.Search(searchType, query)
+	if err != nil {
+		slog.Error("Failed to fetch items from Kodi", "type", searchType, "error", err)
+		writeKodiError(w, err, "Failed to fetch items", http.StatusInternalServerError)
+		return
+	}
+
+	excluded, err := s.db.ExcludedKodiIDs(lID, cacheType, filter)
+	if err != nil {
+		slog.Error("Failed to get excluded items", "list_id", lID, "error", err)
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	candidates := filterMediaItems(allItems, filter, excluded)
+	matches := kodi.FuzzySearchWith(candidates, query, s.fuzzyOptions(lID))
+	if filter.Plot || (len(matches) == 0 && plotMode == "") {
+		matches = appendPlotMatches(matches, kodi.PlotSearch(candidates, query))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	encodeFields(w, s.annotateMembership(lID, cacheType, matches), requestedFields(r), "")
+}
+
+// cacheBuilding reports whether the library cache of cacheType that listID
+// reads is being filled by its first sync, so holds only the pages fetched
+// so far and search should ask the backend instead. Later syncs leave the
+// previous cache in place until they finish, so it stays searchable.
+func (s *Server) cacheBuilding(listID int64, cacheType string) bool {
+	lastSynced, err := s.db.LastSynced(listID, cacheType)
+	if err != nil || lastSynced != "" {
+		return false
+	}
+	client, err := s.getKodiClient(listID)
+	if err != nil {
+		return false
+	}
+	syncType := cacheType
+	if cacheType == "show" {
+		syncType = "tv"
+	}
+	building, err := s.db.LeaseHeld("sync:" + client.HostURL + ":" + syncType)
+	if err != nil {
+		slog.Warn("Failed to check for a running sync", "list_id", listID, "error", err)
+		return false
+	}
+	if building {
+		slog.Debug("Library cache is still being built, searching live", "list_id", listID, "media_type", cacheType)
+	}
+	return building
+}
+
+// fuzzyOptions returns the ranking of live searches for listID: the global
+// fuzzy settings with the list's own on top.
+func (s *Server) fuzzyOptions(listID int64) kodi.FuzzyOptions {
+	opts := s.config.Fuzzy
+	if list, err := s.db.GetList(listID); err == nil && list.Fuzzy != nil {
+		opts = opts.With(*list.Fuzzy)
+	}
+	return opts
+}
+
+// cachedMediaItem presents a library cache row in the shape Kodi search
+// results use.
+func cachedMediaItem(c database.CachedItem) kodi.MediaItem {
+	return kodi.MediaItem{
+		ID: c.KodiID, Title: c.Title, Label: c.Title, Year: c.Year, Thumbnail: c.Poster, Runtime: c.Runtime, EpisodeCount: c.EpisodeCount, WatchedEpisodes: c.WatchedEpisodes, Rating: c.Rating, Plot: c.Plot,
+		AudioLanguages: c.AudioLanguages, SubtitleLanguages: c.SubtitleLanguages, Genres: c.Genres,
+		RuntimeFormatted: media.FormatRuntime(c.Runtime),
+		Quality:          c.Quality,
+		Playcount:        c.Playcount, LastPlayed: c.LastPlayed, Resume: resumeFromCache(c), Artist: c.Artist,
+	}
+}
+
+func resumeFromCache(c database.CachedItem) *kodi.Resume {
+	if c.ResumePosition <= 0 {
+		return nil
+	}
+	return &kodi.Resume{Position: float64(c.ResumePosition), Total: float64(c.Runtime)}
+}
+
+// filterMediaItems applies a search filter to items fetched live from a backend,
+// mirroring the conditions SearchLibraryCache applies in SQL.
+// excluded holds the IDs ExcludedKodiIDs ruled out.
+func filterMediaItems(items []kodi.MediaItem, filter database.SearchFilter, excluded map[int]bool) []kodi.MediaItem {
+	var out []kodi.MediaItem
+	for _, item := range items {
+		if excluded[item.ID] {
+			continue
+		}
+		if filter.ExcludeWatched && item.EpisodeCount > 0 && item.WatchedEpisodes >= item.EpisodeCount {
+			continue
+		}
+		if slices.ContainsFunc(filter.ExcludeGenres, func(g string) bool { return containsFold(item.Genres, g) }) {
+			continue
+		}
+		if slices.ContainsFunc(filter.Genres, func(g string) bool { return !containsFold(item.Genres, g) }) {
+			continue
+		}
+		if filter.MaxRuntime > 0 && (item.Runtime == 0 || item.Runtime > filter.MaxRuntime) {
+			continue
+		}
+		if filter.MinResolution > 0 && item.VideoResolution() < filter.MinResolution {
+			continue
+		}
+		if filter.AudioLanguage != "" && !containsFold(item.AudioLanguages, filter.AudioLanguage) {
+			continue
+		}
+		if filter.SubtitleLanguage != "" && !containsFold(item.SubtitleLanguages, filter.SubtitleLanguage) {
+			continue
+		}
+		out = append(out, item)
+	}
+	return out
+}
+
+// appendPlotMatches adds plot matches below the title matches, skipping
+// items already found by title and keeping the result list short.
+func appendPlotMatches(matches, plotMatches []kodi.MediaItem) []kodi.MediaItem {
+	seen := make(map[int]bool, len(matches))
+	for _, m := range matches {
+		seen[m.ID] = true
+	}
+	for _, m := range plotMatches {
+		if len(matches) >= 20 {
+			break
+		}
+		if !seen[m.ID] {
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
+
+// queryValues collects a parameter given repeatedly or comma-separated,
+// e.g. exclude_genre=horror&exclude_genre=war or exclude_genre=horror,war.
+func queryValues(q url.Values, key string) []string {
+	var out []string
+	for _, v := range q[key] {
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				out = append(out, part)
+			}
+		}
+	}
+	return out
+}
+
+func containsFold(values []string, target string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"whats-next/internal/database"
+)
+
+// savedSearchParams are the /search query parameters a saved search may
+// hold. list_id is always the owning list.
+var savedSearchParams = map[string]bool{
+	"q": true, "content_type": true, "audio_language": true, "subtitle_language": true, "plot": true,
+	"genre": true, "max_runtime": true, "min_resolution": true, "exclude_genre": true, "exclude_list": true, "exclude_watched": true,
+}
+
+// handleSavedSearches manages a list's saved searches:
+//
+//	GET    /lists/{id}/searches                    list them
+//	POST   /lists/{id}/searches                    save {"name", "params"}
+//	DELETE /lists/{id}/searches/{searchID}         remove one
+//	GET    /lists/{id}/searches/{searchID}/results run it through /search
+func (s *Server) handleSavedSearches(w http.ResponseWriter, r *http.Request, listID int64, rest []string) {
+	if len(rest) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			searches, err := s.db.GetSavedSearches(listID)
+			if err != nil {
+				writeDBError(w, err, "Failed to retrieve saved searches", "list_id", listID)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(searches)
+		case http.MethodPost:
+			s.createSavedSearch(w, r, listID)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	searchID, err := strconv.ParseInt(rest[0], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid saved search ID", http.StatusBadRequest)
+		return
+	}
+	switch {
+	case len(rest) == 1 && r.Method == http.MethodDelete:
+		if err := s.db.DeleteSavedSearch(listID, searchID); err != nil {
+			writeDBError(w, err, "Failed to delete saved search", "list_id", listID, "search_id", searchID)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case len(rest) == 2 && rest[1] == "results" && r.Method == http.MethodGet:
+		s.runSavedSearch(w, r, listID, searchID)
+	case len(rest) <= 2:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) createSavedSearch(w http.ResponseWriter, r *http.Request, listID int64) {
+	var req struct {
+		Name   string              `json:"name"`
+		Params map[string][]string `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+	if req.Params == nil {
+		req.Params = map[string][]string{}
+	}
+	for key := range req.Params {
+		if !savedSearchParams[key] {
+			http.Error(w, "Unsupported search parameter: "+key, http.StatusBadRequest)
+			return
+		}
+	}
+
+	search := database.SavedSearch{ListID: listID, Name: req.Name, Params: req.Params}
+	id, err := s.db.AddSavedSearch(search)
+	if err != nil {
+		writeDBError(w, err, "Failed to save search", "list_id", listID)
+		return
+	}
+	saved, err := s.db.GetSavedSearch(listID, id)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve saved search", "list_id", listID, "search_id", id)
+		return
+	}
+	slog.Info("Saved search", "list_id", listID, "name", saved.Name)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(saved)
+}
+
+// runSavedSearch answers exactly as /search would for the stored parameters.
+// Searches saved without a content_type use the list's.
+func (s *Server) runSavedSearch(w http.ResponseWriter, r *http.Request, listID, searchID int64) {
+	list, err := s.db.GetList(listID)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve list", "list_id", listID)
+		return
+	}
+	search, err := s.db.GetSavedSearch(listID, searchID)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve saved search", "list_id", listID, "search_id", searchID)
+		return
+	}
+
+	query := url.Values(search.Params)
+	query.Set("list_id", strconv.FormatInt(listID, 10))
+	if query.Get("content_type") == "" {
+		query.Set("content_type", list.ContentType)
+	}
+	if fields := r.URL.Query()["fields"]; len(fields) > 0 {
+		query["fields"] = fields
+	}
+	sr := r.Clone(r.Context())
+	sr.URL.RawQuery = query.Encode()
+	s.handleSearch(w, sr)
+}
@@ -0,0 +1,149 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"whats-next/internal/database"
+)
+
+// handleSections manages the sections dividing a list:
+//
+//	GET    /lists/{id}/sections              list them in order
+//	POST   /lists/{id}/sections              add {"name"} after the others
+//	PATCH  /lists/{id}/sections/{sectionID}  rename or reorder {"name", "sort_order"}
+//	DELETE /lists/{id}/sections/{sectionID}  remove it, keeping its items
+func (s *Server) handleSections(w http.ResponseWriter, r *http.Request, listID int64, rest []string) {
+	if len(rest) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			sections, err := s.db.GetSections(listID)
+			if err != nil {
+				writeDBError(w, err, "Failed to retrieve sections", "list_id", listID)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(sections)
+		case http.MethodPost:
+			s.createSection(w, r, listID)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	if len(rest) != 1 {
+		http.NotFound(w, r)
+		return
+	}
+	sectionID, err := strconv.ParseInt(rest[0], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid section ID", http.StatusBadRequest)
+		return
+	}
+	switch r.Method {
+	case http.MethodPatch:
+		s.updateSection(w, r, listID, sectionID)
+	case http.MethodDelete:
+		if err := s.db.DeleteSection(listID, sectionID); err != nil {
+			writeDBError(w, err, "Failed to delete section", "list_id", listID, "section_id", sectionID)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) createSection(w http.ResponseWriter, r *http.Request, listID int64) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+	id, err := s.db.AddSection(database.Section{ListID: listID, Name: req.Name})
+	if err != nil {
+		writeDBError(w, err, "Failed to add section", "list_id", listID)
+		return
+	}
+	section, err := s.db.GetSection(listID, id)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve section", "list_id", listID, "section_id", id)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(section)
+}
+
+func (s *Server) updateSection(w http.ResponseWriter, r *http.Request, listID, sectionID int64) {
+	section, err := s.db.GetSection(listID, sectionID)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve section", "list_id", listID, "section_id", sectionID)
+		return
+	}
+	var req struct {
+		Name      *string `json:"name"`
+		SortOrder *int    `json:"sort_order"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Name != nil {
+		section.Name = strings.TrimSpace(*req.Name)
+		if section.Name == "" {
+			http.Error(w, "Name is required", http.StatusBadRequest)
+			return
+		}
+	}
+	if req.SortOrder != nil {
+		section.SortOrder = *req.SortOrder
+	}
+	if err := s.db.UpdateSection(*section); err != nil {
+		writeDBError(w, err, "Failed to update section", "list_id", listID, "section_id", sectionID)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(section)
+}
+
+// handleItemSection moves an item into a section of its list, or out of
+// any section with 0 or null: PATCH /items/{id}/section {"section_id": N}.
+func (s *Server) handleItemSection(w http.ResponseWriter, r *http.Request, itemID int64) {
+	if r.Method != http.MethodPatch {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		SectionID *int64 `json:"section_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	var sectionID int64
+	if req.SectionID != nil {
+		sectionID = *req.SectionID
+	}
+	if err := s.db.SetItemSection(itemID, sectionID); err != nil {
+		writeDBError(w, err, "Failed to move item", "item_id", itemID, "section_id", sectionID)
+		return
+	}
+	item, err := s.db.GetItem(itemID)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve item", "item_id", itemID)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(item)
+}
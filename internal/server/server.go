@@ -1,59 +1,175 @@
 package server
 
 import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log/slog"
 	"net/http"
-	"net/url"
 	"os"
-	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 	"unicode"
 
+	"whats-next/internal/backend"
 	"whats-next/internal/database"
 	"whats-next/internal/kodi"
+	"whats-next/internal/outbound"
+	"whats-next/internal/storage"
 )
 
 type Server struct {
 	db         *database.DB
 	config     database.Config
 	httpClient *http.Client
+	posters    storage.Store
+
+	// instanceID identifies this replica when claiming job leases.
+	instanceID string
+	jobs       sync.WaitGroup
+	tasks      taskRegistry
+	metrics    metricsRecorder
+	usage      counter[database.UsageKey]
+
+	// kodiOverride, when set, replaces every list's Kodi host (mock mode).
+	kodiOverride string
+	// kodiEventsOverride likewise replaces every host's notification address.
+	kodiEventsOverride string
+
+	// jobsCtx is cancelled when background jobs stop; work deferred by
+	// webhook events is tied to it.
+	jobsCtx context.Context
+
+	eventMu     sync.Mutex
+	eventTimers map[string]*time.Timer // pending debounced event work by key
+
+	// listenerMu guards the notification listeners; eventCtx is nil until
+	// startKodiEvents runs, and stays nil with notifications disabled.
+	listenerMu     sync.Mutex
+	eventCtx       context.Context
+	eventPort      int
+	eventListeners map[string]*eventHost // by host
+
+	authMu       sync.Mutex
+	authFailures map[string]time.Time // Kodi hosts rejecting our credentials, by first failure
+
+	kodiRetry   kodi.RetryPolicy
+	kodiClients kodiClientManager
+	jellyfin    jellyfinBackends
+
+	// outbound paces httpClient's requests to third-party APIs.
+	outbound *outbound.Transport
+
+	selfCheckMu   sync.Mutex
+	selfChecks    []selfCheck // last self-check, see runSelfCheck
+	selfCheckedAt time.Time
 }
 
-func NewServer(db *database.DB, config database.Config) *Server {
+func NewServer(db *database.DB, config database.Config, posters storage.Store) *Server {
+	transport, err := outbound.New(nil, config.ExternalAPIs)
+	if err != nil {
+		slog.Error("Invalid external_apis, using default budgets", "error", err)
+		transport, _ = outbound.New(nil, nil)
+	}
+	if err := config.Fuzzy.Validate(); err != nil {
+		slog.Error("Invalid fuzzy, using default ranking", "error", err)
+		config.Fuzzy = kodi.FuzzyOptions{}
+	}
 	return &Server{
-		db:     db,
-		config: config,
+		db:      db,
+		config:  config,
+		posters: posters,
+
+		instanceID:   newInstanceID(),
+		kodiRetry:    retryPolicy(config.KodiRetry),
+		eventTimers:  map[string]*time.Timer{},
+		authFailures: map[string]time.Time{},
+		outbound:     transport,
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:   30 * time.Second,
+			Transport: transport,
 		},
 	}
 }
 
-func (s *Server) Routes() http.Handler {
-	mux := http.NewServeMux()
-	mux.HandleFunc("/health", s.handleHealth)
-	mux.HandleFunc("/lists", s.handleLists)
-	mux.HandleFunc("/lists/", s.handleListRoutes)
-	mux.HandleFunc("/items/", s.handleItemRoutes)
-	mux.HandleFunc("/search", s.handleSearch)
-	mux.HandleFunc("/sync", s.handleSyncLibrary)
-	mux.HandleFunc("/tv/seasons", s.handleGetSeasons)
-	mux.HandleFunc("/tv/episodes", s.handleGetEpisodes)
-
-	// Serve posters from local storage
-	// Ensure directory exists
-	os.MkdirAll("data/posters", 0755)
-	mux.Handle("/posters/", http.StripPrefix("/posters/", http.FileServer(http.Dir("data/posters"))))
+// newInstanceID returns an identifier unique to this process, e.g.
+// "web-1:42:9f3a1c0b".
+func newInstanceID() string {
+	host, _ := os.Hostname()
+	b := make([]byte, 4)
+	rand.Read(b)
+	return fmt.Sprintf("%s:%d:%s", host, os.Getpid(), hex.EncodeToString(b))
+}
 
-	mux.HandleFunc("/config", s.handleGetConfig)
+// leaseHolder returns a token for one claim of a job lease: the instance ID
+// and a random suffix, so concurrent claims within this replica, and their
+// releases, never mistake each other for the same holder.
+func (s *Server) leaseHolder() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return s.instanceID + ":" + hex.EncodeToString(b)
+}
 
-	return mux
+func (s *Server) Routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", withTimeout(readTimeout, s.handleHealth))
+	mux.HandleFunc("/lists", withTimeout(readTimeout, s.handleLists))
+	mux.HandleFunc("/groups", withTimeout(writeTimeout, s.handleGroups))
+	mux.HandleFunc("/groups/", withTimeout(writeTimeout, s.handleGroups))
+	mux.HandleFunc("/dashboard", withTimeout(readTimeout, s.handleDashboard))
+	// List and item routes include imports, poster uploads and Kodi
+	// credential checks
+	mux.HandleFunc("/lists/", withTimeout(writeTimeout, s.handleListRoutes))
+	mux.HandleFunc("/federation/list", withTimeout(readTimeout, s.handleFederatedList))
+	mux.HandleFunc("/items/", withTimeout(writeTimeout, s.handleItemRoutes))
+	mux.HandleFunc("/search", withTimeout(writeTimeout, s.handleSearch))
+	mux.HandleFunc("/library/browse", withTimeout(readTimeout, s.handleBrowse))
+	mux.HandleFunc("/library/index", withTimeout(readTimeout, s.handleLibraryIndex))
+	mux.HandleFunc("/library/genres", withTimeout(writeTimeout, s.handleGenres))
+	mux.HandleFunc("/library/recent", withTimeout(writeTimeout, s.handleRecent))
+	mux.HandleFunc("/library/inprogress", withTimeout(writeTimeout, s.handleInProgress))
+	mux.HandleFunc("/library/favourites", withTimeout(writeTimeout, s.handleFavourites))
+	mux.HandleFunc("/library/files", withTimeout(writeTimeout, s.handleFiles))
+	mux.HandleFunc("/library/playlists", withTimeout(writeTimeout, s.handleKodiPlaylists))
+	mux.HandleFunc("/pvr/", withTimeout(writeTimeout, s.handlePVR))
+	mux.HandleFunc("/suggestions", withTimeout(readTimeout, s.handleSuggestions))
+	mux.HandleFunc("/kodi/events", withTimeout(readTimeout, s.handleKodiWebhook))
+	mux.HandleFunc("/sync", withTimeout(syncTimeout, s.handleSyncLibrary))
+	mux.HandleFunc("/sync/all", withTimeout(readTimeout, s.handleSyncAll))
+	mux.HandleFunc("/sync/failures", withTimeout(readTimeout, s.handlePosterFailures))
+	mux.HandleFunc("/sync/retry-failed", withTimeout(syncTimeout, s.handleRetryFailedPosters))
+	mux.HandleFunc("/tasks/", withTimeout(readTimeout, s.handleTask))
+	mux.HandleFunc("/tv/seasons", withTimeout(writeTimeout, s.handleGetSeasons))
+	mux.HandleFunc("/tv/episodes", withTimeout(writeTimeout, s.handleGetEpisodes))
+	mux.HandleFunc("/tv/details", withTimeout(writeTimeout, s.handleTVDetails))
+	mux.HandleFunc("/details", withTimeout(writeTimeout, s.handleMovieDetails))
+	mux.HandleFunc("/movies/sets", withTimeout(writeTimeout, s.handleGetMovieSets))
+	mux.HandleFunc("/movies/sets/movies", withTimeout(writeTimeout, s.handleGetSetMovies))
+
+	// Serve posters from the configured store
+	mux.HandleFunc("/posters/", withTimeout(writeTimeout, s.handlePosterFile))
+
+	mux.HandleFunc("/config", withTimeout(readTimeout, s.handleGetConfig))
+	mux.HandleFunc("/notifications", withTimeout(readTimeout, s.handleNotifications))
+	mux.HandleFunc("/notifications/", withTimeout(readTimeout, s.handleNotificationRoutes))
+	mux.HandleFunc("/admin/rehost", withTimeout(writeTimeout, s.handleRehost))
+	mux.HandleFunc("/admin/dedupe-cache", withTimeout(writeTimeout, s.handleDedupeCache))
+	mux.HandleFunc("/admin/usage", withTimeout(readTimeout, s.handleUsage))
+	mux.HandleFunc("/settings/export", withTimeout(readTimeout, s.handleExportSettings))
+	mux.HandleFunc("/settings/import", withTimeout(writeTimeout, s.handleImportSettings))
+	mux.HandleFunc("/metrics/summary", withTimeout(readTimeout, s.handleMetricsSummary))
+	mux.HandleFunc("/digest", withTimeout(writeTimeout, s.handleDigest))
+	mux.HandleFunc("/setup", withTimeout(writeTimeout, s.handleSetup))
+	mux.HandleFunc("/setup/", withTimeout(writeTimeout, s.handleSetup))
+
+	return s.countRequests(mux)
 }
 
 func (s *Server) handleGetConfig(w http.ResponseWriter, r *http.Request) {
@@ -64,33 +180,115 @@ func (s *Server) handleGetConfig(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+type kodiAuthFailure struct {
+	Host  string    `json:"host"`
+	Since time.Time `json:"since"`
+}
+
+// handleHealth reports the server as up. kodi_auth_ok is false while any
+// Kodi host is rejecting its credentials, listed in kodi_auth_failures;
+// kodi_hosts has what was last seen of every host called since startup, and
+// external_apis the traffic to third-party APIs and feeds.
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	s.authMu.Lock()
+	failures := make([]kodiAuthFailure, 0, len(s.authFailures))
+	for host, since := range s.authFailures {
+		failures = append(failures, kodiAuthFailure{Host: host, Since: since})
+	}
+	s.authMu.Unlock()
+	slices.SortFunc(failures, func(a, b kodiAuthFailure) int { return strings.Compare(a.Host, b.Host) })
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":             "ok",
+		"kodi_auth_ok":       len(failures) == 0,
+		"kodi_auth_failures": failures,
+		"kodi_hosts":         s.kodiClients.snapshot(),
+		"external_apis":      s.outbound.Stats(),
+	})
 }
 
 func (s *Server) getKodiClient(listID int64) (*kodi.Client, error) {
-	lists, err := s.db.GetAllLists()
+	list, err := s.db.GetList(listID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get lists from database: %w", err)
+		return nil, fmt.Errorf("failed to get list %d: %w", listID, err)
 	}
-	var list *database.List
-	for _, l := range lists {
-		if l.ID == listID {
-			list = &l
-			break
-		}
+	if list.Backend == database.BackendJellyfin {
+		return nil, fmt.Errorf("list %d is on Jellyfin: %w", listID, errKodiOnly)
 	}
-	if list == nil {
-		return nil, fmt.Errorf("list not found: %d", listID)
-	}
-	host := list.KodiHost
-	user := list.Username
-	pass := list.Password
-	if os.Getenv("MOCK_KODI") == "true" {
-		host = "mock"
+	// A list inheriting its group's connection reads it from the group, so
+	// the group's current host and credentials are used
+	return s.kodiClient(list.KodiHost, list.Username, list.Password, listKodiOptions(list))
+}
+
+// newKodiClient builds a client for host, honouring the mock override.
+// Whether the host answers and accepts the credentials is tracked for
+// /health. Most callers want the shared client from kodiClient instead.
+func (s *Server) newKodiClient(host, user, pass string, opts kodiOptions) (*kodi.Client, error) {
+	target := host
+	if s.kodiOverride != "" {
+		target = s.kodiOverride
+	}
+	client := kodi.NewClient(target, user, pass)
+	if err := client.SetTLS(opts.TLS); err != nil {
+		return nil, fmt.Errorf("invalid TLS options for %s: %w", host, err)
+	}
+	if opts.RPCTimeout > 0 {
+		client.HTTPClient.Timeout = opts.RPCTimeout
+	}
+	client.ImageTimeout = opts.ImageTimeout
+	client.UseTextures = s.config.TextureCache == nil || *s.config.TextureCache
+	client.OnAuth = func(ok bool) { s.recordKodiAuth(host, ok) }
+	client.OnCall = func(err error) { s.kodiClients.record(host, err) }
+	client.Retry = s.kodiRetry
+	client.PageSize = kodi.DefaultPageSize
+	if s.config.KodiPageSize > 0 {
+		client.PageSize = s.config.KodiPageSize
+	}
+	return client, nil
+}
+
+// retryPolicy applies the kodi_retry config to the default policy.
+func retryPolicy(cfg database.KodiRetryConfig) kodi.RetryPolicy {
+	p := kodi.DefaultRetryPolicy
+	if cfg.Attempts > 0 {
+		p.Attempts = cfg.Attempts
+	}
+	p.BaseDelay = jobInterval("kodi_retry.base_delay", cfg.BaseDelay, p.BaseDelay)
+	p.MaxDelay = jobInterval("kodi_retry.max_delay", cfg.MaxDelay, p.MaxDelay)
+	switch {
+	case cfg.BreakerThreshold < 0:
+		p.BreakerThreshold = 0
+	case cfg.BreakerThreshold > 0:
+		p.BreakerThreshold = cfg.BreakerThreshold
+	}
+	p.BreakerCooldown = jobInterval("kodi_retry.breaker_cooldown", cfg.BreakerCooldown, p.BreakerCooldown)
+	return p
+}
+
+func (s *Server) recordKodiAuth(host string, ok bool) {
+	s.authMu.Lock()
+	defer s.authMu.Unlock()
+	_, failing := s.authFailures[host]
+	switch {
+	case ok && failing:
+		delete(s.authFailures, host)
+		slog.Info("Kodi accepted credentials again", "host", host)
+	case !ok && !failing:
+		s.authFailures[host] = time.Now()
+		slog.Warn("Kodi rejected credentials", "host", host)
 	}
-	return kodi.NewClient(host, user, pass), nil
+}
+
+// UseKodiHost points every list at hostURL, e.g. an in-process mock Kodi.
+func (s *Server) UseKodiHost(hostURL string) {
+	s.kodiOverride = hostURL
+}
+
+// UseKodiEvents subscribes to notifications at addr (host:port) for every
+// Kodi host, e.g. an in-process mock Kodi.
+func (s *Server) UseKodiEvents(addr string) {
+	s.kodiEventsOverride = addr
 }
 
 func slugify(s string) string {
@@ -118,33 +316,81 @@ func init() {
 		}
 	}()
 }
-func (s *Server) downloadBestImage(client *kodi.Client, item kodi.MediaItem, mediaType string) (string, error) {
-	var imageURI string
-	if item.Art != nil {
-		if val, ok := item.Art["poster"]; ok && val != "" {
-			imageURI = val
-		} else if val, ok := item.Art["thumb"]; ok && val != "" {
-			imageURI = val
+
+// bestImageURI picks the Kodi artwork to store for item: the first of the
+// preferred art types Kodi has, then the poster, the thumb art and the plain
+// thumbnail. It also returns the art type picked ("" for the thumbnail).
+func bestImageURI(item kodi.MediaItem, prefs []string) (uri, artType string) {
+	for _, t := range append(slices.Clone(prefs), "poster", "thumb") {
+		if val := item.Art[t]; val != "" {
+			return val, t
 		}
 	}
+	return item.Thumbnail, ""
+}
 
-	if imageURI == "" {
-		imageURI = item.Thumbnail
+func (s *Server) downloadBestImage(b backend.MediaBackend, item kodi.MediaItem, mediaType string) (string, error) {
+	return s.downloadArt(b, item, mediaType, nil)
+}
+
+// extraArtTypes are the art types stored alongside the poster so cards can
+// show richer artwork.
+var extraArtTypes = []string{"fanart", "banner", "clearlogo"}
+
+// downloadExtraArt stores each of extraArtTypes Kodi has for item. Failures
+// are logged and skipped; the poster is what matters for a sync.
+func (s *Server) downloadExtraArt(b backend.MediaBackend, item kodi.MediaItem, mediaType string) []database.ItemArt {
+	return s.storeExtraArt(b, item, mediaType, nil)
+}
+
+// storeExtraArt is downloadExtraArt, replacing the stored files of the art
+// types in replace.
+func (s *Server) storeExtraArt(b backend.MediaBackend, item kodi.MediaItem, mediaType string, replace []string) []database.ItemArt {
+	var stored []database.ItemArt
+	for _, t := range extraArtTypes {
+		if item.Art[t] == "" {
+			continue
+		}
+		url, err := s.storeArt(b, item, mediaType, []string{t}, slices.Contains(replace, t))
+		if err != nil {
+			slog.Warn("Failed to download artwork", "media_type", mediaType, "kodi_id", item.ID, "art_type", t, "error", err)
+			continue
+		}
+		if url != "" {
+			stored = append(stored, database.ItemArt{KodiID: item.ID, ArtType: t, URL: url})
+		}
+	}
+	return stored
+}
+
+// downloadArt stores the artwork bestImageURI picks for prefs. Art other than
+// the poster or thumb is kept in a file suffixed with its type.
+func (s *Server) downloadArt(b backend.MediaBackend, item kodi.MediaItem, mediaType string, prefs []string) (string, error) {
+	return s.storeArt(b, item, mediaType, prefs, false)
+}
+
+// artFileName is the poster store file an item's artwork of artType is
+// kept in.
+func artFileName(item kodi.MediaItem, mediaType, artType string) string {
+	if artType != "" && artType != "poster" && artType != "thumb" {
+		return fmt.Sprintf("%s_%s_%d_%s.jpg", mediaType, slugify(item.Title), item.Year, artType)
 	}
+	return fmt.Sprintf("%s_%s_%d.jpg", mediaType, slugify(item.Title), item.Year)
+}
 
+// storeArt is downloadArt, replacing a stored file when replace is set
+// rather than keeping it.
+func (s *Server) storeArt(b backend.MediaBackend, item kodi.MediaItem, mediaType string, prefs []string, replace bool) (string, error) {
+	imageURI, artType := bestImageURI(item, prefs)
 	if imageURI == "" {
 		return "", nil
 	}
-	if client.HostURL == "mock" {
-		return imageURI, nil
-	}
 
-	fileName := fmt.Sprintf("%s_%s_%d.jpg", mediaType, slugify(item.Title), item.Year)
-	localPath := filepath.Join("data/posters", fileName)
+	fileName := artFileName(item, mediaType, artType)
 	publicURL := "/api/posters/" + fileName
 
 	// Fast path: check if file already exists
-	if _, err := os.Stat(localPath); err == nil {
+	if ok, _ := s.posters.Exists(fileName); ok && !replace {
 		return publicURL, nil
 	}
 
@@ -155,61 +401,73 @@ func (s *Server) downloadBestImage(client *kodi.Client, item kodi.MediaItem, med
 	defer mu.Unlock()
 
 	// Check again after acquiring lock
-	if _, err := os.Stat(localPath); err == nil {
+	if ok, _ := s.posters.Exists(fileName); ok && !replace {
 		return publicURL, nil
 	}
 
-	// Kodi serves images at [HOST]/image/[ENCODED_URI]
-	encodedURI := url.QueryEscape(imageURI)
-	targetURL := client.HostURL + "/image/" + encodedURI
-	if !strings.HasPrefix(targetURL, "http") {
-		targetURL = "http://" + targetURL
-	}
-
-	slog.Info("Downloading best image", "media_type", mediaType, "kodi_id", item.ID, "title", item.Title, "local_path", localPath)
+	slog.Info("Downloading best image", "media_type", mediaType, "kodi_id", item.ID, "title", item.Title, "file", fileName)
 
-	req, err := http.NewRequest("GET", targetURL, nil)
-	if err != nil {
-		slog.Error("Invalid request for image", "url", targetURL, "error", err)
-		return "", err
+	body, err := b.GetArtwork(imageURI)
+	if errors.Is(err, kodi.ErrImageNotFound) {
+		slog.Warn("Image not found on the media server (404)", "title", item.Title, "uri", imageURI)
+		return "", nil // Return empty, not error, to keep sync going
 	}
-	if client.Username != "" {
-		req.SetBasicAuth(client.Username, client.Password)
-	}
-
-	resp, err := s.httpClient.Do(req)
 	if err != nil {
-		slog.Error("Network error downloading image", "media_type", mediaType, "kodi_id", item.ID, "error", err)
+		slog.Error("Failed to download image", "media_type", mediaType, "kodi_id", item.ID, "uri", imageURI, "error", err)
 		return "", err
 	}
-	defer resp.Body.Close()
+	defer body.Close()
 
-	if resp.StatusCode != 200 {
-		if resp.StatusCode == 404 {
-			slog.Warn("Image not found on Kodi (404)", "title", item.Title, "url", targetURL)
-			return "", nil // Return empty, not error, to keep sync going
-		}
-		slog.Error("Kodi returned error status for image", "status_code", resp.StatusCode, "url", targetURL)
-		return "", fmt.Errorf("kodi image error: %d", resp.StatusCode)
-	}
-
-	out, err := os.Create(localPath)
-	if err != nil {
-		slog.Error("File creation error", "path", localPath, "error", err)
+	if err := s.posters.Put(fileName, body); err != nil {
+		slog.Error("Failed to store image", "file", fileName, "error", err)
 		return "", err
 	}
-	defer out.Close()
 
-	n, err := io.Copy(out, resp.Body)
-	if err != nil {
-		slog.Error("Copy error", "path", localPath, "error", err)
-		return "", err
+	slog.Info("Successfully saved image", "file", fileName)
+	return publicURL, nil
+}
+
+// downloadItemPoster stores the artwork at imageURI locally for a list item,
+// returning the /api/posters URL.
+func (s *Server) downloadItemPoster(b backend.MediaBackend, item database.Item, imageURI string) (string, error) {
+	// Convert database item to MediaItem format for downloader
+	tempMedia := kodi.MediaItem{
+		ID:        item.KodiID,
+		Title:     item.Title,
+		Year:      item.Year,
+		Thumbnail: imageURI,
+	}
+	// Map it correctly for filename generation
+	saveType := "movie"
+	switch item.MediaType {
+	case "show", "season":
+		saveType = "show"
+	case "set", "album", "artist", "recording", "channel":
+		saveType = item.MediaType
+	case "episode":
+		// Episodes share their show's poster file
+		saveType = "show"
+		if item.TVShowID != 0 {
+			tempMedia.ID, tempMedia.Title, tempMedia.Year = item.TVShowID, item.ShowTitle, item.Year
+		} else {
+			saveType = "episode"
+		}
 	}
+	return s.downloadBestImage(b, tempMedia, saveType)
+}
 
-	slog.Info("Successfully saved image", "path", localPath, "bytes", n)
-	return publicURL, nil
+// listView is a list as the API returns it. Kodi credentials are
+// write-only: the empty Username and Password hide the list's own, and
+// HasCredentials tells whether any are set.
+type listView struct {
+	database.List
+	Username       string `json:"username,omitempty"`
+	Password       string `json:"password,omitempty"`
+	HasCredentials bool   `json:"has_credentials"`
 }
 
+// handleLists returns the lists in use, or with ?archived=true the archived
+// ones, grouped in the groups' display order.
 func (s *Server) handleLists(w http.ResponseWriter, r *http.Request) {
 	lists, err := s.db.GetAllLists()
 	if err != nil {
@@ -217,13 +475,32 @@ func (s *Server) handleLists(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to retrieve lists", http.StatusInternalServerError)
 		return
 	}
+	groups, err := s.db.GetGroups()
+	if err != nil {
+		slog.Error("Failed to get groups from database", "error", err)
+		http.Error(w, "Failed to retrieve lists", http.StatusInternalServerError)
+		return
+	}
+	position := make(map[string]int, len(groups))
+	for n, g := range groups {
+		position[g.Name] = n
+	}
+	slices.SortStableFunc(lists, func(a, b database.List) int { return cmp.Compare(position[a.GroupName], position[b.GroupName]) })
+	archived := r.URL.Query().Get("archived") == "true"
+	views := make([]listView, 0, len(lists))
+	for _, l := range lists {
+		if (l.ArchivedAt != "") != archived {
+			continue
+		}
+		views = append(views, listView{List: l, HasCredentials: l.Username != "" || l.Password != ""})
+	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(lists)
+	json.NewEncoder(w).Encode(views)
 }
 
 func (s *Server) handleListRoutes(w http.ResponseWriter, r *http.Request) {
 	pathParts := strings.Split(strings.TrimPrefix(r.URL.Path, "/lists/"), "/")
-	if len(pathParts) < 2 || pathParts[1] != "items" {
+	if len(pathParts) < 2 && (pathParts[0] == "" || r.Method != http.MethodDelete) {
 		http.NotFound(w, r)
 		return
 	}
@@ -234,16 +511,188 @@ func (s *Server) handleListRoutes(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid list ID", http.StatusBadRequest)
 		return
 	}
+	if len(pathParts) == 1 {
+		s.handleDeleteList(w, r, listID)
+		return
+	}
+
+	bulkWatched := pathParts[1] == "items" && len(pathParts) == 3 && pathParts[2] == "watched"
+	if r.Method != http.MethodGet && mirroredListRoutes[pathParts[1]] && !bulkWatched && s.refuseMirrored(w, listID) {
+		return
+	}
 
+	switch pathParts[1] {
+	case "items":
+		if bulkWatched {
+			s.handleBulkWatched(w, r, listID)
+			return
+		}
+		s.handleListItems(w, r, listID)
+	case "plain":
+		s.handlePlainList(w, r, listID)
+	case "pin":
+		s.handleListPin(w, r, listID)
+	case "order":
+		s.handleListOrder(w, r, listID)
+	case "streams":
+		s.handleGetPlayerStreams(w, r, listID)
+	case "kodi-playlist":
+		s.handleKodiPlaylist(w, r, listID)
+	case "import":
+		s.handleImport(w, r, listID)
+	case "pending":
+		s.handlePendingMatches(w, r, listID)
+	case "credentials":
+		s.handleUpdateCredentials(w, r, listID)
+	case "test":
+		s.handleTestConnection(w, r, listID)
+	case "migration":
+		s.handleHostMigration(w, r, listID)
+	case "queue":
+		s.handleQueueList(w, r, listID)
+	case "queue-all":
+		s.handleQueueAll(w, r, listID)
+	case "index":
+		s.handleListIndex(w, r, listID)
+	case "searches":
+		s.handleSavedSearches(w, r, listID, pathParts[2:])
+	case "feeds":
+		s.handleFeeds(w, r, listID, pathParts[2:])
+	case "shares":
+		s.handleListShares(w, r, listID, pathParts[2:])
+	case "remote":
+		s.handleRemoteList(w, r, listID, pathParts[2:])
+	case "history":
+		s.handleHistory(w, r, listID, pathParts[2:])
+	case "sections":
+		s.handleSections(w, r, listID, pathParts[2:])
+	case "missing":
+		s.handleMissingItems(w, r, listID)
+	case "next-episodes":
+		s.handleNextEpisodes(w, r, listID)
+	case "plan":
+		s.handlePlan(w, r, listID)
+	case "clone":
+		s.handleCloneList(w, r, listID)
+	case "archive", "restore":
+		s.handleArchiveList(w, r, listID, pathParts[1])
+	case "scan", "clean":
+		s.handleLibraryJob(w, r, listID, pathParts[1])
+	case "nowplaying":
+		s.handleNowPlaying(w, r, listID)
+	case "players":
+		s.handleListPlayers(w, r, listID)
+	case "player":
+		if len(pathParts) != 3 {
+			http.NotFound(w, r)
+			return
+		}
+		s.handlePlayerControl(w, r, listID, pathParts[2])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// itemsResponse is GET /lists/{id}/items: the list's items, or their
+// franchise groups with ?group_by=franchise, along with what a client needs
+// to head them.
+type itemsResponse struct {
+	List   itemsListContext `json:"list"`
+	Items  interface{}      `json:"items,omitempty"`
+	Groups interface{}      `json:"groups,omitempty"`
+}
+
+type itemsListContext struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"list_name"`
+	GroupName   string `json:"group_name"`
+	ContentType string `json:"content_type"`
+	// LastSynced is when the library the list draws from was last synced,
+	// or "" if it never was.
+	LastSynced string `json:"last_synced"`
+}
+
+func (s *Server) listContext(listID int64) (itemsListContext, error) {
+	list, err := s.db.GetList(listID)
+	if err != nil {
+		return itemsListContext{}, err
+	}
+	lastSynced, err := s.db.LastSynced(listID, cacheTypeFor(list.ContentType))
+	if err != nil {
+		return itemsListContext{}, err
+	}
+	return itemsListContext{
+		ID:          list.ID,
+		Name:        list.Name,
+		GroupName:   list.GroupName,
+		ContentType: list.ContentType,
+		LastSynced:  lastSynced,
+	}, nil
+}
+
+func (s *Server) handleListItems(w http.ResponseWriter, r *http.Request, listID int64) {
 	if r.Method == http.MethodGet {
-		items, err := s.db.GetItems(listID)
+		getItems := s.db.GetItems
+		if r.URL.Query().Get("archived") == "true" {
+			getItems = s.db.GetArchivedItems
+		}
+		groupBy := r.URL.Query().Get("group_by")
+		if groupBy != "" && groupBy != "franchise" {
+			http.Error(w, "Invalid group_by parameter", http.StatusBadRequest)
+			return
+		}
+		sortBy := r.URL.Query().Get("sort")
+		if sortBy != "" && sortBy != "title" {
+			http.Error(w, "Invalid sort parameter", http.StatusBadRequest)
+			return
+		}
+		offset, limit, err := pageParams(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if groupBy != "" && (sortBy != "" || offset > 0 || limit > 0) {
+			http.Error(w, "group_by can't be combined with sort, offset or limit", http.StatusBadRequest)
+			return
+		}
+		// Read first, so a change racing the query makes the ETag stale
+		// rather than newer than the items
+		rev, err := s.db.ListRevision(listID)
+		if err != nil {
+			writeDBError(w, err, "Failed to retrieve items", "list_id", listID)
+			return
+		}
+		items, err := getItems(listID)
 		if err != nil {
-			slog.Error("Failed to get items from database", "list_id", listID, "error", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			writeDBError(w, err, "Failed to retrieve items", "list_id", listID)
+			return
+		}
+		resp := itemsResponse{}
+		if resp.List, err = s.listContext(listID); err != nil {
+			writeDBError(w, err, "Failed to retrieve items", "list_id", listID)
 			return
 		}
+		if groupBy == "franchise" {
+			sets, err := s.db.ItemSets(listID)
+			if err != nil {
+				// Title heuristics still group most franchises
+				slog.Error("Failed to get movie sets of items", "list_id", listID, "error", err)
+			}
+			resp.Groups, err = selectFields(groupByFranchise(items, sets), requestedFields(r), "items")
+		} else {
+			if sortBy == "title" {
+				sortByTitle(items, func(i database.Item) string { return i.Title })
+			}
+			resp.Items, err = selectFields(pageOf(items, offset, limit), requestedFields(r), "")
+		}
+		if err != nil {
+			slog.Error("Failed to select response fields", "list_id", listID, "error", err)
+			http.Error(w, "Internal error", http.StatusInternalServerError)
+			return
+		}
+		setRevision(w, rev)
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(items)
+		json.NewEncoder(w).Encode(resp)
 		return
 	}
 
@@ -255,27 +704,79 @@ func (s *Server) handleListRoutes(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		item.ListID = listID
+		if item.SectionID != 0 {
+			if _, err := s.db.GetSection(listID, item.SectionID); err != nil {
+				writeDBError(w, err, "Failed to add item", "list_id", listID)
+				return
+			}
+		}
+
+		if item.Wanted {
+			// Free-form wishlist entry: only title/year are meaningful until a
+			// later sync links it to a library item.
+			item.Title = strings.TrimSpace(item.Title)
+			if item.Title == "" {
+				http.Error(w, "Wanted items need a title", http.StatusBadRequest)
+				return
+			}
+			list, err := s.db.GetList(listID)
+			if err != nil {
+				writeDBError(w, err, "Failed to add item", "list_id", listID)
+				return
+			}
+			item.KodiID = 0
+			item.Poster = ""
+			item.MediaType = cacheTypeFor(list.ContentType)
+		} else if item.MediaType == "file" {
+			if strings.TrimSpace(item.FilePath) == "" {
+				http.Error(w, "File items need a file_path", http.StatusBadRequest)
+				return
+			}
+			if err := s.prepareFileItem(&item); errors.Is(err, errOutsideSources) {
+				http.Error(w, "file_path must be within one of Kodi's video sources", http.StatusBadRequest)
+				return
+			} else if err != nil {
+				slog.Error("Failed to check file source", "list_id", listID, "path", item.FilePath, "error", err)
+				writeKodiError(w, err, "Failed to check Kodi sources", http.StatusBadGateway)
+				return
+			}
+		} else if isPVRType(item.MediaType) {
+			if err := s.preparePVRItem(&item); errors.Is(err, kodi.ErrNotFound) {
+				http.Error(w, "Not found in Kodi's PVR", http.StatusNotFound)
+				return
+			} else if errors.Is(err, errPVRMusicList) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			} else if errors.Is(err, errKodiOnly) {
+				writeDBError(w, err, "Failed to add item", "list_id", listID)
+				return
+			} else if err != nil {
+				slog.Error("Failed to get PVR item from Kodi", "list_id", listID, "media_type", item.MediaType, "kodi_id", item.KodiID, "error", err)
+				writeKodiError(w, err, "Failed to read the PVR", http.StatusBadGateway)
+				return
+			}
+		} else if item.MediaType == "episode" {
+			if err := s.enrichEpisode(&item); errors.Is(err, kodi.ErrNotFound) {
+				http.Error(w, "Episode not found in the Kodi library", http.StatusNotFound)
+				return
+			} else if err != nil {
+				writeDBError(w, err, "Failed to add item", "list_id", listID)
+				return
+			}
+		} else {
+			s.enrichFromCache(&item)
+			if poster := s.preferredPoster(item); poster != "" {
+				item.Poster = poster
+			}
+		}
 
 		// Ensure we have a local poster if it's a remote URL
 		if strings.HasPrefix(item.Poster, "image://") || strings.HasPrefix(item.Poster, "http") {
-			client, err := s.getKodiClient(listID)
+			b, err := s.getBackend(listID)
 			if err != nil {
-				slog.Error("Failed to get Kodi client", "list_id", listID, "error", err)
+				slog.Error("Failed to get media backend", "list_id", listID, "error", err)
 			} else {
-				// Convert database item to MediaItem format for downloader
-				tempMedia := kodi.MediaItem{
-					ID:        item.KodiID,
-					Title:     item.Title,
-					Year:      item.Year,
-					Thumbnail: item.Poster,
-				}
-				// Map it correctly for filename generation
-				saveType := "movie"
-				if item.MediaType == "show" || item.MediaType == "season" {
-					saveType = "show"
-				}
-
-				localURL, err := s.downloadBestImage(client, tempMedia, saveType)
+				localURL, err := s.downloadItemPoster(b, item, item.Poster)
 				if err != nil {
 					slog.Warn("Failed to download poster image", "error", err)
 				} else if localURL != "" {
@@ -286,11 +787,11 @@ func (s *Server) handleListRoutes(w http.ResponseWriter, r *http.Request) {
 
 		id, err := s.db.AddItem(item)
 		if err != nil {
-			slog.Error("Failed to add item to database", "error", err)
-			http.Error(w, "Failed to add item", http.StatusInternalServerError)
+			writeDBError(w, err, "Failed to add item", "list_id", listID)
 			return
 		}
 		item.ID = id
+		s.prewarmShow(item)
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(item)
 		return
@@ -299,6 +800,33 @@ func (s *Server) handleListRoutes(w http.ResponseWriter, r *http.Request) {
 	http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 }
 
+// enrichFromCache fills in library metadata the client doesn't send when an
+// item is added (e.g. stream languages) from the synced library cache.
+func (s *Server) enrichFromCache(item *database.Item) {
+	cacheType := item.MediaType
+	if cacheType == "season" {
+		cacheType = "show"
+	}
+	cached, err := s.db.GetCachedItem(item.ListID, item.KodiID, cacheType)
+	if err != nil {
+		return
+	}
+	if len(item.AudioLanguages) == 0 {
+		item.AudioLanguages = cached.AudioLanguages
+	}
+	if len(item.SubtitleLanguages) == 0 {
+		item.SubtitleLanguages = cached.SubtitleLanguages
+	}
+	item.IMDbID = cached.IMDbID
+	item.TMDbID = cached.TMDbID
+	item.Resolution = cached.Resolution
+	item.HDRType = cached.HDRType
+	item.Quality = cached.Quality
+	if item.Artist == "" {
+		item.Artist = cached.Artist
+	}
+}
+
 func (s *Server) handleItemRoutes(w http.ResponseWriter, r *http.Request) {
 	pathParts := strings.Split(strings.TrimPrefix(r.URL.Path, "/items/"), "/")
 	if len(pathParts) < 1 {
@@ -313,188 +841,81 @@ func (s *Server) handleItemRoutes(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if r.Method == http.MethodDelete {
-		if err := s.db.DeleteItem(id); err != nil {
-			slog.Error("Failed to delete item", "item_id", id, "error", err)
-			http.Error(w, "Failed to delete item", http.StatusInternalServerError)
-			return
-		}
-		w.WriteHeader(http.StatusNoContent)
-		return
-	}
-
-	if len(pathParts) == 2 && pathParts[1] == "reorder" {
-		var req struct {
-			SortOrder int `json:"sort_order"`
-		}
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			slog.Warn("Invalid request body for reorder", "error", err)
-			http.Error(w, "Invalid body", http.StatusBadRequest)
+	if route := strings.Join(pathParts[1:], "/"); r.Method != http.MethodGet && mirroredItemRoutes[route] {
+		item, err := s.db.GetItem(id)
+		if err != nil {
+			writeDBError(w, err, "Failed to retrieve item", "item_id", id)
 			return
 		}
-		if err := s.db.UpdateItemOrder(id, req.SortOrder); err != nil {
-			slog.Error("Failed to update item order", "item_id", id, "error", err)
-			http.Error(w, "Failed to update order", http.StatusInternalServerError)
+		if s.refuseMirrored(w, item.ListID) {
 			return
 		}
-		w.WriteHeader(http.StatusOK)
-		return
-	}
-
-	http.Error(w, "Not found", http.StatusNotFound)
-}
-
-func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
-	query := r.URL.Query().Get("q")
-	listIDStr := r.URL.Query().Get("list_id")
-	searchType := r.URL.Query().Get("content_type")
-	if searchType == "" {
-		searchType = r.URL.Query().Get("type")
-	}
-	if searchType == "" {
-		searchType = "movie"
 	}
 
-	lID, err := strconv.ParseInt(listIDStr, 10, 64)
-	if err != nil {
-		slog.Warn("Invalid list_id", "id", listIDStr, "error", err)
-		http.Error(w, "Invalid list_id", http.StatusBadRequest)
+	if len(pathParts) == 2 && pathParts[1] == "poster" {
+		s.handleItemPoster(w, r, id)
 		return
 	}
 
-	cacheType := "movie"
-	if searchType == "tv" {
-		cacheType = "show"
-	}
-
-	count, err := s.db.GetLibraryCacheCount(lID, cacheType)
-	if err != nil {
-		slog.Error("Failed to get cache count", "error", err)
-		http.Error(w, "Internal error", http.StatusInternalServerError)
+	if len(pathParts) == 2 && pathParts[1] == "watched" {
+		s.handleItemWatched(w, r, id)
 		return
 	}
 
-	if count > 0 {
-		cached, err := s.db.SearchLibraryCache(lID, cacheType, query)
-		if err != nil {
-			slog.Error("Failed to search cache", "error", err)
-			http.Error(w, "Internal error", http.StatusInternalServerError)
-			return
-		}
-		var results []kodi.MediaItem
-		for _, c := range cached {
-			results = append(results, kodi.MediaItem{
-				ID: c.KodiID, Title: c.Title, Label: c.Title, Year: c.Year, Thumbnail: c.Poster, Runtime: c.Runtime, EpisodeCount: c.EpisodeCount, Rating: c.Rating, Plot: c.Plot,
-			})
-		}
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(results)
+	if len(pathParts) == 2 && pathParts[1] == "section" {
+		s.handleItemSection(w, r, id)
 		return
 	}
 
-	client, err := s.getKodiClient(lID)
-	if err != nil {
-		slog.Error("Failed to get Kodi client", "error", err)
-		http.Error(w, "Kodi connection failed", http.StatusInternalServerError)
+	if len(pathParts) == 2 && pathParts[1] == "restore" {
+		s.handleRestoreItem(w, r, id)
 		return
 	}
 
-	var allItems []kodi.MediaItem
-	if searchType == "tv" {
-		allItems, err = client.GetTVShows()
-	} else {
-		allItems, err = client.GetMovies()
-	}
-
-	if err != nil {
-		slog.Error("Failed to fetch items from Kodi", "type", searchType, "error", err)
-		http.Error(w, "Failed to fetch items", http.StatusInternalServerError)
+	if len(pathParts) == 2 && pathParts[1] == "play" {
+		s.handlePlayItem(w, r, id)
 		return
 	}
 
-	matches := kodi.FuzzySearch(allItems, query)
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(matches)
-}
-
-func (s *Server) handleSyncLibrary(w http.ResponseWriter, r *http.Request) {
-	listID, err := strconv.ParseInt(r.URL.Query().Get("list_id"), 10, 64)
-	if err != nil {
-		http.Error(w, "Invalid list_id", http.StatusBadRequest)
+	if len(pathParts) == 2 && pathParts[1] == "expand" {
+		s.handleExpandItem(w, r, id)
 		return
 	}
-	syncType := r.URL.Query().Get("content_type")
-	if syncType == "" {
-		syncType = r.URL.Query().Get("type")
-	}
-	if syncType == "" {
-		syncType = "movie"
-	}
-
-	client, err := s.getKodiClient(listID)
-	if err != nil {
-		slog.Error("Failed to get Kodi client", "error", err)
-		http.Error(w, "Kodi connection failed", http.StatusInternalServerError)
-		return
-	}
-
-	var itemsToCache []database.CachedItem
-	var mu sync.Mutex
-	var wg sync.WaitGroup
-	sem := make(chan struct{}, 8)
-
-	var items []kodi.MediaItem
-	var mediaType string
 
-	if syncType == "tv" {
-		items, err = client.GetTVShows()
-		mediaType = "show"
-	} else {
-		items, err = client.GetMovies()
-		mediaType = "movie"
-	}
-
-	if err != nil {
-		slog.Error("Error getting items from Kodi", "type", syncType, "error", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if len(pathParts) == 1 && r.Method == http.MethodDelete {
+		if err := s.db.DeleteItem(id); err != nil {
+			writeDBError(w, err, "Failed to delete item", "item_id", id)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
 		return
 	}
 
-	slog.Info("Starting parallel sync", "type", syncType, "count", len(items))
-	for _, item := range items {
-		wg.Go(func() {
-			sem <- struct{}{}
-			defer func() { <-sem }()
-			defer func() {
-				if r := recover(); r != nil {
-					slog.Error("Panic in sync library goroutine", "panic", r, "media_type", mediaType, "kodi_id", item.ID, "title", item.Title)
-				}
-			}() // Prevent crash on panic while logging
-
-			poster, _ := s.downloadBestImage(client, item, mediaType)
-
-			mu.Lock()
-			itemsToCache = append(itemsToCache, database.CachedItem{
-				ListID: listID, KodiID: item.ID, MediaType: mediaType, Title: item.Title, Year: item.Year, Poster: poster, Runtime: item.Runtime, EpisodeCount: item.EpisodeCount, Rating: item.Rating, Plot: item.Plot,
-			})
-			mu.Unlock()
-		})
-	}
-
-	wg.Wait()
-	slog.Info("Finished sync", "count", len(itemsToCache))
-
-	if err := s.db.ClearLibraryCache(listID, mediaType); err != nil {
-		slog.Error("Failed to clear cache", "error", err)
-	}
-	if err := s.db.AddToLibraryCache(itemsToCache); err != nil {
-		slog.Error("Failed to save cache", "error", err)
-		http.Error(w, "Failed to save cache", http.StatusInternalServerError)
+	if len(pathParts) == 2 && pathParts[1] == "reorder" {
+		expected, err := ifMatchRevision(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var req struct {
+			SortOrder int `json:"sort_order"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			slog.Warn("Invalid request body for reorder", "error", err)
+			http.Error(w, "Invalid body", http.StatusBadRequest)
+			return
+		}
+		rev, err := s.db.MoveItem(id, req.SortOrder, expected)
+		if err != nil {
+			writeDBError(w, err, "Failed to update order", "item_id", id)
+			return
+		}
+		setRevision(w, rev)
+		w.WriteHeader(http.StatusOK)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{"status": "success", "count": len(itemsToCache)})
+	http.Error(w, "Not found", http.StatusNotFound)
 }
 
 func (s *Server) handleGetSeasons(w http.ResponseWriter, r *http.Request) {
@@ -510,20 +931,7 @@ func (s *Server) handleGetSeasons(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid list_id parameter", http.StatusBadRequest)
 		return
 	}
-	client, err := s.getKodiClient(listID)
-	if err != nil {
-		slog.Error("Failed to get Kodi client for seasons", "list_id", listID, "error", err)
-		http.Error(w, "Failed to connect to Kodi", http.StatusInternalServerError)
-		return
-	}
-	seasons, err := client.GetSeasons(showID)
-	if err != nil {
-		slog.Error("Failed to get seasons from Kodi", "show_id", showID, "error", err)
-		http.Error(w, "Failed to fetch seasons", http.StatusInternalServerError)
-		return
-	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(seasons)
+	s.serveShowListing(w, listID, showID, database.SeasonList)
 }
 
 func (s *Server) handleGetEpisodes(w http.ResponseWriter, r *http.Request) {
@@ -545,18 +953,40 @@ func (s *Server) handleGetEpisodes(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid list_id parameter", http.StatusBadRequest)
 		return
 	}
+	s.serveShowListing(w, listID, showID, season)
+}
+
+func (s *Server) handleGetPlayerStreams(w http.ResponseWriter, r *http.Request, listID int64) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	want, err := playerParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	client, err := s.getKodiClient(listID)
 	if err != nil {
-		slog.Error("Failed to get Kodi client for episodes", "list_id", listID, "error", err)
-		http.Error(w, "Failed to connect to Kodi", http.StatusInternalServerError)
+		writeDBError(w, err, "Failed to connect to Kodi", "list_id", listID)
+		return
+	}
+	playerID, ok, err := selectPlayer(client, want, "video")
+	if err != nil {
+		slog.Error("Failed to get active players from Kodi", "list_id", listID, "error", err)
+		writeKodiError(w, err, "Failed to fetch active players", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		w.WriteHeader(http.StatusNoContent)
 		return
 	}
-	episodes, err := client.GetEpisodes(showID, season)
+	streams, err := client.GetPlayerStreams(playerID)
 	if err != nil {
-		slog.Error("Failed to get episodes from Kodi", "show_id", showID, "season", season, "error", err)
-		http.Error(w, "Failed to fetch episodes", http.StatusInternalServerError)
+		slog.Error("Failed to get player streams from Kodi", "list_id", listID, "player_id", playerID, "error", err)
+		writeKodiError(w, err, "Failed to fetch player streams", http.StatusInternalServerError)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(episodes)
+	json.NewEncoder(w).Encode(streams)
 }
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"whats-next/internal/database"
+	"whats-next/internal/kodi/kodimock"
+	"whats-next/internal/storage"
+)
+
+// testEnv is a server on a fresh database and poster store, talking to a
+// mock Kodi serving the built-in demo library.
+type testEnv struct {
+	t    *testing.T
+	srv  *Server
+	db   *database.DB
+	api  *httptest.Server
+	kodi *kodimock.Server
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	db, err := database.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	posters, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocal: %v", err)
+	}
+	lib, err := kodimock.LoadLibrary("")
+	if err != nil {
+		t.Fatalf("LoadLibrary: %v", err)
+	}
+	mock := kodimock.Start(lib)
+	t.Cleanup(mock.Close)
+
+	srv := NewServer(db, database.Config{}, posters)
+	srv.UseKodiHost(mock.URL)
+	t.Cleanup(srv.WaitForJobs)
+	api := httptest.NewServer(srv.Routes())
+	t.Cleanup(api.Close)
+	return &testEnv{t: t, srv: srv, db: db, api: api, kodi: mock}
+}
+
+// addList creates a list of contentType on the mock Kodi.
+func (e *testEnv) addList(name, contentType string) *database.List {
+	e.t.Helper()
+	l, err := e.db.CreateList(database.List{GroupName: "Test", Name: name, ContentType: contentType, KodiHost: "kodi.test:8080"})
+	if err != nil {
+		e.t.Fatalf("CreateList: %v", err)
+	}
+	return l
+}
+
+// do sends a request with body encoded as JSON, unless nil, and returns the
+// response with its body read.
+func (e *testEnv) do(method, path string, body interface{}) (*http.Response, []byte) {
+	e.t.Helper()
+	return e.doWith(method, path, body, nil)
+}
+
+// doWith is do with extra request headers.
+func (e *testEnv) doWith(method, path string, body interface{}, header map[string]string) (*http.Response, []byte) {
+	e.t.Helper()
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			e.t.Fatalf("marshal body: %v", err)
+		}
+		r = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, e.api.URL+path, r)
+	if err != nil {
+		e.t.Fatalf("NewRequest: %v", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.api.Client().Do(req)
+	if err != nil {
+		e.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		e.t.Fatalf("read %s %s: %v", method, path, err)
+	}
+	return resp, data
+}
+
+// expect sends a request like do and fails the test unless it is answered
+// with status, decoding the response into out when it isn't nil.
+func (e *testEnv) expect(status int, method, path string, body, out interface{}) {
+	e.t.Helper()
+	resp, data := e.do(method, path, body)
+	if resp.StatusCode != status {
+		e.t.Fatalf("%s %s: got %d, want %d: %s", method, path, resp.StatusCode, status, data)
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			e.t.Fatalf("%s %s: decode %s: %v", method, path, data, err)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"whats-next/internal/database"
+	"whats-next/internal/kodi"
+)
+
+// handleGetMovieSets lists the movie sets on a list's Kodi host:
+// GET /movies/sets?list_id=. A set is added to a list like any other item,
+// with media_type "set" and its set ID as kodi_id.
+func (s *Server) handleGetMovieSets(w http.ResponseWriter, r *http.Request) {
+	listID, err := strconv.ParseInt(r.URL.Query().Get("list_id"), 10, 64)
+	if err != nil {
+		slog.Warn("Invalid list_id in movie sets request", "list_id", r.URL.Query().Get("list_id"), "error", err)
+		http.Error(w, "Invalid list_id parameter", http.StatusBadRequest)
+		return
+	}
+	client, err := s.getKodiClient(listID)
+	if err != nil {
+		writeDBError(w, err, "Failed to connect to Kodi", "list_id", listID)
+		return
+	}
+	sets, err := client.GetMovieSets()
+	if err != nil {
+		slog.Error("Failed to get movie sets from Kodi", "list_id", listID, "error", err)
+		writeKodiError(w, err, "Failed to fetch movie sets", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sets)
+}
+
+// handleGetSetMovies lists the movies in a set:
+// GET /movies/sets/movies?list_id=&setid=.
+func (s *Server) handleGetSetMovies(w http.ResponseWriter, r *http.Request) {
+	setID, err := strconv.Atoi(r.URL.Query().Get("setid"))
+	if err != nil {
+		slog.Warn("Invalid setid in request", "setid", r.URL.Query().Get("setid"), "error", err)
+		http.Error(w, "Invalid setid parameter", http.StatusBadRequest)
+		return
+	}
+	listID, err := strconv.ParseInt(r.URL.Query().Get("list_id"), 10, 64)
+	if err != nil {
+		slog.Warn("Invalid list_id in set movies request", "list_id", r.URL.Query().Get("list_id"), "error", err)
+		http.Error(w, "Invalid list_id parameter", http.StatusBadRequest)
+		return
+	}
+	client, err := s.getKodiClient(listID)
+	if err != nil {
+		writeDBError(w, err, "Failed to connect to Kodi", "list_id", listID)
+		return
+	}
+	movies, err := client.GetMovieSetMovies(setID)
+	if err != nil {
+		slog.Error("Failed to get movie set from Kodi", "set_id", setID, "error", err)
+		writeKodiError(w, err, "Failed to fetch movie set", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(movies)
+}
+
+// handleExpandItem replaces a movie set on a list with the movies in it:
+// POST /items/{id}/expand. The movies take the set's place and section;
+// ones already on the list are left where they are.
+func (s *Server) handleExpandItem(w http.ResponseWriter, r *http.Request, itemID int64) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	item, err := s.db.GetItem(itemID)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve item", "item_id", itemID)
+		return
+	}
+	if item.MediaType != "set" {
+		http.Error(w, "Only movie sets can be expanded", http.StatusBadRequest)
+		return
+	}
+
+	client, err := s.getKodiClient(item.ListID)
+	if err != nil {
+		writeDBError(w, err, "Failed to connect to Kodi", "list_id", item.ListID)
+		return
+	}
+	movies, err := client.GetMovieSetMovies(item.KodiID)
+	if err != nil {
+		slog.Error("Failed to get movie set from Kodi", "set_id", item.KodiID, "error", err)
+		writeKodiError(w, err, "Failed to fetch movie set", http.StatusBadGateway)
+		return
+	}
+
+	items := make([]database.Item, 0, len(movies))
+	for _, m := range movies {
+		items = append(items, s.setMovieItem(client, item.ListID, m))
+	}
+	added, err := s.db.ExpandItem(itemID, items)
+	if err != nil {
+		writeDBError(w, err, "Failed to expand item", "item_id", itemID)
+		return
+	}
+	slog.Info("Expanded movie set", "item_id", itemID, "title", item.Title, "movies", len(movies), "added", len(added))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(added)
+}
+
+// setMovieItem builds the list item for a movie in a set, from the library
+// cache when it has been synced and from Kodi's own data otherwise.
+func (s *Server) setMovieItem(client *kodi.Client, listID int64, m kodi.MediaItem) database.Item {
+	if cached, err := s.db.GetCachedItem(listID, m.ID, "movie"); err == nil {
+		item := itemFromCache(listID, cached)
+		if poster := s.preferredPoster(item); poster != "" {
+			item.Poster = poster
+		}
+		return item
+	}
+	item := database.Item{
+		ListID: listID, KodiID: m.ID, MediaType: "movie", Title: m.Title, Year: m.Year,
+		Runtime: m.Runtime, Rating: m.Rating, Watched: m.Playcount > 0, Playcount: m.Playcount,
+		LastPlayed: m.LastPlayed, ResumePosition: m.ResumePoint(), IMDbID: m.IMDbID(), TMDbID: m.TMDbID(),
+		Resolution: m.VideoResolution(), HDRType: m.HDRType(), Quality: m.Quality,
+		AudioLanguages: m.AudioLanguages, SubtitleLanguages: m.SubtitleLanguages,
+	}
+	if m.Thumbnail != "" {
+		if poster, err := s.downloadItemPoster(client, item, m.Thumbnail); err != nil {
+			slog.Warn("Failed to download poster image", "kodi_id", m.ID, "error", err)
+		} else {
+			item.Poster = poster
+		}
+	}
+	return item
+}
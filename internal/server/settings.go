@@ -0,0 +1,112 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"whats-next/internal/database"
+)
+
+// settingsProfileVersion is bumped when the profile format changes
+// incompatibly.
+const settingsProfileVersion = 1
+
+// handleExportSettings returns this instance's settings profile as a JSON
+// download: GET /settings/export. Hosts, credentials and list items are left
+// out so the profile can be shared.
+func (s *Server) handleExportSettings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	lists, err := s.db.ExportListSettings()
+	if err != nil {
+		writeDBError(w, err, "Failed to export settings")
+		return
+	}
+
+	groups := []database.Group{}
+	seen := map[string]bool{}
+	for _, l := range lists {
+		if !seen[l.GroupName] {
+			seen[l.GroupName] = true
+			groups = append(groups, database.Group{Name: l.GroupName})
+		}
+	}
+
+	profile := database.SettingsProfile{
+		Version: settingsProfileVersion,
+		ProfileSettings: database.ProfileSettings{
+			Subtitle:               s.config.Subtitle,
+			Footer:                 s.config.Footer,
+			ShowRefreshInterval:    s.config.ShowRefreshInterval,
+			FeedRefreshInterval:    s.config.FeedRefreshInterval,
+			HistoryRefreshInterval: s.config.HistoryRefreshInterval,
+		},
+		Groups: groups,
+		Lists:  lists,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="whats-next-settings.json"`)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(profile)
+}
+
+// handleImportSettings applies a settings profile: POST /settings/import.
+// Groups need a kodi_host for any of their lists that don't exist here yet.
+// Branding and schedules are stored and take effect on the next start,
+// unless config.json sets them itself. ?dry_run=true returns the report
+// without applying the profile.
+func (s *Server) handleImportSettings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var profile database.SettingsProfile
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&profile); err != nil {
+		http.Error(w, "Invalid settings profile", http.StatusBadRequest)
+		return
+	}
+	if profile.Version != settingsProfileVersion {
+		http.Error(w, "Unsupported settings profile version", http.StatusBadRequest)
+		return
+	}
+	for _, l := range profile.Lists {
+		if l.GroupName == "" || l.Name == "" {
+			http.Error(w, "Every list needs a group_name and list_name", http.StatusBadRequest)
+			return
+		}
+	}
+
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+	report, err := s.db.ImportListSettings(profile.Groups, profile.Lists, dryRun)
+	if err != nil {
+		writeDBError(w, err, "Failed to import settings")
+		return
+	}
+	if dryRun {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":           "dry_run",
+			"report":           report,
+			"restart_required": profile.ProfileSettings != database.ProfileSettings{},
+			"dry_run":          true,
+		})
+		return
+	}
+	if err := s.db.StoreImportedSettings(profile.ProfileSettings); err != nil {
+		writeDBError(w, err, "Failed to store imported settings")
+		return
+	}
+	slog.Info("Imported settings profile", "lists_created", report.ListsCreated, "lists_updated", report.ListsUpdated)
+	s.refreshKodiEvents()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":           "success",
+		"report":           report,
+		"restart_required": profile.ProfileSettings != database.ProfileSettings{},
+	})
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"whats-next/internal/database"
+)
+
+func TestImportSettingsDryRunChangesNothing(t *testing.T) {
+	e := newTestEnv(t)
+	list := e.addList("Movies", "movie")
+	profile := map[string]interface{}{
+		"version": settingsProfileVersion,
+		"groups":  []database.Group{{Name: "Friends", KodiHost: "friends.test:8080"}},
+		"lists": []database.ListSettings{
+			{GroupName: list.GroupName, Name: list.Name, ContentType: "movie", Sections: []string{"Tonight", "Tonight"}},
+			{GroupName: "Friends", Name: "Horror", ContentType: "movie", Feeds: []string{"https://feeds.example/horror"}},
+		},
+	}
+
+	var preview, applied struct {
+		Report database.SettingsImport `json:"report"`
+	}
+	e.expect(http.StatusOK, http.MethodPost, "/settings/import?dry_run=true", profile, &preview)
+	want := database.SettingsImport{ListsCreated: 1, ListsUpdated: 1, Sections: 1, Feeds: 1}
+	if preview.Report != want {
+		t.Fatalf("dry run report = %+v, want %+v", preview.Report, want)
+	}
+	lists, err := e.db.GetAllLists()
+	if err != nil {
+		t.Fatalf("GetAllLists: %v", err)
+	}
+	if len(lists) != 1 {
+		t.Fatalf("dry run left %d lists, want 1", len(lists))
+	}
+	groups, err := e.db.GetGroups()
+	if err != nil {
+		t.Fatalf("GetGroups: %v", err)
+	}
+	for _, g := range groups {
+		if g.Name == "Friends" {
+			t.Fatal("dry run added the Friends group")
+		}
+	}
+
+	e.expect(http.StatusOK, http.MethodPost, "/settings/import", profile, &applied)
+	if applied.Report != want {
+		t.Fatalf("import report = %+v, want the dry run's %+v", applied.Report, want)
+	}
+
+	// A new list whose group has no host fails the preview like the import
+	profile["lists"] = []database.ListSettings{{GroupName: "Nowhere", Name: "Lost", ContentType: "movie"}}
+	e.expect(http.StatusBadRequest, http.MethodPost, "/settings/import?dry_run=true", profile, nil)
+}
@@ -0,0 +1,282 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"whats-next/internal/database"
+)
+
+// selfCheckProbe is the poster store file the self-check writes and
+// removes again.
+const selfCheckProbe = ".self-check"
+
+// selfCheck is the outcome of one startup check: the database, the poster
+// store, or a Kodi host ("kodi:<host>").
+type selfCheck struct {
+	Name  string `json:"name"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// setupSteps says which steps of the setup wizard are done.
+type setupSteps struct {
+	List       bool `json:"list"`
+	Connection bool `json:"connection"`
+	Sync       bool `json:"sync"`
+	Branding   bool `json:"branding"`
+}
+
+// runSelfCheck checks that the database and poster store can be written and
+// every Kodi host answers, and keeps the results for GET /setup. Failures
+// are logged as warnings rather than stopping the server, so a fresh
+// instance can still be set up from the UI.
+func (s *Server) runSelfCheck() []selfCheck {
+	checks := []selfCheck{
+		checkResult("database", s.db.SetState("self_check", time.Now().UTC().Format(time.RFC3339))),
+	}
+	err := s.posters.Put(selfCheckProbe, bytes.NewReader([]byte("ok")))
+	if err == nil {
+		err = s.posters.Delete(selfCheckProbe)
+	}
+	checks = append(checks, checkResult("posters", err))
+
+	lists, err := s.db.GetAllLists()
+	if err != nil {
+		checks = append(checks, checkResult("lists", err))
+	}
+	seen := map[string]bool{}
+	for _, l := range lists {
+		if l.Backend == database.BackendJellyfin || l.KodiHost == "" || seen[l.KodiHost] {
+			continue
+		}
+		seen[l.KodiHost] = true
+		check := selfCheck{Name: "kodi:" + l.KodiHost, OK: true}
+		if result := s.testConnection(&l); !result.AuthOK {
+			check.OK, check.Error = false, result.Error
+		}
+		checks = append(checks, check)
+	}
+
+	for _, c := range checks {
+		if !c.OK {
+			slog.Warn("Self-check failed", "check", c.Name, "error", c.Error)
+		}
+	}
+	s.selfCheckMu.Lock()
+	s.selfChecks, s.selfCheckedAt = checks, time.Now().UTC()
+	s.selfCheckMu.Unlock()
+	return checks
+}
+
+func checkResult(name string, err error) selfCheck {
+	if err != nil {
+		return selfCheck{Name: name, Error: err.Error()}
+	}
+	return selfCheck{Name: name, OK: true}
+}
+
+// handleSetup serves the first-run setup wizard under /setup.
+func (s *Server) handleSetup(w http.ResponseWriter, r *http.Request) {
+	switch strings.Trim(strings.TrimPrefix(r.URL.Path, "/setup"), "/") {
+	case "":
+		s.handleSetupStatus(w, r)
+	case "test":
+		s.handleSetupTest(w, r)
+	case "lists":
+		s.handleSetupList(w, r)
+	case "branding":
+		s.handleSetupBranding(w, r)
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// handleSetupStatus reports whether the instance still needs setting up:
+// GET /setup. first_run is true until it has a list; steps says which of the
+// wizard's steps are done and checks has the last self-check, which
+// ?recheck=true runs again. branding is what's in use until the next start.
+func (s *Server) handleSetupStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if r.URL.Query().Get("recheck") == "true" {
+		s.runSelfCheck()
+	}
+	lists, err := s.db.GetAllLists()
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve lists")
+		return
+	}
+	imported, err := s.db.ImportedSettings()
+	if err != nil {
+		writeDBError(w, err, "Failed to read imported settings")
+		return
+	}
+
+	s.selfCheckMu.Lock()
+	checks, checkedAt := s.selfChecks, s.selfCheckedAt
+	s.selfCheckMu.Unlock()
+	if checks == nil {
+		checks = []selfCheck{}
+	}
+
+	steps := setupSteps{
+		List: len(lists) > 0,
+		// Branding set in config.json can't be told from the defaults
+		Branding: imported.Subtitle != "" || imported.Footer != "",
+	}
+	for _, c := range checks {
+		steps.Connection = steps.Connection || (c.OK && strings.HasPrefix(c.Name, "kodi:"))
+	}
+	for _, l := range lists {
+		if synced, _ := s.db.LastSynced(l.ID, syncTypeFor(l.ContentType)); synced != "" {
+			steps.Sync = true
+			break
+		}
+	}
+
+	status := map[string]interface{}{
+		"first_run": len(lists) == 0,
+		"lists":     len(lists),
+		"steps":     steps,
+		"checks":    checks,
+		"branding": map[string]string{
+			"subtitle": s.config.Subtitle,
+			"footer":   s.config.Footer,
+		},
+	}
+	if !checkedAt.IsZero() {
+		status["checked_at"] = checkedAt.Format(time.RFC3339)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// handleSetupTest tests a Kodi host before any list uses it: POST
+// /setup/test with kodi_host, username and password. The result is that of
+// POST /lists/{id}/test.
+func (s *Server) handleSetupTest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var list database.List
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&list); err != nil || list.KodiHost == "" {
+		http.Error(w, "kodi_host is required", http.StatusBadRequest)
+		return
+	}
+	if list.Backend == database.BackendJellyfin {
+		http.Error(w, "Only Kodi hosts can be tested", http.StatusBadRequest)
+		return
+	}
+	result := s.testConnection(&list)
+	slog.Info("Tested Kodi connection", "host", list.KodiHost, "reachable", result.Reachable, "auth_ok", result.AuthOK, "version", result.Version)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// handleSetupList creates a list: POST /setup/lists with the fields a list
+// takes in config.json, and "sync": true to start its first sync as a
+// background task. A Kodi host is tested first, and an unreachable one
+// refused with 502 and the test result unless ?force=true. The list is kept
+// in the database; config.json needn't be edited.
+func (s *Server) handleSetupList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		database.List
+		Sync bool `json:"sync"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if req.GroupName == "" || req.Name == "" {
+		http.Error(w, "group_name and list_name are required", http.StatusBadRequest)
+		return
+	}
+	kodiHost := req.KodiHost != "" && req.Backend != database.BackendJellyfin
+	if kodiHost && r.URL.Query().Get("force") != "true" {
+		if result := s.testConnection(&req.List); !result.AuthOK {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadGateway)
+			json.NewEncoder(w).Encode(result)
+			return
+		}
+	}
+
+	list, err := s.db.CreateList(req.List)
+	if err != nil {
+		writeDBError(w, err, "Failed to create list")
+		return
+	}
+	slog.Info("Created list from setup", "list_id", list.ID, "group", list.GroupName, "list", list.Name)
+	if kodiHost {
+		s.refreshKodiEvents()
+		s.jobs.Go(func() { s.runSelfCheck() })
+	}
+
+	resp := map[string]interface{}{
+		"list": listView{List: *list, HasCredentials: list.Username != "" || list.Password != ""},
+	}
+	if req.Sync && list.Backend != database.BackendJellyfin {
+		syncType := syncTypeFor(list.ContentType)
+		t := s.startTask("sync", func() (interface{}, error) {
+			count, err := s.syncLibrary(list.ID, syncType, true)
+			return map[string]interface{}{"list_id": list.ID, "content_type": syncType, "count": count, "poster_failures": s.posterFailureCount(list.ID, syncType)}, err
+		})
+		resp["sync_task"] = t.ID
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleSetupBranding sets the subtitle and footer: PUT /setup/branding.
+// Like a settings import, they apply from the next start, and config.json
+// still wins for any it sets.
+func (s *Server) handleSetupBranding(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Subtitle *string `json:"subtitle"`
+		Footer   *string `json:"footer"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	settings, err := s.db.ImportedSettings()
+	if err != nil {
+		writeDBError(w, err, "Failed to read imported settings")
+		return
+	}
+	if req.Subtitle != nil {
+		settings.Subtitle = *req.Subtitle
+	}
+	if req.Footer != nil {
+		settings.Footer = *req.Footer
+	}
+	if err := s.db.StoreImportedSettings(settings); err != nil {
+		writeDBError(w, err, "Failed to store branding")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"subtitle":         settings.Subtitle,
+		"footer":           settings.Footer,
+		"restart_required": true,
+	})
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"whats-next/internal/database"
+	"whats-next/internal/kodi"
+)
+
+const (
+	defaultShelfLimit = 20
+	maxShelfLimit     = 100
+)
+
+// handleRecent lists the newest additions to a list's Kodi library:
+// GET /library/recent?list_id=&content_type=movie|tv&limit=.
+func (s *Server) handleRecent(w http.ResponseWriter, r *http.Request) {
+	s.handleShelf(w, r, "recently added", func(client *kodi.Client, tv bool, limit int) ([]kodi.MediaItem, error) {
+		if tv {
+			return client.GetRecentlyAddedEpisodes(limit)
+		}
+		return client.GetRecentlyAddedMovies(limit)
+	})
+}
+
+// handleInProgress lists what was stopped part way through on a list's Kodi
+// host, most recently played first:
+// GET /library/inprogress?list_id=&content_type=movie|tv&limit=.
+func (s *Server) handleInProgress(w http.ResponseWriter, r *http.Request) {
+	s.handleShelf(w, r, "in progress", func(client *kodi.Client, tv bool, limit int) ([]kodi.MediaItem, error) {
+		var items []kodi.MediaItem
+		var err error
+		if tv {
+			items, err = client.GetInProgressEpisodes()
+		} else {
+			items, err = client.GetInProgressMovies()
+		}
+		if len(items) > limit {
+			items = items[:limit]
+		}
+		return items, err
+	})
+}
+
+// handleShelf parses the shared shelf parameters, fetches the items and
+// annotates them like search results. For TV the items are episodes and
+// on_lists and last_watched describe the show they belong to.
+func (s *Server) handleShelf(w http.ResponseWriter, r *http.Request, name string, fetch func(client *kodi.Client, tv bool, limit int) ([]kodi.MediaItem, error)) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	listID, err := strconv.ParseInt(q.Get("list_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid list_id", http.StatusBadRequest)
+		return
+	}
+	tv := q.Get("content_type") == "tv"
+	limit := defaultShelfLimit
+	if v := q.Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(limit, maxShelfLimit)
+	}
+
+	client, err := s.getKodiClient(listID)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve list", "list_id", listID)
+		return
+	}
+	items, err := fetch(client, tv, limit)
+	if err != nil {
+		slog.Error("Failed to get "+name+" items from Kodi", "list_id", listID, "error", err)
+		writeKodiError(w, err, "Failed to fetch "+name+" items", http.StatusBadGateway)
+		return
+	}
+
+	var results []searchResult
+	if tv {
+		results = s.annotateEpisodes(listID, items)
+	} else {
+		results = s.annotateMembership(listID, "movie", items)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
+
+// annotateEpisodes annotates episodes with the group membership and viewing
+// history of their shows, since shows rather than episodes go on lists.
+func (s *Server) annotateEpisodes(listID int64, episodes []kodi.MediaItem) []searchResult {
+	showIDs := make([]int, len(episodes))
+	for i, e := range episodes {
+		showIDs[i] = e.TVShowID
+	}
+	membership, err := s.db.GetGroupMembership(listID, "show", showIDs)
+	if err != nil {
+		slog.Error("Failed to get list membership for episodes", "list_id", listID, "error", err)
+	}
+	watched, err := s.db.LastWatched(listID, "show", showIDs)
+	if err != nil {
+		slog.Error("Failed to get viewing history for episodes", "list_id", listID, "error", err)
+	}
+
+	results := make([]searchResult, len(episodes))
+	for i, e := range episodes {
+		onLists := membership[e.TVShowID]
+		if onLists == nil {
+			onLists = []database.ListRef{}
+		}
+		results[i] = searchResult{MediaItem: e, OnLists: onLists, LastWatched: watched[e.TVShowID]}
+	}
+	return results
+}
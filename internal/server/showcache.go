@@ -0,0 +1,126 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"whats-next/internal/backend"
+	"whats-next/internal/database"
+	"whats-next/internal/kodi"
+)
+
+// showCacheTTL is how long cached season and episode listings are served
+// without asking Kodi. Older listings are refreshed on the next request, and
+// still served if the host can't be reached.
+const showCacheTTL = 6 * time.Hour
+
+// prewarmSlots bounds how many shows are prewarmed at once, so adding a
+// batch of shows doesn't flood the Kodi host.
+var prewarmSlots = make(chan struct{}, 2)
+
+// serveShowListing writes the seasons (season == database.SeasonList) or a
+// season's episodes of showID, from the show cache when it is fresh and from
+// Kodi otherwise.
+func (s *Server) serveShowListing(w http.ResponseWriter, listID int64, showID, season int) {
+	cached, fresh, err := s.db.GetShowCache(listID, showID, season, showCacheTTL)
+	if err != nil {
+		writeDBError(w, err, "Failed to read show cache", "list_id", listID)
+		return
+	}
+	if fresh {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(cached)
+		return
+	}
+
+	b, err := s.getBackend(listID)
+	if err != nil {
+		writeDBError(w, err, "Failed to connect to Kodi", "list_id", listID)
+		return
+	}
+	data, err := s.fetchShowListing(b, listID, showID, season)
+	if err != nil {
+		if cached != nil {
+			slog.Warn("Kodi unavailable, serving cached show listing", "show_id", showID, "season", season, "error", err)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(cached)
+			return
+		}
+		msg := "Failed to fetch episodes"
+		if season == database.SeasonList {
+			msg = "Failed to fetch seasons"
+		}
+		slog.Error(msg, "show_id", showID, "season", season, "error", err)
+		writeKodiError(w, err, msg, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// fetchShowListing gets a listing from the list's backend and stores it in
+// the show cache.
+func (s *Server) fetchShowListing(b backend.MediaBackend, listID int64, showID, season int) ([]byte, error) {
+	var items []kodi.MediaItem
+	var err error
+	if season == database.SeasonList {
+		items, err = b.GetSeasons(showID)
+	} else {
+		items, err = b.GetEpisodes(showID, season)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if items == nil {
+		items = []kodi.MediaItem{}
+	}
+	data, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+	// Append the newline json.Encoder would, so cached and live responses match
+	data = append(data, '\n')
+	if err := s.db.StoreShowCache(listID, showID, season, data); err != nil {
+		slog.Warn("Failed to store show listing", "show_id", showID, "season", season, "error", err)
+	}
+	return data, nil
+}
+
+// prewarmShow caches the seasons and episodes of a show just added to a list
+// in the background, so drilling into it is instant and keeps working while
+// the Kodi host sleeps. Other items are ignored.
+func (s *Server) prewarmShow(item database.Item) {
+	if item.MediaType != "show" || item.Wanted {
+		return
+	}
+	listID, showID := item.ListID, item.KodiID
+	s.jobs.Go(func() {
+		prewarmSlots <- struct{}{}
+		defer func() { <-prewarmSlots }()
+
+		b, err := s.getBackend(listID)
+		if err != nil {
+			slog.Warn("Failed to get backend for show prewarm", "list_id", listID, "error", err)
+			return
+		}
+		data, err := s.fetchShowListing(b, listID, showID, database.SeasonList)
+		if err != nil {
+			slog.Warn("Failed to prewarm show seasons", "list_id", listID, "show_id", showID, "error", err)
+			return
+		}
+		var seasons []kodi.MediaItem
+		if err := json.Unmarshal(data, &seasons); err != nil {
+			slog.Warn("Failed to decode cached seasons", "show_id", showID, "error", err)
+			return
+		}
+		for _, season := range seasons {
+			if _, err := s.fetchShowListing(b, listID, showID, season.Season); err != nil {
+				slog.Warn("Failed to prewarm season episodes", "list_id", listID, "show_id", showID, "season", season.Season, "error", err)
+				return
+			}
+		}
+		slog.Info("Prewarmed show listings", "list_id", listID, "show_id", showID, "seasons", len(seasons))
+	})
+}
@@ -0,0 +1,221 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"whats-next/internal/database"
+	"whats-next/internal/kodi"
+)
+
+const (
+	// suggestionSeeds is how many recent plays suggestions are based on
+	suggestionSeeds    = 20
+	defaultSuggestions = 10
+	maxSuggestions     = 50
+)
+
+// suggestion is an unwatched library title similar to something watched
+// recently.
+type suggestion struct {
+	kodi.MediaItem
+	Score   float64        `json:"score"`
+	Because suggestionSeed `json:"because"`
+	Reasons []string       `json:"reasons"`
+}
+
+type suggestionSeed struct {
+	KodiID int    `json:"kodi_id"`
+	Title  string `json:"title"`
+}
+
+// handleSuggestions proposes titles from the cached library that resemble
+// what was watched recently on the list's Kodi host:
+// GET /suggestions?list_id=N&content_type=movie|tv&limit=10. Everything is
+// worked out locally from the library cache and viewing history; titles
+// already watched or on the list are left out, and a list with no history
+// gets no suggestions.
+func (s *Server) handleSuggestions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	listID, err := strconv.ParseInt(q.Get("list_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid list_id", http.StatusBadRequest)
+		return
+	}
+	limit := defaultSuggestions
+	if v := q.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(limit, maxSuggestions)
+	}
+
+	list, err := s.db.GetList(listID)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve list", "list_id", listID)
+		return
+	}
+	contentType := list.ContentType
+	if v := q.Get("content_type"); v != "" {
+		contentType = v
+	}
+	if contentType == "music" {
+		http.Error(w, "Suggestions are only available for movie and TV lists", http.StatusBadRequest)
+		return
+	}
+	cacheType, historyType := "movie", "movie"
+	if contentType == "tv" {
+		cacheType, historyType = "show", "episode"
+	}
+
+	history, err := s.db.GetHistory(listID, historyType, suggestionSeeds, 0)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve history", "list_id", listID)
+		return
+	}
+	cached, err := s.db.GetLibraryCache(listID, cacheType)
+	if err != nil {
+		slog.Error("Failed to read library cache", "list_id", listID, "error", err)
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	items, err := s.db.GetItems(listID)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve items", "list_id", listID)
+		return
+	}
+	onList := map[int]bool{}
+	for _, item := range items {
+		if item.MediaType == cacheType {
+			onList[item.KodiID] = true
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(suggest(history, cached, onList, limit))
+}
+
+// suggest scores every unwatched candidate against the titles in history,
+// most recent plays weighing most, and returns the best limit of them.
+// Episodes count as plays of their show.
+func suggest(history []database.Viewing, cached []database.CachedItem, onList map[int]bool, limit int) []suggestion {
+	byID := make(map[int]database.CachedItem, len(cached))
+	for _, c := range cached {
+		byID[c.KodiID] = c
+	}
+
+	type seed struct {
+		item   database.CachedItem
+		weight float64
+	}
+	var seeds []seed
+	seen := map[int]bool{}
+	for _, v := range history {
+		id := v.KodiID
+		if v.MediaType == "episode" {
+			id = v.TVShowID
+		}
+		c, ok := byID[id]
+		if !ok || seen[id] {
+			continue
+		}
+		seen[id] = true
+		// Each older seed counts a little less than the one before it
+		seeds = append(seeds, seed{item: c, weight: 1 / (1 + 0.15*float64(len(seeds)))})
+	}
+
+	results := []suggestion{}
+	if len(seeds) == 0 {
+		return results
+	}
+	for _, c := range cached {
+		if seen[c.KodiID] || onList[c.KodiID] || watchedCandidate(c) {
+			continue
+		}
+		var best suggestion
+		for _, sd := range seeds {
+			score, reasons := similarity(sd.item, c)
+			score *= sd.weight
+			if score > best.Score {
+				best = suggestion{Score: score, Because: suggestionSeed{KodiID: sd.item.KodiID, Title: sd.item.Title}, Reasons: reasons}
+			}
+		}
+		if best.Score == 0 {
+			continue
+		}
+		// Break ties between equally similar titles in favour of the
+		// better rated
+		best.Score += c.Rating / 100
+		best.MediaItem = cachedMediaItem(c)
+		results = append(results, best)
+	}
+	slices.SortStableFunc(results, func(a, b suggestion) int {
+		if a.Score != b.Score {
+			if a.Score > b.Score {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.Title, b.Title)
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	for i := range results {
+		results[i].Score = float64(int(results[i].Score*100)) / 100
+	}
+	return results
+}
+
+// watchedCandidate reports whether a title has been seen already: a
+// played movie, or a show with every episode watched.
+func watchedCandidate(c database.CachedItem) bool {
+	if c.MediaType == "show" {
+		return c.EpisodeCount > 0 && c.WatchedEpisodes >= c.EpisodeCount
+	}
+	return c.Playcount > 0
+}
+
+// similarity scores how alike two titles are from shared genres, cast and
+// movie set, with the reasons that contributed.
+func similarity(seed, c database.CachedItem) (float64, []string) {
+	var score float64
+	reasons := []string{}
+	if seed.SetID != 0 && seed.SetID == c.SetID {
+		score += 3
+		reasons = append(reasons, "Same collection: "+c.SetTitle)
+	}
+	var genres []string
+	for _, g := range c.Genres {
+		if slices.ContainsFunc(seed.Genres, func(s string) bool { return strings.EqualFold(s, g) }) {
+			genres = append(genres, g)
+		}
+	}
+	if len(genres) > 0 {
+		// Jaccard overlap, so a title sharing one of two genres beats one
+		// sharing one of six
+		union := len(seed.Genres) + len(c.Genres) - len(genres)
+		score += 2 * float64(len(genres)) / float64(union)
+		reasons = append(reasons, "Genres: "+strings.Join(genres, ", "))
+	}
+	var cast []string
+	for _, name := range c.Cast {
+		if slices.Contains(seed.Cast, name) {
+			cast = append(cast, name)
+		}
+	}
+	if len(cast) > 0 {
+		score += float64(min(len(cast), 3))
+		reasons = append(reasons, "Starring "+strings.Join(cast, ", "))
+	}
+	return score, reasons
+}
@@ -0,0 +1,473 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"whats-next/internal/database"
+	"whats-next/internal/kodi"
+)
+
+// maxConcurrentHostSyncs bounds how many Kodi hosts /sync/all talks to at once.
+const maxConcurrentHostSyncs = 4
+
+// syncLeaseTTL bounds how long a crashed replica can block syncs of a host.
+const syncLeaseTTL = 15 * time.Minute
+
+// defaultSyncConcurrency is how many items a sync downloads artwork for at
+// once, unless the list sets sync_concurrency.
+const defaultSyncConcurrency = 8
+
+// syncConcurrency returns how many items a sync of listID may process at
+// once.
+func (s *Server) syncConcurrency(listID int64) int {
+	list, err := s.db.GetList(listID)
+	if err != nil || list.SyncConcurrency <= 0 {
+		return defaultSyncConcurrency
+	}
+	return list.SyncConcurrency
+}
+
+// syncTypeFor is the library a list of contentType syncs.
+func syncTypeFor(contentType string) string {
+	if contentType != "tv" && contentType != "music" {
+		return "movie"
+	}
+	return contentType
+}
+
+var errSyncInProgress = errors.New("a sync of this library is already running")
+
+func (s *Server) handleSyncLibrary(w http.ResponseWriter, r *http.Request) {
+	listID, err := strconv.ParseInt(r.URL.Query().Get("list_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid list_id", http.StatusBadRequest)
+		return
+	}
+	syncType := r.URL.Query().Get("content_type")
+	if syncType == "" {
+		syncType = r.URL.Query().Get("type")
+	}
+	if syncType == "" {
+		syncType = "movie"
+	}
+	// A sync someone asked for rebuilds the whole cache, so playback state
+	// and removals are current; ?full=false makes do with what Kodi added
+	// since the last one.
+	full := r.URL.Query().Get("full") != "false"
+
+	// ?async=true runs the sync as a background task instead of holding
+	// the request open.
+	if r.URL.Query().Get("async") == "true" {
+		if _, err := s.db.GetList(listID); err != nil {
+			writeDBError(w, err, "Failed to retrieve list", "list_id", listID)
+			return
+		}
+		writeTaskAccepted(w, s.startTask("sync", func() (interface{}, error) {
+			count, err := s.syncLibrary(listID, syncType, full)
+			return map[string]interface{}{"list_id": listID, "content_type": syncType, "count": count, "poster_failures": s.posterFailureCount(listID, syncType)}, err
+		}))
+		return
+	}
+
+	count, err := s.syncLibrary(listID, syncType, full)
+	if errors.Is(err, errSyncInProgress) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if errors.Is(err, database.ErrListNotFound) || errors.Is(err, errKodiOnly) {
+		writeDBError(w, err, "Failed to sync library", "list_id", listID)
+		return
+	}
+	if err != nil {
+		writeKodiError(w, err, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "success", "count": count, "poster_failures": s.posterFailureCount(listID, syncType)})
+}
+
+// syncLibrary refreshes the library cache of one content type for a list
+// (and therefore for every list sharing its Kodi host), downloading posters
+// in parallel. Unless full is set or a full sync is due, only items Kodi
+// added since the last sync are fetched. It returns the number of items
+// cached.
+func (s *Server) syncLibrary(listID int64, syncType string, full bool) (int, error) {
+	since := ""
+	if !full {
+		since = s.syncWatermark(listID, syncType)
+	}
+	return s.syncLibraryFrom(listID, syncType, nil, since)
+}
+
+// syncWatermark returns the dateadded an incremental sync of syncType on
+// listID would fetch from, or "" if the sync should be a full one.
+func (s *Server) syncWatermark(listID int64, syncType string) string {
+	fullEvery := jobInterval("full_sync_interval", s.config.FullSyncInterval, defaultFullSyncInterval)
+	if fullEvery <= 0 {
+		return ""
+	}
+	since, err := s.db.SyncWatermark(listID, cacheTypeFor(syncType), fullEvery)
+	if err != nil {
+		slog.Warn("Failed to read sync watermark, running a full sync", "list_id", listID, "error", err)
+		return ""
+	}
+	return since
+}
+
+// syncLibraryFrom is syncLibrary using lib when it was already fetched, e.g.
+// in one batch for several content types; nil fetches it. since is the
+// watermark lib was fetched from, "" for a full sync.
+func (s *Server) syncLibraryFrom(listID int64, syncType string, lib *kodi.Library, since string) (int, error) {
+	start := time.Now()
+	var count int
+	var err error
+	if syncType == "music" {
+		for _, t := range musicSyncTypes {
+			var n int
+			n, err = s.syncLibraryItems(listID, t, nil, "")
+			count += n
+			if err != nil {
+				break
+			}
+		}
+	} else {
+		count, err = s.syncLibraryItems(listID, syncType, lib, since)
+	}
+	s.metrics.recordSync(count, err, time.Since(start))
+	return count, err
+}
+
+// musicSyncTypes are the caches a music list's sync fills. Music syncs are
+// always full: artists have no dateadded for an incremental one to go by.
+var musicSyncTypes = []string{"album", "artist"}
+
+func (s *Server) syncLibraryItems(listID int64, syncType string, lib *kodi.Library, since string) (int, error) {
+	client, err := s.getKodiClient(listID)
+	if err != nil {
+		slog.Error("Failed to get Kodi client", "error", err)
+		return 0, fmt.Errorf("kodi connection failed: %w", err)
+	}
+
+	// The cache is shared by every replica, so only one may rebuild a
+	// host's library at a time.
+	lease := "sync:" + client.HostURL + ":" + syncType
+	holder := s.leaseHolder()
+	acquired, err := s.db.AcquireLease(lease, holder, syncLeaseTTL)
+	if err != nil {
+		return 0, fmt.Errorf("failed to acquire sync lease: %w", err)
+	}
+	if !acquired {
+		return 0, errSyncInProgress
+	}
+	defer s.db.ReleaseLease(lease, holder)
+
+	mediaType, genreType := "movie", "movie"
+	music := false
+	switch syncType {
+	case "tv":
+		mediaType, genreType = "show", "tvshow"
+	case "album", "artist":
+		mediaType, music = syncType, true
+	}
+
+	// Each page of items is cached as it arrives, so large libraries never
+	// sit in memory whole and the cache stays usable throughout. After a
+	// full sync, items it didn't see are pruned; an incremental sync only
+	// adds and updates.
+	incremental := since != ""
+	var posterFailures []database.PosterFailure
+	var storedArt []database.ItemArt
+	seen := map[int]bool{}
+	addedThrough := since
+	var mu sync.Mutex
+	sem := make(chan struct{}, s.syncConcurrency(listID))
+	cachePage := func(items []kodi.MediaItem) error {
+		var page []database.CachedItem
+		var wg sync.WaitGroup
+		for _, item := range items {
+			wg.Go(func() {
+				sem <- struct{}{}
+				defer func() { <-sem }()
+				defer func() {
+					if r := recover(); r != nil {
+						slog.Error("Panic in sync library goroutine", "panic", r, "media_type", mediaType, "kodi_id", item.ID, "title", item.Title)
+					}
+				}() // Prevent crash on panic while logging
+
+				poster, err := s.downloadBestImage(client, item, mediaType)
+				art := s.downloadExtraArt(client, item, mediaType)
+
+				mu.Lock()
+				if err != nil {
+					imageURI, _ := bestImageURI(item, nil)
+					posterFailures = append(posterFailures, database.PosterFailure{
+						KodiID: item.ID, Title: item.Title, Year: item.Year, ImageURI: imageURI, Error: err.Error(),
+					})
+				}
+				page = append(page, database.CachedItem{
+					ListID: listID, KodiID: item.ID, MediaType: mediaType, Title: item.Title, Year: item.Year, Poster: poster, Runtime: item.Runtime, EpisodeCount: item.EpisodeCount, Rating: item.Rating, Plot: item.Plot,
+					AudioLanguages: item.AudioLanguages, SubtitleLanguages: item.SubtitleLanguages, WatchedEpisodes: item.WatchedEpisodes, Genres: item.Genres,
+					Playcount: item.Playcount, LastPlayed: item.LastPlayed, ResumePosition: item.ResumePoint(), Art: item.Art,
+					IMDbID: item.IMDbID(), TMDbID: item.TMDbID(), Resolution: item.VideoResolution(), HDRType: item.HDRType(),
+					SetID: item.SetID, SetTitle: item.Set, Cast: item.Actors, Artist: item.Artist,
+				})
+				storedArt = append(storedArt, art...)
+				seen[item.ID] = true
+				addedThrough = max(addedThrough, item.DateAdded)
+				mu.Unlock()
+			})
+		}
+		wg.Wait()
+
+		if err := s.db.AddToLibraryCache(page); err != nil {
+			slog.Error("Failed to save cache", "error", err)
+			return fmt.Errorf("failed to save cache: %w", err)
+		}
+		slog.Info("Cached library page", "type", syncType, "count", len(page), "total", len(seen))
+		return nil
+	}
+
+	// Items and genres come back in a single batch, followed by any further
+	// pages of items
+	if music {
+		fetch := client.GetAlbums
+		if syncType == "artist" {
+			fetch = client.GetArtists
+		}
+		items, err := fetch()
+		if err != nil {
+			slog.Error("Error getting items from Kodi", "type", syncType, "error", err)
+			return 0, err
+		}
+		for page := range slices.Chunk(items, client.PageSize) {
+			if err := cachePage(page); err != nil {
+				return 0, err
+			}
+		}
+	} else if lib == nil {
+		lib, err = client.GetLibraryAddedSince(syncType != "tv", syncType == "tv", since, func(_ string, items []kodi.MediaItem) error {
+			return cachePage(items)
+		})
+		if err != nil {
+			slog.Error("Error getting items from Kodi", "type", syncType, "error", err)
+			return 0, err
+		}
+	} else {
+		items := lib.Movies
+		if syncType == "tv" {
+			items = lib.TVShows
+		}
+		for page := range slices.Chunk(items, client.PageSize) {
+			if err := cachePage(page); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if !music {
+		genres := lib.MovieGenres
+		if syncType == "tv" {
+			genres = lib.ShowGenres
+		}
+		if data, err := json.Marshal(genres); err == nil {
+			if err := s.db.StoreGenreCache(listID, genreType, data); err != nil {
+				slog.Warn("Failed to cache genres", "list_id", listID, "error", err)
+			}
+		}
+	}
+
+	var synced []int
+	if incremental {
+		synced = slices.AppendSeq([]int{}, maps.Keys(seen))
+	} else if n, err := s.db.PruneLibraryCache(listID, mediaType, seen); err != nil {
+		slog.Error("Failed to prune cache", "list_id", listID, "error", err)
+	} else if n > 0 {
+		slog.Info("Removed items gone from Kodi from cache", "list_id", listID, "count", n)
+	}
+	if err := s.db.RecordSync(listID, mediaType, addedThrough, !incremental); err != nil {
+		slog.Error("Failed to record sync watermark", "list_id", listID, "error", err)
+	}
+	slog.Info("Finished sync", "count", len(seen), "incremental", incremental)
+
+	if err := s.db.ReplaceItemArt(listID, mediaType, synced, storedArt); err != nil {
+		slog.Error("Failed to record stored artwork", "list_id", listID, "error", err)
+	}
+
+	if err := s.db.ReplacePosterFailures(listID, mediaType, synced, posterFailures); err != nil {
+		slog.Error("Failed to record poster failures", "list_id", listID, "error", err)
+	} else if len(posterFailures) > 0 {
+		slog.Warn("Some posters failed to download", "list_id", listID, "media_type", mediaType, "failed", len(posterFailures))
+	}
+
+	s.migrateHostItems(listID, mediaType)
+	s.retryPendingMatches(listID)
+	s.linkWantedItems(listID)
+	if n, err := s.db.SyncItemPlayback(listID, mediaType); err != nil {
+		slog.Error("Failed to update item playback state", "list_id", listID, "error", err)
+	} else if n > 0 {
+		slog.Info("Updated item playback state", "list_id", listID, "items", n)
+	}
+	if _, err := s.db.SyncItemMetadata(listID, mediaType); err != nil {
+		slog.Error("Failed to update item metadata", "list_id", listID, "error", err)
+	}
+	s.applyWatchedPolicies()
+	s.applyArtPreferences(listID, mediaType)
+	s.reportMissingItems(listID, mediaType)
+
+	return len(seen), nil
+}
+
+type hostSyncResult struct {
+	KodiHost    string `json:"kodi_host"`
+	ListID      int64  `json:"list_id"`
+	ContentType string `json:"content_type"`
+	Count       int    `json:"count"`
+	// PosterFailures counts artwork to retry with /sync/retry-failed.
+	PosterFailures int    `json:"poster_failures"`
+	Error          string `json:"error,omitempty"`
+}
+
+// syncAllSummary is the result of a sync-all task.
+type syncAllSummary struct {
+	Hosts     int              `json:"hosts"`
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Results   []hostSyncResult `json:"results"`
+}
+
+// handleSyncAll starts a background task syncing every Kodi host and
+// responds 202 with the task to poll at /tasks/{id}. Like a single sync, it
+// is a full one unless ?full=false.
+func (s *Server) handleSyncAll(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	full := r.URL.Query().Get("full") != "false"
+	writeTaskAccepted(w, s.startTask("sync_all", func() (interface{}, error) {
+		return s.syncAllHosts(full)
+	}))
+}
+
+// syncAllHosts syncs every distinct Kodi host, once per content type in use
+// on that host, with bounded concurrency across hosts. A failing host doesn't
+// stop the others; each outcome is reported individually. Hosts are synced
+// incrementally where syncLibrary would be, unless full is set.
+func (s *Server) syncAllHosts(full bool) (*syncAllSummary, error) {
+	lists, err := s.db.GetAllLists()
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve lists: %w", err)
+	}
+
+	// The cache is shared per host, so one representative list per
+	// host/content type is enough.
+	type job struct {
+		host        string
+		listID      int64
+		contentType string
+		since       string
+	}
+	var hosts []string
+	jobsByHost := map[string][]job{}
+	seen := map[string]bool{}
+	for _, l := range lists {
+		if l.Backend == database.BackendJellyfin {
+			continue
+		}
+		contentType := syncTypeFor(l.ContentType)
+		key := l.KodiHost + "|" + contentType
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if _, ok := jobsByHost[l.KodiHost]; !ok {
+			hosts = append(hosts, l.KodiHost)
+		}
+		j := job{host: l.KodiHost, listID: l.ID, contentType: contentType}
+		if !full && contentType != "music" {
+			j.since = s.syncWatermark(l.ID, contentType)
+		}
+		jobsByHost[l.KodiHost] = append(jobsByHost[l.KodiHost], j)
+	}
+
+	results := make([][]hostSyncResult, len(hosts))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxConcurrentHostSyncs)
+	for i, host := range hosts {
+		wg.Go(func() {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			// Every video content type in use on the host is fetched in
+			// one batch, then cached sequentially to avoid doubling the load
+			// on a single Kodi box; music is fetched by its own sync.
+			// The fetch starts from the oldest watermark of the host's
+			// content types, and is a full one if any of them is due.
+			hostJobs := jobsByHost[host]
+			var movies, shows bool
+			var watermarks []string
+			for _, j := range hostJobs {
+				movies, shows = movies || j.contentType == "movie", shows || j.contentType == "tv"
+				if j.contentType != "music" {
+					watermarks = append(watermarks, j.since)
+				}
+			}
+			since := ""
+			if len(watermarks) > 0 {
+				since = slices.Min(watermarks)
+			}
+			var lib *kodi.Library
+			var fetchErr error
+			if movies || shows {
+				if client, err := s.getKodiClient(hostJobs[0].listID); err != nil {
+					fetchErr = err
+				} else {
+					lib, fetchErr = client.GetLibraryAddedSince(movies, shows, since, nil)
+				}
+			}
+			for _, j := range hostJobs {
+				res := hostSyncResult{KodiHost: j.host, ListID: j.listID, ContentType: j.contentType}
+				var count int
+				var err error
+				if j.contentType == "music" {
+					count, err = s.syncLibraryFrom(j.listID, j.contentType, nil, "")
+				} else if fetchErr != nil {
+					err = fetchErr
+					s.metrics.recordSync(0, err, 0)
+				} else {
+					count, err = s.syncLibraryFrom(j.listID, j.contentType, lib, since)
+				}
+				if err != nil {
+					slog.Error("Host sync failed", "host", j.host, "list_id", j.listID, "content_type", j.contentType, "error", err)
+					res.Error = err.Error()
+				}
+				res.Count = count
+				res.PosterFailures = s.posterFailureCount(j.listID, j.contentType)
+				results[i] = append(results[i], res)
+			}
+		})
+	}
+	wg.Wait()
+
+	flat := make([]hostSyncResult, 0, len(seen))
+	failed := 0
+	for _, rs := range results {
+		for _, res := range rs {
+			if res.Error != "" {
+				failed++
+			}
+			flat = append(flat, res)
+		}
+	}
+
+	slog.Info("Finished syncing all hosts", "hosts", len(hosts), "jobs", len(flat), "failed", failed)
+	return &syncAllSummary{Hosts: len(hosts), Succeeded: len(flat) - failed, Failed: failed, Results: flat}, nil
+}
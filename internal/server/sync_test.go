@@ -0,0 +1,84 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"whats-next/internal/database"
+)
+
+type syncResponse struct {
+	Status string `json:"status"`
+	Count  int    `json:"count"`
+}
+
+// foundItem is a search result as clients read it; searchResult itself
+// can't be decoded, since kodi.MediaItem's UnmarshalJSON drops on_lists.
+type foundItem struct {
+	ID      int                `json:"id"`
+	Title   string             `json:"title"`
+	OnLists []database.ListRef `json:"on_lists"`
+}
+
+func TestSyncThenSearch(t *testing.T) {
+	e := newTestEnv(t)
+	list := e.addList("Movies", "movie")
+
+	var synced syncResponse
+	e.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/sync?list_id=%d&content_type=movie", list.ID), nil, &synced)
+	cached, err := e.db.GetLibraryCacheCount(list.ID, "movie")
+	if err != nil {
+		t.Fatalf("GetLibraryCacheCount: %v", err)
+	}
+	if synced.Status != "success" || synced.Count == 0 || synced.Count != cached {
+		t.Fatalf("sync reported %+v, cache holds %d movies", synced, cached)
+	}
+
+	search := fmt.Sprintf("/search?list_id=%d&content_type=movie&q=Inception", list.ID)
+	var results []foundItem
+	e.expect(http.StatusOK, http.MethodGet, search, nil, &results)
+	if len(results) != 1 || results[0].Title != "Inception" || len(results[0].OnLists) != 0 {
+		t.Fatalf("search for Inception returned %+v", results)
+	}
+
+	// Adding the result marks it as on the list in later searches
+	e.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/lists/%d/items", list.ID),
+		map[string]interface{}{"kodi_id": results[0].ID, "media_type": "movie", "title": results[0].Title}, nil)
+	e.expect(http.StatusOK, http.MethodGet, search, nil, &results)
+	if len(results) != 1 || len(results[0].OnLists) != 1 || results[0].OnLists[0].ID != list.ID {
+		t.Fatalf("search after adding Inception returned %+v", results)
+	}
+}
+
+func TestSyncRefusedWhileLeaseHeld(t *testing.T) {
+	e := newTestEnv(t)
+	list := e.addList("Movies", "movie")
+	client, err := e.srv.getKodiClient(list.ID)
+	if err != nil {
+		t.Fatalf("getKodiClient: %v", err)
+	}
+
+	// Another replica is syncing the same host
+	lease := "sync:" + client.HostURL + ":movie"
+	if ok, err := e.db.AcquireLease(lease, "other-replica", time.Minute); err != nil || !ok {
+		t.Fatalf("AcquireLease = %v, %v", ok, err)
+	}
+	path := fmt.Sprintf("/sync?list_id=%d&content_type=movie", list.ID)
+	e.expect(http.StatusConflict, http.MethodPost, path, nil, nil)
+
+	if err := e.db.ReleaseLease(lease, "other-replica"); err != nil {
+		t.Fatalf("ReleaseLease: %v", err)
+	}
+	e.expect(http.StatusOK, http.MethodPost, path, nil, nil)
+	if held, err := e.db.LeaseHeld(lease); err != nil || held {
+		t.Fatalf("lease still held after sync: %v, %v", held, err)
+	}
+}
+
+func TestSyncUnknownList(t *testing.T) {
+	e := newTestEnv(t)
+	e.expect(http.StatusNotFound, http.MethodPost, "/sync?list_id=99&content_type=movie", nil, nil)
+	e.expect(http.StatusBadRequest, http.MethodPost, "/sync?list_id=x", nil, nil)
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// taskRetention is how long finished tasks stay queryable.
+const taskRetention = time.Hour
+
+// task is a long-running operation started by a request and run in the
+// background, so it isn't bound by HTTP timeouts. Tasks live in memory on
+// the replica that started them.
+type task struct {
+	ID         string      `json:"id"`
+	Kind       string      `json:"kind"`
+	Status     string      `json:"status"` // running, succeeded, failed
+	StartedAt  time.Time   `json:"started_at"`
+	FinishedAt *time.Time  `json:"finished_at,omitempty"`
+	Result     interface{} `json:"result,omitempty"`
+	Error      string      `json:"error,omitempty"`
+}
+
+type taskRegistry struct {
+	mu    sync.Mutex
+	tasks map[string]*task
+}
+
+// startTask runs fn in the background and returns a snapshot of the new task.
+func (s *Server) startTask(kind string, fn func() (interface{}, error)) task {
+	b := make([]byte, 8)
+	rand.Read(b)
+	t := &task{ID: hex.EncodeToString(b), Kind: kind, Status: "running", StartedAt: time.Now().UTC()}
+
+	s.tasks.mu.Lock()
+	if s.tasks.tasks == nil {
+		s.tasks.tasks = map[string]*task{}
+	}
+	s.pruneTasksLocked()
+	s.tasks.tasks[t.ID] = t
+	snapshot := *t
+	s.tasks.mu.Unlock()
+
+	go func() {
+		result, err := fn()
+		s.tasks.mu.Lock()
+		defer s.tasks.mu.Unlock()
+		now := time.Now().UTC()
+		t.FinishedAt = &now
+		t.Result = result
+		t.Status = "succeeded"
+		if err != nil {
+			t.Status = "failed"
+			t.Error = err.Error()
+			slog.Error("Background task failed", "task_id", t.ID, "kind", kind, "error", err)
+		}
+	}()
+	return snapshot
+}
+
+func (s *Server) pruneTasksLocked() {
+	for id, t := range s.tasks.tasks {
+		if t.FinishedAt != nil && time.Since(*t.FinishedAt) > taskRetention {
+			delete(s.tasks.tasks, id)
+		}
+	}
+}
+
+// writeTaskAccepted responds 202 with the task and where to poll it.
+func writeTaskAccepted(w http.ResponseWriter, t task) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/tasks/"+t.ID)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(t)
+}
+
+// handleTask reports the state of a background task: GET /tasks/{id}.
+func (s *Server) handleTask(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/tasks/")
+
+	s.tasks.mu.Lock()
+	t, ok := s.tasks.tasks[id]
+	var snapshot task
+	if ok {
+		snapshot = *t
+	}
+	s.tasks.mu.Unlock()
+
+	if !ok {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(snapshot)
+}
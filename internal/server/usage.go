@@ -0,0 +1,222 @@
+package server
+
+import (
+	"cmp"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"whats-next/internal/database"
+)
+
+// usageRetention bounds both the usage window and stored usage counts.
+const usageRetention = 90 * 24 * time.Hour
+
+// Kinds of usage counter.
+const (
+	usageEndpoint = "endpoint"
+	usageFeature  = "feature"
+)
+
+// usageFeatures names the feature behind each route, matched by prefix in
+// order. Routes not listed here only count as endpoints.
+var usageFeatures = []struct {
+	method, prefix, feature string
+}{
+	{"", "/lists/{id}/items/watched", "mark_watched"},
+	{http.MethodPost, "/lists/{id}/items", "add_items"},
+	{"", "/lists/{id}/order", "reorder"},
+	{"", "/lists/{id}/plain", "plain_text"},
+	{"", "/lists/{id}/import", "imports"},
+	{"", "/lists/{id}/kodi-playlist", "kodi_playlists"},
+	{"", "/lists/{id}/pending", "imports"},
+	{"", "/lists/{id}/queue-all", "party_mode"},
+	{"", "/lists/{id}/queue", "queue"},
+	{"", "/lists/{id}/player", "remote"},
+	{"", "/lists/{id}/nowplaying", "now_playing"},
+	{"", "/lists/{id}/searches", "saved_searches"},
+	{"", "/lists/{id}/feeds", "feeds"},
+	{"", "/lists/{id}/shares", "federation"},
+	{"", "/lists/{id}/remote", "federation"},
+	{"", "/federation/", "federation"},
+	{"", "/lists/{id}/history", "history"},
+	{"", "/lists/{id}/sections", "sections"},
+	{"", "/lists/{id}/missing", "missing"},
+	{"", "/lists/{id}/next-episodes", "next_episodes"},
+	{"", "/lists/{id}/plan", "planner"},
+	{"", "/lists/{id}/scan", "library_jobs"},
+	{"", "/lists/{id}/clean", "library_jobs"},
+	{"", "/lists/{id}/pin", "pins"},
+	{"", "/lists/{id}/index", "jump_index"},
+	{"", "/items/{id}/play", "play"},
+	{"", "/items/{id}/watched", "mark_watched"},
+	{"", "/items/{id}/restore", "archive"},
+	{"", "/search", "search"},
+	{"", "/suggestions", "suggestions"},
+	{"", "/library/browse", "browse"},
+	{"", "/library/index", "jump_index"},
+	{"", "/library/files", "files"},
+	{"", "/library/playlists", "kodi_playlists"},
+	{"", "/pvr/", "pvr"},
+	{"", "/library/recent", "shelves"},
+	{"", "/library/inprogress", "shelves"},
+	{"", "/library/favourites", "shelves"},
+	{"", "/dashboard", "dashboard"},
+	{"", "/digest", "digest"},
+	{"", "/settings/", "settings_backup"},
+	{"", "/setup", "setup"},
+	{"", "/groups", "groups"},
+	{"", "/kodi/events", "kodi_webhook"},
+	{http.MethodPost, "/sync", "sync"},
+	{http.MethodDelete, "/lists/{id}", "delete_list"},
+	{"", "/lists/{id}/clone", "clone_list"},
+	{"", "/lists/{id}/archive", "archive_list"},
+	{"", "/lists/{id}/restore", "archive_list"},
+}
+
+// usageEnabled reports whether usage_stats leaves counting on.
+func (s *Server) usageEnabled() bool {
+	return s.config.UsageStats == nil || *s.config.UsageStats
+}
+
+// usageRoute reduces a request path to its route, replacing IDs and file
+// names with placeholders so routes are counted together, e.g.
+// "/lists/3/items" becomes "/lists/{id}/items".
+func usageRoute(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	for i, part := range parts {
+		switch {
+		case i > 0 && (parts[0] == "posters" || parts[0] == "tasks"):
+			parts[i] = "{id}"
+		case part != "" && strings.Trim(part, "0123456789") == "":
+			parts[i] = "{id}"
+		}
+	}
+	return "/" + strings.Join(parts, "/")
+}
+
+// featureFor returns the feature a request to route used, or "" for none.
+func featureFor(method, route string) string {
+	for _, f := range usageFeatures {
+		if (f.method == "" || f.method == method) && strings.HasPrefix(route, f.prefix) {
+			return f.feature
+		}
+	}
+	return ""
+}
+
+// recordUsage counts the endpoint and feature a request used. Requests that
+// matched no route are left out, so probes for other paths don't add up.
+func (s *Server) recordUsage(r *http.Request, status int) {
+	if !s.usageEnabled() || status == http.StatusNotFound || status == http.StatusMethodNotAllowed {
+		return
+	}
+	route := usageRoute(r.URL.Path)
+	s.usage.add(database.UsageKey{Kind: usageEndpoint, Name: r.Method + " " + route}, 1)
+	if status < 400 {
+		if feature := featureFor(r.Method, route); feature != "" {
+			s.usage.add(database.UsageKey{Kind: usageFeature, Name: feature}, 1)
+		}
+	}
+}
+
+// flushUsage writes the gathered usage counts to the database.
+func (s *Server) flushUsage() {
+	err := s.usage.flush(func(counts map[database.UsageKey]int64) error {
+		return s.db.AddUsage(time.Now(), counts)
+	})
+	if err != nil {
+		slog.Warn("Failed to store usage counts", "error", err)
+	}
+}
+
+type usageEntry struct {
+	Name     string `json:"name"`
+	Count    int64  `json:"count"`
+	Days     int    `json:"days"` // days it was used on
+	LastUsed string `json:"last_used"`
+}
+
+type usageDay struct {
+	Day      string `json:"day"`
+	Requests int64  `json:"requests"`
+}
+
+type usageSummary struct {
+	Enabled    bool         `json:"enabled"`
+	WindowDays int          `json:"window_days"`
+	Features   []usageEntry `json:"features"`
+	Endpoints  []usageEntry `json:"endpoints"`
+	Daily      []usageDay   `json:"daily"`
+}
+
+// handleUsage reports which features and endpoints were used in the last
+// ?days= days (default 30, at most 90), most used first: GET /admin/usage.
+// The counts are only kept in the local database.
+func (s *Server) handleUsage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	days := 30
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > int(usageRetention/(24*time.Hour)) {
+			http.Error(w, "Invalid days parameter", http.StatusBadRequest)
+			return
+		}
+		days = n
+	}
+
+	// Include this replica's counts that haven't been flushed yet
+	s.flushUsage()
+	counts, err := s.db.GetUsage(time.Now().Add(-time.Duration(days-1) * 24 * time.Hour))
+	if err != nil {
+		slog.Error("Failed to read usage counts", "error", err)
+		http.Error(w, "Failed to read usage counts", http.StatusInternalServerError)
+		return
+	}
+
+	summary := usageSummary{Enabled: s.usageEnabled(), WindowDays: days, Daily: []usageDay{}}
+	entries := map[database.UsageKey]*usageEntry{}
+	for _, c := range counts {
+		day := c.Day.Format(time.DateOnly)
+		e := entries[c.UsageKey]
+		if e == nil {
+			e = &usageEntry{Name: c.Name}
+			entries[c.UsageKey] = e
+		}
+		e.Count += c.Count
+		e.Days++
+		e.LastUsed = day // counts come oldest first
+
+		if c.Kind != usageEndpoint {
+			continue
+		}
+		if n := len(summary.Daily); n == 0 || summary.Daily[n-1].Day != day {
+			summary.Daily = append(summary.Daily, usageDay{Day: day})
+		}
+		summary.Daily[len(summary.Daily)-1].Requests += c.Count
+	}
+
+	summary.Features, summary.Endpoints = []usageEntry{}, []usageEntry{}
+	for key, e := range entries {
+		if key.Kind == usageFeature {
+			summary.Features = append(summary.Features, *e)
+		} else {
+			summary.Endpoints = append(summary.Endpoints, *e)
+		}
+	}
+	byUse := func(a, b usageEntry) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), strings.Compare(a.Name, b.Name))
+	}
+	slices.SortFunc(summary.Features, byUse)
+	slices.SortFunc(summary.Endpoints, byUse)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
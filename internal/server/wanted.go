@@ -0,0 +1,46 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"whats-next/internal/database"
+)
+
+// linkWantedItems resolves wanted items on every list sharing the synced list's
+// Kodi host against the refreshed library cache.
+func (s *Server) linkWantedItems(listID int64) {
+	wanted, err := s.db.GetWantedItemsForHost(listID)
+	if err != nil {
+		slog.Error("Failed to load wanted items", "list_id", listID, "error", err)
+		return
+	}
+
+	for _, item := range wanted {
+		cached, err := s.db.FindCachedMatch(item.ListID, item.MediaType, item.Title, item.Year)
+		if err != nil {
+			if !errors.Is(err, sql.ErrNoRows) {
+				slog.Error("Failed to match wanted item", "item_id", item.ID, "title", item.Title, "error", err)
+			}
+			continue
+		}
+
+		linked, err := s.db.LinkWantedItem(item.ID, cached)
+		if err != nil {
+			slog.Error("Failed to link wanted item", "item_id", item.ID, "error", err)
+			continue
+		}
+		if !linked {
+			slog.Info("Removed wanted item already present on list", "item_id", item.ID, "kodi_id", cached.KodiID)
+			continue
+		}
+
+		slog.Info("Wanted item is now available", "item_id", item.ID, "title", cached.Title, "kodi_id", cached.KodiID)
+		msg := fmt.Sprintf("Wanted title %q (%d) is now available in the library", cached.Title, cached.Year)
+		if err := s.db.AddNotification(database.NotificationWantedAvailable, item.ListID, msg); err != nil {
+			slog.Error("Failed to add notification", "error", err)
+		}
+	}
+}
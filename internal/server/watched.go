@@ -0,0 +1,234 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"whats-next/internal/database"
+	"whats-next/internal/kodi"
+)
+
+// handleItemWatched marks a list item watched or unwatched in the Kodi
+// library and on the item: POST /items/{id}/watched with {"watched": bool}.
+// An empty body marks the item watched. Shows and seasons update every
+// episode they cover.
+func (s *Server) handleItemWatched(w http.ResponseWriter, r *http.Request, itemID int64) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	req := struct {
+		Watched *bool `json:"watched"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	watched := req.Watched == nil || *req.Watched
+
+	item, err := s.db.GetItem(itemID)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve item", "item_id", itemID)
+		return
+	}
+	if item.Wanted || item.KodiID == 0 {
+		http.Error(w, "Item is not in the Kodi library", http.StatusConflict)
+		return
+	}
+
+	client, err := s.getKodiClient(item.ListID)
+	if err != nil {
+		writeDBError(w, err, "Failed to connect to Kodi", "list_id", item.ListID)
+		return
+	}
+	if err := setKodiWatched(client, *item, watched); err != nil {
+		slog.Error("Failed to update playcount on Kodi", "item_id", itemID, "media_type", item.MediaType, "kodi_id", item.KodiID, "error", err)
+		writeKodiError(w, err, "Failed to update Kodi library", http.StatusBadGateway)
+		return
+	}
+
+	if err := s.db.SetItemWatched(itemID, watched); err != nil {
+		writeDBError(w, err, "Failed to save watched state", "item_id", itemID)
+		return
+	}
+	item.Watched = watched
+	s.applyWatchedPolicies()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(item)
+}
+
+// maxBulkWatched caps how many items one bulk watched request may change.
+const maxBulkWatched = 500
+
+// kodiWatchedError is an item bulk watched toggling couldn't update in Kodi.
+type kodiWatchedError struct {
+	ItemID int64  `json:"item_id"`
+	Error  string `json:"error"`
+}
+
+// handleBulkWatched marks several items on a list watched or unwatched at
+// once: POST /lists/{id}/items/watched with {"item_ids": [...], "watched":
+// bool, "kodi": bool}. The items change together or not at all. With "kodi"
+// the playcounts are then set in the Kodi library too, as for a single item;
+// items Kodi couldn't be updated for are reported in kodi_errors.
+func (s *Server) handleBulkWatched(w http.ResponseWriter, r *http.Request, listID int64) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		ItemIDs []int64 `json:"item_ids"`
+		Watched *bool   `json:"watched"`
+		Kodi    bool    `json:"kodi"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.ItemIDs) == 0 || len(req.ItemIDs) > maxBulkWatched {
+		http.Error(w, fmt.Sprintf("item_ids must list 1 to %d items", maxBulkWatched), http.StatusBadRequest)
+		return
+	}
+	watched := req.Watched == nil || *req.Watched
+
+	items := make([]*database.Item, 0, len(req.ItemIDs))
+	var ids []int64
+	seen := map[int64]bool{}
+	for _, id := range req.ItemIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		item, err := s.db.GetItem(id)
+		if err == nil && item.ListID != listID {
+			err = database.ErrItemNotFound
+		}
+		if err != nil {
+			writeDBError(w, err, "Failed to retrieve item", "item_id", id)
+			return
+		}
+		if req.Kodi && (item.Wanted || item.KodiID == 0) {
+			http.Error(w, fmt.Sprintf("Item %d is not in the Kodi library", id), http.StatusConflict)
+			return
+		}
+		items = append(items, item)
+		ids = append(ids, id)
+	}
+
+	var client *kodi.Client
+	if req.Kodi {
+		var err error
+		if client, err = s.getKodiClient(listID); err != nil {
+			writeDBError(w, err, "Failed to connect to Kodi", "list_id", listID)
+			return
+		}
+	}
+	if err := s.db.SetItemsWatched(listID, ids, watched); err != nil {
+		writeDBError(w, err, "Failed to save watched state", "list_id", listID)
+		return
+	}
+
+	kodiErrors := []kodiWatchedError{}
+	for _, item := range items {
+		item.Watched = watched
+		if client == nil {
+			continue
+		}
+		if err := setKodiWatched(client, *item, watched); err != nil {
+			slog.Error("Failed to update playcount on Kodi", "item_id", item.ID, "media_type", item.MediaType, "kodi_id", item.KodiID, "error", err)
+			kodiErrors = append(kodiErrors, kodiWatchedError{ItemID: item.ID, Error: err.Error()})
+		}
+	}
+	slog.Info("Bulk updated watched state", "list_id", listID, "items", len(items), "watched", watched, "kodi", req.Kodi, "kodi_failures", len(kodiErrors))
+	s.applyWatchedPolicies()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"updated": len(items), "items": items, "kodi_errors": kodiErrors})
+}
+
+// applyWatchedPolicies removes or archives watched items on lists that opted
+// in with on_watched. It runs whenever watched state may have changed.
+func (s *Server) applyWatchedPolicies() {
+	removed, archived, err := s.db.ApplyWatchedPolicies()
+	if err != nil {
+		slog.Error("Failed to apply watched item policies", "error", err)
+		return
+	}
+	if removed > 0 || archived > 0 {
+		slog.Info("Cleared watched items from lists", "removed", removed, "archived", archived)
+	}
+}
+
+// handleRestoreItem moves an archived item back onto its list:
+// POST /items/{id}/restore. It stays even though it is still watched.
+func (s *Server) handleRestoreItem(w http.ResponseWriter, r *http.Request, itemID int64) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := s.db.RestoreItem(itemID); err != nil {
+		writeDBError(w, err, "Failed to restore item", "item_id", itemID)
+		return
+	}
+	item, err := s.db.GetItem(itemID)
+	if err != nil {
+		writeDBError(w, err, "Failed to retrieve item", "item_id", itemID)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(item)
+}
+
+// setKodiWatched sets the playcount of the item, of each movie in a set, or
+// of each episode of a show or season, to 1 or 0.
+func setKodiWatched(client *kodi.Client, item database.Item, watched bool) error {
+	playcount := 0
+	if watched {
+		playcount = 1
+	}
+	switch item.MediaType {
+	case "movie":
+		return client.SetMoviePlaycount(item.KodiID, playcount)
+	case "episode":
+		return client.SetEpisodePlaycount(item.KodiID, playcount)
+	case "set":
+		movies, err := client.GetMovieSetMovies(item.KodiID)
+		if err != nil {
+			return err
+		}
+		for _, m := range movies {
+			if err := client.SetMoviePlaycount(m.ID, playcount); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	seasons := []int{item.Season}
+	if item.MediaType == "show" {
+		all, err := client.GetSeasons(item.KodiID)
+		if err != nil {
+			return err
+		}
+		seasons = seasons[:0]
+		for _, se := range all {
+			seasons = append(seasons, se.Season)
+		}
+	}
+	for _, season := range seasons {
+		episodes, err := client.GetEpisodes(item.KodiID, season)
+		if err != nil {
+			return err
+		}
+		for _, ep := range episodes {
+			if err := client.SetEpisodePlaycount(ep.ID, playcount); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"whats-next/internal/database"
+	"whats-next/internal/kodi"
+)
+
+// kodiWebhookEvent is a Kodi notification forwarded by an addon, as its
+// xbmc.Monitor.onNotification receives it, and the list whose host sent it.
+type kodiWebhookEvent struct {
+	ListID int64           `json:"list_id"`
+	Method string          `json:"method"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// handleKodiWebhook accepts a notification from a Kodi addon and acts on it
+// as if it arrived on the host's TCP interface, for hosts whose notification
+// port can't be reached: POST /kodi/events. The shared secret goes in the
+// X-Webhook-Secret header or, for addons that can't set headers, ?secret=.
+func (s *Server) handleKodiWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.config.KodiWebhookSecret == "" {
+		http.Error(w, "Kodi webhook is not enabled", http.StatusNotFound)
+		return
+	}
+	secret := r.Header.Get("X-Webhook-Secret")
+	if secret == "" {
+		secret = r.URL.Query().Get("secret")
+	}
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(s.config.KodiWebhookSecret)) != 1 {
+		http.Error(w, "Invalid webhook secret", http.StatusUnauthorized)
+		return
+	}
+
+	var ev kodiWebhookEvent
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&ev); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if ev.Method == "" {
+		http.Error(w, "method is required", http.StatusBadRequest)
+		return
+	}
+	// Addons pass onNotification's data through as the JSON string it is
+	var encoded string
+	if json.Unmarshal(ev.Data, &encoded) == nil {
+		ev.Data = json.RawMessage(encoded)
+	}
+
+	list, err := s.db.GetList(ev.ListID)
+	if err != nil {
+		writeDBError(w, err, "Failed to get list", "list_id", ev.ListID)
+		return
+	}
+	if list.Backend == database.BackendJellyfin {
+		writeDBError(w, errKodiOnly, "Kodi webhook for a Jellyfin list", "list_id", ev.ListID)
+		return
+	}
+	hosts, err := s.eventHosts()
+	if err != nil {
+		slog.Error("Failed to get lists for Kodi webhook", "error", err)
+		http.Error(w, "Failed to retrieve lists", http.StatusInternalServerError)
+		return
+	}
+	ctx := s.jobsCtx
+	if ctx == nil {
+		http.Error(w, "Server is starting", http.StatusServiceUnavailable)
+		return
+	}
+	for _, h := range hosts {
+		if h.host == list.KodiHost {
+			s.handleKodiEvent(ctx, h, kodi.Notification{Method: ev.Method, Data: ev.Data})
+			break
+		}
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
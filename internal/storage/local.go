@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Local stores posters as files in a directory.
+type Local struct {
+	dir string
+}
+
+// NewLocal returns a Local store rooted at dir, creating it if needed.
+func NewLocal(dir string) (*Local, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create poster directory: %w", err)
+	}
+	return &Local{dir: dir}, nil
+}
+
+func (l *Local) path(name string) (string, error) {
+	if !validName(name) {
+		return "", fmt.Errorf("invalid poster name %q", name)
+	}
+	return filepath.Join(l.dir, name), nil
+}
+
+// Path returns the absolute path of the named file, for handing it to a
+// web server with X-Sendfile.
+func (l *Local) Path(name string) (string, error) {
+	p, err := l.path(name)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Abs(p)
+}
+
+func (l *Local) Exists(name string) (bool, error) {
+	p, err := l.path(name)
+	if err != nil {
+		return false, err
+	}
+	if _, err := os.Stat(p); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Put writes to a temporary file first so readers never see a partial image.
+func (l *Local) Put(name string, r io.Reader) error {
+	p, err := l.path(name)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(l.dir, ".upload-*")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// Get returns the open file, which also satisfies io.ReadSeeker.
+func (l *Local) Get(name string) (io.ReadCloser, error) {
+	p, err := l.path(name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if os.IsNotExist(err) {
+		return nil, ErrNotExist
+	}
+	return f, err
+}
+
+func (l *Local) Delete(name string) error {
+	p, err := l.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// S3 stores posters in an S3-compatible bucket, signing requests with AWS
+// Signature Version 4.
+type S3 struct {
+	endpoint   *url.URL
+	region     string
+	bucket     string
+	prefix     string
+	accessKey  string
+	secretKey  string
+	pathStyle  bool
+	httpClient *http.Client
+}
+
+// NewS3 validates cfg and returns an S3 store. Endpoint defaults to AWS for
+// the configured region; MinIO and most self-hosted stores need PathStyle.
+func NewS3(cfg Config) (*S3, error) {
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("s3 poster storage requires a bucket")
+	}
+	if cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, fmt.Errorf("s3 poster storage requires access_key and secret_key")
+	}
+	region := cfg.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = "https://s3." + region + ".amazonaws.com"
+	}
+	if !strings.HasPrefix(endpoint, "http") {
+		endpoint = "https://" + endpoint
+	}
+	u, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid s3 endpoint: %w", err)
+	}
+	prefix := strings.Trim(cfg.Prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	return &S3{
+		endpoint:   u,
+		region:     region,
+		bucket:     cfg.Bucket,
+		prefix:     prefix,
+		accessKey:  cfg.AccessKey,
+		secretKey:  cfg.SecretKey,
+		pathStyle:  cfg.PathStyle,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+func (s *S3) Exists(name string) (bool, error) {
+	resp, err := s.do(http.MethodHead, name, nil)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+	return false, fmt.Errorf("s3 HEAD %s: status %d", name, resp.StatusCode)
+}
+
+// Put buffers the image so the payload can be signed; posters are small.
+func (s *S3) Put(name string, r io.Reader) error {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(http.MethodPut, name, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return s3Error(http.MethodPut, name, resp)
+	}
+	return nil
+}
+
+func (s *S3) Get(name string) (io.ReadCloser, error) {
+	resp, err := s.do(http.MethodGet, name, nil)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, ErrNotExist
+	}
+	defer resp.Body.Close()
+	return nil, s3Error(http.MethodGet, name, resp)
+}
+
+func (s *S3) Delete(name string) error {
+	resp, err := s.do(http.MethodDelete, name, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return s3Error(http.MethodDelete, name, resp)
+	}
+	return nil
+}
+
+func s3Error(method, name string, resp *http.Response) error {
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("s3 %s %s: status %d: %s", method, name, resp.StatusCode, strings.TrimSpace(string(msg)))
+}
+
+// do sends a signed request for the object name.
+func (s *S3) do(method, name string, body []byte) (*http.Response, error) {
+	if !validName(name) {
+		return nil, fmt.Errorf("invalid poster name %q", name)
+	}
+
+	u := *s.endpoint
+	key := s.prefix + name
+	if s.pathStyle {
+		u.Path = s.endpoint.Path + "/" + s.bucket + "/" + key
+	} else {
+		u.Host = s.bucket + "." + s.endpoint.Host
+		u.Path = s.endpoint.Path + "/" + key
+	}
+	u.RawPath = awsURIEncode(u.Path)
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if method == http.MethodPut {
+		req.Header.Set("Content-Type", "image/jpeg")
+	}
+	s.sign(req, body, time.Now().UTC())
+	return s.httpClient.Do(req)
+}
+
+// sign adds SigV4 headers to req.
+func (s *S3) sign(req *http.Request, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalHeaders := "host:" + req.URL.Host + "\n" +
+		"x-amz-content-sha256:" + payloadHash + "\n" +
+		"x-amz-date:" + amzDate + "\n"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		"", // no query string
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature))
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// awsURIEncode percent-encodes everything except unreserved characters, as
+// SigV4 requires for S3 object paths.
+func awsURIEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
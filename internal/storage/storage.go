@@ -0,0 +1,94 @@
+// Package storage abstracts where poster images are kept, so deployments can
+// use the local data directory or an S3-compatible object store.
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ErrNotExist is returned by Get when the named object isn't stored.
+var ErrNotExist = errors.New("storage: object does not exist")
+
+// Store holds poster images by flat file name (e.g. "movie_alien_1979.jpg").
+type Store interface {
+	Exists(name string) (bool, error)
+	Put(name string, r io.Reader) error
+	Get(name string) (io.ReadCloser, error)
+	Delete(name string) error
+}
+
+// Config selects and configures the poster store. Type is "local" (default)
+// or "s3".
+type Config struct {
+	Type string `json:"type,omitempty"`
+
+	// Local storage
+	Dir string `json:"dir,omitempty"`
+
+	// S3-compatible storage (AWS S3, MinIO, ...)
+	Endpoint  string `json:"endpoint,omitempty"`
+	Region    string `json:"region,omitempty"`
+	Bucket    string `json:"bucket,omitempty"`
+	Prefix    string `json:"prefix,omitempty"`
+	AccessKey string `json:"access_key,omitempty"`
+	SecretKey string `json:"secret_key,omitempty"`
+	PathStyle bool   `json:"path_style,omitempty"`
+
+	// Sendfile hands local poster files to a fronting web server instead of
+	// streaming them from the app: "x-accel-redirect" (nginx) or
+	// "x-sendfile" (Apache, lighttpd). SendfilePrefix is the internal nginx
+	// location aliased to the poster directory, e.g. "/protected-posters/".
+	Sendfile       string `json:"sendfile,omitempty"`
+	SendfilePrefix string `json:"sendfile_prefix,omitempty"`
+}
+
+// Sendfile modes.
+const (
+	SendfileAccel = "x-accel-redirect"
+	SendfileXSend = "x-sendfile"
+)
+
+// New builds the store described by cfg. S3 keys fall back to the standard
+// AWS_ACCESS_KEY_ID / AWS_SECRET_ACCESS_KEY environment variables.
+func New(cfg Config) (Store, error) {
+	switch cfg.Sendfile {
+	case "":
+	case SendfileAccel, SendfileXSend:
+		if t := strings.ToLower(cfg.Type); t != "" && t != "local" {
+			return nil, fmt.Errorf("poster storage sendfile %q needs local storage", cfg.Sendfile)
+		}
+		if cfg.Sendfile == SendfileAccel && cfg.SendfilePrefix == "" {
+			return nil, errors.New("poster storage sendfile \"x-accel-redirect\" needs a sendfile_prefix")
+		}
+	default:
+		return nil, fmt.Errorf("unknown poster storage sendfile mode %q", cfg.Sendfile)
+	}
+
+	switch strings.ToLower(cfg.Type) {
+	case "", "local":
+		dir := cfg.Dir
+		if dir == "" {
+			dir = "data/posters"
+		}
+		return NewLocal(dir)
+	case "s3":
+		if cfg.AccessKey == "" {
+			cfg.AccessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+		}
+		if cfg.SecretKey == "" {
+			cfg.SecretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		}
+		return NewS3(cfg)
+	default:
+		return nil, fmt.Errorf("unknown poster storage type %q", cfg.Type)
+	}
+}
+
+// validName rejects names that could escape the store's namespace.
+func validName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`)
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"flag"
 	"log/slog"
 	"net/http"
 	"os"
@@ -12,7 +13,9 @@ import (
 	"time"
 
 	"whats-next/internal/database"
+	"whats-next/internal/kodi/kodimock"
 	"whats-next/internal/server"
+	"whats-next/internal/storage"
 )
 
 func main() {
@@ -48,17 +51,28 @@ func main() {
 		Subtitle: "A watchlist manager for Kodi",
 		Footer:   "Made with Antigravity by kewalaka",
 	}
+	// Settings imported through the API fill in for anything config.json
+	// doesn't set itself
+	if err := db.ApplyImportedSettings(&fullConfig); err != nil {
+		slog.Error("Failed to apply imported settings", "error", err)
+	}
 
+	// Lists config.json no longer defines, deleted once the server is up
+	var removedLists []database.List
 	if _, err := os.Stat(configFile); err == nil {
 		slog.Info("Loading config from file", "path", configFile)
 		file, err := os.Open(configFile)
 		if err == nil {
 			defer file.Close()
 			if err := json.NewDecoder(file).Decode(&fullConfig); err == nil {
+				if err := db.SyncGroups(fullConfig.Groups); err != nil {
+					slog.Error("Error syncing groups from config", "error", err)
+				}
 				if err := db.SyncLists(fullConfig.Lists); err != nil {
 					slog.Error("Error syncing lists from config", "error", err)
 				} else {
 					slog.Info("Successfully synced lists from config", "count", len(fullConfig.Lists))
+					removedLists = configListsRemoved(db, fullConfig.Lists)
 				}
 			} else {
 				// Backwards compatibility for old array-only config
@@ -71,6 +85,7 @@ func main() {
 						slog.Error("Error syncing lists from legacy config", "error", err)
 					} else {
 						slog.Info("Successfully synced lists from legacy config", "count", len(listConfig))
+						removedLists = configListsRemoved(db, listConfig)
 					}
 				} else {
 					slog.Error("Error decoding config file", "error", err)
@@ -81,15 +96,99 @@ func main() {
 		}
 	}
 
-	if os.Getenv("MOCK_KODI") == "true" {
+	// Demo mode runs against the mock Kodi with sample lists unless the
+	// config defines its own.
+	demoMode := os.Getenv("DEMO_MODE") == "true"
+	if demoMode && len(fullConfig.Lists) == 0 {
+		fullConfig.Groups, fullConfig.Lists = server.DemoConfig()
+		if err := db.SyncGroups(fullConfig.Groups); err != nil {
+			slog.Error("Error syncing demo groups", "error", err)
+		}
+		if err := db.SyncLists(fullConfig.Lists); err != nil {
+			slog.Error("Error syncing demo lists", "error", err)
+		}
+	}
+
+	var mockKodi *kodimock.Server
+	if os.Getenv("MOCK_KODI") == "true" || demoMode {
+		lib, err := kodimock.LoadLibrary(os.Getenv("MOCK_KODI_FIXTURES"))
+		if err != nil {
+			slog.Error("Failed to load mock Kodi library", "error", err)
+			os.Exit(1)
+		}
+		if err := lib.Simulate.Override(os.Getenv("MOCK_KODI_LATENCY"), os.Getenv("MOCK_KODI_ERROR_RATE")); err != nil {
+			slog.Error("Invalid mock Kodi simulation settings", "error", err)
+			os.Exit(1)
+		}
+		mockKodi = kodimock.Start(lib)
+		defer mockKodi.Close()
 		slog.Warn("*****************************************")
-		slog.Warn("!!!  RUNNING IN MOCK KODI MODE (STUB) !!!")
+		slog.Warn("!!!  RUNNING IN MOCK KODI MODE (STUB) !!!", "url", mockKodi.URL)
 		slog.Warn("*****************************************")
 	} else {
 		slog.Info("--- RUNNING IN REAL KODI MODE ---")
 	}
 
-	srv := server.NewServer(db, fullConfig)
+	posters, err := storage.New(fullConfig.PosterStorage)
+	if err != nil {
+		slog.Error("Failed to initialize poster storage", "error", err)
+		os.Exit(1)
+	}
+
+	srv := server.NewServer(db, fullConfig, posters)
+	for _, l := range removedLists {
+		slog.Info("Deleting list removed from config", "list_id", l.ID, "group", l.GroupName, "name", l.Name)
+		if _, err := srv.DeleteList(l.ID, false); err != nil {
+			slog.Error("Failed to delete list removed from config", "list_id", l.ID, "error", err)
+		}
+	}
+	if mockKodi != nil {
+		srv.UseKodiHost(mockKodi.URL)
+		srv.UseKodiEvents(mockKodi.EventAddr)
+	}
+
+	// Maintenance commands run against the same database/config and exit
+	if len(os.Args) > 1 && os.Args[1] == "migrate-posters" {
+		flags := flag.NewFlagSet("migrate-posters", flag.ExitOnError)
+		dryRun := flags.Bool("dry-run", false, "report what would change without writing")
+		flags.Parse(os.Args[2:])
+
+		report, err := srv.MigratePosters(*dryRun)
+		if err != nil {
+			slog.Error("Poster migration failed", "error", err)
+			os.Exit(1)
+		}
+		json.NewEncoder(os.Stdout).Encode(report)
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "dedupe-cache" {
+		flags := flag.NewFlagSet("dedupe-cache", flag.ExitOnError)
+		dryRun := flags.Bool("dry-run", false, "report what would change without writing")
+		flags.Parse(os.Args[2:])
+
+		report, err := db.DedupeLibraryCache(*dryRun)
+		if err != nil {
+			slog.Error("Library cache dedupe failed", "error", err)
+			os.Exit(1)
+		}
+		json.NewEncoder(os.Stdout).Encode(report)
+		return
+	}
+
+	// Databases upgraded from per-list caches are cleaned up on every start;
+	// once merged there is nothing left to do.
+	if report, err := db.DedupeLibraryCache(false); err != nil {
+		slog.Error("Failed to merge duplicate library cache rows", "error", err)
+	} else if report.Removed > 0 || report.Orphans > 0 {
+		slog.Info("Merged duplicate library cache rows", "items", report.Duplicates, "removed", report.Removed, "orphans", report.Orphans)
+	}
+
+	if demoMode {
+		if err := srv.SeedDemo(); err != nil {
+			slog.Error("Failed to seed demo data", "error", err)
+		}
+	}
 
 	// API routes
 	http.Handle("/api/", http.StripPrefix("/api", srv.Routes()))
@@ -107,12 +206,17 @@ func main() {
 	})
 
 	httpServer := &http.Server{
-		Addr:         ":" + port,
-		Handler:      http.DefaultServeMux,
+		Addr:    ":" + port,
+		Handler: server.SecurityHeaders(http.DefaultServeMux, fullConfig.FrameAncestors),
+		// Defaults for non-API routes; API routes set their own deadlines
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 	}
 
+	// Background jobs run until shutdown
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	srv.StartBackgroundJobs(jobsCtx)
+
 	// Graceful shutdown handling
 	go func() {
 		slog.Info("Starting server", "port", port)
@@ -128,6 +232,8 @@ func main() {
 	<-quit
 
 	slog.Info("Shutting down server gracefully...")
+	stopJobs()
+	srv.WaitForJobs()
 
 	// Give connections 30 seconds to drain
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -139,3 +245,14 @@ func main() {
 
 	slog.Info("Server exited")
 }
+
+// configListsRemoved returns the lists an earlier config.json defined that
+// lists no longer includes.
+func configListsRemoved(db *database.DB, lists []database.List) []database.List {
+	removed, err := db.SetConfigLists(lists)
+	if err != nil {
+		slog.Error("Failed to record config lists", "error", err)
+		return nil
+	}
+	return removed
+}
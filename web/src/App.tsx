@@ -3,11 +3,13 @@ import { useQuery } from '@tanstack/react-query'
 import { getLists, getConfig } from './lib/api'
 import { ListSwitcher } from './components/ListSwitcher'
 import { WatchList } from './components/WatchList'
-import { Loader2, Tv } from 'lucide-react'
+import { MetricsPanel } from './components/MetricsPanel'
+import { Activity, Loader2, Tv } from 'lucide-react'
 
 function App() {
     const [activeGroup, setActiveGroup] = useState<string | null>(null);
     const [activeListName, setActiveListName] = useState<string | null>(null);
+    const [showMetrics, setShowMetrics] = useState(false);
 
     const normalizeListName = (name: string | null | undefined) => (name ?? '').toLowerCase();
 
@@ -105,7 +107,15 @@ function App() {
 
             <footer className="mt-20 py-6 text-textMuted text-xs w-full text-center border-t border-white/5">
                 {config?.footer}
+                <button
+                    onClick={() => setShowMetrics(true)}
+                    className="ml-3 inline-flex items-center gap-1 hover:text-white transition-colors"
+                >
+                    <Activity className="w-3 h-3" /> Stats
+                </button>
             </footer>
+
+            {showMetrics && <MetricsPanel onClose={() => setShowMetrics(false)} />}
         </div>
     )
 }
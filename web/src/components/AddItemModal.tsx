@@ -1,18 +1,19 @@
-import { useState } from 'react';
+import { Fragment, useState } from 'react';
 import { useQuery, useMutation, useQueryClient } from '@tanstack/react-query';
-import { searchMedia, addItem, getSeasons, MediaItem, Item } from '../lib/api';
-import { Search, Loader2, X, Star, ChevronRight, ArrowLeft, Plus } from 'lucide-react';
+import { searchMedia, addItem, getSeasons, getEpisodes, getMovieSets, startLibraryJob, MediaItem, Item } from '../lib/api';
+import { Search, Loader2, X, Star, ChevronRight, ChevronDown, ArrowLeft, Plus, FolderSearch } from 'lucide-react';
 
 interface AddItemModalProps {
     isOpen: boolean;
     onClose: () => void;
     listId: number;
-    contentType: string; // 'movie' or 'tv'
+    contentType: string; // 'movie', 'tv' or 'music'
 }
 
 export function AddItemModal({ isOpen, onClose, listId, contentType }: AddItemModalProps) {
     const [query, setQuery] = useState('');
     const [selectedShow, setSelectedShow] = useState<MediaItem | null>(null);
+    const [openSeason, setOpenSeason] = useState<number | null>(null);
     const queryClient = useQueryClient();
 
     const { data: results, isLoading } = useQuery({
@@ -27,13 +28,35 @@ export function AddItemModal({ isOpen, onClose, listId, contentType }: AddItemMo
         enabled: !!selectedShow,
     });
 
+    const { data: seasonEpisodes, isLoading: isLoadingEpisodes } = useQuery({
+        queryKey: ['episodes', selectedShow?.id, openSeason],
+        queryFn: () => getEpisodes(selectedShow!.id, openSeason!, listId),
+        enabled: !!selectedShow && openSeason !== null,
+    });
+
+    // Collections are few, so they are fetched once and matched locally
+    const { data: movieSets } = useQuery({
+        queryKey: ['movie-sets', listId],
+        queryFn: () => getMovieSets(listId),
+        enabled: isOpen && contentType === 'movie',
+        staleTime: 1000 * 60 * 5,
+    });
+    const matchingSets = query.length > 2
+        ? (movieSets ?? []).filter((set) => set.title.toLowerCase().includes(query.toLowerCase()))
+        : [];
+
     const addMutation = useMutation({
-        mutationFn: ({ mediaItem, position }: { mediaItem: MediaItem; position: -1 | 0 }) => {
-            const isSeason = mediaItem.title.toLowerCase().includes('season');
+        mutationFn: ({ mediaItem, position, isSet, isEpisode }: { mediaItem: MediaItem; position: -1 | 0; isSet?: boolean; isEpisode?: boolean }) => {
+            if (isEpisode) {
+                // The server fills in the show, numbering and poster from Kodi
+                return addItem(listId, { title: mediaItem.title, kodi_id: mediaItem.id, media_type: 'episode', season: mediaItem.season || 0, sort_order: position });
+            }
+            const isSeason = !isSet && contentType !== 'music' && mediaItem.title.toLowerCase().includes('season');
             const itemPayload: Partial<Item> = {
                 title: selectedShow ? selectedShow.title : (mediaItem.label || mediaItem.title),
                 kodi_id: selectedShow ? selectedShow.id : mediaItem.id,
-                media_type: isSeason ? 'season' : (contentType === 'tv' ? 'show' : 'movie'),
+                media_type: isSet ? 'set' : isSeason ? 'season' : contentType === 'tv' ? 'show' : contentType === 'music' ? 'album' : 'movie',
+                artist: mediaItem.artist || '',
                 year: (selectedShow?.year || mediaItem.year) || 0,
                 poster_path: (selectedShow?.thumbnail || mediaItem.thumbnail) || '',
                 season: isSeason ? mediaItem.season : 0,
@@ -51,9 +74,24 @@ export function AddItemModal({ isOpen, onClose, listId, contentType }: AddItemMo
         },
     });
 
+    const wantedMutation = useMutation({
+        mutationFn: (title: string) => addItem(listId, { title, wanted: true, sort_order: 0 }),
+        onSuccess: () => {
+            queryClient.invalidateQueries({ queryKey: ['items', listId] });
+            onClose();
+            handleClose();
+        },
+    });
+
+    const scanMutation = useMutation({
+        mutationFn: () => startLibraryJob(listId, 'scan'),
+        onError: (e) => console.error('Library scan failed:', e),
+    });
+
     const handleClose = () => {
         setQuery('');
         setSelectedShow(null);
+        setOpenSeason(null);
     };
 
     const formatRuntime = (seconds: number) => {
@@ -77,7 +115,7 @@ export function AddItemModal({ isOpen, onClose, listId, contentType }: AddItemMo
             <div className="w-full max-w-2xl bg-[#1e1e24] border border-border rounded-xl shadow-2xl overflow-hidden flex flex-col max-h-[80vh]">
                 <div className="p-4 border-b border-white/10 flex items-center gap-3">
                     {selectedShow ? (
-                        <button onClick={() => setSelectedShow(null)} className="p-1 hover:bg-white/10 rounded">
+                        <button onClick={() => { setSelectedShow(null); setOpenSeason(null); }} className="p-1 hover:bg-white/10 rounded">
                             <ArrowLeft className="w-5 h-5 text-textMuted" />
                         </button>
                     ) : (
@@ -103,6 +141,56 @@ export function AddItemModal({ isOpen, onClose, listId, contentType }: AddItemMo
                         </div>
                     ) : null}
 
+                    {!selectedShow && !isLoading && query.length > 2 && results?.length === 0 && matchingSets.length === 0 && (
+                        <div className="flex flex-col items-center gap-3 py-10 text-textMuted">
+                            <p>Not in your library yet.</p>
+                            <button
+                                onClick={() => wantedMutation.mutate(query.trim())}
+                                className="flex items-center gap-1.5 px-3 py-1.5 rounded bg-amber-500/10 hover:bg-amber-500/20 text-amber-400 transition text-sm font-medium"
+                            >
+                                <Plus className="w-3.5 h-3.5" />
+                                <span>Add "{query.trim()}" as wanted</span>
+                            </button>
+                            <button
+                                onClick={() => scanMutation.mutate()}
+                                disabled={scanMutation.isPending || scanMutation.isSuccess}
+                                title="Ask Kodi to scan its sources for new content"
+                                className="flex items-center gap-1.5 px-3 py-1.5 rounded bg-white/5 hover:bg-white/10 text-textMuted hover:text-white transition text-sm font-medium disabled:opacity-60"
+                            >
+                                <FolderSearch className="w-3.5 h-3.5" />
+                                <span>{scanMutation.isSuccess ? 'Scan started, search again shortly' : 'Scan Kodi library'}</span>
+                            </button>
+                        </div>
+                    )}
+
+                    {!selectedShow && matchingSets.map((set) => (
+                        <div key={`set-${set.id}`} className="flex items-center gap-4 p-3 hover:bg-white/5 rounded-lg group transition-colors">
+                            <div className="w-12 h-16 bg-black/40 rounded flex-shrink-0 overflow-hidden">
+                                {set.thumbnail && <img src={getImageURL(set.thumbnail)} className="w-full h-full object-cover" />}
+                            </div>
+                            <div className="flex-1 min-w-0">
+                                <h4 className="font-medium truncate text-white">{set.title}</h4>
+                                <p className="text-sm text-textMuted">Collection</p>
+                            </div>
+                            <div className="flex gap-2">
+                                <button
+                                    onClick={() => addMutation.mutate({ mediaItem: set, position: -1, isSet: true })}
+                                    className="flex items-center gap-1.5 px-3 py-1.5 rounded bg-white/5 hover:bg-amber-500/20 hover:text-amber-400 transition text-sm"
+                                >
+                                    <Star className="w-3.5 h-3.5" />
+                                    <span>Add to top</span>
+                                </button>
+                                <button
+                                    onClick={() => addMutation.mutate({ mediaItem: set, position: 0, isSet: true })}
+                                    className="flex items-center gap-1.5 px-3 py-1.5 rounded bg-primary/10 hover:bg-primary/20 text-primary transition text-sm font-medium"
+                                >
+                                    <Plus className="w-3.5 h-3.5" />
+                                    <span>Add</span>
+                                </button>
+                            </div>
+                        </div>
+                    ))}
+
                     {!selectedShow && results?.map((item) => (
                         <div key={item.id} className="flex items-center gap-4 p-3 hover:bg-white/5 rounded-lg group transition-colors">
                             <div className="w-12 h-16 bg-black/40 rounded flex-shrink-0 overflow-hidden">
@@ -112,13 +200,24 @@ export function AddItemModal({ isOpen, onClose, listId, contentType }: AddItemMo
                                 <div className="flex items-center gap-2">
                                     <h4 className="font-medium truncate text-white">{item.title}</h4>
                                     {item.rating && <span className="flex items-center gap-0.5 text-xs text-amber-400 font-bold"><Star className="w-3 h-3 fill-current" /> {item.rating.toFixed(1)}</span>}
+                                    {item.on_lists?.map((l) => (
+                                        <span key={l.id} className="text-[10px] uppercase tracking-wider font-bold text-primary bg-primary/10 px-1.5 py-0.5 rounded whitespace-nowrap">
+                                            Already on {l.list_name}
+                                        </span>
+                                    ))}
+                                    {item.last_watched && (
+                                        <span title={`Last watched ${item.last_watched}`} className="text-[10px] uppercase tracking-wider font-bold text-emerald-400 bg-emerald-500/10 px-1.5 py-0.5 rounded whitespace-nowrap">
+                                            Watched {item.last_watched.slice(0, 10)}
+                                        </span>
+                                    )}
                                 </div>
                                 <p className="text-sm text-textMuted">
-                                    {item.year} • {contentType === 'tv' ? `Series (${item.episode_count || '?'} Episodes)` : `Movie (${formatRuntime(item.runtime || 0)})`}
+                                    {item.year} • {contentType === 'tv' ? `Series (${item.episode_count || '?'} Episodes)` : contentType === 'music' ? `Album by ${item.artist || 'Unknown artist'}` : `Movie (${formatRuntime(item.runtime || 0)})`}
+                                    {item.quality && ` • ${item.quality}`}
                                 </p>
                             </div>
                             {contentType === 'tv' ? (
-                                <button onClick={() => setSelectedShow(item)} className="p-2 rounded hover:bg-white/10 transition">
+                                <button onClick={() => { setSelectedShow(item); setOpenSeason(null); }} className="p-2 rounded hover:bg-white/10 transition">
                                     <ChevronRight className="w-5 h-5 text-textMuted" />
                                 </button>
                             ) : (
@@ -153,28 +252,54 @@ export function AddItemModal({ isOpen, onClose, listId, contentType }: AddItemMo
                     )}
 
                     {selectedShow && seasons?.map((season) => (
-                        <div key={season.id} className="flex items-center justify-between p-4 hover:bg-white/5 rounded-lg group transition-colors">
-                            <div>
-                                <h4 className="font-medium text-white">{season.title || season.label}</h4>
-                                <p className="text-sm text-textMuted">{season.episode_count} episodes</p>
-                            </div>
-                            <div className="flex gap-2">
-                                <button
-                                    onClick={() => addMutation.mutate({ mediaItem: season, position: -1 })}
-                                    className="flex items-center gap-1.5 px-3 py-1.5 rounded bg-white/5 hover:bg-amber-500/20 hover:text-amber-400 transition text-sm"
-                                >
-                                    <Star className="w-3.5 h-3.5" />
-                                    <span>Add to top</span>
-                                </button>
-                                <button
-                                    onClick={() => addMutation.mutate({ mediaItem: season, position: 0 })}
-                                    className="flex items-center gap-1.5 px-3 py-1.5 rounded bg-primary/10 hover:bg-primary/20 text-primary transition text-sm font-medium"
-                                >
-                                    <Plus className="w-3.5 h-3.5" />
-                                    <span>Add</span>
+                        <Fragment key={season.id}>
+                            <div className="flex items-center justify-between p-4 hover:bg-white/5 rounded-lg group transition-colors">
+                                <button onClick={() => setOpenSeason(openSeason === season.season ? null : season.season ?? null)} className="flex items-center gap-2 text-left">
+                                    {openSeason === season.season ? <ChevronDown className="w-4 h-4 text-textMuted" /> : <ChevronRight className="w-4 h-4 text-textMuted" />}
+                                    <div>
+                                        <h4 className="font-medium text-white">{season.title || season.label}</h4>
+                                        <p className="text-sm text-textMuted">{season.episode_count} episodes</p>
+                                    </div>
                                 </button>
+                                <div className="flex gap-2">
+                                    <button
+                                        onClick={() => addMutation.mutate({ mediaItem: season, position: -1 })}
+                                        className="flex items-center gap-1.5 px-3 py-1.5 rounded bg-white/5 hover:bg-amber-500/20 hover:text-amber-400 transition text-sm"
+                                    >
+                                        <Star className="w-3.5 h-3.5" />
+                                        <span>Add to top</span>
+                                    </button>
+                                    <button
+                                        onClick={() => addMutation.mutate({ mediaItem: season, position: 0 })}
+                                        className="flex items-center gap-1.5 px-3 py-1.5 rounded bg-primary/10 hover:bg-primary/20 text-primary transition text-sm font-medium"
+                                    >
+                                        <Plus className="w-3.5 h-3.5" />
+                                        <span>Add</span>
+                                    </button>
+                                </div>
                             </div>
-                        </div>
+                            {openSeason === season.season && (
+                                <div className="ml-10 mb-2 border-l border-white/10">
+                                    {isLoadingEpisodes && <Loader2 className="w-4 h-4 animate-spin text-textMuted m-3" />}
+                                    {seasonEpisodes?.map((ep) => (
+                                        <div key={ep.id} className="flex items-center justify-between px-3 py-2 hover:bg-white/5 rounded-lg">
+                                            <span className="text-sm text-white truncate">
+                                                <span className="text-textMuted font-mono mr-2">E{String(ep.episode ?? 0).padStart(2, '0')}</span>
+                                                {ep.title || ep.label}
+                                            </span>
+                                            <button
+                                                onClick={() => addMutation.mutate({ mediaItem: { ...ep, season: season.season }, position: 0, isEpisode: true })}
+                                                className="flex items-center gap-1 px-2 py-1 rounded bg-primary/10 hover:bg-primary/20 text-primary transition text-xs font-medium"
+                                                title="Add this episode"
+                                            >
+                                                <Plus className="w-3 h-3" />
+                                                <span>Add</span>
+                                            </button>
+                                        </div>
+                                    ))}
+                                </div>
+                            )}
+                        </Fragment>
                     ))}
                 </div>
             </div>
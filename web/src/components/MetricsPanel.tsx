@@ -0,0 +1,68 @@
+import { useQuery } from '@tanstack/react-query'
+import { getMetricsSummary } from '../lib/api'
+import { Loader2, X } from 'lucide-react'
+
+interface MetricsPanelProps {
+    onClose: () => void;
+}
+
+function Stat({ label, value }: { label: string; value: string | number }) {
+    return (
+        <div className="bg-background/50 rounded-lg p-3 border border-white/5">
+            <div className="text-xs text-textMuted">{label}</div>
+            <div className="text-xl font-semibold">{value}</div>
+        </div>
+    );
+}
+
+export function MetricsPanel({ onClose }: MetricsPanelProps) {
+    const { data, isLoading, error } = useQuery({
+        queryKey: ['metrics'],
+        queryFn: () => getMetricsSummary(),
+        refetchInterval: 60000,
+    });
+
+    const peak = Math.max(1, ...(data?.hourly.map(h => h.requests) ?? []));
+
+    return (
+        <div className="fixed inset-0 bg-black/60 backdrop-blur-sm flex items-center justify-center z-50 p-4" onClick={onClose}>
+            <div className="bg-surface rounded-xl border border-border shadow-2xl w-full max-w-lg p-6" onClick={e => e.stopPropagation()}>
+                <div className="flex justify-between items-center mb-4">
+                    <h2 className="text-lg font-semibold">Last {data?.window_hours ?? 24} hours</h2>
+                    <button onClick={onClose} className="text-textMuted hover:text-white" aria-label="Close">
+                        <X className="w-5 h-5" />
+                    </button>
+                </div>
+
+                {isLoading ? (
+                    <Loader2 className="animate-spin text-textMuted mx-auto" />
+                ) : error || !data ? (
+                    <p className="text-sm text-red-400">Couldn't load metrics.</p>
+                ) : (
+                    <>
+                        <div className="grid grid-cols-3 gap-3 mb-4">
+                            <Stat label="Requests" value={data.requests} />
+                            <Stat label="Server errors" value={data.errors} />
+                            <Stat label="Client errors" value={data.client_errors} />
+                            <Stat label="Syncs" value={data.syncs.runs} />
+                            <Stat label="Failed syncs" value={data.syncs.failures} />
+                            <Stat label="Avg sync" value={`${(data.syncs.avg_duration_ms / 1000).toFixed(1)}s`} />
+                        </div>
+                        {data.hourly.length > 0 && (
+                            <div className="flex items-end gap-0.5 h-16" title="Requests per hour">
+                                {data.hourly.map(h => (
+                                    <div
+                                        key={h.hour}
+                                        className={`flex-1 rounded-t ${h.errors > 0 ? 'bg-red-400/70' : 'bg-primary/60'}`}
+                                        style={{ height: `${(h.requests / peak) * 100}%` }}
+                                        title={`${new Date(h.hour).toLocaleTimeString([], { hour: '2-digit', minute: '2-digit' })}: ${h.requests} requests, ${h.errors} errors`}
+                                    />
+                                ))}
+                            </div>
+                        )}
+                    </>
+                )}
+            </div>
+        </div>
+    );
+}
@@ -2,15 +2,19 @@ import { useState } from 'react';
 import { useSortable } from '@dnd-kit/sortable';
 import { CSS } from '@dnd-kit/utilities';
 import { useQuery } from '@tanstack/react-query';
-import { Item, getEpisodes } from '../lib/api';
-import { GripVertical, Trash2, Tv, Film, Star, Clock, Loader2, ChevronDown, ChevronUp, RefreshCw } from 'lucide-react';
+import { Item, NowPlaying, NextEpisode, getEpisodes, getSetMovies } from '../lib/api';
+import { GripVertical, Trash2, Tv, Film, Star, Clock, Loader2, ChevronDown, ChevronUp, RefreshCw, Eye, EyeOff, Library, Ungroup } from 'lucide-react';
 
 interface SortableItemProps {
     item: Item;
+    nowPlaying?: NowPlaying;
+    nextEpisode?: NextEpisode;
     onDelete: (id: number) => void;
+    onToggleWatched: (id: number, watched: boolean) => void;
+    onExpand: (id: number) => void;
 }
 
-export function SortableItem({ item, onDelete }: SortableItemProps) {
+export function SortableItem({ item, nowPlaying, nextEpisode, onDelete, onToggleWatched, onExpand }: SortableItemProps) {
     const [isExpanded, setIsExpanded] = useState(false);
     const { attributes, listeners, setNodeRef, transform, transition, isDragging } = useSortable({ id: item.id });
 
@@ -22,6 +26,14 @@ export function SortableItem({ item, onDelete }: SortableItemProps) {
         staleTime: 1000 * 60 * 60, // 1 hour
     });
 
+    // Movies in a set are fetched once the set is opened
+    const { data: setMovies, isLoading: isLoadingSet } = useQuery({
+        queryKey: ['set-movies', item.list_id, item.kodi_id],
+        queryFn: () => getSetMovies(item.kodi_id, item.list_id),
+        enabled: item.media_type === 'set' && isExpanded,
+        staleTime: 1000 * 60 * 60,
+    });
+
     const style = {
         transform: CSS.Transform.toString(transform),
         transition,
@@ -48,7 +60,7 @@ export function SortableItem({ item, onDelete }: SortableItemProps) {
         <div
             ref={setNodeRef}
             style={style}
-            className={`group bg-surface rounded-xl border border-border mb-3 shadow-md transition hover:border-primary/30 ${isDragging ? 'shadow-2xl' : ''}`}
+            className={`group bg-surface rounded-xl border mb-3 shadow-md transition hover:border-primary/30 ${item.wanted ? 'border-dashed border-white/20 opacity-70' : nowPlaying ? 'border-primary' : 'border-border'} ${isDragging ? 'shadow-2xl' : ''}`}
         >
             <div className="flex items-center gap-4 p-4">
                 <button {...attributes} {...listeners} className="cursor-grab touch-none select-none text-textMuted hover:text-white p-1">
@@ -62,10 +74,20 @@ export function SortableItem({ item, onDelete }: SortableItemProps) {
                 <div className="flex-1 min-w-0">
                     <div className="flex items-center justify-between mb-1">
                         <div className="flex items-center gap-2">
-                            {item.media_type === 'movie' ? <Film className="w-3.5 h-3.5 text-accent" /> : <Tv className="w-3.5 h-3.5 text-primary" />}
+                            {item.media_type === 'movie' ? <Film className="w-3.5 h-3.5 text-accent" /> : item.media_type === 'set' ? <Library className="w-3.5 h-3.5 text-accent" /> : <Tv className="w-3.5 h-3.5 text-primary" />}
                             <span className="text-[10px] uppercase tracking-wider font-bold text-textMuted">
-                                {item.media_type === 'season' ? `Season ${item.season}` : item.media_type}
+                                {item.media_type === 'season' ? `Season ${item.season}` : item.media_type === 'set' ? 'Collection' : item.media_type === 'episode' && item.episode_code ? item.episode_code : item.media_type}
                             </span>
+                            {item.wanted && (
+                                <span className="text-[10px] uppercase tracking-wider font-bold text-amber-400 bg-amber-400/10 px-1.5 py-0.5 rounded border border-amber-400/20" title="Not in the Kodi library yet">
+                                    Wanted
+                                </span>
+                            )}
+                            {item.missing_since && (
+                                <span className="text-[10px] uppercase tracking-wider font-bold text-red-400 bg-red-400/10 px-1.5 py-0.5 rounded border border-red-400/20" title={`Removed from the Kodi library (noticed ${item.missing_since})`}>
+                                    Removed
+                                </span>
+                            )}
                         </div>
                         {item.rating > 0 && (
                             <div className="flex items-center gap-1 text-xs text-amber-400 font-bold bg-amber-400/10 px-2 py-0.5 rounded-full border border-amber-400/20">
@@ -74,9 +96,44 @@ export function SortableItem({ item, onDelete }: SortableItemProps) {
                             </div>
                         )}
                     </div>
-                    <h3 className="text-lg font-semibold text-white truncate mb-1">{item.title}</h3>
+                    {item.art?.clearlogo ? (
+                        <img src={getImageURL(item.art.clearlogo)} alt={item.title} title={item.title} className="h-7 max-w-[60%] object-contain object-left mb-1" />
+                    ) : (
+                        <h3 className="text-lg font-semibold text-white truncate mb-1">{item.media_type === 'episode' && item.show_title ? item.show_title : item.title}</h3>
+                    )}
+                    {item.media_type === 'episode' && item.show_title && (
+                        <p className="text-sm text-white/80 truncate mb-1">{item.title}</p>
+                    )}
+                    {nowPlaying && (
+                        <div className="mb-1.5" title={nowPlaying.type === 'episode' ? `S${nowPlaying.season}E${nowPlaying.episode}: ${nowPlaying.title}` : undefined}>
+                            <div className="text-[10px] uppercase tracking-wider font-bold text-primary mb-1">
+                                {nowPlaying.paused ? 'Paused' : 'Now playing'}
+                            </div>
+                            <div className="h-1 bg-white/10 rounded-full overflow-hidden">
+                                <div className="h-full bg-primary" style={{ width: `${nowPlaying.percentage}%` }} />
+                            </div>
+                        </div>
+                    )}
                     <div className="flex items-center gap-4 text-sm text-textMuted">
-                        <span>{item.year}</span>
+                        {item.year > 0 && <span>{item.year}</span>}
+                        {item.media_type === 'set' && (
+                            <div className="flex items-center gap-1">
+                                <button
+                                    onClick={() => setIsExpanded(!isExpanded)}
+                                    className="flex items-center gap-1.5 px-2 py-0.5 bg-accent/10 text-accent rounded text-xs font-semibold hover:bg-accent/20 transition"
+                                >
+                                    {isExpanded && isLoadingSet ? <Loader2 className="w-3 h-3 animate-spin" /> : 'Movies'}
+                                    {isExpanded ? <ChevronUp className="w-3 h-3" /> : <ChevronDown className="w-3 h-3" />}
+                                </button>
+                                <button
+                                    onClick={(e) => { e.stopPropagation(); onExpand(item.id); }}
+                                    className="p-1 text-textMuted hover:text-white transition"
+                                    title="Replace the collection with its movies"
+                                >
+                                    <Ungroup className="w-3 h-3" />
+                                </button>
+                            </div>
+                        )}
                         {item.media_type === 'season' && (
                             <div className="flex items-center gap-1">
                                 <button
@@ -100,14 +157,44 @@ export function SortableItem({ item, onDelete }: SortableItemProps) {
                                 <Clock className="w-3 h-3" /> {formatRuntime(totalRuntime)}
                             </span>
                         )}
-                        {item.media_type === 'movie' && item.runtime > 0 && (
+                        {(item.media_type === 'movie' || item.media_type === 'episode') && item.runtime > 0 && (
                             <span className="flex items-center gap-1.5 text-xs">
                                 <Clock className="w-3 h-3" /> {formatRuntime(item.runtime)}
                             </span>
                         )}
+                        {nextEpisode && !nowPlaying && (
+                            <span className="text-xs text-primary" title={nextEpisode.title}>
+                                Next: {nextEpisode.code}
+                            </span>
+                        )}
+                        {item.quality && (
+                            <span className="text-[10px] font-bold tracking-wider text-textMuted bg-white/5 px-1.5 py-0.5 rounded border border-white/10">
+                                {item.quality}
+                            </span>
+                        )}
+                        {!nowPlaying && (item.resume_position ?? 0) > 0 && (
+                            <span className="text-xs text-primary" title={item.last_played ? `Last played ${item.last_played}` : undefined}>
+                                Resume from {formatRuntime(item.resume_position!)}
+                            </span>
+                        )}
+                        {item.watched && !item.resume_position && (
+                            <span className="text-[10px] uppercase tracking-wider font-bold text-primary bg-primary/10 px-1.5 py-0.5 rounded border border-primary/20" title={item.last_played ? `Last played ${item.last_played}` : undefined}>
+                                Watched
+                            </span>
+                        )}
                     </div>
                 </div>
 
+                {!item.wanted && (
+                    <button
+                        onClick={() => onToggleWatched(item.id, !item.watched)}
+                        className={`p-2.5 rounded-lg transition opacity-70 hover:opacity-100 ${item.watched ? 'text-primary hover:bg-primary/10' : 'text-textMuted hover:text-white hover:bg-white/5'}`}
+                        title={item.watched ? 'Mark unwatched' : 'Mark watched'}
+                    >
+                        {item.watched ? <Eye className="w-5 h-5" /> : <EyeOff className="w-5 h-5" />}
+                    </button>
+                )}
+
                 <button
                     onClick={() => onDelete(item.id)}
                     className="p-2.5 rounded-lg text-textMuted hover:text-red-400 hover:bg-red-400/10 transition opacity-70 hover:opacity-100"
@@ -117,6 +204,19 @@ export function SortableItem({ item, onDelete }: SortableItemProps) {
                 </button>
             </div>
 
+            {isExpanded && setMovies && setMovies.length > 0 && (
+                <div className="px-14 pb-4 animate-in slide-in-from-top-2">
+                    <div className="space-y-2 border-l-2 border-white/5 pl-4 py-2">
+                        {setMovies.map(m => (
+                            <div key={m.id} className="flex justify-between items-center text-sm">
+                                <span className="text-white font-medium">{m.title} <span className="text-textMuted">({m.year})</span></span>
+                                <span className="text-xs text-textMuted flex items-center gap-1"><Clock className="w-3 h-3" />{formatRuntime(m.runtime || 0)}</span>
+                            </div>
+                        ))}
+                    </div>
+                </div>
+            )}
+
             {isExpanded && episodes && episodes.length > 0 && (
                 <div className="px-14 pb-4 animate-in slide-in-from-top-2">
                     <div className="space-y-2 border-l-2 border-white/5 pl-4 py-2">
@@ -1,6 +1,6 @@
-import { useState } from 'react';
+import { Fragment, useState } from 'react';
 import { useQuery, useMutation, useQueryClient } from '@tanstack/react-query';
-import { getItems, deleteItem, reorderItem, syncLibrary } from '../lib/api';
+import { getItems, deleteItem, reorderList, ConflictError, KodiAuthError, syncLibrary, queueList, queueAll, getNowPlaying, setItemWatched, getSections, addSection, deleteSection, setItemSection, removeMissingItems, expandItem, getNextEpisodes, getFranchiseGroups, Item } from '../lib/api';
 import { SortableContext, verticalListSortingStrategy, arrayMove } from '@dnd-kit/sortable';
 import {
     DndContext,
@@ -13,7 +13,7 @@ import {
 } from '@dnd-kit/core';
 import { SortableItem } from './SortableItem';
 import { AddItemModal } from './AddItemModal';
-import { Plus, Loader2, RefreshCw } from 'lucide-react';
+import { Plus, Loader2, RefreshCw, ListVideo, SeparatorHorizontal, X, Layers, PartyPopper } from 'lucide-react';
 
 interface WatchListProps {
     listId: number;
@@ -24,6 +24,7 @@ interface WatchListProps {
 export function WatchList({ listId, name, contentType }: WatchListProps) {
     const [isModalOpen, setIsModalOpen] = useState(false);
     const [isSyncing, setIsSyncing] = useState(false);
+    const [groupByFranchise, setGroupByFranchise] = useState(false);
     const queryClient = useQueryClient();
 
     const sensors = useSensors(
@@ -45,15 +46,105 @@ export function WatchList({ listId, name, contentType }: WatchListProps) {
         queryFn: () => getItems(listId),
     });
 
+    // Shares the ['items', listId] prefix so item mutations refresh it too
+    const { data: franchiseGroups } = useQuery({
+        queryKey: ['items', listId, 'franchise'],
+        queryFn: () => getFranchiseGroups(listId),
+        enabled: groupByFranchise,
+    });
+
+    const { data: sections } = useQuery({
+        queryKey: ['sections', listId],
+        queryFn: () => getSections(listId),
+    });
+
+    const { data: nowPlaying } = useQuery({
+        queryKey: ['nowplaying', listId],
+        queryFn: () => getNowPlaying(listId),
+        refetchInterval: 15000,
+        retry: false,
+    });
+
+    const { data: nextEpisodes } = useQuery({
+        queryKey: ['next-episodes', listId],
+        queryFn: () => getNextEpisodes(listId),
+        enabled: contentType === 'tv',
+        staleTime: 1000 * 60 * 5,
+        retry: false,
+    });
+
     const deleteMutation = useMutation({
         mutationFn: deleteItem,
         onSuccess: () => queryClient.invalidateQueries({ queryKey: ['items', listId] }),
     });
 
+    const watchedMutation = useMutation({
+        mutationFn: ({ id, watched }: { id: number; watched: boolean }) => setItemWatched(id, watched),
+        onSuccess: () => {
+            queryClient.invalidateQueries({ queryKey: ['items', listId] });
+            queryClient.invalidateQueries({ queryKey: ['next-episodes', listId] });
+        },
+        onError: (e) => console.error('Update watched failed:', e),
+    });
+
+    const expandMutation = useMutation({
+        mutationFn: expandItem,
+        onSuccess: () => queryClient.invalidateQueries({ queryKey: ['items', listId] }),
+        onError: (e) => console.error('Expand set failed:', e),
+    });
+
+    const queueMutation = useMutation({
+        mutationFn: () => queueList(listId, true),
+        onError: (e) => console.error('Queue failed:', e),
+    });
+
+    const partyMutation = useMutation({
+        mutationFn: () => queueAll(listId),
+        onError: (e) => console.error('Party mode failed:', e),
+    });
+
     const reorderMutation = useMutation({
-        mutationFn: ({ id, sortOrder }: { id: number; sortOrder: number }) => reorderItem(id, sortOrder),
+        mutationFn: ({ ids, checkRevision }: { ids: number[]; checkRevision: boolean }) => reorderList(listId, ids, checkRevision),
+        onError: (e) => {
+            // Someone else changed the list; show their version
+            if (!(e instanceof ConflictError)) console.error('Reorder failed:', e);
+            queryClient.invalidateQueries({ queryKey: ['items', listId] });
+        },
+    });
+
+    const invalidateSections = () => {
+        queryClient.invalidateQueries({ queryKey: ['sections', listId] });
+        queryClient.invalidateQueries({ queryKey: ['items', listId] });
+    };
+
+    const addSectionMutation = useMutation({
+        mutationFn: (name: string) => addSection(listId, name),
+        onSuccess: invalidateSections,
+        onError: (e) => console.error('Add section failed:', e),
+    });
+
+    const deleteSectionMutation = useMutation({
+        mutationFn: (sectionId: number) => deleteSection(listId, sectionId),
+        onSuccess: invalidateSections,
+    });
+
+    const sectionMutation = useMutation({
+        mutationFn: ({ id, sectionId }: { id: number; sectionId: number }) => setItemSection(id, sectionId),
+        onSuccess: () => queryClient.invalidateQueries({ queryKey: ['items', listId] }),
+        onError: (e) => console.error('Move item failed:', e),
+    });
+
+    const cleanupMutation = useMutation({
+        mutationFn: () => removeMissingItems(listId),
+        onSuccess: () => queryClient.invalidateQueries({ queryKey: ['items', listId] }),
+        onError: (e) => console.error('Cleanup failed:', e),
     });
 
+    const handleAddSection = () => {
+        const name = window.prompt('Section name, e.g. "This weekend"')?.trim();
+        if (name) addSectionMutation.mutate(name);
+    };
+
     const handleSync = async () => {
         setIsSyncing(true);
         try {
@@ -61,6 +152,7 @@ export function WatchList({ listId, name, contentType }: WatchListProps) {
             queryClient.invalidateQueries({ queryKey: ['items', listId] });
         } catch (e) {
             console.error('Sync failed:', e);
+            if (e instanceof KodiAuthError) window.alert(`${e.message}. Check the username and password for this list.`);
         } finally {
             setIsSyncing(false);
         }
@@ -68,22 +160,46 @@ export function WatchList({ listId, name, contentType }: WatchListProps) {
 
     const onDragEnd = (event: DragEndEvent) => {
         const { active, over } = event;
+        // Grouping overrides the manual order, so there is nothing to drop into
+        if (groupByFranchise) return;
         if (over && active.id !== over.id) {
             const oldIndex = items!.findIndex((i) => i.id === active.id);
             const newIndex = items!.findIndex((i) => i.id === over.id);
             const newItems = arrayMove(items!, oldIndex, newIndex);
 
+            const ids = newItems.map((i) => i.id);
+
+            // Dropping onto an item in another section moves it there
+            const moved = items![oldIndex];
+            const target = items![newIndex];
+            const changesSection = (moved.section_id ?? 0) !== (target.section_id ?? 0);
+            if (changesSection) {
+                newItems[newIndex] = { ...moved, section_id: target.section_id };
+            }
+
             // Update UI optimistically
             queryClient.setQueryData(['items', listId], newItems);
 
-            // Persist changes
-            newItems.forEach((item, index) => {
-                reorderMutation.mutate({ id: item.id, sortOrder: index });
-            });
+            // Persist changes. The section move bumps the list's revision,
+            // so the order that follows it can't be checked against it.
+            if (changesSection) {
+                sectionMutation.mutate(
+                    { id: moved.id, sectionId: target.section_id ?? 0 },
+                    { onSuccess: () => reorderMutation.mutate({ ids, checkRevision: false }) },
+                );
+            } else {
+                reorderMutation.mutate({ ids, checkRevision: true });
+            }
         }
     };
 
+    const renderItem = (item: Item) => (
+        <SortableItem key={item.id} item={item} nowPlaying={nowPlaying?.item_id === item.id ? nowPlaying : undefined} nextEpisode={nextEpisodes?.find((n) => n.item_id === item.id)} onDelete={(id) => deleteMutation.mutate(id)} onToggleWatched={(id, watched) => watchedMutation.mutate({ id, watched })} onExpand={(id) => expandMutation.mutate(id)} />
+    );
+
     const safeItems = items || [];
+    const safeSections = sections || [];
+    const missingCount = safeItems.filter((i) => i.missing_since).length;
 
     return (
         <div className="w-full">
@@ -95,6 +211,40 @@ export function WatchList({ listId, name, contentType }: WatchListProps) {
                     </span>
                 </h2>
                 <div className="flex gap-2">
+                    <button
+                        onClick={() => setGroupByFranchise(!groupByFranchise)}
+                        title="Cluster titles of the same franchise or collection"
+                        className={`flex items-center gap-2 px-4 py-2 rounded-lg font-medium transition-all text-sm border ${groupByFranchise ? 'bg-primary/20 text-primary border-primary/30' : 'bg-white/5 hover:bg-white/10 text-white border-white/10'}`}
+                    >
+                        <Layers className="w-4 h-4" />
+                        Group
+                    </button>
+                    <button
+                        onClick={handleAddSection}
+                        title="Add a section divider to this list"
+                        className="flex items-center gap-2 px-4 py-2 bg-white/5 hover:bg-white/10 text-white rounded-lg font-medium transition-all text-sm border border-white/10"
+                    >
+                        <SeparatorHorizontal className="w-4 h-4" />
+                        Section
+                    </button>
+                    <button
+                        onClick={() => queueMutation.mutate()}
+                        disabled={queueMutation.isPending || safeItems.length === 0}
+                        title="Replace the Kodi playlist with this list and start playing"
+                        className="flex items-center gap-2 px-4 py-2 bg-white/5 hover:bg-white/10 text-white rounded-lg font-medium transition-all text-sm border border-white/10 disabled:opacity-50"
+                    >
+                        <ListVideo className="w-4 h-4" />
+                        {queueMutation.isPending ? 'Queuing...' : 'Play all'}
+                    </button>
+                    <button
+                        onClick={() => partyMutation.mutate()}
+                        disabled={partyMutation.isPending || safeItems.length === 0}
+                        title="Play every unwatched item back to back"
+                        className="flex items-center gap-2 px-4 py-2 bg-white/5 hover:bg-white/10 text-white rounded-lg font-medium transition-all text-sm border border-white/10 disabled:opacity-50"
+                    >
+                        <PartyPopper className="w-4 h-4" />
+                        {partyMutation.isPending ? 'Queuing...' : 'Party mode'}
+                    </button>
                     <button
                         onClick={handleSync}
                         disabled={isSyncing}
@@ -113,19 +263,64 @@ export function WatchList({ listId, name, contentType }: WatchListProps) {
                 </div>
             </div>
 
+            {missingCount > 0 && (
+                <div className="flex items-center justify-between gap-4 mb-4 px-4 py-3 rounded-lg border border-red-400/20 bg-red-400/5 text-sm">
+                    <span className="text-red-300">
+                        {missingCount} {missingCount === 1 ? 'item is' : 'items are'} no longer in the Kodi library.
+                    </span>
+                    <button
+                        onClick={() => cleanupMutation.mutate()}
+                        disabled={cleanupMutation.isPending}
+                        className="px-3 py-1.5 rounded bg-red-400/10 hover:bg-red-400/20 text-red-300 font-medium transition disabled:opacity-50"
+                    >
+                        {cleanupMutation.isPending ? 'Removing...' : 'Remove them'}
+                    </button>
+                </div>
+            )}
+
             {isLoading ? (
                 <div className="flex justify-center py-20">
                     <Loader2 className="animate-spin text-textMuted w-10 h-10" />
                 </div>
-            ) : safeItems.length === 0 ? (
+            ) : safeItems.length === 0 && safeSections.length === 0 ? (
                 <div className="bg-surface rounded-xl border border-border p-12 text-center shadow-xl">
                     <p className="text-textMuted">Your list is empty. Time to add something!</p>
                 </div>
             ) : (
                 <DndContext sensors={sensors} collisionDetection={closestCenter} onDragEnd={onDragEnd}>
                     <SortableContext items={safeItems.map(i => i.id)} strategy={verticalListSortingStrategy}>
-                        {safeItems.map((item) => (
-                            <SortableItem key={item.id} item={item} onDelete={(id) => deleteMutation.mutate(id)} />
+                        {[{ id: 0, name: '' }, ...safeSections].map((section) => (
+                            <Fragment key={section.id}>
+                                {section.id !== 0 && (
+                                    <div className="flex items-center gap-3 mt-6 mb-3 group">
+                                        <h3 className="text-sm font-semibold uppercase tracking-wide text-textMuted">{section.name}</h3>
+                                        <div className="flex-1 h-px bg-border" />
+                                        <button
+                                            onClick={() => deleteSectionMutation.mutate(section.id)}
+                                            title="Remove section (its items stay on the list)"
+                                            className="opacity-0 group-hover:opacity-100 text-textMuted hover:text-red-400 transition"
+                                        >
+                                            <X className="w-4 h-4" />
+                                        </button>
+                                    </div>
+                                )}
+                                {groupByFranchise && franchiseGroups ? (
+                                    franchiseGroups.filter((group) => (group.section_id ?? 0) === section.id).map((group) => (
+                                        group.franchise ? (
+                                            <div key={`franchise-${group.items[0].id}`} className="mb-3 pl-3 border-l-2 border-primary/30">
+                                                <div className="text-[10px] uppercase tracking-wider font-bold text-primary mb-2">
+                                                    {group.franchise} <span className="text-textMuted">· {group.items.length}</span>
+                                                </div>
+                                                {group.items.map(renderItem)}
+                                            </div>
+                                        ) : (
+                                            group.items.map(renderItem)
+                                        )
+                                    ))
+                                ) : (
+                                    safeItems.filter((item) => (item.section_id ?? 0) === section.id).map(renderItem)
+                                )}
+                            </Fragment>
                         ))}
                     </SortableContext>
                 </DndContext>
@@ -4,21 +4,53 @@ export interface List {
     list_name: string;
     content_type: string;
     kodi_host: string;
+    has_credentials: boolean;
+    on_watched?: 'remove' | 'archive';
+    art_preference?: string[];
+    archived_at?: string;
 }
 
 export interface Item {
     id: number;
     list_id: number;
     kodi_id: number;
-    media_type: 'movie' | 'episode' | 'show' | 'season';
+    media_type: 'movie' | 'episode' | 'show' | 'season' | 'set' | 'album' | 'artist' | 'recording' | 'channel' | 'file';
     title: string;
+    artist?: string;
     year: number;
     poster_path: string;
     runtime: number;
     episode_count: number;
+    watched_episodes?: number;
     season: number;
     rating: number;
     sort_order: number;
+    wanted?: boolean;
+    watched?: boolean;
+    playcount?: number;
+    last_played?: string;
+    resume_position?: number;
+    archived_at?: string;
+    section_id?: number;
+    missing_since?: string;
+    imdb_id?: string;
+    tmdb_id?: string;
+    resolution?: number;
+    hdr_type?: string;
+    quality?: string;
+    art?: Partial<Record<'fanart' | 'banner' | 'clearlogo', string>>;
+    // Episode items only
+    episode?: number;
+    tvshow_id?: number;
+    show_title?: string;
+    episode_code?: string; // e.g. "S03E05"
+}
+
+export interface Section {
+    id: number;
+    list_id: number;
+    name: string;
+    sort_order: number;
 }
 
 export interface MediaItem {
@@ -31,9 +63,20 @@ export interface MediaItem {
     runtime?: number;
     rating?: number;
     showtitle?: string;
+    artist?: string;
     season?: number;
     episode?: number;
     episode_count?: number;
+    genre?: string[];
+    playcount?: number;
+    lastplayed?: string;
+    dateadded?: string;
+    tvshowid?: number;
+    resume?: { position: number; total: number };
+    uniqueid?: Record<string, string>;
+    quality?: string;
+    on_lists?: { id: number; list_name: string }[];
+    last_watched?: string;
 }
 
 const API_BASE = '/api';
@@ -43,8 +86,186 @@ export async function getLists(): Promise<List[]> {
     return res.json();
 }
 
+export interface Group {
+    id: number;
+    group_name: string;
+    kodi_host: string;
+    sort_order: number;
+    has_credentials: boolean;
+    lists: number;
+}
+
+// Groups in display order; getLists returns lists in the same order.
+export async function getGroups(): Promise<Group[]> {
+    const res = await fetch(`${API_BASE}/groups`);
+    return res.json();
+}
+
+// Puts the groups in the given order; groups left out follow.
+export async function reorderGroups(groupIds: number[]): Promise<Group[]> {
+    const res = await fetch(`${API_BASE}/groups/order`, {
+        method: 'PUT',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ group_ids: groupIds }),
+    });
+    if (!res.ok) throw new Error(`Reorder failed (status ${res.status})`);
+    return res.json();
+}
+
+export async function renameGroup(groupId: number, name: string): Promise<Group> {
+    const res = await fetch(`${API_BASE}/groups/${groupId}`, {
+        method: 'PATCH',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ group_name: name }),
+    });
+    if (!res.ok) {
+        const text = await res.text();
+        throw new Error(text.trim() || `Rename failed (status ${res.status})`);
+    }
+    return res.json();
+}
+
+export async function getArchivedLists(): Promise<List[]> {
+    const res = await fetch(`${API_BASE}/lists?archived=true`);
+    return res.json();
+}
+
+export interface ListClone {
+    list: List;
+    created: boolean;
+    added: number;
+    wanted: number;
+    skipped: { item_id: number; media_type: string; title: string; year: number; reason: string }[];
+}
+
+// Copies a list's items into an existing list ({list_id}) or a new one
+// ({list_name}, optionally in another group or on another host).
+export async function cloneList(
+    listId: number,
+    target: { list_id?: number; list_name?: string; group_name?: string; kodi_host?: string; username?: string; password?: string },
+): Promise<ListClone> {
+    const res = await fetch(`${API_BASE}/lists/${listId}/clone`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(target),
+    });
+    if (!res.ok) {
+        const text = await res.text();
+        throw new Error(text.trim() || `Clone failed (status ${res.status})`);
+    }
+    return res.json();
+}
+
+// Archives a list, hiding it from getLists, or restores an archived one.
+export async function setListArchived(listId: number, archived: boolean): Promise<List> {
+    const res = await fetch(`${API_BASE}/lists/${listId}/${archived ? 'archive' : 'restore'}`, { method: 'POST' });
+    if (!res.ok) {
+        const text = await res.text();
+        throw new Error(text.trim() || `Archive failed (status ${res.status})`);
+    }
+    return res.json();
+}
+
+// Revision of each list as of its last fetch, sent back as If-Match so
+// concurrent edits are rejected instead of interleaved.
+const listRevisions = new Map<number, string>();
+
+// The list a GET /lists/{id}/items response belongs to, for headers.
+export interface ListContext {
+    id: number;
+    list_name: string;
+    group_name: string;
+    content_type: string;
+    last_synced: string;
+}
+
 export async function getItems(listId: number): Promise<Item[]> {
     const res = await fetch(`${API_BASE}/lists/${listId}/items`);
+    const etag = res.headers.get('ETag');
+    if (etag) listRevisions.set(listId, etag);
+    const body: { list: ListContext; items: Item[] } = await res.json();
+    return body.items;
+}
+
+// A run of items from one franchise (Kodi movie set or shared title), or a
+// single item outside any franchise.
+export interface FranchiseGroup {
+    franchise?: string;
+    set_id?: number;
+    section_id?: number;
+    items: Item[];
+}
+
+export async function getFranchiseGroups(listId: number): Promise<FranchiseGroup[]> {
+    const res = await fetch(`${API_BASE}/lists/${listId}/items?group_by=franchise`);
+    if (!res.ok) throw new Error(`Grouped items failed (status ${res.status})`);
+    const body: { list: ListContext; groups: FranchiseGroup[] } = await res.json();
+    return body.groups;
+}
+
+export class ConflictError extends Error {}
+
+// Kodi rejected the list's credentials, as opposed to being unreachable.
+export class KodiAuthError extends Error {
+    constructor(message: string, public host: string) {
+        super(message);
+    }
+}
+
+// Throws KodiAuthError when a failed response reports rejected Kodi credentials.
+async function checkKodiAuth(res: Response): Promise<void> {
+    if (res.status !== 502 || !res.headers.get('Content-Type')?.includes('application/json')) return;
+    const body = await res.clone().json().catch(() => null);
+    if (body?.error === 'kodi_auth_failed') throw new KodiAuthError(body.message, body.host);
+}
+
+// Saves a list's order in one request. Rejects with ConflictError when the
+// list changed since it was fetched; refetch and let the user retry.
+export async function reorderList(listId: number, itemIds: number[], checkRevision = true): Promise<void> {
+    const revision = listRevisions.get(listId);
+    const res = await fetch(`${API_BASE}/lists/${listId}/order`, {
+        method: 'PUT',
+        headers: {
+            'Content-Type': 'application/json',
+            ...(checkRevision && revision ? { 'If-Match': revision } : {}),
+        },
+        body: JSON.stringify({ item_ids: itemIds }),
+    });
+    if (res.status === 409) throw new ConflictError((await res.text()).trim());
+    if (!res.ok) throw new Error(`Reorder failed (status ${res.status})`);
+    const etag = res.headers.get('ETag');
+    if (etag) listRevisions.set(listId, etag);
+}
+
+export async function getArchivedItems(listId: number): Promise<Item[]> {
+    const res = await fetch(`${API_BASE}/lists/${listId}/items?archived=true`);
+    const body: { list: ListContext; items: Item[] } = await res.json();
+    return body.items;
+}
+
+export async function restoreItem(itemId: number): Promise<Item> {
+    const res = await fetch(`${API_BASE}/items/${itemId}/restore`, { method: 'POST' });
+    if (!res.ok) {
+        const text = await res.text();
+        throw new Error(text.trim() || `Restore failed (status ${res.status})`);
+    }
+    return res.json();
+}
+
+// Replaces a movie set on its list with the movies in it.
+export async function expandItem(itemId: number): Promise<Item[]> {
+    const res = await fetch(`${API_BASE}/items/${itemId}/expand`, { method: 'POST' });
+    if (!res.ok) {
+        const text = await res.text();
+        throw new Error(text.trim() || `Expand failed (status ${res.status})`);
+    }
+    return res.json();
+}
+
+// Removes every item whose media was deleted from the Kodi library.
+export async function removeMissingItems(listId: number): Promise<{ removed: number }> {
+    const res = await fetch(`${API_BASE}/lists/${listId}/missing`, { method: 'DELETE' });
+    if (!res.ok) throw new Error(`Cleanup failed (status ${res.status})`);
     return res.json();
 }
 
@@ -54,6 +275,10 @@ export async function addItem(listId: number, item: Partial<Item>): Promise<Item
         headers: { 'Content-Type': 'application/json' },
         body: JSON.stringify(item),
     });
+    if (!res.ok) {
+        const text = await res.text();
+        throw new Error(text.trim() || `Failed to add item (status ${res.status})`);
+    }
     return res.json();
 }
 
@@ -61,6 +286,40 @@ export async function deleteItem(itemId: number): Promise<void> {
     await fetch(`${API_BASE}/items/${itemId}`, { method: 'DELETE' });
 }
 
+export async function setItemWatched(itemId: number, watched: boolean): Promise<Item> {
+    const res = await fetch(`${API_BASE}/items/${itemId}/watched`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ watched }),
+    });
+    if (!res.ok) {
+        const text = await res.text();
+        throw new Error(text.trim() || `Update failed (status ${res.status})`);
+    }
+    return res.json();
+}
+
+export interface BulkWatchedResult {
+    updated: number;
+    items: Item[];
+    kodi_errors: { item_id: number; error: string }[];
+}
+
+// Sets the watched flag of several items together; with kodi the playcounts
+// are updated in the Kodi library as well.
+export async function setItemsWatched(listId: number, itemIds: number[], watched: boolean, kodi = false): Promise<BulkWatchedResult> {
+    const res = await fetch(`${API_BASE}/lists/${listId}/items/watched`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ item_ids: itemIds, watched, kodi }),
+    });
+    if (!res.ok) {
+        const text = await res.text();
+        throw new Error(text.trim() || `Update failed (status ${res.status})`);
+    }
+    return res.json();
+}
+
 export async function reorderItem(itemId: number, sortOrder: number): Promise<void> {
     await fetch(`${API_BASE}/items/${itemId}/reorder`, {
         method: 'PATCH',
@@ -69,22 +328,387 @@ export async function reorderItem(itemId: number, sortOrder: number): Promise<vo
     });
 }
 
-export async function searchMedia(query: string, listId: number, contentType: string): Promise<MediaItem[]> {
+export async function getSections(listId: number): Promise<Section[]> {
+    const res = await fetch(`${API_BASE}/lists/${listId}/sections`);
+    if (!res.ok) throw new Error(`Failed to load sections (status ${res.status})`);
+    return res.json();
+}
+
+export async function addSection(listId: number, name: string): Promise<Section> {
+    const res = await fetch(`${API_BASE}/lists/${listId}/sections`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ name }),
+    });
+    if (!res.ok) {
+        const text = await res.text();
+        throw new Error(text.trim() || `Failed to add section (status ${res.status})`);
+    }
+    return res.json();
+}
+
+export async function updateSection(listId: number, sectionId: number, changes: { name?: string; sort_order?: number }): Promise<Section> {
+    const res = await fetch(`${API_BASE}/lists/${listId}/sections/${sectionId}`, {
+        method: 'PATCH',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(changes),
+    });
+    if (!res.ok) {
+        const text = await res.text();
+        throw new Error(text.trim() || `Failed to update section (status ${res.status})`);
+    }
+    return res.json();
+}
+
+export async function deleteSection(listId: number, sectionId: number): Promise<void> {
+    await fetch(`${API_BASE}/lists/${listId}/sections/${sectionId}`, { method: 'DELETE' });
+}
+
+// A sectionId of 0 moves the item out of any section.
+export async function setItemSection(itemId: number, sectionId: number): Promise<Item> {
+    const res = await fetch(`${API_BASE}/items/${itemId}/section`, {
+        method: 'PATCH',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ section_id: sectionId }),
+    });
+    if (!res.ok) {
+        const text = await res.text();
+        throw new Error(text.trim() || `Failed to move item (status ${res.status})`);
+    }
+    return res.json();
+}
+
+export interface ActivePlayer {
+    playerid: number;
+    type: 'video' | 'audio' | 'picture';
+}
+
+export async function getActivePlayers(listId: number): Promise<ActivePlayer[]> {
+    const res = await fetch(`${API_BASE}/lists/${listId}/players`);
+    if (!res.ok) throw new Error(`Failed to fetch players (status ${res.status})`);
+    return res.json();
+}
+
+// Playback won't replace music or a slideshow unless playerId names it.
+export async function queueList(listId: number, play: boolean, playerId?: number): Promise<{ queued: number; skipped: number[]; playing: boolean }> {
+    const params = new URLSearchParams();
+    if (play) params.set('play', 'true');
+    if (playerId !== undefined) params.set('player_id', playerId.toString());
+    const query = params.toString();
+    const res = await fetch(`${API_BASE}/lists/${listId}/queue${query ? `?${query}` : ''}`, { method: 'POST' });
+    if (!res.ok) {
+        const text = await res.text();
+        throw new Error(text.trim() || `Queue failed (status ${res.status})`);
+    }
+    return res.json();
+}
+
+// Party mode: queues every unwatched item and starts playing it.
+export async function queueAll(listId: number, playerId?: number): Promise<{ queued: number; skipped: number[]; watched: number[]; playing: boolean }> {
+    const query = playerId !== undefined ? `?player_id=${playerId}` : '';
+    const res = await fetch(`${API_BASE}/lists/${listId}/queue-all${query}`, { method: 'POST' });
+    if (!res.ok) {
+        const text = await res.text();
+        throw new Error(text.trim() || `Queue failed (status ${res.status})`);
+    }
+    return res.json();
+}
+
+export interface NextEpisode {
+    item_id: number;
+    episode_id: number;
+    season: number;
+    episode: number;
+    code: string; // e.g. "S03E05"
+    title: string;
+    resume_position?: number;
+}
+
+// Shows and seasons with nothing left to watch are omitted.
+export async function getNextEpisodes(listId: number): Promise<NextEpisode[]> {
+    const res = await fetch(`${API_BASE}/lists/${listId}/next-episodes`);
+    if (!res.ok) throw new Error(`Next episodes failed (status ${res.status})`);
+    return res.json();
+}
+
+export interface NowPlaying {
+    type: 'movie' | 'episode' | 'unknown';
+    kodi_id?: number;
+    title: string;
+    show_title?: string;
+    season?: number;
+    episode?: number;
+    poster_path?: string;
+    percentage: number;
+    paused: boolean;
+    item_id: number | null;
+}
+
+// Resolves to null when nothing is playing. Defaults to the video player.
+export async function getNowPlaying(listId: number, playerId?: number): Promise<NowPlaying | null> {
+    const query = playerId !== undefined ? `?player_id=${playerId}` : '';
+    const res = await fetch(`${API_BASE}/lists/${listId}/nowplaying${query}`);
+    if (!res.ok) throw new Error(`Now playing failed (status ${res.status})`);
+    return res.status === 204 ? null : res.json();
+}
+
+export type PlayerAction = 'playpause' | 'stop' | 'seek';
+
+export interface SeekTarget {
+    percentage?: number;
+    seconds?: number;
+    step?: 'smallforward' | 'smallbackward' | 'bigforward' | 'bigbackward';
+}
+
+export async function controlPlayer(listId: number, action: PlayerAction, seek?: SeekTarget, playerId?: number): Promise<any> {
+    const query = playerId !== undefined ? `?player_id=${playerId}` : '';
+    const res = await fetch(`${API_BASE}/lists/${listId}/player/${action}${query}`, {
+        method: 'POST',
+        headers: seek ? { 'Content-Type': 'application/json' } : undefined,
+        body: seek ? JSON.stringify(seek) : undefined,
+    });
+    if (!res.ok) {
+        const text = await res.text();
+        throw new Error(text.trim() || `Player command failed (status ${res.status})`);
+    }
+    return res.status === 204 ? null : res.json();
+}
+
+export interface SearchExclusions {
+    genres?: string[];
+    lists?: number[];
+    watched?: boolean;
+}
+
+// minResolution keeps titles of at least that quality, e.g. '1080p' or '4k'.
+export async function searchMedia(query: string, listId: number, contentType: string, exclude?: SearchExclusions, minResolution?: string): Promise<MediaItem[]> {
     const params = new URLSearchParams({ q: query, list_id: listId.toString(), content_type: contentType });
+    if (minResolution) params.set('min_resolution', minResolution);
+    exclude?.genres?.forEach(g => params.append('exclude_genre', g));
+    exclude?.lists?.forEach(id => params.append('exclude_list', id.toString()));
+    if (exclude?.watched) params.set('exclude_watched', 'true');
     const res = await fetch(`${API_BASE}/search?${params}`);
     return res.json();
 }
 
+export type BrowseBy = 'decade' | 'year' | 'genre' | 'rating';
+
+export interface BrowseGroup {
+    key: string;
+    label: string;
+    count: number;
+}
+
+export async function browseGroups(listId: number, contentType: string, by: BrowseBy): Promise<BrowseGroup[]> {
+    const params = new URLSearchParams({ list_id: listId.toString(), content_type: contentType, by });
+    const res = await fetch(`${API_BASE}/library/browse?${params}`);
+    if (!res.ok) throw new Error(`Browse failed (status ${res.status})`);
+    return (await res.json()).groups;
+}
+
+export async function browseItems(listId: number, contentType: string, by: BrowseBy, key: string): Promise<MediaItem[]> {
+    const params = new URLSearchParams({ list_id: listId.toString(), content_type: contentType, by, key });
+    const res = await fetch(`${API_BASE}/library/browse?${params}`);
+    if (!res.ok) throw new Error(`Browse failed (status ${res.status})`);
+    return (await res.json()).items;
+}
+
+export interface Genre {
+    genre_id?: number; // absent when listed from the library cache
+    title: string;
+    count: number;
+}
+
+export async function getGenres(listId: number, contentType: string): Promise<Genre[]> {
+    const params = new URLSearchParams({ list_id: listId.toString(), content_type: contentType });
+    const res = await fetch(`${API_BASE}/library/genres?${params}`);
+    if (!res.ok) throw new Error(`Genres failed (status ${res.status})`);
+    return res.json();
+}
+
+export interface Suggestion extends MediaItem {
+    score: number;
+    because: { kodi_id: number; title: string };
+    reasons: string[];
+}
+
+export async function getSuggestions(listId: number, limit = 10): Promise<Suggestion[]> {
+    const params = new URLSearchParams({ list_id: listId.toString(), limit: limit.toString() });
+    const res = await fetch(`${API_BASE}/suggestions?${params}`);
+    if (!res.ok) throw new Error(`Suggestions failed (status ${res.status})`);
+    return res.json();
+}
+
+export type Shelf = 'recent' | 'inprogress';
+
+// For TV the items are episodes; on_lists and last_watched describe their show.
+export async function getShelf(listId: number, contentType: string, shelf: Shelf, limit?: number): Promise<MediaItem[]> {
+    const params = new URLSearchParams({ list_id: listId.toString(), content_type: contentType });
+    if (limit) params.set('limit', limit.toString());
+    const res = await fetch(`${API_BASE}/library/${shelf}?${params}`);
+    await checkKodiAuth(res);
+    if (!res.ok) throw new Error(`Shelf failed (status ${res.status})`);
+    return res.json();
+}
+
+export interface SavedSearch {
+    id: number;
+    list_id: number;
+    name: string;
+    params: Record<string, string[]>;
+    created_at: string;
+}
+
+export async function getSavedSearches(listId: number): Promise<SavedSearch[]> {
+    const res = await fetch(`${API_BASE}/lists/${listId}/searches`);
+    if (!res.ok) throw new Error(`Failed to load saved searches (status ${res.status})`);
+    return res.json();
+}
+
+export async function saveSearch(listId: number, name: string, params: Record<string, string[]>): Promise<SavedSearch> {
+    const res = await fetch(`${API_BASE}/lists/${listId}/searches`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ name, params }),
+    });
+    if (!res.ok) {
+        const text = await res.text();
+        throw new Error(text.trim() || `Save failed (status ${res.status})`);
+    }
+    return res.json();
+}
+
+export async function deleteSavedSearch(listId: number, searchId: number): Promise<void> {
+    await fetch(`${API_BASE}/lists/${listId}/searches/${searchId}`, { method: 'DELETE' });
+}
+
+export async function runSavedSearch(listId: number, searchId: number): Promise<MediaItem[]> {
+    const res = await fetch(`${API_BASE}/lists/${listId}/searches/${searchId}/results`);
+    if (!res.ok) throw new Error(`Search failed (status ${res.status})`);
+    return res.json();
+}
+
+export interface Feed {
+    id: number;
+    list_id: number;
+    url: string;
+    title: string;
+    last_checked_at: string;
+    last_error?: string;
+    created_at: string;
+}
+
+export interface FeedCheck extends Feed {
+    new: number;
+    matched: number;
+    pending: number;
+}
+
+export async function getFeeds(listId: number): Promise<Feed[]> {
+    const res = await fetch(`${API_BASE}/lists/${listId}/feeds`);
+    if (!res.ok) throw new Error(`Failed to load feeds (status ${res.status})`);
+    return res.json();
+}
+
+export async function addFeed(listId: number, url: string): Promise<FeedCheck> {
+    const res = await fetch(`${API_BASE}/lists/${listId}/feeds`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ url }),
+    });
+    if (!res.ok) {
+        const text = await res.text();
+        throw new Error(text.trim() || `Subscribe failed (status ${res.status})`);
+    }
+    return res.json();
+}
+
+export async function checkFeed(listId: number, feedId: number): Promise<FeedCheck> {
+    const res = await fetch(`${API_BASE}/lists/${listId}/feeds/${feedId}/check`, { method: 'POST' });
+    if (!res.ok) throw new Error(`Feed check failed (status ${res.status})`);
+    return res.json();
+}
+
+export async function deleteFeed(listId: number, feedId: number): Promise<void> {
+    await fetch(`${API_BASE}/lists/${listId}/feeds/${feedId}`, { method: 'DELETE' });
+}
+
+export interface Viewing {
+    id: number;
+    media_type: 'movie' | 'episode';
+    kodi_id: number;
+    tvshow_id?: number;
+    title: string;
+    show_title?: string;
+    season?: number;
+    episode?: number;
+    year?: number;
+    runtime: number;
+    played_at: string;
+    playcount: number;
+    resume_position: number;
+    completed: boolean;
+}
+
+export interface HistoryStats {
+    plays: number;
+    movies_watched: number;
+    episodes_watched: number;
+    watch_seconds: number;
+    watch_formatted: string;
+    days: { date: string; plays: number; watch_seconds: number }[];
+    most_watched_shows: string[];
+}
+
+export async function getHistory(listId: number, options: { mediaType?: 'movie' | 'episode'; limit?: number; offset?: number } = {}): Promise<Viewing[]> {
+    const params = new URLSearchParams();
+    if (options.mediaType) params.set('media_type', options.mediaType);
+    if (options.limit) params.set('limit', options.limit.toString());
+    if (options.offset) params.set('offset', options.offset.toString());
+    const res = await fetch(`${API_BASE}/lists/${listId}/history?${params}`);
+    if (!res.ok) throw new Error(`Failed to load history (status ${res.status})`);
+    return res.json();
+}
+
+export async function getHistoryStats(listId: number, days?: number): Promise<HistoryStats> {
+    const params = days ? `?days=${days}` : '';
+    const res = await fetch(`${API_BASE}/lists/${listId}/history/stats${params}`);
+    if (!res.ok) throw new Error(`Failed to load history stats (status ${res.status})`);
+    return res.json();
+}
+
+export async function refreshHistory(listId: number): Promise<{ added: number }> {
+    const res = await fetch(`${API_BASE}/lists/${listId}/history/refresh`, { method: 'POST' });
+    if (!res.ok) throw new Error(`History refresh failed (status ${res.status})`);
+    return res.json();
+}
+
 export async function syncLibrary(listId: number, contentType: string): Promise<{ count: number }> {
     const params = new URLSearchParams({ list_id: listId.toString(), content_type: contentType });
     const res = await fetch(`${API_BASE}/sync?${params}`);
     if (!res.ok) {
+        await checkKodiAuth(res);
         const text = await res.text();
         throw new Error(`Sync failed (status ${res.status}): ${text || 'Unknown error'}`);
     }
     return res.json();
 }
 
+export type LibraryJob = 'scan' | 'clean';
+
+// Kodi runs the job in the background; the response only confirms it started.
+export async function startLibraryJob(listId: number, job: LibraryJob, directory?: string): Promise<void> {
+    const res = await fetch(`${API_BASE}/lists/${listId}/${job}`, {
+        method: 'POST',
+        headers: directory ? { 'Content-Type': 'application/json' } : undefined,
+        body: directory ? JSON.stringify({ directory }) : undefined,
+    });
+    if (!res.ok) {
+        const text = await res.text();
+        throw new Error(text.trim() || `Library ${job} failed (status ${res.status})`);
+    }
+}
+
 export async function getSeasons(showId: number, listId: number): Promise<MediaItem[]> {
     const params = new URLSearchParams({ tvshowid: showId.toString(), list_id: listId.toString() });
     const res = await fetch(`${API_BASE}/tv/seasons?${params}`);
@@ -97,6 +721,111 @@ export async function getEpisodes(showId: number, season: number, listId: number
     return res.json();
 }
 
+export async function getMovieSets(listId: number): Promise<MediaItem[]> {
+    const params = new URLSearchParams({ list_id: listId.toString() });
+    const res = await fetch(`${API_BASE}/movies/sets?${params}`);
+    return res.json();
+}
+
+export async function getSetMovies(setId: number, listId: number): Promise<MediaItem[]> {
+    const params = new URLSearchParams({ setid: setId.toString(), list_id: listId.toString() });
+    const res = await fetch(`${API_BASE}/movies/sets/movies?${params}`);
+    return res.json();
+}
+
+export interface CastMember {
+    name: string;
+    role?: string;
+    order: number;
+    thumbnail?: string;
+}
+
+export interface MovieDetails {
+    id: number;
+    title: string;
+    originaltitle?: string;
+    tagline?: string;
+    plot?: string;
+    year?: number;
+    rating?: number;
+    mpaa?: string;
+    runtime?: number;
+    runtime_formatted?: string;
+    genre?: string[];
+    director?: string[];
+    writer?: string[];
+    studio?: string[];
+    country?: string[];
+    cast: CastMember[];
+    playcount: number;
+    poster_path?: string;
+    streamdetails: {
+        video: { codec?: string; width?: number; height?: number; aspect?: number; duration?: number; hdrtype?: string }[];
+        audio: { codec?: string; language?: string; channels?: number }[];
+        subtitle: { language?: string }[];
+    };
+}
+
+export async function getMovieDetails(listId: number, movieId: number): Promise<MovieDetails> {
+    const params = new URLSearchParams({ list_id: listId.toString(), movie_id: movieId.toString() });
+    const res = await fetch(`${API_BASE}/details?${params}`);
+    if (!res.ok) throw new Error(`Failed to fetch movie details (status ${res.status})`);
+    return res.json();
+}
+
+export interface TVShowDetails {
+    id: number;
+    title: string;
+    originaltitle?: string;
+    plot?: string;
+    year?: number;
+    premiered?: string;
+    rating?: number;
+    mpaa?: string;
+    status?: string;
+    genre?: string[];
+    studio?: string[];
+    cast: CastMember[];
+    season?: number;
+    episode?: number;
+    watchedepisodes: number;
+    poster_path?: string;
+}
+
+export interface EpisodeDetails {
+    id: number;
+    title: string;
+    showtitle?: string;
+    tvshowid: number;
+    season: number;
+    episode: number;
+    firstaired?: string;
+    plot?: string;
+    rating?: number;
+    runtime?: number;
+    runtime_formatted?: string;
+    director?: string[];
+    writer?: string[];
+    cast: CastMember[];
+    playcount: number;
+    thumbnail_path?: string;
+    streamdetails: MovieDetails['streamdetails'];
+}
+
+export async function getTVShowDetails(listId: number, showId: number): Promise<TVShowDetails> {
+    const params = new URLSearchParams({ list_id: listId.toString(), tvshowid: showId.toString() });
+    const res = await fetch(`${API_BASE}/tv/details?${params}`);
+    if (!res.ok) throw new Error(`Failed to fetch show details (status ${res.status})`);
+    return res.json();
+}
+
+export async function getEpisodeDetails(listId: number, episodeId: number): Promise<EpisodeDetails> {
+    const params = new URLSearchParams({ list_id: listId.toString(), episodeid: episodeId.toString() });
+    const res = await fetch(`${API_BASE}/tv/details?${params}`);
+    if (!res.ok) throw new Error(`Failed to fetch episode details (status ${res.status})`);
+    return res.json();
+}
+
 export interface Config {
     subtitle: string;
     footer: string;
@@ -106,3 +835,45 @@ export async function getConfig(): Promise<Config> {
     const res = await fetch(`${API_BASE}/config`);
     return res.json();
 }
+
+export interface MetricsHour {
+    hour: string;
+    requests: number;
+    errors: number;
+    client_errors: number;
+    syncs: number;
+}
+
+export interface MetricsSummary {
+    window_hours: number;
+    requests: number;
+    errors: number;
+    client_errors: number;
+    syncs: { runs: number; failures: number; items: number; avg_duration_ms: number };
+    hourly: MetricsHour[];
+}
+
+export async function getMetricsSummary(hours = 24): Promise<MetricsSummary> {
+    const res = await fetch(`${API_BASE}/metrics/summary?hours=${hours}`);
+    if (!res.ok) throw new Error(`Failed to fetch metrics (status ${res.status})`);
+    return res.json();
+}
+
+export interface ConnectionTest {
+    reachable: boolean;
+    auth_ok: boolean;
+    latency_ms: number;
+    error?: string;
+    name?: string;
+    version?: string;
+    api_version?: string;
+    capabilities?: Record<string, boolean>;
+    supported: boolean;
+    warnings: string[];
+}
+
+export async function testConnection(listId: number): Promise<ConnectionTest> {
+    const res = await fetch(`${API_BASE}/lists/${listId}/test`, { method: 'POST' });
+    if (!res.ok) throw new Error(`Connection test failed (status ${res.status})`);
+    return res.json();
+}